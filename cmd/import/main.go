@@ -0,0 +1,129 @@
+// import 从CSV或XLSX文件批量导入楼号、户号和居民，校验规则和事务行为与 /api/import 接口相同。
+//
+// 用法:
+//
+//	go run ./cmd/import -type buildings -file buildings.xlsx -dry-run
+//	go run ./cmd/import -type residents -file residents.csv -property 1
+//
+// 数据库连接从 .env 或环境变量读取。有数据行校验失败时输出逐行的错误并以状态码 1 退出，不写入任何数据。
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"ilock-http-service/internal/domain/services"
+	"ilock-http-service/internal/infrastructure/config"
+	"ilock-http-service/internal/infrastructure/database"
+	"ilock-http-service/internal/infrastructure/spreadsheet"
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func main() {
+	kind := flag.String("type", "", "导入类型：buildings、households 或 residents")
+	path := flag.String("file", "", "CSV或XLSX文件路径")
+	dryRun := flag.Bool("dry-run", false, "只校验不写入")
+	propertyID := flag.Uint("property", 0, "限定导入到指定物业，0 表示不限物业")
+	asJSON := flag.Bool("json", false, "以JSON格式输出导入结果")
+	flag.Parse()
+
+	if *kind == "" || *path == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	rows, err := readFile(*path)
+	if err != nil {
+		log.Fatalf("读取导入文件失败: %v", err)
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Printf("无法加载.env文件: %v，使用环境变量中的配置", err)
+	}
+	cfg := config.GetConfig()
+
+	pool, err := database.NewConnectionPool(cfg)
+	if err != nil {
+		log.Fatalf("无法创建数据库连接池: %v", err)
+	}
+	defer pool.Close()
+
+	// 导入时不输出每一条SQL
+	db := pool.GetDB().Session(&gorm.Session{Logger: logger.Default.LogMode(logger.Warn)})
+	service := services.NewImportService(db, cfg, database.NewConnectionPoolWithDB(db), nil)
+	if *propertyID > 0 {
+		id := *propertyID
+		service = service.WithPropertyScope(&id)
+	}
+
+	report, err := service.Import(*kind, rows, *dryRun)
+	if err != nil && !errors.Is(err, services.ErrImportRowsInvalid) {
+		log.Fatalf("导入失败: %v", err)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			log.Fatalf("输出导入结果失败: %v", err)
+		}
+	} else {
+		printReport(report)
+	}
+
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// readFile 按扩展名读取CSV或XLSX文件的所有行
+func readFile(path string) ([][]string, error) {
+	format, err := spreadsheet.FormatFromFilename(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return spreadsheet.ReadRows(file, info.Size(), format)
+}
+
+// printReport 输出导入结果和逐行的错误
+func printReport(report *services.ImportReport) {
+	mode := "导入"
+	if report.DryRun {
+		mode = "试运行"
+	}
+	fmt.Printf("%s %s: 共 %d 行，通过 %d 行，失败 %d 行\n", mode, report.Kind, report.Total, report.Succeeded, report.Failed)
+
+	for _, rowErr := range report.Errors {
+		if rowErr.Column != "" {
+			fmt.Printf("  第 %d 行 %s: %s\n", rowErr.Row, rowErr.Column, rowErr.Message)
+		} else {
+			fmt.Printf("  第 %d 行: %s\n", rowErr.Row, rowErr.Message)
+		}
+	}
+
+	switch {
+	case report.Committed:
+		fmt.Println("数据已写入")
+	case report.DryRun:
+		fmt.Println("试运行，没有写入数据")
+	default:
+		fmt.Println("有数据行校验失败，没有写入任何数据")
+	}
+}
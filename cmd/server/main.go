@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"ilock-http-service/internal/app/routes"
 	"ilock-http-service/internal/domain/models"
+	"ilock-http-service/internal/domain/services"
 	"ilock-http-service/internal/infrastructure/config"
 	"ilock-http-service/internal/infrastructure/database"
 	Logger "ilock-http-service/pkg/logger"
@@ -91,6 +92,11 @@ func main() {
 	// 确保系统中有管理员账户
 	ensureAdminExists(db, cfg)
 
+	// 同步内置权限、角色和默认角色绑定
+	if err := services.NewRBACService(db, cfg).SeedDefaults(); err != nil {
+		log.Fatalf("初始化权限数据失败: %v", err)
+	}
+
 	// 初始化路由
 	r := routes.SetupRouter(db, cfg)
 
@@ -124,7 +130,16 @@ func initDB(cfg *config.Config) (*gorm.DB, error) {
 
 // autoMigrate 自动迁移所有模型（只添加新列和新表）
 func autoMigrate(db *gorm.DB) error {
-	err := db.AutoMigrate(
+	if err := setupJoinTables(db); err != nil {
+		return err
+	}
+	legacyRelations, err := loadLegacyStaffDeviceRelations(db)
+	if err != nil {
+		return err
+	}
+
+	err = db.AutoMigrate(
+		&models.Property{},
 		&models.Admin{},
 		&models.PropertyStaff{},
 		&models.Device{},
@@ -133,16 +148,89 @@ func autoMigrate(db *gorm.DB) error {
 		&models.AccessLog{},
 		&models.EmergencyLog{},
 		&models.SystemLog{},
+		&models.EmergencyAlarm{},
+		&models.EmergencyAlarmLog{},
+		&models.EmergencyEscalationStep{},
+		&models.EmergencyContact{},
+		&models.EmergencyNotification{},
+		&models.EmergencyNotificationDelivery{},
+		&models.EmergencyUnlockSession{},
+		&models.EmergencyUnlockDevice{},
+		&models.DeviceEvent{},
+		&models.WebhookSubscription{},
+		&models.WebhookDelivery{},
+		&models.Permission{},
+		&models.Role{},
+		&models.RoleBinding{},
+		&models.Passcode{},
+		&models.StaffDeviceRelation{},
+		&models.RefreshToken{},
+		&models.AccountLockout{},
+		&models.PasswordHistory{},
+		&models.AdminTwoFactor{},
+		&models.AdminRecoveryCode{},
+		&models.APIKey{},
+		&models.APIKeyUsage{},
 	)
 
 	if err != nil {
 		return err
 	}
 
+	if len(legacyRelations) > 0 {
+		if err := restoreLegacyStaffDeviceRelations(db, legacyRelations); err != nil {
+			return err
+		}
+	}
+
 	fmt.Println("Database migration completed")
 	return nil
 }
 
+// setupJoinTables 让员工与设备的多对多关联使用 StaffDeviceRelation 作为关系表模型，
+// 保证迁移出的表包含职责字段，且预加载和关系表读写使用相同的列
+func setupJoinTables(db *gorm.DB) error {
+	if err := db.SetupJoinTable(&models.PropertyStaff{}, "Devices", &models.StaffDeviceRelation{}); err != nil {
+		return fmt.Errorf("failed to setup staff device join table: %w", err)
+	}
+	if err := db.SetupJoinTable(&models.Device{}, "Staff", &models.StaffDeviceRelation{}); err != nil {
+		return fmt.Errorf("failed to setup device staff join table: %w", err)
+	}
+	return nil
+}
+
+// loadLegacyStaffDeviceRelations 旧版由多对多关联自动生成的关系表使用 (property_staff_id, device_id) 联合主键，
+// 无法直接添加自增ID列；读出已有关联后删除旧表，迁移完成后由 restoreLegacyStaffDeviceRelations 写回
+func loadLegacyStaffDeviceRelations(db *gorm.DB) ([]models.StaffDeviceRelation, error) {
+	migrator := db.Migrator()
+	if !migrator.HasTable("staff_device_relations") || !migrator.HasColumn("staff_device_relations", "property_staff_id") {
+		return nil, nil
+	}
+
+	log.Println("发现旧版staff_device_relations表，迁移为新的员工设备关系表")
+	var relations []models.StaffDeviceRelation
+	if err := db.Table("staff_device_relations").
+		Select("property_staff_id AS staff_id, device_id").
+		Scan(&relations).Error; err != nil {
+		return nil, fmt.Errorf("failed to read legacy staff_device_relations: %w", err)
+	}
+	if err := migrator.DropTable("staff_device_relations"); err != nil {
+		return nil, fmt.Errorf("failed to drop legacy staff_device_relations: %w", err)
+	}
+	return relations, nil
+}
+
+// restoreLegacyStaffDeviceRelations 将旧版关系表中的关联写入新表
+func restoreLegacyStaffDeviceRelations(db *gorm.DB, relations []models.StaffDeviceRelation) error {
+	for i := range relations {
+		relations[i].Role = models.StaffDeviceRoleMaintainer
+	}
+	if err := db.CreateInBatches(relations, 100).Error; err != nil {
+		return fmt.Errorf("failed to restore legacy staff_device_relations: %w", err)
+	}
+	return nil
+}
+
 // advancedMigrate 执行高级迁移，包括修改列、删除列等
 func advancedMigrate(db *gorm.DB, cfg *config.Config) error {
 	// 获取底层SQL连接
@@ -246,6 +334,14 @@ func dropAndRecreateTables(db *gorm.DB) error {
 	tables := []string{
 		"admins", "property_staffs", "devices", "residents", "call_records",
 		"access_logs", "emergency_logs", "system_logs", "buildings", "households",
+		"emergency_alarms", "emergency_alarm_logs", "emergency_escalation_steps", "emergency_contacts",
+		"emergency_notifications", "emergency_notification_deliveries", "device_events",
+		"emergency_unlock_sessions", "emergency_unlock_devices",
+		"webhook_subscriptions", "webhook_deliveries", "properties",
+		"permissions", "roles", "role_permissions", "role_bindings",
+		"passcodes", "staff_device_relations", "refresh_tokens",
+		"account_lockouts", "password_histories", "admin_two_factors", "admin_recovery_codes",
+		"api_keys", "api_key_usages",
 	}
 
 	for _, table := range tables {
@@ -272,11 +368,13 @@ func ensureAdminExists(db *gorm.DB, cfg *config.Config) {
 			log.Fatalf("生成密码哈希失败: %v", err)
 		}
 
+		// 默认密码来自部署配置，首次登录后必须修改
 		admin := models.Admin{
-			Username: "admin",
-			Password: string(hashedPassword),
-			Role:     "system_admin",
-			Status:   "active",
+			Username:           "admin",
+			Password:           string(hashedPassword),
+			Role:               "system_admin",
+			Status:             "active",
+			MustChangePassword: true,
 		}
 
 		if err := db.Create(&admin).Error; err != nil {
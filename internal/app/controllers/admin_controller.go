@@ -41,6 +41,8 @@ type CreateAdminRequest struct {
 	Password string `json:"password" binding:"required" example:"Admin@123"`
 	Phone    string `json:"phone" binding:"required" example:"13800138000"`
 	Email    string `json:"email" binding:"required,email" example:"admin@example.com"`
+	// 所属物业ID，为空表示平台管理员
+	PropertyID *uint `json:"property_id" example:"1"`
 }
 
 // UpdateAdminRequest 更新管理员请求
@@ -48,6 +50,10 @@ type UpdateAdminRequest struct {
 	Phone    string `json:"phone" example:"13800138000"`
 	Email    string `json:"email" binding:"omitempty,email" example:"admin@example.com"`
 	Password string `json:"password" example:"NewPassword@123"`
+	// 所属物业ID，修改后需重新登录生效
+	PropertyID *uint `json:"property_id" example:"1"`
+	// 为 true 时清除所属物业，改为平台管理员，不能与 property_id 同时传入
+	ClearProperty bool `json:"clear_property" example:"false"`
 }
 
 // HandleAdminFunc 返回一个处理管理员请求的Gin处理函数
@@ -55,6 +61,12 @@ func HandleAdminFunc(container *container.ServiceContainer, method string) gin.H
 	return func(ctx *gin.Context) {
 		controller := NewAdminController(ctx, container)
 
+		// 管理员账号只能由平台管理员维护
+		if getCurrentPropertyID(ctx) != nil {
+			response.FailWithMessage(ctx, code.ErrPropertyScope, "物业管理员无权管理管理员账号", nil)
+			return
+		}
+
 		switch method {
 		case "getAdmins":
 			controller.GetAdmins()
@@ -195,15 +207,19 @@ func (c *AdminController) CreateAdmin() {
 
 	// 创建管理员对象
 	admin := &models.Admin{
-		Username: req.Username,
-		Password: req.Password, // 密码加密将在 Service 层处理
-		Email:    req.Email,
-		Phone:    req.Phone,
+		Username:   req.Username,
+		Password:   req.Password, // 密码加密将在 Service 层处理
+		Email:      req.Email,
+		Phone:      req.Phone,
+		PropertyID: req.PropertyID,
 	}
 
 	// 使用 AdminService 创建管理员
 	adminService := c.Container.GetService("admin").(services.InterfaceAdminService)
 	if err := adminService.CreateAdmin(admin); err != nil {
+		if failPropertyScope(c.Ctx, err) || failPasswordPolicy(c.Ctx, err) {
+			return
+		}
 		response.FailWithMessage(c.Ctx, code.ErrDatabase, "创建管理员失败: "+err.Error(), nil)
 		return
 	}
@@ -211,11 +227,12 @@ func (c *AdminController) CreateAdmin() {
 	// 成功创建后返回管理员信息（不含密码）
 	admin.Password = ""
 	response.Success(c.Ctx, gin.H{
-		"id":         admin.ID,
-		"username":   admin.Username,
-		"phone":      admin.Phone,
-		"email":      admin.Email,
-		"created_at": admin.CreatedAt,
+		"id":          admin.ID,
+		"username":    admin.Username,
+		"phone":       admin.Phone,
+		"email":       admin.Email,
+		"property_id": admin.PropertyID,
+		"created_at":  admin.CreatedAt,
 	})
 }
 
@@ -259,11 +276,24 @@ func (c *AdminController) UpdateAdmin() {
 	if req.Password != "" {
 		updates["password"] = req.Password
 	}
+	if req.PropertyID != nil && req.ClearProperty {
+		response.FailWithMessage(c.Ctx, code.ErrBind, "property_id 和 clear_property 不能同时传入", nil)
+		return
+	}
+	if req.PropertyID != nil {
+		updates["property_id"] = *req.PropertyID
+	}
+	if req.ClearProperty {
+		updates["property_id"] = nil
+	}
 
 	// 使用 AdminService 更新管理员
 	adminService := c.Container.GetService("admin").(services.InterfaceAdminService)
 	admin, err := adminService.UpdateAdmin(uint(id), updates)
 	if err != nil {
+		if failPropertyScope(c.Ctx, err) || failPasswordPolicy(c.Ctx, err) {
+			return
+		}
 		if err.Error() == "管理员不存在" {
 			response.NotFound(c.Ctx, err.Error())
 			return
@@ -272,13 +302,21 @@ func (c *AdminController) UpdateAdmin() {
 		return
 	}
 
+	// 修改密码或所属物业后注销管理员已登录的会话，旧令牌不再可用
+	_, passwordChanged := updates["password"]
+	_, propertyChanged := updates["property_id"]
+	if passwordChanged || propertyChanged {
+		revokeAccountTokens(c.Container, "admin", uint(id))
+	}
+
 	// 返回更新后的管理员信息（不含密码）
 	response.Success(c.Ctx, gin.H{
-		"id":         admin.ID,
-		"username":   admin.Username,
-		"phone":      admin.Phone,
-		"email":      admin.Email,
-		"updated_at": admin.UpdatedAt,
+		"id":          admin.ID,
+		"username":    admin.Username,
+		"phone":       admin.Phone,
+		"email":       admin.Email,
+		"property_id": admin.PropertyID,
+		"updated_at":  admin.UpdatedAt,
 	})
 }
 
@@ -319,6 +357,7 @@ func (c *AdminController) DeleteAdmin() {
 		response.FailWithMessage(c.Ctx, code.ErrDatabase, "删除管理员失败: "+err.Error(), nil)
 		return
 	}
+	revokeAccountTokens(c.Container, "admin", uint(id))
 
 	response.Success(c.Ctx, nil)
 }
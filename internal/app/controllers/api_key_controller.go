@@ -0,0 +1,376 @@
+package controllers
+
+import (
+	"errors"
+	"ilock-http-service/internal/domain/models"
+	"ilock-http-service/internal/domain/services"
+	"ilock-http-service/internal/domain/services/container"
+	"ilock-http-service/internal/error/code"
+	"ilock-http-service/internal/error/response"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InterfaceAPIKeyController 定义API密钥控制器接口
+type InterfaceAPIKeyController interface {
+	GetAPIKeys()
+	GetAPIKey()
+	CreateAPIKey()
+	UpdateAPIKey()
+	RotateAPIKey()
+	RevokeAPIKey()
+	GetAPIKeyUsage()
+}
+
+// APIKeyController 处理API密钥管理相关的请求
+type APIKeyController struct {
+	Ctx       *gin.Context
+	Container *container.ServiceContainer
+}
+
+// NewAPIKeyController 创建一个新的API密钥控制器
+func NewAPIKeyController(ctx *gin.Context, container *container.ServiceContainer) *APIKeyController {
+	return &APIKeyController{
+		Ctx:       ctx,
+		Container: container,
+	}
+}
+
+// CreateAPIKeyRequest 表示创建API密钥的请求
+type CreateAPIKeyRequest struct {
+	Name        string     `json:"name" binding:"required" example:"BMS"`
+	Scopes      []string   `json:"scopes" binding:"required,min=1" example:"device:read,emergency:read"` // 权限编码
+	AllowedIPs  []string   `json:"allowed_ips" example:"203.0.113.10,10.0.0.0/24"`                       // IP或CIDR，为空表示不限制
+	PropertyID  *uint      `json:"property_id" example:"1"`                                              // 限定访问的物业，为空表示不限物业
+	ExpiresAt   *time.Time `json:"expires_at" example:"2026-01-01T00:00:00Z"`                            // 为空表示长期有效
+	Description string     `json:"description" example:"楼宇管理系统"`
+}
+
+// UpdateAPIKeyRequest 表示更新API密钥的请求，未提供的字段保持不变
+type UpdateAPIKeyRequest struct {
+	Name        *string    `json:"name" example:"BMS"`
+	Scopes      []string   `json:"scopes" example:"device:read"`
+	AllowedIPs  []string   `json:"allowed_ips" example:"203.0.113.10"` // 传空数组表示取消限制
+	PropertyID  *uint      `json:"property_id" example:"1"`
+	ExpiresAt   *time.Time `json:"expires_at" example:"2026-01-01T00:00:00Z"`
+	Description *string    `json:"description" example:"楼宇管理系统"`
+}
+
+// HandleAPIKeyFunc 返回一个处理API密钥请求的Gin处理函数
+func HandleAPIKeyFunc(container *container.ServiceContainer, method string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		controller := NewAPIKeyController(ctx, container)
+
+		// API密钥可以访问所有物业的数据，只能由平台管理员管理
+		if getCurrentPropertyID(ctx) != nil {
+			response.FailWithMessage(ctx, code.ErrPropertyScope, "物业管理员无权管理API密钥", nil)
+			return
+		}
+
+		switch method {
+		case "getAPIKeys":
+			controller.GetAPIKeys()
+		case "getAPIKey":
+			controller.GetAPIKey()
+		case "createAPIKey":
+			controller.CreateAPIKey()
+		case "updateAPIKey":
+			controller.UpdateAPIKey()
+		case "rotateAPIKey":
+			controller.RotateAPIKey()
+		case "revokeAPIKey":
+			controller.RevokeAPIKey()
+		case "getAPIKeyUsage":
+			controller.GetAPIKeyUsage()
+		default:
+			response.FailWithMessage(ctx, code.ErrBind, "无效的方法", nil)
+		}
+	}
+}
+
+// 1. GetAPIKeys 获取API密钥列表
+// @Summary 获取API密钥列表
+// @Description 分页获取API密钥，默认不包括已吊销的密钥，完整密钥不会返回
+// @Tags APIKey
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param include_revoked query bool false "是否包括已吊销的密钥"
+// @Param page query int false "页码，默认为1"
+// @Param page_size query int false "每页条数，默认为10"
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} ErrorResponse
+// @Router /api-keys [get]
+func (c *APIKeyController) GetAPIKeys() {
+	page, _ := strconv.Atoi(c.Ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.Ctx.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+	includeRevoked := c.Ctx.Query("include_revoked") == "true"
+
+	keys, total, err := c.apiKeyService().GetKeys(includeRevoked, page, pageSize)
+	if err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrDatabase, "获取API密钥失败: "+err.Error(), nil)
+		return
+	}
+
+	response.Success(c.Ctx, gin.H{
+		"total":       total,
+		"page":        page,
+		"page_size":   pageSize,
+		"total_pages": (total + int64(pageSize) - 1) / int64(pageSize),
+		"data":        keys,
+	})
+}
+
+// 2. GetAPIKey 获取单个API密钥
+// @Summary 获取API密钥详情
+// @Description 根据ID获取API密钥，包括权限范围、IP白名单和用量
+// @Tags APIKey
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "密钥ID"
+// @Success 200 {object} models.APIKey
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api-keys/{id} [get]
+func (c *APIKeyController) GetAPIKey() {
+	id, ok := c.parseID()
+	if !ok {
+		return
+	}
+
+	key, err := c.apiKeyService().GetKeyByID(id)
+	if err != nil {
+		c.failAPIKey(err, "获取API密钥失败")
+		return
+	}
+
+	response.Success(c.Ctx, key)
+}
+
+// 3. CreateAPIKey 创建API密钥
+// @Summary 创建API密钥
+// @Description 创建API密钥，响应中的key只返回这一次，调用接口时放在X-API-Key请求头中
+// @Tags APIKey
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param api_key body CreateAPIKeyRequest true "密钥信息"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api-keys [post]
+func (c *APIKeyController) CreateAPIKey() {
+	var req CreateAPIKeyRequest
+	if err := c.Ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrBind, "无效的请求参数: "+err.Error(), nil)
+		return
+	}
+
+	key := &models.APIKey{
+		Name:        req.Name,
+		Scopes:      strings.Join(req.Scopes, ","),
+		AllowedIPs:  strings.Join(req.AllowedIPs, ","),
+		PropertyID:  req.PropertyID,
+		ExpiresAt:   req.ExpiresAt,
+		Description: req.Description,
+		CreatedBy:   getCurrentUserID(c.Ctx),
+	}
+
+	rawKey, err := c.apiKeyService().CreateKey(key)
+	if err != nil {
+		c.failAPIKey(err, "创建API密钥失败")
+		return
+	}
+
+	c.Ctx.Status(http.StatusCreated)
+	response.Success(c.Ctx, gin.H{
+		"api_key": key,
+		"key":     rawKey,
+	})
+}
+
+// 4. UpdateAPIKey 更新API密钥
+// @Summary 更新API密钥
+// @Description 更新密钥的名称、权限范围、IP白名单、物业、过期时间或描述，立即生效；已吊销的密钥不能修改
+// @Tags APIKey
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "密钥ID"
+// @Param api_key body UpdateAPIKeyRequest true "密钥信息"
+// @Success 200 {object} models.APIKey
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api-keys/{id} [put]
+func (c *APIKeyController) UpdateAPIKey() {
+	id, ok := c.parseID()
+	if !ok {
+		return
+	}
+
+	var req UpdateAPIKeyRequest
+	if err := c.Ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrBind, "无效的请求参数: "+err.Error(), nil)
+		return
+	}
+
+	updates := make(map[string]interface{})
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.Scopes != nil {
+		updates["scopes"] = strings.Join(req.Scopes, ",")
+	}
+	if req.AllowedIPs != nil {
+		updates["allowed_ips"] = strings.Join(req.AllowedIPs, ",")
+	}
+	if req.PropertyID != nil {
+		updates["property_id"] = req.PropertyID
+	}
+	if req.ExpiresAt != nil {
+		updates["expires_at"] = req.ExpiresAt
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if len(updates) == 0 {
+		response.ParamError(c.Ctx, "没有需要更新的字段")
+		return
+	}
+
+	key, err := c.apiKeyService().UpdateKey(id, updates)
+	if err != nil {
+		c.failAPIKey(err, "更新API密钥失败")
+		return
+	}
+
+	response.Success(c.Ctx, key)
+}
+
+// 5. RotateAPIKey 轮换API密钥
+// @Summary 轮换API密钥
+// @Description 生成新的密钥并立即生效，原密钥失效；权限范围和用量统计保留，新密钥只返回这一次
+// @Tags APIKey
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "密钥ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api-keys/{id}/rotate [post]
+func (c *APIKeyController) RotateAPIKey() {
+	id, ok := c.parseID()
+	if !ok {
+		return
+	}
+
+	rawKey, key, err := c.apiKeyService().RotateKey(id)
+	if err != nil {
+		c.failAPIKey(err, "轮换API密钥失败")
+		return
+	}
+
+	response.Success(c.Ctx, gin.H{
+		"api_key": key,
+		"key":     rawKey,
+	})
+}
+
+// 6. RevokeAPIKey 吊销API密钥
+// @Summary 吊销API密钥
+// @Description 吊销API密钥，立即失效且不能恢复，记录保留用于查看用量
+// @Tags APIKey
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "密钥ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api-keys/{id} [delete]
+func (c *APIKeyController) RevokeAPIKey() {
+	id, ok := c.parseID()
+	if !ok {
+		return
+	}
+
+	if err := c.apiKeyService().RevokeKey(id); err != nil {
+		c.failAPIKey(err, "吊销API密钥失败")
+		return
+	}
+
+	response.Success(c.Ctx, gin.H{"message": "API密钥已吊销"})
+}
+
+// 7. GetAPIKeyUsage 获取API密钥用量
+// @Summary 获取API密钥用量
+// @Description 获取密钥最近若干天每天的请求次数，没有请求的日期不返回；累计次数和最后使用时间见密钥详情
+// @Tags APIKey
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "密钥ID"
+// @Param days query int false "天数，默认为30，最多90"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api-keys/{id}/usage [get]
+func (c *APIKeyController) GetAPIKeyUsage() {
+	id, ok := c.parseID()
+	if !ok {
+		return
+	}
+	days, _ := strconv.Atoi(c.Ctx.DefaultQuery("days", "30"))
+
+	usage, err := c.apiKeyService().GetUsage(id, days)
+	if err != nil {
+		c.failAPIKey(err, "获取API密钥用量失败")
+		return
+	}
+
+	response.Success(c.Ctx, gin.H{"data": usage})
+}
+
+// parseID 解析路径中的密钥ID
+func (c *APIKeyController) parseID() (uint, bool) {
+	id, err := strconv.ParseUint(c.Ctx.Param("id"), 10, 32)
+	if err != nil {
+		response.ParamError(c.Ctx, "无效的密钥ID")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// failAPIKey 将API密钥服务错误映射为响应错误码
+func (c *APIKeyController) failAPIKey(err error, message string) {
+	if failPropertyScope(c.Ctx, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, services.ErrAPIKeyNotFound):
+		response.FailWithMessage(c.Ctx, code.ErrAPIKeyNotFound, err.Error(), nil)
+	case errors.Is(err, services.ErrAPIKeyRevoked):
+		response.FailWithMessage(c.Ctx, code.ErrAPIKeyRevoked, err.Error(), nil)
+	case errors.Is(err, services.ErrInvalidAPIKey):
+		response.FailWithMessage(c.Ctx, code.ErrValidation, err.Error(), nil)
+	default:
+		response.FailWithMessage(c.Ctx, code.ErrDatabase, message+": "+err.Error(), nil)
+	}
+}
+
+// apiKeyService 获取API密钥服务
+func (c *APIKeyController) apiKeyService() services.InterfaceAPIKeyService {
+	return c.Container.GetService("api_key").(services.InterfaceAPIKeyService)
+}
@@ -0,0 +1,207 @@
+package controllers
+
+import (
+	"fmt"
+	"ilock-http-service/internal/domain/services"
+	"ilock-http-service/internal/domain/services/container"
+	"ilock-http-service/internal/error/code"
+	"ilock-http-service/internal/error/response"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InterfaceAuditController 定义审计日志控制器接口
+type InterfaceAuditController interface {
+	GetAuditLogs()
+	ExportAuditLogs()
+}
+
+// AuditController 处理审计日志查询和导出请求
+type AuditController struct {
+	Ctx       *gin.Context
+	Container *container.ServiceContainer
+}
+
+// NewAuditController 创建一个新的审计日志控制器
+func NewAuditController(ctx *gin.Context, container *container.ServiceContainer) *AuditController {
+	return &AuditController{
+		Ctx:       ctx,
+		Container: container,
+	}
+}
+
+// HandleAuditFunc 返回一个处理审计日志请求的Gin处理函数
+func HandleAuditFunc(container *container.ServiceContainer, method string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		controller := NewAuditController(ctx, container)
+
+		switch method {
+		case "getAuditLogs":
+			controller.GetAuditLogs()
+		case "exportAuditLogs":
+			controller.ExportAuditLogs()
+		default:
+			response.FailWithMessage(ctx, code.ErrBind, "无效的方法", nil)
+		}
+	}
+}
+
+// 1. GetAuditLogs 查询审计日志
+// @Summary 查询审计日志
+// @Description 按操作人、物业、操作、目标、结果和时间范围分页查询审计日志，最新的在前。物业账号只能查询本物业账号的操作
+// @Tags Audit
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param actor_type query string false "操作人类型：admin, staff, user, api_key"
+// @Param actor_id query int false "操作人ID"
+// @Param property_id query int false "操作人所属物业ID"
+// @Param action query string false "操作，模糊匹配，如 /api/devices"
+// @Param target query string false "目标，如 device 或 device:3"
+// @Param failed query bool false "true 只返回失败的请求，false 只返回成功的请求"
+// @Param start_time query string false "开始时间 (RFC3339)"
+// @Param end_time query string false "结束时间 (RFC3339)"
+// @Param page query int false "页码，默认为1"
+// @Param page_size query int false "每页条数，默认为10"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /audit [get]
+func (c *AuditController) GetAuditLogs() {
+	query, ok := c.parseQuery()
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(c.Ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.Ctx.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+	query.Page = page
+	query.PageSize = pageSize
+
+	logs, total, err := c.auditService().GetLogs(query)
+	if err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrDatabase, "获取审计日志失败: "+err.Error(), nil)
+		return
+	}
+
+	response.Success(c.Ctx, gin.H{
+		"total":       total,
+		"page":        page,
+		"page_size":   pageSize,
+		"total_pages": (total + int64(pageSize) - 1) / int64(pageSize),
+		"data":        logs,
+	})
+}
+
+// 2. ExportAuditLogs 导出审计日志
+// @Summary 导出审计日志
+// @Description 按与查询接口相同的条件导出审计日志为CSV文件，最新的在前，单次最多导出10000行
+// @Tags Audit
+// @Produce text/csv
+// @Security BearerAuth
+// @Param actor_type query string false "操作人类型：admin, staff, user, api_key"
+// @Param actor_id query int false "操作人ID"
+// @Param property_id query int false "操作人所属物业ID"
+// @Param action query string false "操作，模糊匹配"
+// @Param target query string false "目标，如 device 或 device:3"
+// @Param failed query bool false "true 只返回失败的请求，false 只返回成功的请求"
+// @Param start_time query string false "开始时间 (RFC3339)"
+// @Param end_time query string false "结束时间 (RFC3339)"
+// @Success 200 {file} file
+// @Failure 400 {object} ErrorResponse
+// @Router /audit/export [get]
+func (c *AuditController) ExportAuditLogs() {
+	query, ok := c.parseQuery()
+	if !ok {
+		return
+	}
+
+	filename := fmt.Sprintf("audit_logs_%s.csv", time.Now().Format("20060102_150405"))
+	c.Ctx.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Ctx.Status(http.StatusOK)
+
+	// 写入UTF-8 BOM，使Excel正确识别中文
+	c.Ctx.Writer.WriteString("\xEF\xBB\xBF")
+	if _, err := c.auditService().ExportLogs(query, c.Ctx.Writer); err != nil {
+		// 响应头已经发出，只能记录错误
+		log.Printf("[Audit] 导出审计日志失败: %v", err)
+	}
+}
+
+// parseQuery 解析查询条件，参数不合法时写入错误响应并返回false
+// 物业账号只能查询本物业账号的操作
+func (c *AuditController) parseQuery() (services.AuditQuery, bool) {
+	query := services.AuditQuery{
+		ActorType: c.Ctx.Query("actor_type"),
+		Action:    c.Ctx.Query("action"),
+		Target:    c.Ctx.Query("target"),
+	}
+
+	if value := c.Ctx.Query("actor_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			response.FailWithMessage(c.Ctx, code.ErrValidation, "无效的操作人ID", nil)
+			return query, false
+		}
+		actorID := uint(id)
+		query.ActorID = &actorID
+	}
+	if value := c.Ctx.Query("property_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			response.FailWithMessage(c.Ctx, code.ErrValidation, "无效的物业ID", nil)
+			return query, false
+		}
+		propertyID := uint(id)
+		query.PropertyID = &propertyID
+	}
+	if value := c.Ctx.Query("failed"); value != "" {
+		failed, err := strconv.ParseBool(value)
+		if err != nil {
+			response.FailWithMessage(c.Ctx, code.ErrValidation, "failed 只能为 true 或 false", nil)
+			return query, false
+		}
+		query.Failed = &failed
+	}
+	if value := c.Ctx.Query("start_time"); value != "" {
+		startTime, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			response.FailWithMessage(c.Ctx, code.ErrValidation, "无效的开始时间格式", nil)
+			return query, false
+		}
+		query.StartTime = &startTime
+	}
+	if value := c.Ctx.Query("end_time"); value != "" {
+		endTime, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			response.FailWithMessage(c.Ctx, code.ErrValidation, "无效的结束时间格式", nil)
+			return query, false
+		}
+		query.EndTime = &endTime
+	}
+
+	if propertyID := getCurrentPropertyID(c.Ctx); propertyID != nil {
+		if query.PropertyID != nil && *query.PropertyID != *propertyID {
+			response.FailWithMessage(c.Ctx, code.ErrPropertyScope, "只能查询本物业的审计日志", nil)
+			return query, false
+		}
+		query.PropertyID = propertyID
+	}
+	return query, true
+}
+
+// auditService 获取审计日志服务
+func (c *AuditController) auditService() services.InterfaceAuditService {
+	return c.Container.GetService("audit").(services.InterfaceAuditService)
+}
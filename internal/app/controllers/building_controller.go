@@ -2,7 +2,6 @@ package controllers
 
 import (
 	"ilock-http-service/internal/domain/models"
-	"ilock-http-service/internal/domain/services"
 	"ilock-http-service/internal/domain/services/container"
 	"ilock-http-service/internal/error/code"
 	"ilock-http-service/internal/error/response"
@@ -43,6 +42,7 @@ type BuildingRequest struct {
 	BuildingCode string `json:"building_code" binding:"required" example:"B001"`
 	Address      string `json:"address" example:"小区东南角"`
 	Status       string `json:"status" example:"active"` // active, inactive
+	PropertyID   *uint  `json:"property_id" example:"1"` // 所属物业ID，可选
 }
 
 // HandleBuildingFunc 返回一个处理楼号请求的Gin处理函数
@@ -95,7 +95,7 @@ func (c *BuildingController) GetBuildings() {
 	}
 
 	// 获取楼号服务
-	buildingService := c.Container.GetService("building").(services.InterfaceBuildingService)
+	buildingService := scopedBuildingService(c.Ctx, c.Container)
 	buildings, total, err := buildingService.GetAllBuildings(page, pageSize)
 	if err != nil {
 		response.FailWithMessage(c.Ctx, code.ErrDatabase, "获取楼号列表失败: "+err.Error(), nil)
@@ -133,7 +133,7 @@ func (c *BuildingController) GetBuilding() {
 	}
 
 	// 获取楼号服务
-	buildingService := c.Container.GetService("building").(services.InterfaceBuildingService)
+	buildingService := scopedBuildingService(c.Ctx, c.Container)
 	building, err := buildingService.GetBuildingByID(uint(buildingID))
 	if err != nil {
 		response.NotFound(c.Ctx, "楼号不存在: "+err.Error())
@@ -167,6 +167,7 @@ func (c *BuildingController) CreateBuilding() {
 		BuildingName: req.BuildingName,
 		BuildingCode: req.BuildingCode,
 		Address:      req.Address,
+		PropertyID:   req.PropertyID,
 	}
 
 	// 如果提供了状态，则设置状态
@@ -177,8 +178,11 @@ func (c *BuildingController) CreateBuilding() {
 	}
 
 	// 获取楼号服务
-	buildingService := c.Container.GetService("building").(services.InterfaceBuildingService)
+	buildingService := scopedBuildingService(c.Ctx, c.Container)
 	if err := buildingService.CreateBuilding(building); err != nil {
+		if failPropertyScope(c.Ctx, err) {
+			return
+		}
 		response.FailWithMessage(c.Ctx, code.ErrDatabase, "创建楼号失败: "+err.Error(), nil)
 		return
 	}
@@ -229,11 +233,17 @@ func (c *BuildingController) UpdateBuilding() {
 	if req.Status != "" {
 		updates["status"] = req.Status
 	}
+	if req.PropertyID != nil {
+		updates["property_id"] = *req.PropertyID
+	}
 
 	// 获取楼号服务
-	buildingService := c.Container.GetService("building").(services.InterfaceBuildingService)
+	buildingService := scopedBuildingService(c.Ctx, c.Container)
 	building, err := buildingService.UpdateBuilding(uint(buildingID), updates)
 	if err != nil {
+		if failPropertyScope(c.Ctx, err) {
+			return
+		}
 		response.FailWithMessage(c.Ctx, code.ErrDatabase, "更新楼号失败: "+err.Error(), nil)
 		return
 	}
@@ -263,7 +273,7 @@ func (c *BuildingController) DeleteBuilding() {
 	}
 
 	// 获取楼号服务
-	buildingService := c.Container.GetService("building").(services.InterfaceBuildingService)
+	buildingService := scopedBuildingService(c.Ctx, c.Container)
 	if err := buildingService.DeleteBuilding(uint(buildingID)); err != nil {
 		response.FailWithMessage(c.Ctx, code.ErrDatabase, "删除楼号失败: "+err.Error(), nil)
 		return
@@ -294,7 +304,7 @@ func (c *BuildingController) GetBuildingDevices() {
 	}
 
 	// 获取楼号服务
-	buildingService := c.Container.GetService("building").(services.InterfaceBuildingService)
+	buildingService := scopedBuildingService(c.Ctx, c.Container)
 	devices, err := buildingService.GetBuildingDevices(uint(buildingID))
 	if err != nil {
 		response.FailWithMessage(c.Ctx, code.ErrDatabase, "获取楼号关联设备失败: "+err.Error(), nil)
@@ -326,7 +336,7 @@ func (c *BuildingController) GetBuildingHouseholds() {
 	}
 
 	// 获取楼号服务
-	buildingService := c.Container.GetService("building").(services.InterfaceBuildingService)
+	buildingService := scopedBuildingService(c.Ctx, c.Container)
 	households, err := buildingService.GetBuildingHouseholds(uint(buildingID))
 	if err != nil {
 		response.FailWithMessage(c.Ctx, code.ErrDatabase, "获取楼号下户号失败: "+err.Error(), nil)
@@ -92,7 +92,7 @@ func (c *CallRecordController) GetCallRecords() {
 		pageSize = 10
 	}
 
-	callRecordService := c.Container.GetService("call_record").(services.InterfaceCallRecordService)
+	callRecordService := c.callRecordService()
 
 	calls, total, err := callRecordService.GetAllCallRecords(page, pageSize)
 	if err != nil {
@@ -130,7 +130,7 @@ func (c *CallRecordController) GetCallRecord() {
 		return
 	}
 
-	callRecordService := c.Container.GetService("call_record").(services.InterfaceCallRecordService)
+	callRecordService := c.callRecordService()
 
 	record, err := callRecordService.GetCallRecordByID(uint(recordID))
 	if err != nil {
@@ -152,7 +152,7 @@ func (c *CallRecordController) GetCallRecord() {
 // @Failure      500  {object}  ErrorResponse
 // @Router       /call_records/statistics [get]
 func (c *CallRecordController) GetCallStatistics() {
-	callRecordService := c.Container.GetService("call_record").(services.InterfaceCallRecordService)
+	callRecordService := c.callRecordService()
 
 	statistics, err := callRecordService.GetCallStatistics()
 	if err != nil {
@@ -197,7 +197,7 @@ func (c *CallRecordController) GetDeviceCallRecords() {
 		pageSize = 10
 	}
 
-	callRecordService := c.Container.GetService("call_record").(services.InterfaceCallRecordService)
+	callRecordService := c.callRecordService()
 
 	calls, total, err := callRecordService.GetCallRecordsByDeviceID(uint(id), page, pageSize)
 	if err != nil {
@@ -248,7 +248,7 @@ func (c *CallRecordController) GetResidentCallRecords() {
 		pageSize = 10
 	}
 
-	callRecordService := c.Container.GetService("call_record").(services.InterfaceCallRecordService)
+	callRecordService := c.callRecordService()
 
 	calls, total, err := callRecordService.GetCallRecordsByResidentID(uint(id), page, pageSize)
 	if err != nil {
@@ -293,7 +293,7 @@ func (c *CallRecordController) SubmitCallFeedback() {
 		return
 	}
 
-	callRecordService := c.Container.GetService("call_record").(services.InterfaceCallRecordService)
+	callRecordService := c.callRecordService()
 
 	feedback := &services.CallFeedback{
 		CallID:  uint(id),
@@ -330,7 +330,7 @@ func (c *CallRecordController) GetCallSession() {
 		return
 	}
 
-	callRecordService := c.Container.GetService("call_record").(services.InterfaceCallRecordService)
+	callRecordService := c.callRecordService()
 
 	record, err := callRecordService.GetCallRecordByCallID(callID)
 	if err != nil {
@@ -340,3 +340,8 @@ func (c *CallRecordController) GetCallSession() {
 
 	response.Success(c.Ctx, record)
 }
+
+// callRecordService 获取限定在当前用户所属物业内的通话记录服务
+func (c *CallRecordController) callRecordService() services.InterfaceCallRecordService {
+	return c.Container.GetService("call_record").(services.InterfaceCallRecordService).WithPropertyScope(getCurrentPropertyID(c.Ctx))
+}
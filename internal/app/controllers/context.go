@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"errors"
+	"ilock-http-service/internal/domain/services"
+	"ilock-http-service/internal/domain/services/container"
+	"ilock-http-service/internal/error/code"
+	"ilock-http-service/internal/error/response"
+	"log"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getCurrentUserID 获取认证中间件写入上下文的用户ID，未登录时返回0
+// JWT的MapClaims会将数字解析为float64，这里统一转换为uint
+func getCurrentUserID(ctx *gin.Context) uint {
+	value, exists := ctx.Get("userID")
+	if !exists {
+		return 0
+	}
+
+	switch id := value.(type) {
+	case uint:
+		return id
+	case float64:
+		return uint(id)
+	case int:
+		return uint(id)
+	default:
+		return 0
+	}
+}
+
+// getCurrentRole 获取认证中间件写入上下文的角色，未登录时返回"system"
+func getCurrentRole(ctx *gin.Context) string {
+	if role, ok := ctx.Get("role"); ok {
+		if roleStr, ok := role.(string); ok && roleStr != "" {
+			return roleStr
+		}
+	}
+	return "system"
+}
+
+// getCurrentDeviceID 获取设备认证中间件写入上下文的设备ID，非设备调用时返回0
+func getCurrentDeviceID(ctx *gin.Context) uint {
+	if value, exists := ctx.Get("deviceID"); exists {
+		if id, ok := value.(uint); ok {
+			return id
+		}
+	}
+	return 0
+}
+
+// getCurrentPropertyID 获取令牌中的物业ID，平台管理员和设备令牌返回nil
+// 物业员工和居民未关联物业时返回指向0的指针，使其看不到任何物业的数据
+func getCurrentPropertyID(ctx *gin.Context) *uint {
+	value, exists := ctx.Get("propertyID")
+	if !exists {
+		if role := getCurrentRole(ctx); role == "staff" || role == "user" {
+			var none uint
+			return &none
+		}
+		return nil
+	}
+
+	var id uint
+	switch v := value.(type) {
+	case uint:
+		id = v
+	case float64:
+		id = uint(v)
+	case int:
+		id = uint(v)
+	default:
+		return nil
+	}
+	return &id
+}
+
+// scopedBuildingService 获取限定在当前用户所属物业内的楼号服务
+func scopedBuildingService(ctx *gin.Context, c *container.ServiceContainer) services.InterfaceBuildingService {
+	return c.GetService("building").(services.InterfaceBuildingService).WithPropertyScope(getCurrentPropertyID(ctx))
+}
+
+// scopedHouseholdService 获取限定在当前用户所属物业内的户号服务
+func scopedHouseholdService(ctx *gin.Context, c *container.ServiceContainer) services.InterfaceHouseholdService {
+	return c.GetService("household").(services.InterfaceHouseholdService).WithPropertyScope(getCurrentPropertyID(ctx))
+}
+
+// scopedDeviceService 获取限定在当前用户所属物业内的设备服务
+func scopedDeviceService(ctx *gin.Context, c *container.ServiceContainer) services.InterfaceDeviceService {
+	return c.GetService("device").(services.InterfaceDeviceService).WithPropertyScope(getCurrentPropertyID(ctx))
+}
+
+// scopedResidentService 获取限定在当前用户所属物业内的居民服务
+func scopedResidentService(ctx *gin.Context, c *container.ServiceContainer) services.InterfaceResidentService {
+	return c.GetService("resident").(services.InterfaceResidentService).WithPropertyScope(getCurrentPropertyID(ctx))
+}
+
+// scopedStaffService 获取限定在当前用户所属物业内的物业员工服务
+func scopedStaffService(ctx *gin.Context, c *container.ServiceContainer) services.InterfaceStaffService {
+	return c.GetService("staff").(services.InterfaceStaffService).WithPropertyScope(getCurrentPropertyID(ctx))
+}
+
+// failPropertyScope 资源不属于当前物业或指定的物业不存在时写入错误响应并返回true
+func failPropertyScope(ctx *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, services.ErrOutOfPropertyScope):
+		response.FailWithMessage(ctx, code.ErrPropertyScope, err.Error(), nil)
+	case errors.Is(err, services.ErrPropertyNotFound):
+		response.FailWithMessage(ctx, code.ErrPropertyNotFound, err.Error(), nil)
+	default:
+		return false
+	}
+	return true
+}
+
+// failPasswordPolicy 新密码不符合密码策略或与最近使用过的密码相同时写入错误响应
+func failPasswordPolicy(ctx *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, services.ErrPasswordTooWeak):
+		response.FailWithMessage(ctx, code.ErrPasswordPolicy, err.Error(), nil)
+	case errors.Is(err, services.ErrPasswordReused):
+		response.FailWithMessage(ctx, code.ErrPasswordReused, err.Error(), nil)
+	default:
+		return false
+	}
+	return true
+}
+
+// revokeAccountTokens 账号被删除后注销其所有登录会话，已签发的令牌立即失效；失败只记录日志，不影响删除结果
+func revokeAccountTokens(c *container.ServiceContainer, role string, userID uint) {
+	if err := c.GetService("jwt").(services.InterfaceJWTService).RevokeAllTokens(role, userID); err != nil {
+		log.Printf("注销账号 %s:%d 的登录会话失败: %v", role, userID, err)
+	}
+}
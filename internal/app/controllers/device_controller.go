@@ -3,6 +3,7 @@ package controllers
 import (
 	"ilock-http-service/internal/error/code"
 	"ilock-http-service/internal/error/response"
+	"errors"
 	"ilock-http-service/internal/domain/models"
 	"ilock-http-service/internal/domain/services"
 	"ilock-http-service/internal/domain/services/container"
@@ -27,6 +28,8 @@ type InterfaceDeviceController interface {
 	AssociateDeviceWithHousehold()
 	GetDeviceHouseholds()
 	RemoveDeviceHouseholdAssociation()
+	AuthenticateDevice()
+	RotateDeviceSecret()
 }
 
 // DeviceController 处理设备相关的请求
@@ -63,6 +66,12 @@ type DeviceRequestInput struct {
 	StaffIDs     []uint `json:"staff_ids" example:"1,2,3"`   // 关联的物业员工ID列表(可选)
 }
 
+// DeviceAuthRequest 设备认证请求
+type DeviceAuthRequest struct {
+	SerialNumber string `json:"serial_number" binding:"required" example:"SN12345678"`
+	Secret       string `json:"secret" binding:"required" example:"3f9a..."` // 创建设备或轮换密钥时返回的设备密钥
+}
+
 // DeviceBuildingRequest 设备关联楼号请求
 type DeviceBuildingRequest struct {
 	BuildingID uint `json:"building_id" binding:"required" example:"1"`
@@ -114,6 +123,10 @@ func HandleDeviceFunc(container *container.ServiceContainer, method string) gin.
 			controller.GetDeviceHouseholds()
 		case "removeDeviceHouseholdAssociation":
 			controller.RemoveDeviceHouseholdAssociation()
+		case "authenticateDevice":
+			controller.AuthenticateDevice()
+		case "rotateDeviceSecret":
+			controller.RotateDeviceSecret()
 		default:
 			response.FailWithMessage(ctx, code.ErrBind, "无效的方法", nil)
 		}
@@ -144,7 +157,7 @@ func (c *DeviceController) GetDevices() {
 		}
 	}
 
-	deviceService := c.Container.GetService("device").(services.InterfaceDeviceService)
+	deviceService := scopedDeviceService(c.Ctx, c.Container)
 
 	var devices []models.Device
 	var err error
@@ -185,7 +198,7 @@ func (c *DeviceController) GetDevice() {
 		return
 	}
 
-	deviceService := c.Container.GetService("device").(services.InterfaceDeviceService)
+	deviceService := scopedDeviceService(c.Ctx, c.Container)
 
 	device, err := deviceService.GetDeviceByID(uint(deviceID))
 	if err != nil {
@@ -206,7 +219,7 @@ func (c *DeviceController) GetDevice() {
 
 // 3. CreateDevice 创建新设备
 // @Summary 创建新设备
-// @Description 创建一个新的门禁设备，支持设备类型和关联
+// @Description 创建一个新的门禁设备，支持设备类型和关联；响应中的secret是设备密钥，只返回这一次
 // @Tags device
 // @Accept json
 // @Produce json
@@ -254,11 +267,11 @@ func (c *DeviceController) CreateDevice() {
 		device.Status = models.DeviceStatusOffline
 	}
 
-	deviceService := c.Container.GetService("device").(services.InterfaceDeviceService)
+	deviceService := scopedDeviceService(c.Ctx, c.Container)
 
 	// 如果提供了楼号ID，验证楼号是否存在
 	if req.BuildingID > 0 {
-		buildingService := c.Container.GetService("building").(services.InterfaceBuildingService)
+		buildingService := scopedBuildingService(c.Ctx, c.Container)
 		_, err := buildingService.GetBuildingByID(req.BuildingID)
 		if err != nil {
 			c.Ctx.JSON(http.StatusBadRequest, gin.H{
@@ -272,6 +285,9 @@ func (c *DeviceController) CreateDevice() {
 
 	// 创建设备 - 这里不设置household_id
 	if err := deviceService.CreateDevice(device); err != nil {
+		if failPropertyScope(c.Ctx, err) {
+			return
+		}
 		c.Ctx.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
 			"message": "创建设备失败: " + err.Error(),
@@ -282,7 +298,7 @@ func (c *DeviceController) CreateDevice() {
 
 	// 如果提供了户号ID列表，关联户号
 	if len(req.HouseholdIDs) > 0 {
-		householdService := c.Container.GetService("household").(services.InterfaceHouseholdService)
+		householdService := scopedHouseholdService(c.Ctx, c.Container)
 
 		// 关联第一个户号
 		if len(req.HouseholdIDs) > 0 {
@@ -372,7 +388,7 @@ func (c *DeviceController) UpdateDevice() {
 	}
 	if req.BuildingID > 0 {
 		// 验证楼号是否存在
-		buildingService := c.Container.GetService("building").(services.InterfaceBuildingService)
+		buildingService := scopedBuildingService(c.Ctx, c.Container)
 		_, err := buildingService.GetBuildingByID(req.BuildingID)
 		if err != nil {
 			c.Ctx.JSON(http.StatusBadRequest, gin.H{
@@ -397,11 +413,14 @@ func (c *DeviceController) UpdateDevice() {
 		}
 	}
 
-	deviceService := c.Container.GetService("device").(services.InterfaceDeviceService)
+	deviceService := scopedDeviceService(c.Ctx, c.Container)
 
 	// 更新设备基本信息
 	device, err := deviceService.UpdateDevice(uint(deviceID), updates)
 	if err != nil {
+		if failPropertyScope(c.Ctx, err) {
+			return
+		}
 		c.Ctx.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
 			"message": "更新设备失败: " + err.Error(),
@@ -413,7 +432,7 @@ func (c *DeviceController) UpdateDevice() {
 	// 如果提供了户号ID列表，更新关联
 	if len(req.HouseholdIDs) > 0 {
 		// 先清除现有关联
-		householdService := c.Container.GetService("household").(services.InterfaceHouseholdService)
+		householdService := scopedHouseholdService(c.Ctx, c.Container)
 
 		// 获取当前关联的户号
 		households, err := deviceService.GetDeviceHouseholds(uint(deviceID))
@@ -481,7 +500,7 @@ func (c *DeviceController) DeleteDevice() {
 		return
 	}
 
-	deviceService := c.Container.GetService("device").(services.InterfaceDeviceService)
+	deviceService := scopedDeviceService(c.Ctx, c.Container)
 
 	if err := deviceService.DeleteDevice(uint(deviceID)); err != nil {
 		c.Ctx.JSON(http.StatusInternalServerError, gin.H{
@@ -491,6 +510,7 @@ func (c *DeviceController) DeleteDevice() {
 		})
 		return
 	}
+	revokeAccountTokens(c.Container, services.DeviceRole, uint(deviceID))
 
 	c.Ctx.JSON(http.StatusOK, gin.H{
 		"code":    0,
@@ -596,7 +616,7 @@ func (c *DeviceController) CheckDeviceHealth() {
 	}
 
 	// 获取设备服务
-	deviceService := c.Container.GetService("device").(services.InterfaceDeviceService)
+	deviceService := scopedDeviceService(c.Ctx, c.Container)
 
 	// 检查设备是否存在
 	_, err = deviceService.GetDeviceByID(uint(deviceID))
@@ -609,12 +629,8 @@ func (c *DeviceController) CheckDeviceHealth() {
 		return
 	}
 
-	// 更新设备状态为在线
-	updates := map[string]interface{}{
-		"status": models.DeviceStatusOnline,
-	}
-
-	_, err = deviceService.UpdateDevice(uint(deviceID), updates)
+	// 记录心跳并更新设备状态为在线
+	_, err = deviceService.RecordHeartbeat(uint(deviceID))
 	if err != nil {
 		c.Ctx.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
@@ -672,7 +688,7 @@ func (c *DeviceController) AssociateDeviceWithBuilding() {
 	}
 
 	// 验证楼号是否存在
-	buildingService := c.Container.GetService("building").(services.InterfaceBuildingService)
+	buildingService := scopedBuildingService(c.Ctx, c.Container)
 	_, err = buildingService.GetBuildingByID(req.BuildingID)
 	if err != nil {
 		c.Ctx.JSON(http.StatusBadRequest, gin.H{
@@ -684,13 +700,16 @@ func (c *DeviceController) AssociateDeviceWithBuilding() {
 	}
 
 	// 更新设备关联的楼号
-	deviceService := c.Container.GetService("device").(services.InterfaceDeviceService)
+	deviceService := scopedDeviceService(c.Ctx, c.Container)
 	updates := map[string]interface{}{
 		"building_id": req.BuildingID,
 	}
 
 	device, err := deviceService.UpdateDevice(uint(deviceID), updates)
 	if err != nil {
+		if failPropertyScope(c.Ctx, err) {
+			return
+		}
 		c.Ctx.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
 			"message": "关联设备与楼号失败: " + err.Error(),
@@ -743,7 +762,7 @@ func (c *DeviceController) AssociateDeviceWithHousehold() {
 	}
 
 	// 验证设备是否存在
-	deviceService := c.Container.GetService("device").(services.InterfaceDeviceService)
+	deviceService := scopedDeviceService(c.Ctx, c.Container)
 	_, err = deviceService.GetDeviceByID(uint(deviceID))
 	if err != nil {
 		c.Ctx.JSON(http.StatusNotFound, gin.H{
@@ -755,8 +774,11 @@ func (c *DeviceController) AssociateDeviceWithHousehold() {
 	}
 
 	// 关联设备到户号
-	householdService := c.Container.GetService("household").(services.InterfaceHouseholdService)
+	householdService := scopedHouseholdService(c.Ctx, c.Container)
 	if err := householdService.AssociateHouseholdWithDevice(req.HouseholdID, uint(deviceID)); err != nil {
+		if failPropertyScope(c.Ctx, err) {
+			return
+		}
 		c.Ctx.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
 			"message": "关联设备与户号失败: " + err.Error(),
@@ -798,7 +820,7 @@ func (c *DeviceController) GetDeviceHouseholds() {
 	}
 
 	// 验证设备是否存在
-	deviceService := c.Container.GetService("device").(services.InterfaceDeviceService)
+	deviceService := scopedDeviceService(c.Ctx, c.Container)
 	_, err = deviceService.GetDeviceByID(uint(deviceID))
 	if err != nil {
 		c.Ctx.JSON(http.StatusNotFound, gin.H{
@@ -854,7 +876,7 @@ func (c *DeviceController) RemoveDeviceHouseholdAssociation() {
 	}
 
 	// 验证设备是否存在
-	deviceService := c.Container.GetService("device").(services.InterfaceDeviceService)
+	deviceService := scopedDeviceService(c.Ctx, c.Container)
 	device, err := deviceService.GetDeviceByID(uint(deviceID))
 	if err != nil {
 		c.Ctx.JSON(http.StatusNotFound, gin.H{
@@ -876,7 +898,7 @@ func (c *DeviceController) RemoveDeviceHouseholdAssociation() {
 	}
 
 	// 解除设备与户号的关联
-	householdService := c.Container.GetService("household").(services.InterfaceHouseholdService)
+	householdService := scopedHouseholdService(c.Ctx, c.Container)
 	if err := householdService.RemoveHouseholdDeviceAssociation(device.HouseholdID, uint(deviceID)); err != nil {
 		c.Ctx.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
@@ -892,3 +914,80 @@ func (c *DeviceController) RemoveDeviceHouseholdAssociation() {
 		"data":    nil,
 	})
 }
+
+// 12. AuthenticateDevice 设备认证
+// @Summary 设备认证
+// @Description 设备用序列号和设备密钥换取设备令牌，令牌带有device_id，用于调用设备端接口；令牌过期后重新认证
+// @Tags device
+// @Accept json
+// @Produce json
+// @Param request body DeviceAuthRequest true "设备序列号和密钥"
+// @Success 200 {object} services.DeviceToken
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /device/auth [post]
+func (c *DeviceController) AuthenticateDevice() {
+	var req DeviceAuthRequest
+	if err := c.Ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrBind, "无效的请求参数: "+err.Error(), nil)
+		return
+	}
+
+	deviceService := c.Container.GetService("device").(services.InterfaceDeviceService)
+	device, err := deviceService.AuthenticateDevice(req.SerialNumber, req.Secret)
+	if err != nil {
+		if errors.Is(err, services.ErrDeviceCredentialsInvalid) {
+			response.Fail(c.Ctx, code.ErrDeviceCredentialsInvalid, nil)
+			return
+		}
+		response.FailWithMessage(c.Ctx, code.ErrDatabase, "设备认证失败: "+err.Error(), nil)
+		return
+	}
+
+	jwtService := c.Container.GetService("jwt").(services.InterfaceJWTService)
+	token, err := jwtService.IssueDeviceToken(device.ID)
+	if err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrUnknown, "签发设备令牌失败: "+err.Error(), nil)
+		return
+	}
+
+	response.Success(c.Ctx, token)
+}
+
+// 13. RotateDeviceSecret 轮换设备密钥
+// @Summary 轮换设备密钥
+// @Description 重新签发设备密钥，旧密钥和已签发的设备令牌立即失效；响应中的secret只返回这一次。设备需要重新认证
+// @Tags device
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "设备ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /devices/{id}/rotate-secret [post]
+func (c *DeviceController) RotateDeviceSecret() {
+	deviceID, err := strconv.Atoi(c.Ctx.Param("id"))
+	if err != nil || deviceID <= 0 {
+		response.FailWithMessage(c.Ctx, code.ErrBind, "无效的设备ID", nil)
+		return
+	}
+
+	// 验证设备是否存在且属于当前物业
+	deviceService := scopedDeviceService(c.Ctx, c.Container)
+	if _, err := deviceService.GetDeviceByID(uint(deviceID)); err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrDeviceNotFound, err.Error(), nil)
+		return
+	}
+
+	secret, err := deviceService.RotateSecret(uint(deviceID))
+	if err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrDatabase, "轮换设备密钥失败: "+err.Error(), nil)
+		return
+	}
+	revokeAccountTokens(c.Container, services.DeviceRole, uint(deviceID))
+
+	response.Success(c.Ctx, gin.H{"device_id": deviceID, "secret": secret})
+}
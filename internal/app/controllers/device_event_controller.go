@@ -0,0 +1,194 @@
+package controllers
+
+import (
+	"ilock-http-service/internal/domain/models"
+	"ilock-http-service/internal/domain/services"
+	"ilock-http-service/internal/domain/services/container"
+	"ilock-http-service/internal/error/code"
+	"ilock-http-service/internal/error/response"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InterfaceDeviceEventController 定义设备事件控制器接口
+type InterfaceDeviceEventController interface {
+	ReportDeviceEvent()
+	GetDoorHistory()
+}
+
+// DeviceEventController 处理设备事件相关的请求
+type DeviceEventController struct {
+	Ctx       *gin.Context
+	Container *container.ServiceContainer
+}
+
+// NewDeviceEventController 创建一个新的设备事件控制器
+func NewDeviceEventController(ctx *gin.Context, container *container.ServiceContainer) *DeviceEventController {
+	return &DeviceEventController{
+		Ctx:       ctx,
+		Container: container,
+	}
+}
+
+// DeviceEventRequest 设备事件上报请求
+type DeviceEventRequest struct {
+	DeviceID   uint   `json:"device_id" binding:"required" example:"1"`
+	EventType  string `json:"event_type" binding:"required" example:"door_forced"` // door_opened, door_closed, door_forced, door_held_open, tamper, access_granted, access_denied
+	Timestamp  int64  `json:"timestamp" example:"1688208000000"`                   // Unix毫秒时间戳，可选
+	Details    string `json:"details" example:"门磁检测到非授权开门"`
+	ResidentID uint   `json:"resident_id" example:"1"` // 开门事件对应的居民ID，可选
+	Method     string `json:"method" example:"face"`   // 开门方式: remote, code, face, fingerprint
+}
+
+// HandleDeviceEventFunc 返回一个处理设备事件请求的Gin处理函数
+func HandleDeviceEventFunc(container *container.ServiceContainer, method string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		controller := NewDeviceEventController(ctx, container)
+
+		switch method {
+		case "reportDeviceEvent":
+			controller.ReportDeviceEvent()
+		case "getDoorHistory":
+			controller.GetDoorHistory()
+		default:
+			response.FailWithMessage(ctx, code.ErrBind, "无效的方法", nil)
+		}
+	}
+}
+
+// 1. ReportDeviceEvent 设备上报门磁事件
+// @Summary      Report Device Event
+// @Description  Device reports a door sensor event; door_forced and tamper events raise an emergency alarm automatically, access_granted and access_denied events are also written to the access log
+// @Tags         device
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body DeviceEventRequest true "Device event"
+// @Success      200  {object}  models.DeviceEvent
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Router       /device/event [post]
+func (c *DeviceEventController) ReportDeviceEvent() {
+	var req DeviceEventRequest
+	if err := c.Ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrBind, "无效的请求参数: "+err.Error(), nil)
+		return
+	}
+
+	eventType := models.DeviceEventType(req.EventType)
+	if !eventType.IsValid() {
+		response.FailWithMessage(c.Ctx, code.ErrValidation, "不支持的事件类型: "+req.EventType, nil)
+		return
+	}
+
+	event := &models.DeviceEvent{
+		DeviceID:  req.DeviceID,
+		EventType: eventType,
+		Source:    "http",
+		Details:   req.Details,
+		Method:    req.Method,
+	}
+	if req.ResidentID > 0 {
+		event.ResidentID = &req.ResidentID
+	}
+	if req.Timestamp > 0 {
+		event.Timestamp = time.UnixMilli(req.Timestamp)
+	}
+
+	deviceEventService := c.Container.GetService("device_event").(services.InterfaceDeviceEventService)
+	if err := deviceEventService.ReportEvent(event); err != nil {
+		if err.Error() == "设备不存在" {
+			response.FailWithMessage(c.Ctx, code.ErrDeviceNotFound, err.Error(), nil)
+			return
+		}
+		response.FailWithMessage(c.Ctx, code.ErrDatabase, "记录设备事件失败: "+err.Error(), nil)
+		return
+	}
+
+	response.Success(c.Ctx, event)
+}
+
+// 2. GetDoorHistory 查询设备的门禁事件历史
+// @Summary      Get Door History
+// @Description  Get door sensor event history of a device, newest first
+// @Tags         device
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id path int true "设备ID"
+// @Param        event_type query string false "事件类型"
+// @Param        start_time query string false "开始时间 (RFC3339)"
+// @Param        end_time query string false "结束时间 (RFC3339)"
+// @Param        page query int false "页码，默认为1"
+// @Param        page_size query int false "每页条数，默认为10"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /devices/{id}/events [get]
+func (c *DeviceEventController) GetDoorHistory() {
+	deviceID, err := strconv.Atoi(c.Ctx.Param("id"))
+	if err != nil || deviceID <= 0 {
+		response.FailWithMessage(c.Ctx, code.ErrValidation, "无效的设备ID", nil)
+		return
+	}
+
+	// 只能查询本物业的设备，其他物业的设备视为不存在
+	if _, err := scopedDeviceService(c.Ctx, c.Container).GetDeviceByID(uint(deviceID)); err != nil {
+		if failPropertyScope(c.Ctx, err) {
+			return
+		}
+		response.FailWithMessage(c.Ctx, code.ErrDeviceNotFound, err.Error(), nil)
+		return
+	}
+
+	page, _ := strconv.Atoi(c.Ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.Ctx.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	query := services.DeviceEventQuery{
+		DeviceID:  uint(deviceID),
+		EventType: c.Ctx.Query("event_type"),
+		Page:      page,
+		PageSize:  pageSize,
+	}
+
+	if startStr := c.Ctx.Query("start_time"); startStr != "" {
+		startTime, err := time.Parse(time.RFC3339, startStr)
+		if err != nil {
+			response.FailWithMessage(c.Ctx, code.ErrValidation, "无效的开始时间格式", nil)
+			return
+		}
+		query.StartTime = &startTime
+	}
+	if endStr := c.Ctx.Query("end_time"); endStr != "" {
+		endTime, err := time.Parse(time.RFC3339, endStr)
+		if err != nil {
+			response.FailWithMessage(c.Ctx, code.ErrValidation, "无效的结束时间格式", nil)
+			return
+		}
+		query.EndTime = &endTime
+	}
+
+	deviceEventService := c.Container.GetService("device_event").(services.InterfaceDeviceEventService)
+	events, total, err := deviceEventService.GetDoorHistory(query)
+	if err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrDatabase, "获取门禁历史失败: "+err.Error(), nil)
+		return
+	}
+
+	response.Success(c.Ctx, gin.H{
+		"total":       total,
+		"page":        page,
+		"page_size":   pageSize,
+		"total_pages": (total + int64(pageSize) - 1) / int64(pageSize),
+		"data":        events,
+	})
+}
@@ -1,11 +1,14 @@
 package controllers
 
 import (
+	"errors"
 	"ilock-http-service/internal/domain/models"
 	"ilock-http-service/internal/domain/services"
 	"ilock-http-service/internal/domain/services/container"
 	"ilock-http-service/internal/error/code"
 	"ilock-http-service/internal/error/response"
+	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -17,6 +20,26 @@ type InterfaceEmergencyController interface {
 	GetEmergencyContacts()
 	EmergencyUnlockAll()
 	NotifyAllUsers()
+	GetAlarms()
+	GetAlarm()
+	AcknowledgeAlarm()
+	AssignAlarm()
+	ResolveAlarm()
+	TriggerEmergency()
+	GetEmergencyLogs()
+	GetEmergencyLogByID()
+	UpdateEmergencyLog()
+	CreateEmergencyContact()
+	UpdateEmergencyContact()
+	DeleteEmergencyContact()
+	ReorderEmergencyContacts()
+	GetNotificationProgress()
+	GetNotificationDeliveries()
+	StartEmergencyUnlock()
+	GetUnlockSessions()
+	GetUnlockSession()
+	EndEmergencyUnlock()
+	GetDrillReport()
 }
 
 // EmergencyController 处理紧急情况相关的请求
@@ -38,13 +61,31 @@ type EmergencyAlarmRequest struct {
 	Type        string `json:"type" binding:"required" example:"fire"` // 如：fire(火灾)、intrusion(入侵)、medical(医疗)等
 	Location    string `json:"location" binding:"required" example:"Building A, Floor 3"`
 	Description string `json:"description" example:"火灾警报被触发，疑似厨房起火"`
-	ReportedBy  uint   `json:"reported_by" example:"1"`                    // 报告人ID
-	PropertyID  uint   `json:"property_id" binding:"required" example:"1"` // 物业ID
+	OnBehalfOf  uint   `json:"on_behalf_of" example:"1"` // 代为报警的居民ID，居民触发时忽略
+	PropertyID  uint   `json:"property_id" example:"1"`  // 物业ID，居民触发时固定为本人所在物业
+	IsDrill     bool   `json:"is_drill" example:"false"` // 是否为演练
 }
 
-// EmergencyUnlockRequest 表示紧急解锁请求
+// EmergencyUnlockRequest 表示解锁所有门的紧急解锁请求
 type EmergencyUnlockRequest struct {
-	Reason string `json:"reason" binding:"required" example:"火灾疏散"`
+	Reason      string `json:"reason" binding:"required" example:"火灾疏散"`
+	RelockAfter int    `json:"relock_after" binding:"min=0" example:"1800"` // 多少秒后自动重新上锁，0表示需手动结束
+	IsDrill     bool   `json:"is_drill" example:"false"`                    // 演练不向设备下发解锁指令
+}
+
+// ScopedEmergencyUnlockRequest 表示按范围紧急解锁的请求，按设备、楼号、物业的顺序取第一个非空范围
+type ScopedEmergencyUnlockRequest struct {
+	Reason      string `json:"reason" binding:"required" example:"A栋火灾疏散"`
+	PropertyID  *uint  `json:"property_id" example:"1"`
+	BuildingIDs []uint `json:"building_ids" example:"1"`
+	DeviceIDs   []uint `json:"device_ids" example:"1"`
+	RelockAfter int    `json:"relock_after" binding:"min=0" example:"1800"` // 多少秒后自动重新上锁，0表示需手动结束
+	IsDrill     bool   `json:"is_drill" example:"false"`                    // 演练不向设备下发解锁指令
+}
+
+// EndEmergencyUnlockRequest 表示结束紧急解锁的请求
+type EndEmergencyUnlockRequest struct {
+	Reason string `json:"reason" example:"火情已排除"`
 }
 
 // EmergencyNotificationRequest 表示紧急通知请求
@@ -56,6 +97,66 @@ type EmergencyNotificationRequest struct {
 	TargetType string     `json:"target_type" example:"all"`                  // all, residents, staff
 	PropertyID *uint      `json:"property_id" example:"1"`                    // 关联的物业ID，可以为空表示全局通知
 	IsPublic   bool       `json:"is_public" example:"false"`                  // 是否为公开通知
+	IsDrill    bool       `json:"is_drill" example:"false"`                   // 是否为演练
+}
+
+// AlarmAcknowledgeRequest 表示确认警报请求
+type AlarmAcknowledgeRequest struct {
+	Remark string `json:"remark" example:"已收到，正在前往现场"`
+}
+
+// AlarmAssignRequest 表示指派警报请求
+type AlarmAssignRequest struct {
+	StaffID uint   `json:"staff_id" binding:"required" example:"3"`
+	Remark  string `json:"remark" example:"请安保组长处理"`
+}
+
+// AlarmResolveRequest 表示解决警报请求
+type AlarmResolveRequest struct {
+	Resolution string `json:"resolution" binding:"required" example:"现场确认为误报，已复位烟感"`
+}
+
+// TriggerEmergencyRequest 表示居民或设备发起紧急求助的请求，居民发起时resident_id和property_id以令牌为准
+type TriggerEmergencyRequest struct {
+	ResidentID  uint   `json:"resident_id" example:"1"`
+	DeviceID    uint   `json:"device_id" example:"1"`
+	Description string `json:"description" example:"老人在家中摔倒"`
+	PropertyID  *uint  `json:"property_id" example:"1"`
+	IsDrill     bool   `json:"is_drill" example:"false"` // 是否为演练
+}
+
+// UpdateEmergencyLogRequest 表示更新紧急事件状态的请求
+type UpdateEmergencyLogRequest struct {
+	Status string `json:"status" binding:"required,oneof=responded resolved" example:"responded"` // responded, resolved
+}
+
+// EmergencyContactRequest 表示创建紧急联系人的请求
+type EmergencyContactRequest struct {
+	Name         string `json:"name" binding:"required" example:"张经理"`
+	PhoneNumber  string `json:"phone_number" binding:"required" example:"13800138000"`
+	Role         string `json:"role" binding:"required" example:"物业经理"` // 如：警察、消防、医院、物业经理等
+	Priority     int    `json:"priority" example:"10"`                  // 数字越大优先级越高
+	PropertyID   *uint  `json:"property_id" example:"1"`                // 为空表示全局联系人
+	PropertyName string `json:"property_name" example:"阳光花园物业"`
+	Remark       string `json:"remark" example:"24小时值班"`
+}
+
+// UpdateEmergencyContactRequest 表示更新紧急联系人的请求，未提供的字段保持不变
+type UpdateEmergencyContactRequest struct {
+	Name         string `json:"name" example:"张经理"`
+	PhoneNumber  string `json:"phone_number" example:"13800138000"`
+	Role         string `json:"role" example:"物业经理"`
+	Priority     *int   `json:"priority" example:"10"`
+	PropertyID   *uint  `json:"property_id" example:"1"`
+	MakeGlobal   bool   `json:"make_global" example:"false"` // 为true时清除物业关联，改为全局联系人
+	PropertyName string `json:"property_name" example:"阳光花园物业"`
+	Remark       string `json:"remark" example:"24小时值班"`
+}
+
+// ReorderEmergencyContactsRequest 表示重排紧急联系人优先级的请求
+type ReorderEmergencyContactsRequest struct {
+	PropertyID *uint  `json:"property_id" example:"1"`                          // 为空表示重排全局联系人
+	ContactIDs []uint `json:"contact_ids" binding:"required,min=1" example:"3"` // 按通知顺序排列，排在前面的优先级更高
 }
 
 // HandleEmergencyFunc 返回一个处理紧急情况请求的Gin处理函数
@@ -72,6 +173,46 @@ func HandleEmergencyFunc(container *container.ServiceContainer, method string) g
 			controller.EmergencyUnlockAll()
 		case "notifyAllUsers":
 			controller.NotifyAllUsers()
+		case "getAlarms":
+			controller.GetAlarms()
+		case "getAlarm":
+			controller.GetAlarm()
+		case "acknowledgeAlarm":
+			controller.AcknowledgeAlarm()
+		case "assignAlarm":
+			controller.AssignAlarm()
+		case "resolveAlarm":
+			controller.ResolveAlarm()
+		case "triggerEmergency":
+			controller.TriggerEmergency()
+		case "getEmergencyLogs":
+			controller.GetEmergencyLogs()
+		case "getEmergencyLogByID":
+			controller.GetEmergencyLogByID()
+		case "updateEmergencyLog":
+			controller.UpdateEmergencyLog()
+		case "createEmergencyContact":
+			controller.CreateEmergencyContact()
+		case "updateEmergencyContact":
+			controller.UpdateEmergencyContact()
+		case "deleteEmergencyContact":
+			controller.DeleteEmergencyContact()
+		case "reorderEmergencyContacts":
+			controller.ReorderEmergencyContacts()
+		case "getNotificationProgress":
+			controller.GetNotificationProgress()
+		case "getNotificationDeliveries":
+			controller.GetNotificationDeliveries()
+		case "startEmergencyUnlock":
+			controller.StartEmergencyUnlock()
+		case "getUnlockSessions":
+			controller.GetUnlockSessions()
+		case "getUnlockSession":
+			controller.GetUnlockSession()
+		case "endEmergencyUnlock":
+			controller.EndEmergencyUnlock()
+		case "getDrillReport":
+			controller.GetDrillReport()
 		default:
 			response.FailWithMessage(ctx, code.ErrBind, "无效的方法", nil)
 		}
@@ -97,63 +238,116 @@ func (c *EmergencyController) TriggerAlarm() {
 		return
 	}
 
-	// 获取用户ID和角色
-	userID, exists := c.Ctx.Get("userID")
-	if !exists {
-		userID = uint(0) // 如果没有用户ID，设置为0表示系统自动触发
-	}
+	// 获取用户ID，未登录时为0表示系统自动触发
+	userID := getCurrentUserID(c.Ctx)
 
 	// 创建警报对象
 	alarm := &models.EmergencyAlarm{
 		Type:        req.Type,
 		Location:    req.Location,
 		Description: req.Description,
-		Status:      "triggered",
-		Timestamp:   time.Now(),
+		IsDrill:     req.IsDrill,
 	}
 
-	// 如果提供了PropertyID，则设置它
-	if req.PropertyID > 0 {
-		alarm.PropertyID = &req.PropertyID
-	}
+	emergencyService := c.emergencyService()
+
+	// 报告人和审计日志中的操作人始终是当前登录账号
+	operator := c.alarmOperator()
+	alarm.ReportedBy = operator.ID
 
-	// 如果前端没有提供报告人，使用当前登录用户
-	if req.ReportedBy == 0 && userID != uint(0) {
-		alarm.ReportedBy = userID.(uint)
+	// 居民只能以本人名义在本人所在物业触发警报，忽略请求体中的代报居民和物业
+	if operator.Role == "user" {
+		propertyID, ok := c.residentPropertyID(userID)
+		if !ok {
+			return
+		}
+		alarm.PropertyID = propertyID
+		// 物业已固定为居民户号所在物业，不再按令牌中的物业限定
+		emergencyService = c.Container.GetService("emergency").(services.InterfaceEmergencyService)
 	} else {
-		alarm.ReportedBy = req.ReportedBy
+		// 代居民报警时居民必须存在，未提供物业时使用居民所在物业
+		var residentPropertyID *uint
+		if req.OnBehalfOf > 0 {
+			propertyID, ok := c.residentPropertyID(req.OnBehalfOf)
+			if !ok {
+				return
+			}
+			alarm.OnBehalfOf = &req.OnBehalfOf
+			residentPropertyID = propertyID
+		}
+
+		// 物业账号未提供时默认为本物业
+		if req.PropertyID > 0 {
+			alarm.PropertyID = &req.PropertyID
+		} else if residentPropertyID != nil {
+			alarm.PropertyID = residentPropertyID
+		} else if getCurrentPropertyID(c.Ctx) == nil {
+			response.FailWithMessage(c.Ctx, code.ErrValidation, "必须提供物业ID", nil)
+			return
+		}
+	}
+
+	if err := emergencyService.TriggerAlarm(alarm, operator); err != nil {
+		if failPropertyScope(c.Ctx, err) {
+			return
+		}
+		response.FailWithMessage(c.Ctx, code.ErrDatabase, "触发警报失败: "+err.Error(), nil)
+		return
 	}
 
-	// 直接返回演示模式响应，不尝试保存到数据库
-	// 这样可以避免数据库错误
 	response.Success(c.Ctx, gin.H{
-		"alarm_id":    999, // 模拟ID
-		"type":        alarm.Type,
-		"location":    alarm.Location,
-		"timestamp":   alarm.Timestamp,
-		"status":      "demo_mode",
-		"reported_by": alarm.ReportedBy,
+		"alarm_id":     alarm.ID,
+		"type":         alarm.Type,
+		"location":     alarm.Location,
+		"timestamp":    alarm.Timestamp,
+		"status":       alarm.Status,
+		"reported_by":  alarm.ReportedBy,
+		"on_behalf_of": alarm.OnBehalfOf,
+		"is_drill":     alarm.IsDrill,
 	})
 }
 
 // 2. GetEmergencyContacts 处理获取紧急联系人列表的请求
 // @Summary      Get Emergency Contacts
-// @Description  Get a list of all emergency contacts in the system
+// @Description  Get emergency contacts ordered by priority; with building_id each contact is flagged with whether it applies to that building
 // @Tags         Emergency
 // @Accept       json
 // @Produce      json
+// @Param        property_id query int false "物业ID，只返回该物业及全局联系人"
+// @Param        building_id query int false "楼号ID，标记适用于该楼号的联系人"
 // @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  ErrorResponse
 // @Failure      500  {object}  ErrorResponse
 // @Router       /emergency/contacts [get]
 // @Security     BearerAuth
 func (c *EmergencyController) GetEmergencyContacts() {
-	// 获取紧急服务
-	emergencyService := c.Container.GetService("emergency").(services.InterfaceEmergencyService)
+	var query services.ContactQuery
+	if propertyStr := c.Ctx.Query("property_id"); propertyStr != "" {
+		propertyID, err := strconv.Atoi(propertyStr)
+		if err != nil || propertyID <= 0 {
+			response.FailWithMessage(c.Ctx, code.ErrValidation, "无效的物业ID", nil)
+			return
+		}
+		id := uint(propertyID)
+		query.PropertyID = &id
+	}
+	if buildingStr := c.Ctx.Query("building_id"); buildingStr != "" {
+		buildingID, err := strconv.Atoi(buildingStr)
+		if err != nil || buildingID <= 0 {
+			response.FailWithMessage(c.Ctx, code.ErrValidation, "无效的楼号ID", nil)
+			return
+		}
+		id := uint(buildingID)
+		query.BuildingID = &id
+	}
+
+	// 获取紧急联系人服务
+	contactService := c.contactService()
 
 	// 获取联系人列表
-	contacts, err := emergencyService.GetEmergencyContacts()
+	contacts, err := contactService.GetContacts(query)
 	if err != nil {
-		response.FailWithMessage(c.Ctx, code.ErrDatabase, "获取联系人失败: "+err.Error(), nil)
+		c.failContact(err, "获取联系人失败")
 		return
 	}
 
@@ -165,12 +359,12 @@ func (c *EmergencyController) GetEmergencyContacts() {
 
 // 3. EmergencyUnlockAll 处理紧急情况下解锁所有门的请求
 // @Summary      Emergency Unlock All Doors
-// @Description  Unlock all doors in the system during an emergency
+// @Description  Send emergency unlock commands to every device over MQTT and track device acknowledgements; optionally relock automatically after relock_after seconds
 // @Tags         Emergency
 // @Accept       json
 // @Produce      json
 // @Param        request body EmergencyUnlockRequest true "Unlock request parameters"
-// @Success      200  {object}  map[string]interface{}
+// @Success      200  {object}  models.EmergencyUnlockSession
 // @Failure      400  {object}  ErrorResponse
 // @Failure      500  {object}  ErrorResponse
 // @Router       /emergency/unlock-all [post]
@@ -182,19 +376,19 @@ func (c *EmergencyController) EmergencyUnlockAll() {
 		return
 	}
 
-	// 获取紧急服务
-	emergencyService := c.Container.GetService("emergency").(services.InterfaceEmergencyService)
-
-	// 执行紧急解锁
-	if err := emergencyService.EmergencyUnlockAll(req.Reason); err != nil {
-		response.FailWithMessage(c.Ctx, code.ErrDatabase, "紧急解锁失败: "+err.Error(), nil)
+	unlockService := c.unlockService()
+	session, err := unlockService.StartUnlock(services.UnlockRequest{
+		Reason:      req.Reason,
+		RelockAfter: req.RelockAfter,
+		IsDrill:     req.IsDrill,
+		Operator:    c.alarmOperator(),
+	})
+	if err != nil {
+		c.failUnlock(err, "紧急解锁失败")
 		return
 	}
 
-	response.Success(c.Ctx, gin.H{
-		"timestamp": time.Now(),
-		"reason":    req.Reason,
-	})
+	response.Success(c.Ctx, session)
 }
 
 // 4. NotifyAllUsers 处理紧急情况下发送通知给所有用户的请求
@@ -217,19 +411,11 @@ func (c *EmergencyController) NotifyAllUsers() {
 	}
 
 	// 获取用户ID和角色
-	userID, exists := c.Ctx.Get("userID")
-	if !exists {
-		userID = uint(0)
-	}
-
-	role, _ := c.Ctx.Get("role")
-	roleStr, ok := role.(string)
-	if !ok {
-		roleStr = "system"
-	}
+	userID := getCurrentUserID(c.Ctx)
+	roleStr := getCurrentRole(c.Ctx)
 
 	// 获取紧急服务
-	emergencyService := c.Container.GetService("emergency").(services.InterfaceEmergencyService)
+	emergencyService := c.emergencyService()
 
 	// 创建通知对象
 	notification := &models.EmergencyNotification{
@@ -237,10 +423,11 @@ func (c *EmergencyController) NotifyAllUsers() {
 		Content:    req.Content,
 		Severity:   req.Severity,
 		TargetType: req.TargetType,
-		SenderID:   userID.(uint),
+		SenderID:   userID,
 		SenderRole: roleStr,
 		PropertyID: req.PropertyID,
 		IsPublic:   req.IsPublic,
+		IsDrill:    req.IsDrill,
 	}
 
 	// 设置过期时间（如果提供）
@@ -250,6 +437,13 @@ func (c *EmergencyController) NotifyAllUsers() {
 
 	// 发送通知
 	if err := emergencyService.NotifyAllUsers(notification); err != nil {
+		if failPropertyScope(c.Ctx, err) {
+			return
+		}
+		if errors.Is(err, services.ErrInvalidTargetType) {
+			response.FailWithMessage(c.Ctx, code.ErrValidation, err.Error(), nil)
+			return
+		}
 		response.FailWithMessage(c.Ctx, code.ErrDatabase, "发送通知失败: "+err.Error(), nil)
 		return
 	}
@@ -262,5 +456,984 @@ func (c *EmergencyController) NotifyAllUsers() {
 		"target_type": notification.TargetType,
 		"expires_at":  notification.ExpiresAt,
 		"sender_role": notification.SenderRole,
+		"is_drill":    notification.IsDrill,
+	})
+}
+
+// 5. GetAlarms 获取警报列表
+// @Summary      Get Emergency Alarms
+// @Description  List emergency alarms, filterable by status, type, property, assignee and drill flag
+// @Tags         Emergency
+// @Accept       json
+// @Produce      json
+// @Param        status query string false "警报状态: triggered, processing, resolved"
+// @Param        type query string false "警报类型"
+// @Param        property_id query int false "物业ID"
+// @Param        assigned_to query int false "负责人员工ID"
+// @Param        is_drill query bool false "是否为演练，不传表示全部"
+// @Param        page query int false "页码，默认为1"
+// @Param        page_size query int false "每页条数，默认为10"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      500  {object}  ErrorResponse
+// @Router       /emergency/alarms [get]
+// @Security     BearerAuth
+func (c *EmergencyController) GetAlarms() {
+	page, _ := strconv.Atoi(c.Ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.Ctx.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	query := services.AlarmQuery{
+		Status:   c.Ctx.Query("status"),
+		Type:     c.Ctx.Query("type"),
+		Page:     page,
+		PageSize: pageSize,
+	}
+	if id, err := strconv.Atoi(c.Ctx.Query("property_id")); err == nil && id > 0 {
+		propertyID := uint(id)
+		query.PropertyID = &propertyID
+	}
+	if id, err := strconv.Atoi(c.Ctx.Query("assigned_to")); err == nil && id > 0 {
+		staffID := uint(id)
+		query.AssignedTo = &staffID
+	}
+	if isDrill, err := strconv.ParseBool(c.Ctx.Query("is_drill")); err == nil {
+		query.IsDrill = &isDrill
+	}
+
+	emergencyService := c.emergencyService()
+	alarms, total, err := emergencyService.GetAlarms(query)
+	if err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrDatabase, "获取警报列表失败: "+err.Error(), nil)
+		return
+	}
+
+	response.Success(c.Ctx, gin.H{
+		"total":       total,
+		"page":        page,
+		"page_size":   pageSize,
+		"total_pages": (total + int64(pageSize) - 1) / int64(pageSize),
+		"data":        alarms,
+	})
+}
+
+// 6. GetAlarm 获取警报详情
+// @Summary      Get Emergency Alarm
+// @Description  Get an emergency alarm with its audit trail
+// @Tags         Emergency
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "警报ID"
+// @Success      200  {object}  models.EmergencyAlarm
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Router       /emergency/alarms/{id} [get]
+// @Security     BearerAuth
+func (c *EmergencyController) GetAlarm() {
+	alarmID, ok := c.parseAlarmID()
+	if !ok {
+		return
+	}
+
+	emergencyService := c.emergencyService()
+	alarm, err := emergencyService.GetAlarmByID(alarmID)
+	if err != nil {
+		c.failAlarm(err, "获取警报失败")
+		return
+	}
+
+	response.Success(c.Ctx, alarm)
+}
+
+// 7. AcknowledgeAlarm 确认警报
+// @Summary      Acknowledge Emergency Alarm
+// @Description  Acknowledge a triggered alarm, moving it to processing
+// @Tags         Emergency
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "警报ID"
+// @Param        request body AlarmAcknowledgeRequest false "确认备注"
+// @Success      200  {object}  models.EmergencyAlarm
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Router       /emergency/alarms/{id}/acknowledge [post]
+// @Security     BearerAuth
+func (c *EmergencyController) AcknowledgeAlarm() {
+	alarmID, ok := c.parseAlarmID()
+	if !ok {
+		return
+	}
+
+	var req AlarmAcknowledgeRequest
+	if c.Ctx.Request.ContentLength > 0 {
+		if err := c.Ctx.ShouldBindJSON(&req); err != nil {
+			response.FailWithMessage(c.Ctx, code.ErrBind, "无效的请求参数: "+err.Error(), nil)
+			return
+		}
+	}
+
+	emergencyService := c.emergencyService()
+	alarm, err := emergencyService.AcknowledgeAlarm(alarmID, c.alarmOperator(), req.Remark)
+	if err != nil {
+		c.failAlarm(err, "确认警报失败")
+		return
+	}
+
+	response.Success(c.Ctx, alarm)
+}
+
+// 8. AssignAlarm 指派警报给物业员工
+// @Summary      Assign Emergency Alarm
+// @Description  Assign an alarm to a property staff member
+// @Tags         Emergency
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "警报ID"
+// @Param        request body AlarmAssignRequest true "指派信息"
+// @Success      200  {object}  models.EmergencyAlarm
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Router       /emergency/alarms/{id}/assign [post]
+// @Security     BearerAuth
+func (c *EmergencyController) AssignAlarm() {
+	alarmID, ok := c.parseAlarmID()
+	if !ok {
+		return
+	}
+
+	var req AlarmAssignRequest
+	if err := c.Ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrBind, "无效的请求参数: "+err.Error(), nil)
+		return
+	}
+
+	emergencyService := c.emergencyService()
+	alarm, err := emergencyService.AssignAlarm(alarmID, req.StaffID, c.alarmOperator(), req.Remark)
+	if err != nil {
+		c.failAlarm(err, "指派警报失败")
+		return
+	}
+
+	response.Success(c.Ctx, alarm)
+}
+
+// 9. ResolveAlarm 解决警报
+// @Summary      Resolve Emergency Alarm
+// @Description  Resolve an alarm that is being processed
+// @Tags         Emergency
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "警报ID"
+// @Param        request body AlarmResolveRequest true "处理结果"
+// @Success      200  {object}  models.EmergencyAlarm
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Router       /emergency/alarms/{id}/resolve [post]
+// @Security     BearerAuth
+func (c *EmergencyController) ResolveAlarm() {
+	alarmID, ok := c.parseAlarmID()
+	if !ok {
+		return
+	}
+
+	var req AlarmResolveRequest
+	if err := c.Ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrBind, "无效的请求参数: "+err.Error(), nil)
+		return
+	}
+
+	emergencyService := c.emergencyService()
+	alarm, err := emergencyService.ResolveAlarm(alarmID, c.alarmOperator(), req.Resolution)
+	if err != nil {
+		c.failAlarm(err, "解决警报失败")
+		return
+	}
+
+	response.Success(c.Ctx, alarm)
+}
+
+// parseAlarmID 解析路径中的警报ID
+func (c *EmergencyController) parseAlarmID() (uint, bool) {
+	id, err := strconv.Atoi(c.Ctx.Param("id"))
+	if err != nil || id <= 0 {
+		response.FailWithMessage(c.Ctx, code.ErrValidation, "无效的警报ID", nil)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// alarmOperator 返回当前登录用户作为警报操作人
+func (c *EmergencyController) alarmOperator() services.AlarmOperator {
+	return services.AlarmOperator{
+		ID:   getCurrentUserID(c.Ctx),
+		Role: getCurrentRole(c.Ctx),
+	}
+}
+
+// failAlarm 根据警报服务返回的错误类型输出响应
+func (c *EmergencyController) failAlarm(err error, message string) {
+	if failPropertyScope(c.Ctx, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, services.ErrAlarmNotFound):
+		response.FailWithMessage(c.Ctx, code.ErrAlarmNotFound, err.Error(), nil)
+	case errors.Is(err, services.ErrAlarmTransition):
+		response.FailWithMessage(c.Ctx, code.ErrAlarmStatusInvalid, err.Error(), nil)
+	default:
+		response.FailWithMessage(c.Ctx, code.ErrDatabase, message+": "+err.Error(), nil)
+	}
+}
+
+// 10. TriggerEmergency 发起紧急求助
+// @Summary      Trigger Emergency
+// @Description  Record an emergency raised by a resident or device; unanswered emergencies are escalated through the contact list
+// @Tags         Emergency
+// @Accept       json
+// @Produce      json
+// @Param        request body TriggerEmergencyRequest true "紧急求助信息"
+// @Success      200  {object}  models.EmergencyLog
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /emergency/trigger [post]
+// @Security     BearerAuth
+func (c *EmergencyController) TriggerEmergency() {
+	var req TriggerEmergencyRequest
+	if err := c.Ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrBind, "无效的请求参数: "+err.Error(), nil)
+		return
+	}
+
+	emergencyService := c.emergencyService()
+
+	// 居民只能以本人名义求助，物业取本人户号所在物业，设备必须在本物业内
+	if getCurrentRole(c.Ctx) == "user" {
+		residentID := getCurrentUserID(c.Ctx)
+		propertyID, ok := c.residentPropertyID(residentID)
+		if !ok {
+			return
+		}
+		if req.DeviceID != 0 {
+			if _, err := scopedDeviceService(c.Ctx, c.Container).GetDeviceByID(req.DeviceID); err != nil {
+				response.FailWithMessage(c.Ctx, code.ErrDeviceNotFound, err.Error(), nil)
+				return
+			}
+		}
+		req.ResidentID = residentID
+		req.PropertyID = propertyID
+		// 物业已固定为居民户号所在物业，不再按令牌中的物业限定
+		emergencyService = c.Container.GetService("emergency").(services.InterfaceEmergencyService)
+	}
+
+	if req.ResidentID == 0 && req.DeviceID == 0 {
+		response.FailWithMessage(c.Ctx, code.ErrValidation, "必须提供居民ID或设备ID", nil)
+		return
+	}
+
+	emergency := &models.EmergencyLog{
+		ResidentID:  req.ResidentID,
+		DeviceID:    req.DeviceID,
+		Description: req.Description,
+		PropertyID:  req.PropertyID,
+		IsDrill:     req.IsDrill,
+	}
+
+	if err := emergencyService.TriggerEmergency(emergency); err != nil {
+		if failPropertyScope(c.Ctx, err) {
+			return
+		}
+		response.FailWithMessage(c.Ctx, code.ErrDatabase, "发起紧急求助失败: "+err.Error(), nil)
+		return
+	}
+
+	response.Success(c.Ctx, emergency)
+}
+
+// residentPropertyID 查询居民户号所在的物业，居民不存在时写入错误响应并返回false
+func (c *EmergencyController) residentPropertyID(residentID uint) (*uint, bool) {
+	residentService := c.Container.GetService("resident").(services.InterfaceResidentService)
+	resident, err := residentService.GetResidentByID(residentID)
+	if err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrResidentNotFound, err.Error(), nil)
+		return nil, false
+	}
+	return services.ResolveHouseholdPropertyID(c.Container.GetDB(), resident.HouseholdID), true
+}
+
+// 11. GetEmergencyLogs 获取紧急事件列表
+// @Summary      Get Emergency Logs
+// @Description  List emergencies, newest first
+// @Tags         Emergency
+// @Accept       json
+// @Produce      json
+// @Param        status query string false "状态: pending, escalated, responded, resolved"
+// @Param        page query int false "页码，默认为1"
+// @Param        page_size query int false "每页条数，默认为10"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      500  {object}  ErrorResponse
+// @Router       /emergency [get]
+// @Security     BearerAuth
+func (c *EmergencyController) GetEmergencyLogs() {
+	page, _ := strconv.Atoi(c.Ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.Ctx.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	emergencyService := c.emergencyService()
+	logs, total, err := emergencyService.GetEmergencyLogs(c.Ctx.Query("status"), page, pageSize)
+	if err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrDatabase, "获取紧急事件列表失败: "+err.Error(), nil)
+		return
+	}
+
+	response.Success(c.Ctx, gin.H{
+		"total":       total,
+		"page":        page,
+		"page_size":   pageSize,
+		"total_pages": (total + int64(pageSize) - 1) / int64(pageSize),
+		"data":        logs,
+	})
+}
+
+// 12. GetEmergencyLogByID 获取紧急事件详情
+// @Summary      Get Emergency Log
+// @Description  Get an emergency with every escalation step taken so far
+// @Tags         Emergency
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "紧急事件ID"
+// @Success      200  {object}  models.EmergencyLog
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Router       /emergency/{id} [get]
+// @Security     BearerAuth
+func (c *EmergencyController) GetEmergencyLogByID() {
+	id, err := strconv.Atoi(c.Ctx.Param("id"))
+	if err != nil || id <= 0 {
+		response.FailWithMessage(c.Ctx, code.ErrValidation, "无效的紧急事件ID", nil)
+		return
+	}
+
+	emergencyService := c.emergencyService()
+	emergency, err := emergencyService.GetEmergencyLogByID(uint(id))
+	if err != nil {
+		if errors.Is(err, services.ErrEmergencyNotFound) {
+			response.FailWithMessage(c.Ctx, code.ErrRecordNotFound, err.Error(), nil)
+			return
+		}
+		response.FailWithMessage(c.Ctx, code.ErrDatabase, "获取紧急事件失败: "+err.Error(), nil)
+		return
+	}
+
+	response.Success(c.Ctx, emergency)
+}
+
+// 13. UpdateEmergencyLog 响应或解决紧急事件
+// @Summary      Update Emergency Log
+// @Description  Respond to or resolve an emergency; responding stops further escalation
+// @Tags         Emergency
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "紧急事件ID"
+// @Param        request body UpdateEmergencyLogRequest true "新状态"
+// @Success      200  {object}  models.EmergencyLog
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Router       /emergency/{id} [put]
+// @Security     BearerAuth
+func (c *EmergencyController) UpdateEmergencyLog() {
+	id, err := strconv.Atoi(c.Ctx.Param("id"))
+	if err != nil || id <= 0 {
+		response.FailWithMessage(c.Ctx, code.ErrValidation, "无效的紧急事件ID", nil)
+		return
+	}
+
+	var req UpdateEmergencyLogRequest
+	if err := c.Ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrBind, "无效的请求参数: "+err.Error(), nil)
+		return
+	}
+
+	emergencyService := c.emergencyService()
+	emergency, err := emergencyService.UpdateEmergencyStatus(uint(id), models.EmergencyStatus(req.Status), getCurrentUserID(c.Ctx))
+	if err != nil {
+		if errors.Is(err, services.ErrEmergencyNotFound) {
+			response.FailWithMessage(c.Ctx, code.ErrRecordNotFound, err.Error(), nil)
+			return
+		}
+		response.FailWithMessage(c.Ctx, code.ErrValidation, "更新紧急事件失败: "+err.Error(), nil)
+		return
+	}
+
+	response.Success(c.Ctx, emergency)
+}
+
+// 14. CreateEmergencyContact 创建紧急联系人
+// @Summary      Create Emergency Contact
+// @Description  Create an emergency contact; contacts without property_id are global and used as fallback for every property
+// @Tags         Emergency
+// @Accept       json
+// @Produce      json
+// @Param        request body EmergencyContactRequest true "紧急联系人信息"
+// @Success      201  {object}  models.EmergencyContact
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /emergency/contacts [post]
+// @Security     BearerAuth
+func (c *EmergencyController) CreateEmergencyContact() {
+	var req EmergencyContactRequest
+	if err := c.Ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrBind, "无效的请求参数: "+err.Error(), nil)
+		return
+	}
+
+	contact := &models.EmergencyContact{
+		Name:         req.Name,
+		PhoneNumber:  req.PhoneNumber,
+		Role:         req.Role,
+		Priority:     req.Priority,
+		PropertyID:   req.PropertyID,
+		PropertyName: req.PropertyName,
+		Remark:       req.Remark,
+	}
+
+	contactService := c.contactService()
+	if err := contactService.CreateContact(contact); err != nil {
+		c.failContact(err, "创建联系人失败")
+		return
+	}
+
+	c.Ctx.Status(http.StatusCreated)
+	response.Success(c.Ctx, contact)
+}
+
+// 15. UpdateEmergencyContact 更新紧急联系人
+// @Summary      Update Emergency Contact
+// @Description  Update an emergency contact; omitted fields are left unchanged
+// @Tags         Emergency
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "联系人ID"
+// @Param        request body UpdateEmergencyContactRequest true "紧急联系人信息"
+// @Success      200  {object}  models.EmergencyContact
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /emergency/contacts/{id} [put]
+// @Security     BearerAuth
+func (c *EmergencyController) UpdateEmergencyContact() {
+	id, ok := c.parseContactID()
+	if !ok {
+		return
+	}
+
+	var req UpdateEmergencyContactRequest
+	if err := c.Ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrBind, "无效的请求参数: "+err.Error(), nil)
+		return
+	}
+
+	// 创建更新映射
+	updates := make(map[string]interface{})
+	if req.Name != "" {
+		updates["name"] = req.Name
+	}
+	if req.PhoneNumber != "" {
+		updates["phone_number"] = req.PhoneNumber
+	}
+	if req.Role != "" {
+		updates["role"] = req.Role
+	}
+	if req.Priority != nil {
+		updates["priority"] = *req.Priority
+	}
+	if req.MakeGlobal {
+		updates["property_id"] = nil
+		updates["property_name"] = ""
+	} else if req.PropertyID != nil {
+		updates["property_id"] = *req.PropertyID
+	}
+	if req.PropertyName != "" && !req.MakeGlobal {
+		updates["property_name"] = req.PropertyName
+	}
+	if req.Remark != "" {
+		updates["remark"] = req.Remark
+	}
+
+	if len(updates) == 0 {
+		response.FailWithMessage(c.Ctx, code.ErrValidation, "没有需要更新的字段", nil)
+		return
+	}
+
+	contactService := c.contactService()
+	contact, err := contactService.UpdateContact(id, updates)
+	if err != nil {
+		c.failContact(err, "更新联系人失败")
+		return
+	}
+
+	response.Success(c.Ctx, contact)
+}
+
+// 16. DeleteEmergencyContact 删除紧急联系人
+// @Summary      Delete Emergency Contact
+// @Description  Delete an emergency contact
+// @Tags         Emergency
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "联系人ID"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /emergency/contacts/{id} [delete]
+// @Security     BearerAuth
+func (c *EmergencyController) DeleteEmergencyContact() {
+	id, ok := c.parseContactID()
+	if !ok {
+		return
+	}
+
+	contactService := c.contactService()
+	if err := contactService.DeleteContact(id); err != nil {
+		c.failContact(err, "删除联系人失败")
+		return
+	}
+
+	response.Success(c.Ctx, nil)
+}
+
+// 17. ReorderEmergencyContacts 重排紧急联系人的通知顺序
+// @Summary      Reorder Emergency Contacts
+// @Description  Reorder the contacts of one property (or the global contacts) by rewriting their priorities
+// @Tags         Emergency
+// @Accept       json
+// @Produce      json
+// @Param        request body ReorderEmergencyContactsRequest true "联系人顺序"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /emergency/contacts/reorder [put]
+// @Security     BearerAuth
+func (c *EmergencyController) ReorderEmergencyContacts() {
+	var req ReorderEmergencyContactsRequest
+	if err := c.Ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrBind, "无效的请求参数: "+err.Error(), nil)
+		return
+	}
+
+	contactService := c.contactService()
+	contacts, err := contactService.ReorderContacts(req.PropertyID, req.ContactIDs)
+	if err != nil {
+		c.failContact(err, "重排联系人失败")
+		return
+	}
+
+	response.Success(c.Ctx, gin.H{
+		"contacts": contacts,
+		"total":    len(contacts),
+	})
+}
+
+// parseContactID 解析路径中的联系人ID
+func (c *EmergencyController) parseContactID() (uint, bool) {
+	id, err := strconv.Atoi(c.Ctx.Param("id"))
+	if err != nil || id <= 0 {
+		response.FailWithMessage(c.Ctx, code.ErrValidation, "无效的联系人ID", nil)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// failContact 根据紧急联系人服务返回的错误类型输出响应
+func (c *EmergencyController) failContact(err error, message string) {
+	if failPropertyScope(c.Ctx, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, services.ErrContactNotFound):
+		response.FailWithMessage(c.Ctx, code.ErrContactNotFound, err.Error(), nil)
+	case errors.Is(err, services.ErrBuildingNotFound):
+		response.FailWithMessage(c.Ctx, code.ErrRecordNotFound, err.Error(), nil)
+	case errors.Is(err, services.ErrInvalidPhoneNumber), errors.Is(err, services.ErrContactScope):
+		response.FailWithMessage(c.Ctx, code.ErrValidation, err.Error(), nil)
+	default:
+		response.FailWithMessage(c.Ctx, code.ErrDatabase, message+": "+err.Error(), nil)
+	}
+}
+
+// 18. GetNotificationProgress 获取紧急通知的投递进度
+// @Summary      Get Notification Delivery Progress
+// @Description  Summarize how many recipients an emergency notification reached, how many confirmed delivery and how many read it, broken down by channel and status
+// @Tags         Emergency
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "通知ID"
+// @Success      200  {object}  services.NotificationProgress
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /emergency/notifications/{id}/progress [get]
+// @Security     BearerAuth
+func (c *EmergencyController) GetNotificationProgress() {
+	id, ok := c.parseNotificationID()
+	if !ok {
+		return
+	}
+
+	notificationService := c.notificationService()
+	progress, err := notificationService.GetDeliveryProgress(id)
+	if err != nil {
+		if errors.Is(err, services.ErrNotificationNotFound) {
+			response.FailWithMessage(c.Ctx, code.ErrNotificationNotFound, err.Error(), nil)
+			return
+		}
+		response.FailWithMessage(c.Ctx, code.ErrDatabase, "获取投递进度失败: "+err.Error(), nil)
+		return
+	}
+
+	response.Success(c.Ctx, progress)
+}
+
+// 19. GetNotificationDeliveries 获取紧急通知的投递记录
+// @Summary      Get Notification Deliveries
+// @Description  List per-recipient delivery records of an emergency notification with delivery and read receipts
+// @Tags         Emergency
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "通知ID"
+// @Param        channel query string false "投递渠道: inbox, mqtt, sms, email, push"
+// @Param        status query string false "投递状态: pending, sent, delivered, failed"
+// @Param        page query int false "页码，默认为1"
+// @Param        page_size query int false "每页条数，默认为10"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /emergency/notifications/{id}/deliveries [get]
+// @Security     BearerAuth
+func (c *EmergencyController) GetNotificationDeliveries() {
+	id, ok := c.parseNotificationID()
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(c.Ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.Ctx.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	notificationService := c.notificationService()
+	deliveries, total, err := notificationService.GetDeliveries(services.DeliveryQuery{
+		NotificationID: id,
+		Channel:        c.Ctx.Query("channel"),
+		Status:         c.Ctx.Query("status"),
+		Page:           page,
+		PageSize:       pageSize,
 	})
+	if err != nil {
+		if errors.Is(err, services.ErrNotificationNotFound) {
+			response.FailWithMessage(c.Ctx, code.ErrNotificationNotFound, err.Error(), nil)
+			return
+		}
+		response.FailWithMessage(c.Ctx, code.ErrDatabase, "获取投递记录失败: "+err.Error(), nil)
+		return
+	}
+
+	response.Success(c.Ctx, gin.H{
+		"total":       total,
+		"page":        page,
+		"page_size":   pageSize,
+		"total_pages": (total + int64(pageSize) - 1) / int64(pageSize),
+		"data":        deliveries,
+	})
+}
+
+// parseNotificationID 解析路径中的通知ID
+func (c *EmergencyController) parseNotificationID() (uint, bool) {
+	id, err := strconv.Atoi(c.Ctx.Param("id"))
+	if err != nil || id <= 0 {
+		response.FailWithMessage(c.Ctx, code.ErrValidation, "无效的通知ID", nil)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// 20. StartEmergencyUnlock 按物业、楼号或设备范围紧急解锁
+// @Summary      Start Scoped Emergency Unlock
+// @Description  Send emergency unlock commands to the devices of a property, buildings or a device list over MQTT; each device acknowledgement is tracked, and doors relock automatically after relock_after seconds when set
+// @Tags         Emergency
+// @Accept       json
+// @Produce      json
+// @Param        request body ScopedEmergencyUnlockRequest true "Scoped unlock request parameters"
+// @Success      200  {object}  models.EmergencyUnlockSession
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /emergency/unlock [post]
+// @Security     BearerAuth
+func (c *EmergencyController) StartEmergencyUnlock() {
+	var req ScopedEmergencyUnlockRequest
+	if err := c.Ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrBind, "无效的请求参数: "+err.Error(), nil)
+		return
+	}
+
+	unlockService := c.unlockService()
+	session, err := unlockService.StartUnlock(services.UnlockRequest{
+		Reason:      req.Reason,
+		PropertyID:  req.PropertyID,
+		BuildingIDs: req.BuildingIDs,
+		DeviceIDs:   req.DeviceIDs,
+		RelockAfter: req.RelockAfter,
+		IsDrill:     req.IsDrill,
+		Operator:    c.alarmOperator(),
+	})
+	if err != nil {
+		c.failUnlock(err, "紧急解锁失败")
+		return
+	}
+
+	response.Success(c.Ctx, session)
+}
+
+// 21. GetUnlockSessions 获取紧急解锁记录
+// @Summary      Get Emergency Unlock Sessions
+// @Description  List emergency unlock sessions, optionally filtered by status
+// @Tags         Emergency
+// @Accept       json
+// @Produce      json
+// @Param        status query string false "会话状态: active, ended, expired"
+// @Param        page query int false "页码，默认为1"
+// @Param        page_size query int false "每页条数，默认为10"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      500  {object}  ErrorResponse
+// @Router       /emergency/unlocks [get]
+// @Security     BearerAuth
+func (c *EmergencyController) GetUnlockSessions() {
+	page, _ := strconv.Atoi(c.Ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.Ctx.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	unlockService := c.unlockService()
+	sessions, total, err := unlockService.GetUnlockSessions(c.Ctx.Query("status"), page, pageSize)
+	if err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrDatabase, "获取紧急解锁记录失败: "+err.Error(), nil)
+		return
+	}
+
+	response.Success(c.Ctx, gin.H{
+		"total":       total,
+		"page":        page,
+		"page_size":   pageSize,
+		"total_pages": (total + int64(pageSize) - 1) / int64(pageSize),
+		"data":        sessions,
+	})
+}
+
+// 22. GetUnlockSession 获取紧急解锁详情
+// @Summary      Get Emergency Unlock Session
+// @Description  Get an emergency unlock session with the unlock and relock acknowledgement of every device
+// @Tags         Emergency
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "解锁会话ID"
+// @Success      200  {object}  models.EmergencyUnlockSession
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /emergency/unlocks/{id} [get]
+// @Security     BearerAuth
+func (c *EmergencyController) GetUnlockSession() {
+	id, ok := c.parseUnlockSessionID()
+	if !ok {
+		return
+	}
+
+	unlockService := c.unlockService()
+	session, err := unlockService.GetUnlockSession(id)
+	if err != nil {
+		c.failUnlock(err, "获取紧急解锁详情失败")
+		return
+	}
+
+	response.Success(c.Ctx, session)
+}
+
+// 23. EndEmergencyUnlock 结束紧急解锁并重新上锁
+// @Summary      End Emergency Unlock
+// @Description  End an active emergency unlock session and send relock commands; devices still covered by another active session stay unlocked
+// @Tags         Emergency
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "解锁会话ID"
+// @Param        request body EndEmergencyUnlockRequest false "End request parameters"
+// @Success      200  {object}  models.EmergencyUnlockSession
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /emergency/unlocks/{id}/end [post]
+// @Security     BearerAuth
+func (c *EmergencyController) EndEmergencyUnlock() {
+	id, ok := c.parseUnlockSessionID()
+	if !ok {
+		return
+	}
+
+	var req EndEmergencyUnlockRequest
+	if c.Ctx.Request.ContentLength > 0 {
+		if err := c.Ctx.ShouldBindJSON(&req); err != nil {
+			response.FailWithMessage(c.Ctx, code.ErrBind, "无效的请求参数: "+err.Error(), nil)
+			return
+		}
+	}
+
+	unlockService := c.unlockService()
+	session, err := unlockService.EndUnlock(id, c.alarmOperator(), req.Reason)
+	if err != nil {
+		c.failUnlock(err, "结束紧急解锁失败")
+		return
+	}
+
+	response.Success(c.Ctx, session)
+}
+
+// parseUnlockSessionID 解析路径中的解锁会话ID
+func (c *EmergencyController) parseUnlockSessionID() (uint, bool) {
+	id, err := strconv.Atoi(c.Ctx.Param("id"))
+	if err != nil || id <= 0 {
+		response.FailWithMessage(c.Ctx, code.ErrValidation, "无效的解锁会话ID", nil)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// failUnlock 根据紧急解锁服务返回的错误类型输出响应
+func (c *EmergencyController) failUnlock(err error, message string) {
+	if failPropertyScope(c.Ctx, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, services.ErrUnlockSessionNotFound):
+		response.FailWithMessage(c.Ctx, code.ErrUnlockSessionNotFound, err.Error(), nil)
+	case errors.Is(err, services.ErrUnlockSessionEnded):
+		response.FailWithMessage(c.Ctx, code.ErrUnlockSessionEnded, err.Error(), nil)
+	case errors.Is(err, services.ErrNoDevicesInScope), errors.Is(err, services.ErrUnlockDeviceNotFound):
+		response.FailWithMessage(c.Ctx, code.ErrValidation, err.Error(), nil)
+	default:
+		response.FailWithMessage(c.Ctx, code.ErrDatabase, message+": "+err.Error(), nil)
+	}
+}
+
+// 24. GetDrillReport 获取应急演练报告
+// @Summary      Get Emergency Drill Report
+// @Description  Summarize drill alarms, help requests, notifications and unlocks in a time range, with acknowledgement, escalation and per-recipient read times
+// @Tags         Emergency
+// @Accept       json
+// @Produce      json
+// @Param        from query string false "开始时间，RFC3339或YYYY-MM-DD，默认为24小时前"
+// @Param        to query string false "结束时间，RFC3339或YYYY-MM-DD（含当天），默认为当前时间"
+// @Param        property_id query int false "物业ID"
+// @Success      200  {object}  services.DrillReport
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /emergency/drills/report [get]
+// @Security     BearerAuth
+func (c *EmergencyController) GetDrillReport() {
+	now := time.Now()
+	query := services.DrillReportQuery{
+		From: now.Add(-24 * time.Hour),
+		To:   now,
+	}
+
+	if from := c.Ctx.Query("from"); from != "" {
+		t, err := parseReportTime(from, false)
+		if err != nil {
+			response.FailWithMessage(c.Ctx, code.ErrValidation, "无效的开始时间", nil)
+			return
+		}
+		query.From = t
+	}
+	if to := c.Ctx.Query("to"); to != "" {
+		t, err := parseReportTime(to, true)
+		if err != nil {
+			response.FailWithMessage(c.Ctx, code.ErrValidation, "无效的结束时间", nil)
+			return
+		}
+		query.To = t
+	}
+	if query.From.After(query.To) {
+		response.FailWithMessage(c.Ctx, code.ErrValidation, "开始时间不能晚于结束时间", nil)
+		return
+	}
+	if id, err := strconv.Atoi(c.Ctx.Query("property_id")); err == nil && id > 0 {
+		propertyID := uint(id)
+		query.PropertyID = &propertyID
+	}
+	if propertyID := getCurrentPropertyID(c.Ctx); propertyID != nil {
+		if query.PropertyID != nil && *query.PropertyID != *propertyID {
+			response.FailWithMessage(c.Ctx, code.ErrPropertyScope, "只能查询本物业的演练报告", nil)
+			return
+		}
+		query.PropertyID = propertyID
+	}
+
+	drillService := c.Container.GetService("emergency_drill").(services.InterfaceEmergencyDrillService)
+	report, err := drillService.GetDrillReport(query)
+	if err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrDatabase, "生成演练报告失败: "+err.Error(), nil)
+		return
+	}
+
+	response.Success(c.Ctx, report)
+}
+
+// emergencyService 获取限定在当前用户所属物业内的紧急事件服务
+func (c *EmergencyController) emergencyService() services.InterfaceEmergencyService {
+	return c.Container.GetService("emergency").(services.InterfaceEmergencyService).WithPropertyScope(getCurrentPropertyID(c.Ctx))
+}
+
+// contactService 获取限定在当前用户所属物业内的紧急联系人服务
+func (c *EmergencyController) contactService() services.InterfaceEmergencyContactService {
+	return c.Container.GetService("emergency_contact").(services.InterfaceEmergencyContactService).WithPropertyScope(getCurrentPropertyID(c.Ctx))
+}
+
+// notificationService 获取限定在当前用户所属物业内的紧急通知服务
+func (c *EmergencyController) notificationService() services.InterfaceEmergencyNotificationService {
+	return c.Container.GetService("emergency_notification").(services.InterfaceEmergencyNotificationService).WithPropertyScope(getCurrentPropertyID(c.Ctx))
+}
+
+// unlockService 获取限定在当前用户所属物业内的紧急解锁服务
+func (c *EmergencyController) unlockService() services.InterfaceEmergencyUnlockService {
+	return c.Container.GetService("emergency_unlock").(services.InterfaceEmergencyUnlockService).WithPropertyScope(getCurrentPropertyID(c.Ctx))
+}
+
+// parseReportTime 解析报告时间参数，只有日期时结束时间取当天末尾
+func parseReportTime(value string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
 }
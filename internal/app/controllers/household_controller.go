@@ -2,7 +2,6 @@ package controllers
 
 import (
 	"ilock-http-service/internal/domain/models"
-	"ilock-http-service/internal/domain/services"
 	"ilock-http-service/internal/domain/services/container"
 	"ilock-http-service/internal/error/code"
 	"ilock-http-service/internal/error/response"
@@ -116,7 +115,7 @@ func (c *HouseholdController) GetHouseholds() {
 	}
 
 	// 获取户号服务
-	householdService := c.Container.GetService("household").(services.InterfaceHouseholdService)
+	householdService := scopedHouseholdService(c.Ctx, c.Container)
 
 	var households []models.Household
 	var total int64
@@ -165,7 +164,7 @@ func (c *HouseholdController) GetHousehold() {
 	}
 
 	// 获取户号服务
-	householdService := c.Container.GetService("household").(services.InterfaceHouseholdService)
+	householdService := scopedHouseholdService(c.Ctx, c.Container)
 	household, err := householdService.GetHouseholdByID(uint(householdID))
 	if err != nil {
 		response.NotFound(c.Ctx, "户号不存在: "+err.Error())
@@ -208,10 +207,10 @@ func (c *HouseholdController) CreateHousehold() {
 	}
 
 	// 获取户号服务
-	householdService := c.Container.GetService("household").(services.InterfaceHouseholdService)
+	householdService := scopedHouseholdService(c.Ctx, c.Container)
 
 	// 验证楼号是否存在
-	buildingService := c.Container.GetService("building").(services.InterfaceBuildingService)
+	buildingService := scopedBuildingService(c.Ctx, c.Container)
 	_, err := buildingService.GetBuildingByID(req.BuildingID)
 	if err != nil {
 		response.FailWithMessage(c.Ctx, code.ErrBind, "关联的楼号不存在: "+err.Error(), nil)
@@ -219,6 +218,9 @@ func (c *HouseholdController) CreateHousehold() {
 	}
 
 	if err := householdService.CreateHousehold(household); err != nil {
+		if failPropertyScope(c.Ctx, err) {
+			return
+		}
 		response.FailWithMessage(c.Ctx, code.ErrDatabase, "创建户号失败: "+err.Error(), nil)
 		return
 	}
@@ -262,7 +264,7 @@ func (c *HouseholdController) UpdateHousehold() {
 	}
 	if req.BuildingID > 0 {
 		// 验证楼号是否存在
-		buildingService := c.Container.GetService("building").(services.InterfaceBuildingService)
+		buildingService := scopedBuildingService(c.Ctx, c.Container)
 		_, err := buildingService.GetBuildingByID(req.BuildingID)
 		if err != nil {
 			response.FailWithMessage(c.Ctx, code.ErrBind, "关联的楼号不存在: "+err.Error(), nil)
@@ -275,9 +277,12 @@ func (c *HouseholdController) UpdateHousehold() {
 	}
 
 	// 获取户号服务
-	householdService := c.Container.GetService("household").(services.InterfaceHouseholdService)
+	householdService := scopedHouseholdService(c.Ctx, c.Container)
 	household, err := householdService.UpdateHousehold(uint(householdID), updates)
 	if err != nil {
+		if failPropertyScope(c.Ctx, err) {
+			return
+		}
 		response.FailWithMessage(c.Ctx, code.ErrDatabase, "更新户号失败: "+err.Error(), nil)
 		return
 	}
@@ -307,7 +312,7 @@ func (c *HouseholdController) DeleteHousehold() {
 	}
 
 	// 获取户号服务
-	householdService := c.Container.GetService("household").(services.InterfaceHouseholdService)
+	householdService := scopedHouseholdService(c.Ctx, c.Container)
 	if err := householdService.DeleteHousehold(uint(householdID)); err != nil {
 		response.FailWithMessage(c.Ctx, code.ErrDatabase, "删除户号失败: "+err.Error(), nil)
 		return
@@ -338,7 +343,7 @@ func (c *HouseholdController) GetHouseholdDevices() {
 	}
 
 	// 获取户号服务
-	householdService := c.Container.GetService("household").(services.InterfaceHouseholdService)
+	householdService := scopedHouseholdService(c.Ctx, c.Container)
 	devices, err := householdService.GetHouseholdDevices(uint(householdID))
 	if err != nil {
 		response.FailWithMessage(c.Ctx, code.ErrDatabase, "获取户号关联设备失败: "+err.Error(), nil)
@@ -370,7 +375,7 @@ func (c *HouseholdController) GetHouseholdResidents() {
 	}
 
 	// 获取户号服务
-	householdService := c.Container.GetService("household").(services.InterfaceHouseholdService)
+	householdService := scopedHouseholdService(c.Ctx, c.Container)
 	residents, err := householdService.GetHouseholdResidents(uint(householdID))
 	if err != nil {
 		response.FailWithMessage(c.Ctx, code.ErrDatabase, "获取户号下居民失败: "+err.Error(), nil)
@@ -409,10 +414,10 @@ func (c *HouseholdController) AssociateHouseholdWithDevice() {
 	}
 
 	// 获取户号服务
-	householdService := c.Container.GetService("household").(services.InterfaceHouseholdService)
+	householdService := scopedHouseholdService(c.Ctx, c.Container)
 
 	// 验证设备是否存在
-	deviceService := c.Container.GetService("device").(services.InterfaceDeviceService)
+	deviceService := scopedDeviceService(c.Ctx, c.Container)
 	_, err = deviceService.GetDeviceByID(req.DeviceID)
 	if err != nil {
 		response.FailWithMessage(c.Ctx, code.ErrBind, "设备不存在: "+err.Error(), nil)
@@ -420,6 +425,9 @@ func (c *HouseholdController) AssociateHouseholdWithDevice() {
 	}
 
 	if err := householdService.AssociateHouseholdWithDevice(uint(householdID), req.DeviceID); err != nil {
+		if failPropertyScope(c.Ctx, err) {
+			return
+		}
 		response.FailWithMessage(c.Ctx, code.ErrDatabase, "关联户号与设备失败: "+err.Error(), nil)
 		return
 	}
@@ -459,7 +467,7 @@ func (c *HouseholdController) RemoveHouseholdDeviceAssociation() {
 	}
 
 	// 获取户号服务
-	householdService := c.Container.GetService("household").(services.InterfaceHouseholdService)
+	householdService := scopedHouseholdService(c.Ctx, c.Container)
 	if err := householdService.RemoveHouseholdDeviceAssociation(uint(householdID), uint(deviceID)); err != nil {
 		response.FailWithMessage(c.Ctx, code.ErrDatabase, "解除户号与设备关联失败: "+err.Error(), nil)
 		return
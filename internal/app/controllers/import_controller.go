@@ -0,0 +1,161 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"ilock-http-service/internal/domain/services"
+	"ilock-http-service/internal/domain/services/container"
+	"ilock-http-service/internal/error/code"
+	"ilock-http-service/internal/error/response"
+	"ilock-http-service/internal/infrastructure/spreadsheet"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// importMaxFileSize 导入文件的最大字节数
+const importMaxFileSize = 10 << 20
+
+// InterfaceImportController 定义批量导入控制器接口
+type InterfaceImportController interface {
+	ImportBuildings()
+	ImportHouseholds()
+	ImportResidents()
+}
+
+// ImportController 处理楼号、户号和居民的批量导入请求
+type ImportController struct {
+	Ctx       *gin.Context
+	Container *container.ServiceContainer
+}
+
+// NewImportController 创建一个新的批量导入控制器
+func NewImportController(ctx *gin.Context, container *container.ServiceContainer) *ImportController {
+	return &ImportController{
+		Ctx:       ctx,
+		Container: container,
+	}
+}
+
+// HandleImportFunc 返回一个处理批量导入请求的Gin处理函数
+func HandleImportFunc(container *container.ServiceContainer, method string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		controller := NewImportController(ctx, container)
+
+		switch method {
+		case "importBuildings":
+			controller.ImportBuildings()
+		case "importHouseholds":
+			controller.ImportHouseholds()
+		case "importResidents":
+			controller.ImportResidents()
+		default:
+			response.FailWithMessage(ctx, code.ErrBind, "无效的方法", nil)
+		}
+	}
+}
+
+// 1. ImportBuildings 批量导入楼号
+// @Summary 批量导入楼号
+// @Description 上传CSV或XLSX文件批量创建楼号，第一行为表头：building_code(必填)、building_name(必填)、address、status、property_id。所有行在一个事务中创建，任意一行失败时不写入任何数据；dry_run=true 时只校验不写入
+// @Tags Import
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param file formData file true "CSV或XLSX文件，最大10MB"
+// @Param dry_run query bool false "为true时只校验不写入"
+// @Success 200 {object} services.ImportReport
+// @Failure 400 {object} ErrorResponse "文件无效，或有数据行校验失败（data 为逐行的错误报告）"
+// @Router /import/buildings [post]
+func (c *ImportController) ImportBuildings() {
+	c.importFile(services.ImportBuildings)
+}
+
+// 2. ImportHouseholds 批量导入户号
+// @Summary 批量导入户号
+// @Description 上传CSV或XLSX文件批量创建户号，第一行为表头：building_code(必填，楼号必须已存在)、household_number(必填，如 1-1-101)、status。所有行在一个事务中创建，任意一行失败时不写入任何数据；dry_run=true 时只校验不写入
+// @Tags Import
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param file formData file true "CSV或XLSX文件，最大10MB"
+// @Param dry_run query bool false "为true时只校验不写入"
+// @Success 200 {object} services.ImportReport
+// @Failure 400 {object} ErrorResponse "文件无效，或有数据行校验失败（data 为逐行的错误报告）"
+// @Router /import/households [post]
+func (c *ImportController) ImportHouseholds() {
+	c.importFile(services.ImportHouseholds)
+}
+
+// 3. ImportResidents 批量导入居民
+// @Summary 批量导入居民
+// @Description 上传CSV或XLSX文件批量创建居民，第一行为表头：building_code(必填)、household_number(必填，户号必须已存在)、name(必填)、phone(必填)、email。所有行在一个事务中创建，任意一行失败时不写入任何数据；dry_run=true 时只校验不写入
+// @Tags Import
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param file formData file true "CSV或XLSX文件，最大10MB"
+// @Param dry_run query bool false "为true时只校验不写入"
+// @Success 200 {object} services.ImportReport
+// @Failure 400 {object} ErrorResponse "文件无效，或有数据行校验失败（data 为逐行的错误报告）"
+// @Router /import/residents [post]
+func (c *ImportController) ImportResidents() {
+	c.importFile(services.ImportResidents)
+}
+
+// importFile 读取上传的文件并导入，数据行校验失败时在 data 中返回逐行的错误报告
+func (c *ImportController) importFile(kind string) {
+	dryRun := false
+	if value := c.Ctx.Query("dry_run"); value != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			response.FailWithMessage(c.Ctx, code.ErrValidation, "dry_run 只能为 true 或 false", nil)
+			return
+		}
+	}
+
+	header, err := c.Ctx.FormFile("file")
+	if err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrImportFileInvalid, "请上传导入文件", nil)
+		return
+	}
+	if header.Size > importMaxFileSize {
+		response.FailWithMessage(c.Ctx, code.ErrImportFileInvalid, fmt.Sprintf("导入文件不能超过%dMB", importMaxFileSize>>20), nil)
+		return
+	}
+	format, err := spreadsheet.FormatFromFilename(header.Filename)
+	if err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrImportFileInvalid, err.Error(), nil)
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrImportFileInvalid, "读取导入文件失败: "+err.Error(), nil)
+		return
+	}
+	defer file.Close()
+
+	rows, err := spreadsheet.ReadRows(file, header.Size, format)
+	if err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrImportFileInvalid, err.Error(), nil)
+		return
+	}
+
+	service := c.Container.GetService("import").(services.InterfaceImportService).WithPropertyScope(getCurrentPropertyID(c.Ctx))
+	report, err := service.Import(kind, rows, dryRun)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrImportRowsInvalid):
+			response.FailWithMessage(c.Ctx, code.ErrImportRowsInvalid, err.Error(), report)
+		case errors.Is(err, services.ErrImportHeaderInvalid), errors.Is(err, services.ErrImportEmpty),
+			errors.Is(err, services.ErrImportTooManyRows):
+			response.FailWithMessage(c.Ctx, code.ErrImportFileInvalid, err.Error(), nil)
+		default:
+			response.FailWithMessage(c.Ctx, code.ErrDatabase, "导入失败: "+err.Error(), nil)
+		}
+		return
+	}
+
+	response.Success(c.Ctx, report)
+}
@@ -1,19 +1,30 @@
 package controllers
 
 import (
-	"ilock-http-service/internal/domain/models"
+	"errors"
 	"ilock-http-service/internal/domain/services"
 	"ilock-http-service/internal/domain/services/container"
 	"ilock-http-service/internal/error/code"
 	"ilock-http-service/internal/error/response"
+	"math"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/golang-jwt/jwt/v4"
 )
 
 // InterfaceJWTController 定义认证控制器接口
 type InterfaceJWTController interface {
 	Login()
+	VerifyTwoFactor()
+	RequestLoginCode()
+	LoginWithCode()
+	Refresh()
+	Logout()
+	LogoutAll()
+	RevokeTokens()
+	UnlockAccount()
+	GetLockouts()
 }
 
 // JWTController 处理身份验证请求
@@ -45,11 +56,14 @@ type LoginResponse struct {
 
 // LoginData 表示登录成功后返回的数据
 type LoginData struct {
-	Token     string `json:"token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
-	UserID    uint   `json:"user_id" example:"1"`
-	Role      string `json:"role" example:"admin"`
-	Username  string `json:"username" example:"admin"`
-	CreatedAt string `json:"created_at" example:"2023-01-01T00:00:00Z"`
+	Token            string `json:"token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	RefreshToken     string `json:"refresh_token" example:"9f86d081884c7d659a2feaa0c55ad015..."`
+	ExpiresIn        int    `json:"expires_in" example:"900"`
+	RefreshExpiresAt string `json:"refresh_expires_at" example:"2023-01-31T00:00:00Z"`
+	UserID           uint   `json:"user_id" example:"1"`
+	Role             string `json:"role" example:"admin"`
+	Username         string `json:"username" example:"admin"`
+	CreatedAt        string `json:"created_at" example:"2023-01-01T00:00:00Z"`
 }
 
 // ErrorResponse 表示错误响应
@@ -67,6 +81,24 @@ func HandleJWTFunc(container *container.ServiceContainer, method string) gin.Han
 		switch method {
 		case "login":
 			controller.Login()
+		case "verifyTwoFactor":
+			controller.VerifyTwoFactor()
+		case "requestLoginCode":
+			controller.RequestLoginCode()
+		case "loginWithCode":
+			controller.LoginWithCode()
+		case "refresh":
+			controller.Refresh()
+		case "logout":
+			controller.Logout()
+		case "logoutAll":
+			controller.LogoutAll()
+		case "revokeTokens":
+			controller.RevokeTokens()
+		case "unlockAccount":
+			controller.UnlockAccount()
+		case "getLockouts":
+			controller.GetLockouts()
 		default:
 			response.FailWithMessage(ctx, code.ErrBind, "无效的方法", nil)
 		}
@@ -75,7 +107,7 @@ func HandleJWTFunc(container *container.ServiceContainer, method string) gin.Han
 
 // Login 处理用户登录
 // @Summary      User Login
-// @Description  Process user login and return JWT token with different permissions based on user role
+// @Description  Process user login and return JWT token with different permissions based on user role. Admins with two-factor authentication enabled receive two_factor_required and a two_factor_token instead, submit the code to /auth/2fa/verify
 // @Tags         Auth
 // @Accept       json
 // @Produce      json
@@ -83,6 +115,8 @@ func HandleJWTFunc(container *container.ServiceContainer, method string) gin.Han
 // @Success      200  {object}  LoginResponse{data=LoginData}  "Success response with token"
 // @Failure      400  {object}  ErrorResponse  "Bad request"
 // @Failure      401  {object}  ErrorResponse  "Unauthorized"
+// @Failure      403  {object}  ErrorResponse  "Account locked or disabled"
+// @Failure      429  {object}  ErrorResponse  "Too many failed attempts, retry after data.retry_after seconds"
 // @Failure      500  {object}  ErrorResponse  "Internal server error"
 // @Router       /auth/login [post]
 func (c *JWTController) Login() {
@@ -92,119 +126,381 @@ func (c *JWTController) Login() {
 		return
 	}
 
-	// 获取数据库连接
-	db := c.Container.GetDB()
-	// 获取JWT服务
-	jwtService := c.Container.GetService("jwt").(*services.JWTService)
-
-	// 尝试查找管理员用户
-	var admin models.Admin
-	if err := db.Where("username = ?", req.Username).First(&admin).Error; err == nil {
-		// 比较密码
-		if err := bcrypt.CompareHashAndPassword([]byte(admin.Password), []byte(req.Password)); err == nil {
-			// 生成管理员令牌
-			token, err := jwtService.GenerateToken(admin.ID, "admin", nil, nil)
-			if err != nil {
-				response.FailWithMessage(c.Ctx, code.ErrDatabase, "生成令牌失败", nil)
-				return
-			}
-
-			response.Success(c.Ctx, gin.H{
-				"token":      token,
-				"user_id":    admin.ID,
-				"role":       "admin",
-				"username":   admin.Username,
-				"created_at": admin.CreatedAt,
-			})
-			return
-		}
+	result, err := c.jwtService().Login(req.Username, req.Password, c.clientInfo())
+	if err != nil {
+		c.failLogin(err)
+		return
 	}
 
-	// 尝试查找物业人员
-	var staff models.PropertyStaff
-	if err := db.Where("username = ?", req.Username).First(&staff).Error; err == nil {
-		// 获取密码字段
-		var password string
+	response.Success(c.Ctx, result)
+}
 
-		// 使用原始查询获取所需字段，移除对不存在的property_id的引用
-		row := db.Table("property_staffs").
-			Select("password").
-			Where("id = ?", staff.ID).
-			Row()
+// TwoFactorLoginRequest 表示提交登录两步验证码的请求
+type TwoFactorLoginRequest struct {
+	TwoFactorToken string `json:"two_factor_token" binding:"required" example:"5d41402abc4b2a76b9719d911017c592..."`
+	Code           string `json:"code" binding:"required" example:"123456"` // 身份验证器App中的验证码或恢复码
+}
 
-		if err := row.Scan(&password); err != nil {
-			response.FailWithMessage(c.Ctx, code.ErrDatabase, "数据库错误: "+err.Error(), nil)
-			return
-		}
+// VerifyTwoFactor 提交登录的两步验证码
+// @Summary      Verify Two-Factor Login
+// @Description  Complete the login of an admin with two-factor authentication by submitting an authenticator code or a recovery code with the two_factor_token returned by /auth/login. The token expires after a few minutes or too many wrong codes
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        request body TwoFactorLoginRequest true "Two-factor token and code"
+// @Success      200  {object}  LoginResponse{data=LoginData}
+// @Failure      400  {object}  ErrorResponse
+// @Failure      401  {object}  ErrorResponse
+// @Failure      429  {object}  ErrorResponse
+// @Router       /auth/2fa/verify [post]
+func (c *JWTController) VerifyTwoFactor() {
+	var req TwoFactorLoginRequest
+	if err := c.Ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrBind, "无效的请求参数", nil)
+		return
+	}
 
-		// 比较密码
-		if err := bcrypt.CompareHashAndPassword([]byte(password), []byte(req.Password)); err == nil {
-			// 生成物业人员令牌，不再传递propertyID
-			token, err := jwtService.GenerateToken(staff.ID, "staff", nil, nil)
-			if err != nil {
-				response.FailWithMessage(c.Ctx, code.ErrDatabase, "生成令牌失败", nil)
-				return
-			}
-
-			// 获取用户名
-			var username string
-			db.Table("property_staffs").
-				Select("username").
-				Where("id = ?", staff.ID).
-				Row().
-				Scan(&username)
-
-			response.Success(c.Ctx, gin.H{
-				"token":      token,
-				"user_id":    staff.ID,
-				"role":       "staff",
-				"username":   username,
-				"created_at": staff.CreatedAt,
-			})
-			return
-		}
+	result, err := c.jwtService().VerifyTwoFactor(req.TwoFactorToken, req.Code, c.clientInfo())
+	if err != nil {
+		c.failLogin(err)
+		return
+	}
+
+	response.Success(c.Ctx, result)
+}
+
+// LoginCodeRequest 表示发送短信登录验证码的请求
+type LoginCodeRequest struct {
+	Phone string `json:"phone" binding:"required" example:"13800138000"`
+}
+
+// CodeLoginRequest 表示使用短信验证码登录的请求
+type CodeLoginRequest struct {
+	Phone string `json:"phone" binding:"required" example:"13800138000"`
+	Code  string `json:"code" binding:"required" example:"123456"`
+}
+
+// RequestLoginCode 发送短信登录验证码
+// @Summary      Request SMS Login Code
+// @Description  Send a one-time login code by SMS to the phone number of a resident. The response is the same whether or not a resident uses the phone number. Each phone number is limited by a cooldown between codes and a daily cap
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        request body LoginCodeRequest true "Resident phone number"
+// @Success      200  {object}  LoginResponse
+// @Failure      400  {object}  ErrorResponse  "SMS login is not configured"
+// @Failure      429  {object}  ErrorResponse  "Too many codes requested for the phone number"
+// @Router       /auth/sms/request [post]
+func (c *JWTController) RequestLoginCode() {
+	var req LoginCodeRequest
+	if err := c.Ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrBind, "无效的请求参数", nil)
+		return
 	}
 
-	// 尝试查找普通居民
-	var resident models.Resident
-	if err := db.Where("phone = ?", req.Username).First(&resident).Error; err == nil {
-		// 获取密码字段
-		var password string
-		var name string
-		var phone string
+	if err := c.smsLoginService().RequestCode(req.Phone); err != nil {
+		c.failLogin(err)
+		return
+	}
 
-		// 使用原始查询获取所需字段
-		row := db.Table("residents").
-			Select("password, name, phone").
-			Where("id = ?", resident.ID).
-			Row()
+	response.Success(c.Ctx, nil)
+}
 
-		if err := row.Scan(&password, &name, &phone); err != nil {
-			response.FailWithMessage(c.Ctx, code.ErrDatabase, "数据库错误: "+err.Error(), nil)
+// LoginWithCode 使用短信验证码登录
+// @Summary      SMS Code Login
+// @Description  Log in as a resident with the phone number and the code sent by /auth/sms/request. Returns the same tokens as /auth/login. The code can be used once and is discarded after too many wrong attempts; wrong codes count towards the login lockout
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        request body CodeLoginRequest true "Phone number and code"
+// @Success      200  {object}  LoginResponse{data=LoginData}
+// @Failure      400  {object}  ErrorResponse
+// @Failure      401  {object}  ErrorResponse  "Code invalid or expired"
+// @Failure      403  {object}  ErrorResponse  "Account locked"
+// @Failure      429  {object}  ErrorResponse  "Too many failed attempts, retry after data.retry_after seconds"
+// @Router       /auth/sms/login [post]
+func (c *JWTController) LoginWithCode() {
+	var req CodeLoginRequest
+	if err := c.Ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrBind, "无效的请求参数", nil)
+		return
+	}
+
+	result, err := c.smsLoginService().VerifyCode(req.Phone, req.Code, c.clientInfo())
+	if err != nil {
+		c.failLogin(err)
+		return
+	}
+
+	response.Success(c.Ctx, result)
+}
+
+// RefreshRequest 表示刷新令牌的请求
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required" example:"9f86d081884c7d659a2feaa0c55ad015..."`
+}
+
+// RevokeTokensRequest 表示注销指定账号所有会话的请求
+type RevokeTokensRequest struct {
+	SubjectType string `json:"subject_type" binding:"required,oneof=admin staff user" example:"staff"`
+	SubjectID   uint   `json:"subject_id" binding:"required" example:"3"`
+}
+
+// UnlockAccountRequest 表示解锁账号的请求
+type UnlockAccountRequest struct {
+	SubjectType string `json:"subject_type" binding:"required,oneof=admin staff user" example:"staff"`
+	SubjectID   uint   `json:"subject_id" binding:"required" example:"3"`
+}
+
+// Refresh 使用刷新令牌换取新令牌
+// @Summary      Refresh Token
+// @Description  Exchange a refresh token for a new access token and a new refresh token; the old refresh token becomes invalid
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        request body RefreshRequest true "Refresh token"
+// @Success      200  {object}  LoginResponse{data=services.TokenPair}
+// @Failure      400  {object}  ErrorResponse  "Bad request"
+// @Failure      401  {object}  ErrorResponse  "Refresh token invalid, expired or reused"
+// @Router       /auth/refresh [post]
+func (c *JWTController) Refresh() {
+	var req RefreshRequest
+	if err := c.Ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrBind, "无效的请求参数", nil)
+		return
+	}
+
+	tokens, err := c.jwtService().RefreshTokens(req.RefreshToken, c.clientInfo())
+	if err != nil {
+		if errors.Is(err, services.ErrRefreshTokenInvalid) || errors.Is(err, services.ErrRefreshTokenReused) {
+			response.FailWithMessage(c.Ctx, code.ErrTokenInvalid, err.Error(), nil)
 			return
 		}
+		response.FailWithMessage(c.Ctx, code.ErrDatabase, "刷新令牌失败: "+err.Error(), nil)
+		return
+	}
 
-		// 比较密码
-		if err := bcrypt.CompareHashAndPassword([]byte(password), []byte(req.Password)); err == nil {
-			// 生成居民令牌
-			token, err := jwtService.GenerateToken(resident.ID, "user", nil, nil)
-			if err != nil {
-				response.FailWithMessage(c.Ctx, code.ErrDatabase, "生成令牌失败", nil)
-				return
-			}
-
-			response.Success(c.Ctx, gin.H{
-				"token":      token,
-				"user_id":    resident.ID,
-				"role":       "user",
-				"username":   name,
-				"phone":      phone,
-				"created_at": resident.CreatedAt,
-			})
-			return
+	response.Success(c.Ctx, tokens)
+}
+
+// Logout 注销当前登录会话
+// @Summary      Logout
+// @Description  Revoke the current access token and the refresh tokens of its login session
+// @Tags         Auth
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  LoginResponse
+// @Failure      401  {object}  ErrorResponse
+// @Router       /auth/logout [post]
+func (c *JWTController) Logout() {
+	claims, ok := c.currentClaims()
+	if !ok {
+		return
+	}
+
+	if err := c.jwtService().Logout(claims); err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrDatabase, "注销失败: "+err.Error(), nil)
+		return
+	}
+
+	response.Success(c.Ctx, nil)
+}
+
+// LogoutAll 注销当前账号的所有登录会话
+// @Summary      Logout All Sessions
+// @Description  Revoke every refresh token of the current account and all access tokens issued before now
+// @Tags         Auth
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  LoginResponse
+// @Failure      401  {object}  ErrorResponse
+// @Router       /auth/logout-all [post]
+func (c *JWTController) LogoutAll() {
+	if err := c.jwtService().RevokeAllTokens(getCurrentRole(c.Ctx), getCurrentUserID(c.Ctx)); err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrDatabase, "注销失败: "+err.Error(), nil)
+		return
+	}
+
+	response.Success(c.Ctx, nil)
+}
+
+// RevokeTokens 注销指定账号的所有登录会话
+// @Summary      Revoke Account Sessions
+// @Description  Platform admins revoke every session of an account, e.g. when a token is stolen
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body UnlockAccountRequest true "Account to unlock"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Router       /auth/revoke [post]
+func (c *JWTController) RevokeTokens() {
+	if getCurrentPropertyID(c.Ctx) != nil {
+		response.FailWithMessage(c.Ctx, code.ErrPropertyScope, "只有平台管理员可以注销其他账号的会话", nil)
+		return
+	}
+
+	var req RevokeTokensRequest
+	if err := c.Ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrBind, "无效的请求参数: "+err.Error(), nil)
+		return
+	}
+
+	if err := c.jwtService().RevokeAllTokens(req.SubjectType, req.SubjectID); err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrDatabase, "注销失败: "+err.Error(), nil)
+		return
+	}
+
+	response.Success(c.Ctx, nil)
+}
+
+// UnlockAccount 解锁因登录失败次数过多被锁定的账号
+// @Summary      Unlock Account
+// @Description  Platform admins end the login lockout of an account before it expires; admins and staff manually set to "locked" are restored to "active"
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body RevokeTokensRequest true "Account"
+// @Success      200  {object}  LoginResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Router       /auth/unlock [post]
+func (c *JWTController) UnlockAccount() {
+	if getCurrentPropertyID(c.Ctx) != nil {
+		response.FailWithMessage(c.Ctx, code.ErrPropertyScope, "只有平台管理员可以解锁账号", nil)
+		return
+	}
+
+	var req UnlockAccountRequest
+	if err := c.Ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrBind, "无效的请求参数: "+err.Error(), nil)
+		return
+	}
+
+	account := services.LoginAccount{SubjectType: req.SubjectType, SubjectID: req.SubjectID}
+	if err := c.loginGuard().Unlock(account, getCurrentUserID(c.Ctx), c.Ctx.ClientIP()); err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrDatabase, "解锁失败: "+err.Error(), nil)
+		return
+	}
+
+	response.Success(c.Ctx, nil)
+}
+
+// GetLockouts 获取账号锁定记录
+// @Summary      List Account Lockouts
+// @Description  Platform admins list login lockouts, newest first; active=true returns only lockouts still in effect
+// @Tags         Auth
+// @Produce      json
+// @Security     BearerAuth
+// @Param        active query bool false "Only lockouts still in effect"
+// @Param        page query int false "Page number, default 1"
+// @Param        page_size query int false "Page size, default 10"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      403  {object}  ErrorResponse
+// @Router       /auth/lockouts [get]
+func (c *JWTController) GetLockouts() {
+	if getCurrentPropertyID(c.Ctx) != nil {
+		response.FailWithMessage(c.Ctx, code.ErrPropertyScope, "只有平台管理员可以查看账号锁定记录", nil)
+		return
+	}
+
+	page, _ := strconv.Atoi(c.Ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.Ctx.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	lockouts, total, err := c.loginGuard().GetLockouts(c.Ctx.Query("active") == "true", page, pageSize)
+	if err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrDatabase, "获取账号锁定记录失败: "+err.Error(), nil)
+		return
+	}
+
+	response.Success(c.Ctx, gin.H{
+		"total":       total,
+		"page":        page,
+		"page_size":   pageSize,
+		"total_pages": (total + int64(pageSize) - 1) / int64(pageSize),
+		"data":        lockouts,
+	})
+}
+
+// failLogin 将登录错误映射为响应错误码，被限制时通过 Retry-After 和 data.retry_after 返回可以重试的秒数
+func (c *JWTController) failLogin(err error) {
+	var blocked *services.LoginBlockedError
+	if errors.As(err, &blocked) {
+		retryAfter := int(math.Ceil(blocked.RetryAfter.Seconds()))
+		c.Ctx.Header("Retry-After", strconv.Itoa(retryAfter))
+
+		errorCode := code.ErrLoginThrottled
+		switch {
+		case errors.Is(err, services.ErrAccountLocked):
+			errorCode = code.ErrAccountLocked
+		case errors.Is(err, services.ErrLoginIPBlocked):
+			errorCode = code.ErrTooManyRequests
 		}
+		response.FailWithMessage(c.Ctx, errorCode, err.Error(), gin.H{"retry_after": retryAfter})
+		return
 	}
 
-	// 用户名或密码无效
-	response.Unauthorized(c.Ctx)
+	switch {
+	case errors.Is(err, services.ErrInvalidCredentials):
+		// 用户名或密码无效
+		response.Unauthorized(c.Ctx)
+	case errors.Is(err, services.ErrAccountDisabled):
+		response.FailWithMessage(c.Ctx, code.ErrAccountDisabled, err.Error(), nil)
+	case errors.Is(err, services.ErrTwoFactorCodeInvalid):
+		response.FailWithMessage(c.Ctx, code.ErrTwoFactorCodeInvalid, err.Error(), nil)
+	case errors.Is(err, services.ErrTwoFactorChallengeInvalid):
+		response.FailWithMessage(c.Ctx, code.ErrTwoFactorExpired, err.Error(), nil)
+	case errors.Is(err, services.ErrLoginCodeInvalid):
+		response.FailWithMessage(c.Ctx, code.ErrLoginCodeInvalid, err.Error(), nil)
+	case errors.Is(err, services.ErrLoginCodeTooFrequent):
+		response.FailWithMessage(c.Ctx, code.ErrTooManyRequests, err.Error(), nil)
+	case errors.Is(err, services.ErrSMSLoginUnavailable):
+		response.FailWithMessage(c.Ctx, code.ErrSMSLoginUnavailable, err.Error(), nil)
+	default:
+		response.FailWithMessage(c.Ctx, code.ErrDatabase, "登录失败: "+err.Error(), nil)
+	}
+}
+
+// loginGuard 获取登录防暴力破解服务
+func (c *JWTController) loginGuard() services.InterfaceLoginGuardService {
+	return c.Container.GetService("login_guard").(services.InterfaceLoginGuardService)
+}
+
+// smsLoginService 获取短信验证码登录服务
+func (c *JWTController) smsLoginService() services.InterfaceSMSLoginService {
+	return c.Container.GetService("sms_login").(services.InterfaceSMSLoginService)
+}
+
+// jwtService 获取JWT服务
+func (c *JWTController) jwtService() services.InterfaceJWTService {
+	return c.Container.GetService("jwt").(services.InterfaceJWTService)
+}
+
+// clientInfo 获取签发令牌时记录的客户端信息，IP同时用于登录失败计数和IP封禁；
+// 路由只信任 TRUSTED_PROXIES 中代理转发的 X-Forwarded-For，客户端无法通过请求头伪造该IP
+func (c *JWTController) clientInfo() services.ClientInfo {
+	return services.ClientInfo{
+		UserAgent: c.Ctx.Request.UserAgent(),
+		IP:        c.Ctx.ClientIP(),
+	}
+}
+
+// currentClaims 获取认证中间件写入的令牌声明
+func (c *JWTController) currentClaims() (jwt.MapClaims, bool) {
+	value, exists := c.Ctx.Get("claims")
+	claims, ok := value.(jwt.MapClaims)
+	if !exists || !ok {
+		response.Unauthorized(c.Ctx)
+		return nil, false
+	}
+	return claims, true
 }
@@ -0,0 +1,480 @@
+package controllers
+
+import (
+	"errors"
+	"ilock-http-service/internal/domain/models"
+	"ilock-http-service/internal/domain/services"
+	"ilock-http-service/internal/domain/services/container"
+	"ilock-http-service/internal/error/code"
+	"ilock-http-service/internal/error/response"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// InterfaceMeController 定义居民自助控制器接口
+type InterfaceMeController interface {
+	GetProfile()
+	UpdateProfile()
+	ChangePassword()
+	GetHousehold()
+	GetCallRecords()
+	GetDevices()
+	GetPasscodes()
+	CreatePasscode()
+	RevokePasscode()
+	GetInbox()
+	MarkInboxRead()
+}
+
+// MeController 处理居民本人相关的请求，数据限定在调用者本人及其所在户号
+type MeController struct {
+	Ctx       *gin.Context
+	Container *container.ServiceContainer
+}
+
+// NewMeController 创建一个新的居民自助控制器
+func NewMeController(ctx *gin.Context, container *container.ServiceContainer) *MeController {
+	return &MeController{
+		Ctx:       ctx,
+		Container: container,
+	}
+}
+
+// UpdateProfileRequest 表示更新本人资料的请求，未提供的字段保持不变
+type UpdateProfileRequest struct {
+	Name  string `json:"name" example:"张三"`
+	Email string `json:"email" binding:"omitempty,email" example:"zhangsan@example.com"`
+	Phone string `json:"phone" example:"13800138000"`
+}
+
+// ChangePasswordRequest 表示修改密码的请求
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password" example:"OldPassword@123"` // 尚未设置密码时可为空
+	NewPassword string `json:"new_password" binding:"required,min=6" example:"NewPassword@123"`
+}
+
+// CreatePasscodeRequest 表示生成通行码的请求
+type CreatePasscodeRequest struct {
+	Label      string     `json:"label" example:"快递"`
+	ValidFrom  *time.Time `json:"valid_from" example:"2023-07-01T09:00:00+08:00"`  // 默认立即生效
+	ValidUntil *time.Time `json:"valid_until" example:"2023-07-01T18:00:00+08:00"` // 默认24小时后失效，最长30天
+	MaxUses    int        `json:"max_uses" example:"1"`                            // 0表示不限次数
+}
+
+// HandleMeFunc 返回一个处理居民自助请求的Gin处理函数
+func HandleMeFunc(container *container.ServiceContainer, method string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		controller := NewMeController(ctx, container)
+
+		// 自助接口按居民ID查询数据，只对居民账号开放
+		if getCurrentRole(ctx) != "user" {
+			response.FailWithMessage(ctx, code.ErrPropertyScope, "仅居民账号可以使用该接口", nil)
+			return
+		}
+
+		switch method {
+		case "getProfile":
+			controller.GetProfile()
+		case "updateProfile":
+			controller.UpdateProfile()
+		case "changePassword":
+			controller.ChangePassword()
+		case "getHousehold":
+			controller.GetHousehold()
+		case "getCallRecords":
+			controller.GetCallRecords()
+		case "getDevices":
+			controller.GetDevices()
+		case "getPasscodes":
+			controller.GetPasscodes()
+		case "createPasscode":
+			controller.CreatePasscode()
+		case "revokePasscode":
+			controller.RevokePasscode()
+		case "getInbox":
+			controller.GetInbox()
+		case "markInboxRead":
+			controller.MarkInboxRead()
+		default:
+			response.FailWithMessage(ctx, code.ErrBind, "无效的方法", nil)
+		}
+	}
+}
+
+// 1. GetProfile 获取本人资料
+// @Summary 获取本人资料
+// @Description 获取当前居民的资料及所在户号、楼号
+// @Tags Me
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.Resident
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /me [get]
+func (c *MeController) GetProfile() {
+	resident, err := c.portalService().GetProfile(getCurrentUserID(c.Ctx))
+	if err != nil {
+		c.failMe(err, "获取资料失败")
+		return
+	}
+
+	response.Success(c.Ctx, resident)
+}
+
+// 2. UpdateProfile 更新本人资料
+// @Summary 更新本人资料
+// @Description 更新姓名、邮箱和手机号，手机号即登录账号
+// @Tags Me
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body UpdateProfileRequest true "资料"
+// @Success 200 {object} models.Resident
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /me [put]
+func (c *MeController) UpdateProfile() {
+	var req UpdateProfileRequest
+	if err := c.Ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrBind, "无效的请求参数: "+err.Error(), nil)
+		return
+	}
+
+	updates := make(map[string]interface{})
+	if req.Name != "" {
+		updates["name"] = strings.TrimSpace(req.Name)
+	}
+	if req.Email != "" {
+		updates["email"] = strings.TrimSpace(req.Email)
+	}
+	if req.Phone != "" {
+		updates["phone"] = strings.TrimSpace(req.Phone)
+	}
+
+	resident, err := c.portalService().UpdateProfile(getCurrentUserID(c.Ctx), updates)
+	if err != nil {
+		c.failMe(err, "更新资料失败")
+		return
+	}
+
+	response.Success(c.Ctx, resident)
+}
+
+// 3. ChangePassword 修改本人密码
+// @Summary 修改密码
+// @Description 校验原密码后设置新密码，尚未设置过密码时无需原密码；修改后本人的所有登录会话被注销，需要重新登录
+// @Tags Me
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body ChangePasswordRequest true "密码"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /me/password [put]
+func (c *MeController) ChangePassword() {
+	var req ChangePasswordRequest
+	if err := c.Ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrBind, "无效的请求参数: "+err.Error(), nil)
+		return
+	}
+
+	// 与 /api/auth/password 使用同一个密码服务，修改后注销本人的所有会话
+	userID := getCurrentUserID(c.Ctx)
+	account := services.LoginAccount{SubjectType: "user", SubjectID: userID}
+	if err := c.passwordService().ChangePassword(account, req.OldPassword, req.NewPassword); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = services.ErrResidentNotFound
+		}
+		c.failMe(err, "修改密码失败")
+		return
+	}
+
+	revokeAccountTokens(c.Container, "user", userID)
+	response.Success(c.Ctx, nil)
+}
+
+// 4. GetHousehold 获取本户信息
+// @Summary 获取本户信息
+// @Description 获取所在户号、楼号和同户成员
+// @Tags Me
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.Household
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /me/household [get]
+func (c *MeController) GetHousehold() {
+	household, err := c.portalService().GetHousehold(getCurrentUserID(c.Ctx))
+	if err != nil {
+		c.failMe(err, "获取户号信息失败")
+		return
+	}
+
+	response.Success(c.Ctx, household)
+}
+
+// 5. GetCallRecords 获取本人通话记录
+// @Summary 获取本人通话记录
+// @Description 分页获取当前居民的通话记录，按时间倒序
+// @Tags Me
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "页码，默认为1"
+// @Param page_size query int false "每页条数，默认为10"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /me/call-records [get]
+func (c *MeController) GetCallRecords() {
+	page, pageSize := c.pagination()
+
+	records, total, err := c.portalService().GetCallRecords(getCurrentUserID(c.Ctx), page, pageSize)
+	if err != nil {
+		c.failMe(err, "获取通话记录失败")
+		return
+	}
+
+	response.Success(c.Ctx, gin.H{
+		"total":       total,
+		"page":        page,
+		"page_size":   pageSize,
+		"total_pages": (total + int64(pageSize) - 1) / int64(pageSize),
+		"data":        records,
+	})
+}
+
+// 6. GetDevices 获取本户设备
+// @Summary 获取本户设备
+// @Description 获取关联到本户的设备和所在楼号的公共设备
+// @Tags Me
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.Device
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /me/devices [get]
+func (c *MeController) GetDevices() {
+	devices, err := c.portalService().GetDevices(getCurrentUserID(c.Ctx))
+	if err != nil {
+		c.failMe(err, "获取设备失败")
+		return
+	}
+
+	response.Success(c.Ctx, devices)
+}
+
+// 7. GetPasscodes 获取本户有效通行码
+// @Summary 获取有效通行码
+// @Description 获取本户未作废、未过期且未用完的通行码
+// @Tags Me
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.Passcode
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /me/passcodes [get]
+func (c *MeController) GetPasscodes() {
+	passcodes, err := c.portalService().GetActivePasscodes(getCurrentUserID(c.Ctx))
+	if err != nil {
+		c.failMe(err, "获取通行码失败")
+		return
+	}
+
+	response.Success(c.Ctx, passcodes)
+}
+
+// 8. CreatePasscode 生成通行码
+// @Summary 生成通行码
+// @Description 为访客、快递等生成本户的6位临时通行码
+// @Tags Me
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreatePasscodeRequest true "通行码参数"
+// @Success 201 {object} models.Passcode
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /me/passcodes [post]
+func (c *MeController) CreatePasscode() {
+	var req CreatePasscodeRequest
+	if err := c.Ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrBind, "无效的请求参数: "+err.Error(), nil)
+		return
+	}
+
+	passcode := &models.Passcode{
+		Label:   req.Label,
+		MaxUses: req.MaxUses,
+	}
+	if req.ValidFrom != nil {
+		passcode.ValidFrom = *req.ValidFrom
+	}
+	if req.ValidUntil != nil {
+		passcode.ValidUntil = *req.ValidUntil
+	}
+
+	if err := c.portalService().CreatePasscode(getCurrentUserID(c.Ctx), passcode); err != nil {
+		c.failMe(err, "生成通行码失败")
+		return
+	}
+
+	c.Ctx.Status(http.StatusCreated)
+	response.Success(c.Ctx, passcode)
+}
+
+// 9. RevokePasscode 作废通行码
+// @Summary 作废通行码
+// @Description 作废本户的通行码
+// @Tags Me
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "通行码ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /me/passcodes/{id} [delete]
+func (c *MeController) RevokePasscode() {
+	id, ok := c.parseID("无效的通行码ID")
+	if !ok {
+		return
+	}
+
+	if err := c.portalService().RevokePasscode(getCurrentUserID(c.Ctx), id); err != nil {
+		c.failMe(err, "作废通行码失败")
+		return
+	}
+
+	response.Success(c.Ctx, nil)
+}
+
+// 10. GetInbox 获取本人收件箱
+// @Summary 获取收件箱
+// @Description 分页获取当前居民收到的通知，按时间倒序，同时返回未读数
+// @Tags Me
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param unread query bool false "只看未读"
+// @Param page query int false "页码，默认为1"
+// @Param page_size query int false "每页条数，默认为10"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /me/inbox [get]
+func (c *MeController) GetInbox() {
+	page, pageSize := c.pagination()
+	unreadOnly, _ := strconv.ParseBool(c.Ctx.DefaultQuery("unread", "false"))
+
+	deliveries, total, unread, err := c.portalService().GetInbox(getCurrentUserID(c.Ctx), services.InboxQuery{
+		UnreadOnly: unreadOnly,
+		Page:       page,
+		PageSize:   pageSize,
+	})
+	if err != nil {
+		c.failMe(err, "获取收件箱失败")
+		return
+	}
+
+	response.Success(c.Ctx, gin.H{
+		"total":       total,
+		"unread":      unread,
+		"page":        page,
+		"page_size":   pageSize,
+		"total_pages": (total + int64(pageSize) - 1) / int64(pageSize),
+		"data":        deliveries,
+	})
+}
+
+// 11. MarkInboxRead 标记通知已读
+// @Summary 标记通知已读
+// @Description 将收件箱中的通知标记为已读，同时视为其他渠道已送达
+// @Tags Me
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "通知ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /me/inbox/{id}/read [post]
+func (c *MeController) MarkInboxRead() {
+	id, ok := c.parseID("无效的通知ID")
+	if !ok {
+		return
+	}
+
+	if err := c.portalService().MarkInboxRead(getCurrentUserID(c.Ctx), id); err != nil {
+		c.failMe(err, "标记已读失败")
+		return
+	}
+
+	response.Success(c.Ctx, nil)
+}
+
+// portalService 获取居民自助服务
+func (c *MeController) portalService() services.InterfaceResidentPortalService {
+	return c.Container.GetService("resident_portal").(services.InterfaceResidentPortalService)
+}
+
+// passwordService 获取密码服务
+func (c *MeController) passwordService() services.InterfacePasswordService {
+	return c.Container.GetService("password").(services.InterfacePasswordService)
+}
+
+// pagination 解析分页参数
+func (c *MeController) pagination() (int, int) {
+	page, _ := strconv.Atoi(c.Ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.Ctx.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+	return page, pageSize
+}
+
+// parseID 解析路径中的ID，失败时写入错误响应
+func (c *MeController) parseID(message string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Ctx.Param("id"), 10, 32)
+	if err != nil {
+		response.ParamError(c.Ctx, message)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// failMe 将居民自助服务错误映射为响应错误码
+func (c *MeController) failMe(err error, message string) {
+	if failPasswordPolicy(c.Ctx, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, services.ErrResidentNotFound):
+		response.FailWithMessage(c.Ctx, code.ErrResidentNotFound, err.Error(), nil)
+	case errors.Is(err, services.ErrResidentNoHousehold):
+		response.FailWithMessage(c.Ctx, code.ErrResidentNoHousehold, err.Error(), nil)
+	case errors.Is(err, services.ErrOldPasswordIncorrect):
+		response.FailWithMessage(c.Ctx, code.ErrUserPasswordIncorrect, err.Error(), nil)
+	case errors.Is(err, services.ErrPasscodeNotFound):
+		response.FailWithMessage(c.Ctx, code.ErrPasscodeNotFound, err.Error(), nil)
+	case errors.Is(err, services.ErrInvalidPasscode):
+		response.FailWithMessage(c.Ctx, code.ErrInvalidPasscode, err.Error(), nil)
+	case errors.Is(err, services.ErrDeliveryNotFound):
+		response.FailWithMessage(c.Ctx, code.ErrNotificationNotFound, err.Error(), nil)
+	default:
+		response.FailWithMessage(c.Ctx, code.ErrDatabase, message+": "+err.Error(), nil)
+	}
+}
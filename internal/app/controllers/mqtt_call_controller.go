@@ -8,6 +8,7 @@ import (
 	"ilock-http-service/internal/error/response"
 	"ilock-http-service/internal/infrastructure/config"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -154,6 +155,7 @@ func HandleMQTTCallFunc(container *container.ServiceContainer, method string) gi
 // @Tags         MQTT
 // @Accept       json
 // @Produce      json
+// @Security     BearerAuth
 // @Param        request body InitiateCallRequest true "通话请求参数：支持device_id(必填)、household_number(可选)、timestamp(可选)参数"
 // @Success      200  {object}  InitiateCallResponse
 // @Failure      400  {object}  ErrorResponse
@@ -233,6 +235,7 @@ func (c *MQTTCallController) InitiateCall() {
 // @Tags         MQTT
 // @Accept       json
 // @Produce      json
+// @Security     BearerAuth
 // @Param        request body CallActionRequest true "设备通话动作请求，包含call_id和action字段"
 // @Success      200  {object}  map[string]interface{}
 // @Failure      400  {object}  ErrorResponse
@@ -253,6 +256,13 @@ func (c *MQTTCallController) CallerAction() {
 	}
 
 	mqttCallService := c.Container.GetService("mqtt_call").(services.InterfaceMQTTCallService)
+
+	// 设备只能控制自己发起的通话
+	if session, exists := mqttCallService.GetCallSession(req.CallID); exists && session.DeviceID != strconv.FormatUint(uint64(getCurrentDeviceID(c.Ctx)), 10) {
+		response.FailWithMessage(c.Ctx, code.ErrDeviceMismatch, "通话不是由当前设备发起的", nil)
+		return
+	}
+
 	if err := mqttCallService.HandleCallerAction(req.CallID, req.Action, req.Reason); err != nil {
 		c.HandleError(http.StatusInternalServerError, "处理呼叫方动作失败", err)
 		return
@@ -267,6 +277,7 @@ func (c *MQTTCallController) CallerAction() {
 // @Tags         MQTT
 // @Accept       json
 // @Produce      json
+// @Security     BearerAuth
 // @Param        request body CallActionRequest true "居民通话动作请求，包含call_id、action和可选的reason字段"
 // @Success      200  {object}  map[string]interface{}
 // @Failure      400  {object}  ErrorResponse
@@ -286,6 +297,11 @@ func (c *MQTTCallController) CalleeAction() {
 		return
 	}
 
+	// 居民只能操作呼叫本人的通话
+	if !c.checkCallParticipant(req.CallID, false) {
+		return
+	}
+
 	mqttCallService := c.Container.GetService("mqtt_call").(services.InterfaceMQTTCallService)
 	if err := mqttCallService.HandleCalleeAction(req.CallID, req.Action, req.Reason); err != nil {
 		c.HandleError(http.StatusInternalServerError, "处理被呼叫方动作失败", err)
@@ -355,10 +371,11 @@ func (c *MQTTCallController) GetCallSession() {
 
 // 5. EndCallSession 结束通话会话
 // @Summary      结束MQTT通话会话
-// @Description  强制结束通话会话并通知所有参与方，适用于系统管理或异常情况下的通话强制终止
+// @Description  强制结束通话会话并通知所有参与方，适用于系统管理或异常情况下的通话强制终止。居民只能结束呼叫本人的通话，管理员和物业员工需要 call_record:write 权限，且只能结束本物业设备的通话
 // @Tags         MQTT
 // @Accept       json
 // @Produce      json
+// @Security     BearerAuth
 // @Param        request body EndCallSessionRequest true "结束通话会话请求，包含call_id和可选的reason字段"
 // @Success      200  {object}  map[string]interface{}
 // @Failure      400  {object}  ErrorResponse
@@ -371,6 +388,10 @@ func (c *MQTTCallController) EndCallSession() {
 		return
 	}
 
+	if !c.checkCallParticipant(req.CallID, true) {
+		return
+	}
+
 	mqttCallService := c.Container.GetService("mqtt_call").(services.InterfaceMQTTCallService)
 	if err := mqttCallService.EndCallSession(req.CallID, req.Reason); err != nil {
 		c.HandleError(http.StatusInternalServerError, "结束通话会话失败", err)
@@ -386,6 +407,7 @@ func (c *MQTTCallController) EndCallSession() {
 // @Tags         Device
 // @Accept       json
 // @Produce      json
+// @Security     BearerAuth
 // @Param        request body PublishDeviceStatusRequest true "设备状态信息：必须包含device_id、online、battery字段，可选包含properties自定义属性"
 // @Success      200  {object}  map[string]interface{}
 // @Failure      400  {object}  ErrorResponse
@@ -464,6 +486,49 @@ func (c *MQTTCallController) PublishSystemMessage() {
 	c.HandleSuccess(nil)
 }
 
+// checkCallParticipant 校验当前账号可以操作通话：居民必须是通话的被呼叫方；
+// allowManage 为 true 时管理员和物业员工拥有 call_record:write 权限且设备属于本物业也可以操作。
+// 会话不存在时交给通话服务返回错误
+func (c *MQTTCallController) checkCallParticipant(callID string, allowManage bool) bool {
+	mqttCallService := c.Container.GetService("mqtt_call").(services.InterfaceMQTTCallService)
+	session, exists := mqttCallService.GetCallSession(callID)
+	if !exists {
+		return true
+	}
+
+	role := getCurrentRole(c.Ctx)
+	userID := getCurrentUserID(c.Ctx)
+	if role == "user" {
+		if session.ResidentID == strconv.FormatUint(uint64(userID), 10) {
+			return true
+		}
+		response.FailWithMessage(c.Ctx, code.ErrCallNotParticipant, "通话不是呼叫当前居民的", nil)
+		return false
+	}
+
+	if allowManage && (role == "admin" || role == "staff") {
+		rbacService := c.Container.GetService("rbac").(services.InterfaceRBACService)
+		allowed, err := rbacService.HasPermission(role, userID, services.PermCallRecordWrite)
+		if err != nil {
+			c.HandleError(http.StatusInternalServerError, "检查权限失败", err)
+			return false
+		}
+		if allowed {
+			deviceID, err := strconv.ParseUint(session.DeviceID, 10, 64)
+			if err == nil {
+				if _, err = scopedDeviceService(c.Ctx, c.Container).GetDeviceByID(uint(deviceID)); err == nil {
+					return true
+				}
+			}
+			response.FailWithMessage(c.Ctx, code.ErrPropertyScope, "通话设备不属于当前物业", nil)
+			return false
+		}
+	}
+
+	response.FailWithMessage(c.Ctx, code.ErrCallNotParticipant, "不是通话的参与方", nil)
+	return false
+}
+
 // HandleSuccess 处理成功响应
 func (c *MQTTCallController) HandleSuccess(data interface{}) {
 	response.Success(c.Ctx, data)
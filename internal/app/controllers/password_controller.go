@@ -0,0 +1,189 @@
+package controllers
+
+import (
+	"errors"
+	"ilock-http-service/internal/domain/services"
+	"ilock-http-service/internal/domain/services/container"
+	"ilock-http-service/internal/error/code"
+	"ilock-http-service/internal/error/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InterfacePasswordController 定义密码控制器接口
+type InterfacePasswordController interface {
+	GetPasswordPolicy()
+	ChangePassword()
+	RequestPasswordReset()
+	ConfirmPasswordReset()
+}
+
+// PasswordController 处理修改密码和找回密码请求
+type PasswordController struct {
+	Ctx       *gin.Context
+	Container *container.ServiceContainer
+}
+
+// NewPasswordController 创建一个新的密码控制器
+func NewPasswordController(ctx *gin.Context, container *container.ServiceContainer) *PasswordController {
+	return &PasswordController{
+		Ctx:       ctx,
+		Container: container,
+	}
+}
+
+// UpdatePasswordRequest 表示修改本人密码的请求
+type UpdatePasswordRequest struct {
+	OldPassword string `json:"old_password" example:"OldPassword@123"` // 居民尚未设置密码时可为空
+	NewPassword string `json:"new_password" binding:"required" example:"NewPassword@123"`
+}
+
+// PasswordResetRequest 表示发送找回密码验证码的请求
+type PasswordResetRequest struct {
+	Username string `json:"username" binding:"required" example:"admin"`               // 管理员或物业员工用户名，居民手机号
+	Channel  string `json:"channel" binding:"omitempty,oneof=sms email" example:"sms"` // 为空时使用第一个可以送达的渠道
+}
+
+// PasswordResetConfirmRequest 表示使用验证码设置新密码的请求
+type PasswordResetConfirmRequest struct {
+	Username    string `json:"username" binding:"required" example:"admin"`
+	Code        string `json:"code" binding:"required" example:"123456"`
+	NewPassword string `json:"new_password" binding:"required" example:"NewPassword@123"`
+}
+
+// HandlePasswordFunc 返回一个处理密码请求的Gin处理函数
+func HandlePasswordFunc(container *container.ServiceContainer, method string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		controller := NewPasswordController(ctx, container)
+
+		switch method {
+		case "getPasswordPolicy":
+			controller.GetPasswordPolicy()
+		case "changePassword":
+			controller.ChangePassword()
+		case "requestPasswordReset":
+			controller.RequestPasswordReset()
+		case "confirmPasswordReset":
+			controller.ConfirmPasswordReset()
+		default:
+			response.FailWithMessage(ctx, code.ErrBind, "无效的方法", nil)
+		}
+	}
+}
+
+// 1. GetPasswordPolicy 获取密码策略
+// @Summary      Get Password Policy
+// @Description  Return the password strength rules applied when passwords are created, changed or reset
+// @Tags         Auth
+// @Produce      json
+// @Success      200  {object}  services.PasswordPolicy
+// @Router       /auth/password-policy [get]
+func (c *PasswordController) GetPasswordPolicy() {
+	response.Success(c.Ctx, c.passwordService().GetPolicy())
+}
+
+// 2. ChangePassword 修改本人密码
+// @Summary      Change Password
+// @Description  Change the password of the current admin, staff or resident account after checking the old one. All sessions of the account are revoked, log in again with the new password. Accounts required to change their password can only call this endpoint and logout
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body UpdatePasswordRequest true "Old and new password"
+// @Success      200  {object}  LoginResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      401  {object}  ErrorResponse
+// @Router       /auth/password [put]
+func (c *PasswordController) ChangePassword() {
+	var req UpdatePasswordRequest
+	if err := c.Ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrBind, "无效的请求参数: "+err.Error(), nil)
+		return
+	}
+
+	role, userID := getCurrentRole(c.Ctx), getCurrentUserID(c.Ctx)
+	account := services.LoginAccount{SubjectType: role, SubjectID: userID}
+	if err := c.passwordService().ChangePassword(account, req.OldPassword, req.NewPassword); err != nil {
+		c.failPassword(err, "修改密码失败")
+		return
+	}
+
+	// 修改密码后所有会话失效，包括要求修改密码时签发的受限令牌
+	revokeAccountTokens(c.Container, role, userID)
+	response.Success(c.Ctx, nil)
+}
+
+// 3. RequestPasswordReset 发送找回密码验证码
+// @Summary      Request Password Reset
+// @Description  Send a one-time code to the phone or email of the account. Admins and staff use their username, residents their phone number. The response is the same whether or not the account exists
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        request body PasswordResetRequest true "Account and channel"
+// @Success      200  {object}  LoginResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      429  {object}  ErrorResponse
+// @Router       /auth/password-reset/request [post]
+func (c *PasswordController) RequestPasswordReset() {
+	var req PasswordResetRequest
+	if err := c.Ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrBind, "无效的请求参数: "+err.Error(), nil)
+		return
+	}
+
+	if err := c.passwordService().RequestReset(req.Username, req.Channel); err != nil {
+		c.failPassword(err, "发送验证码失败")
+		return
+	}
+
+	response.Success(c.Ctx, nil)
+}
+
+// 4. ConfirmPasswordReset 使用验证码设置新密码
+// @Summary      Confirm Password Reset
+// @Description  Set a new password with the one-time code. The code can be used once and is discarded after too many wrong attempts. All sessions of the account are revoked
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        request body PasswordResetConfirmRequest true "Account, code and new password"
+// @Success      200  {object}  LoginResponse
+// @Failure      400  {object}  ErrorResponse
+// @Router       /auth/password-reset/confirm [post]
+func (c *PasswordController) ConfirmPasswordReset() {
+	var req PasswordResetConfirmRequest
+	if err := c.Ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrBind, "无效的请求参数: "+err.Error(), nil)
+		return
+	}
+
+	account, err := c.passwordService().ConfirmReset(req.Username, req.Code, req.NewPassword)
+	if err != nil {
+		c.failPassword(err, "重置密码失败")
+		return
+	}
+
+	revokeAccountTokens(c.Container, account.SubjectType, account.SubjectID)
+	response.Success(c.Ctx, nil)
+}
+
+// failPassword 将密码服务错误映射为响应错误码
+func (c *PasswordController) failPassword(err error, message string) {
+	if failPasswordPolicy(c.Ctx, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, services.ErrOldPasswordIncorrect):
+		response.FailWithMessage(c.Ctx, code.ErrUserPasswordIncorrect, err.Error(), nil)
+	case errors.Is(err, services.ErrResetCodeInvalid):
+		response.FailWithMessage(c.Ctx, code.ErrResetCodeInvalid, err.Error(), nil)
+	case errors.Is(err, services.ErrResetTooFrequent):
+		response.FailWithMessage(c.Ctx, code.ErrTooManyRequests, err.Error(), nil)
+	default:
+		response.FailWithMessage(c.Ctx, code.ErrDatabase, message+": "+err.Error(), nil)
+	}
+}
+
+// passwordService 获取密码服务
+func (c *PasswordController) passwordService() services.InterfacePasswordService {
+	return c.Container.GetService("password").(services.InterfacePasswordService)
+}
@@ -0,0 +1,296 @@
+package controllers
+
+import (
+	"errors"
+	"ilock-http-service/internal/domain/models"
+	"ilock-http-service/internal/domain/services"
+	"ilock-http-service/internal/domain/services/container"
+	"ilock-http-service/internal/error/code"
+	"ilock-http-service/internal/error/response"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InterfacePropertyController 定义物业控制器接口
+type InterfacePropertyController interface {
+	GetProperties()
+	GetProperty()
+	CreateProperty()
+	UpdateProperty()
+	DeleteProperty()
+}
+
+// PropertyController 处理物业相关的请求
+type PropertyController struct {
+	Ctx       *gin.Context
+	Container *container.ServiceContainer
+}
+
+// NewPropertyController 创建一个新的物业控制器
+func NewPropertyController(ctx *gin.Context, container *container.ServiceContainer) *PropertyController {
+	return &PropertyController{
+		Ctx:       ctx,
+		Container: container,
+	}
+}
+
+// CreatePropertyRequest 表示创建物业的请求
+type CreatePropertyRequest struct {
+	Name         string `json:"name" binding:"required" example:"阳光花园物业"`
+	Code         string `json:"code" binding:"required" example:"P001"`
+	Address      string `json:"address" example:"北京市朝阳区阳光路1号"`
+	ContactName  string `json:"contact_name" example:"王经理"`
+	ContactPhone string `json:"contact_phone" example:"13800138000"`
+	Status       string `json:"status" example:"active"` // active, inactive
+}
+
+// UpdatePropertyRequest 表示更新物业的请求，未提供的字段保持不变
+type UpdatePropertyRequest struct {
+	Name         string `json:"name" example:"阳光花园物业"`
+	Code         string `json:"code" example:"P001"`
+	Address      string `json:"address" example:"北京市朝阳区阳光路1号"`
+	ContactName  string `json:"contact_name" example:"王经理"`
+	ContactPhone string `json:"contact_phone" example:"13800138000"`
+	Status       string `json:"status" example:"active"`
+}
+
+// HandlePropertyFunc 返回一个处理物业请求的Gin处理函数
+func HandlePropertyFunc(container *container.ServiceContainer, method string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		controller := NewPropertyController(ctx, container)
+
+		switch method {
+		case "getProperties":
+			controller.GetProperties()
+		case "getProperty":
+			controller.GetProperty()
+		case "createProperty":
+			controller.CreateProperty()
+		case "updateProperty":
+			controller.UpdateProperty()
+		case "deleteProperty":
+			controller.DeleteProperty()
+		default:
+			response.FailWithMessage(ctx, code.ErrBind, "无效的方法", nil)
+		}
+	}
+}
+
+// 1. GetProperties 获取物业列表
+// @Summary 获取物业列表
+// @Description 平台管理员获取所有物业，物业管理员只能看到自己所属的物业
+// @Tags Property
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "页码，默认为1"
+// @Param page_size query int false "每页条数，默认为10"
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} ErrorResponse
+// @Router /properties [get]
+func (c *PropertyController) GetProperties() {
+	page, _ := strconv.Atoi(c.Ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.Ctx.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	propertyService := c.propertyService()
+	properties, total, err := propertyService.GetAllProperties(page, pageSize)
+	if err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrDatabase, "获取物业列表失败: "+err.Error(), nil)
+		return
+	}
+
+	response.Success(c.Ctx, gin.H{
+		"total":       total,
+		"page":        page,
+		"page_size":   pageSize,
+		"total_pages": (total + int64(pageSize) - 1) / int64(pageSize),
+		"data":        properties,
+	})
+}
+
+// 2. GetProperty 获取物业详情
+// @Summary 获取物业详情
+// @Description 根据ID获取物业及其下属楼号
+// @Tags Property
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "物业ID"
+// @Success 200 {object} models.Property
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /properties/{id} [get]
+func (c *PropertyController) GetProperty() {
+	id, ok := c.parseID()
+	if !ok {
+		return
+	}
+
+	property, err := c.propertyService().GetPropertyByID(id)
+	if err != nil {
+		c.failProperty(err, "获取物业失败")
+		return
+	}
+
+	response.Success(c.Ctx, property)
+}
+
+// 3. CreateProperty 创建物业
+// @Summary 创建物业
+// @Description 创建一个新的物业，仅平台管理员可用
+// @Tags Property
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreatePropertyRequest true "物业信息"
+// @Success 201 {object} models.Property
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /properties [post]
+func (c *PropertyController) CreateProperty() {
+	var req CreatePropertyRequest
+	if err := c.Ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrBind, "无效的请求参数: "+err.Error(), nil)
+		return
+	}
+
+	property := &models.Property{
+		Name:         strings.TrimSpace(req.Name),
+		Code:         strings.TrimSpace(req.Code),
+		Address:      req.Address,
+		ContactName:  req.ContactName,
+		ContactPhone: req.ContactPhone,
+		Status:       req.Status,
+	}
+
+	if err := c.propertyService().CreateProperty(property); err != nil {
+		c.failProperty(err, "创建物业失败")
+		return
+	}
+
+	c.Ctx.Status(http.StatusCreated)
+	response.Success(c.Ctx, property)
+}
+
+// 4. UpdateProperty 更新物业
+// @Summary 更新物业
+// @Description 更新物业信息，物业管理员只能更新自己所属的物业
+// @Tags Property
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "物业ID"
+// @Param request body UpdatePropertyRequest true "物业信息"
+// @Success 200 {object} models.Property
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /properties/{id} [put]
+func (c *PropertyController) UpdateProperty() {
+	id, ok := c.parseID()
+	if !ok {
+		return
+	}
+
+	var req UpdatePropertyRequest
+	if err := c.Ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrBind, "无效的请求参数: "+err.Error(), nil)
+		return
+	}
+
+	updates := make(map[string]interface{})
+	if req.Name != "" {
+		updates["name"] = strings.TrimSpace(req.Name)
+	}
+	if req.Code != "" {
+		updates["code"] = strings.TrimSpace(req.Code)
+	}
+	if req.Address != "" {
+		updates["address"] = req.Address
+	}
+	if req.ContactName != "" {
+		updates["contact_name"] = req.ContactName
+	}
+	if req.ContactPhone != "" {
+		updates["contact_phone"] = req.ContactPhone
+	}
+	if req.Status != "" {
+		updates["status"] = req.Status
+	}
+
+	property, err := c.propertyService().UpdateProperty(id, updates)
+	if err != nil {
+		c.failProperty(err, "更新物业失败")
+		return
+	}
+
+	response.Success(c.Ctx, property)
+}
+
+// 5. DeleteProperty 删除物业
+// @Summary 删除物业
+// @Description 删除没有楼号和物业员工的物业，仅平台管理员可用
+// @Tags Property
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "物业ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /properties/{id} [delete]
+func (c *PropertyController) DeleteProperty() {
+	id, ok := c.parseID()
+	if !ok {
+		return
+	}
+
+	if err := c.propertyService().DeleteProperty(id); err != nil {
+		c.failProperty(err, "删除物业失败")
+		return
+	}
+
+	response.Success(c.Ctx, nil)
+}
+
+// propertyService 获取限定在当前用户所属物业内的物业服务
+func (c *PropertyController) propertyService() services.InterfacePropertyService {
+	return c.Container.GetService("property").(services.InterfacePropertyService).WithPropertyScope(getCurrentPropertyID(c.Ctx))
+}
+
+// parseID 解析路径中的物业ID，失败时写入错误响应
+func (c *PropertyController) parseID() (uint, bool) {
+	id, err := strconv.ParseUint(c.Ctx.Param("id"), 10, 32)
+	if err != nil {
+		response.ParamError(c.Ctx, "无效的物业ID")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// failProperty 将物业服务错误映射为响应错误码
+func (c *PropertyController) failProperty(err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrPropertyNotFound):
+		response.FailWithMessage(c.Ctx, code.ErrPropertyNotFound, err.Error(), nil)
+	case errors.Is(err, services.ErrPropertyCodeExists):
+		response.FailWithMessage(c.Ctx, code.ErrValidation, err.Error(), nil)
+	case errors.Is(err, services.ErrPropertyInUse):
+		response.FailWithMessage(c.Ctx, code.ErrPropertyInUse, err.Error(), nil)
+	case errors.Is(err, services.ErrOutOfPropertyScope):
+		response.FailWithMessage(c.Ctx, code.ErrPropertyScope, err.Error(), nil)
+	default:
+		response.FailWithMessage(c.Ctx, code.ErrDatabase, message+": "+err.Error(), nil)
+	}
+}
@@ -0,0 +1,430 @@
+package controllers
+
+import (
+	"errors"
+	"ilock-http-service/internal/domain/models"
+	"ilock-http-service/internal/domain/services"
+	"ilock-http-service/internal/domain/services/container"
+	"ilock-http-service/internal/error/code"
+	"ilock-http-service/internal/error/response"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InterfaceRBACController 定义角色权限控制器接口
+type InterfaceRBACController interface {
+	GetMyPermissions()
+	GetPermissions()
+	GetRoles()
+	GetRole()
+	CreateRole()
+	UpdateRole()
+	DeleteRole()
+	GetRoleBindings()
+	CreateRoleBinding()
+	DeleteRoleBinding()
+}
+
+// RBACController 处理角色、权限与角色绑定相关的请求
+type RBACController struct {
+	Ctx       *gin.Context
+	Container *container.ServiceContainer
+}
+
+// NewRBACController 创建一个新的角色权限控制器
+func NewRBACController(ctx *gin.Context, container *container.ServiceContainer) *RBACController {
+	return &RBACController{
+		Ctx:       ctx,
+		Container: container,
+	}
+}
+
+// CreateRoleRequest 表示创建角色的请求
+type CreateRoleRequest struct {
+	Name        string   `json:"name" binding:"required" example:"security_guard"`
+	DisplayName string   `json:"display_name" example:"保安"`
+	Description string   `json:"description" example:"查看设备并处理警报"`
+	Permissions []string `json:"permissions" example:"device:read,emergency:read,emergency:write"`
+}
+
+// UpdateRoleRequest 表示更新角色的请求，permissions不传时保持不变，传空数组表示清空
+type UpdateRoleRequest struct {
+	Name        *string  `json:"name" example:"security_guard"`
+	DisplayName *string  `json:"display_name" example:"保安"`
+	Description *string  `json:"description" example:"查看设备并处理警报"`
+	Permissions []string `json:"permissions" example:"device:read,emergency:read"`
+}
+
+// CreateRoleBindingRequest 表示创建角色绑定的请求
+type CreateRoleBindingRequest struct {
+	SubjectType string `json:"subject_type" binding:"required" example:"staff"` // admin, staff, user
+	SubjectID   uint   `json:"subject_id" example:"3"`                          // 0表示该类型的所有账号
+	RoleID      uint   `json:"role_id" binding:"required" example:"2"`
+}
+
+// HandleRBACFunc 返回一个处理角色权限请求的Gin处理函数
+func HandleRBACFunc(container *container.ServiceContainer, method string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		controller := NewRBACController(ctx, container)
+
+		// 角色和权限对所有物业生效，只能由平台管理员维护
+		if method != "getMyPermissions" && getCurrentPropertyID(ctx) != nil {
+			response.FailWithMessage(ctx, code.ErrPropertyScope, "物业管理员无权管理角色", nil)
+			return
+		}
+
+		switch method {
+		case "getMyPermissions":
+			controller.GetMyPermissions()
+		case "getPermissions":
+			controller.GetPermissions()
+		case "getRoles":
+			controller.GetRoles()
+		case "getRole":
+			controller.GetRole()
+		case "createRole":
+			controller.CreateRole()
+		case "updateRole":
+			controller.UpdateRole()
+		case "deleteRole":
+			controller.DeleteRole()
+		case "getRoleBindings":
+			controller.GetRoleBindings()
+		case "createRoleBinding":
+			controller.CreateRoleBinding()
+		case "deleteRoleBinding":
+			controller.DeleteRoleBinding()
+		default:
+			response.FailWithMessage(ctx, code.ErrBind, "无效的方法", nil)
+		}
+	}
+}
+
+// 1. GetMyPermissions 获取当前账号的权限
+// @Summary 获取当前账号的权限
+// @Description 返回当前账号类型、ID及其拥有的所有权限编码，前端可据此控制菜单和按钮
+// @Tags RBAC
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} ErrorResponse
+// @Router /rbac/me [get]
+func (c *RBACController) GetMyPermissions() {
+	role := getCurrentRole(c.Ctx)
+	userID := getCurrentUserID(c.Ctx)
+
+	permissions, err := c.rbacService().GetSubjectPermissions(role, userID)
+	if err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrDatabase, "获取权限失败: "+err.Error(), nil)
+		return
+	}
+
+	response.Success(c.Ctx, gin.H{
+		"role":        role,
+		"user_id":     userID,
+		"property_id": getCurrentPropertyID(c.Ctx),
+		"permissions": permissions,
+	})
+}
+
+// 2. GetPermissions 获取所有权限
+// @Summary 获取权限列表
+// @Description 获取系统中所有可分配的权限
+// @Tags RBAC
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.Permission
+// @Failure 500 {object} ErrorResponse
+// @Router /rbac/permissions [get]
+func (c *RBACController) GetPermissions() {
+	permissions, err := c.rbacService().GetPermissions()
+	if err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrDatabase, "获取权限列表失败: "+err.Error(), nil)
+		return
+	}
+
+	response.Success(c.Ctx, permissions)
+}
+
+// 3. GetRoles 获取所有角色
+// @Summary 获取角色列表
+// @Description 获取所有角色及其权限
+// @Tags RBAC
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.Role
+// @Failure 500 {object} ErrorResponse
+// @Router /rbac/roles [get]
+func (c *RBACController) GetRoles() {
+	roles, err := c.rbacService().GetRoles()
+	if err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrDatabase, "获取角色列表失败: "+err.Error(), nil)
+		return
+	}
+
+	response.Success(c.Ctx, roles)
+}
+
+// 4. GetRole 获取角色详情
+// @Summary 获取角色详情
+// @Description 根据ID获取角色及其权限
+// @Tags RBAC
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "角色ID"
+// @Success 200 {object} models.Role
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /rbac/roles/{id} [get]
+func (c *RBACController) GetRole() {
+	id, ok := c.parseID("无效的角色ID")
+	if !ok {
+		return
+	}
+
+	role, err := c.rbacService().GetRoleByID(id)
+	if err != nil {
+		c.failRBAC(err, "获取角色失败")
+		return
+	}
+
+	response.Success(c.Ctx, role)
+}
+
+// 5. CreateRole 创建角色
+// @Summary 创建角色
+// @Description 创建自定义角色并分配权限
+// @Tags RBAC
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateRoleRequest true "角色信息"
+// @Success 201 {object} models.Role
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /rbac/roles [post]
+func (c *RBACController) CreateRole() {
+	var req CreateRoleRequest
+	if err := c.Ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrBind, "无效的请求参数: "+err.Error(), nil)
+		return
+	}
+
+	role := &models.Role{
+		Name:        strings.TrimSpace(req.Name),
+		DisplayName: req.DisplayName,
+		Description: req.Description,
+	}
+
+	if err := c.rbacService().CreateRole(role, req.Permissions); err != nil {
+		c.failRBAC(err, "创建角色失败")
+		return
+	}
+
+	c.Ctx.Status(http.StatusCreated)
+	response.Success(c.Ctx, role)
+}
+
+// 6. UpdateRole 更新角色
+// @Summary 更新角色
+// @Description 更新角色信息和权限，系统管理员角色始终拥有所有权限
+// @Tags RBAC
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "角色ID"
+// @Param request body UpdateRoleRequest true "角色信息"
+// @Success 200 {object} models.Role
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /rbac/roles/{id} [put]
+func (c *RBACController) UpdateRole() {
+	id, ok := c.parseID("无效的角色ID")
+	if !ok {
+		return
+	}
+
+	var req UpdateRoleRequest
+	if err := c.Ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrBind, "无效的请求参数: "+err.Error(), nil)
+		return
+	}
+
+	updates := make(map[string]interface{})
+	if req.Name != nil {
+		updates["name"] = strings.TrimSpace(*req.Name)
+	}
+	if req.DisplayName != nil {
+		updates["display_name"] = *req.DisplayName
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+
+	role, err := c.rbacService().UpdateRole(id, updates, req.Permissions)
+	if err != nil {
+		c.failRBAC(err, "更新角色失败")
+		return
+	}
+
+	response.Success(c.Ctx, role)
+}
+
+// 7. DeleteRole 删除角色
+// @Summary 删除角色
+// @Description 删除自定义角色及其绑定，内置角色不能删除
+// @Tags RBAC
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "角色ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /rbac/roles/{id} [delete]
+func (c *RBACController) DeleteRole() {
+	id, ok := c.parseID("无效的角色ID")
+	if !ok {
+		return
+	}
+
+	if err := c.rbacService().DeleteRole(id); err != nil {
+		c.failRBAC(err, "删除角色失败")
+		return
+	}
+
+	response.Success(c.Ctx, nil)
+}
+
+// 8. GetRoleBindings 获取角色绑定
+// @Summary 获取角色绑定
+// @Description 获取角色绑定，可按账号类型和账号ID过滤
+// @Tags RBAC
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param subject_type query string false "账号类型：admin, staff, user"
+// @Param subject_id query int false "账号ID，0表示绑定到该类型所有账号"
+// @Success 200 {array} models.RoleBinding
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /rbac/bindings [get]
+func (c *RBACController) GetRoleBindings() {
+	var subjectID *uint
+	if value := c.Ctx.Query("subject_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			response.ParamError(c.Ctx, "无效的账号ID")
+			return
+		}
+		parsed := uint(id)
+		subjectID = &parsed
+	}
+
+	bindings, err := c.rbacService().GetRoleBindings(c.Ctx.Query("subject_type"), subjectID)
+	if err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrDatabase, "获取角色绑定失败: "+err.Error(), nil)
+		return
+	}
+
+	response.Success(c.Ctx, bindings)
+}
+
+// 9. CreateRoleBinding 创建角色绑定
+// @Summary 创建角色绑定
+// @Description 将角色授予账号，subject_id为0表示授予该类型的所有账号，账号的权限为所有绑定角色权限的并集
+// @Tags RBAC
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateRoleBindingRequest true "绑定信息"
+// @Success 201 {object} models.RoleBinding
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /rbac/bindings [post]
+func (c *RBACController) CreateRoleBinding() {
+	var req CreateRoleBindingRequest
+	if err := c.Ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrBind, "无效的请求参数: "+err.Error(), nil)
+		return
+	}
+
+	binding := &models.RoleBinding{
+		SubjectType: req.SubjectType,
+		SubjectID:   req.SubjectID,
+		RoleID:      req.RoleID,
+	}
+
+	if err := c.rbacService().CreateRoleBinding(binding); err != nil {
+		c.failRBAC(err, "创建角色绑定失败")
+		return
+	}
+
+	c.Ctx.Status(http.StatusCreated)
+	response.Success(c.Ctx, binding)
+}
+
+// 10. DeleteRoleBinding 删除角色绑定
+// @Summary 删除角色绑定
+// @Description 删除角色绑定，系统管理员的默认绑定不能删除
+// @Tags RBAC
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "绑定ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /rbac/bindings/{id} [delete]
+func (c *RBACController) DeleteRoleBinding() {
+	id, ok := c.parseID("无效的绑定ID")
+	if !ok {
+		return
+	}
+
+	if err := c.rbacService().DeleteRoleBinding(id); err != nil {
+		c.failRBAC(err, "删除角色绑定失败")
+		return
+	}
+
+	response.Success(c.Ctx, nil)
+}
+
+// rbacService 获取权限服务
+func (c *RBACController) rbacService() services.InterfaceRBACService {
+	return c.Container.GetService("rbac").(services.InterfaceRBACService)
+}
+
+// parseID 解析路径中的ID，失败时写入错误响应
+func (c *RBACController) parseID(message string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Ctx.Param("id"), 10, 32)
+	if err != nil {
+		response.ParamError(c.Ctx, message)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// failRBAC 将权限服务错误映射为响应错误码
+func (c *RBACController) failRBAC(err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrRoleNotFound):
+		response.FailWithMessage(c.Ctx, code.ErrRoleNotFound, err.Error(), nil)
+	case errors.Is(err, services.ErrRoleBindingNotFound):
+		response.FailWithMessage(c.Ctx, code.ErrRoleBindingNotFound, err.Error(), nil)
+	case errors.Is(err, services.ErrRoleBuiltIn), errors.Is(err, services.ErrRoleBindingProtected):
+		response.FailWithMessage(c.Ctx, code.ErrRoleProtected, err.Error(), nil)
+	case errors.Is(err, services.ErrRoleNameExists), errors.Is(err, services.ErrRoleBindingExists),
+		errors.Is(err, services.ErrInvalidPermission), errors.Is(err, services.ErrInvalidSubjectType):
+		response.FailWithMessage(c.Ctx, code.ErrValidation, err.Error(), nil)
+	default:
+		response.FailWithMessage(c.Ctx, code.ErrDatabase, message+": "+err.Error(), nil)
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"ilock-http-service/internal/error/code"
 	"ilock-http-service/internal/error/response"
 	"ilock-http-service/internal/domain/models"
-	"ilock-http-service/internal/domain/services"
 	"ilock-http-service/internal/domain/services/container"
 	"net/http"
 	"strconv"
@@ -76,7 +75,7 @@ func (c *ResidentController) GetResidents() {
 	}
 
 	// 使用 ResidentService 获取居民列表
-	residentService := c.Container.GetService("resident").(services.InterfaceResidentService)
+	residentService := scopedResidentService(c.Ctx, c.Container)
 	residents, total, err := residentService.GetAllResidents(page, pageSize)
 	if err != nil {
 		response.FailWithMessage(c.Ctx, code.ErrDatabase, "获取居民列表失败", nil)
@@ -119,7 +118,7 @@ func (c *ResidentController) GetResident() {
 	}
 
 	// 使用 ResidentService 获取居民详情
-	residentService := c.Container.GetService("resident").(services.InterfaceResidentService)
+	residentService := scopedResidentService(c.Ctx, c.Container)
 	resident, err := residentService.GetResidentByID(uint(idUint))
 	if err != nil {
 		if err.Error() == "居民不存在" {
@@ -162,8 +161,11 @@ func (c *ResidentController) CreateResident() {
 	}
 
 	// 使用 ResidentService 创建居民
-	residentService := c.Container.GetService("resident").(services.InterfaceResidentService)
+	residentService := scopedResidentService(c.Ctx, c.Container)
 	if err := residentService.CreateResident(resident); err != nil {
+		if failPropertyScope(c.Ctx, err) {
+			return
+		}
 		if err.Error() == "手机号已被使用" || err.Error() == "户号不存在" {
 			response.FailWithMessage(c.Ctx, code.ErrBind, err.Error(), nil)
 			return
@@ -225,9 +227,12 @@ func (c *ResidentController) UpdateResident() {
 	}
 
 	// 使用 ResidentService 更新居民
-	residentService := c.Container.GetService("resident").(services.InterfaceResidentService)
+	residentService := scopedResidentService(c.Ctx, c.Container)
 	resident, err := residentService.UpdateResident(uint(idUint), updates)
 	if err != nil {
+		if failPropertyScope(c.Ctx, err) {
+			return
+		}
 		if err.Error() == "居民不存在" {
 			response.NotFound(c.Ctx, err.Error())
 			return
@@ -270,7 +275,7 @@ func (c *ResidentController) DeleteResident() {
 	}
 
 	// 使用 ResidentService 删除居民
-	residentService := c.Container.GetService("resident").(services.InterfaceResidentService)
+	residentService := scopedResidentService(c.Ctx, c.Container)
 	if err := residentService.DeleteResident(uint(idUint)); err != nil {
 		if err.Error() == "居民不存在" {
 			response.NotFound(c.Ctx, err.Error())
@@ -279,6 +284,7 @@ func (c *ResidentController) DeleteResident() {
 		response.FailWithMessage(c.Ctx, code.ErrDatabase, "删除居民失败: "+err.Error(), nil)
 		return
 	}
+	revokeAccountTokens(c.Container, "user", uint(idUint))
 
 	response.Success(c.Ctx, nil)
 }
@@ -60,6 +60,7 @@ func HandleRTCFunc(container *container.ServiceContainer, method string) gin.Han
 // @Tags         RTC
 // @Accept       json
 // @Produce      json
+// @Security     BearerAuth
 // @Param        request body TokenRequest true "Token request parameters"
 // @Success      200  {object}  map[string]interface{}
 // @Failure      400  {object}  ErrorResponse
@@ -104,6 +105,7 @@ type CallRequest struct {
 // @Tags         RTC
 // @Accept       json
 // @Produce      json
+// @Security     BearerAuth
 // @Param        request body CallRequest true "Call request parameters"
 // @Success      200  {object}  map[string]interface{}
 // @Failure      400  {object}  ErrorResponse
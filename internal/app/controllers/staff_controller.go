@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"ilock-http-service/internal/error/code"
 	"ilock-http-service/internal/error/response"
 	"ilock-http-service/internal/domain/models"
@@ -8,6 +9,7 @@ import (
 	"ilock-http-service/internal/domain/services/container"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -20,6 +22,13 @@ type InterfaceStaffController interface {
 	UpdateStaff()
 	DeleteStaff()
 	GetStaffsWithDevices()
+	GetStaffDevices()
+	AssignDevice()
+	UnassignDevice()
+	SetStaffCredentials()
+	ResetStaffPassword()
+	EnableStaff()
+	DisableStaff()
 }
 
 // StaffController 处理物业员工相关的请求
@@ -63,7 +72,7 @@ func (c *StaffController) GetStaffs() {
 	}
 
 	// 使用 StaffService 获取物业员工列表
-	staffService := c.Container.GetService("staff").(services.InterfaceStaffService)
+	staffService := scopedStaffService(c.Ctx, c.Container)
 
 	staffs, total, err := staffService.GetAllStaff(page, pageSize, search)
 	if err != nil {
@@ -77,6 +86,7 @@ func (c *StaffController) GetStaffs() {
 		staffResponses = append(staffResponses, gin.H{
 			"id":            staff.ID,
 			"phone":         staff.Phone,
+			"property_id":   staff.PropertyID,
 			"property_name": staff.PropertyName,
 			"position":      staff.Position,
 			"role":          staff.Role,
@@ -118,7 +128,7 @@ func (c *StaffController) GetStaff() {
 	}
 
 	// 使用 StaffService 获取物业员工详情
-	staffService := c.Container.GetService("staff").(services.InterfaceStaffService)
+	staffService := scopedStaffService(c.Ctx, c.Container)
 	staff, err := staffService.GetStaffByIDWithDevices(uint(id))
 	if err != nil {
 		if err.Error() == "物业员工不存在" {
@@ -139,6 +149,7 @@ func (c *StaffController) GetStaff() {
 	response.Success(c.Ctx, gin.H{
 		"id":            staff.ID,
 		"phone":         staff.Phone,
+		"property_id":   staff.PropertyID,
 		"property_name": staff.PropertyName,
 		"position":      staff.Position,
 		"role":          staff.Role,
@@ -155,6 +166,7 @@ func (c *StaffController) GetStaff() {
 type CreateStaffRequest struct {
 	Name         string `json:"name" example:"王物业"` // 注意: 已从模型中移除，但保留请求结构以兼容前端
 	Phone        string `json:"phone" binding:"required" example:"13700001234"`
+	PropertyID   *uint  `json:"property_id" example:"1"` // 所属物业ID，物业管理员创建时固定为其所在物业
 	PropertyName string `json:"property_name" example:"阳光花园小区"`
 	Position     string `json:"position" example:"物业经理"`
 	Role         string `json:"role" binding:"required" example:"manager"` // 可选值: manager, staff, security
@@ -191,6 +203,7 @@ func (c *StaffController) CreateStaff() {
 	// 创建物业员工对象
 	staff := &models.PropertyStaff{
 		Phone:        req.Phone,
+		PropertyID:   req.PropertyID,
 		PropertyName: req.PropertyName,
 		Position:     req.Position,
 		Role:         req.Role,
@@ -201,8 +214,15 @@ func (c *StaffController) CreateStaff() {
 	}
 
 	// 使用 StaffService 创建物业员工
-	staffService := c.Container.GetService("staff").(services.InterfaceStaffService)
+	staffService := scopedStaffService(c.Ctx, c.Container)
 	if err := staffService.CreateStaff(staff); err != nil {
+		if failPropertyScope(c.Ctx, err) || failPasswordPolicy(c.Ctx, err) {
+			return
+		}
+		if errors.Is(err, services.ErrStaffStatusInvalid) {
+			response.FailWithMessage(c.Ctx, code.ErrStaffStatusInvalid, err.Error(), nil)
+			return
+		}
 		if err.Error() == "手机号已被使用" || err.Error() == "用户名已存在" {
 			c.Ctx.JSON(http.StatusBadRequest, gin.H{
 				"code":    400,
@@ -221,22 +241,16 @@ func (c *StaffController) CreateStaff() {
 
 	// 关联设备
 	if len(req.DeviceIDs) > 0 {
-		// 获取设备服务
-		deviceService := c.Container.GetService("device").(services.InterfaceDeviceService)
-
-		// 为每个设备设置物业ID
-		for _, deviceID := range req.DeviceIDs {
-			updates := map[string]interface{}{
-				"property_id": staff.ID,
-			}
-			if _, err := deviceService.UpdateDevice(deviceID, updates); err != nil {
-				c.Ctx.JSON(http.StatusInternalServerError, gin.H{
-					"code":    500,
-					"message": "关联设备失败: " + err.Error(),
-					"data":    nil,
-				})
+		if err := staffService.SetStaffDevices(staff.ID, req.DeviceIDs); err != nil {
+			if failPropertyScope(c.Ctx, err) {
 				return
 			}
+			c.Ctx.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "关联设备失败: " + err.Error(),
+				"data":    nil,
+			})
+			return
 		}
 	}
 
@@ -246,6 +260,7 @@ func (c *StaffController) CreateStaff() {
 		"data": gin.H{
 			"id":            staff.ID,
 			"phone":         staff.Phone,
+			"property_id":   staff.PropertyID,
 			"property_name": staff.PropertyName,
 			"position":      staff.Position,
 			"role":          staff.Role,
@@ -262,6 +277,7 @@ func (c *StaffController) CreateStaff() {
 type UpdateStaffRequest struct {
 	Name         string `json:"name" example:"李物业"`
 	Phone        string `json:"phone" example:"13700005678"`
+	PropertyID   *uint  `json:"property_id" example:"1"` // 调整所属物业，只有平台管理员可以修改
 	PropertyName string `json:"property_name" example:"幸福家园小区"`
 	Position     string `json:"position" example:"前台客服"`
 	Role         string `json:"role" example:"staff"`    // 可选值: manager, staff, security
@@ -317,6 +333,9 @@ func (c *StaffController) UpdateStaff() {
 	if req.PropertyName != "" {
 		updates["property_name"] = req.PropertyName
 	}
+	if req.PropertyID != nil {
+		updates["property_id"] = *req.PropertyID
+	}
 	if req.Position != "" {
 		updates["position"] = req.Position
 	}
@@ -337,9 +356,16 @@ func (c *StaffController) UpdateStaff() {
 	}
 
 	// 使用 StaffService 更新物业员工
-	staffService := c.Container.GetService("staff").(services.InterfaceStaffService)
+	staffService := scopedStaffService(c.Ctx, c.Container)
 	staff, err := staffService.UpdateStaff(uint(id), updates)
 	if err != nil {
+		if failPropertyScope(c.Ctx, err) || failPasswordPolicy(c.Ctx, err) {
+			return
+		}
+		if errors.Is(err, services.ErrStaffStatusInvalid) {
+			response.FailWithMessage(c.Ctx, code.ErrStaffStatusInvalid, err.Error(), nil)
+			return
+		}
 		if err.Error() == "物业员工不存在" {
 			c.Ctx.JSON(http.StatusNotFound, gin.H{
 				"code":    404,
@@ -364,50 +390,24 @@ func (c *StaffController) UpdateStaff() {
 		return
 	}
 
+	// 修改密码或停用账号后注销员工已登录的会话
+	if req.Password != "" || (req.Status != "" && req.Status != models.StaffStatusActive) {
+		revokeAccountTokens(c.Container, "staff", staff.ID)
+	}
+
 	// 如果请求中包含设备ID列表，更新关联设备
 	if req.DeviceIDs != nil {
-		// 获取设备服务
-		deviceService := c.Container.GetService("device").(services.InterfaceDeviceService)
-
-		// 清除现有关联 - 将属于该员工的所有设备的property_id设为NULL
-		devices, err := staffService.GetStaffDevices(uint(id))
-		if err != nil {
+		if err := staffService.SetStaffDevices(uint(id), req.DeviceIDs); err != nil {
+			if failPropertyScope(c.Ctx, err) {
+				return
+			}
 			c.Ctx.JSON(http.StatusInternalServerError, gin.H{
 				"code":    500,
-				"message": "获取设备关联失败: " + err.Error(),
+				"message": "关联设备失败: " + err.Error(),
 				"data":    nil,
 			})
 			return
 		}
-
-		for _, device := range devices {
-			updates := map[string]interface{}{
-				"property_id": nil,
-			}
-			if _, err := deviceService.UpdateDevice(device.ID, updates); err != nil {
-				c.Ctx.JSON(http.StatusInternalServerError, gin.H{
-					"code":    500,
-					"message": "清除设备关联失败: " + err.Error(),
-					"data":    nil,
-				})
-				return
-			}
-		}
-
-		// 添加新关联
-		for _, deviceID := range req.DeviceIDs {
-			updates := map[string]interface{}{
-				"property_id": uint(id),
-			}
-			if _, err := deviceService.UpdateDevice(deviceID, updates); err != nil {
-				c.Ctx.JSON(http.StatusInternalServerError, gin.H{
-					"code":    500,
-					"message": "关联设备失败: " + err.Error(),
-					"data":    nil,
-				})
-				return
-			}
-		}
 	}
 
 	// 获取更新后的设备ID列表
@@ -432,6 +432,7 @@ func (c *StaffController) UpdateStaff() {
 		"data": gin.H{
 			"id":            staff.ID,
 			"phone":         staff.Phone,
+			"property_id":   staff.PropertyID,
 			"property_name": staff.PropertyName,
 			"position":      staff.Position,
 			"role":          staff.Role,
@@ -471,7 +472,7 @@ func (c *StaffController) DeleteStaff() {
 	}
 
 	// 使用 StaffService 删除物业员工
-	staffService := c.Container.GetService("staff").(services.InterfaceStaffService)
+	staffService := scopedStaffService(c.Ctx, c.Container)
 	if err := staffService.DeleteStaff(uint(id)); err != nil {
 		if err.Error() == "物业员工不存在" {
 			c.Ctx.JSON(http.StatusNotFound, gin.H{
@@ -489,6 +490,8 @@ func (c *StaffController) DeleteStaff() {
 		return
 	}
 
+	revokeAccountTokens(c.Container, "staff", uint(id))
+
 	c.Ctx.JSON(http.StatusOK, gin.H{
 		"code":    0,
 		"message": "成功删除物业员工",
@@ -523,7 +526,7 @@ func (c *StaffController) GetStaffsWithDevices() {
 	}
 
 	// 使用 StaffService 获取物业员工列表
-	staffService := c.Container.GetService("staff").(services.InterfaceStaffService)
+	staffService := scopedStaffService(c.Ctx, c.Container)
 
 	// 这里假设 StaffService 有一个获取带设备的员工列表的方法
 	// 如果没有，可以先获取员工列表，然后针对每个员工查询其设备
@@ -566,6 +569,7 @@ func (c *StaffController) GetStaffsWithDevices() {
 		staffResponses = append(staffResponses, gin.H{
 			"id":            staff.ID,
 			"phone":         staff.Phone,
+			"property_id":   staff.PropertyID,
 			"property_name": staff.PropertyName,
 			"position":      staff.Position,
 			"role":          staff.Role,
@@ -590,6 +594,278 @@ func (c *StaffController) GetStaffsWithDevices() {
 	})
 }
 
+// AssignDeviceRequest 表示为物业员工分配设备的请求体
+type AssignDeviceRequest struct {
+	DeviceID uint   `json:"device_id" binding:"required" example:"1"`
+	Role     string `json:"role" example:"maintainer"` // 职责，如 manager、maintainer，默认 maintainer
+}
+
+// GetStaffDevices 获取物业员工负责的设备
+// @Summary      获取物业员工负责的设备
+// @Description  获取分配给指定物业员工的设备及其职责
+// @Tags         Staff
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "物业员工ID" example:"2"
+// @Success      200  {array}   models.StaffDeviceRelation
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Router       /staffs/{id}/devices [get]
+// @Security     BearerAuth
+func (c *StaffController) GetStaffDevices() {
+	id, err := strconv.ParseUint(c.Ctx.Param("id"), 10, 32)
+	if err != nil {
+		response.ParamError(c.Ctx, "无效的ID参数")
+		return
+	}
+
+	relations, err := scopedStaffService(c.Ctx, c.Container).GetStaffDeviceRelations(uint(id))
+	if err != nil {
+		c.failStaffDevice(err, "获取员工设备失败")
+		return
+	}
+
+	response.Success(c.Ctx, relations)
+}
+
+// AssignDevice 为物业员工分配设备
+// @Summary      分配设备
+// @Description  将设备分配给物业员工，已分配时更新职责；设备必须属于员工所在物业
+// @Tags         Staff
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "物业员工ID" example:"2"
+// @Param        request body AssignDeviceRequest true "设备和职责"
+// @Success      200  {object}  models.StaffDeviceRelation
+// @Failure      400  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Router       /staffs/{id}/devices [post]
+// @Security     BearerAuth
+func (c *StaffController) AssignDevice() {
+	id, err := strconv.ParseUint(c.Ctx.Param("id"), 10, 32)
+	if err != nil {
+		response.ParamError(c.Ctx, "无效的ID参数")
+		return
+	}
+
+	var req AssignDeviceRequest
+	if err := c.Ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrBind, "无效的请求参数: "+err.Error(), nil)
+		return
+	}
+
+	relation, err := scopedStaffService(c.Ctx, c.Container).AssignDevice(uint(id), req.DeviceID, req.Role)
+	if err != nil {
+		c.failStaffDevice(err, "分配设备失败")
+		return
+	}
+
+	response.Success(c.Ctx, relation)
+}
+
+// UnassignDevice 取消物业员工的设备分配
+// @Summary      取消分配设备
+// @Description  取消物业员工与设备的分配关系
+// @Tags         Staff
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "物业员工ID" example:"2"
+// @Param        device_id path int true "设备ID" example:"1"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Router       /staffs/{id}/devices/{device_id} [delete]
+// @Security     BearerAuth
+func (c *StaffController) UnassignDevice() {
+	id, err := strconv.ParseUint(c.Ctx.Param("id"), 10, 32)
+	if err != nil {
+		response.ParamError(c.Ctx, "无效的ID参数")
+		return
+	}
+	deviceID, err := strconv.ParseUint(c.Ctx.Param("device_id"), 10, 32)
+	if err != nil {
+		response.ParamError(c.Ctx, "无效的设备ID")
+		return
+	}
+
+	if err := scopedStaffService(c.Ctx, c.Container).UnassignDevice(uint(id), uint(deviceID)); err != nil {
+		c.failStaffDevice(err, "取消分配失败")
+		return
+	}
+
+	response.Success(c.Ctx, nil)
+}
+
+// StaffCredentialsRequest 表示设置物业员工登录凭证的请求体
+type StaffCredentialsRequest struct {
+	Username      string `json:"username" binding:"omitempty,max=50" example:"wangwuye"` // 新用户名，为空时不修改
+	Password      string `json:"password" example:"Property@789"`                        // 新密码，为空时不修改
+	RequireChange bool   `json:"require_change" example:"true"`                          // 为真时员工下次登录后必须先修改密码
+}
+
+// SetStaffCredentials 设置物业员工登录凭证
+// @Summary      设置物业员工登录凭证
+// @Description  修改物业员工的登录用户名和/或密码，新密码需要符合密码策略。修改密码后员工的所有登录会话被注销
+// @Tags         Staff
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "物业员工ID" example:"2"
+// @Param        request body StaffCredentialsRequest true "用户名和密码"
+// @Success      200  {object}  models.PropertyStaff
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Router       /staffs/{id}/credentials [put]
+// @Security     BearerAuth
+func (c *StaffController) SetStaffCredentials() {
+	id, err := strconv.ParseUint(c.Ctx.Param("id"), 10, 32)
+	if err != nil {
+		response.ParamError(c.Ctx, "无效的ID参数")
+		return
+	}
+
+	var req StaffCredentialsRequest
+	if err := c.Ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrBind, "无效的请求参数: "+err.Error(), nil)
+		return
+	}
+	req.Username = strings.TrimSpace(req.Username)
+	if req.Username == "" && req.Password == "" {
+		response.FailWithMessage(c.Ctx, code.ErrValidation, "用户名和密码至少需要提供一个", nil)
+		return
+	}
+
+	staff, err := scopedStaffService(c.Ctx, c.Container).SetCredentials(uint(id), req.Username, req.Password, req.RequireChange)
+	if err != nil {
+		c.failStaffAccount(err, "设置登录凭证失败")
+		return
+	}
+	if req.Password != "" {
+		revokeAccountTokens(c.Container, "staff", staff.ID)
+	}
+
+	response.Success(c.Ctx, staff)
+}
+
+// ResetStaffPassword 重置物业员工密码
+// @Summary      重置物业员工密码
+// @Description  为物业员工生成符合密码策略的临时密码，员工使用临时密码登录后必须先修改密码。临时密码只在本次响应中返回，员工的所有登录会话被注销
+// @Tags         Staff
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "物业员工ID" example:"2"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Router       /staffs/{id}/reset-password [post]
+// @Security     BearerAuth
+func (c *StaffController) ResetStaffPassword() {
+	id, err := strconv.ParseUint(c.Ctx.Param("id"), 10, 32)
+	if err != nil {
+		response.ParamError(c.Ctx, "无效的ID参数")
+		return
+	}
+
+	staff, password, err := scopedStaffService(c.Ctx, c.Container).ResetPassword(uint(id))
+	if err != nil {
+		c.failStaffAccount(err, "重置密码失败")
+		return
+	}
+	revokeAccountTokens(c.Container, "staff", staff.ID)
+
+	response.Success(c.Ctx, gin.H{
+		"id":                 staff.ID,
+		"username":           staff.Username,
+		"temporary_password": password,
+	})
+}
+
+// EnableStaff 启用物业员工账号
+// @Summary      启用物业员工账号
+// @Description  将物业员工状态设置为 active，员工可以重新登录。因连续登录失败被锁定的账号请使用解锁接口
+// @Tags         Staff
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "物业员工ID" example:"2"
+// @Success      200  {object}  models.PropertyStaff
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Router       /staffs/{id}/enable [post]
+// @Security     BearerAuth
+func (c *StaffController) EnableStaff() {
+	c.setStaffStatus(models.StaffStatusActive)
+}
+
+// DisableStaff 停用物业员工账号
+// @Summary      停用物业员工账号
+// @Description  将物业员工状态设置为 inactive，员工不能再登录，已登录的会话立即注销
+// @Tags         Staff
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "物业员工ID" example:"2"
+// @Success      200  {object}  models.PropertyStaff
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Router       /staffs/{id}/disable [post]
+// @Security     BearerAuth
+func (c *StaffController) DisableStaff() {
+	c.setStaffStatus(models.StaffStatusInactive)
+}
+
+// setStaffStatus 修改物业员工状态，停用时注销其所有登录会话
+func (c *StaffController) setStaffStatus(status string) {
+	id, err := strconv.ParseUint(c.Ctx.Param("id"), 10, 32)
+	if err != nil {
+		response.ParamError(c.Ctx, "无效的ID参数")
+		return
+	}
+
+	staff, err := scopedStaffService(c.Ctx, c.Container).SetStatus(uint(id), status)
+	if err != nil {
+		c.failStaffAccount(err, "修改员工状态失败")
+		return
+	}
+	if status != models.StaffStatusActive {
+		revokeAccountTokens(c.Container, "staff", staff.ID)
+	}
+
+	response.Success(c.Ctx, staff)
+}
+
+// failStaffAccount 将员工账号错误映射为响应错误码
+func (c *StaffController) failStaffAccount(err error, message string) {
+	if failPropertyScope(c.Ctx, err) || failPasswordPolicy(c.Ctx, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, services.ErrStaffNotFound):
+		response.FailWithMessage(c.Ctx, code.ErrStaffNotFound, err.Error(), nil)
+	case errors.Is(err, services.ErrStaffUsernameExists):
+		response.FailWithMessage(c.Ctx, code.ErrStaffUsernameExists, err.Error(), nil)
+	case errors.Is(err, services.ErrStaffStatusInvalid):
+		response.FailWithMessage(c.Ctx, code.ErrStaffStatusInvalid, err.Error(), nil)
+	default:
+		response.FailWithMessage(c.Ctx, code.ErrDatabase, message+": "+err.Error(), nil)
+	}
+}
+
+// failStaffDevice 将员工设备分配错误映射为响应错误码
+func (c *StaffController) failStaffDevice(err error, message string) {
+	if failPropertyScope(c.Ctx, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, services.ErrStaffNotFound):
+		response.FailWithMessage(c.Ctx, code.ErrStaffNotFound, err.Error(), nil)
+	case errors.Is(err, services.ErrStaffDeviceNotFound):
+		response.FailWithMessage(c.Ctx, code.ErrDeviceNotFound, err.Error(), nil)
+	case errors.Is(err, services.ErrStaffDeviceNotAssigned):
+		response.FailWithMessage(c.Ctx, code.ErrStaffDeviceNotAssigned, err.Error(), nil)
+	default:
+		response.FailWithMessage(c.Ctx, code.ErrDatabase, message+": "+err.Error(), nil)
+	}
+}
+
 // HandleStaffFunc 返回一个处理物业员工请求的Gin处理函数
 func HandleStaffFunc(container *container.ServiceContainer, method string) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
@@ -608,6 +884,20 @@ func HandleStaffFunc(container *container.ServiceContainer, method string) gin.H
 			controller.DeleteStaff()
 		case "getStaffsWithDevices":
 			controller.GetStaffsWithDevices()
+		case "getStaffDevices":
+			controller.GetStaffDevices()
+		case "assignDevice":
+			controller.AssignDevice()
+		case "unassignDevice":
+			controller.UnassignDevice()
+		case "setStaffCredentials":
+			controller.SetStaffCredentials()
+		case "resetStaffPassword":
+			controller.ResetStaffPassword()
+		case "enableStaff":
+			controller.EnableStaff()
+		case "disableStaff":
+			controller.DisableStaff()
 		default:
 			ctx.JSON(http.StatusBadRequest, gin.H{
 				"code":    400,
@@ -0,0 +1,201 @@
+package controllers
+
+import (
+	"errors"
+	"ilock-http-service/internal/domain/services"
+	"ilock-http-service/internal/domain/services/container"
+	"ilock-http-service/internal/error/code"
+	"ilock-http-service/internal/error/response"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InterfaceStaffPortalController 定义物业员工工作台控制器接口
+type InterfaceStaffPortalController interface {
+	GetDevices()
+	GetCallRecords()
+	GetAlarms()
+	GetAccessLogs()
+}
+
+// StaffPortalController 处理物业员工查看本人负责范围的请求，数据限定在分配给调用者的设备内
+type StaffPortalController struct {
+	Ctx       *gin.Context
+	Container *container.ServiceContainer
+}
+
+// NewStaffPortalController 创建一个新的物业员工工作台控制器
+func NewStaffPortalController(ctx *gin.Context, container *container.ServiceContainer) *StaffPortalController {
+	return &StaffPortalController{
+		Ctx:       ctx,
+		Container: container,
+	}
+}
+
+// HandleStaffPortalFunc 返回一个处理物业员工工作台请求的Gin处理函数
+func HandleStaffPortalFunc(container *container.ServiceContainer, method string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		controller := NewStaffPortalController(ctx, container)
+
+		// 工作台按员工ID查询负责的设备，只对物业员工账号开放
+		if getCurrentRole(ctx) != "staff" {
+			response.FailWithMessage(ctx, code.ErrPropertyScope, "仅物业员工账号可以使用该接口", nil)
+			return
+		}
+
+		switch method {
+		case "getDevices":
+			controller.GetDevices()
+		case "getCallRecords":
+			controller.GetCallRecords()
+		case "getAlarms":
+			controller.GetAlarms()
+		case "getAccessLogs":
+			controller.GetAccessLogs()
+		default:
+			response.FailWithMessage(ctx, code.ErrBind, "无效的方法", nil)
+		}
+	}
+}
+
+// 1. GetDevices 获取本人负责的设备
+// @Summary 获取本人负责的设备
+// @Description 获取分配给当前物业员工的设备、所在楼号及职责
+// @Tags StaffPortal
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.StaffDeviceRelation
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /staffs/me/devices [get]
+func (c *StaffPortalController) GetDevices() {
+	relations, err := c.portalService().GetDevices(getCurrentUserID(c.Ctx))
+	if err != nil {
+		c.failStaffPortal(err, "获取负责设备失败")
+		return
+	}
+
+	response.Success(c.Ctx, relations)
+}
+
+// 2. GetCallRecords 获取负责设备的通话记录
+// @Summary 获取负责设备的通话记录
+// @Description 分页获取当前物业员工负责设备的通话记录，按时间倒序
+// @Tags StaffPortal
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param device_id query int false "只看某台负责的设备"
+// @Param page query int false "页码，默认为1"
+// @Param page_size query int false "每页条数，默认为10"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /staffs/me/call-records [get]
+func (c *StaffPortalController) GetCallRecords() {
+	query := c.assignedQuery()
+
+	records, total, err := c.portalService().GetCallRecords(getCurrentUserID(c.Ctx), query)
+	if err != nil {
+		c.failStaffPortal(err, "获取通话记录失败")
+		return
+	}
+
+	c.paginated(query, total, records)
+}
+
+// 3. GetAlarms 获取与本人相关的警报
+// @Summary 获取与本人相关的警报
+// @Description 分页获取指派给当前物业员工的警报，以及其负责设备上报事件触发的警报
+// @Tags StaffPortal
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param device_id query int false "只看某台负责的设备触发的警报"
+// @Param status query string false "警报状态：triggered, processing, resolved"
+// @Param page query int false "页码，默认为1"
+// @Param page_size query int false "每页条数，默认为10"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /staffs/me/alarms [get]
+func (c *StaffPortalController) GetAlarms() {
+	query := c.assignedQuery()
+	query.Status = c.Ctx.Query("status")
+
+	alarms, total, err := c.portalService().GetAlarms(getCurrentUserID(c.Ctx), query)
+	if err != nil {
+		c.failStaffPortal(err, "获取警报失败")
+		return
+	}
+
+	c.paginated(query, total, alarms)
+}
+
+// 4. GetAccessLogs 获取负责设备的门禁记录
+// @Summary 获取负责设备的门禁记录
+// @Description 分页获取当前物业员工负责设备的门禁记录，按时间倒序
+// @Tags StaffPortal
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param device_id query int false "只看某台负责的设备"
+// @Param page query int false "页码，默认为1"
+// @Param page_size query int false "每页条数，默认为10"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /staffs/me/access-logs [get]
+func (c *StaffPortalController) GetAccessLogs() {
+	query := c.assignedQuery()
+
+	logs, total, err := c.portalService().GetAccessLogs(getCurrentUserID(c.Ctx), query)
+	if err != nil {
+		c.failStaffPortal(err, "获取门禁记录失败")
+		return
+	}
+
+	c.paginated(query, total, logs)
+}
+
+// portalService 获取物业员工工作台服务
+func (c *StaffPortalController) portalService() services.InterfaceStaffPortalService {
+	return c.Container.GetService("staff_portal").(services.InterfaceStaffPortalService)
+}
+
+// assignedQuery 解析设备过滤和分页参数
+func (c *StaffPortalController) assignedQuery() services.AssignedQuery {
+	page, _ := strconv.Atoi(c.Ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.Ctx.DefaultQuery("page_size", "10"))
+	deviceID, _ := strconv.ParseUint(c.Ctx.Query("device_id"), 10, 32)
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+	return services.AssignedQuery{DeviceID: uint(deviceID), Page: page, PageSize: pageSize}
+}
+
+// paginated 写入分页响应
+func (c *StaffPortalController) paginated(query services.AssignedQuery, total int64, data interface{}) {
+	response.Success(c.Ctx, gin.H{
+		"total":       total,
+		"page":        query.Page,
+		"page_size":   query.PageSize,
+		"total_pages": (total + int64(query.PageSize) - 1) / int64(query.PageSize),
+		"data":        data,
+	})
+}
+
+// failStaffPortal 将工作台服务错误映射为响应错误码
+func (c *StaffPortalController) failStaffPortal(err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrStaffNotFound):
+		response.FailWithMessage(c.Ctx, code.ErrStaffNotFound, err.Error(), nil)
+	default:
+		response.FailWithMessage(c.Ctx, code.ErrDatabase, message+": "+err.Error(), nil)
+	}
+}
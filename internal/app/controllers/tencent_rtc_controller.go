@@ -43,6 +43,7 @@ type GetUserSigRequest struct {
 // @Tags         TencentRTC
 // @Accept       json
 // @Produce      json
+// @Security     BearerAuth
 // @Param        request body GetUserSigRequest true "UserSig request parameters"
 // @Success      200  {object}  map[string]interface{}
 // @Failure      400  {object}  ErrorResponse
@@ -86,6 +87,7 @@ type TencentCallRequest struct {
 // @Tags         TencentRTC
 // @Accept       json
 // @Produce      json
+// @Security     BearerAuth
 // @Param        request body TencentCallRequest true "Call request parameters"
 // @Success      200  {object}  map[string]interface{}
 // @Failure      400  {object}  ErrorResponse
@@ -0,0 +1,290 @@
+package controllers
+
+import (
+	"errors"
+	"ilock-http-service/internal/domain/services"
+	"ilock-http-service/internal/domain/services/container"
+	"ilock-http-service/internal/error/code"
+	"ilock-http-service/internal/error/response"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InterfaceTwoFactorController 定义两步验证控制器接口
+type InterfaceTwoFactorController interface {
+	GetTwoFactorStatus()
+	SetupTwoFactor()
+	EnableTwoFactor()
+	DisableTwoFactor()
+	RegenerateRecoveryCodes()
+	ResetTwoFactor()
+}
+
+// TwoFactorController 处理管理员两步验证的绑定和管理请求
+type TwoFactorController struct {
+	Ctx       *gin.Context
+	Container *container.ServiceContainer
+}
+
+// NewTwoFactorController 创建一个新的两步验证控制器
+func NewTwoFactorController(ctx *gin.Context, container *container.ServiceContainer) *TwoFactorController {
+	return &TwoFactorController{
+		Ctx:       ctx,
+		Container: container,
+	}
+}
+
+// TwoFactorCodeRequest 表示提交两步验证码的请求
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required" example:"123456"` // 身份验证器App中的6位验证码
+}
+
+// DisableTwoFactorRequest 表示关闭两步验证的请求
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" binding:"required" example:"Admin@123"`
+	Code     string `json:"code" binding:"required" example:"123456"` // 验证码或恢复码
+}
+
+// ResetTwoFactorRequest 表示重置其他管理员两步验证的请求
+type ResetTwoFactorRequest struct {
+	AdminID uint `json:"admin_id" binding:"required" example:"2"`
+}
+
+// HandleTwoFactorFunc 返回一个处理两步验证请求的Gin处理函数
+func HandleTwoFactorFunc(container *container.ServiceContainer, method string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		controller := NewTwoFactorController(ctx, container)
+
+		switch method {
+		case "getTwoFactorStatus":
+			controller.GetTwoFactorStatus()
+		case "setupTwoFactor":
+			controller.SetupTwoFactor()
+		case "enableTwoFactor":
+			controller.EnableTwoFactor()
+		case "disableTwoFactor":
+			controller.DisableTwoFactor()
+		case "regenerateRecoveryCodes":
+			controller.RegenerateRecoveryCodes()
+		case "resetTwoFactor":
+			controller.ResetTwoFactor()
+		default:
+			response.FailWithMessage(ctx, code.ErrBind, "无效的方法", nil)
+		}
+	}
+}
+
+// 1. GetTwoFactorStatus 获取本人的两步验证状态
+// @Summary      Get Two-Factor Status
+// @Description  Return whether TOTP two-factor authentication is enabled for the current admin, whether the policy requires it and how many recovery codes are left
+// @Tags         Auth
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  services.TwoFactorStatus
+// @Failure      403  {object}  ErrorResponse
+// @Router       /auth/2fa [get]
+func (c *TwoFactorController) GetTwoFactorStatus() {
+	if !c.requireAdmin() {
+		return
+	}
+
+	status, err := c.twoFactorService().GetStatus(getCurrentUserID(c.Ctx))
+	if err != nil {
+		c.failTwoFactor(err, "获取两步验证状态失败")
+		return
+	}
+	response.Success(c.Ctx, status)
+}
+
+// 2. SetupTwoFactor 生成两步验证密钥
+// @Summary      Set Up Two-Factor
+// @Description  Generate a new TOTP secret and otpauth:// provisioning URI for the current admin. Render the URI as a QR code for the authenticator app, then confirm with /auth/2fa/enable. Calling it again before enabling replaces the secret
+// @Tags         Auth
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  services.TwoFactorSetup
+// @Failure      400  {object}  ErrorResponse
+// @Router       /auth/2fa/setup [post]
+func (c *TwoFactorController) SetupTwoFactor() {
+	if !c.requireAdmin() {
+		return
+	}
+
+	setup, err := c.twoFactorService().Setup(getCurrentUserID(c.Ctx))
+	if err != nil {
+		c.failTwoFactor(err, "生成两步验证密钥失败")
+		return
+	}
+	response.Success(c.Ctx, setup)
+}
+
+// 3. EnableTwoFactor 启用两步验证
+// @Summary      Enable Two-Factor
+// @Description  Confirm the authenticator with a current code. Returns ten one-time recovery codes that are shown only once. All sessions of the account are revoked, log in again with the code
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body TwoFactorCodeRequest true "Authenticator code"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  ErrorResponse
+// @Failure      401  {object}  ErrorResponse
+// @Router       /auth/2fa/enable [post]
+func (c *TwoFactorController) EnableTwoFactor() {
+	if !c.requireAdmin() {
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.Ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrBind, "无效的请求参数: "+err.Error(), nil)
+		return
+	}
+
+	adminID := getCurrentUserID(c.Ctx)
+	codes, err := c.twoFactorService().Enable(adminID, req.Code)
+	if err != nil {
+		c.failTwoFactor(err, "启用两步验证失败")
+		return
+	}
+
+	// 启用前签发的令牌未经过两步验证，包括要求绑定时签发的受限令牌
+	revokeAccountTokens(c.Container, "admin", adminID)
+	response.Success(c.Ctx, gin.H{"recovery_codes": codes})
+}
+
+// 4. DisableTwoFactor 关闭两步验证
+// @Summary      Disable Two-Factor
+// @Description  Turn off two-factor authentication with the current password and an authenticator or recovery code. Refused when the admin holds a permission that requires two-factor authentication
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body DisableTwoFactorRequest true "Password and code"
+// @Success      200  {object}  LoginResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Router       /auth/2fa/disable [post]
+func (c *TwoFactorController) DisableTwoFactor() {
+	if !c.requireAdmin() {
+		return
+	}
+
+	var req DisableTwoFactorRequest
+	if err := c.Ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrBind, "无效的请求参数: "+err.Error(), nil)
+		return
+	}
+
+	if err := c.twoFactorService().Disable(getCurrentUserID(c.Ctx), req.Password, req.Code); err != nil {
+		c.failTwoFactor(err, "关闭两步验证失败")
+		return
+	}
+	response.Success(c.Ctx, nil)
+}
+
+// 5. RegenerateRecoveryCodes 重新生成恢复码
+// @Summary      Regenerate Recovery Codes
+// @Description  Replace all recovery codes with ten new ones after checking an authenticator code. Previous recovery codes stop working
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body TwoFactorCodeRequest true "Authenticator code"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  ErrorResponse
+// @Failure      401  {object}  ErrorResponse
+// @Router       /auth/2fa/recovery-codes [post]
+func (c *TwoFactorController) RegenerateRecoveryCodes() {
+	if !c.requireAdmin() {
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.Ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrBind, "无效的请求参数: "+err.Error(), nil)
+		return
+	}
+
+	codes, err := c.twoFactorService().RegenerateRecoveryCodes(getCurrentUserID(c.Ctx), req.Code)
+	if err != nil {
+		c.failTwoFactor(err, "生成恢复码失败")
+		return
+	}
+	response.Success(c.Ctx, gin.H{"recovery_codes": codes})
+}
+
+// 6. ResetTwoFactor 重置其他管理员的两步验证
+// @Summary      Reset Two-Factor
+// @Description  Platform admins remove the two-factor authentication of an admin who lost their authenticator and recovery codes. All sessions of that admin are revoked; if the policy requires two-factor authentication, they must enrol again after the next login
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body ResetTwoFactorRequest true "Admin"
+// @Success      200  {object}  LoginResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Router       /auth/2fa/reset [post]
+func (c *TwoFactorController) ResetTwoFactor() {
+	if getCurrentPropertyID(c.Ctx) != nil {
+		response.FailWithMessage(c.Ctx, code.ErrPropertyScope, "只有平台管理员可以重置两步验证", nil)
+		return
+	}
+
+	var req ResetTwoFactorRequest
+	if err := c.Ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrBind, "无效的请求参数: "+err.Error(), nil)
+		return
+	}
+	if req.AdminID == getCurrentUserID(c.Ctx) {
+		response.FailWithMessage(c.Ctx, code.ErrValidation, "不能重置本人的两步验证，请使用恢复码登录后关闭或重新生成", nil)
+		return
+	}
+
+	if err := c.twoFactorService().Reset(req.AdminID, getCurrentUserID(c.Ctx), c.Ctx.ClientIP()); err != nil {
+		c.failTwoFactor(err, "重置两步验证失败")
+		return
+	}
+
+	revokeAccountTokens(c.Container, "admin", req.AdminID)
+	response.Success(c.Ctx, nil)
+}
+
+// requireAdmin 两步验证目前只对管理员开放
+func (c *TwoFactorController) requireAdmin() bool {
+	if getCurrentRole(c.Ctx) == "admin" {
+		return true
+	}
+	c.Ctx.JSON(http.StatusForbidden, gin.H{
+		"code":    403,
+		"message": "Insufficient permissions: two-factor authentication is only available to admins",
+		"data":    nil,
+	})
+	return false
+}
+
+// failTwoFactor 将两步验证服务错误映射为响应错误码
+func (c *TwoFactorController) failTwoFactor(err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrTwoFactorCodeInvalid):
+		response.FailWithMessage(c.Ctx, code.ErrTwoFactorCodeInvalid, err.Error(), nil)
+	case errors.Is(err, services.ErrTwoFactorAlreadyEnabled):
+		response.FailWithMessage(c.Ctx, code.ErrTwoFactorAlreadyEnabled, err.Error(), nil)
+	case errors.Is(err, services.ErrTwoFactorNotEnabled), errors.Is(err, services.ErrTwoFactorNotSetup):
+		response.FailWithMessage(c.Ctx, code.ErrTwoFactorNotEnabled, err.Error(), nil)
+	case errors.Is(err, services.ErrTwoFactorRequired):
+		response.FailWithMessage(c.Ctx, code.ErrTwoFactorRequired, err.Error(), nil)
+	case errors.Is(err, services.ErrOldPasswordIncorrect):
+		response.FailWithMessage(c.Ctx, code.ErrUserPasswordIncorrect, "密码错误", nil)
+	default:
+		response.FailWithMessage(c.Ctx, code.ErrDatabase, message+": "+err.Error(), nil)
+	}
+}
+
+// twoFactorService 获取两步验证服务
+func (c *TwoFactorController) twoFactorService() services.InterfaceTwoFactorService {
+	return c.Container.GetService("two_factor").(services.InterfaceTwoFactorService)
+}
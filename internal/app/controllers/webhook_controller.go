@@ -0,0 +1,406 @@
+package controllers
+
+import (
+	"errors"
+	"ilock-http-service/internal/domain/models"
+	"ilock-http-service/internal/domain/services"
+	"ilock-http-service/internal/domain/services/container"
+	"ilock-http-service/internal/error/code"
+	"ilock-http-service/internal/error/response"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InterfaceWebhookController 定义Webhook控制器接口
+type InterfaceWebhookController interface {
+	GetWebhooks()
+	GetWebhook()
+	CreateWebhook()
+	UpdateWebhook()
+	DeleteWebhook()
+	RotateWebhookSecret()
+	GetWebhookDeliveries()
+	ReplayWebhookDelivery()
+}
+
+// WebhookController 处理Webhook订阅与投递相关的请求
+type WebhookController struct {
+	Ctx       *gin.Context
+	Container *container.ServiceContainer
+}
+
+// NewWebhookController 创建一个新的Webhook控制器
+func NewWebhookController(ctx *gin.Context, container *container.ServiceContainer) *WebhookController {
+	return &WebhookController{
+		Ctx:       ctx,
+		Container: container,
+	}
+}
+
+// CreateWebhookRequest 表示创建Webhook订阅的请求
+type CreateWebhookRequest struct {
+	Name        string   `json:"name" binding:"required" example:"BMS"`
+	URL         string   `json:"url" binding:"required" example:"https://bms.example.com/hooks/ilock"`
+	Events      []string `json:"events" binding:"required,min=1" example:"call.started,alarm.triggered"` // 事件类型，["*"]表示所有事件
+	Secret      string   `json:"secret" example:""`                                                      // 签名密钥，为空时自动生成
+	Description string   `json:"description" example:"楼宇管理系统"`
+}
+
+// UpdateWebhookRequest 表示更新Webhook订阅的请求，未提供的字段保持不变
+type UpdateWebhookRequest struct {
+	Name        *string  `json:"name" example:"BMS"`
+	URL         *string  `json:"url" example:"https://bms.example.com/hooks/ilock"`
+	Events      []string `json:"events" example:"*"`
+	Active      *bool    `json:"active" example:"true"`
+	Description *string  `json:"description" example:"楼宇管理系统"`
+}
+
+// HandleWebhookFunc 返回一个处理Webhook请求的Gin处理函数
+func HandleWebhookFunc(container *container.ServiceContainer, method string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		controller := NewWebhookController(ctx, container)
+
+		// Webhook推送所有物业的事件，只能由平台管理员配置
+		if getCurrentPropertyID(ctx) != nil {
+			response.FailWithMessage(ctx, code.ErrPropertyScope, "物业管理员无权配置Webhook", nil)
+			return
+		}
+
+		switch method {
+		case "getWebhooks":
+			controller.GetWebhooks()
+		case "getWebhook":
+			controller.GetWebhook()
+		case "createWebhook":
+			controller.CreateWebhook()
+		case "updateWebhook":
+			controller.UpdateWebhook()
+		case "deleteWebhook":
+			controller.DeleteWebhook()
+		case "rotateWebhookSecret":
+			controller.RotateWebhookSecret()
+		case "getWebhookDeliveries":
+			controller.GetWebhookDeliveries()
+		case "replayWebhookDelivery":
+			controller.ReplayWebhookDelivery()
+		default:
+			response.FailWithMessage(ctx, code.ErrBind, "无效的方法", nil)
+		}
+	}
+}
+
+// 1. GetWebhooks 获取Webhook订阅列表
+// @Summary 获取Webhook订阅列表
+// @Description 获取所有Webhook订阅及可订阅的事件类型，签名密钥不会返回
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} ErrorResponse
+// @Router /webhooks [get]
+func (c *WebhookController) GetWebhooks() {
+	webhookService := c.Container.GetService("webhook").(services.InterfaceWebhookService)
+	subscriptions, err := webhookService.GetSubscriptions()
+	if err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrDatabase, "获取Webhook订阅失败: "+err.Error(), nil)
+		return
+	}
+
+	response.Success(c.Ctx, gin.H{
+		"data":   subscriptions,
+		"events": services.WebhookEventTypes,
+	})
+}
+
+// 2. GetWebhook 获取单个Webhook订阅
+// @Summary 获取Webhook订阅详情
+// @Description 根据ID获取Webhook订阅
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "订阅ID"
+// @Success 200 {object} models.WebhookSubscription
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /webhooks/{id} [get]
+func (c *WebhookController) GetWebhook() {
+	id, ok := c.parseID("无效的订阅ID")
+	if !ok {
+		return
+	}
+
+	webhookService := c.Container.GetService("webhook").(services.InterfaceWebhookService)
+	subscription, err := webhookService.GetSubscriptionByID(id)
+	if err != nil {
+		c.failWebhook(err, "获取Webhook订阅失败")
+		return
+	}
+
+	response.Success(c.Ctx, subscription)
+}
+
+// 3. CreateWebhook 创建Webhook订阅
+// @Summary 创建Webhook订阅
+// @Description 创建Webhook订阅，响应中的secret只返回这一次，用于校验X-Webhook-Signature
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param webhook body CreateWebhookRequest true "订阅信息"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /webhooks [post]
+func (c *WebhookController) CreateWebhook() {
+	var req CreateWebhookRequest
+	if err := c.Ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrBind, "无效的请求参数: "+err.Error(), nil)
+		return
+	}
+
+	subscription := &models.WebhookSubscription{
+		Name:        req.Name,
+		URL:         req.URL,
+		Events:      strings.Join(req.Events, ","),
+		Secret:      req.Secret,
+		Description: req.Description,
+	}
+
+	webhookService := c.Container.GetService("webhook").(services.InterfaceWebhookService)
+	if err := webhookService.CreateSubscription(subscription); err != nil {
+		c.failWebhook(err, "创建Webhook订阅失败")
+		return
+	}
+
+	c.Ctx.Status(http.StatusCreated)
+	response.Success(c.Ctx, gin.H{
+		"subscription": subscription,
+		"secret":       subscription.Secret,
+	})
+}
+
+// 4. UpdateWebhook 更新Webhook订阅
+// @Summary 更新Webhook订阅
+// @Description 更新订阅的名称、地址、事件类型或启用状态
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "订阅ID"
+// @Param webhook body UpdateWebhookRequest true "订阅信息"
+// @Success 200 {object} models.WebhookSubscription
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /webhooks/{id} [put]
+func (c *WebhookController) UpdateWebhook() {
+	id, ok := c.parseID("无效的订阅ID")
+	if !ok {
+		return
+	}
+
+	var req UpdateWebhookRequest
+	if err := c.Ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrBind, "无效的请求参数: "+err.Error(), nil)
+		return
+	}
+
+	updates := make(map[string]interface{})
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.URL != nil {
+		updates["url"] = *req.URL
+	}
+	if req.Events != nil {
+		updates["events"] = strings.Join(req.Events, ",")
+	}
+	if req.Active != nil {
+		updates["active"] = *req.Active
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if len(updates) == 0 {
+		response.ParamError(c.Ctx, "没有需要更新的字段")
+		return
+	}
+
+	webhookService := c.Container.GetService("webhook").(services.InterfaceWebhookService)
+	subscription, err := webhookService.UpdateSubscription(id, updates)
+	if err != nil {
+		c.failWebhook(err, "更新Webhook订阅失败")
+		return
+	}
+
+	response.Success(c.Ctx, subscription)
+}
+
+// 5. DeleteWebhook 删除Webhook订阅
+// @Summary 删除Webhook订阅
+// @Description 删除Webhook订阅，尚未完成的投递将标记为失败
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "订阅ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /webhooks/{id} [delete]
+func (c *WebhookController) DeleteWebhook() {
+	id, ok := c.parseID("无效的订阅ID")
+	if !ok {
+		return
+	}
+
+	webhookService := c.Container.GetService("webhook").(services.InterfaceWebhookService)
+	if err := webhookService.DeleteSubscription(id); err != nil {
+		c.failWebhook(err, "删除Webhook订阅失败")
+		return
+	}
+
+	response.Success(c.Ctx, gin.H{"message": "Webhook订阅已删除"})
+}
+
+// 6. RotateWebhookSecret 轮换签名密钥
+// @Summary 轮换Webhook签名密钥
+// @Description 生成新的签名密钥并立即生效，新密钥只返回这一次
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "订阅ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /webhooks/{id}/rotate-secret [post]
+func (c *WebhookController) RotateWebhookSecret() {
+	id, ok := c.parseID("无效的订阅ID")
+	if !ok {
+		return
+	}
+
+	webhookService := c.Container.GetService("webhook").(services.InterfaceWebhookService)
+	secret, err := webhookService.RotateSecret(id)
+	if err != nil {
+		c.failWebhook(err, "轮换签名密钥失败")
+		return
+	}
+
+	response.Success(c.Ctx, gin.H{"secret": secret})
+}
+
+// 7. GetWebhookDeliveries 获取投递记录
+// @Summary 获取Webhook投递记录
+// @Description 分页查询投递记录，可按订阅、事件类型和状态过滤
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param subscription_id query int false "订阅ID"
+// @Param event_type query string false "事件类型，如alarm.triggered"
+// @Param status query string false "投递状态：pending, success, failed"
+// @Param page query int false "页码，默认为1"
+// @Param page_size query int false "每页条数，默认为10"
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} ErrorResponse
+// @Router /webhooks/deliveries [get]
+func (c *WebhookController) GetWebhookDeliveries() {
+	page, _ := strconv.Atoi(c.Ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.Ctx.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	query := services.WebhookDeliveryQuery{
+		EventType: c.Ctx.Query("event_type"),
+		Status:    c.Ctx.Query("status"),
+		Page:      page,
+		PageSize:  pageSize,
+	}
+	if subscriptionID := c.Ctx.Query("subscription_id"); subscriptionID != "" {
+		id, err := strconv.ParseUint(subscriptionID, 10, 32)
+		if err != nil {
+			response.ParamError(c.Ctx, "无效的订阅ID")
+			return
+		}
+		query.SubscriptionID = uint(id)
+	}
+
+	webhookService := c.Container.GetService("webhook").(services.InterfaceWebhookService)
+	deliveries, total, err := webhookService.GetDeliveries(query)
+	if err != nil {
+		response.FailWithMessage(c.Ctx, code.ErrDatabase, "获取投递记录失败: "+err.Error(), nil)
+		return
+	}
+
+	response.Success(c.Ctx, gin.H{
+		"total":       total,
+		"page":        page,
+		"page_size":   pageSize,
+		"total_pages": (total + int64(pageSize) - 1) / int64(pageSize),
+		"data":        deliveries,
+	})
+}
+
+// 8. ReplayWebhookDelivery 重放失败的投递
+// @Summary 重放Webhook投递
+// @Description 重置失败投递的重试次数并立即重新发送，请求体与原投递相同，签名使用当前密钥
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "投递记录ID"
+// @Success 200 {object} models.WebhookDelivery
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /webhooks/deliveries/{id}/replay [post]
+func (c *WebhookController) ReplayWebhookDelivery() {
+	id, ok := c.parseID("无效的投递记录ID")
+	if !ok {
+		return
+	}
+
+	webhookService := c.Container.GetService("webhook").(services.InterfaceWebhookService)
+	delivery, err := webhookService.ReplayDelivery(id)
+	if err != nil {
+		c.failWebhook(err, "重放投递失败")
+		return
+	}
+
+	response.Success(c.Ctx, delivery)
+}
+
+// parseID 解析路径中的ID
+func (c *WebhookController) parseID(message string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Ctx.Param("id"), 10, 32)
+	if err != nil {
+		response.ParamError(c.Ctx, message)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// failWebhook 将Webhook服务错误映射为响应错误码
+func (c *WebhookController) failWebhook(err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrWebhookNotFound):
+		response.FailWithMessage(c.Ctx, code.ErrWebhookNotFound, err.Error(), nil)
+	case errors.Is(err, services.ErrWebhookDeliveryNotFound):
+		response.FailWithMessage(c.Ctx, code.ErrWebhookDeliveryNotFound, err.Error(), nil)
+	case errors.Is(err, services.ErrWebhookNotReplayable):
+		response.FailWithMessage(c.Ctx, code.ErrWebhookNotReplayable, err.Error(), nil)
+	case errors.Is(err, services.ErrInvalidWebhook):
+		response.FailWithMessage(c.Ctx, code.ErrValidation, err.Error(), nil)
+	default:
+		response.FailWithMessage(c.Ctx, code.ErrDatabase, message+": "+err.Error(), nil)
+	}
+}
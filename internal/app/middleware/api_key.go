@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"errors"
+	"ilock-http-service/internal/domain/services"
+	"ilock-http-service/internal/error/code"
+	"ilock-http-service/internal/error/response"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HeaderAPIKey 第三方系统携带API密钥的请求头
+const HeaderAPIKey = "X-API-Key"
+
+var apiKeyService services.InterfaceAPIKeyService
+
+// InitAPIKeyMiddleware 初始化API密钥认证
+func InitAPIKeyMiddleware(service services.InterfaceAPIKeyService) {
+	apiKeyService = service
+}
+
+// accountOnlyPaths 只能由账号本人调用的接口，API密钥没有对应的账号
+var accountOnlyPaths = map[string]bool{
+	"/api/auth/logout":             true,
+	"/api/auth/logout-all":         true,
+	"/api/auth/password":           true,
+	"/api/auth/2fa":                true,
+	"/api/auth/2fa/setup":          true,
+	"/api/auth/2fa/enable":         true,
+	"/api/auth/2fa/disable":        true,
+	"/api/auth/2fa/recovery-codes": true,
+	"/api/rbac/me":                 true,
+}
+
+// authenticateAPIKey 校验请求头中的API密钥，通过后将密钥ID、权限范围和物业写入上下文，失败时写入错误响应
+func authenticateAPIKey(c *gin.Context, rawKey string) bool {
+	if accountOnlyPaths[c.FullPath()] {
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    403,
+			"message": "Insufficient permissions: endpoint requires an account token",
+			"data":    nil,
+		})
+		c.Abort()
+		return false
+	}
+
+	key, err := apiKeyService.Authenticate(rawKey, c.ClientIP())
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrAPIKeyInvalid):
+			response.Fail(c, code.ErrAPIKeyInvalid, nil)
+		case errors.Is(err, services.ErrAPIKeyIPDenied):
+			response.Fail(c, code.ErrAPIKeyIPDenied, nil)
+		default:
+			log.Printf("[APIKey] 校验API密钥失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "Failed to verify API key",
+				"data":    nil,
+			})
+		}
+		c.Abort()
+		return false
+	}
+
+	// 用量统计不影响请求
+	go apiKeyService.RecordUsage(key.ID, c.ClientIP())
+
+	c.Set("userID", key.ID)
+	c.Set("role", services.APIKeyRole)
+	if key.PropertyID != nil {
+		c.Set("propertyID", *key.PropertyID)
+	}
+	scopes := make(map[string]bool)
+	for _, scope := range key.ScopeList() {
+		scopes[scope] = true
+	}
+	c.Set("apiKeyScopes", scopes)
+	return true
+}
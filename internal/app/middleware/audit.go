@@ -0,0 +1,254 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"ilock-http-service/internal/domain/models"
+	"ilock-http-service/internal/domain/services"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+var auditService services.InterfaceAuditService
+
+// InitAuditMiddleware 初始化审计中间件
+func InitAuditMiddleware(service services.InterfaceAuditService) {
+	auditService = service
+}
+
+// auditResource 接口操作的实体，Table 用于读取变更前后的记录
+type auditResource struct {
+	Type  string // 审计日志中的目标类型
+	Table string // 实体所在的表
+	// DataKey 新建接口的响应中实体所在的字段，为空表示 data 本身就是实体
+	DataKey string
+	// Self 操作的是调用者本人，目标ID取当前用户ID
+	Self bool
+}
+
+// auditResources 需要记录字段变更的接口，路径中的 :id 为实体ID；新建接口从响应的 data.id 中取得实体ID
+var auditResources = map[string]auditResource{
+	"/api/admin":     {Type: "admin", Table: "admins"},
+	"/api/admin/:id": {Type: "admin", Table: "admins"},
+
+	"/api/devices":                   {Type: "device", Table: "devices"},
+	"/api/devices/:id":               {Type: "device", Table: "devices"},
+	"/api/devices/:id/rotate-secret": {Type: "device", Table: "devices"},
+	"/api/devices/:id/building":      {Type: "device", Table: "devices"},
+	"/api/devices/:id/households":    {Type: "device", Table: "devices"},
+
+	"/api/residents":     {Type: "resident", Table: "residents"},
+	"/api/residents/:id": {Type: "resident", Table: "residents"},
+
+	"/api/staffs":                        {Type: "staff", Table: "property_staffs"},
+	"/api/staffs/:id":                    {Type: "staff", Table: "property_staffs"},
+	"/api/staffs/:id/devices":            {Type: "staff", Table: "property_staffs"},
+	"/api/staffs/:id/devices/:device_id": {Type: "staff", Table: "property_staffs"},
+	"/api/staffs/:id/credentials":        {Type: "staff", Table: "property_staffs"},
+	"/api/staffs/:id/reset-password":     {Type: "staff", Table: "property_staffs"},
+	"/api/staffs/:id/enable":             {Type: "staff", Table: "property_staffs"},
+	"/api/staffs/:id/disable":            {Type: "staff", Table: "property_staffs"},
+
+	"/api/call-records/:id/feedback": {Type: "call_record", Table: "call_records"},
+
+	"/api/emergency/:id":                    {Type: "emergency_log", Table: "emergency_logs"},
+	"/api/emergency/trigger":                {Type: "emergency_log", Table: "emergency_logs"},
+	"/api/emergency/contacts":               {Type: "emergency_contact", Table: "emergency_contacts"},
+	"/api/emergency/contacts/:id":           {Type: "emergency_contact", Table: "emergency_contacts"},
+	"/api/emergency/alarms/:id/acknowledge": {Type: "emergency_alarm", Table: "emergency_alarms"},
+	"/api/emergency/alarms/:id/assign":      {Type: "emergency_alarm", Table: "emergency_alarms"},
+	"/api/emergency/alarms/:id/resolve":     {Type: "emergency_alarm", Table: "emergency_alarms"},
+	"/api/emergency/unlock":                 {Type: "emergency_unlock", Table: "emergency_unlock_sessions"},
+	"/api/emergency/unlock-all":             {Type: "emergency_unlock", Table: "emergency_unlock_sessions"},
+	"/api/emergency/unlocks/:id/end":        {Type: "emergency_unlock", Table: "emergency_unlock_sessions"},
+
+	"/api/properties":     {Type: "property", Table: "properties"},
+	"/api/properties/:id": {Type: "property", Table: "properties"},
+
+	"/api/buildings":     {Type: "building", Table: "buildings"},
+	"/api/buildings/:id": {Type: "building", Table: "buildings"},
+
+	"/api/households":                        {Type: "household", Table: "households"},
+	"/api/households/:id":                    {Type: "household", Table: "households"},
+	"/api/households/:id/devices":            {Type: "household", Table: "households"},
+	"/api/households/:id/devices/:device_id": {Type: "household", Table: "households"},
+
+	"/api/import/buildings":  {Type: "building", Table: "buildings"},
+	"/api/import/households": {Type: "household", Table: "households"},
+	"/api/import/residents":  {Type: "resident", Table: "residents"},
+
+	"/api/me":               {Type: "resident", Table: "residents", Self: true},
+	"/api/me/password":      {Type: "resident", Table: "residents", Self: true},
+	"/api/me/passcodes":     {Type: "passcode", Table: "passcodes"},
+	"/api/me/passcodes/:id": {Type: "passcode", Table: "passcodes"},
+
+	"/api/rbac/roles":        {Type: "role", Table: "roles"},
+	"/api/rbac/roles/:id":    {Type: "role", Table: "roles"},
+	"/api/rbac/bindings":     {Type: "role_binding", Table: "role_bindings"},
+	"/api/rbac/bindings/:id": {Type: "role_binding", Table: "role_bindings"},
+
+	"/api/webhooks":                   {Type: "webhook", Table: "webhook_subscriptions", DataKey: "subscription"},
+	"/api/webhooks/:id":               {Type: "webhook", Table: "webhook_subscriptions"},
+	"/api/webhooks/:id/rotate-secret": {Type: "webhook", Table: "webhook_subscriptions"},
+
+	"/api/api-keys":            {Type: "api_key", Table: "api_keys", DataKey: "api_key"},
+	"/api/api-keys/:id":        {Type: "api_key", Table: "api_keys"},
+	"/api/api-keys/:id/rotate": {Type: "api_key", Table: "api_keys"},
+}
+
+// auditSkipPaths 不记录的写操作：注销本人会话不改变数据，两步验证和账号解锁由对应的服务记录
+var auditSkipPaths = map[string]bool{
+	"/api/auth/logout":             true,
+	"/api/auth/logout-all":         true,
+	"/api/auth/unlock":             true,
+	"/api/auth/2fa/setup":          true,
+	"/api/auth/2fa/enable":         true,
+	"/api/auth/2fa/disable":        true,
+	"/api/auth/2fa/recovery-codes": true,
+	"/api/auth/2fa/reset":          true,
+}
+
+// Audit 记录认证后的写操作（POST、PUT、PATCH、DELETE），需放在认证中间件之后
+// 操作人、IP、接口和返回的状态码写入审计日志；接口操作的实体在 auditResources 中声明时，同时记录实体变更前后的字段差异
+func Audit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if auditService == nil || c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead ||
+			c.Request.Method == http.MethodOptions || auditSkipPaths[c.FullPath()] {
+			c.Next()
+			return
+		}
+
+		role, actorID := auditActor(c)
+		resource, tracked := auditResources[c.FullPath()]
+
+		var targetID uint
+		var before map[string]interface{}
+		if tracked {
+			if resource.Self {
+				targetID = actorID
+			} else if id, err := strconv.ParseUint(c.Param("id"), 10, 64); err == nil {
+				targetID = uint(id)
+			}
+			if targetID > 0 {
+				snapshot, err := auditService.Snapshot(resource.Table, targetID)
+				if err != nil {
+					log.Printf("[Audit] 读取 %s:%d 变更前的记录失败: %v", resource.Type, targetID, err)
+				}
+				before = snapshot
+			}
+		}
+
+		// 新建接口需要从响应中取得实体ID
+		var writer *responseWriter
+		if tracked && targetID == 0 {
+			writer = &responseWriter{ResponseWriter: c.Writer, body: &bytes.Buffer{}}
+			c.Writer = writer
+		}
+
+		c.Next()
+
+		status := c.Writer.Status()
+		entry := &models.SystemLog{
+			ActorType:  role,
+			ActorID:    actorID,
+			PropertyID: auditPropertyID(c),
+			Action:     c.Request.Method + " " + c.FullPath(),
+			IPAddress:  c.ClientIP(),
+			StatusCode: status,
+		}
+		if role == "admin" {
+			entry.AdminID = &actorID
+		}
+
+		var after map[string]interface{}
+		if tracked {
+			if targetID == 0 && status < http.StatusBadRequest {
+				targetID = createdEntityID(writer.body.Bytes(), resource.DataKey)
+			}
+			if targetID > 0 {
+				entry.Target = fmt.Sprintf("%s:%d", resource.Type, targetID)
+				if status < http.StatusBadRequest {
+					snapshot, err := auditService.Snapshot(resource.Table, targetID)
+					if err != nil {
+						log.Printf("[Audit] 读取 %s:%d 变更后的记录失败: %v", resource.Type, targetID, err)
+					}
+					after = snapshot
+				} else {
+					// 请求失败时数据没有变化，不记录字段
+					before = nil
+				}
+			} else {
+				entry.Target = resource.Type
+			}
+		}
+
+		if err := auditService.Record(entry, before, after); err != nil {
+			log.Printf("[Audit] 写入审计日志 %s 失败: %v", entry.Action, err)
+		}
+	}
+}
+
+// auditActor 获取认证中间件写入上下文的角色和用户ID
+func auditActor(c *gin.Context) (string, uint) {
+	role, _ := c.Get("role")
+	roleStr, _ := role.(string)
+
+	var userID uint
+	if value, exists := c.Get("userID"); exists {
+		switch id := value.(type) {
+		case float64:
+			userID = uint(id)
+		case uint:
+			userID = id
+		}
+	}
+	return roleStr, userID
+}
+
+// auditPropertyID 获取操作人所属的物业，平台账号返回nil
+func auditPropertyID(c *gin.Context) *uint {
+	value, exists := c.Get("propertyID")
+	if !exists {
+		return nil
+	}
+
+	var id uint
+	switch v := value.(type) {
+	case uint:
+		id = v
+	case float64:
+		id = uint(v)
+	default:
+		return nil
+	}
+	return &id
+}
+
+// createdEntityID 从新建接口的响应 {"data": {...}} 中取得实体ID
+func createdEntityID(body []byte, dataKey string) uint {
+	var resp struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil || resp.Data == nil {
+		return 0
+	}
+
+	data := resp.Data
+	if dataKey != "" {
+		var nested map[string]json.RawMessage
+		if err := json.Unmarshal(resp.Data[dataKey], &nested); err != nil {
+			return 0
+		}
+		data = nested
+	}
+
+	var id uint
+	if err := json.Unmarshal(data["id"], &id); err != nil {
+		return 0
+	}
+	return id
+}
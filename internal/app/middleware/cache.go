@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/md5"
 	"encoding/hex"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"sort"
@@ -68,8 +69,14 @@ func defaultKeyFunc(c *gin.Context) string {
 		}
 	}
 
-	// 生成缓存键
+	// 生成缓存键，不同角色、不同物业看到的数据范围不同，需要区分
 	key := path + "?" + queryString
+	if role, exists := c.Get("role"); exists && role != nil {
+		key += "#role=" + fmt.Sprint(role)
+	}
+	if propID, exists := c.Get("propertyID"); exists && propID != nil {
+		key += "#property=" + fmt.Sprint(propID)
+	}
 
 	// 使用MD5哈希缓存键
 	hasher := md5.New()
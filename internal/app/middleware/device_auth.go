@@ -0,0 +1,279 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"ilock-http-service/internal/domain/services"
+	"ilock-http-service/internal/error/code"
+	"ilock-http-service/internal/error/response"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// 设备签名请求的请求头
+const (
+	HeaderDeviceID  = "X-Device-ID"
+	HeaderTimestamp = "X-Timestamp"
+	HeaderNonce     = "X-Nonce"
+	HeaderSignature = "X-Signature"
+)
+
+var deviceSignatureService services.InterfaceDeviceSignatureService
+
+// InitDeviceSignatureMiddleware 初始化设备签名请求校验
+func InitDeviceSignatureMiddleware(service services.InterfaceDeviceSignatureService) {
+	deviceSignatureService = service
+}
+
+// AuthenticateDevice 验证设备令牌或设备签名，只允许设备调用，设备ID写入上下文的 deviceID
+func AuthenticateDevice() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// 无法管理令牌的设备可以改用签名请求
+		if c.GetHeader(HeaderSignature) != "" {
+			if authenticateSignedRequest(c) {
+				c.Next()
+			}
+			return
+		}
+
+		claims, ok := parseBearerClaims(c)
+		if !ok {
+			return
+		}
+
+		if role, _ := claims["role"].(string); role != services.DeviceRole {
+			c.JSON(http.StatusForbidden, gin.H{
+				"code":    403,
+				"message": "Insufficient permissions: requires device token",
+				"data":    nil,
+			})
+			c.Abort()
+			return
+		}
+
+		if !setDeviceContext(c, claims) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// AuthenticateDeviceOrUser 设备令牌、设备签名和账号令牌都可以访问，用于设备与居民端共用的接口
+func AuthenticateDeviceOrUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader(HeaderSignature) != "" {
+			if authenticateSignedRequest(c) {
+				c.Next()
+			}
+			return
+		}
+
+		claims, ok := parseBearerClaims(c)
+		if !ok {
+			return
+		}
+
+		role, _ := claims["role"].(string)
+		switch role {
+		case services.DeviceRole:
+			if !setDeviceContext(c, claims) {
+				return
+			}
+		case "user", "staff", "admin":
+			c.Set("userID", claims["user_id"])
+			c.Set("role", role)
+			if propID, exists := claims["property_id"]; exists && propID != nil {
+				c.Set("propertyID", propID)
+			}
+			c.Set("claims", claims)
+		default:
+			c.JSON(http.StatusForbidden, gin.H{
+				"code":    403,
+				"message": "Insufficient permissions: requires device or user token",
+				"data":    nil,
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// BindDeviceID 设备调用时，请求体中的 device_id 必须是令牌中的设备，需放在设备认证中间件之后；
+// 账号调用和未携带 device_id 的请求直接放行，由处理函数校验参数
+func BindDeviceID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, exists := c.Get("deviceID")
+		deviceID, _ := value.(uint)
+		if !exists || deviceID == 0 || c.Request.Body == nil {
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "Failed to read request body",
+				"data":    nil,
+			})
+			c.Abort()
+			return
+		}
+		// 处理函数还要绑定请求体，读取后放回
+		c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
+
+		var payload struct {
+			DeviceID json.RawMessage `json:"device_id"`
+		}
+		if err := json.Unmarshal(body, &payload); err != nil || len(payload.DeviceID) == 0 || string(payload.DeviceID) == "null" {
+			c.Next()
+			return
+		}
+
+		if requested, ok := parseDeviceID(payload.DeviceID); !ok || requested != deviceID {
+			response.FailWithMessage(c, code.ErrDeviceMismatch, "请求中的设备与设备令牌不一致", nil)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// parseBearerClaims 校验授权头中的令牌并返回claims，失败时写入401响应
+func parseBearerClaims(c *gin.Context) (jwt.MapClaims, bool) {
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":    401,
+			"message": "Authorization header is required",
+			"data":    nil,
+		})
+		c.Abort()
+		return nil, false
+	}
+
+	token, err := jwtService.ValidateToken(extractToken(authHeader))
+	if err != nil || !token.Valid {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":    401,
+			"message": "Invalid or expired token",
+			"data":    nil,
+		})
+		c.Abort()
+		return nil, false
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":    401,
+			"message": "Invalid token claims",
+			"data":    nil,
+		})
+		c.Abort()
+		return nil, false
+	}
+
+	if rejectRevokedToken(c, claims) || rejectRestrictedToken(c, claims) {
+		return nil, false
+	}
+	return claims, true
+}
+
+// authenticateSignedRequest 校验设备签名请求，失败时写入错误响应；请求体读取后放回，供处理函数绑定
+func authenticateSignedRequest(c *gin.Context) bool {
+	var body []byte
+	if c.Request.Body != nil {
+		var err error
+		if body, err = io.ReadAll(c.Request.Body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "Failed to read request body",
+				"data":    nil,
+			})
+			c.Abort()
+			return false
+		}
+		c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
+	}
+
+	deviceID, err := deviceSignatureService.VerifyRequest(services.SignedRequest{
+		DeviceID:  c.GetHeader(HeaderDeviceID),
+		Timestamp: c.GetHeader(HeaderTimestamp),
+		Nonce:     c.GetHeader(HeaderNonce),
+		Signature: c.GetHeader(HeaderSignature),
+		Method:    c.Request.Method,
+		Path:      c.Request.URL.RequestURI(),
+		Body:      body,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrSignatureInvalid):
+			response.Fail(c, code.ErrDeviceSignatureInvalid, nil)
+		case errors.Is(err, services.ErrSignatureExpired):
+			response.Fail(c, code.ErrDeviceSignatureExpired, nil)
+		case errors.Is(err, services.ErrNonceReused):
+			response.Fail(c, code.ErrDeviceNonceReused, nil)
+		default:
+			// 随机数无法记录时拒绝请求，否则无法防止重放
+			log.Printf("[DeviceAuth] 校验设备签名失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "Failed to verify request signature",
+				"data":    nil,
+			})
+		}
+		c.Abort()
+		return false
+	}
+
+	setDevice(c, deviceID)
+	return true
+}
+
+// setDeviceContext 将设备令牌中的设备ID写入上下文，设备令牌不带物业ID
+func setDeviceContext(c *gin.Context, claims jwt.MapClaims) bool {
+	deviceID, ok := claims["device_id"].(float64)
+	if !ok || deviceID <= 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":    401,
+			"message": "Invalid token claims: missing device_id",
+			"data":    nil,
+		})
+		c.Abort()
+		return false
+	}
+
+	setDevice(c, uint(deviceID))
+	c.Set("claims", claims)
+	return true
+}
+
+// setDevice 将已认证的设备写入上下文，设备的用户ID就是设备ID
+func setDevice(c *gin.Context, deviceID uint) {
+	c.Set("deviceID", deviceID)
+	c.Set("userID", deviceID)
+	c.Set("role", services.DeviceRole)
+}
+
+// parseDeviceID 解析请求体中的设备ID，设备固件可能以数字或字符串发送
+func parseDeviceID(raw json.RawMessage) (uint, bool) {
+	text := strings.TrimSpace(string(raw))
+	if unquoted, err := strconv.Unquote(text); err == nil {
+		text = strings.TrimSpace(unquoted)
+	}
+
+	id, err := strconv.ParseUint(text, 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return uint(id), true
+}
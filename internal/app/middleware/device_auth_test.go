@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"ilock-http-service/internal/domain/services"
+	"ilock-http-service/internal/error/code"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeSignatureService 记录收到的签名请求，返回预设的结果
+type fakeSignatureService struct {
+	received services.SignedRequest
+	deviceID uint
+	err      error
+}
+
+func (s *fakeSignatureService) VerifyRequest(req services.SignedRequest) (uint, error) {
+	s.received = req
+	return s.deviceID, s.err
+}
+
+// serveSignedRequest 让请求经过 authenticateSignedRequest，返回响应和处理函数读到的请求体
+func serveSignedRequest(t *testing.T, service services.InterfaceDeviceSignatureService, req *http.Request) (*httptest.ResponseRecorder, string, uint) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	InitDeviceSignatureMiddleware(service)
+
+	var handlerBody string
+	var handlerDevice uint
+	r := gin.New()
+	r.POST("/api/device/status", func(c *gin.Context) {
+		if !authenticateSignedRequest(c) {
+			return
+		}
+		body, _ := io.ReadAll(c.Request.Body)
+		handlerBody = string(body)
+		handlerDevice = c.GetUint("deviceID")
+		c.Status(http.StatusNoContent)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w, handlerBody, handlerDevice
+}
+
+func TestAuthenticateSignedRequestPassesCanonicalParts(t *testing.T) {
+	service := &fakeSignatureService{deviceID: 7}
+	body := `{"device_id":7}`
+	req := httptest.NewRequest(http.MethodPost, "/api/device/status?full=1", strings.NewReader(body))
+	req.Header.Set(HeaderDeviceID, "7")
+	req.Header.Set(HeaderTimestamp, "1700000000")
+	req.Header.Set(HeaderNonce, "42")
+	req.Header.Set(HeaderSignature, "abcd")
+
+	w, handlerBody, handlerDevice := serveSignedRequest(t, service, req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusNoContent)
+	}
+
+	got := service.received
+	if got.DeviceID != "7" || got.Timestamp != "1700000000" || got.Nonce != "42" || got.Signature != "abcd" {
+		t.Fatalf("headers not passed through: %+v", got)
+	}
+	if got.Method != http.MethodPost || got.Path != "/api/device/status?full=1" || string(got.Body) != body {
+		t.Fatalf("canonical parts = %s %s %s", got.Method, got.Path, got.Body)
+	}
+	if handlerBody != body {
+		t.Fatalf("handler read body %q, want %q", handlerBody, body)
+	}
+	if handlerDevice != 7 {
+		t.Fatalf("deviceID = %d, want 7", handlerDevice)
+	}
+}
+
+func TestAuthenticateSignedRequestErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   int
+	}{
+		{name: "invalid signature", err: services.ErrSignatureInvalid, wantStatus: code.GetStatus(code.ErrDeviceSignatureInvalid), wantCode: code.ErrDeviceSignatureInvalid},
+		{name: "stale timestamp", err: services.ErrSignatureExpired, wantStatus: code.GetStatus(code.ErrDeviceSignatureExpired), wantCode: code.ErrDeviceSignatureExpired},
+		{name: "replayed nonce", err: services.ErrNonceReused, wantStatus: code.GetStatus(code.ErrDeviceNonceReused), wantCode: code.ErrDeviceNonceReused},
+		{name: "nonce store unavailable", err: errors.New("connection refused"), wantStatus: http.StatusInternalServerError, wantCode: 500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/device/status", strings.NewReader("{}"))
+			w, _, _ := serveSignedRequest(t, &fakeSignatureService{err: tt.err}, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			var resp struct {
+				Code int `json:"code"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if resp.Code != tt.wantCode {
+				t.Fatalf("code = %d, want %d", resp.Code, tt.wantCode)
+			}
+		})
+	}
+}
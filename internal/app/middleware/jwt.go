@@ -2,20 +2,79 @@ package middleware
 
 import (
 	"ilock-http-service/internal/domain/services"
-	"ilock-http-service/internal/infrastructure/config"
+	"ilock-http-service/internal/error/code"
+	"ilock-http-service/internal/error/response"
+	"log"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
-	"gorm.io/gorm"
 )
 
 var jwtService services.InterfaceJWTService
 
-// InitAuthMiddleware 初始化认证中间件
-func InitAuthMiddleware(cfg *config.Config, db *gorm.DB) {
-	jwtService = services.NewJWTService(cfg, db)
+// InitAuthMiddleware 初始化认证中间件，与登录、注销共用同一个JWT服务
+func InitAuthMiddleware(service services.InterfaceJWTService) {
+	jwtService = service
+}
+
+// rejectRevokedToken 已注销的令牌返回401；无法确认令牌是否已注销时返回503，不放行
+func rejectRevokedToken(c *gin.Context, claims jwt.MapClaims) bool {
+	revoked, err := jwtService.IsTokenRevoked(claims)
+	if err != nil {
+		log.Printf("[Auth] 检查令牌是否已注销失败: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code":    503,
+			"message": "Unable to verify token status, please retry later",
+			"data":    nil,
+		})
+		c.Abort()
+		return true
+	}
+	if !revoked {
+		return false
+	}
+
+	c.JSON(http.StatusUnauthorized, gin.H{
+		"code":    401,
+		"message": "Token has been revoked",
+		"data":    nil,
+	})
+	c.Abort()
+	return true
+}
+
+// passwordChangeAllowedPaths 必须修改密码的账号仍可以访问的接口
+var passwordChangeAllowedPaths = map[string]bool{
+	"/api/auth/password":   true,
+	"/api/auth/logout":     true,
+	"/api/auth/logout-all": true,
+}
+
+// twoFactorSetupAllowedPaths 必须绑定两步验证的管理员仍可以访问的接口
+var twoFactorSetupAllowedPaths = map[string]bool{
+	"/api/auth/2fa":        true,
+	"/api/auth/2fa/setup":  true,
+	"/api/auth/2fa/enable": true,
+	"/api/auth/password":   true,
+	"/api/auth/logout":     true,
+	"/api/auth/logout-all": true,
+}
+
+// rejectRestrictedToken 令牌要求先修改密码或绑定两步验证时，只放行对应的接口和注销接口，其他接口返回403
+func rejectRestrictedToken(c *gin.Context, claims jwt.MapClaims) bool {
+	if required, _ := claims["pwd_change"].(bool); required && !passwordChangeAllowedPaths[c.FullPath()] {
+		response.Fail(c, code.ErrPasswordChangeRequired, nil)
+		c.Abort()
+		return true
+	}
+	if required, _ := claims["mfa_setup"].(bool); required && !twoFactorSetupAllowedPaths[c.FullPath()] {
+		response.Fail(c, code.ErrTwoFactorSetupRequired, nil)
+		c.Abort()
+		return true
+	}
+	return false
 }
 
 // extractToken 从授权头中提取token
@@ -66,6 +125,10 @@ func AuthenticateSystemAdmin() gin.HandlerFunc {
 				return
 			}
 
+			if rejectRevokedToken(c, claims) || rejectRestrictedToken(c, claims) {
+				return
+			}
+
 			// 检查是否是系统管理员
 			if role, exists := claims["role"].(string); !exists || role != "admin" {
 				c.JSON(http.StatusForbidden, gin.H{
@@ -80,6 +143,10 @@ func AuthenticateSystemAdmin() gin.HandlerFunc {
 			// 存储claims到上下文
 			c.Set("userID", claims["user_id"])
 			c.Set("role", claims["role"])
+			// propertyID可能不存在，所以只有在claims中有值时才设置
+			if propID, exists := claims["property_id"]; exists && propID != nil {
+				c.Set("propertyID", propID)
+			}
 			c.Set("claims", claims)
 			c.Next()
 		} else {
@@ -133,6 +200,10 @@ func AuthenticatePropertyStaff() gin.HandlerFunc {
 				return
 			}
 
+			if rejectRevokedToken(c, claims) || rejectRestrictedToken(c, claims) {
+				return
+			}
+
 			// 检查是否是物业人员
 			role, exists := claims["role"].(string)
 			if !exists || (role != "staff" && role != "admin") { // 管理员也可以访问物业人员的接口
@@ -166,9 +237,16 @@ func AuthenticatePropertyStaff() gin.HandlerFunc {
 	}
 }
 
-// AuthenticateUser 验证普通用户权限
+// AuthenticateUser 验证普通用户权限，第三方系统可以使用API密钥代替令牌
 func AuthenticateUser() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if rawKey := c.GetHeader(HeaderAPIKey); rawKey != "" {
+			if authenticateAPIKey(c, rawKey) {
+				c.Next()
+			}
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{
@@ -205,6 +283,10 @@ func AuthenticateUser() gin.HandlerFunc {
 				return
 			}
 
+			if rejectRevokedToken(c, claims) || rejectRestrictedToken(c, claims) {
+				return
+			}
+
 			// 检查是否有任何有效角色
 			role, exists := claims["role"].(string)
 			if !exists || (role != "user" && role != "staff" && role != "admin") {
@@ -220,6 +302,10 @@ func AuthenticateUser() gin.HandlerFunc {
 			// 存储claims到上下文
 			c.Set("userID", claims["user_id"])
 			c.Set("role", role)
+			// propertyID可能不存在，所以只有在claims中有值时才设置
+			if propID, exists := claims["property_id"]; exists && propID != nil {
+				c.Set("propertyID", propID)
+			}
 			c.Set("claims", claims)
 			c.Next()
 		} else {
@@ -294,9 +380,16 @@ func Authentication() gin.HandlerFunc {
 			c.Abort()
 			return
 		}
+		if rejectRevokedToken(c, claims) || rejectRestrictedToken(c, claims) {
+			return
+		}
 
 		c.Set("userID", claims["user_id"])
 		c.Set("role", claims["role"])
+		// propertyID可能不存在，所以只有在claims中有值时才设置
+		if propID, exists := claims["property_id"]; exists && propID != nil {
+			c.Set("propertyID", propID)
+		}
 		c.Set("claims", claims)
 		c.Next()
 	}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"ilock-http-service/internal/domain/services"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+var rbacService services.InterfaceRBACService
+
+// InitRBACMiddleware 初始化权限中间件
+func InitRBACMiddleware(service services.InterfaceRBACService) {
+	rbacService = service
+}
+
+// RequirePermission 校验当前账号是否拥有指定权限，需放在认证中间件之后
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, _ := c.Get("role")
+		roleStr, _ := role.(string)
+
+		var userID uint
+		if value, exists := c.Get("userID"); exists {
+			switch id := value.(type) {
+			case float64:
+				userID = uint(id)
+			case uint:
+				userID = id
+			}
+		}
+
+		if roleStr == "" || userID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code":    401,
+				"message": "Authentication required",
+				"data":    nil,
+			})
+			c.Abort()
+			return
+		}
+
+		// API密钥只能使用创建时授予的权限范围
+		if roleStr == services.APIKeyRole {
+			scopes, _ := c.Get("apiKeyScopes")
+			if allowed, _ := scopes.(map[string]bool); !allowed[permission] {
+				c.JSON(http.StatusForbidden, gin.H{
+					"code":    403,
+					"message": "Insufficient permissions: API key scope requires " + permission,
+					"data":    nil,
+				})
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+
+		allowed, err := rbacService.HasPermission(roleStr, userID, permission)
+		if err != nil {
+			log.Printf("[RBAC] 查询权限失败: role=%s, user_id=%d, err=%v", roleStr, userID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "Failed to check permission",
+				"data":    nil,
+			})
+			c.Abort()
+			return
+		}
+
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{
+				"code":    403,
+				"message": "Insufficient permissions: requires " + permission,
+				"data":    nil,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -4,8 +4,11 @@ import (
 	_ "ilock-http-service/docs"
 	"ilock-http-service/internal/app/controllers"
 	"ilock-http-service/internal/app/middleware"
+	"ilock-http-service/internal/domain/services"
 	"ilock-http-service/internal/domain/services/container"
 	"ilock-http-service/internal/infrastructure/config"
+	"log"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -19,6 +22,11 @@ func SetupRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
 	// 初始化 Gin
 	r := gin.Default()
 
+	// 只有来自可信代理的请求才使用X-Forwarded-For，否则API密钥IP白名单和登录IP封禁都可以被伪造的请求头绕过
+	if err := r.SetTrustedProxies(trustedProxies(cfg)); err != nil {
+		log.Fatalf("TRUSTED_PROXIES 配置无效: %v", err)
+	}
+
 	// 添加 CORS 中间件
 	r.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "http://localhost:20033")
@@ -40,7 +48,11 @@ func SetupRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
 	// 创建服务容器
 	serviceContainer := container.NewServiceContainer(db, cfg, nil)
 	// 初始化中间件
-	middleware.InitAuthMiddleware(cfg, db)
+	middleware.InitAuthMiddleware(serviceContainer.GetService("jwt").(services.InterfaceJWTService))
+	middleware.InitRBACMiddleware(serviceContainer.GetService("rbac").(services.InterfaceRBACService))
+	middleware.InitDeviceSignatureMiddleware(serviceContainer.GetService("device_signature").(services.InterfaceDeviceSignatureService))
+	middleware.InitAPIKeyMiddleware(serviceContainer.GetService("api_key").(services.InterfaceAPIKeyService))
+	middleware.InitAuditMiddleware(serviceContainer.GetService("audit").(services.InterfaceAuditService))
 	// 添加 Swagger 文档路由
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
@@ -49,6 +61,17 @@ func SetupRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
 	return r
 }
 
+// trustedProxies 解析配置中的可信代理列表，未配置时返回nil，即不信任任何代理
+func trustedProxies(cfg *config.Config) []string {
+	var proxies []string
+	for _, proxy := range strings.Split(cfg.TrustedProxies, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
 // registerRoutes 配置所有API路由
 func registerRoutes(
 	r *gin.Engine,
@@ -81,30 +104,43 @@ func registerPublicRoutes(
 
 	// 认证路由
 	api.POST("/auth/login", controllers.HandleJWTFunc(container, "login"))
+	api.POST("/auth/refresh", controllers.HandleJWTFunc(container, "refresh"))
+	api.POST("/auth/2fa/verify", middleware.PathRateLimiter(5, 10), controllers.HandleJWTFunc(container, "verifyTwoFactor"))
+	// 居民短信验证码登录路由
+	api.POST("/auth/sms/request", middleware.PathRateLimiter(5, 10), controllers.HandleJWTFunc(container, "requestLoginCode"))
+	api.POST("/auth/sms/login", middleware.PathRateLimiter(5, 10), controllers.HandleJWTFunc(container, "loginWithCode"))
+	// 找回密码路由，验证码通过短信或邮件发送
+	api.GET("/auth/password-policy", controllers.HandlePasswordFunc(container, "getPasswordPolicy"))
+	api.POST("/auth/password-reset/request", middleware.PathRateLimiter(5, 10), controllers.HandlePasswordFunc(container, "requestPasswordReset"))
+	api.POST("/auth/password-reset/confirm", middleware.PathRateLimiter(5, 10), controllers.HandlePasswordFunc(container, "confirmPasswordReset"))
+	// 设备认证路由，设备用序列号和密钥换取设备令牌
+	api.POST("/device/auth", middleware.PathRateLimiter(5, 10), controllers.HandleDeviceFunc(container, "authenticateDevice"))
 	// 阿里云RTC路由
 	rtcGroup := api.Group("/rtc")
 	rtcGroup.Use(middleware.PathRateLimiter(5, 10)) // 每秒5个请求，最多突发10个
-	rtcGroup.POST("/token", controllers.HandleRTCFunc(container, "getToken"))
-	rtcGroup.POST("/call", controllers.HandleRTCFunc(container, "startCall"))
+	rtcGroup.POST("/token", middleware.AuthenticateDeviceOrUser(), controllers.HandleRTCFunc(container, "getToken"))
+	rtcGroup.POST("/call", middleware.AuthenticateDevice(), middleware.BindDeviceID(), controllers.HandleRTCFunc(container, "startCall"))
 	// 腾讯云RTC路由
 	trtcGroup := api.Group("/trtc")
 	trtcGroup.Use(middleware.PathRateLimiter(5, 10)) // 每秒5个请求，最多突发10个
-	trtcGroup.POST("/usersig", controllers.HandleTencentRTCFunc(container, "getUserSig"))
-	trtcGroup.POST("/call", controllers.HandleTencentRTCFunc(container, "startCall"))
+	trtcGroup.POST("/usersig", middleware.AuthenticateDeviceOrUser(), controllers.HandleTencentRTCFunc(container, "getUserSig"))
+	trtcGroup.POST("/call", middleware.AuthenticateDevice(), middleware.BindDeviceID(), controllers.HandleTencentRTCFunc(container, "startCall"))
 
 	// MQTT通话和消息路由组 - 更新以匹配API文档
 	mqttGroup := api.Group("/mqtt")
 	mqttGroup.Use(middleware.PathRateLimiter(20, 40))                                                                                                             // 每秒20个请求，最多突发40个
-	mqttGroup.POST("/call", controllers.HandleMQTTCallFunc(container, "initiateCall"))                                                                            // 发起通话，支持可选的户号参数或住户电话
-	mqttGroup.POST("/controller/device", controllers.HandleMQTTCallFunc(container, "callerAction"))                                                               // 修改路径从caller-action到controller/device
-	mqttGroup.POST("/controller/resident", controllers.HandleMQTTCallFunc(container, "calleeAction"))                                                             // 修改路径从callee-action到controller/resident
+	mqttGroup.POST("/call", middleware.AuthenticateDevice(), middleware.BindDeviceID(), controllers.HandleMQTTCallFunc(container, "initiateCall"))                // 发起通话，支持可选的户号参数或住户电话
+	mqttGroup.POST("/controller/device", middleware.AuthenticateDevice(), controllers.HandleMQTTCallFunc(container, "callerAction"))                              // 修改路径从caller-action到controller/device
+	mqttGroup.POST("/controller/resident", middleware.AuthenticateUser(), controllers.HandleMQTTCallFunc(container, "calleeAction"))                              // 居民只能操作呼叫本人的通话
 	mqttGroup.GET("/session", middleware.Cache(middleware.CacheConfig{Expiration: 5 * time.Second}), controllers.HandleMQTTCallFunc(container, "getCallSession")) // 修改为GET请求
-	mqttGroup.POST("/end-session", controllers.HandleMQTTCallFunc(container, "endCallSession"))
-	mqttGroup.POST("/device/status", controllers.HandleMQTTCallFunc(container, "publishDeviceStatus"))
+	mqttGroup.POST("/end-session", middleware.AuthenticateUser(), controllers.HandleMQTTCallFunc(container, "endCallSession"))                                    // 居民只能结束本人的通话，管理员和物业员工需要 call_record:write 权限
+	mqttGroup.POST("/device/status", middleware.AuthenticateDevice(), middleware.BindDeviceID(), controllers.HandleMQTTCallFunc(container, "publishDeviceStatus"))
 	mqttGroup.POST("/system/message", controllers.HandleMQTTCallFunc(container, "publishSystemMessage"))
 
-	// 设备健康检测路由
-	api.POST("/device/status", controllers.HandleDeviceFunc(container, "checkDeviceHealth"))
+	// 设备健康检测路由，需要设备令牌，请求体中的 device_id 必须是令牌中的设备
+	api.POST("/device/status", middleware.AuthenticateDevice(), middleware.BindDeviceID(), controllers.HandleDeviceFunc(container, "checkDeviceHealth"))
+	// 设备事件上报路由（门磁、防拆等）
+	api.POST("/device/event", middleware.AuthenticateDevice(), middleware.BindDeviceID(), controllers.HandleDeviceEventFunc(container, "reportDeviceEvent"))
 }
 
 // registerAuthenticatedRoutes 注册需要认证的路由
@@ -114,91 +150,209 @@ func registerAuthenticatedRoutes(
 ) {
 	// 添加认证中间件
 	auth := api.Group("/")
-	auth.Use(middleware.AuthenticateSystemAdmin())
+	// 认证后按路由声明的权限校验，管理员、物业员工和居民共用同一套接口
+	auth.Use(middleware.AuthenticateUser())
 
 	// 添加通用限流中间件 - 每秒30个请求，最多突发50个请求
 	auth.Use(middleware.IPRateLimiter(30, 50))
 
+	// 记录所有写操作的操作人、IP和字段变更
+	auth.Use(middleware.Audit())
+
 	// 管理员路由
 	adminGroup := auth.Group("/admin")
-	adminGroup.GET("", middleware.Cache(middleware.CacheConfig{Expiration: 1 * time.Minute}), controllers.HandleAdminFunc(container, "getAdmins"))
-	adminGroup.GET("/:id", middleware.Cache(middleware.CacheConfig{Expiration: 1 * time.Minute}), controllers.HandleAdminFunc(container, "getAdmin"))
-	adminGroup.POST("", controllers.HandleAdminFunc(container, "createAdmin"))
-	adminGroup.PUT("/:id", controllers.HandleAdminFunc(container, "updateAdmin"))
-	adminGroup.DELETE("/:id", controllers.HandleAdminFunc(container, "deleteAdmin"))
+	adminGroup.GET("", middleware.RequirePermission(services.PermAdminRead), middleware.Cache(middleware.CacheConfig{Expiration: 1 * time.Minute}), controllers.HandleAdminFunc(container, "getAdmins"))
+	adminGroup.GET("/:id", middleware.RequirePermission(services.PermAdminRead), middleware.Cache(middleware.CacheConfig{Expiration: 1 * time.Minute}), controllers.HandleAdminFunc(container, "getAdmin"))
+	adminGroup.POST("", middleware.RequirePermission(services.PermAdminWrite), controllers.HandleAdminFunc(container, "createAdmin"))
+	adminGroup.PUT("/:id", middleware.RequirePermission(services.PermAdminWrite), controllers.HandleAdminFunc(container, "updateAdmin"))
+	adminGroup.DELETE("/:id", middleware.RequirePermission(services.PermAdminWrite), controllers.HandleAdminFunc(container, "deleteAdmin"))
 
 	// 设备路由
 	devicesGroup := auth.Group("/devices")
 	{
-		devicesGroup.GET("", middleware.Cache(middleware.CacheConfig{Expiration: 30 * time.Second}), controllers.HandleDeviceFunc(container, "getDevices"))
-		devicesGroup.GET("/:id", middleware.Cache(middleware.CacheConfig{Expiration: 30 * time.Second}), controllers.HandleDeviceFunc(container, "getDevice"))
-		devicesGroup.POST("", controllers.HandleDeviceFunc(container, "createDevice"))
-		devicesGroup.PUT("/:id", controllers.HandleDeviceFunc(container, "updateDevice"))
-		devicesGroup.DELETE("/:id", controllers.HandleDeviceFunc(container, "deleteDevice"))
-		devicesGroup.GET("/:id/status", controllers.HandleDeviceFunc(container, "getDeviceStatus"))
-		devicesGroup.POST("/:id/building", controllers.HandleDeviceFunc(container, "associateDeviceWithBuilding"))
-		devicesGroup.GET("/:id/households", middleware.Cache(middleware.CacheConfig{Expiration: 1 * time.Minute}), controllers.HandleDeviceFunc(container, "getDeviceHouseholds"))
-		devicesGroup.POST("/:id/households", controllers.HandleDeviceFunc(container, "associateDeviceWithHousehold"))
-		devicesGroup.DELETE("/:id/households", controllers.HandleDeviceFunc(container, "removeDeviceHouseholdAssociation"))
+		devicesGroup.GET("", middleware.RequirePermission(services.PermDeviceRead), middleware.Cache(middleware.CacheConfig{Expiration: 30 * time.Second}), controllers.HandleDeviceFunc(container, "getDevices"))
+		devicesGroup.GET("/:id", middleware.RequirePermission(services.PermDeviceRead), middleware.Cache(middleware.CacheConfig{Expiration: 30 * time.Second}), controllers.HandleDeviceFunc(container, "getDevice"))
+		devicesGroup.POST("", middleware.RequirePermission(services.PermDeviceWrite), controllers.HandleDeviceFunc(container, "createDevice"))
+		devicesGroup.PUT("/:id", middleware.RequirePermission(services.PermDeviceWrite), controllers.HandleDeviceFunc(container, "updateDevice"))
+		devicesGroup.DELETE("/:id", middleware.RequirePermission(services.PermDeviceWrite), controllers.HandleDeviceFunc(container, "deleteDevice"))
+		devicesGroup.GET("/:id/status", middleware.RequirePermission(services.PermDeviceRead), controllers.HandleDeviceFunc(container, "getDeviceStatus"))
+		devicesGroup.POST("/:id/rotate-secret", middleware.RequirePermission(services.PermDeviceWrite), controllers.HandleDeviceFunc(container, "rotateDeviceSecret"))
+		devicesGroup.POST("/:id/building", middleware.RequirePermission(services.PermDeviceWrite), controllers.HandleDeviceFunc(container, "associateDeviceWithBuilding"))
+		devicesGroup.GET("/:id/households", middleware.RequirePermission(services.PermDeviceRead), middleware.Cache(middleware.CacheConfig{Expiration: 1 * time.Minute}), controllers.HandleDeviceFunc(container, "getDeviceHouseholds"))
+		devicesGroup.POST("/:id/households", middleware.RequirePermission(services.PermDeviceWrite), controllers.HandleDeviceFunc(container, "associateDeviceWithHousehold"))
+		devicesGroup.DELETE("/:id/households", middleware.RequirePermission(services.PermDeviceWrite), controllers.HandleDeviceFunc(container, "removeDeviceHouseholdAssociation"))
+		devicesGroup.GET("/:id/events", middleware.RequirePermission(services.PermDeviceRead), controllers.HandleDeviceEventFunc(container, "getDoorHistory"))
 	}
 
 	// 居民路由
 	residentGroup := auth.Group("/residents")
-	residentGroup.GET("", middleware.Cache(middleware.CacheConfig{Expiration: 1 * time.Minute}), controllers.HandleResidentFunc(container, "getResidents"))
-	residentGroup.GET("/:id", middleware.Cache(middleware.CacheConfig{Expiration: 1 * time.Minute}), controllers.HandleResidentFunc(container, "getResident"))
-	residentGroup.POST("", controllers.HandleResidentFunc(container, "createResident"))
-	residentGroup.PUT("/:id", controllers.HandleResidentFunc(container, "updateResident"))
-	residentGroup.DELETE("/:id", controllers.HandleResidentFunc(container, "deleteResident"))
+	residentGroup.GET("", middleware.RequirePermission(services.PermResidentRead), middleware.Cache(middleware.CacheConfig{Expiration: 1 * time.Minute}), controllers.HandleResidentFunc(container, "getResidents"))
+	residentGroup.GET("/:id", middleware.RequirePermission(services.PermResidentRead), middleware.Cache(middleware.CacheConfig{Expiration: 1 * time.Minute}), controllers.HandleResidentFunc(container, "getResident"))
+	residentGroup.POST("", middleware.RequirePermission(services.PermResidentWrite), controllers.HandleResidentFunc(container, "createResident"))
+	residentGroup.PUT("/:id", middleware.RequirePermission(services.PermResidentWrite), controllers.HandleResidentFunc(container, "updateResident"))
+	residentGroup.DELETE("/:id", middleware.RequirePermission(services.PermResidentWrite), controllers.HandleResidentFunc(container, "deleteResident"))
 
 	// 物业员工路由
 	staffGroup := auth.Group("/staffs")
-	staffGroup.GET("", middleware.Cache(middleware.CacheConfig{Expiration: 1 * time.Minute}), controllers.HandleStaffFunc(container, "getStaff"))
-	staffGroup.GET("/with-devices", middleware.Cache(middleware.CacheConfig{Expiration: 1 * time.Minute}), controllers.HandleStaffFunc(container, "getStaffWithDevices"))
-	staffGroup.GET("/:id", middleware.Cache(middleware.CacheConfig{Expiration: 1 * time.Minute}), controllers.HandleStaffFunc(container, "getStaffByID"))
-	staffGroup.POST("", controllers.HandleStaffFunc(container, "createStaff"))
-	staffGroup.PUT("/:id", controllers.HandleStaffFunc(container, "updateStaff"))
-	staffGroup.DELETE("/:id", controllers.HandleStaffFunc(container, "deleteStaff"))
+	staffGroup.GET("", middleware.RequirePermission(services.PermStaffRead), middleware.Cache(middleware.CacheConfig{Expiration: 1 * time.Minute}), controllers.HandleStaffFunc(container, "getStaffs"))
+	staffGroup.GET("/with-devices", middleware.RequirePermission(services.PermStaffRead), middleware.Cache(middleware.CacheConfig{Expiration: 1 * time.Minute}), controllers.HandleStaffFunc(container, "getStaffsWithDevices"))
+	staffGroup.GET("/:id", middleware.RequirePermission(services.PermStaffRead), middleware.Cache(middleware.CacheConfig{Expiration: 1 * time.Minute}), controllers.HandleStaffFunc(container, "getStaff"))
+	staffGroup.POST("", middleware.RequirePermission(services.PermStaffWrite), controllers.HandleStaffFunc(container, "createStaff"))
+	staffGroup.PUT("/:id", middleware.RequirePermission(services.PermStaffWrite), controllers.HandleStaffFunc(container, "updateStaff"))
+	staffGroup.DELETE("/:id", middleware.RequirePermission(services.PermStaffWrite), controllers.HandleStaffFunc(container, "deleteStaff"))
+	staffGroup.GET("/:id/devices", middleware.RequirePermission(services.PermStaffRead), controllers.HandleStaffFunc(container, "getStaffDevices"))
+	staffGroup.POST("/:id/devices", middleware.RequirePermission(services.PermStaffWrite), controllers.HandleStaffFunc(container, "assignDevice"))
+	staffGroup.DELETE("/:id/devices/:device_id", middleware.RequirePermission(services.PermStaffWrite), controllers.HandleStaffFunc(container, "unassignDevice"))
+	// 物业员工登录账号管理
+	staffGroup.PUT("/:id/credentials", middleware.RequirePermission(services.PermStaffWrite), controllers.HandleStaffFunc(container, "setStaffCredentials"))
+	staffGroup.POST("/:id/reset-password", middleware.RequirePermission(services.PermStaffWrite), controllers.HandleStaffFunc(container, "resetStaffPassword"))
+	staffGroup.POST("/:id/enable", middleware.RequirePermission(services.PermStaffWrite), controllers.HandleStaffFunc(container, "enableStaff"))
+	staffGroup.POST("/:id/disable", middleware.RequirePermission(services.PermStaffWrite), controllers.HandleStaffFunc(container, "disableStaff"))
+
+	// 物业员工工作台路由，只返回分配给当前员工的设备相关数据
+	staffGroup.GET("/me/devices", middleware.RequirePermission(services.PermAssignedRead), controllers.HandleStaffPortalFunc(container, "getDevices"))
+	staffGroup.GET("/me/call-records", middleware.RequirePermission(services.PermAssignedRead), controllers.HandleStaffPortalFunc(container, "getCallRecords"))
+	staffGroup.GET("/me/alarms", middleware.RequirePermission(services.PermAssignedRead), controllers.HandleStaffPortalFunc(container, "getAlarms"))
+	staffGroup.GET("/me/access-logs", middleware.RequirePermission(services.PermAssignedRead), controllers.HandleStaffPortalFunc(container, "getAccessLogs"))
 
 	// 通话记录路由
 	callRecordGroup := auth.Group("/call-records")
-	callRecordGroup.GET("", middleware.Cache(middleware.CacheConfig{Expiration: 30 * time.Second}), controllers.HandleCallRecordFunc(container, "getCallRecords"))
-	callRecordGroup.GET("/statistics", middleware.Cache(middleware.CacheConfig{Expiration: 5 * time.Minute}), controllers.HandleCallRecordFunc(container, "getCallStatistics"))
-	callRecordGroup.GET("/device/:deviceId", middleware.Cache(middleware.CacheConfig{Expiration: 30 * time.Second}), controllers.HandleCallRecordFunc(container, "getDeviceCallRecords"))
-	callRecordGroup.GET("/resident/:residentId", middleware.Cache(middleware.CacheConfig{Expiration: 30 * time.Second}), controllers.HandleCallRecordFunc(container, "getResidentCallRecords"))
-	callRecordGroup.GET("/session", middleware.Cache(middleware.CacheConfig{Expiration: 5 * time.Second}), controllers.HandleCallRecordFunc(container, "getCallSession"))
-	callRecordGroup.GET("/:id", middleware.Cache(middleware.CacheConfig{Expiration: 1 * time.Minute}), controllers.HandleCallRecordFunc(container, "getCallRecordByID"))
-	callRecordGroup.POST("/:id/feedback", controllers.HandleCallRecordFunc(container, "submitCallFeedback"))
+	callRecordGroup.GET("", middleware.RequirePermission(services.PermCallRecordRead), middleware.Cache(middleware.CacheConfig{Expiration: 30 * time.Second}), controllers.HandleCallRecordFunc(container, "getCallRecords"))
+	callRecordGroup.GET("/statistics", middleware.RequirePermission(services.PermCallRecordRead), middleware.Cache(middleware.CacheConfig{Expiration: 5 * time.Minute}), controllers.HandleCallRecordFunc(container, "getCallStatistics"))
+	callRecordGroup.GET("/device/:deviceId", middleware.RequirePermission(services.PermCallRecordRead), middleware.Cache(middleware.CacheConfig{Expiration: 30 * time.Second}), controllers.HandleCallRecordFunc(container, "getDeviceCallRecords"))
+	callRecordGroup.GET("/resident/:residentId", middleware.RequirePermission(services.PermCallRecordRead), middleware.Cache(middleware.CacheConfig{Expiration: 30 * time.Second}), controllers.HandleCallRecordFunc(container, "getResidentCallRecords"))
+	callRecordGroup.GET("/session", middleware.RequirePermission(services.PermCallRecordRead), middleware.Cache(middleware.CacheConfig{Expiration: 5 * time.Second}), controllers.HandleCallRecordFunc(container, "getCallSession"))
+	callRecordGroup.GET("/:id", middleware.RequirePermission(services.PermCallRecordRead), middleware.Cache(middleware.CacheConfig{Expiration: 1 * time.Minute}), controllers.HandleCallRecordFunc(container, "getCallRecordByID"))
+	callRecordGroup.POST("/:id/feedback", middleware.RequirePermission(services.PermCallRecordWrite), controllers.HandleCallRecordFunc(container, "submitCallFeedback"))
 
 	// 紧急情况路由
 	emergencyGroup := auth.Group("/emergency")
-	emergencyGroup.GET("", middleware.Cache(middleware.CacheConfig{Expiration: 10 * time.Second}), controllers.HandleEmergencyFunc(container, "getEmergencyLogs"))
-	emergencyGroup.GET("/:id", middleware.Cache(middleware.CacheConfig{Expiration: 30 * time.Second}), controllers.HandleEmergencyFunc(container, "getEmergencyLogByID"))
-	emergencyGroup.PUT("/:id", controllers.HandleEmergencyFunc(container, "updateEmergencyLog"))
-	emergencyGroup.POST("/trigger", controllers.HandleEmergencyFunc(container, "triggerEmergency"))
-	emergencyGroup.POST("/alarm", controllers.HandleEmergencyFunc(container, "triggerAlarm"))
-	emergencyGroup.GET("/contacts", middleware.Cache(middleware.CacheConfig{Expiration: 5 * time.Minute}), controllers.HandleEmergencyFunc(container, "getEmergencyContacts"))
-	emergencyGroup.POST("/notify-all", controllers.HandleEmergencyFunc(container, "notifyAllUsers"))
-	emergencyGroup.POST("/unlock-all", controllers.HandleEmergencyFunc(container, "emergencyUnlockAll"))
+	emergencyGroup.GET("", middleware.RequirePermission(services.PermEmergencyRead), middleware.Cache(middleware.CacheConfig{Expiration: 10 * time.Second}), controllers.HandleEmergencyFunc(container, "getEmergencyLogs"))
+	emergencyGroup.GET("/:id", middleware.RequirePermission(services.PermEmergencyRead), middleware.Cache(middleware.CacheConfig{Expiration: 30 * time.Second}), controllers.HandleEmergencyFunc(container, "getEmergencyLogByID"))
+	emergencyGroup.PUT("/:id", middleware.RequirePermission(services.PermEmergencyWrite), controllers.HandleEmergencyFunc(container, "updateEmergencyLog"))
+	emergencyGroup.POST("/trigger", middleware.RequirePermission(services.PermEmergencyTrigger), controllers.HandleEmergencyFunc(container, "triggerEmergency"))
+	emergencyGroup.POST("/alarm", middleware.RequirePermission(services.PermEmergencyTrigger), controllers.HandleEmergencyFunc(container, "triggerAlarm"))
+	emergencyGroup.GET("/contacts", middleware.RequirePermission(services.PermEmergencyRead), middleware.Cache(middleware.CacheConfig{Expiration: 30 * time.Second}), controllers.HandleEmergencyFunc(container, "getEmergencyContacts"))
+	emergencyGroup.POST("/contacts", middleware.RequirePermission(services.PermEmergencyWrite), controllers.HandleEmergencyFunc(container, "createEmergencyContact"))
+	emergencyGroup.PUT("/contacts/reorder", middleware.RequirePermission(services.PermEmergencyWrite), controllers.HandleEmergencyFunc(container, "reorderEmergencyContacts"))
+	emergencyGroup.PUT("/contacts/:id", middleware.RequirePermission(services.PermEmergencyWrite), controllers.HandleEmergencyFunc(container, "updateEmergencyContact"))
+	emergencyGroup.DELETE("/contacts/:id", middleware.RequirePermission(services.PermEmergencyWrite), controllers.HandleEmergencyFunc(container, "deleteEmergencyContact"))
+	emergencyGroup.POST("/notify-all", middleware.RequirePermission(services.PermEmergencyNotify), controllers.HandleEmergencyFunc(container, "notifyAllUsers"))
+	emergencyGroup.GET("/notifications/:id/progress", middleware.RequirePermission(services.PermEmergencyRead), controllers.HandleEmergencyFunc(container, "getNotificationProgress"))
+	emergencyGroup.GET("/notifications/:id/deliveries", middleware.RequirePermission(services.PermEmergencyRead), controllers.HandleEmergencyFunc(container, "getNotificationDeliveries"))
+	emergencyGroup.POST("/unlock-all", middleware.RequirePermission(services.PermEmergencyUnlock), controllers.HandleEmergencyFunc(container, "emergencyUnlockAll"))
+	emergencyGroup.POST("/unlock", middleware.RequirePermission(services.PermEmergencyUnlock), controllers.HandleEmergencyFunc(container, "startEmergencyUnlock"))
+	emergencyGroup.GET("/unlocks", middleware.RequirePermission(services.PermEmergencyRead), controllers.HandleEmergencyFunc(container, "getUnlockSessions"))
+	emergencyGroup.GET("/unlocks/:id", middleware.RequirePermission(services.PermEmergencyRead), controllers.HandleEmergencyFunc(container, "getUnlockSession"))
+	emergencyGroup.POST("/unlocks/:id/end", middleware.RequirePermission(services.PermEmergencyUnlock), controllers.HandleEmergencyFunc(container, "endEmergencyUnlock"))
+	emergencyGroup.GET("/drills/report", middleware.RequirePermission(services.PermEmergencyRead), controllers.HandleEmergencyFunc(container, "getDrillReport"))
+	emergencyGroup.GET("/alarms", middleware.RequirePermission(services.PermEmergencyRead), controllers.HandleEmergencyFunc(container, "getAlarms"))
+	emergencyGroup.GET("/alarms/:id", middleware.RequirePermission(services.PermEmergencyRead), controllers.HandleEmergencyFunc(container, "getAlarm"))
+	emergencyGroup.POST("/alarms/:id/acknowledge", middleware.RequirePermission(services.PermEmergencyWrite), controllers.HandleEmergencyFunc(container, "acknowledgeAlarm"))
+	emergencyGroup.POST("/alarms/:id/assign", middleware.RequirePermission(services.PermEmergencyWrite), controllers.HandleEmergencyFunc(container, "assignAlarm"))
+	emergencyGroup.POST("/alarms/:id/resolve", middleware.RequirePermission(services.PermEmergencyWrite), controllers.HandleEmergencyFunc(container, "resolveAlarm"))
+
+	// 物业路由
+	propertyGroup := auth.Group("/properties")
+	propertyGroup.GET("", middleware.RequirePermission(services.PermPropertyRead), controllers.HandlePropertyFunc(container, "getProperties"))
+	propertyGroup.GET("/:id", middleware.RequirePermission(services.PermPropertyRead), controllers.HandlePropertyFunc(container, "getProperty"))
+	propertyGroup.POST("", middleware.RequirePermission(services.PermPropertyWrite), controllers.HandlePropertyFunc(container, "createProperty"))
+	propertyGroup.PUT("/:id", middleware.RequirePermission(services.PermPropertyWrite), controllers.HandlePropertyFunc(container, "updateProperty"))
+	propertyGroup.DELETE("/:id", middleware.RequirePermission(services.PermPropertyWrite), controllers.HandlePropertyFunc(container, "deleteProperty"))
 
 	// 楼号路由
 	buildingGroup := auth.Group("/buildings")
-	buildingGroup.GET("", middleware.Cache(middleware.CacheConfig{Expiration: 5 * time.Minute}), controllers.HandleBuildingFunc(container, "getBuildings"))
-	buildingGroup.GET("/:id", middleware.Cache(middleware.CacheConfig{Expiration: 5 * time.Minute}), controllers.HandleBuildingFunc(container, "getBuilding"))
-	buildingGroup.POST("", controllers.HandleBuildingFunc(container, "createBuilding"))
-	buildingGroup.PUT("/:id", controllers.HandleBuildingFunc(container, "updateBuilding"))
-	buildingGroup.DELETE("/:id", controllers.HandleBuildingFunc(container, "deleteBuilding"))
-	buildingGroup.GET("/:id/devices", middleware.Cache(middleware.CacheConfig{Expiration: 1 * time.Minute}), controllers.HandleBuildingFunc(container, "getBuildingDevices"))
-	buildingGroup.GET("/:id/households", middleware.Cache(middleware.CacheConfig{Expiration: 1 * time.Minute}), controllers.HandleBuildingFunc(container, "getBuildingHouseholds"))
+	buildingGroup.GET("", middleware.RequirePermission(services.PermBuildingRead), middleware.Cache(middleware.CacheConfig{Expiration: 5 * time.Minute}), controllers.HandleBuildingFunc(container, "getBuildings"))
+	buildingGroup.GET("/:id", middleware.RequirePermission(services.PermBuildingRead), middleware.Cache(middleware.CacheConfig{Expiration: 5 * time.Minute}), controllers.HandleBuildingFunc(container, "getBuilding"))
+	buildingGroup.POST("", middleware.RequirePermission(services.PermBuildingWrite), controllers.HandleBuildingFunc(container, "createBuilding"))
+	buildingGroup.PUT("/:id", middleware.RequirePermission(services.PermBuildingWrite), controllers.HandleBuildingFunc(container, "updateBuilding"))
+	buildingGroup.DELETE("/:id", middleware.RequirePermission(services.PermBuildingWrite), controllers.HandleBuildingFunc(container, "deleteBuilding"))
+	buildingGroup.GET("/:id/devices", middleware.RequirePermission(services.PermBuildingRead), middleware.Cache(middleware.CacheConfig{Expiration: 1 * time.Minute}), controllers.HandleBuildingFunc(container, "getBuildingDevices"))
+	buildingGroup.GET("/:id/households", middleware.RequirePermission(services.PermBuildingRead), middleware.Cache(middleware.CacheConfig{Expiration: 1 * time.Minute}), controllers.HandleBuildingFunc(container, "getBuildingHouseholds"))
 
 	// 户号路由
 	householdGroup := auth.Group("/households")
-	householdGroup.GET("", middleware.Cache(middleware.CacheConfig{Expiration: 5 * time.Minute}), controllers.HandleHouseholdFunc(container, "getHouseholds"))
-	householdGroup.GET("/:id", middleware.Cache(middleware.CacheConfig{Expiration: 5 * time.Minute}), controllers.HandleHouseholdFunc(container, "getHousehold"))
-	householdGroup.POST("", controllers.HandleHouseholdFunc(container, "createHousehold"))
-	householdGroup.PUT("/:id", controllers.HandleHouseholdFunc(container, "updateHousehold"))
-	householdGroup.DELETE("/:id", controllers.HandleHouseholdFunc(container, "deleteHousehold"))
-	householdGroup.GET("/:id/devices", middleware.Cache(middleware.CacheConfig{Expiration: 1 * time.Minute}), controllers.HandleHouseholdFunc(container, "getHouseholdDevices"))
-	householdGroup.GET("/:id/residents", middleware.Cache(middleware.CacheConfig{Expiration: 1 * time.Minute}), controllers.HandleHouseholdFunc(container, "getHouseholdResidents"))
-	householdGroup.POST("/:id/devices", controllers.HandleHouseholdFunc(container, "associateHouseholdWithDevice"))
-	householdGroup.DELETE("/:id/devices/:device_id", controllers.HandleHouseholdFunc(container, "removeHouseholdDeviceAssociation"))
+	householdGroup.GET("", middleware.RequirePermission(services.PermHouseholdRead), middleware.Cache(middleware.CacheConfig{Expiration: 5 * time.Minute}), controllers.HandleHouseholdFunc(container, "getHouseholds"))
+	householdGroup.GET("/:id", middleware.RequirePermission(services.PermHouseholdRead), middleware.Cache(middleware.CacheConfig{Expiration: 5 * time.Minute}), controllers.HandleHouseholdFunc(container, "getHousehold"))
+	householdGroup.POST("", middleware.RequirePermission(services.PermHouseholdWrite), controllers.HandleHouseholdFunc(container, "createHousehold"))
+	householdGroup.PUT("/:id", middleware.RequirePermission(services.PermHouseholdWrite), controllers.HandleHouseholdFunc(container, "updateHousehold"))
+	householdGroup.DELETE("/:id", middleware.RequirePermission(services.PermHouseholdWrite), controllers.HandleHouseholdFunc(container, "deleteHousehold"))
+	householdGroup.GET("/:id/devices", middleware.RequirePermission(services.PermHouseholdRead), middleware.Cache(middleware.CacheConfig{Expiration: 1 * time.Minute}), controllers.HandleHouseholdFunc(container, "getHouseholdDevices"))
+	householdGroup.GET("/:id/residents", middleware.RequirePermission(services.PermHouseholdRead), middleware.Cache(middleware.CacheConfig{Expiration: 1 * time.Minute}), controllers.HandleHouseholdFunc(container, "getHouseholdResidents"))
+	householdGroup.POST("/:id/devices", middleware.RequirePermission(services.PermHouseholdWrite), controllers.HandleHouseholdFunc(container, "associateHouseholdWithDevice"))
+	householdGroup.DELETE("/:id/devices/:device_id", middleware.RequirePermission(services.PermHouseholdWrite), controllers.HandleHouseholdFunc(container, "removeHouseholdDeviceAssociation"))
+
+	// 批量导入路由，上传CSV或XLSX文件，dry_run=true 时只校验不写入
+	importGroup := auth.Group("/import")
+	importGroup.POST("/buildings", middleware.RequirePermission(services.PermBuildingWrite), controllers.HandleImportFunc(container, "importBuildings"))
+	importGroup.POST("/households", middleware.RequirePermission(services.PermHouseholdWrite), controllers.HandleImportFunc(container, "importHouseholds"))
+	importGroup.POST("/residents", middleware.RequirePermission(services.PermResidentWrite), controllers.HandleImportFunc(container, "importResidents"))
+
+	// 会话路由，注销本人会话和修改本人密码不需要额外权限
+	authGroup := auth.Group("/auth")
+	authGroup.POST("/logout", controllers.HandleJWTFunc(container, "logout"))
+	authGroup.POST("/logout-all", controllers.HandleJWTFunc(container, "logoutAll"))
+	authGroup.PUT("/password", controllers.HandlePasswordFunc(container, "changePassword"))
+	authGroup.POST("/revoke", middleware.RequirePermission(services.PermSessionManage), controllers.HandleJWTFunc(container, "revokeTokens"))
+	authGroup.POST("/unlock", middleware.RequirePermission(services.PermSessionManage), controllers.HandleJWTFunc(container, "unlockAccount"))
+	authGroup.GET("/lockouts", middleware.RequirePermission(services.PermSessionManage), controllers.HandleJWTFunc(container, "getLockouts"))
+	authGroup.GET("/2fa", controllers.HandleTwoFactorFunc(container, "getTwoFactorStatus"))
+	authGroup.POST("/2fa/setup", controllers.HandleTwoFactorFunc(container, "setupTwoFactor"))
+	authGroup.POST("/2fa/enable", controllers.HandleTwoFactorFunc(container, "enableTwoFactor"))
+	authGroup.POST("/2fa/disable", controllers.HandleTwoFactorFunc(container, "disableTwoFactor"))
+	authGroup.POST("/2fa/recovery-codes", controllers.HandleTwoFactorFunc(container, "regenerateRecoveryCodes"))
+	authGroup.POST("/2fa/reset", middleware.RequirePermission(services.PermSessionManage), controllers.HandleTwoFactorFunc(container, "resetTwoFactor"))
+
+	// 居民自助路由
+	meGroup := auth.Group("/me")
+	meGroup.GET("", middleware.RequirePermission(services.PermSelfRead), controllers.HandleMeFunc(container, "getProfile"))
+	meGroup.PUT("", middleware.RequirePermission(services.PermSelfWrite), controllers.HandleMeFunc(container, "updateProfile"))
+	meGroup.PUT("/password", middleware.RequirePermission(services.PermSelfWrite), controllers.HandleMeFunc(container, "changePassword"))
+	meGroup.GET("/household", middleware.RequirePermission(services.PermSelfRead), controllers.HandleMeFunc(container, "getHousehold"))
+	meGroup.GET("/call-records", middleware.RequirePermission(services.PermSelfRead), controllers.HandleMeFunc(container, "getCallRecords"))
+	meGroup.GET("/devices", middleware.RequirePermission(services.PermSelfRead), controllers.HandleMeFunc(container, "getDevices"))
+	meGroup.GET("/passcodes", middleware.RequirePermission(services.PermSelfRead), controllers.HandleMeFunc(container, "getPasscodes"))
+	meGroup.POST("/passcodes", middleware.RequirePermission(services.PermSelfWrite), controllers.HandleMeFunc(container, "createPasscode"))
+	meGroup.DELETE("/passcodes/:id", middleware.RequirePermission(services.PermSelfWrite), controllers.HandleMeFunc(container, "revokePasscode"))
+	meGroup.GET("/inbox", middleware.RequirePermission(services.PermSelfRead), controllers.HandleMeFunc(container, "getInbox"))
+	meGroup.POST("/inbox/:id/read", middleware.RequirePermission(services.PermSelfWrite), controllers.HandleMeFunc(container, "markInboxRead"))
+
+	// 角色权限路由
+	rbacGroup := auth.Group("/rbac")
+	rbacGroup.GET("/me", controllers.HandleRBACFunc(container, "getMyPermissions"))
+	rbacGroup.GET("/permissions", middleware.RequirePermission(services.PermRoleManage), controllers.HandleRBACFunc(container, "getPermissions"))
+	rbacGroup.GET("/roles", middleware.RequirePermission(services.PermRoleManage), controllers.HandleRBACFunc(container, "getRoles"))
+	rbacGroup.POST("/roles", middleware.RequirePermission(services.PermRoleManage), controllers.HandleRBACFunc(container, "createRole"))
+	rbacGroup.GET("/roles/:id", middleware.RequirePermission(services.PermRoleManage), controllers.HandleRBACFunc(container, "getRole"))
+	rbacGroup.PUT("/roles/:id", middleware.RequirePermission(services.PermRoleManage), controllers.HandleRBACFunc(container, "updateRole"))
+	rbacGroup.DELETE("/roles/:id", middleware.RequirePermission(services.PermRoleManage), controllers.HandleRBACFunc(container, "deleteRole"))
+	rbacGroup.GET("/bindings", middleware.RequirePermission(services.PermRoleManage), controllers.HandleRBACFunc(container, "getRoleBindings"))
+	rbacGroup.POST("/bindings", middleware.RequirePermission(services.PermRoleManage), controllers.HandleRBACFunc(container, "createRoleBinding"))
+	rbacGroup.DELETE("/bindings/:id", middleware.RequirePermission(services.PermRoleManage), controllers.HandleRBACFunc(container, "deleteRoleBinding"))
+
+	// Webhook路由
+	webhookGroup := auth.Group("/webhooks")
+	webhookGroup.GET("", middleware.RequirePermission(services.PermWebhookManage), controllers.HandleWebhookFunc(container, "getWebhooks"))
+	webhookGroup.POST("", middleware.RequirePermission(services.PermWebhookManage), controllers.HandleWebhookFunc(container, "createWebhook"))
+	webhookGroup.GET("/deliveries", middleware.RequirePermission(services.PermWebhookManage), controllers.HandleWebhookFunc(container, "getWebhookDeliveries"))
+	webhookGroup.POST("/deliveries/:id/replay", middleware.RequirePermission(services.PermWebhookManage), controllers.HandleWebhookFunc(container, "replayWebhookDelivery"))
+	webhookGroup.GET("/:id", middleware.RequirePermission(services.PermWebhookManage), controllers.HandleWebhookFunc(container, "getWebhook"))
+	webhookGroup.PUT("/:id", middleware.RequirePermission(services.PermWebhookManage), controllers.HandleWebhookFunc(container, "updateWebhook"))
+	webhookGroup.DELETE("/:id", middleware.RequirePermission(services.PermWebhookManage), controllers.HandleWebhookFunc(container, "deleteWebhook"))
+	webhookGroup.POST("/:id/rotate-secret", middleware.RequirePermission(services.PermWebhookManage), controllers.HandleWebhookFunc(container, "rotateWebhookSecret"))
+
+	// API密钥路由
+	apiKeyGroup := auth.Group("/api-keys")
+	apiKeyGroup.GET("", middleware.RequirePermission(services.PermAPIKeyManage), controllers.HandleAPIKeyFunc(container, "getAPIKeys"))
+	apiKeyGroup.POST("", middleware.RequirePermission(services.PermAPIKeyManage), controllers.HandleAPIKeyFunc(container, "createAPIKey"))
+	apiKeyGroup.GET("/:id", middleware.RequirePermission(services.PermAPIKeyManage), controllers.HandleAPIKeyFunc(container, "getAPIKey"))
+	apiKeyGroup.PUT("/:id", middleware.RequirePermission(services.PermAPIKeyManage), controllers.HandleAPIKeyFunc(container, "updateAPIKey"))
+	apiKeyGroup.DELETE("/:id", middleware.RequirePermission(services.PermAPIKeyManage), controllers.HandleAPIKeyFunc(container, "revokeAPIKey"))
+	apiKeyGroup.POST("/:id/rotate", middleware.RequirePermission(services.PermAPIKeyManage), controllers.HandleAPIKeyFunc(container, "rotateAPIKey"))
+	apiKeyGroup.GET("/:id/usage", middleware.RequirePermission(services.PermAPIKeyManage), controllers.HandleAPIKeyFunc(container, "getAPIKeyUsage"))
+
+	// 审计日志路由
+	auditGroup := auth.Group("/audit")
+	auditGroup.GET("", middleware.RequirePermission(services.PermAuditRead), controllers.HandleAuditFunc(container, "getAuditLogs"))
+	auditGroup.GET("/export", middleware.RequirePermission(services.PermAuditRead), controllers.HandleAuditFunc(container, "exportAuditLogs"))
 }
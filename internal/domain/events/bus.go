@@ -0,0 +1,147 @@
+package events
+
+import (
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Event 领域事件，Name返回事件名称，订阅时按名称匹配
+type Event interface {
+	Name() string
+}
+
+// Handler 事件处理函数
+type Handler func(event Event)
+
+// All 订阅所有事件时使用的名称
+const All = "*"
+
+// subscriber 一个订阅者
+type subscriber struct {
+	id      uint64
+	name    string
+	handler Handler
+	async   bool
+}
+
+// Bus 进程内的发布/订阅事件总线
+// 同步订阅者在Publish调用方的协程中按订阅顺序执行，适合需要在返回前完成的处理（如缓存失效）；
+// 异步订阅者在独立协程中执行，不阻塞发布方（如审计、通知、Webhook）。
+// 处理函数中的panic会被捕获并记录，不影响发布方和其他订阅者。
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers map[string][]*subscriber
+	nextID      uint64
+	wg          sync.WaitGroup
+	closed      bool
+}
+
+// NewBus 创建事件总线
+func NewBus() *Bus {
+	return &Bus{
+		subscribers: make(map[string][]*subscriber),
+	}
+}
+
+// 1 Subscribe 注册同步订阅者，name为事件名称或All，返回取消订阅的函数
+func (b *Bus) Subscribe(name string, handler Handler) func() {
+	return b.subscribe(name, handler, false)
+}
+
+// 2 SubscribeAsync 注册异步订阅者，name为事件名称或All，返回取消订阅的函数
+func (b *Bus) SubscribeAsync(name string, handler Handler) func() {
+	return b.subscribe(name, handler, true)
+}
+
+// 3 Publish 发布事件，同步订阅者执行完毕后返回
+func (b *Bus) Publish(event Event) {
+	if b == nil || event == nil {
+		return
+	}
+
+	b.mu.RLock()
+	if b.closed {
+		b.mu.RUnlock()
+		return
+	}
+	matched := make([]*subscriber, 0, len(b.subscribers[event.Name()])+len(b.subscribers[All]))
+	matched = append(matched, b.subscribers[event.Name()]...)
+	matched = append(matched, b.subscribers[All]...)
+	for _, sub := range matched {
+		if sub.async {
+			b.wg.Add(1)
+		}
+	}
+	b.mu.RUnlock()
+
+	for _, sub := range matched {
+		if sub.async {
+			go func(sub *subscriber) {
+				defer b.wg.Done()
+				b.dispatch(sub, event)
+			}(sub)
+			continue
+		}
+		b.dispatch(sub, event)
+	}
+}
+
+// 4 Close 停止接收新事件，并等待已分发的异步处理完成，最多等待timeout
+func (b *Bus) Close(timeout time.Duration) {
+	b.mu.Lock()
+	b.closed = true
+	b.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		log.Printf("[EventBus] 等待异步订阅者超时(%s)", timeout)
+	}
+}
+
+// subscribe 注册订阅者
+func (b *Bus) subscribe(name string, handler Handler, async bool) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	sub := &subscriber{id: b.nextID, name: name, handler: handler, async: async}
+	b.subscribers[name] = append(b.subscribers[name], sub)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { b.unsubscribe(sub) })
+	}
+}
+
+// unsubscribe 移除订阅者
+func (b *Bus) unsubscribe(target *subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	subs := b.subscribers[target.name]
+	for i, sub := range subs {
+		if sub.id == target.id {
+			b.subscribers[target.name] = append(subs[:i:i], subs[i+1:]...)
+			break
+		}
+	}
+}
+
+// dispatch 执行处理函数并捕获panic
+func (b *Bus) dispatch(sub *subscriber, event Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[EventBus] 处理事件 %s 时发生panic: %v\n%s", event.Name(), r, debug.Stack())
+		}
+	}()
+	sub.handler(event)
+}
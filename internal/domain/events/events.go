@@ -0,0 +1,209 @@
+package events
+
+import (
+	"ilock-http-service/internal/domain/models"
+	"time"
+)
+
+// 事件名称
+const (
+	DeviceCreatedEvent       = "device.created"
+	DeviceUpdatedEvent       = "device.updated"
+	DeviceDeletedEvent       = "device.deleted"
+	DeviceStatusChangedEvent = "device.status_changed"
+	DeviceHeartbeatEvent     = "device.heartbeat"
+	DeviceEventReportedEvent = "device.event_reported"
+	AccessGrantedEvent       = "access.granted"
+	AccessDeniedEvent        = "access.denied"
+
+	CallStartedEvent  = "call.started"
+	CallAnsweredEvent = "call.answered"
+	CallEndedEvent    = "call.ended"
+
+	AlarmTriggeredEvent     = "alarm.triggered"
+	AlarmAcknowledgedEvent  = "alarm.acknowledged"
+	AlarmAssignedEvent      = "alarm.assigned"
+	AlarmResolvedEvent      = "alarm.resolved"
+	EmergencyTriggeredEvent = "emergency.triggered"
+
+	HouseholdCreatedEvent = "household.created"
+	HouseholdUpdatedEvent = "household.updated"
+	HouseholdDeletedEvent = "household.deleted"
+
+	ResidentCreatedEvent = "resident.created"
+	ResidentUpdatedEvent = "resident.updated"
+	ResidentDeletedEvent = "resident.deleted"
+)
+
+// DeviceCreated 设备已创建
+type DeviceCreated struct {
+	Device *models.Device
+}
+
+func (DeviceCreated) Name() string { return DeviceCreatedEvent }
+
+// DeviceUpdated 设备信息已更新，Changes为本次更新的字段
+type DeviceUpdated struct {
+	Device  *models.Device
+	Changes map[string]interface{}
+}
+
+func (DeviceUpdated) Name() string { return DeviceUpdatedEvent }
+
+// DeviceDeleted 设备已删除
+type DeviceDeleted struct {
+	DeviceID uint
+}
+
+func (DeviceDeleted) Name() string { return DeviceDeletedEvent }
+
+// DeviceStatusChanged 设备在线状态发生变化
+type DeviceStatusChanged struct {
+	Device   *models.Device
+	Previous models.DeviceStatus
+	Current  models.DeviceStatus
+}
+
+func (DeviceStatusChanged) Name() string { return DeviceStatusChangedEvent }
+
+// DeviceHeartbeat 设备通过健康检测接口上报在线
+type DeviceHeartbeat struct {
+	DeviceID uint
+	At       time.Time
+}
+
+func (DeviceHeartbeat) Name() string { return DeviceHeartbeatEvent }
+
+// DeviceEventReported 设备上报了门磁或防拆事件
+type DeviceEventReported struct {
+	Event  *models.DeviceEvent
+	Device *models.Device
+}
+
+func (DeviceEventReported) Name() string { return DeviceEventReportedEvent }
+
+// AccessAttempted 设备上报了一次开门结果，Granted决定事件名称
+type AccessAttempted struct {
+	Granted   bool
+	Event     *models.DeviceEvent
+	AccessLog *models.AccessLog
+	Device    *models.Device
+}
+
+func (e AccessAttempted) Name() string {
+	if e.Granted {
+		return AccessGrantedEvent
+	}
+	return AccessDeniedEvent
+}
+
+// CallStarted 门禁设备发起呼叫
+type CallStarted struct {
+	CallID     string
+	DeviceID   string
+	ResidentID string
+	Status     string
+}
+
+func (CallStarted) Name() string { return CallStartedEvent }
+
+// CallAnswered 住户接听呼叫
+type CallAnswered struct {
+	CallID     string
+	DeviceID   string
+	ResidentID string
+}
+
+func (CallAnswered) Name() string { return CallAnsweredEvent }
+
+// CallEnded 通话结束，包括拒接、超时和挂断
+type CallEnded struct {
+	CallID string
+	Status string
+	Reason string
+}
+
+func (CallEnded) Name() string { return CallEndedEvent }
+
+// AlarmTriggered 紧急警报已触发
+type AlarmTriggered struct {
+	Alarm *models.EmergencyAlarm
+}
+
+func (AlarmTriggered) Name() string { return AlarmTriggeredEvent }
+
+// AlarmAcknowledged 紧急警报已确认
+type AlarmAcknowledged struct {
+	Alarm      *models.EmergencyAlarm
+	OperatorID uint
+}
+
+func (AlarmAcknowledged) Name() string { return AlarmAcknowledgedEvent }
+
+// AlarmAssigned 紧急警报已指派给物业人员
+type AlarmAssigned struct {
+	Alarm      *models.EmergencyAlarm
+	StaffID    uint
+	OperatorID uint
+}
+
+func (AlarmAssigned) Name() string { return AlarmAssignedEvent }
+
+// AlarmResolved 紧急警报已解决
+type AlarmResolved struct {
+	Alarm      *models.EmergencyAlarm
+	OperatorID uint
+}
+
+func (AlarmResolved) Name() string { return AlarmResolvedEvent }
+
+// EmergencyTriggered 住户发起了紧急求助
+type EmergencyTriggered struct {
+	Emergency *models.EmergencyLog
+}
+
+func (EmergencyTriggered) Name() string { return EmergencyTriggeredEvent }
+
+// HouseholdCreated 户号已创建
+type HouseholdCreated struct {
+	Household *models.Household
+}
+
+func (HouseholdCreated) Name() string { return HouseholdCreatedEvent }
+
+// HouseholdUpdated 户号已更新，Changes为本次更新的字段
+type HouseholdUpdated struct {
+	Household *models.Household
+	Changes   map[string]interface{}
+}
+
+func (HouseholdUpdated) Name() string { return HouseholdUpdatedEvent }
+
+// HouseholdDeleted 户号已删除
+type HouseholdDeleted struct {
+	HouseholdID uint
+}
+
+func (HouseholdDeleted) Name() string { return HouseholdDeletedEvent }
+
+// ResidentCreated 住户已创建
+type ResidentCreated struct {
+	Resident *models.Resident
+}
+
+func (ResidentCreated) Name() string { return ResidentCreatedEvent }
+
+// ResidentUpdated 住户已更新，Changes为本次更新的字段
+type ResidentUpdated struct {
+	Resident *models.Resident
+	Changes  map[string]interface{}
+}
+
+func (ResidentUpdated) Name() string { return ResidentUpdatedEvent }
+
+// ResidentDeleted 住户已删除
+type ResidentDeleted struct {
+	ResidentID uint
+}
+
+func (ResidentDeleted) Name() string { return ResidentDeletedEvent }
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// AccountLockout 表示登录失败次数过多导致的账号锁定，到期自动解除，也可以由管理员提前解锁
+type AccountLockout struct {
+	BaseModel
+	SubjectType    string     `gorm:"type:varchar(20);not null;index:idx_lockout_subject" json:"subject_type"` // 账号类型：admin, staff, user
+	SubjectID      uint       `gorm:"not null;index:idx_lockout_subject" json:"subject_id"`                    // 账号ID
+	Username       string     `gorm:"type:varchar(100)" json:"username"`                                       // 登录时使用的用户名或手机号
+	IP             string     `gorm:"type:varchar(64)" json:"ip"`                                              // 触发锁定的最后一次失败登录IP
+	FailedAttempts int        `json:"failed_attempts"`                                                         // 锁定前统计窗口内的失败次数
+	LockedUntil    time.Time  `gorm:"index" json:"locked_until"`                                               // 自动解锁时间
+	UnlockedAt     *time.Time `json:"unlocked_at,omitempty"`                                                   // 管理员解锁时间
+	UnlockedBy     *uint      `json:"unlocked_by,omitempty"`                                                   // 解锁的管理员ID
+}
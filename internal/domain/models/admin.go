@@ -3,10 +3,13 @@ package models
 // Admin represents system administrators
 type Admin struct {
 	BaseModel
-	Username string `gorm:"type:varchar(50);unique;not null" json:"username"`
-	Password string `gorm:"type:varchar(100);not null" json:"-"` // Password not exposed in JSON
-	Email    string `gorm:"type:varchar(100);unique" json:"email"`
-	Phone    string `gorm:"type:varchar(20)" json:"phone"`
-	Role     string `gorm:"type:varchar(50);default:'admin'" json:"role"`    // Role: system_admin, admin
-	Status   string `gorm:"type:varchar(20);default:'active'" json:"status"` // Status: active, inactive, locked
+	Username   string `gorm:"type:varchar(50);unique;not null" json:"username"`
+	Password   string `gorm:"type:varchar(100);not null" json:"-"` // Password not exposed in JSON
+	Email      string `gorm:"type:varchar(100);unique" json:"email"`
+	Phone      string `gorm:"type:varchar(20)" json:"phone"`
+	Role       string `gorm:"type:varchar(50);default:'admin'" json:"role"`    // Role: system_admin, admin
+	Status     string `gorm:"type:varchar(20);default:'active'" json:"status"` // Status: active, inactive, locked
+	PropertyID *uint  `gorm:"index" json:"property_id,omitempty"`              // 物业管理员所属物业，为空表示平台管理员
+	// 为真时登录后只能修改密码，默认管理员首次登录时必须修改密码
+	MustChangePassword bool `gorm:"default:false" json:"must_change_password"`
 }
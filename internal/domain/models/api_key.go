@@ -0,0 +1,54 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// APIKey 表示第三方系统调用接口使用的API密钥，按权限范围和IP白名单限制访问
+type APIKey struct {
+	BaseModel
+	Name         string     `gorm:"type:varchar(100);not null" json:"name"`
+	Prefix       string     `gorm:"type:varchar(20);not null;index" json:"prefix"`   // 密钥前几位，用于识别密钥，完整密钥只在创建和轮换时返回
+	KeyHash      string     `gorm:"type:char(64);not null;uniqueIndex" json:"-"`     // 密钥的SHA-256哈希
+	Scopes       string     `gorm:"type:varchar(1000);not null" json:"scopes"`       // 允许使用的权限编码，逗号分隔
+	AllowedIPs   string     `gorm:"type:varchar(1000)" json:"allowed_ips,omitempty"` // 允许调用的IP或CIDR，逗号分隔，为空表示不限制
+	PropertyID   *uint      `gorm:"index" json:"property_id"`                        // 限定访问的物业，为空表示不限物业
+	Description  string     `gorm:"type:varchar(255)" json:"description,omitempty"`
+	CreatedBy    uint       `json:"created_by"`           // 创建密钥的管理员ID
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"` // 过期时间，为空表示长期有效
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"` // 吊销时间，吊销后不能恢复
+	RotatedAt    *time.Time `json:"rotated_at,omitempty"` // 最近一次轮换的时间
+	LastUsedAt   *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP   string     `gorm:"type:varchar(64)" json:"last_used_ip,omitempty"`
+	RequestCount int64      `gorm:"not null;default:0" json:"request_count"` // 累计请求次数
+}
+
+// ScopeList 返回密钥的权限编码列表
+func (k *APIKey) ScopeList() []string {
+	return splitList(k.Scopes)
+}
+
+// AllowedIPList 返回密钥的IP白名单
+func (k *APIKey) AllowedIPList() []string {
+	return splitList(k.AllowedIPs)
+}
+
+// APIKeyUsage 表示API密钥每天的请求次数
+type APIKeyUsage struct {
+	BaseModel
+	APIKeyID uint   `gorm:"not null;uniqueIndex:idx_api_key_usage_day" json:"api_key_id"`
+	Date     string `gorm:"type:char(10);not null;uniqueIndex:idx_api_key_usage_day" json:"date"` // 日期，格式为2006-01-02
+	Requests int64  `gorm:"not null;default:0" json:"requests"`
+}
+
+// splitList 拆分逗号分隔的字符串，忽略空白项
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
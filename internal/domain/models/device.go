@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // DeviceStatus represents the status of a door access device
 type DeviceStatus string
 
@@ -19,10 +21,16 @@ type Device struct {
 	BuildingID   uint         `json:"building_id,omitempty"`  // 关联的楼号ID
 	HouseholdID  uint         `json:"household_id,omitempty"` // 关联的户号ID
 
+	// Credentials - 设备凭证，设备用序列号和密钥换取设备令牌
+	SecretHash     string     `gorm:"type:char(64)" json:"-"`     // 设备密钥的SHA-256哈希，不保存明文
+	SecretIssuedAt *time.Time `json:"secret_issued_at,omitempty"` // 设备密钥签发时间，为空表示尚未签发
+	SigningKey     string     `gorm:"type:varchar(255)" json:"-"` // 由设备密钥派生的请求签名密钥，加密保存
+	Secret         string     `gorm:"-" json:"secret,omitempty"`  // 设备密钥明文，只在创建或轮换时返回一次
+
 	// Relations - 关联关系
-	Staff         []PropertyStaff `gorm:"many2many:staff_device_relations;" json:"staff,omitempty"` // 通过关系表关联的物业人员列表
-	Building      *Building       `gorm:"foreignKey:BuildingID" json:"building,omitempty"`          // 关联的楼号（多对一）
-	Household     *Household      `gorm:"foreignKey:HouseholdID" json:"household,omitempty"`        // 关联的户号（多对一）
+	Staff         []PropertyStaff `gorm:"many2many:staff_device_relations;joinForeignKey:DeviceID;joinReferences:StaffID" json:"staff,omitempty"` // 通过关系表关联的物业人员列表
+	Building      *Building       `gorm:"foreignKey:BuildingID" json:"building,omitempty"`                                                        // 关联的楼号（多对一）
+	Household     *Household      `gorm:"foreignKey:HouseholdID" json:"household,omitempty"`                                                      // 关联的户号（多对一）
 	CallRecords   []CallRecord    `gorm:"foreignKey:DeviceID" json:"call_records,omitempty"`
 	AccessLogs    []AccessLog     `gorm:"foreignKey:DeviceID" json:"access_logs,omitempty"`
 	EmergencyLogs []EmergencyLog  `gorm:"foreignKey:DeviceID" json:"emergency_logs,omitempty"`
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+)
+
+// DeviceEventType 表示门磁/设备上报的事件类型
+type DeviceEventType string
+
+const (
+	DeviceEventDoorOpened    DeviceEventType = "door_opened"    // 门已打开
+	DeviceEventDoorClosed    DeviceEventType = "door_closed"    // 门已关闭
+	DeviceEventDoorForced    DeviceEventType = "door_forced"    // 门被强行打开
+	DeviceEventDoorHeldOpen  DeviceEventType = "door_held_open" // 门长时间未关闭
+	DeviceEventTamper        DeviceEventType = "tamper"         // 设备被拆卸或破坏
+	DeviceEventAccessGranted DeviceEventType = "access_granted" // 开门成功
+	DeviceEventAccessDenied  DeviceEventType = "access_denied"  // 开门被拒绝
+)
+
+// IsValid 检查事件类型是否受支持
+func (t DeviceEventType) IsValid() bool {
+	switch t {
+	case DeviceEventDoorOpened, DeviceEventDoorClosed, DeviceEventDoorForced, DeviceEventDoorHeldOpen, DeviceEventTamper,
+		DeviceEventAccessGranted, DeviceEventAccessDenied:
+		return true
+	}
+	return false
+}
+
+// IsAccess 判断是否为开门结果事件，此类事件同时写入门禁记录
+func (t DeviceEventType) IsAccess() bool {
+	return t == DeviceEventAccessGranted || t == DeviceEventAccessDenied
+}
+
+// RaisesAlarm 判断该类型事件是否需要自动触发紧急警报：强行开门、门长时间未关闭和防拆
+func (t DeviceEventType) RaisesAlarm() bool {
+	switch t {
+	case DeviceEventDoorForced, DeviceEventDoorHeldOpen, DeviceEventTamper:
+		return true
+	}
+	return false
+}
+
+// DeviceEvent 表示设备上报的门磁事件，用于门禁历史查询
+type DeviceEvent struct {
+	BaseModel
+	DeviceID   uint            `gorm:"index;not null" json:"device_id"`
+	EventType  DeviceEventType `gorm:"type:varchar(30);index;not null" json:"event_type"`
+	Timestamp  time.Time       `gorm:"index" json:"timestamp"`                   // 设备端事件发生时间
+	Source     string          `gorm:"type:varchar(10)" json:"source"`           // 上报渠道: mqtt, http
+	Details    string          `gorm:"type:text" json:"details,omitempty"`       // 设备附加信息
+	AlarmID    *uint           `json:"alarm_id,omitempty"`                       // 自动创建的紧急警报ID
+	ResidentID *uint           `json:"resident_id,omitempty"`                    // 开门事件对应的居民ID
+	Method     string          `gorm:"type:varchar(20)" json:"method,omitempty"` // 开门方式: remote, code, face, fingerprint
+
+	// 关联关系
+	Device *Device `gorm:"foreignKey:DeviceID" json:"device,omitempty"`
+}
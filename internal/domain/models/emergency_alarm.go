@@ -4,17 +4,63 @@ import (
 	"time"
 )
 
+// 紧急警报状态
+const (
+	AlarmStatusTriggered  = "triggered"  // 已触发
+	AlarmStatusProcessing = "processing" // 处理中
+	AlarmStatusResolved   = "resolved"   // 已解决
+)
+
+// alarmTransitions 定义警报允许的状态流转：triggered → processing → resolved
+var alarmTransitions = map[string][]string{
+	AlarmStatusTriggered:  {AlarmStatusProcessing},
+	AlarmStatusProcessing: {AlarmStatusProcessing, AlarmStatusResolved},
+}
+
 // EmergencyAlarm 表示紧急警报信息
 type EmergencyAlarm struct {
 	BaseModel
-	Type        string     `gorm:"type:varchar(30);not null" json:"type"` // 如：fire(火灾)、intrusion(入侵)、medical(医疗)等
-	Location    string     `gorm:"type:varchar(100);not null" json:"location"`
-	Description string     `gorm:"type:text" json:"description"`
-	Status      string     `gorm:"type:varchar(20);default:'triggered'" json:"status"` // 如：triggered(已触发)、processing(处理中)、resolved(已解决)
-	Timestamp   time.Time  `json:"timestamp"`
-	ReportedBy  uint       `json:"reported_by"` // 报告人ID，0表示系统自动报警
-	ResolvedAt  *time.Time `json:"resolved_at,omitempty"`
-	ResolvedBy  *uint      `json:"resolved_by,omitempty"`
-	Resolution  string     `gorm:"type:text" json:"resolution,omitempty"`
-	PropertyID  *uint      `json:"property_id,omitempty"` // 可为空，非外键
+	Type           string     `gorm:"type:varchar(30);not null" json:"type"` // 如：fire(火灾)、intrusion(入侵)、medical(医疗)等
+	Location       string     `gorm:"type:varchar(100);not null" json:"location"`
+	Description    string     `gorm:"type:text" json:"description"`
+	Status         string     `gorm:"type:varchar(20);default:'triggered'" json:"status"` // 如：triggered(已触发)、processing(处理中)、resolved(已解决)
+	Timestamp      time.Time  `json:"timestamp"`
+	ReportedBy     uint       `json:"reported_by"`            // 报告人ID，即触发警报的登录账号，0表示系统自动报警
+	OnBehalfOf     *uint      `json:"on_behalf_of,omitempty"` // 管理员或物业员工代为报警的居民ID
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"`
+	AcknowledgedBy *uint      `json:"acknowledged_by,omitempty"`
+	AssignedTo     *uint      `json:"assigned_to,omitempty"` // 负责处理的物业员工ID
+	AssignedAt     *time.Time `json:"assigned_at,omitempty"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty"`
+	ResolvedBy     *uint      `json:"resolved_by,omitempty"`
+	Resolution     string     `gorm:"type:text" json:"resolution,omitempty"`
+	PropertyID     *uint      `json:"property_id,omitempty"`               // 可为空，非外键
+	IsDrill        bool       `gorm:"default:false;index" json:"is_drill"` // 是否为演练
+
+	// 关联关系
+	Assignee *PropertyStaff      `gorm:"foreignKey:AssignedTo" json:"assignee,omitempty"`
+	Logs     []EmergencyAlarmLog `gorm:"foreignKey:AlarmID" json:"logs,omitempty"`
+}
+
+// CanTransitionTo 检查警报能否从当前状态流转到目标状态
+func (a *EmergencyAlarm) CanTransitionTo(status string) bool {
+	for _, next := range alarmTransitions[a.Status] {
+		if next == status {
+			return true
+		}
+	}
+	return false
+}
+
+// EmergencyAlarmLog 记录警报的每一次状态变更，用于审计
+type EmergencyAlarmLog struct {
+	BaseModel
+	AlarmID      uint      `gorm:"index;not null" json:"alarm_id"`
+	Action       string    `gorm:"type:varchar(30);not null" json:"action"` // trigger, acknowledge, assign, resolve
+	FromStatus   string    `gorm:"type:varchar(20)" json:"from_status"`
+	ToStatus     string    `gorm:"type:varchar(20)" json:"to_status"`
+	OperatorID   uint      `json:"operator_id"` // 操作人ID，0表示系统
+	OperatorRole string    `gorm:"type:varchar(20)" json:"operator_role"`
+	Remark       string    `gorm:"type:text" json:"remark,omitempty"`
+	Timestamp    time.Time `json:"timestamp"`
 }
@@ -16,14 +16,41 @@ const (
 
 // 紧急事件日志
 type EmergencyLog struct {
-	ID          uint            `gorm:"primaryKey" json:"id"`
-	ResidentID  uint            `json:"resident_id"`
-	DeviceID    uint            `json:"device_id"`
-	Status      EmergencyStatus `gorm:"type:varchar(20)" json:"status"`
-	TriggeredAt time.Time       `json:"triggered_at"`
-	ResolvedAt  *time.Time      `json:"resolved_at"` // 可空字段
+	ID              uint            `gorm:"primaryKey" json:"id"`
+	ResidentID      uint            `json:"resident_id"`
+	DeviceID        uint            `json:"device_id"`
+	Status          EmergencyStatus `gorm:"type:varchar(20)" json:"status"`
+	Description     string          `gorm:"type:text" json:"description,omitempty"`
+	PropertyID      *uint           `json:"property_id,omitempty"` // 关联的物业ID，用于选择紧急联系人
+	TriggeredAt     time.Time       `json:"triggered_at"`
+	EscalationLevel int             `gorm:"default:0" json:"escalation_level"` // 已升级的轮次，0表示尚未升级
+	LastEscalatedAt *time.Time      `json:"last_escalated_at,omitempty"`
+	RespondedAt     *time.Time      `json:"responded_at,omitempty"`
+	RespondedBy     *uint           `json:"responded_by,omitempty"`
+	ResolvedAt      *time.Time      `json:"resolved_at"`                         // 可空字段
+	IsDrill         bool            `gorm:"default:false;index" json:"is_drill"` // 是否为演练
 
 	// Relations
-	Device   *Device   `gorm:"foreignKey:DeviceID" json:"device,omitempty"`
-	Resident *Resident `gorm:"foreignKey:ResidentID" json:"resident,omitempty"`
+	Device          *Device                   `gorm:"foreignKey:DeviceID" json:"device,omitempty"`
+	Resident        *Resident                 `gorm:"foreignKey:ResidentID" json:"resident,omitempty"`
+	EscalationSteps []EmergencyEscalationStep `gorm:"foreignKey:EmergencyLogID" json:"escalation_steps,omitempty"`
+}
+
+// IsAwaitingResponse 判断紧急事件是否仍在等待响应
+func (l *EmergencyLog) IsAwaitingResponse() bool {
+	return l.Status == EmergencyStatusPending || l.Status == EmergencyStatusEscalated
+}
+
+// EmergencyEscalationStep 记录紧急事件升级过程中的每一次联系人通知
+type EmergencyEscalationStep struct {
+	BaseModel
+	EmergencyLogID uint      `gorm:"index;not null" json:"emergency_log_id"`
+	Level          int       `json:"level"` // 升级轮次
+	ContactID      uint      `json:"contact_id"`
+	ContactName    string    `gorm:"type:varchar(50)" json:"contact_name"`
+	PhoneNumber    string    `gorm:"type:varchar(20)" json:"phone_number"`
+	Notifier       string    `gorm:"type:varchar(30)" json:"notifier"` // 通知渠道名称
+	Success        bool      `json:"success"`
+	Error          string    `gorm:"type:text" json:"error,omitempty"`
+	Timestamp      time.Time `json:"timestamp"`
 }
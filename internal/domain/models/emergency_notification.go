@@ -17,4 +17,37 @@ type EmergencyNotification struct {
 	SenderRole string    `gorm:"type:varchar(20)" json:"sender_role"`          // 发送者角色
 	PropertyID *uint     `json:"property_id,omitempty"`                        // 关联的物业ID，可以为空表示全局通知
 	IsPublic   bool      `gorm:"default:false" json:"is_public"`               // 是否为公开通知
+	IsDrill    bool      `gorm:"default:false;index" json:"is_drill"`          // 是否为演练
+}
+
+// 通知接收人类型
+const (
+	RecipientTypeResident = "resident" // 居民
+	RecipientTypeStaff    = "staff"    // 物业人员
+)
+
+// 通知投递状态
+const (
+	DeliveryStatusPending   = "pending"   // 等待投递
+	DeliveryStatusSent      = "sent"      // 已发出，等待终端回执
+	DeliveryStatusDelivered = "delivered" // 终端已确认送达
+	DeliveryStatusFailed    = "failed"    // 投递失败
+)
+
+// EmergencyNotificationDelivery 表示紧急通知在某个渠道上对某个接收人的投递记录
+// 渠道为inbox的记录即接收人的站内信
+type EmergencyNotificationDelivery struct {
+	BaseModel
+	NotificationID uint       `gorm:"index;not null" json:"notification_id"`
+	RecipientType  string     `gorm:"type:varchar(20);index:idx_delivery_recipient;not null" json:"recipient_type"` // resident, staff
+	RecipientID    uint       `gorm:"index:idx_delivery_recipient;not null" json:"recipient_id"`
+	Channel        string     `gorm:"type:varchar(20);not null" json:"channel"`               // mqtt, inbox, sms, email, push
+	Status         string     `gorm:"type:varchar(20);index;default:'pending'" json:"status"` // pending, sent, delivered, failed
+	Error          string     `gorm:"type:varchar(255)" json:"error,omitempty"`               // 投递失败原因
+	SentAt         *time.Time `json:"sent_at,omitempty"`                                      // 发出时间
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`                                 // 送达回执时间
+	ReadAt         *time.Time `json:"read_at,omitempty"`                                      // 已读回执时间
+
+	// 关联关系
+	Notification *EmergencyNotification `gorm:"foreignKey:NotificationID" json:"notification,omitempty"`
 }
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+)
+
+// 紧急解锁范围
+const (
+	UnlockScopeAll      = "all"      // 所有设备
+	UnlockScopeProperty = "property" // 物业下所有楼号的设备
+	UnlockScopeBuilding = "building" // 指定楼号的设备
+	UnlockScopeDevices  = "devices"  // 指定设备
+)
+
+// 紧急解锁会话状态
+const (
+	UnlockSessionActive  = "active"  // 解锁中
+	UnlockSessionEnded   = "ended"   // 已手动结束并重新上锁
+	UnlockSessionExpired = "expired" // 已到时自动重新上锁
+)
+
+// 设备指令回执状态
+const (
+	CommandStatusPending = "pending" // 已下发，等待设备回执
+	CommandStatusAcked   = "acked"   // 设备确认执行成功
+	CommandStatusFailed  = "failed"  // 设备报告执行失败或下发失败
+	CommandStatusTimeout = "timeout" // 超时未收到回执
+	CommandStatusSkipped = "skipped" // 未下发，如演练会话或设备仍被其他解锁会话占用
+)
+
+// EmergencyUnlockSession 表示一次紧急解锁操作
+type EmergencyUnlockSession struct {
+	BaseModel
+	Reason       string     `gorm:"type:varchar(255);not null" json:"reason"`
+	ScopeType    string     `gorm:"type:varchar(20);not null" json:"scope_type"`  // all, property, building, devices
+	ScopeIDs     string     `gorm:"type:varchar(500)" json:"scope_ids,omitempty"` // 范围ID，逗号分隔
+	PropertyID   *uint      `gorm:"index" json:"property_id,omitempty"`           // 所有设备属于同一物业时记录该物业，用于按物业查询
+	Status       string     `gorm:"type:varchar(20);index;default:'active'" json:"status"`
+	OperatorID   uint       `json:"operator_id"`
+	OperatorRole string     `gorm:"type:varchar(20)" json:"operator_role"`
+	StartedAt    time.Time  `json:"started_at"`
+	RelockAt     *time.Time `gorm:"index" json:"relock_at,omitempty"` // 计划自动上锁时间，为空表示需手动结束
+	EndedAt      *time.Time `json:"ended_at,omitempty"`
+	EndedBy      *uint      `json:"ended_by,omitempty"`
+	EndReason    string     `gorm:"type:varchar(255)" json:"end_reason,omitempty"`
+	IsDrill      bool       `gorm:"default:false;index" json:"is_drill"` // 演练会话不向设备下发指令
+
+	// 关联关系
+	Devices []EmergencyUnlockDevice `gorm:"foreignKey:SessionID" json:"devices,omitempty"`
+}
+
+// EmergencyUnlockDevice 表示紧急解锁会话中单台设备的解锁与重新上锁回执
+type EmergencyUnlockDevice struct {
+	BaseModel
+	SessionID    uint       `gorm:"index;not null" json:"session_id"`
+	DeviceID     uint       `gorm:"index;not null" json:"device_id"`
+	UnlockStatus string     `gorm:"type:varchar(20);index;default:'pending'" json:"unlock_status"`
+	UnlockSentAt *time.Time `json:"unlock_sent_at,omitempty"`
+	UnlockAckAt  *time.Time `json:"unlock_ack_at,omitempty"`
+	RelockStatus string     `gorm:"type:varchar(20);index" json:"relock_status,omitempty"` // 结束会话前为空
+	RelockSentAt *time.Time `json:"relock_sent_at,omitempty"`
+	RelockAckAt  *time.Time `json:"relock_ack_at,omitempty"`
+	Error        string     `gorm:"type:varchar(255)" json:"error,omitempty"`
+
+	// 关联关系
+	Device *Device `gorm:"foreignKey:DeviceID" json:"device,omitempty"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// 通行码状态
+const (
+	PasscodeStatusActive  = "active"  // 有效
+	PasscodeStatusRevoked = "revoked" // 已作废
+)
+
+// Passcode 表示居民为访客、快递等生成的临时通行码，归属于户号
+type Passcode struct {
+	BaseModel
+	HouseholdID uint      `gorm:"index;not null" json:"household_id"`                    // 所属户号ID
+	ResidentID  uint      `gorm:"index;not null" json:"resident_id"`                     // 创建人（居民）ID
+	Code        string    `gorm:"type:varchar(12);index;not null" json:"code"`           // 通行码
+	Label       string    `gorm:"type:varchar(50)" json:"label"`                         // 用途，如"访客"、"快递"
+	ValidFrom   time.Time `json:"valid_from"`                                            // 生效时间
+	ValidUntil  time.Time `gorm:"index" json:"valid_until"`                              // 失效时间
+	MaxUses     int       `gorm:"default:0" json:"max_uses"`                             // 最大使用次数，0表示不限
+	UsedCount   int       `gorm:"default:0" json:"used_count"`                           // 已使用次数
+	Status      string    `gorm:"type:varchar(20);index;default:'active'" json:"status"` // 状态：active, revoked
+}
@@ -0,0 +1,9 @@
+package models
+
+// PasswordHistory 表示账号使用过的密码，用于阻止重复使用最近的密码
+type PasswordHistory struct {
+	BaseModel
+	SubjectType  string `gorm:"type:varchar(20);not null;index:idx_password_history_subject" json:"subject_type"` // 账号类型：admin, staff, user
+	SubjectID    uint   `gorm:"not null;index:idx_password_history_subject" json:"subject_id"`                    // 账号ID
+	PasswordHash string `gorm:"type:varchar(100);not null" json:"-"`                                              // 密码的bcrypt哈希
+}
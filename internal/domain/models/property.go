@@ -0,0 +1,16 @@
+package models
+
+// Property 表示物业（小区），楼号和物业员工归属于物业
+type Property struct {
+	BaseModel
+	Name         string `gorm:"type:varchar(100);not null" json:"name"`          // 物业名称，如"阳光花园"
+	Code         string `gorm:"type:varchar(20);unique;not null" json:"code"`    // 物业编码，如"P001"
+	Address      string `gorm:"type:varchar(200)" json:"address"`                // 物业地址
+	ContactName  string `gorm:"type:varchar(50)" json:"contact_name"`            // 联系人
+	ContactPhone string `gorm:"type:varchar(20)" json:"contact_phone"`           // 联系电话
+	Status       string `gorm:"type:varchar(20);default:'active'" json:"status"` // 状态：active, inactive
+
+	// 关联关系
+	Buildings []Building      `gorm:"foreignKey:PropertyID" json:"buildings,omitempty"` // 物业下的楼号（一对多）
+	Staff     []PropertyStaff `gorm:"foreignKey:PropertyID" json:"staff,omitempty"`     // 物业员工（一对多）
+}
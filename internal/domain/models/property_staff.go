@@ -1,17 +1,31 @@
 package models
 
+import "time"
+
+// 物业员工账号状态，只有 active 可以登录
+const (
+	StaffStatusActive    = "active"    // 正常
+	StaffStatusInactive  = "inactive"  // 已停用
+	StaffStatusSuspended = "suspended" // 暂停使用
+	StaffStatusLocked    = "locked"    // 被锁定，管理员解锁后恢复
+)
+
 // PropertyStaff 表示物业员工
 type PropertyStaff struct {
 	BaseModel
-	Phone        string `gorm:"type:varchar(20);unique;not null" json:"phone"`
+	Phone        string `gorm:"type:varchar(20);unique;not null" json:"phone"` // 手机号，也可以作为登录名
+	PropertyID   *uint  `gorm:"index" json:"property_id,omitempty"`            // 所属物业ID
 	PropertyName string `gorm:"type:varchar(100)" json:"property_name"`
 	Position     string `gorm:"type:varchar(50)" json:"position"`
 	Role         string `gorm:"type:varchar(20);not null" json:"role"` // manager, staff, etc.
 	Status       string `gorm:"type:varchar(20);default:'active'" json:"status"`
 	Remark       string `gorm:"type:text" json:"remark"`
 	Username     string `gorm:"type:varchar(50);unique;not null" json:"username"`
-	Password     string `gorm:"type:varchar(100);not null" json:"-"` // Password not exposed in JSON
+	Password     string `gorm:"type:varchar(100);not null" json:"-"` // 密码的bcrypt哈希，不在JSON中输出
+	// 为真时登录后只能修改密码，管理员重置密码后员工必须先修改密码
+	MustChangePassword bool       `gorm:"default:false" json:"must_change_password"`
+	LastLoginAt        *time.Time `json:"last_login_at,omitempty"` // 最近一次登录时间
 
 	// 关联关系 - 使用多对多关系替代直接关联
-	Devices []Device `gorm:"many2many:staff_device_relations;" json:"devices,omitempty"` // 通过关系表关联的设备列表
+	Devices []Device `gorm:"many2many:staff_device_relations;joinForeignKey:StaffID;joinReferences:DeviceID" json:"devices,omitempty"` // 通过关系表关联的设备列表
 }
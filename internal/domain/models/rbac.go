@@ -0,0 +1,35 @@
+package models
+
+// Permission 表示一项接口权限，编码格式为"资源:操作"，如"device:write"
+type Permission struct {
+	BaseModel
+	Code        string `gorm:"type:varchar(64);unique;not null" json:"code"` // 权限编码
+	Name        string `gorm:"type:varchar(100)" json:"name"`                // 权限名称
+	Description string `gorm:"type:varchar(255)" json:"description"`         // 说明
+}
+
+// Role 表示一组权限的集合
+type Role struct {
+	BaseModel
+	Name        string `gorm:"type:varchar(50);unique;not null" json:"name"` // 角色标识，如"admin"
+	DisplayName string `gorm:"type:varchar(100)" json:"display_name"`        // 显示名称
+	Description string `gorm:"type:varchar(255)" json:"description"`         // 说明
+	BuiltIn     bool   `gorm:"default:false" json:"built_in"`                // 是否为内置角色，内置角色不能删除
+	Revision    int    `gorm:"default:0" json:"-"`                           // 内置角色已应用的默认权限修订版本
+
+	// 关联关系
+	Permissions []Permission `gorm:"many2many:role_permissions" json:"permissions,omitempty"` // 角色拥有的权限（多对多）
+}
+
+// RoleBinding 将角色授予账号
+// SubjectType为账号类型（admin、staff、user，与令牌中的role一致），SubjectID为账号ID，
+// SubjectID为0表示授予该类型的所有账号
+type RoleBinding struct {
+	BaseModel
+	SubjectType string `gorm:"type:varchar(20);not null;uniqueIndex:idx_role_binding" json:"subject_type"` // 账号类型
+	SubjectID   uint   `gorm:"not null;default:0;uniqueIndex:idx_role_binding" json:"subject_id"`          // 账号ID，0表示该类型所有账号
+	RoleID      uint   `gorm:"not null;uniqueIndex:idx_role_binding" json:"role_id"`                       // 角色ID
+
+	// 关联关系
+	Role *Role `gorm:"foreignKey:RoleID" json:"role,omitempty"` // 绑定的角色
+}
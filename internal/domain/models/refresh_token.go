@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// RefreshToken 表示服务端保存的刷新令牌，每次刷新都会轮换，只保存令牌的哈希
+type RefreshToken struct {
+	BaseModel
+	SubjectType string     `gorm:"type:varchar(20);not null;index:idx_refresh_subject" json:"subject_type"` // 账号类型：admin, staff, user
+	SubjectID   uint       `gorm:"not null;index:idx_refresh_subject" json:"subject_id"`                    // 账号ID
+	SessionID   string     `gorm:"type:varchar(32);not null;index" json:"session_id"`                       // 登录会话ID，同一次登录轮换出的令牌共用
+	TokenHash   string     `gorm:"type:char(64);uniqueIndex;not null" json:"-"`                             // 令牌的SHA-256哈希
+	ExpiresAt   time.Time  `gorm:"index" json:"expires_at"`                                                 // 过期时间
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`                                                    // 轮换或注销时间
+	UserAgent   string     `gorm:"type:varchar(255)" json:"user_agent"`
+	IP          string     `gorm:"type:varchar(64)" json:"ip"`
+}
@@ -7,11 +7,16 @@ import (
 // SystemLog represents system operation logs
 type SystemLog struct {
 	BaseModel
-	AdminID   uint      `json:"admin_id"`
-	Action    string    `gorm:"type:varchar(100);not null" json:"action"`
-	Target    string    `gorm:"type:varchar(100)" json:"target"` // Target of action (device, user, config)
-	IPAddress string    `gorm:"type:varchar(45)" json:"ip_address"`
-	Timestamp time.Time `json:"timestamp"`
+	AdminID    *uint     `gorm:"index" json:"admin_id,omitempty"`          // 为空表示系统自动记录的事件或非管理员的操作
+	ActorType  string    `gorm:"type:varchar(20);index" json:"actor_type"` // 操作人类型：admin, staff, user, api_key，为空表示系统
+	ActorID    uint      `gorm:"index" json:"actor_id"`                    // 操作人ID，API密钥为密钥ID
+	PropertyID *uint     `gorm:"index" json:"property_id,omitempty"`       // 操作人所属物业，为空表示平台账号或系统
+	Action     string    `gorm:"type:varchar(100);not null;index" json:"action"`
+	Target     string    `gorm:"type:varchar(100);index" json:"target"` // Target of action, 格式为"类型:ID"，如 device:3
+	IPAddress  string    `gorm:"type:varchar(45)" json:"ip_address"`
+	StatusCode int       `json:"status_code,omitempty"`              // 接口返回的HTTP状态码，服务内部记录的事件为0
+	Changes    string    `gorm:"type:text" json:"changes,omitempty"` // 字段变更的JSON，格式为 {"字段":{"old":旧值,"new":新值}}
+	Timestamp  time.Time `gorm:"index" json:"timestamp"`
 
 	// Relations
 	Admin *Admin `gorm:"foreignKey:AdminID" json:"admin,omitempty"`
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// AdminTwoFactor 表示管理员的TOTP两步验证，EnabledAt 为空表示已生成密钥但尚未完成绑定
+type AdminTwoFactor struct {
+	BaseModel
+	AdminID      uint       `gorm:"not null;uniqueIndex" json:"admin_id"` // 管理员ID
+	Secret       string     `gorm:"type:varchar(255);not null" json:"-"`  // 加密保存的TOTP密钥
+	EnabledAt    *time.Time `json:"enabled_at,omitempty"`                 // 完成绑定的时间
+	LastUsedStep int64      `gorm:"not null;default:0" json:"-"`          // 最近一次通过验证的时间步，同一验证码不能重复使用
+}
+
+// AdminRecoveryCode 表示管理员两步验证的恢复码，每个恢复码只能使用一次
+type AdminRecoveryCode struct {
+	BaseModel
+	AdminID  uint       `gorm:"not null;index" json:"admin_id"`  // 管理员ID
+	CodeHash string     `gorm:"type:char(64);not null" json:"-"` // 恢复码的SHA-256哈希
+	UsedAt   *time.Time `json:"used_at,omitempty"`               // 使用时间
+}
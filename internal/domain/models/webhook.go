@@ -0,0 +1,54 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Webhook投递状态
+const (
+	WebhookDeliveryPending = "pending" // 等待发送或等待重试
+	WebhookDeliverySuccess = "success" // 对方返回2xx
+	WebhookDeliveryFailed  = "failed"  // 重试次数用尽，可通过管理接口重放
+)
+
+// WebhookSubscription 表示外部系统订阅的Webhook
+type WebhookSubscription struct {
+	BaseModel
+	Name        string `gorm:"type:varchar(100);not null" json:"name"`
+	URL         string `gorm:"type:varchar(500);not null" json:"url"`
+	Secret      string `gorm:"type:varchar(100);not null" json:"-"`      // 签名密钥，只在创建时返回
+	Events      string `gorm:"type:varchar(500);not null" json:"events"` // 订阅的事件类型，逗号分隔，*表示所有事件
+	Active      bool   `gorm:"index" json:"active"`
+	Description string `gorm:"type:varchar(255)" json:"description,omitempty"`
+}
+
+// Subscribes 判断订阅是否包含指定事件类型
+func (w *WebhookSubscription) Subscribes(eventType string) bool {
+	for _, event := range strings.Split(w.Events, ",") {
+		event = strings.TrimSpace(event)
+		if event == "*" || event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery 表示一次事件向一个订阅的投递及其重试状态
+type WebhookDelivery struct {
+	BaseModel
+	SubscriptionID uint       `gorm:"index;not null" json:"subscription_id"`
+	EventID        string     `gorm:"type:varchar(36);index;not null" json:"event_id"`
+	EventType      string     `gorm:"type:varchar(50);index;not null" json:"event_type"`
+	Payload        string     `gorm:"type:text" json:"payload"`                               // 发送的请求体
+	Status         string     `gorm:"type:varchar(20);index;default:'pending'" json:"status"` // pending, success, failed
+	Attempts       int        `gorm:"default:0" json:"attempts"`
+	ResponseCode   int        `json:"response_code,omitempty"`
+	LastError      string     `gorm:"type:varchar(500)" json:"last_error,omitempty"`
+	NextAttemptAt  *time.Time `gorm:"index" json:"next_attempt_at,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	Replays        int        `gorm:"default:0" json:"replays"` // 管理员手动重放次数
+
+	// 关联关系
+	Subscription *WebhookSubscription `gorm:"foreignKey:SubscriptionID" json:"subscription,omitempty"`
+}
@@ -2,7 +2,6 @@ package services
 
 import (
 	"errors"
-	"fmt"
 	"ilock-http-service/internal/domain/models"
 	"ilock-http-service/internal/infrastructure/config"
 
@@ -101,17 +100,26 @@ func (s *AdminService) CreateAdmin(admin *models.Admin) error {
 		return errors.New("用户名已存在")
 	}
 
-	// 设置密码哈希
-	hashedPassword, err := bcrypt.GenerateFromPassword(
-		[]byte(admin.Password),
-		bcrypt.DefaultCost,
-	)
+	// 物业管理员必须关联已存在的物业
+	if admin.PropertyID != nil {
+		if err := checkPropertyExists(s.DB, *admin.PropertyID); err != nil {
+			return err
+		}
+	}
+
+	// 校验密码策略并设置密码哈希
+	hashedPassword, err := hashNewPassword(s.DB, s.Config, LoginAccount{SubjectType: "admin"}, "", admin.Password)
 	if err != nil {
-		return fmt.Errorf("密码加密失败: %v", err)
+		return err
 	}
-	admin.Password = string(hashedPassword)
+	admin.Password = hashedPassword
 
-	return s.DB.Create(admin).Error
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(admin).Error; err != nil {
+			return err
+		}
+		return recordPasswordHistory(tx, s.Config, LoginAccount{SubjectType: "admin", SubjectID: admin.ID}, hashedPassword)
+	})
 }
 
 // 6  UpdateAdmin 更新管理员信息
@@ -133,19 +141,33 @@ func (s *AdminService) UpdateAdmin(id uint, updates map[string]interface{}) (*mo
 		}
 	}
 
-	// 如果更新密码，需要进行哈希处理
+	// 如果修改所属物业，目标物业必须存在
+	if err := (PropertyScope{}).checkPropertyChange(s.DB, updates); err != nil {
+		return nil, err
+	}
+
+	// 如果更新密码，需要校验密码策略和历史密码并进行哈希处理
+	account := LoginAccount{SubjectType: "admin", SubjectID: admin.ID}
+	hashedPassword := ""
 	if password, ok := updates["password"].(string); ok {
-		hashedPassword, err := bcrypt.GenerateFromPassword(
-			[]byte(password),
-			bcrypt.DefaultCost,
-		)
+		hashedPassword, err = hashNewPassword(s.DB, s.Config, account, admin.Password, password)
 		if err != nil {
-			return nil, fmt.Errorf("密码加密失败: %v", err)
+			return nil, err
 		}
-		updates["password"] = string(hashedPassword)
+		updates["password"] = hashedPassword
+		updates["must_change_password"] = false
 	}
 
-	if err := s.DB.Model(admin).Updates(updates).Error; err != nil {
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(admin).Updates(updates).Error; err != nil {
+			return err
+		}
+		if hashedPassword == "" {
+			return nil
+		}
+		return recordPasswordHistory(tx, s.Config, account, hashedPassword)
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -0,0 +1,372 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"ilock-http-service/internal/domain/models"
+	"ilock-http-service/internal/infrastructure/config"
+	"ilock-http-service/pkg/utils"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// InterfaceAPIKeyService 定义API密钥服务接口
+type InterfaceAPIKeyService interface {
+	CreateKey(key *models.APIKey) (string, error)
+	UpdateKey(id uint, updates map[string]interface{}) (*models.APIKey, error)
+	RotateKey(id uint) (string, *models.APIKey, error)
+	RevokeKey(id uint) error
+	GetKeys(includeRevoked bool, page, pageSize int) ([]models.APIKey, int64, error)
+	GetKeyByID(id uint) (*models.APIKey, error)
+	GetUsage(id uint, days int) ([]models.APIKeyUsage, error)
+	Authenticate(rawKey, ip string) (*models.APIKey, error)
+	RecordUsage(id uint, ip string)
+}
+
+var (
+	// ErrAPIKeyNotFound API密钥不存在
+	ErrAPIKeyNotFound = errors.New("API密钥不存在")
+	// ErrAPIKeyRevoked API密钥已被吊销
+	ErrAPIKeyRevoked = errors.New("API密钥已被吊销")
+	// ErrAPIKeyInvalid API密钥无效、已过期或已被吊销
+	ErrAPIKeyInvalid = errors.New("API密钥无效或已过期")
+	// ErrAPIKeyIPDenied 请求IP不在API密钥的白名单中
+	ErrAPIKeyIPDenied = errors.New("请求IP不在API密钥的白名单中")
+	// ErrInvalidAPIKey 权限范围、IP白名单或过期时间不合法
+	ErrInvalidAPIKey = errors.New("API密钥的权限范围、IP白名单或过期时间不合法")
+)
+
+// APIKeyRole 使用API密钥认证时写入上下文的角色，用户ID为密钥ID
+const APIKeyRole = "api_key"
+
+// apiKeyPrefix 密钥的固定前缀，便于在日志和代码仓库中识别泄露的密钥
+const apiKeyPrefix = "ilk_"
+
+// apiKeyDisplayLength 列表中显示的密钥前缀长度
+const apiKeyDisplayLength = 12
+
+// apiKeyUsageMaxDays 最多查询多少天的用量
+const apiKeyUsageMaxDays = 90
+
+// apiKeyForbiddenScopes 不能授予API密钥的权限：按账号本人过滤数据的权限没有对应的账号；
+// 会话、角色和API密钥管理只能由管理员本人操作，避免密钥提升自身权限
+var apiKeyForbiddenScopes = map[string]bool{
+	PermSelfRead:      true,
+	PermSelfWrite:     true,
+	PermAssignedRead:  true,
+	PermSessionManage: true,
+	PermRoleManage:    true,
+	PermAPIKeyManage:  true,
+}
+
+// APIKeyService 管理第三方系统使用的API密钥，校验权限范围和IP白名单并统计用量
+type APIKeyService struct {
+	DB     *gorm.DB
+	Config *config.Config
+}
+
+// NewAPIKeyService 创建一个新的API密钥服务
+func NewAPIKeyService(db *gorm.DB, cfg *config.Config) InterfaceAPIKeyService {
+	return &APIKeyService{
+		DB:     db,
+		Config: cfg,
+	}
+}
+
+// 1 CreateKey 创建API密钥，返回只展示这一次的完整密钥
+func (s *APIKeyService) CreateKey(key *models.APIKey) (string, error) {
+	if err := s.normalize(key); err != nil {
+		return "", err
+	}
+
+	rawKey, err := generateAPIKey()
+	if err != nil {
+		return "", err
+	}
+	key.Prefix = rawKey[:apiKeyDisplayLength]
+	key.KeyHash = hashToken(rawKey)
+
+	if err := s.DB.Create(key).Error; err != nil {
+		return "", err
+	}
+	return rawKey, nil
+}
+
+// 2 UpdateKey 更新API密钥的名称、权限范围、IP白名单、物业和过期时间，已吊销的密钥不能修改
+func (s *APIKeyService) UpdateKey(id uint, updates map[string]interface{}) (*models.APIKey, error) {
+	key, err := s.GetKeyByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if key.RevokedAt != nil {
+		return nil, ErrAPIKeyRevoked
+	}
+
+	if scopes, ok := updates["scopes"].(string); ok {
+		normalized, err := normalizeAPIKeyScopes(scopes)
+		if err != nil {
+			return nil, err
+		}
+		updates["scopes"] = normalized
+	}
+	if allowedIPs, ok := updates["allowed_ips"].(string); ok {
+		normalized, err := normalizeAllowedIPs(allowedIPs)
+		if err != nil {
+			return nil, err
+		}
+		updates["allowed_ips"] = normalized
+	}
+	if propertyID, ok := updates["property_id"].(*uint); ok && propertyID != nil {
+		if err := checkPropertyExists(s.DB, *propertyID); err != nil {
+			return nil, err
+		}
+	}
+	if expiresAt, ok := updates["expires_at"].(*time.Time); ok && expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: 过期时间必须晚于当前时间", ErrInvalidAPIKey)
+	}
+
+	if err := s.DB.Model(key).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	return s.GetKeyByID(id)
+}
+
+// 3 RotateKey 生成新的密钥并立即生效，原密钥失效，权限范围和用量统计保留
+func (s *APIKeyService) RotateKey(id uint) (string, *models.APIKey, error) {
+	key, err := s.GetKeyByID(id)
+	if err != nil {
+		return "", nil, err
+	}
+	if key.RevokedAt != nil {
+		return "", nil, ErrAPIKeyRevoked
+	}
+
+	rawKey, err := generateAPIKey()
+	if err != nil {
+		return "", nil, err
+	}
+	if err := s.DB.Model(key).Updates(map[string]interface{}{
+		"prefix":     rawKey[:apiKeyDisplayLength],
+		"key_hash":   hashToken(rawKey),
+		"rotated_at": time.Now(),
+	}).Error; err != nil {
+		return "", nil, err
+	}
+
+	key, err = s.GetKeyByID(id)
+	if err != nil {
+		return "", nil, err
+	}
+	return rawKey, key, nil
+}
+
+// 4 RevokeKey 吊销API密钥，立即失效且不能恢复，记录保留用于查看用量
+func (s *APIKeyService) RevokeKey(id uint) error {
+	key, err := s.GetKeyByID(id)
+	if err != nil {
+		return err
+	}
+	if key.RevokedAt != nil {
+		return nil
+	}
+	return s.DB.Model(key).Update("revoked_at", time.Now()).Error
+}
+
+// 5 GetKeys 分页获取API密钥，默认不包括已吊销的密钥
+func (s *APIKeyService) GetKeys(includeRevoked bool, page, pageSize int) ([]models.APIKey, int64, error) {
+	var keys []models.APIKey
+	var total int64
+
+	db := s.DB.Model(&models.APIKey{})
+	if !includeRevoked {
+		db = db.Where("revoked_at IS NULL")
+	}
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	if err := db.Order("id DESC").Limit(pageSize).Offset(offset).Find(&keys).Error; err != nil {
+		return nil, 0, err
+	}
+	return keys, total, nil
+}
+
+// 6 GetKeyByID 根据ID获取API密钥
+func (s *APIKeyService) GetKeyByID(id uint) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := s.DB.First(&key, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+// 7 GetUsage 获取API密钥最近若干天每天的请求次数，没有请求的日期不返回
+func (s *APIKeyService) GetUsage(id uint, days int) ([]models.APIKeyUsage, error) {
+	if _, err := s.GetKeyByID(id); err != nil {
+		return nil, err
+	}
+	if days < 1 || days > apiKeyUsageMaxDays {
+		days = 30
+	}
+
+	since := time.Now().AddDate(0, 0, -(days - 1)).Format("2006-01-02")
+	var usage []models.APIKeyUsage
+	if err := s.DB.Where("api_key_id = ? AND date >= ?", id, since).Order("date ASC").Find(&usage).Error; err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+// 8 Authenticate 校验请求携带的API密钥：密钥存在、未吊销、未过期，且请求IP在白名单中
+func (s *APIKeyService) Authenticate(rawKey, ip string) (*models.APIKey, error) {
+	if !strings.HasPrefix(rawKey, apiKeyPrefix) {
+		return nil, ErrAPIKeyInvalid
+	}
+
+	var key models.APIKey
+	if err := s.DB.Where("key_hash = ?", hashToken(rawKey)).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAPIKeyInvalid
+		}
+		return nil, err
+	}
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && time.Now().After(*key.ExpiresAt)) {
+		return nil, ErrAPIKeyInvalid
+	}
+	if !ipAllowed(key.AllowedIPList(), ip) {
+		return nil, ErrAPIKeyIPDenied
+	}
+	return &key, nil
+}
+
+// 9 RecordUsage 记录一次请求：更新累计次数、最后使用时间和IP，并累加当天的请求次数；失败只记录日志
+func (s *APIKeyService) RecordUsage(id uint, ip string) {
+	now := time.Now()
+	if err := s.DB.Model(&models.APIKey{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"request_count": gorm.Expr("request_count + 1"),
+		"last_used_at":  now,
+		"last_used_ip":  truncate(ip, 64),
+	}).Error; err != nil {
+		log.Printf("[APIKey] 更新密钥 %d 的使用记录失败: %v", id, err)
+	}
+
+	usage := &models.APIKeyUsage{APIKeyID: id, Date: now.Format("2006-01-02"), Requests: 1}
+	if err := s.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "api_key_id"}, {Name: "date"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"requests": gorm.Expr("requests + 1"), "updated_at": now}),
+	}).Create(usage).Error; err != nil {
+		log.Printf("[APIKey] 更新密钥 %d 的每日用量失败: %v", id, err)
+	}
+}
+
+// normalize 校验并规范化新密钥的权限范围、IP白名单、物业和过期时间
+func (s *APIKeyService) normalize(key *models.APIKey) error {
+	scopes, err := normalizeAPIKeyScopes(key.Scopes)
+	if err != nil {
+		return err
+	}
+	allowedIPs, err := normalizeAllowedIPs(key.AllowedIPs)
+	if err != nil {
+		return err
+	}
+	if key.PropertyID != nil {
+		if err := checkPropertyExists(s.DB, *key.PropertyID); err != nil {
+			return err
+		}
+	}
+	if key.ExpiresAt != nil && !key.ExpiresAt.After(time.Now()) {
+		return fmt.Errorf("%w: 过期时间必须晚于当前时间", ErrInvalidAPIKey)
+	}
+
+	key.Scopes = scopes
+	key.AllowedIPs = allowedIPs
+	return nil
+}
+
+// generateAPIKey 生成带固定前缀的随机密钥
+func generateAPIKey() (string, error) {
+	secret, err := utils.RandomHex(24)
+	if err != nil {
+		return "", fmt.Errorf("生成API密钥失败: %w", err)
+	}
+	return apiKeyPrefix + secret, nil
+}
+
+// normalizeAPIKeyScopes 校验权限编码存在并去重
+func normalizeAPIKeyScopes(scopes string) (string, error) {
+	known := make(map[string]bool, len(PermissionDefinitions))
+	for _, def := range PermissionDefinitions {
+		known[def.Code] = true
+	}
+
+	var normalized []string
+	seen := make(map[string]bool)
+	for _, scope := range strings.Split(scopes, ",") {
+		scope = strings.TrimSpace(scope)
+		if scope == "" || seen[scope] {
+			continue
+		}
+		if !known[scope] {
+			return "", fmt.Errorf("%w: 未知的权限编码 %s", ErrInvalidAPIKey, scope)
+		}
+		if apiKeyForbiddenScopes[scope] {
+			return "", fmt.Errorf("%w: 不能授予API密钥 %s 权限", ErrInvalidAPIKey, scope)
+		}
+		seen[scope] = true
+		normalized = append(normalized, scope)
+	}
+	if len(normalized) == 0 {
+		return "", fmt.Errorf("%w: 至少需要一个权限", ErrInvalidAPIKey)
+	}
+	return strings.Join(normalized, ","), nil
+}
+
+// normalizeAllowedIPs 校验IP白名单中的每一项都是IP地址或CIDR
+func normalizeAllowedIPs(allowedIPs string) (string, error) {
+	var normalized []string
+	for _, entry := range strings.Split(allowedIPs, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if strings.Contains(entry, "/") {
+			if _, _, err := net.ParseCIDR(entry); err != nil {
+				return "", fmt.Errorf("%w: 无效的CIDR %s", ErrInvalidAPIKey, entry)
+			}
+		} else if net.ParseIP(entry) == nil {
+			return "", fmt.Errorf("%w: 无效的IP %s", ErrInvalidAPIKey, entry)
+		}
+		normalized = append(normalized, entry)
+	}
+	return strings.Join(normalized, ","), nil
+}
+
+// ipAllowed 判断IP是否在白名单中，白名单为空时不限制
+func ipAllowed(allowed []string, ip string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, entry := range allowed {
+		if strings.Contains(entry, "/") {
+			if _, network, err := net.ParseCIDR(entry); err == nil && network.Contains(parsed) {
+				return true
+			}
+		} else if allowedIP := net.ParseIP(entry); allowedIP != nil && allowedIP.Equal(parsed) {
+			return true
+		}
+	}
+	return false
+}
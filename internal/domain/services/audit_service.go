@@ -0,0 +1,278 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"ilock-http-service/internal/domain/models"
+	"ilock-http-service/internal/infrastructure/config"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// InterfaceAuditService 定义审计日志服务接口
+type InterfaceAuditService interface {
+	Snapshot(table string, id uint) (map[string]interface{}, error)
+	Record(entry *models.SystemLog, before, after map[string]interface{}) error
+	GetLogs(query AuditQuery) ([]models.SystemLog, int64, error)
+	ExportLogs(query AuditQuery, w io.Writer) (int, error)
+}
+
+// AuditChange 表示一个字段的变更
+type AuditChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// AuditQuery 审计日志查询条件
+type AuditQuery struct {
+	ActorType  string
+	ActorID    *uint
+	PropertyID *uint
+	Action     string // 模糊匹配
+	Target     string // "device" 匹配该类型的所有记录，"device:3" 精确匹配
+	Failed     *bool  // true 只返回失败的请求，false 只返回成功的请求和服务内部记录的事件
+	StartTime  *time.Time
+	EndTime    *time.Time
+	Page       int
+	PageSize   int
+}
+
+// auditRedacted 敏感字段在变更记录中的替代值
+const auditRedacted = "***"
+
+// auditExportMaxRows 单次导出的最大行数
+const auditExportMaxRows = 10000
+
+// auditExportBatchSize 导出时每次查询的行数
+const auditExportBatchSize = 500
+
+// auditRedactedColumns 只记录是否变更、不记录值的字段
+var auditRedactedColumns = map[string]bool{
+	"password":      true,
+	"password_hash": true,
+	"secret":        true,
+	"secret_hash":   true,
+	"signing_key":   true,
+	"key_hash":      true,
+	"token_hash":    true,
+	"code_hash":     true,
+}
+
+// auditIgnoredColumns 每次写入都会变化、不需要记录的字段
+var auditIgnoredColumns = map[string]bool{
+	"created_at": true,
+	"updated_at": true,
+}
+
+// AuditService 记录和查询审计日志，审计日志保存在 system_logs 表中
+type AuditService struct {
+	DB     *gorm.DB
+	Config *config.Config
+}
+
+// NewAuditService 创建一个新的审计日志服务
+func NewAuditService(db *gorm.DB, cfg *config.Config) InterfaceAuditService {
+	return &AuditService{
+		DB:     db,
+		Config: cfg,
+	}
+}
+
+// 1. Snapshot 读取一行记录的所有字段，用于对比变更前后的值，记录不存在时返回nil
+func (s *AuditService) Snapshot(table string, id uint) (map[string]interface{}, error) {
+	row := map[string]interface{}{}
+	err := s.DB.Table(table).Where("id = ?", id).Take(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return row, nil
+}
+
+// 2. Record 写入一条审计日志，before 和 after 不为空时计算字段变更
+func (s *AuditService) Record(entry *models.SystemLog, before, after map[string]interface{}) error {
+	if before != nil || after != nil {
+		if changes := diffSnapshots(before, after); len(changes) > 0 {
+			data, err := json.Marshal(changes)
+			if err != nil {
+				return err
+			}
+			entry.Changes = string(data)
+		}
+	}
+
+	entry.Action = truncate(entry.Action, 100)
+	entry.Target = truncate(entry.Target, 100)
+	entry.IPAddress = truncate(entry.IPAddress, 45)
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+	return s.DB.Create(entry).Error
+}
+
+// 3. GetLogs 按条件分页查询审计日志，最新的在前
+func (s *AuditService) GetLogs(query AuditQuery) ([]models.SystemLog, int64, error) {
+	var logs []models.SystemLog
+	var total int64
+
+	db := s.filter(query)
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (query.Page - 1) * query.PageSize
+	if err := db.Order("timestamp DESC, id DESC").Limit(query.PageSize).Offset(offset).Find(&logs).Error; err != nil {
+		return nil, 0, err
+	}
+	return logs, total, nil
+}
+
+// 4. ExportLogs 按条件导出审计日志为CSV，最多导出 auditExportMaxRows 行，返回导出的行数
+func (s *AuditService) ExportLogs(query AuditQuery, w io.Writer) (int, error) {
+	writer := csv.NewWriter(w)
+	header := []string{"id", "timestamp", "actor_type", "actor_id", "property_id", "action", "target", "ip_address", "status_code", "changes"}
+	if err := writer.Write(header); err != nil {
+		return 0, err
+	}
+
+	rows := 0
+	for rows < auditExportMaxRows {
+		var batch []models.SystemLog
+		size := auditExportBatchSize
+		if remaining := auditExportMaxRows - rows; remaining < size {
+			size = remaining
+		}
+		if err := s.filter(query).Order("timestamp DESC, id DESC").Limit(size).Offset(rows).Find(&batch).Error; err != nil {
+			return rows, err
+		}
+
+		for _, entry := range batch {
+			propertyID := ""
+			if entry.PropertyID != nil {
+				propertyID = strconv.FormatUint(uint64(*entry.PropertyID), 10)
+			}
+			record := []string{
+				strconv.FormatUint(uint64(entry.ID), 10),
+				entry.Timestamp.Format(time.RFC3339),
+				entry.ActorType,
+				strconv.FormatUint(uint64(entry.ActorID), 10),
+				propertyID,
+				entry.Action,
+				entry.Target,
+				entry.IPAddress,
+				strconv.Itoa(entry.StatusCode),
+				entry.Changes,
+			}
+			if err := writer.Write(record); err != nil {
+				return rows, err
+			}
+		}
+		rows += len(batch)
+		if len(batch) < size {
+			break
+		}
+	}
+
+	writer.Flush()
+	return rows, writer.Error()
+}
+
+// filter 根据查询条件构造审计日志查询
+func (s *AuditService) filter(query AuditQuery) *gorm.DB {
+	db := s.DB.Model(&models.SystemLog{})
+	if query.ActorType != "" {
+		db = db.Where("actor_type = ?", query.ActorType)
+	}
+	if query.ActorID != nil {
+		db = db.Where("actor_id = ?", *query.ActorID)
+	}
+	if query.PropertyID != nil {
+		db = db.Where("property_id = ?", *query.PropertyID)
+	}
+	if query.Action != "" {
+		db = db.Where("action LIKE ?", "%"+escapeLike(query.Action)+"%")
+	}
+	if query.Target != "" {
+		if strings.Contains(query.Target, ":") {
+			db = db.Where("target = ?", query.Target)
+		} else {
+			db = db.Where("target = ? OR target LIKE ?", query.Target, escapeLike(query.Target)+":%")
+		}
+	}
+	if query.Failed != nil {
+		if *query.Failed {
+			db = db.Where("status_code >= ?", 400)
+		} else {
+			db = db.Where("status_code < ?", 400)
+		}
+	}
+	if query.StartTime != nil {
+		db = db.Where("timestamp >= ?", *query.StartTime)
+	}
+	if query.EndTime != nil {
+		db = db.Where("timestamp <= ?", *query.EndTime)
+	}
+	return db
+}
+
+// diffSnapshots 对比变更前后的字段值，新建时 before 为空，删除时 after 为空
+func diffSnapshots(before, after map[string]interface{}) map[string]AuditChange {
+	changes := make(map[string]AuditChange)
+	columns := make(map[string]bool, len(before)+len(after))
+	for column := range before {
+		columns[column] = true
+	}
+	for column := range after {
+		columns[column] = true
+	}
+
+	for column := range columns {
+		if auditIgnoredColumns[column] {
+			continue
+		}
+		oldValue, newValue := before[column], after[column]
+		if auditValueEqual(oldValue, newValue) {
+			continue
+		}
+		if auditRedactedColumns[column] {
+			if oldValue != nil {
+				oldValue = auditRedacted
+			}
+			if newValue != nil {
+				newValue = auditRedacted
+			}
+		}
+		changes[column] = AuditChange{Old: oldValue, New: newValue}
+	}
+	return changes
+}
+
+// auditValueEqual 比较两个数据库字段值，驱动返回的类型可能不同，统一按字符串比较
+func auditValueEqual(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if reflect.DeepEqual(a, b) {
+		return true
+	}
+	if ta, ok := a.(time.Time); ok {
+		if tb, ok := b.(time.Time); ok {
+			return ta.Equal(tb)
+		}
+	}
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+// escapeLike 转义LIKE查询中的通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -10,6 +10,7 @@ import (
 
 // InterfaceBuildingService 定义楼号服务接口
 type InterfaceBuildingService interface {
+	WithPropertyScope(propertyID *uint) InterfaceBuildingService
 	GetAllBuildings(page, pageSize int) ([]models.Building, int64, error)
 	GetBuildingByID(id uint) (*models.Building, error)
 	CreateBuilding(building *models.Building) error
@@ -23,6 +24,7 @@ type InterfaceBuildingService interface {
 type BuildingService struct {
 	DB     *gorm.DB
 	Config *config.Config
+	Scope  PropertyScope
 }
 
 // NewBuildingService 创建一个新的楼号服务
@@ -33,19 +35,26 @@ func NewBuildingService(db *gorm.DB, cfg *config.Config) InterfaceBuildingServic
 	}
 }
 
+// 0. WithPropertyScope 返回限定在指定物业内的服务
+func (s *BuildingService) WithPropertyScope(propertyID *uint) InterfaceBuildingService {
+	scoped := *s
+	scoped.Scope = PropertyScope{PropertyID: propertyID}
+	return &scoped
+}
+
 // 1. GetAllBuildings 获取所有楼号列表，支持分页
 func (s *BuildingService) GetAllBuildings(page, pageSize int) ([]models.Building, int64, error) {
 	var buildings []models.Building
 	var total int64
 
 	// 获取总数
-	if err := s.DB.Model(&models.Building{}).Count(&total).Error; err != nil {
+	if err := s.DB.Model(&models.Building{}).Scopes(s.Scope.Buildings).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
 	// 分页查询
 	offset := (page - 1) * pageSize
-	if err := s.DB.Limit(pageSize).Offset(offset).Find(&buildings).Error; err != nil {
+	if err := s.DB.Scopes(s.Scope.Buildings).Limit(pageSize).Offset(offset).Find(&buildings).Error; err != nil {
 		return nil, 0, err
 	}
 
@@ -55,7 +64,7 @@ func (s *BuildingService) GetAllBuildings(page, pageSize int) ([]models.Building
 // 2. GetBuildingByID 根据ID获取楼号
 func (s *BuildingService) GetBuildingByID(id uint) (*models.Building, error) {
 	var building models.Building
-	if err := s.DB.First(&building, id).Error; err != nil {
+	if err := s.DB.Scopes(s.Scope.Buildings).First(&building, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("楼号不存在")
 		}
@@ -75,6 +84,15 @@ func (s *BuildingService) CreateBuilding(building *models.Building) error {
 		return errors.New("楼号编码已存在")
 	}
 
+	// 限定物业时楼号归属当前物业，否则校验指定的物业存在
+	if s.Scope.Scoped() {
+		building.PropertyID = s.Scope.PropertyID
+	} else if building.PropertyID != nil {
+		if err := checkPropertyExists(s.DB, *building.PropertyID); err != nil {
+			return err
+		}
+	}
+
 	// 设置默认状态
 	if building.Status == "" {
 		building.Status = "active"
@@ -101,6 +119,10 @@ func (s *BuildingService) UpdateBuilding(id uint, updates map[string]interface{}
 		}
 	}
 
+	if err := s.Scope.checkPropertyChange(s.DB, updates); err != nil {
+		return nil, err
+	}
+
 	if err := s.DB.Model(building).Updates(updates).Error; err != nil {
 		return nil, err
 	}
@@ -29,6 +29,7 @@ type CallFeedback struct {
 
 // InterfaceCallRecordService defines the call record service interface
 type InterfaceCallRecordService interface {
+	WithPropertyScope(propertyID *uint) InterfaceCallRecordService
 	GetAllCallRecords(page, pageSize int) ([]models.CallRecord, int64, error)
 	GetCallRecordByID(id uint) (*models.CallRecord, error)
 	GetCallRecordsByDeviceID(deviceID uint, page, pageSize int) ([]models.CallRecord, int64, error)
@@ -42,6 +43,7 @@ type InterfaceCallRecordService interface {
 type CallRecordService struct {
 	DB     *gorm.DB
 	Config *config.Config
+	Scope  PropertyScope
 }
 
 // NewCallRecordService 创建一个新的通话记录服务
@@ -52,19 +54,26 @@ func NewCallRecordService(db *gorm.DB, cfg *config.Config) InterfaceCallRecordSe
 	}
 }
 
+// WithPropertyScope 返回限定在指定物业内的通话记录服务，nil表示不限物业
+func (s *CallRecordService) WithPropertyScope(propertyID *uint) InterfaceCallRecordService {
+	scoped := *s
+	scoped.Scope = PropertyScope{PropertyID: propertyID}
+	return &scoped
+}
+
 // 1 GetAllCallRecords 获取所有通话记录，支持分页
 func (s *CallRecordService) GetAllCallRecords(page, pageSize int) ([]models.CallRecord, int64, error) {
 	var calls []models.CallRecord
 	var total int64
 
 	// 获取总数
-	if err := s.DB.Model(&models.CallRecord{}).Count(&total).Error; err != nil {
+	if err := s.DB.Model(&models.CallRecord{}).Scopes(s.Scope.CallRecords).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
 	// 分页查询，并预加载关联
 	offset := (page - 1) * pageSize
-	if err := s.DB.Preload("Device").Preload("Residents").
+	if err := s.DB.Scopes(s.Scope.CallRecords).Preload("Device").Preload("Residents").
 		Order("timestamp DESC").
 		Limit(pageSize).Offset(offset).
 		Find(&calls).Error; err != nil {
@@ -77,7 +86,7 @@ func (s *CallRecordService) GetAllCallRecords(page, pageSize int) ([]models.Call
 // 2 GetCallRecordByID 根据ID获取通话记录
 func (s *CallRecordService) GetCallRecordByID(id uint) (*models.CallRecord, error) {
 	var call models.CallRecord
-	if err := s.DB.Preload("Device").Preload("Residents").First(&call, id).Error; err != nil {
+	if err := s.DB.Scopes(s.Scope.CallRecords).Preload("Device").Preload("Residents").First(&call, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("通话记录不存在")
 		}
@@ -93,7 +102,7 @@ func (s *CallRecordService) GetCallRecordsByDeviceID(deviceID uint, page, pageSi
 
 	// 检查设备是否存在
 	var device models.Device
-	if err := s.DB.First(&device, deviceID).Error; err != nil {
+	if err := s.DB.Scopes(s.Scope.Devices).First(&device, deviceID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, 0, errors.New("设备不存在")
 		}
@@ -125,7 +134,7 @@ func (s *CallRecordService) GetCallRecordsByResidentID(residentID uint, page, pa
 
 	// 检查居民是否存在
 	var resident models.Resident
-	if err := s.DB.First(&resident, residentID).Error; err != nil {
+	if err := s.DB.Scopes(s.Scope.Residents).First(&resident, residentID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, 0, errors.New("居民不存在")
 		}
@@ -133,13 +142,13 @@ func (s *CallRecordService) GetCallRecordsByResidentID(residentID uint, page, pa
 	}
 
 	// 获取总数
-	if err := s.DB.Model(&models.CallRecord{}).Where("resident_id = ?", residentID).Count(&total).Error; err != nil {
+	if err := s.DB.Model(&models.CallRecord{}).Scopes(s.Scope.CallRecords).Where("resident_id = ?", residentID).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
 	// 分页查询，并预加载关联
 	offset := (page - 1) * pageSize
-	if err := s.DB.Preload("Device").Preload("Residents").
+	if err := s.DB.Scopes(s.Scope.CallRecords).Preload("Device").Preload("Residents").
 		Where("resident_id = ?", residentID).
 		Order("timestamp DESC").
 		Limit(pageSize).Offset(offset).
@@ -156,22 +165,22 @@ func (s *CallRecordService) GetCallStatistics() (*CallStatistics, error) {
 	var totalDuration int64
 
 	// 获取总通话数
-	if err := s.DB.Model(&models.CallRecord{}).Count(&statistics.TotalCalls).Error; err != nil {
+	if err := s.DB.Model(&models.CallRecord{}).Scopes(s.Scope.CallRecords).Count(&statistics.TotalCalls).Error; err != nil {
 		return nil, err
 	}
 
 	// 获取已接通话数
-	if err := s.DB.Model(&models.CallRecord{}).Where("call_status = ?", models.CallStatusAnswered).Count(&statistics.AnsweredCalls).Error; err != nil {
+	if err := s.DB.Model(&models.CallRecord{}).Scopes(s.Scope.CallRecords).Where("call_status = ?", models.CallStatusAnswered).Count(&statistics.AnsweredCalls).Error; err != nil {
 		return nil, err
 	}
 
 	// 获取未接通话数
-	if err := s.DB.Model(&models.CallRecord{}).Where("call_status = ?", models.CallStatusMissed).Count(&statistics.MissedCalls).Error; err != nil {
+	if err := s.DB.Model(&models.CallRecord{}).Scopes(s.Scope.CallRecords).Where("call_status = ?", models.CallStatusMissed).Count(&statistics.MissedCalls).Error; err != nil {
 		return nil, err
 	}
 
 	// 获取超时通话数
-	if err := s.DB.Model(&models.CallRecord{}).Where("call_status = ?", models.CallStatusTimeout).Count(&statistics.TimeoutCalls).Error; err != nil {
+	if err := s.DB.Model(&models.CallRecord{}).Scopes(s.Scope.CallRecords).Where("call_status = ?", models.CallStatusTimeout).Count(&statistics.TimeoutCalls).Error; err != nil {
 		return nil, err
 	}
 
@@ -180,7 +189,7 @@ func (s *CallRecordService) GetCallStatistics() (*CallStatistics, error) {
 		var result struct {
 			TotalDuration int64
 		}
-		if err := s.DB.Model(&models.CallRecord{}).
+		if err := s.DB.Model(&models.CallRecord{}).Scopes(s.Scope.CallRecords).
 			Where("call_status = ?", models.CallStatusAnswered).
 			Select("sum(duration) as total_duration").
 			Scan(&result).Error; err != nil {
@@ -197,7 +206,7 @@ func (s *CallRecordService) GetCallStatistics() (*CallStatistics, error) {
 func (s *CallRecordService) SubmitCallFeedback(feedback *CallFeedback) error {
 	// 验证通话记录是否存在
 	var call models.CallRecord
-	if err := s.DB.First(&call, feedback.CallID).Error; err != nil {
+	if err := s.DB.Scopes(s.Scope.CallRecords).First(&call, feedback.CallID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return errors.New("通话记录不存在")
 		}
@@ -224,7 +233,7 @@ func (s *CallRecordService) GetCallRecordByCallID(callID string) (*models.CallRe
 	var call models.CallRecord
 
 	// 查询字段名可能需要根据实际的数据表结构调整
-	if err := s.DB.Preload("Device").Preload("Residents").
+	if err := s.DB.Scopes(s.Scope.CallRecords).Preload("Device").Preload("Residents").
 		Where("call_id = ?", callID).
 		First(&call).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
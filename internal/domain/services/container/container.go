@@ -3,11 +3,15 @@ package container
 import (
 	"context"
 	"log"
+	"strings"
 	"sync"
 	"time"
 
+	"ilock-http-service/internal/domain/events"
 	"ilock-http-service/internal/domain/services"
 	"ilock-http-service/internal/infrastructure/config"
+	"ilock-http-service/internal/infrastructure/database"
+	"ilock-http-service/internal/infrastructure/notifier"
 
 	"github.com/go-redis/redis/v8"
 	"gorm.io/gorm"
@@ -19,8 +23,19 @@ type ServiceContainer struct {
 	config *config.Config
 	redis  *redis.Client
 
+	// 领域事件总线
+	eventBus *events.Bus
+
 	// 基础服务
-	jwtService services.InterfaceJWTService
+	jwtService       services.InterfaceJWTService
+	rbacService      services.InterfaceRBACService
+	deviceSignature  services.InterfaceDeviceSignatureService
+	loginGuard       services.InterfaceLoginGuardService
+	passwordService  services.InterfacePasswordService
+	twoFactorService services.InterfaceTwoFactorService
+	apiKeyService    services.InterfaceAPIKeyService
+	auditService     services.InterfaceAuditService
+	smsLoginService  services.InterfaceSMSLoginService
 
 	// RTC相关服务
 	rtcService        services.InterfaceRTCService
@@ -29,6 +44,12 @@ type ServiceContainer struct {
 	// 数据存储服务
 	redisService services.InterfaceRedisService
 
+	// 通知服务
+	notificationService services.InterfaceNotificationService
+
+	// Webhook推送服务
+	webhookService services.InterfaceWebhookService
+
 	// MQTT通话服务
 	mqttCallService services.InterfaceMQTTCallService
 
@@ -36,11 +57,31 @@ type ServiceContainer struct {
 	deviceService     services.InterfaceDeviceService
 	adminService      services.InterfaceAdminService
 	residentService   services.InterfaceResidentService
+	portalService     services.InterfaceResidentPortalService
 	staffService      services.InterfaceStaffService
+	staffPortal       services.InterfaceStaffPortalService
 	callRecordService services.InterfaceCallRecordService
 	emergencyService  services.InterfaceEmergencyService
 	buildingService   services.InterfaceBuildingService
 	householdService  services.InterfaceHouseholdService
+	propertyService   services.InterfacePropertyService
+	importService     services.InterfaceImportService
+
+	// 设备事件服务
+	deviceEventService services.InterfaceDeviceEventService
+
+	// 紧急通知投递服务
+	emergencyNotificationService services.InterfaceEmergencyNotificationService
+
+	// 紧急联系人与升级服务
+	emergencyContactService services.InterfaceEmergencyContactService
+	escalationService       services.InterfaceEscalationService
+
+	// 紧急解锁服务
+	emergencyUnlockService services.InterfaceEmergencyUnlockService
+
+	// 应急演练报告服务
+	emergencyDrillService services.InterfaceEmergencyDrillService
 
 	mu sync.RWMutex
 }
@@ -79,18 +120,51 @@ func (c *ServiceContainer) initializeServices() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	// 初始化事件总线，其他服务通过它发布领域事件
+	c.eventBus = events.NewBus()
+
+	// 初始化Redis服务
+	c.redisService = services.NewRedisService(c.config)
+
 	// 初始化基础服务
-	c.jwtService = services.NewJWTService(c.config, c.db)
+	c.loginGuard = services.NewLoginGuardService(c.db, c.config, c.redisService)
+	c.rbacService = services.NewRBACService(c.db, c.config)
+	c.twoFactorService = services.NewTwoFactorService(c.db, c.config, c.rbacService)
+	c.jwtService = services.NewJWTService(c.config, c.db, c.redisService, c.loginGuard, c.twoFactorService)
+	c.deviceSignature = services.NewDeviceSignatureService(c.db, c.config, c.redisService)
+	c.apiKeyService = services.NewAPIKeyService(c.db, c.config)
+	c.auditService = services.NewAuditService(c.db, c.config)
 
 	// 初始化RTC服务
 	c.rtcService = services.NewRTCService(c.config)
 	c.tencentRTCService = services.NewTencentRTCService(c.config)
 
-	// 初始化Redis服务
-	c.redisService = services.NewRedisService(c.config)
+	// 初始化通知服务
+	c.notificationService = services.NewNotificationService(c.db, c.config)
+
+	// 初始化密码服务，找回密码验证码优先通过短信发送
+	c.passwordService = services.NewPasswordService(c.db, c.config, c.redisService)
+	for _, channel := range []notifier.Channel{notifier.ChannelSMS, notifier.ChannelEmail} {
+		if c.notificationService.HasChannel(channel) {
+			c.passwordService.RegisterNotifier(&services.DispatchPasswordResetNotifier{
+				Channel:  channel,
+				Notifier: c.notificationService,
+			})
+		}
+	}
+
+	// 初始化居民短信验证码登录服务，验证码通过短信渠道发送
+	c.smsLoginService = services.NewSMSLoginService(c.db, c.config, c.redisService, c.loginGuard, c.jwtService)
+	if c.notificationService.HasChannel(notifier.ChannelSMS) {
+		c.smsLoginService.RegisterSender(&services.DispatchLoginCodeSender{Notifier: c.notificationService})
+	}
+
+	// 初始化Webhook推送服务，并订阅事件总线
+	c.webhookService = services.NewWebhookService(c.db, c.config)
+	c.webhookService.SubscribeEvents(c.eventBus)
 
 	// 初始化MQTT通话服务 - 使用接口类型
-	c.mqttCallService = services.NewMQTTCallService(c.db, c.config, c.tencentRTCService)
+	c.mqttCallService = services.NewMQTTCallService(c.db, c.config, c.tencentRTCService, c.notificationService, c.eventBus)
 
 	// 连接MQTT服务器
 	if err := c.mqttCallService.Connect(); err != nil {
@@ -98,16 +172,63 @@ func (c *ServiceContainer) initializeServices() {
 	}
 
 	// 初始化业务服务
-	c.deviceService = services.NewDeviceService(c.db, c.config)
+	c.deviceService = services.NewDeviceService(c.db, c.config, c.eventBus)
 	c.adminService = services.NewAdminService(c.db, c.config)
-	c.residentService = services.NewResidentService(c.db, c.config)
+	c.residentService = services.NewResidentService(c.db, c.config, c.eventBus)
 	c.staffService = services.NewStaffService(c.db, c.config)
+	c.staffPortal = services.NewStaffPortalService(c.db, c.config)
 	c.callRecordService = services.NewCallRecordService(c.db, c.config)
-	c.emergencyService = services.NewEmergencyService(c.db, c.config)
+	c.emergencyNotificationService = services.NewEmergencyNotificationService(c.db, c.config, c.mqttCallService)
+	if err := c.mqttCallService.RegisterTopicHandler(services.TopicNotificationReceipt, c.emergencyNotificationService.HandleMQTTReceipt); err != nil {
+		log.Printf("注册通知回执主题失败: %v", err)
+	}
+	c.portalService = services.NewResidentPortalService(c.db, c.config, c.emergencyNotificationService, c.eventBus)
+	for _, channel := range strings.Split(c.config.EmergencyNotifyChannels, ",") {
+		channel = strings.TrimSpace(channel)
+		if channel == "" {
+			continue
+		}
+		if !c.notificationService.HasChannel(notifier.Channel(channel)) {
+			log.Printf("紧急通知渠道 %s 未配置，已跳过", channel)
+			continue
+		}
+		c.emergencyNotificationService.RegisterChannel(&services.DispatchNotificationChannel{
+			Channel:  notifier.Channel(channel),
+			Notifier: c.notificationService,
+		})
+	}
+	c.emergencyContactService = services.NewEmergencyContactService(c.db, c.config)
+	c.emergencyService = services.NewEmergencyService(c.db, c.config, c.emergencyNotificationService, c.emergencyContactService, c.notificationService, c.eventBus)
+	c.escalationService = services.NewEscalationService(c.db, c.config, c.emergencyContactService)
+	if c.notificationService.HasChannel(notifier.ChannelSMS) {
+		c.escalationService.RegisterNotifier(&services.SMSEscalationNotifier{
+			Notifier: c.notificationService,
+			Timeout:  c.config.EmergencyEscalationTimeout,
+		})
+	}
+
+	// 初始化紧急解锁服务，并订阅设备指令回执主题
+	c.emergencyUnlockService = services.NewEmergencyUnlockService(c.db, c.config, c.mqttCallService)
+	if err := c.mqttCallService.RegisterTopicHandler(services.TopicDeviceCommandAck, c.emergencyUnlockService.HandleMQTTAck); err != nil {
+		log.Printf("注册设备指令回执主题失败: %v", err)
+	}
+
+	// 初始化应急演练报告服务
+	c.emergencyDrillService = services.NewEmergencyDrillService(c.db, c.config)
 
-	// 初始化楼号和户号服务
+	// 初始化物业、楼号和户号服务
 	c.buildingService = services.NewBuildingService(c.db, c.config)
-	c.householdService = services.NewHouseholdService(c.db, c.config)
+	c.propertyService = services.NewPropertyService(c.db, c.config)
+	c.householdService = services.NewHouseholdService(c.db, c.config, c.eventBus)
+
+	// 初始化楼号、户号和居民的批量导入服务
+	c.importService = services.NewImportService(c.db, c.config, database.NewConnectionPoolWithDB(c.db), c.eventBus)
+
+	// 初始化设备事件服务，并订阅设备事件主题
+	c.deviceEventService = services.NewDeviceEventService(c.db, c.config, c.emergencyService, c.eventBus)
+	if err := c.mqttCallService.RegisterTopicHandler(services.TopicDeviceEvent, c.deviceEventService.HandleMQTTEvent); err != nil {
+		log.Printf("注册设备事件主题失败: %v", err)
+	}
 }
 
 // GetService 获取指定名称的服务
@@ -120,12 +241,32 @@ func (c *ServiceContainer) GetService(name string) interface{} {
 		return c.config
 	case "db":
 		return c.db
+	case "event_bus":
+		return c.eventBus
 	case "jwt":
 		return c.jwtService
+	case "device_signature":
+		return c.deviceSignature
+	case "login_guard":
+		return c.loginGuard
+	case "password":
+		return c.passwordService
+	case "two_factor":
+		return c.twoFactorService
+	case "api_key":
+		return c.apiKeyService
+	case "audit":
+		return c.auditService
+	case "sms_login":
+		return c.smsLoginService
 	case "rtc":
 		return c.rtcService
 	case "tencent_rtc":
 		return c.tencentRTCService
+	case "notification":
+		return c.notificationService
+	case "webhook":
+		return c.webhookService
 	case "mqtt_call":
 		return c.mqttCallService
 	case "redis":
@@ -136,16 +277,38 @@ func (c *ServiceContainer) GetService(name string) interface{} {
 		return c.adminService
 	case "resident":
 		return c.residentService
+	case "resident_portal":
+		return c.portalService
 	case "staff":
 		return c.staffService
+	case "staff_portal":
+		return c.staffPortal
 	case "call_record":
 		return c.callRecordService
 	case "emergency":
 		return c.emergencyService
 	case "building":
 		return c.buildingService
+	case "property":
+		return c.propertyService
+	case "rbac":
+		return c.rbacService
 	case "household":
 		return c.householdService
+	case "import":
+		return c.importService
+	case "device_event":
+		return c.deviceEventService
+	case "emergency_notification":
+		return c.emergencyNotificationService
+	case "emergency_contact":
+		return c.emergencyContactService
+	case "escalation":
+		return c.escalationService
+	case "emergency_unlock":
+		return c.emergencyUnlockService
+	case "emergency_drill":
+		return c.emergencyDrillService
 	default:
 		return nil
 	}
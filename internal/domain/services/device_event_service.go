@@ -0,0 +1,208 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"ilock-http-service/internal/domain/events"
+	"ilock-http-service/internal/domain/models"
+	"ilock-http-service/internal/infrastructure/config"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// InterfaceDeviceEventService 定义设备事件服务接口
+type InterfaceDeviceEventService interface {
+	ReportEvent(event *models.DeviceEvent) error
+	GetDoorHistory(query DeviceEventQuery) ([]models.DeviceEvent, int64, error)
+	HandleMQTTEvent(payload []byte)
+}
+
+// DeviceEventMessage 设备通过MQTT上报的事件消息
+type DeviceEventMessage struct {
+	DeviceID   uint   `json:"device_id"`
+	EventType  string `json:"event_type"`
+	Timestamp  int64  `json:"timestamp"` // Unix毫秒时间戳，为0时使用服务器时间
+	Details    string `json:"details,omitempty"`
+	ResidentID uint   `json:"resident_id,omitempty"` // 开门事件对应的居民ID
+	Method     string `json:"method,omitempty"`      // 开门方式: remote, code, face, fingerprint
+}
+
+// DeviceEventQuery 门禁历史查询条件
+type DeviceEventQuery struct {
+	DeviceID  uint
+	EventType string
+	StartTime *time.Time
+	EndTime   *time.Time
+	Page      int
+	PageSize  int
+}
+
+// DeviceEventService 提供设备事件上报与查询服务
+type DeviceEventService struct {
+	DB               *gorm.DB
+	Config           *config.Config
+	EmergencyService InterfaceEmergencyService
+	Events           *events.Bus
+}
+
+// NewDeviceEventService 创建一个新的设备事件服务
+func NewDeviceEventService(db *gorm.DB, cfg *config.Config, emergencyService InterfaceEmergencyService, bus *events.Bus) InterfaceDeviceEventService {
+	return &DeviceEventService{
+		DB:               db,
+		Config:           cfg,
+		EmergencyService: emergencyService,
+		Events:           bus,
+	}
+}
+
+// 1 ReportEvent 记录设备事件，强行开门和防拆事件会自动触发紧急警报，开门结果事件同时写入门禁记录
+func (s *DeviceEventService) ReportEvent(event *models.DeviceEvent) error {
+	if !event.EventType.IsValid() {
+		return fmt.Errorf("不支持的事件类型: %s", event.EventType)
+	}
+
+	var device models.Device
+	if err := s.DB.Preload("Building").First(&device, event.DeviceID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("设备不存在")
+		}
+		return err
+	}
+
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	if event.EventType.RaisesAlarm() {
+		alarm := &models.EmergencyAlarm{
+			Type:        string(event.EventType),
+			Location:    deviceLocation(&device),
+			Description: fmt.Sprintf("设备 %s(%s) 上报事件: %s", device.Name, device.SerialNumber, event.EventType),
+			ReportedBy:  0, // 系统自动报警
+		}
+		if device.Building != nil {
+			alarm.PropertyID = device.Building.PropertyID
+		}
+		if event.Details != "" {
+			alarm.Description += "，" + event.Details
+		}
+
+		if err := s.EmergencyService.TriggerAlarm(alarm, AlarmOperator{}); err != nil {
+			// 报警失败不影响事件入库，事件本身仍需保留用于追溯
+			log.Printf("[DeviceEvent] 设备 %d 自动触发警报失败: %v", device.ID, err)
+		} else if alarm.ID > 0 {
+			event.AlarmID = &alarm.ID
+		}
+	}
+
+	if !event.EventType.IsAccess() {
+		if err := s.DB.Create(event).Error; err != nil {
+			return err
+		}
+		s.Events.Publish(events.DeviceEventReported{Event: event, Device: &device})
+		return nil
+	}
+
+	accessLog := models.AccessLog{
+		DeviceID:  event.DeviceID,
+		Result:    models.AccessResultSuccess,
+		Timestamp: event.Timestamp,
+		Method:    models.AccessMethod(event.Method),
+	}
+	if event.ResidentID != nil {
+		accessLog.ResidentID = *event.ResidentID
+	}
+	if event.EventType == models.DeviceEventAccessDenied {
+		accessLog.Result = models.AccessResultFailure
+	}
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(event).Error; err != nil {
+			return err
+		}
+		return tx.Create(&accessLog).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	s.Events.Publish(events.AccessAttempted{
+		Granted:   accessLog.Result == models.AccessResultSuccess,
+		Event:     event,
+		AccessLog: &accessLog,
+		Device:    &device,
+	})
+	return nil
+}
+
+// 2 GetDoorHistory 查询门禁事件历史，按事件时间倒序
+func (s *DeviceEventService) GetDoorHistory(query DeviceEventQuery) ([]models.DeviceEvent, int64, error) {
+	var events []models.DeviceEvent
+	var total int64
+
+	db := s.DB.Model(&models.DeviceEvent{})
+	if query.DeviceID > 0 {
+		db = db.Where("device_id = ?", query.DeviceID)
+	}
+	if query.EventType != "" {
+		db = db.Where("event_type = ?", query.EventType)
+	}
+	if query.StartTime != nil {
+		db = db.Where("timestamp >= ?", *query.StartTime)
+	}
+	if query.EndTime != nil {
+		db = db.Where("timestamp <= ?", *query.EndTime)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (query.Page - 1) * query.PageSize
+	if err := db.Order("timestamp DESC").Limit(query.PageSize).Offset(offset).Find(&events).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return events, total, nil
+}
+
+// 3 HandleMQTTEvent 处理设备通过MQTT上报的事件
+func (s *DeviceEventService) HandleMQTTEvent(payload []byte) {
+	var msg DeviceEventMessage
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		log.Printf("[DeviceEvent] 解析设备事件消息失败: %v", err)
+		return
+	}
+
+	event := &models.DeviceEvent{
+		DeviceID:  msg.DeviceID,
+		EventType: models.DeviceEventType(msg.EventType),
+		Source:    "mqtt",
+		Details:   msg.Details,
+		Method:    msg.Method,
+	}
+	if msg.ResidentID > 0 {
+		event.ResidentID = &msg.ResidentID
+	}
+	if msg.Timestamp > 0 {
+		event.Timestamp = time.UnixMilli(msg.Timestamp)
+	}
+
+	if err := s.ReportEvent(event); err != nil {
+		log.Printf("[DeviceEvent] 处理设备 %d 的 %s 事件失败: %v", msg.DeviceID, msg.EventType, err)
+	}
+}
+
+// deviceLocation 返回设备的可读位置，设备未填写位置时使用所属楼号
+func deviceLocation(device *models.Device) string {
+	if device.Location != "" {
+		return device.Location
+	}
+	if device.Building != nil {
+		return device.Building.BuildingName
+	}
+	return device.Name
+}
@@ -1,15 +1,23 @@
 package services
 
 import (
+	"crypto/subtle"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"ilock-http-service/internal/domain/events"
 	"ilock-http-service/internal/domain/models"
 	"ilock-http-service/internal/infrastructure/config"
+	"ilock-http-service/pkg/utils"
+	"log"
+	"time"
 
 	"gorm.io/gorm"
 )
 
 // InterfaceDeviceService defines the device service interface
 type InterfaceDeviceService interface {
+	WithPropertyScope(propertyID *uint) InterfaceDeviceService
 	GetAllDevices() ([]models.Device, error)
 	GetDevicesByBuilding(buildingID uint) ([]models.Device, error)
 	GetDeviceByID(id uint) (*models.Device, error)
@@ -22,26 +30,42 @@ type InterfaceDeviceService interface {
 	UnlockDevice(id uint) error
 	GetDeviceHouseholds(deviceID uint) ([]models.Household, error)
 	GetDeviceBuilding(deviceID uint) (*models.Building, error)
+	RecordHeartbeat(id uint) (*models.Device, error)
+	RotateSecret(id uint) (string, error)
+	AuthenticateDevice(serialNumber, secret string) (*models.Device, error)
 }
 
+// ErrDeviceCredentialsInvalid 设备序列号或密钥错误，尚未签发密钥的设备同样无法认证
+var ErrDeviceCredentialsInvalid = errors.New("设备序列号或密钥错误")
+
 // DeviceService 提供设备相关的服务
 type DeviceService struct {
 	DB     *gorm.DB
 	Config *config.Config
+	Events *events.Bus
+	Scope  PropertyScope
 }
 
 // NewDeviceService 创建一个新的设备服务
-func NewDeviceService(db *gorm.DB, cfg *config.Config) InterfaceDeviceService {
+func NewDeviceService(db *gorm.DB, cfg *config.Config, bus *events.Bus) InterfaceDeviceService {
 	return &DeviceService{
 		DB:     db,
 		Config: cfg,
+		Events: bus,
 	}
 }
 
+// 0 WithPropertyScope 返回限定在指定物业内的服务
+func (s *DeviceService) WithPropertyScope(propertyID *uint) InterfaceDeviceService {
+	scoped := *s
+	scoped.Scope = PropertyScope{PropertyID: propertyID}
+	return &scoped
+}
+
 // 1 GetAllDevices 获取所有设备列表
 func (s *DeviceService) GetAllDevices() ([]models.Device, error) {
 	var devices []models.Device
-	if err := s.DB.Preload("Staff").Preload("Building").Find(&devices).Error; err != nil {
+	if err := s.DB.Scopes(s.Scope.Devices).Preload("Staff").Preload("Building").Find(&devices).Error; err != nil {
 		return nil, err
 	}
 
@@ -51,7 +75,7 @@ func (s *DeviceService) GetAllDevices() ([]models.Device, error) {
 // 1.2 GetDevicesByBuilding 根据楼号获取设备列表
 func (s *DeviceService) GetDevicesByBuilding(buildingID uint) ([]models.Device, error) {
 	var devices []models.Device
-	if err := s.DB.Where("building_id = ?", buildingID).Preload("Staff").Preload("Building").Find(&devices).Error; err != nil {
+	if err := s.DB.Scopes(s.Scope.Devices).Where("building_id = ?", buildingID).Preload("Staff").Preload("Building").Find(&devices).Error; err != nil {
 		return nil, err
 	}
 
@@ -61,7 +85,8 @@ func (s *DeviceService) GetDevicesByBuilding(buildingID uint) ([]models.Device,
 // 2 GetDeviceByID 根据ID获取设备
 func (s *DeviceService) GetDeviceByID(id uint) (*models.Device, error) {
 	var device models.Device
-	if err := s.DB.Preload("Staff").
+	if err := s.DB.Scopes(s.Scope.Devices).
+		Preload("Staff").
 		Preload("Building").
 		Preload("Household").
 		First(&device, id).Error; err != nil {
@@ -76,6 +101,16 @@ func (s *DeviceService) GetDeviceByID(id uint) (*models.Device, error) {
 
 // 3 CreateDevice 创建新设备
 func (s *DeviceService) CreateDevice(device *models.Device) error {
+	// 限定物业时设备必须安装在当前物业的楼号
+	if s.Scope.Scoped() {
+		if device.BuildingID == 0 {
+			return errors.New("必须指定设备所属楼号")
+		}
+		if err := s.Scope.checkBuilding(s.DB, device.BuildingID); err != nil {
+			return err
+		}
+	}
+
 	// 验证序列号唯一性
 	var count int64
 	if err := s.DB.Model(&models.Device{}).Where("serial_number = ?", device.SerialNumber).Count(&count).Error; err != nil {
@@ -90,7 +125,17 @@ func (s *DeviceService) CreateDevice(device *models.Device) error {
 		device.Status = models.DeviceStatusOffline
 	}
 
-	return s.DB.Create(device).Error
+	// 签发设备密钥，明文通过 device.Secret 返回给调用方
+	if err := issueDeviceSecret(device, s.Config); err != nil {
+		return err
+	}
+
+	if err := s.DB.Create(device).Error; err != nil {
+		return err
+	}
+
+	s.Events.Publish(events.DeviceCreated{Device: device})
+	return nil
 }
 
 // 4 UpdateDevice 更新设备信息
@@ -111,12 +156,30 @@ func (s *DeviceService) UpdateDevice(id uint, updates map[string]interface{}) (*
 		}
 	}
 
+	// 不能把设备移到其他物业的楼号
+	if buildingID, ok := updates["building_id"].(uint); ok {
+		if err := s.Scope.checkBuilding(s.DB, buildingID); err != nil {
+			return nil, err
+		}
+	}
+
+	previousStatus := device.Status
 	if err := s.DB.Model(device).Updates(updates).Error; err != nil {
 		return nil, err
 	}
 
 	// 重新获取更新后的设备信息
-	return s.GetDeviceByID(id)
+	updated, err := s.GetDeviceByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.Events.Publish(events.DeviceUpdated{Device: updated, Changes: updates})
+	if updated.Status != previousStatus {
+		s.Events.Publish(events.DeviceStatusChanged{Device: updated, Previous: previousStatus, Current: updated.Status})
+	}
+
+	return updated, nil
 }
 
 // 5 DeleteDevice 删除设备
@@ -128,7 +191,12 @@ func (s *DeviceService) DeleteDevice(id uint) error {
 
 	// 不再需要删除多对多关系表中的记录
 	// 直接删除设备即可
-	return s.DB.Delete(device).Error
+	if err := s.DB.Delete(device).Error; err != nil {
+		return err
+	}
+
+	s.Events.Publish(events.DeviceDeleted{DeviceID: id})
+	return nil
 }
 
 // 6 GetDeviceStatus 获取设备状态 (TODO: 硬件集成)
@@ -218,3 +286,116 @@ func (s *DeviceService) GetDeviceBuilding(deviceID uint) (*models.Building, erro
 
 	return &building, nil
 }
+
+// 12 RecordHeartbeat 记录设备健康检测上报，将设备标记为在线
+func (s *DeviceService) RecordHeartbeat(id uint) (*models.Device, error) {
+	device, err := s.UpdateDevice(id, map[string]interface{}{
+		"status": models.DeviceStatusOnline,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.Events.Publish(events.DeviceHeartbeat{DeviceID: id, At: time.Now()})
+	return device, nil
+}
+
+// 13 RotateSecret 重新签发设备密钥并返回新密钥，旧密钥立即失效
+func (s *DeviceService) RotateSecret(id uint) (string, error) {
+	device, err := s.GetDeviceByID(id)
+	if err != nil {
+		return "", err
+	}
+
+	if err := issueDeviceSecret(device, s.Config); err != nil {
+		return "", err
+	}
+	if err := s.DB.Model(device).Updates(map[string]interface{}{
+		"secret_hash":      device.SecretHash,
+		"secret_issued_at": device.SecretIssuedAt,
+		"signing_key":      device.SigningKey,
+	}).Error; err != nil {
+		return "", err
+	}
+	return device.Secret, nil
+}
+
+// 14 AuthenticateDevice 校验设备序列号和密钥，设备请求不属于任何物业，不受物业范围限制
+func (s *DeviceService) AuthenticateDevice(serialNumber, secret string) (*models.Device, error) {
+	if serialNumber == "" || secret == "" {
+		return nil, ErrDeviceCredentialsInvalid
+	}
+
+	var device models.Device
+	if err := s.DB.Where("serial_number = ?", serialNumber).First(&device).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDeviceCredentialsInvalid
+		}
+		return nil, err
+	}
+
+	if device.SecretHash == "" || subtle.ConstantTimeCompare([]byte(device.SecretHash), []byte(hashToken(secret))) != 1 {
+		return nil, ErrDeviceCredentialsInvalid
+	}
+
+	// 升级前签发的密钥没有签名密钥，设备认证时用密钥明文补上
+	if device.SigningKey == "" {
+		signingKey, err := sealDeviceSigningKey(secret, s.Config)
+		if err == nil {
+			err = s.DB.Model(&device).Update("signing_key", signingKey).Error
+		}
+		if err != nil {
+			log.Printf("[Device] 保存设备 %d 的签名密钥失败: %v", device.ID, err)
+		}
+	}
+	return &device, nil
+}
+
+// issueDeviceSecret 生成设备密钥，数据库只保存哈希和加密后的签名密钥
+func issueDeviceSecret(device *models.Device, cfg *config.Config) error {
+	secret, err := utils.RandomHex(32)
+	if err != nil {
+		return fmt.Errorf("生成设备密钥失败: %w", err)
+	}
+	signingKey, err := sealDeviceSigningKey(secret, cfg)
+	if err != nil {
+		return fmt.Errorf("加密设备签名密钥失败: %w", err)
+	}
+
+	now := time.Now()
+	device.Secret = secret
+	device.SecretHash = hashToken(secret)
+	device.SecretIssuedAt = &now
+	device.SigningKey = signingKey
+	return nil
+}
+
+// deviceSigningKeyLabel 由设备密钥派生签名密钥时使用的标签
+const deviceSigningKeyLabel = "ilock-device-signing"
+
+// deriveDeviceSigningKey 由设备密钥派生签名请求使用的密钥：hex(HMAC-SHA256(key=设备密钥, "ilock-device-signing"))。
+// 设备用密钥明文自行计算，服务端只保存加密后的结果，读取数据库无法伪造签名
+func deriveDeviceSigningKey(secret string) (string, error) {
+	sum, err := utils.Sign([]byte(secret), []byte(deviceSigningKeyLabel))
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sum), nil
+}
+
+// sealDeviceSigningKey 派生并加密设备签名密钥
+func sealDeviceSigningKey(secret string, cfg *config.Config) (string, error) {
+	signingKey, err := deriveDeviceSigningKey(secret)
+	if err != nil {
+		return "", err
+	}
+	return utils.EncryptString(deviceSigningKeySecret(cfg), signingKey)
+}
+
+// deviceSigningKeySecret 加密设备签名密钥使用的密钥，未单独配置时使用JWT密钥
+func deviceSigningKeySecret(cfg *config.Config) string {
+	if cfg.DeviceSigningKeySecret != "" {
+		return cfg.DeviceSigningKeySecret
+	}
+	return cfg.JWTSecretKey
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"ilock-http-service/internal/domain/models"
+	"ilock-http-service/internal/infrastructure/config"
+	"ilock-http-service/pkg/utils"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// InterfaceDeviceSignatureService 定义设备签名请求校验服务接口
+type InterfaceDeviceSignatureService interface {
+	VerifyRequest(req SignedRequest) (uint, error)
+}
+
+var (
+	// ErrSignatureInvalid 签名头缺失、格式错误或签名不匹配
+	ErrSignatureInvalid = errors.New("请求签名无效")
+	// ErrSignatureExpired 签名时间戳超出允许的时钟偏差
+	ErrSignatureExpired = errors.New("请求签名已过期，请校准设备时间")
+	// ErrNonceReused 随机数在有效期内已被使用，视为重放请求
+	ErrNonceReused = errors.New("请求随机数已被使用")
+)
+
+// 签名请求随机数的Redis键前缀
+const deviceNoncePrefix = "device:nonce:"
+
+// SignedRequest 设备签名请求中参与校验的内容
+type SignedRequest struct {
+	DeviceID  string // X-Device-ID 请求头
+	Timestamp string // X-Timestamp 请求头，Unix秒
+	Nonce     string // X-Nonce 请求头，32位整数
+	Signature string // X-Signature 请求头，十六进制
+	Method    string
+	Path      string // 请求路径，包含查询参数
+	Body      []byte
+}
+
+// DeviceSignatureService 校验不使用设备令牌的设备签名请求
+type DeviceSignatureService struct {
+	DB      *gorm.DB
+	Config  *config.Config
+	Redis   InterfaceRedisService
+	maxSkew time.Duration
+}
+
+// NewDeviceSignatureService 创建一个新的设备签名请求校验服务
+func NewDeviceSignatureService(db *gorm.DB, cfg *config.Config, redisService InterfaceRedisService) InterfaceDeviceSignatureService {
+	return &DeviceSignatureService{
+		DB:      db,
+		Config:  cfg,
+		Redis:   redisService,
+		maxSkew: time.Duration(cfg.DeviceSignatureMaxSkew) * time.Second,
+	}
+}
+
+// signedRequestFields 签名请求头解析后的内容
+type signedRequestFields struct {
+	deviceID  uint
+	timestamp int32
+	nonce     int32
+	signature []byte
+}
+
+// 1 VerifyRequest 校验签名请求并返回设备ID：
+// 签名密钥由 utils.GenerateSign 以 deriveDeviceSigningKey 派生的设备签名密钥、时间戳和随机数派生，
+// 签名为该密钥对"方法\n路径\n请求体SHA-256十六进制串"的HMAC-SHA256；
+// 校验通过后随机数写入Redis，有效期内重复使用视为重放
+func (s *DeviceSignatureService) VerifyRequest(req SignedRequest) (uint, error) {
+	fields, err := s.parseSignedRequest(req, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	var device models.Device
+	if err := s.DB.Select("id, signing_key").First(&device, fields.deviceID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, ErrSignatureInvalid
+		}
+		return 0, err
+	}
+	if device.SigningKey == "" {
+		return 0, ErrSignatureInvalid
+	}
+	signingKey, err := utils.DecryptString(deviceSigningKeySecret(s.Config), device.SigningKey)
+	if err != nil {
+		// 加密密钥更换后需要设备重新认证或轮换密钥
+		log.Printf("[DeviceSignature] 解密设备 %d 的签名密钥失败: %v", fields.deviceID, err)
+		return 0, ErrSignatureInvalid
+	}
+
+	if err := s.checkSignature(req, fields, signingKey); err != nil {
+		return 0, err
+	}
+	return fields.deviceID, nil
+}
+
+// parseSignedRequest 解析签名请求头，并检查时间戳与 now 的偏差
+func (s *DeviceSignatureService) parseSignedRequest(req SignedRequest, now time.Time) (*signedRequestFields, error) {
+	deviceID, err := strconv.ParseUint(req.DeviceID, 10, 32)
+	if err != nil || deviceID == 0 {
+		return nil, ErrSignatureInvalid
+	}
+	timestamp, err := strconv.ParseInt(req.Timestamp, 10, 32)
+	if err != nil {
+		return nil, ErrSignatureInvalid
+	}
+	nonce, err := strconv.ParseInt(req.Nonce, 10, 32)
+	if err != nil {
+		return nil, ErrSignatureInvalid
+	}
+	signature, err := hex.DecodeString(req.Signature)
+	if err != nil {
+		return nil, ErrSignatureInvalid
+	}
+
+	skew := now.Sub(time.Unix(timestamp, 0))
+	if skew > s.maxSkew || skew < -s.maxSkew {
+		return nil, ErrSignatureExpired
+	}
+	return &signedRequestFields{
+		deviceID:  uint(deviceID),
+		timestamp: int32(timestamp),
+		nonce:     int32(nonce),
+		signature: signature,
+	}, nil
+}
+
+// checkSignature 用设备签名密钥校验签名，通过后记录随机数，有效期内重复使用视为重放
+func (s *DeviceSignatureService) checkSignature(req SignedRequest, fields *signedRequestFields, signingKey string) error {
+	expected, err := signDeviceRequest(signingKey, fields.timestamp, fields.nonce, req.Method, req.Path, req.Body)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(fields.signature, expected) {
+		return ErrSignatureInvalid
+	}
+
+	// 随机数只需保留到时间戳超出偏差窗口，之后的重放会因过期被拒绝
+	key := fmt.Sprintf("%s%d:%d", deviceNoncePrefix, fields.deviceID, fields.nonce)
+	fresh, err := s.Redis.SetNX(key, fields.timestamp, 2*s.maxSkew)
+	if err != nil {
+		return fmt.Errorf("记录请求随机数失败: %w", err)
+	}
+	if !fresh {
+		return ErrNonceReused
+	}
+	return nil
+}
+
+// signDeviceRequest 计算签名请求的签名
+func signDeviceRequest(signingSecret string, timestamp, nonce int32, method, path string, body []byte) ([]byte, error) {
+	key, err := utils.GenerateSign(signingSecret, timestamp, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	bodyHash := sha256.Sum256(body)
+	canonical := strings.ToUpper(method) + "\n" + path + "\n" + hex.EncodeToString(bodyHash[:])
+	return utils.Sign(key, []byte(canonical))
+}
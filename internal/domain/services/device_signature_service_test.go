@@ -0,0 +1,165 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"ilock-http-service/pkg/utils"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const testSigningKey = "device-signing-key"
+
+// signedTestRequest 构造一个用 testSigningKey 正确签名的请求
+func signedTestRequest(t *testing.T, timestamp time.Time, nonce int32, method, path string, body []byte) SignedRequest {
+	t.Helper()
+	signature, err := signDeviceRequest(testSigningKey, int32(timestamp.Unix()), nonce, method, path, body)
+	if err != nil {
+		t.Fatalf("sign request: %v", err)
+	}
+	return SignedRequest{
+		DeviceID:  "7",
+		Timestamp: strconv.FormatInt(timestamp.Unix(), 10),
+		Nonce:     strconv.Itoa(int(nonce)),
+		Signature: hex.EncodeToString(signature),
+		Method:    method,
+		Path:      path,
+		Body:      body,
+	}
+}
+
+func newTestSignatureService(redisService InterfaceRedisService) *DeviceSignatureService {
+	return &DeviceSignatureService{Redis: redisService, maxSkew: 300 * time.Second}
+}
+
+func TestSignDeviceRequestCanonicalString(t *testing.T) {
+	body := []byte(`{"device_id":7}`)
+	got, err := signDeviceRequest(testSigningKey, 1700000000, 42, "post", "/api/device/status?full=1", body)
+	if err != nil {
+		t.Fatalf("signDeviceRequest: %v", err)
+	}
+
+	key, err := utils.GenerateSign(testSigningKey, 1700000000, 42)
+	if err != nil {
+		t.Fatalf("GenerateSign: %v", err)
+	}
+	bodyHash := sha256.Sum256(body)
+	want, _ := utils.Sign(key, []byte("POST\n/api/device/status?full=1\n"+hex.EncodeToString(bodyHash[:])))
+	if !bytes.Equal(got, want) {
+		t.Fatalf("signature = %x, want %x", got, want)
+	}
+}
+
+func TestParseSignedRequest(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	service := newTestSignatureService(newFakeRedis())
+	valid := signedTestRequest(t, now, 1, "POST", "/api/device/status", nil)
+
+	tests := []struct {
+		name    string
+		modify  func(req *SignedRequest)
+		wantErr error
+	}{
+		{name: "valid", modify: func(req *SignedRequest) {}},
+		{name: "timestamp at skew limit", modify: func(req *SignedRequest) { req.Timestamp = strconv.FormatInt(now.Unix()-300, 10) }},
+		{name: "stale timestamp", modify: func(req *SignedRequest) { req.Timestamp = strconv.FormatInt(now.Unix()-301, 10) }, wantErr: ErrSignatureExpired},
+		{name: "future timestamp", modify: func(req *SignedRequest) { req.Timestamp = strconv.FormatInt(now.Unix()+301, 10) }, wantErr: ErrSignatureExpired},
+		{name: "millisecond timestamp", modify: func(req *SignedRequest) { req.Timestamp = strconv.FormatInt(now.UnixMilli(), 10) }, wantErr: ErrSignatureInvalid},
+		{name: "missing device", modify: func(req *SignedRequest) { req.DeviceID = "" }, wantErr: ErrSignatureInvalid},
+		{name: "zero device", modify: func(req *SignedRequest) { req.DeviceID = "0" }, wantErr: ErrSignatureInvalid},
+		{name: "nonce out of range", modify: func(req *SignedRequest) { req.Nonce = "4294967296" }, wantErr: ErrSignatureInvalid},
+		{name: "signature not hex", modify: func(req *SignedRequest) { req.Signature = "not-hex" }, wantErr: ErrSignatureInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid
+			tt.modify(&req)
+			fields, err := service.parseSignedRequest(req, now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && fields.deviceID != 7 {
+				t.Fatalf("deviceID = %d, want 7", fields.deviceID)
+			}
+		})
+	}
+}
+
+func TestCheckSignature(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{"device_id":7,"status":"online"}`)
+
+	tests := []struct {
+		name    string
+		modify  func(req *SignedRequest)
+		replay  bool
+		wantErr error
+	}{
+		{name: "valid", modify: func(req *SignedRequest) {}},
+		{name: "lowercase method", modify: func(req *SignedRequest) { req.Method = "post" }},
+		{name: "replayed nonce", modify: func(req *SignedRequest) {}, replay: true, wantErr: ErrNonceReused},
+		{name: "tampered body", modify: func(req *SignedRequest) { req.Body = []byte(`{"device_id":8,"status":"online"}`) }, wantErr: ErrSignatureInvalid},
+		{name: "tampered path", modify: func(req *SignedRequest) { req.Path = "/api/device/status?debug=1" }, wantErr: ErrSignatureInvalid},
+		{name: "tampered method", modify: func(req *SignedRequest) { req.Method = "PUT" }, wantErr: ErrSignatureInvalid},
+		{name: "other device key", modify: func(req *SignedRequest) {
+			signature, _ := signDeviceRequest("other-key", int32(now.Unix()), 99, req.Method, req.Path, req.Body)
+			req.Signature = hex.EncodeToString(signature)
+		}, wantErr: ErrSignatureInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newTestSignatureService(newFakeRedis())
+			req := signedTestRequest(t, now, 99, "POST", "/api/device/status", body)
+			tt.modify(&req)
+			fields, err := service.parseSignedRequest(req, now)
+			if err != nil {
+				t.Fatalf("parseSignedRequest: %v", err)
+			}
+
+			err = service.checkSignature(req, fields, testSigningKey)
+			if tt.replay {
+				if err != nil {
+					t.Fatalf("first request: %v", err)
+				}
+				err = service.checkSignature(req, fields, testSigningKey)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckSignatureNonceIsPerDevice(t *testing.T) {
+	now := time.Now()
+	service := newTestSignatureService(newFakeRedis())
+	req := signedTestRequest(t, now, 5, "GET", "/api/device/config", nil)
+	fields, _ := service.parseSignedRequest(req, now)
+	if err := service.checkSignature(req, fields, testSigningKey); err != nil {
+		t.Fatalf("device 7: %v", err)
+	}
+
+	other := req
+	other.DeviceID = "8"
+	otherFields, _ := service.parseSignedRequest(other, now)
+	if err := service.checkSignature(other, otherFields, testSigningKey); err != nil {
+		t.Fatalf("same nonce from device 8 should be accepted: %v", err)
+	}
+}
+
+func TestCheckSignatureRedisUnavailable(t *testing.T) {
+	now := time.Now()
+	redisService := newFakeRedis()
+	redisService.err = errors.New("connection refused")
+	service := newTestSignatureService(redisService)
+	req := signedTestRequest(t, now, 1, "POST", "/api/device/status", nil)
+	fields, _ := service.parseSignedRequest(req, now)
+
+	err := service.checkSignature(req, fields, testSigningKey)
+	if err == nil || errors.Is(err, ErrSignatureInvalid) || errors.Is(err, ErrNonceReused) {
+		t.Fatalf("err = %v, want a Redis error so the request is rejected with 500", err)
+	}
+}
@@ -0,0 +1,286 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"ilock-http-service/internal/domain/models"
+	"ilock-http-service/internal/infrastructure/config"
+	"ilock-http-service/pkg/utils"
+
+	"gorm.io/gorm"
+)
+
+// InterfaceEmergencyContactService 定义紧急联系人服务接口
+type InterfaceEmergencyContactService interface {
+	WithPropertyScope(propertyID *uint) InterfaceEmergencyContactService
+	GetContacts(query ContactQuery) ([]EmergencyContactView, error)
+	GetContactByID(id uint) (*models.EmergencyContact, error)
+	CreateContact(contact *models.EmergencyContact) error
+	UpdateContact(id uint, updates map[string]interface{}) (*models.EmergencyContact, error)
+	DeleteContact(id uint) error
+	ReorderContacts(propertyID *uint, contactIDs []uint) ([]models.EmergencyContact, error)
+	ResolveContacts(propertyID *uint) ([]models.EmergencyContact, error)
+}
+
+var (
+	// ErrContactNotFound 紧急联系人不存在
+	ErrContactNotFound = errors.New("紧急联系人不存在")
+	// ErrInvalidPhoneNumber 电话号码格式不正确
+	ErrInvalidPhoneNumber = errors.New("电话号码格式不正确")
+	// ErrContactScope 联系人不属于指定的物业范围
+	ErrContactScope = errors.New("存在不属于该范围的联系人")
+	// ErrBuildingNotFound 楼号不存在
+	ErrBuildingNotFound = errors.New("楼号不存在")
+)
+
+// 联系人作用范围
+const (
+	ContactScopeProperty = "property" // 物业专属联系人
+	ContactScopeGlobal   = "global"   // 全局联系人
+)
+
+// ContactQuery 紧急联系人列表查询条件
+type ContactQuery struct {
+	PropertyID *uint // 只返回该物业及全局联系人
+	BuildingID *uint // 标记哪些联系人适用于该楼号
+}
+
+// EmergencyContactView 紧急联系人列表项，附带作用范围和适用性
+type EmergencyContactView struct {
+	models.EmergencyContact
+	Scope   string `json:"scope"`             // property, global
+	Applies *bool  `json:"applies,omitempty"` // 按楼号查询时，是否适用于该楼号
+}
+
+// EmergencyContactService 提供紧急联系人管理与按物业解析服务
+type EmergencyContactService struct {
+	DB     *gorm.DB
+	Config *config.Config
+	Scope  PropertyScope
+}
+
+// NewEmergencyContactService 创建新的紧急联系人服务
+func NewEmergencyContactService(db *gorm.DB, cfg *config.Config) InterfaceEmergencyContactService {
+	return &EmergencyContactService{
+		DB:     db,
+		Config: cfg,
+	}
+}
+
+// WithPropertyScope 返回限定在指定物业内的紧急联系人服务，nil表示不限物业
+// 限定物业时可以查看本物业和全局联系人，但只能管理本物业的联系人
+func (s *EmergencyContactService) WithPropertyScope(propertyID *uint) InterfaceEmergencyContactService {
+	scoped := *s
+	scoped.Scope = PropertyScope{PropertyID: propertyID}
+	return &scoped
+}
+
+// 1 GetContacts 获取紧急联系人列表，按优先级排序
+func (s *EmergencyContactService) GetContacts(query ContactQuery) ([]EmergencyContactView, error) {
+	// 限定物业时只能查询本物业，未指定物业时默认为本物业
+	propertyID, err := s.Scope.ownProperty(query.PropertyID)
+	if err != nil {
+		return nil, err
+	}
+	query.PropertyID = propertyID
+	if query.BuildingID != nil {
+		if err := s.Scope.checkBuilding(s.DB, *query.BuildingID); err != nil {
+			return nil, err
+		}
+	}
+
+	// 按楼号查询时，以楼号所属物业判断适用性
+	var buildingPropertyID *uint
+	if query.BuildingID != nil {
+		var building models.Building
+		if err := s.DB.First(&building, *query.BuildingID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrBuildingNotFound
+			}
+			return nil, err
+		}
+		buildingPropertyID = building.PropertyID
+	}
+
+	var contacts []models.EmergencyContact
+	db := s.DB.Model(&models.EmergencyContact{})
+	if query.PropertyID != nil {
+		db = db.Where("property_id = ? OR property_id IS NULL", *query.PropertyID)
+	}
+	if err := db.Order("priority DESC, id ASC").Find(&contacts).Error; err != nil {
+		return nil, err
+	}
+
+	views := make([]EmergencyContactView, 0, len(contacts))
+	for _, contact := range contacts {
+		view := EmergencyContactView{
+			EmergencyContact: contact,
+			Scope:            contactScope(&contact),
+		}
+		if query.BuildingID != nil {
+			applies := contactApplies(&contact, buildingPropertyID)
+			view.Applies = &applies
+		}
+		views = append(views, view)
+	}
+
+	return views, nil
+}
+
+// 2 GetContactByID 根据ID获取紧急联系人
+func (s *EmergencyContactService) GetContactByID(id uint) (*models.EmergencyContact, error) {
+	var contact models.EmergencyContact
+	if err := s.DB.Scopes(s.Scope.Owned).First(&contact, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrContactNotFound
+		}
+		return nil, err
+	}
+	return &contact, nil
+}
+
+// 3 CreateContact 创建紧急联系人
+func (s *EmergencyContactService) CreateContact(contact *models.EmergencyContact) error {
+	if !utils.IsValidPhoneNumber(contact.PhoneNumber) {
+		return ErrInvalidPhoneNumber
+	}
+	contact.PhoneNumber = utils.NormalizePhoneNumber(contact.PhoneNumber)
+
+	propertyID, err := s.Scope.ownProperty(contact.PropertyID)
+	if err != nil {
+		return err
+	}
+	contact.PropertyID = propertyID
+
+	return s.DB.Create(contact).Error
+}
+
+// 4 UpdateContact 更新紧急联系人信息
+func (s *EmergencyContactService) UpdateContact(id uint, updates map[string]interface{}) (*models.EmergencyContact, error) {
+	contact, err := s.GetContactByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if phone, ok := updates["phone_number"].(string); ok {
+		if !utils.IsValidPhoneNumber(phone) {
+			return nil, ErrInvalidPhoneNumber
+		}
+		updates["phone_number"] = utils.NormalizePhoneNumber(phone)
+	}
+
+	// 物业账号不能把联系人移到其他物业或改为全局联系人
+	if s.Scope.Scoped() {
+		if err := s.Scope.checkPropertyChange(s.DB, updates); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.DB.Model(contact).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+
+	return s.GetContactByID(id)
+}
+
+// 5 DeleteContact 删除紧急联系人
+func (s *EmergencyContactService) DeleteContact(id uint) error {
+	contact, err := s.GetContactByID(id)
+	if err != nil {
+		return err
+	}
+
+	return s.DB.Delete(contact).Error
+}
+
+// 6 ReorderContacts 按给定顺序重排同一范围内的联系人，排在前面的优先级更高
+func (s *EmergencyContactService) ReorderContacts(propertyID *uint, contactIDs []uint) ([]models.EmergencyContact, error) {
+	if len(contactIDs) == 0 {
+		return nil, errors.New("联系人列表不能为空")
+	}
+
+	seen := make(map[uint]bool, len(contactIDs))
+	for _, id := range contactIDs {
+		if seen[id] {
+			return nil, fmt.Errorf("联系人ID重复: %d", id)
+		}
+		seen[id] = true
+	}
+
+	if s.Scope.Scoped() && (propertyID == nil || *propertyID != *s.Scope.PropertyID) {
+		return nil, ErrOutOfPropertyScope
+	}
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var contacts []models.EmergencyContact
+		if err := scopeByProperty(tx.Model(&models.EmergencyContact{}), propertyID).
+			Where("id IN ?", contactIDs).Find(&contacts).Error; err != nil {
+			return err
+		}
+		if len(contacts) != len(contactIDs) {
+			return ErrContactScope
+		}
+
+		for i, id := range contactIDs {
+			priority := len(contactIDs) - i
+			if err := tx.Model(&models.EmergencyContact{}).Where("id = ?", id).Update("priority", priority).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var contacts []models.EmergencyContact
+	if err := scopeByProperty(s.DB.Model(&models.EmergencyContact{}), propertyID).
+		Order("priority DESC, id ASC").Find(&contacts).Error; err != nil {
+		return nil, err
+	}
+	return contacts, nil
+}
+
+// 7 ResolveContacts 按通知顺序解析物业的紧急联系人：先物业专属联系人，再全局联系人
+func (s *EmergencyContactService) ResolveContacts(propertyID *uint) ([]models.EmergencyContact, error) {
+	var resolved []models.EmergencyContact
+
+	if propertyID != nil {
+		if err := s.DB.Where("property_id = ?", *propertyID).
+			Order("priority DESC, id ASC").Find(&resolved).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var globals []models.EmergencyContact
+	if err := s.DB.Where("property_id IS NULL").
+		Order("priority DESC, id ASC").Find(&globals).Error; err != nil {
+		return nil, err
+	}
+
+	return append(resolved, globals...), nil
+}
+
+// scopeByProperty 限定查询范围为指定物业，propertyID为空时限定为全局联系人
+func scopeByProperty(db *gorm.DB, propertyID *uint) *gorm.DB {
+	if propertyID == nil {
+		return db.Where("property_id IS NULL")
+	}
+	return db.Where("property_id = ?", *propertyID)
+}
+
+// contactScope 返回联系人的作用范围
+func contactScope(contact *models.EmergencyContact) string {
+	if contact.PropertyID == nil {
+		return ContactScopeGlobal
+	}
+	return ContactScopeProperty
+}
+
+// contactApplies 判断联系人是否适用于指定物业，全局联系人总是适用
+func contactApplies(contact *models.EmergencyContact, propertyID *uint) bool {
+	if contact.PropertyID == nil {
+		return true
+	}
+	return propertyID != nil && *contact.PropertyID == *propertyID
+}
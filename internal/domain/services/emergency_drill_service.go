@@ -0,0 +1,371 @@
+package services
+
+import (
+	"ilock-http-service/internal/domain/models"
+	"ilock-http-service/internal/infrastructure/config"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// InterfaceEmergencyDrillService 定义应急演练报告服务接口
+type InterfaceEmergencyDrillService interface {
+	GetDrillReport(query DrillReportQuery) (*DrillReport, error)
+}
+
+// DrillReportQuery 演练报告查询条件
+type DrillReportQuery struct {
+	From       time.Time
+	To         time.Time
+	PropertyID *uint
+}
+
+// DrillReport 演练报告，汇总时间范围内演练警报、求助、通知和解锁的响应情况
+type DrillReport struct {
+	From          time.Time                 `json:"from"`
+	To            time.Time                 `json:"to"`
+	PropertyID    *uint                     `json:"property_id,omitempty"`
+	Alarms        []DrillAlarmReport        `json:"alarms"`
+	Emergencies   []DrillEmergencyReport    `json:"emergencies"`
+	Notifications []DrillNotificationReport `json:"notifications"`
+	Unlocks       []DrillUnlockReport       `json:"unlocks"`
+}
+
+// DrillAlarmReport 演练警报的确认和解决耗时
+type DrillAlarmReport struct {
+	AlarmID            uint      `json:"alarm_id"`
+	Type               string    `json:"type"`
+	Location           string    `json:"location"`
+	TriggeredAt        time.Time `json:"triggered_at"`
+	AcknowledgedBy     *uint     `json:"acknowledged_by,omitempty"`
+	AcknowledgeSeconds *float64  `json:"acknowledge_seconds,omitempty"` // 触发到确认的秒数，为空表示未确认
+	AssignedTo         *uint     `json:"assigned_to,omitempty"`
+	ResolvedBy         *uint     `json:"resolved_by,omitempty"`
+	ResolveSeconds     *float64  `json:"resolve_seconds,omitempty"` // 触发到解决的秒数，为空表示未解决
+}
+
+// DrillEmergencyReport 演练求助的升级过程和响应耗时
+type DrillEmergencyReport struct {
+	EmergencyID     uint                 `json:"emergency_id"`
+	TriggeredAt     time.Time            `json:"triggered_at"`
+	EscalationLevel int                  `json:"escalation_level"`
+	RespondedBy     *uint                `json:"responded_by,omitempty"`
+	ResponseSeconds *float64             `json:"response_seconds,omitempty"` // 触发到响应的秒数，为空表示未响应
+	Contacts        []DrillContactReport `json:"contacts"`
+}
+
+// DrillContactReport 升级过程中被通知的紧急联系人
+type DrillContactReport struct {
+	ContactID     uint    `json:"contact_id"`
+	ContactName   string  `json:"contact_name"`
+	Level         int     `json:"level"`
+	Notifier      string  `json:"notifier"`
+	Success       bool    `json:"success"`
+	NotifiedAfter float64 `json:"notified_after"` // 触发后多少秒通知该联系人
+}
+
+// DrillNotificationReport 演练通知的送达和已读情况
+type DrillNotificationReport struct {
+	NotificationID  uint                   `json:"notification_id"`
+	Title           string                 `json:"title"`
+	SentAt          time.Time              `json:"sent_at"`
+	Recipients      int                    `json:"recipients"`
+	Delivered       int                    `json:"delivered"`
+	Read            int                    `json:"read"`
+	AvgReadSeconds  *float64               `json:"avg_read_seconds,omitempty"`
+	MaxReadSeconds  *float64               `json:"max_read_seconds,omitempty"`
+	RecipientDetail []DrillRecipientReport `json:"recipient_detail"`
+}
+
+// DrillRecipientReport 单个接收人的响应耗时
+type DrillRecipientReport struct {
+	RecipientType    string   `json:"recipient_type"`
+	RecipientID      uint     `json:"recipient_id"`
+	Name             string   `json:"name"`
+	DeliveredSeconds *float64 `json:"delivered_seconds,omitempty"` // 发送到外部渠道首次确认送达的秒数
+	ReadSeconds      *float64 `json:"read_seconds,omitempty"`      // 发送到已读的秒数，为空表示未读
+}
+
+// DrillUnlockReport 演练解锁会话覆盖的设备
+type DrillUnlockReport struct {
+	SessionID       uint       `json:"session_id"`
+	ScopeType       string     `json:"scope_type"`
+	Devices         int        `json:"devices"`
+	StartedAt       time.Time  `json:"started_at"`
+	EndedAt         *time.Time `json:"ended_at,omitempty"`
+	DurationSeconds *float64   `json:"duration_seconds,omitempty"`
+}
+
+// EmergencyDrillService 汇总应急演练的响应情况
+type EmergencyDrillService struct {
+	DB     *gorm.DB
+	Config *config.Config
+}
+
+// NewEmergencyDrillService 创建应急演练报告服务
+func NewEmergencyDrillService(db *gorm.DB, cfg *config.Config) InterfaceEmergencyDrillService {
+	return &EmergencyDrillService{
+		DB:     db,
+		Config: cfg,
+	}
+}
+
+// 1 GetDrillReport 生成时间范围内的演练报告，按接收人统计响应耗时
+func (s *EmergencyDrillService) GetDrillReport(query DrillReportQuery) (*DrillReport, error) {
+	report := &DrillReport{
+		From:          query.From,
+		To:            query.To,
+		PropertyID:    query.PropertyID,
+		Alarms:        []DrillAlarmReport{},
+		Emergencies:   []DrillEmergencyReport{},
+		Notifications: []DrillNotificationReport{},
+		Unlocks:       []DrillUnlockReport{},
+	}
+
+	if err := s.reportAlarms(query, report); err != nil {
+		return nil, err
+	}
+	if err := s.reportEmergencies(query, report); err != nil {
+		return nil, err
+	}
+	if err := s.reportNotifications(query, report); err != nil {
+		return nil, err
+	}
+	if err := s.reportUnlocks(query, report); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// reportAlarms 统计演练警报的确认和解决耗时
+func (s *EmergencyDrillService) reportAlarms(query DrillReportQuery, report *DrillReport) error {
+	var alarms []models.EmergencyAlarm
+	db := s.DB.Where("is_drill = ? AND timestamp BETWEEN ? AND ?", true, query.From, query.To)
+	if query.PropertyID != nil {
+		db = db.Where("property_id = ?", *query.PropertyID)
+	}
+	if err := db.Order("timestamp ASC").Find(&alarms).Error; err != nil {
+		return err
+	}
+
+	for _, alarm := range alarms {
+		report.Alarms = append(report.Alarms, DrillAlarmReport{
+			AlarmID:            alarm.ID,
+			Type:               alarm.Type,
+			Location:           alarm.Location,
+			TriggeredAt:        alarm.Timestamp,
+			AcknowledgedBy:     alarm.AcknowledgedBy,
+			AcknowledgeSeconds: elapsedSeconds(alarm.Timestamp, alarm.AcknowledgedAt),
+			AssignedTo:         alarm.AssignedTo,
+			ResolvedBy:         alarm.ResolvedBy,
+			ResolveSeconds:     elapsedSeconds(alarm.Timestamp, alarm.ResolvedAt),
+		})
+	}
+	return nil
+}
+
+// reportEmergencies 统计演练求助的升级和响应耗时
+func (s *EmergencyDrillService) reportEmergencies(query DrillReportQuery, report *DrillReport) error {
+	var emergencies []models.EmergencyLog
+	db := s.DB.Where("is_drill = ? AND triggered_at BETWEEN ? AND ?", true, query.From, query.To)
+	if query.PropertyID != nil {
+		db = db.Where("property_id = ?", *query.PropertyID)
+	}
+	if err := db.Preload("EscalationSteps", func(db *gorm.DB) *gorm.DB { return db.Order("timestamp ASC") }).
+		Order("triggered_at ASC").Find(&emergencies).Error; err != nil {
+		return err
+	}
+
+	for _, emergency := range emergencies {
+		item := DrillEmergencyReport{
+			EmergencyID:     emergency.ID,
+			TriggeredAt:     emergency.TriggeredAt,
+			EscalationLevel: emergency.EscalationLevel,
+			RespondedBy:     emergency.RespondedBy,
+			ResponseSeconds: elapsedSeconds(emergency.TriggeredAt, emergency.RespondedAt),
+			Contacts:        []DrillContactReport{},
+		}
+		for _, step := range emergency.EscalationSteps {
+			item.Contacts = append(item.Contacts, DrillContactReport{
+				ContactID:     step.ContactID,
+				ContactName:   step.ContactName,
+				Level:         step.Level,
+				Notifier:      step.Notifier,
+				Success:       step.Success,
+				NotifiedAfter: *elapsedSeconds(emergency.TriggeredAt, &step.Timestamp),
+			})
+		}
+		report.Emergencies = append(report.Emergencies, item)
+	}
+	return nil
+}
+
+// reportNotifications 按接收人统计演练通知的送达和已读耗时
+func (s *EmergencyDrillService) reportNotifications(query DrillReportQuery, report *DrillReport) error {
+	var notifications []models.EmergencyNotification
+	db := s.DB.Where("is_drill = ? AND timestamp BETWEEN ? AND ?", true, query.From, query.To)
+	if query.PropertyID != nil {
+		db = db.Where("property_id = ?", *query.PropertyID)
+	}
+	if err := db.Order("timestamp ASC").Find(&notifications).Error; err != nil {
+		return err
+	}
+
+	for _, notification := range notifications {
+		var deliveries []models.EmergencyNotificationDelivery
+		if err := s.DB.Where("notification_id = ?", notification.ID).Order("id ASC").Find(&deliveries).Error; err != nil {
+			return err
+		}
+
+		names, err := s.recipientNames(deliveries)
+		if err != nil {
+			return err
+		}
+
+		// 每个接收人汇总为一行：站内信提供已读时间，外部渠道取最早的送达时间
+		index := make(map[string]int)
+		item := DrillNotificationReport{
+			NotificationID:  notification.ID,
+			Title:           notification.Title,
+			SentAt:          notification.Timestamp,
+			RecipientDetail: []DrillRecipientReport{},
+		}
+		for _, delivery := range deliveries {
+			key := recipientKey(delivery.RecipientType, delivery.RecipientID)
+			i, ok := index[key]
+			if !ok {
+				i = len(item.RecipientDetail)
+				index[key] = i
+				item.RecipientDetail = append(item.RecipientDetail, DrillRecipientReport{
+					RecipientType: delivery.RecipientType,
+					RecipientID:   delivery.RecipientID,
+					Name:          names[key],
+				})
+			}
+			detail := &item.RecipientDetail[i]
+
+			if delivery.Channel == NotificationChannelInbox {
+				detail.ReadSeconds = elapsedSeconds(notification.Timestamp, delivery.ReadAt)
+				continue
+			}
+			if delivered := elapsedSeconds(notification.Timestamp, delivery.DeliveredAt); delivered != nil &&
+				(detail.DeliveredSeconds == nil || *delivered < *detail.DeliveredSeconds) {
+				detail.DeliveredSeconds = delivered
+			}
+		}
+
+		var readTotal float64
+		for _, detail := range item.RecipientDetail {
+			if detail.DeliveredSeconds != nil {
+				item.Delivered++
+			}
+			if detail.ReadSeconds == nil {
+				continue
+			}
+			item.Read++
+			readTotal += *detail.ReadSeconds
+			if item.MaxReadSeconds == nil || *detail.ReadSeconds > *item.MaxReadSeconds {
+				slowest := *detail.ReadSeconds
+				item.MaxReadSeconds = &slowest
+			}
+		}
+		item.Recipients = len(item.RecipientDetail)
+		if item.Read > 0 {
+			avg := roundSeconds(readTotal / float64(item.Read))
+			item.AvgReadSeconds = &avg
+		}
+
+		// 未读的排在最后，已读的按耗时从快到慢排列
+		sort.SliceStable(item.RecipientDetail, func(i, j int) bool {
+			a, b := item.RecipientDetail[i].ReadSeconds, item.RecipientDetail[j].ReadSeconds
+			if a == nil || b == nil {
+				return a != nil && b == nil
+			}
+			return *a < *b
+		})
+
+		report.Notifications = append(report.Notifications, item)
+	}
+	return nil
+}
+
+// reportUnlocks 统计演练解锁会话
+func (s *EmergencyDrillService) reportUnlocks(query DrillReportQuery, report *DrillReport) error {
+	var sessions []models.EmergencyUnlockSession
+	db := s.DB.Where("is_drill = ? AND started_at BETWEEN ? AND ?", true, query.From, query.To)
+	if query.PropertyID != nil {
+		// 早期的会话没有记录property_id，按物业范围解锁的会话从范围ID中识别
+		db = db.Where("property_id = ? OR (scope_type = ? AND scope_ids = ?)",
+			*query.PropertyID, models.UnlockScopeProperty, strconv.FormatUint(uint64(*query.PropertyID), 10))
+	}
+	if err := db.Preload("Devices").Order("started_at ASC").Find(&sessions).Error; err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		report.Unlocks = append(report.Unlocks, DrillUnlockReport{
+			SessionID:       session.ID,
+			ScopeType:       session.ScopeType,
+			Devices:         len(session.Devices),
+			StartedAt:       session.StartedAt,
+			EndedAt:         session.EndedAt,
+			DurationSeconds: elapsedSeconds(session.StartedAt, session.EndedAt),
+		})
+	}
+	return nil
+}
+
+// recipientNames 批量查询投递记录中接收人的名称
+func (s *EmergencyDrillService) recipientNames(deliveries []models.EmergencyNotificationDelivery) (map[string]string, error) {
+	var residentIDs, staffIDs []uint
+	for _, delivery := range deliveries {
+		if delivery.Channel != NotificationChannelInbox {
+			continue
+		}
+		switch delivery.RecipientType {
+		case models.RecipientTypeResident:
+			residentIDs = append(residentIDs, delivery.RecipientID)
+		case models.RecipientTypeStaff:
+			staffIDs = append(staffIDs, delivery.RecipientID)
+		}
+	}
+
+	names := make(map[string]string, len(residentIDs)+len(staffIDs))
+	if len(residentIDs) > 0 {
+		var residents []models.Resident
+		if err := s.DB.Select("id", "name").Where("id IN ?", residentIDs).Find(&residents).Error; err != nil {
+			return nil, err
+		}
+		for _, resident := range residents {
+			names[recipientKey(models.RecipientTypeResident, resident.ID)] = resident.Name
+		}
+	}
+	if len(staffIDs) > 0 {
+		var staffs []models.PropertyStaff
+		if err := s.DB.Select("id", "username").Where("id IN ?", staffIDs).Find(&staffs).Error; err != nil {
+			return nil, err
+		}
+		for _, staff := range staffs {
+			names[recipientKey(models.RecipientTypeStaff, staff.ID)] = staff.Username
+		}
+	}
+	return names, nil
+}
+
+// elapsedSeconds 计算两个时间点之间的秒数，结束时间为空时返回nil
+func elapsedSeconds(start time.Time, end *time.Time) *float64 {
+	if end == nil {
+		return nil
+	}
+	seconds := roundSeconds(end.Sub(start).Seconds())
+	return &seconds
+}
+
+// roundSeconds 秒数保留一位小数
+func roundSeconds(seconds float64) float64 {
+	return math.Round(seconds*10) / 10
+}
@@ -0,0 +1,253 @@
+package services
+
+import (
+	"fmt"
+	"ilock-http-service/internal/domain/models"
+	"ilock-http-service/internal/infrastructure/config"
+	"ilock-http-service/internal/infrastructure/notifier"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// InterfaceEscalationService 定义紧急事件升级服务接口
+type InterfaceEscalationService interface {
+	RegisterNotifier(notifier EscalationNotifier)
+	EscalateOverdue() (int, error)
+	Stop()
+}
+
+// EscalationNotifier 紧急联系人通知渠道，可按需注册多个实现
+type EscalationNotifier interface {
+	Name() string
+	NotifyContact(contact models.EmergencyContact, emergency *models.EmergencyLog, level int) error
+}
+
+// LogEscalationNotifier 仅写入日志的通知渠道，作为未接入外部通知时的默认实现
+type LogEscalationNotifier struct{}
+
+// Name 返回通知渠道名称
+func (n *LogEscalationNotifier) Name() string {
+	return "log"
+}
+
+// NotifyContact 将升级通知写入日志
+func (n *LogEscalationNotifier) NotifyContact(contact models.EmergencyContact, emergency *models.EmergencyLog, level int) error {
+	drill := ""
+	if emergency.IsDrill {
+		drill = "[演练]"
+	}
+	log.Printf("[Escalation] %s第%d轮升级: 通知紧急联系人 %s(%s, %s)，紧急事件ID=%d",
+		drill, level, contact.Name, contact.Role, contact.PhoneNumber, emergency.ID)
+	return nil
+}
+
+// SMSEscalationNotifier 通过统一通知服务给紧急联系人发送短信
+type SMSEscalationNotifier struct {
+	Notifier InterfaceNotificationService
+	Timeout  int // 升级超时秒数，用于短信内容
+}
+
+// Name 返回通知渠道名称
+func (n *SMSEscalationNotifier) Name() string {
+	return string(notifier.ChannelSMS)
+}
+
+// NotifyContact 发送升级短信
+func (n *SMSEscalationNotifier) NotifyContact(contact models.EmergencyContact, emergency *models.EmergencyLog, level int) error {
+	return n.Notifier.Send(notifier.Message{
+		Channel:  notifier.ChannelSMS,
+		To:       contact.PhoneNumber,
+		Template: "emergency_escalation",
+		Data: map[string]interface{}{
+			"name":         contact.Name,
+			"level":        level,
+			"emergency_id": emergency.ID,
+			"description":  emergency.Description,
+			"timeout":      n.Timeout,
+			"is_drill":     emergency.IsDrill,
+		},
+	})
+}
+
+// EscalationService 定时检查未响应的紧急事件，并按优先级逐轮通知紧急联系人
+type EscalationService struct {
+	DB             *gorm.DB
+	Config         *config.Config
+	ContactService InterfaceEmergencyContactService
+	notifiers      []EscalationNotifier
+	mu             sync.RWMutex
+	stopChan       chan struct{}
+	stopOnce       sync.Once
+}
+
+// NewEscalationService 创建紧急事件升级服务并启动定时检查任务
+func NewEscalationService(db *gorm.DB, cfg *config.Config, contactService InterfaceEmergencyContactService) InterfaceEscalationService {
+	service := &EscalationService{
+		DB:             db,
+		Config:         cfg,
+		ContactService: contactService,
+		notifiers:      []EscalationNotifier{&LogEscalationNotifier{}},
+		stopChan:       make(chan struct{}),
+	}
+
+	// 启动升级检查定时任务
+	go service.startEscalationTask()
+
+	return service
+}
+
+// 1 RegisterNotifier 注册通知渠道
+func (s *EscalationService) RegisterNotifier(notifier EscalationNotifier) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notifiers = append(s.notifiers, notifier)
+}
+
+// 2 EscalateOverdue 升级所有超时未响应的紧急事件，返回本次升级的事件数
+func (s *EscalationService) EscalateOverdue() (int, error) {
+	timeout := time.Duration(s.Config.EmergencyEscalationTimeout) * time.Second
+	deadline := time.Now().Add(-timeout)
+
+	// 尚未升级的按触发时间判断，已升级的按上次升级时间判断
+	var overdue []models.EmergencyLog
+	if err := s.DB.Where("status IN ? AND responded_at IS NULL", []models.EmergencyStatus{models.EmergencyStatusPending, models.EmergencyStatusEscalated}).
+		Where("(last_escalated_at IS NULL AND triggered_at <= ?) OR last_escalated_at <= ?", deadline, deadline).
+		Find(&overdue).Error; err != nil {
+		return 0, err
+	}
+
+	escalated := 0
+	for i := range overdue {
+		if err := s.escalate(&overdue[i]); err != nil {
+			log.Printf("[Escalation] 升级紧急事件 %d 失败: %v", overdue[i].ID, err)
+			continue
+		}
+		escalated++
+	}
+
+	return escalated, nil
+}
+
+// 3 Stop 停止升级检查任务
+func (s *EscalationService) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stopChan)
+	})
+}
+
+// escalate 将紧急事件升级一轮，并通知下一批联系人
+func (s *EscalationService) escalate(emergency *models.EmergencyLog) error {
+	now := time.Now()
+	level := emergency.EscalationLevel + 1
+
+	// 条件更新，避免与响应操作并发时覆盖已响应的状态
+	result := s.DB.Model(&models.EmergencyLog{}).
+		Where("id = ? AND escalation_level = ? AND responded_at IS NULL", emergency.ID, emergency.EscalationLevel).
+		Updates(map[string]interface{}{
+			"status":            models.EmergencyStatusEscalated,
+			"escalation_level":  level,
+			"last_escalated_at": now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		// 已被响应或已由其他实例升级
+		return nil
+	}
+
+	emergency.Status = models.EmergencyStatusEscalated
+	emergency.EscalationLevel = level
+	emergency.LastEscalatedAt = &now
+
+	contacts, err := s.nextContacts(emergency, level)
+	if err != nil {
+		return err
+	}
+	if len(contacts) == 0 {
+		log.Printf("[Escalation] 紧急事件 %d 没有可通知的紧急联系人", emergency.ID)
+		return nil
+	}
+
+	s.mu.RLock()
+	notifiers := append([]EscalationNotifier(nil), s.notifiers...)
+	s.mu.RUnlock()
+
+	for _, contact := range contacts {
+		for _, notifier := range notifiers {
+			step := models.EmergencyEscalationStep{
+				EmergencyLogID: emergency.ID,
+				Level:          level,
+				ContactID:      contact.ID,
+				ContactName:    contact.Name,
+				PhoneNumber:    contact.PhoneNumber,
+				Notifier:       notifier.Name(),
+				Success:        true,
+				Timestamp:      time.Now(),
+			}
+			if err := notifier.NotifyContact(contact, emergency, level); err != nil {
+				step.Success = false
+				step.Error = err.Error()
+			}
+			if err := s.DB.Create(&step).Error; err != nil {
+				log.Printf("[Escalation] 保存升级记录失败: %v", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// nextContacts 按解析顺序选出本轮需要通知的联系人，物业联系人优先，全局联系人兜底，轮询完毕后从头开始
+func (s *EscalationService) nextContacts(emergency *models.EmergencyLog, level int) ([]models.EmergencyContact, error) {
+	contacts, err := s.ContactService.ResolveContacts(emergency.PropertyID)
+	if err != nil {
+		return nil, fmt.Errorf("获取紧急联系人失败: %w", err)
+	}
+	if len(contacts) == 0 {
+		return nil, nil
+	}
+
+	batchSize := s.Config.EmergencyEscalationBatchSize
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	if batchSize > len(contacts) {
+		batchSize = len(contacts)
+	}
+
+	start := ((level - 1) * batchSize) % len(contacts)
+	selected := make([]models.EmergencyContact, 0, batchSize)
+	for i := 0; i < batchSize; i++ {
+		selected = append(selected, contacts[(start+i)%len(contacts)])
+	}
+
+	return selected, nil
+}
+
+// startEscalationTask 启动升级检查定时任务
+func (s *EscalationService) startEscalationTask() {
+	interval := time.Duration(s.Config.EmergencyEscalationCheckInterval) * time.Second
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if count, err := s.EscalateOverdue(); err != nil {
+				log.Printf("[Escalation] 检查超时紧急事件失败: %v", err)
+			} else if count > 0 {
+				log.Printf("[Escalation] 已升级 %d 个超时未响应的紧急事件", count)
+			}
+		case <-s.stopChan:
+			return
+		}
+	}
+}
@@ -0,0 +1,561 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"ilock-http-service/internal/domain/models"
+	"ilock-http-service/internal/infrastructure/config"
+	"ilock-http-service/internal/infrastructure/notifier"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// InterfaceEmergencyNotificationService 定义紧急通知投递服务接口
+type InterfaceEmergencyNotificationService interface {
+	WithPropertyScope(propertyID *uint) InterfaceEmergencyNotificationService
+	RegisterChannel(channel NotificationChannel)
+	Dispatch(notification *models.EmergencyNotification) (int, error)
+	MarkDelivered(notificationID uint, recipientType string, recipientID uint, channel string) error
+	MarkRead(notificationID uint, recipientType string, recipientID uint) error
+	GetDeliveryProgress(notificationID uint) (*NotificationProgress, error)
+	GetDeliveries(query DeliveryQuery) ([]models.EmergencyNotificationDelivery, int64, error)
+	HandleMQTTReceipt(payload []byte)
+}
+
+var (
+	// ErrNotificationNotFound 紧急通知不存在
+	ErrNotificationNotFound = errors.New("紧急通知不存在")
+	// ErrDeliveryNotFound 接收人没有该通知的投递记录
+	ErrDeliveryNotFound = errors.New("接收人未收到该通知")
+	// ErrInvalidTargetType 通知目标类型不受支持
+	ErrInvalidTargetType = errors.New("不支持的通知目标类型")
+)
+
+// 通知目标类型
+const (
+	NotificationTargetAll       = "all"       // 所有人
+	NotificationTargetResidents = "residents" // 居民
+	NotificationTargetStaff     = "staff"     // 物业人员
+)
+
+// 内置投递渠道
+const (
+	NotificationChannelInbox = "inbox" // 站内信，投递记录本身即收件箱
+	NotificationChannelMQTT  = "mqtt"  // MQTT推送到接收人终端
+)
+
+// NotificationRecipient 紧急通知接收人
+type NotificationRecipient struct {
+	Type  string `json:"type"` // resident, staff
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Phone string `json:"phone"`
+	Email string `json:"email"`
+}
+
+// NotificationChannel 紧急通知投递渠道，短信、邮件、推送等渠道通过RegisterChannel接入
+type NotificationChannel interface {
+	Name() string
+	Accepts(recipient NotificationRecipient) bool
+	Send(notification *models.EmergencyNotification, recipient NotificationRecipient) error
+}
+
+// NotificationMessage 通过MQTT推送给接收人的紧急通知消息
+type NotificationMessage struct {
+	NotificationID uint   `json:"notification_id"`
+	Title          string `json:"title"`
+	Content        string `json:"content"`
+	Severity       string `json:"severity"`
+	Timestamp      int64  `json:"timestamp"`  // Unix毫秒时间戳
+	ExpiresAt      int64  `json:"expires_at"` // Unix毫秒时间戳
+	IsDrill        bool   `json:"is_drill"`   // 演练通知，终端应明确标注
+}
+
+// NotificationReceiptMessage 终端通过MQTT上报的通知回执
+type NotificationReceiptMessage struct {
+	NotificationID uint   `json:"notification_id"`
+	RecipientType  string `json:"recipient_type"`
+	RecipientID    uint   `json:"recipient_id"`
+	Status         string `json:"status"` // delivered, read
+}
+
+// NotificationProgress 紧急通知投递进度
+type NotificationProgress struct {
+	NotificationID uint                        `json:"notification_id"`
+	Recipients     int64                       `json:"recipients"` // 接收人总数
+	Delivered      int64                       `json:"delivered"`  // 至少一个外部渠道确认送达的接收人数
+	Read           int64                       `json:"read"`       // 已读的接收人数
+	Channels       map[string]map[string]int64 `json:"channels"`   // 渠道 -> 投递状态 -> 数量
+}
+
+// DeliveryQuery 投递记录查询条件
+type DeliveryQuery struct {
+	NotificationID uint
+	Channel        string
+	Status         string
+	Page           int
+	PageSize       int
+}
+
+// MQTTNotificationChannel 通过MQTT向接收人主题推送通知
+type MQTTNotificationChannel struct {
+	MQTTService InterfaceMQTTCallService
+}
+
+// Name 返回渠道名称
+func (c *MQTTNotificationChannel) Name() string {
+	return NotificationChannelMQTT
+}
+
+// Accepts 所有接收人都有MQTT主题
+func (c *MQTTNotificationChannel) Accepts(recipient NotificationRecipient) bool {
+	return true
+}
+
+// Send 推送通知到接收人主题
+func (c *MQTTNotificationChannel) Send(notification *models.EmergencyNotification, recipient NotificationRecipient) error {
+	topic := fmt.Sprintf("%s/%s/%d", TopicNotificationPrefix, recipient.Type, recipient.ID)
+	return c.MQTTService.PublishMessage(topic, NotificationMessage{
+		NotificationID: notification.ID,
+		Title:          notification.Title,
+		Content:        notification.Content,
+		Severity:       notification.Severity,
+		Timestamp:      notification.Timestamp.UnixMilli(),
+		ExpiresAt:      notification.ExpiresAt.UnixMilli(),
+		IsDrill:        notification.IsDrill,
+	})
+}
+
+// DispatchNotificationChannel 通过统一通知服务投递紧急通知的短信、邮件和推送渠道
+type DispatchNotificationChannel struct {
+	Channel  notifier.Channel
+	Notifier InterfaceNotificationService
+}
+
+// Name 返回渠道名称
+func (c *DispatchNotificationChannel) Name() string {
+	return string(c.Channel)
+}
+
+// Accepts 短信需要手机号，邮件需要邮箱
+func (c *DispatchNotificationChannel) Accepts(recipient NotificationRecipient) bool {
+	return c.address(recipient) != ""
+}
+
+// Send 使用紧急通知模板发送
+func (c *DispatchNotificationChannel) Send(notification *models.EmergencyNotification, recipient NotificationRecipient) error {
+	return c.Notifier.Send(notifier.Message{
+		Channel:  c.Channel,
+		To:       c.address(recipient),
+		Template: "emergency_notification",
+		Data: map[string]interface{}{
+			"title":    notification.Title,
+			"content":  notification.Content,
+			"severity": notification.Severity,
+			"name":     recipient.Name,
+			"is_drill": notification.IsDrill,
+		},
+	})
+}
+
+// address 返回接收人在该渠道的地址，推送以"类型:ID"标识终端用户
+func (c *DispatchNotificationChannel) address(recipient NotificationRecipient) string {
+	switch c.Channel {
+	case notifier.ChannelSMS:
+		return recipient.Phone
+	case notifier.ChannelEmail:
+		return recipient.Email
+	case notifier.ChannelPush:
+		return recipientKey(recipient.Type, recipient.ID)
+	default:
+		return ""
+	}
+}
+
+// EmergencyNotificationService 将紧急通知扇出到目标受众，并记录每个接收人的投递与已读回执
+type EmergencyNotificationService struct {
+	DB       *gorm.DB
+	Config   *config.Config
+	Scope    PropertyScope
+	channels []NotificationChannel
+	mu       sync.RWMutex
+}
+
+// NewEmergencyNotificationService 创建紧急通知投递服务，默认启用MQTT渠道
+func NewEmergencyNotificationService(db *gorm.DB, cfg *config.Config, mqttService InterfaceMQTTCallService) InterfaceEmergencyNotificationService {
+	service := &EmergencyNotificationService{
+		DB:     db,
+		Config: cfg,
+	}
+	if mqttService != nil {
+		service.channels = append(service.channels, &MQTTNotificationChannel{MQTTService: mqttService})
+	}
+	return service
+}
+
+// WithPropertyScope 返回限定在指定物业内的紧急通知服务，nil表示不限物业
+func (s *EmergencyNotificationService) WithPropertyScope(propertyID *uint) InterfaceEmergencyNotificationService {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return &EmergencyNotificationService{
+		DB:       s.DB,
+		Config:   s.Config,
+		Scope:    PropertyScope{PropertyID: propertyID},
+		channels: append([]NotificationChannel(nil), s.channels...),
+	}
+}
+
+// 1 RegisterChannel 注册投递渠道，同名渠道会被替换
+func (s *EmergencyNotificationService) RegisterChannel(channel NotificationChannel) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, existing := range s.channels {
+		if existing.Name() == channel.Name() {
+			s.channels[i] = channel
+			return
+		}
+	}
+	s.channels = append(s.channels, channel)
+}
+
+// 2 Dispatch 解析通知受众并生成投递记录，外部渠道在后台异步发送，返回接收人数
+func (s *EmergencyNotificationService) Dispatch(notification *models.EmergencyNotification) (int, error) {
+	recipients, err := s.resolveRecipients(notification.TargetType, notification.PropertyID)
+	if err != nil {
+		return 0, err
+	}
+	if len(recipients) == 0 {
+		return 0, nil
+	}
+
+	s.mu.RLock()
+	channels := append([]NotificationChannel(nil), s.channels...)
+	s.mu.RUnlock()
+
+	// 站内信写入即送达，外部渠道先记为待投递
+	now := time.Now()
+	deliveries := make([]models.EmergencyNotificationDelivery, 0, len(recipients)*(len(channels)+1))
+	for _, recipient := range recipients {
+		deliveries = append(deliveries, models.EmergencyNotificationDelivery{
+			NotificationID: notification.ID,
+			RecipientType:  recipient.Type,
+			RecipientID:    recipient.ID,
+			Channel:        NotificationChannelInbox,
+			Status:         models.DeliveryStatusDelivered,
+			SentAt:         &now,
+			DeliveredAt:    &now,
+		})
+		for _, channel := range channels {
+			if !channel.Accepts(recipient) {
+				continue
+			}
+			deliveries = append(deliveries, models.EmergencyNotificationDelivery{
+				NotificationID: notification.ID,
+				RecipientType:  recipient.Type,
+				RecipientID:    recipient.ID,
+				Channel:        channel.Name(),
+				Status:         models.DeliveryStatusPending,
+			})
+		}
+	}
+
+	if err := s.DB.CreateInBatches(deliveries, 200).Error; err != nil {
+		return 0, fmt.Errorf("保存投递记录失败: %w", err)
+	}
+
+	go s.deliver(notification, recipients, channels, deliveries)
+
+	return len(recipients), nil
+}
+
+// 3 MarkDelivered 记录接收人在指定渠道的送达回执
+func (s *EmergencyNotificationService) MarkDelivered(notificationID uint, recipientType string, recipientID uint, channel string) error {
+	if err := s.ensureDelivery(notificationID, recipientType, recipientID); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	return s.DB.Model(&models.EmergencyNotificationDelivery{}).
+		Where("notification_id = ? AND recipient_type = ? AND recipient_id = ? AND channel = ? AND delivered_at IS NULL",
+			notificationID, recipientType, recipientID, channel).
+		Updates(map[string]interface{}{
+			"status":       models.DeliveryStatusDelivered,
+			"delivered_at": now,
+			"error":        "",
+		}).Error
+}
+
+// 4 MarkRead 记录接收人的已读回执，已读同时视为站内信以外的未确认渠道已送达
+func (s *EmergencyNotificationService) MarkRead(notificationID uint, recipientType string, recipientID uint) error {
+	if err := s.ensureDelivery(notificationID, recipientType, recipientID); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.EmergencyNotificationDelivery{}).
+			Where("notification_id = ? AND recipient_type = ? AND recipient_id = ? AND read_at IS NULL",
+				notificationID, recipientType, recipientID).
+			Update("read_at", now).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.EmergencyNotificationDelivery{}).
+			Where("notification_id = ? AND recipient_type = ? AND recipient_id = ? AND status = ?",
+				notificationID, recipientType, recipientID, models.DeliveryStatusSent).
+			Updates(map[string]interface{}{
+				"status":       models.DeliveryStatusDelivered,
+				"delivered_at": now,
+			}).Error
+	})
+}
+
+// 5 GetDeliveryProgress 统计紧急通知的投递进度
+func (s *EmergencyNotificationService) GetDeliveryProgress(notificationID uint) (*NotificationProgress, error) {
+	if err := s.checkNotification(notificationID); err != nil {
+		return nil, err
+	}
+
+	progress := &NotificationProgress{
+		NotificationID: notificationID,
+		Channels:       make(map[string]map[string]int64),
+	}
+
+	// 每个接收人都有且只有一条站内信记录
+	if err := s.DB.Model(&models.EmergencyNotificationDelivery{}).
+		Where("notification_id = ? AND channel = ?", notificationID, NotificationChannelInbox).
+		Count(&progress.Recipients).Error; err != nil {
+		return nil, err
+	}
+	if err := s.DB.Model(&models.EmergencyNotificationDelivery{}).
+		Where("notification_id = ? AND channel = ? AND read_at IS NOT NULL", notificationID, NotificationChannelInbox).
+		Count(&progress.Read).Error; err != nil {
+		return nil, err
+	}
+	if err := s.DB.Model(&models.EmergencyNotificationDelivery{}).
+		Where("notification_id = ? AND channel <> ? AND status = ?", notificationID, NotificationChannelInbox, models.DeliveryStatusDelivered).
+		Select("COUNT(DISTINCT recipient_type, recipient_id)").
+		Count(&progress.Delivered).Error; err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		Channel string
+		Status  string
+		Count   int64
+	}
+	if err := s.DB.Model(&models.EmergencyNotificationDelivery{}).
+		Select("channel, status, COUNT(*) AS count").
+		Where("notification_id = ?", notificationID).
+		Group("channel, status").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		if progress.Channels[row.Channel] == nil {
+			progress.Channels[row.Channel] = make(map[string]int64)
+		}
+		progress.Channels[row.Channel][row.Status] = row.Count
+	}
+
+	return progress, nil
+}
+
+// 6 GetDeliveries 分页查询紧急通知的投递记录
+func (s *EmergencyNotificationService) GetDeliveries(query DeliveryQuery) ([]models.EmergencyNotificationDelivery, int64, error) {
+	var deliveries []models.EmergencyNotificationDelivery
+	var total int64
+
+	if s.Scope.Scoped() {
+		if err := s.checkNotification(query.NotificationID); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	db := s.DB.Model(&models.EmergencyNotificationDelivery{}).Where("notification_id = ?", query.NotificationID)
+	if query.Channel != "" {
+		db = db.Where("channel = ?", query.Channel)
+	}
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (query.Page - 1) * query.PageSize
+	if err := db.Order("id ASC").Limit(query.PageSize).Offset(offset).Find(&deliveries).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return deliveries, total, nil
+}
+
+// 7 HandleMQTTReceipt 处理终端通过MQTT上报的送达/已读回执
+func (s *EmergencyNotificationService) HandleMQTTReceipt(payload []byte) {
+	var msg NotificationReceiptMessage
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		log.Printf("[Notification] 解析通知回执失败: %v", err)
+		return
+	}
+
+	var err error
+	switch msg.Status {
+	case "delivered":
+		err = s.MarkDelivered(msg.NotificationID, msg.RecipientType, msg.RecipientID, NotificationChannelMQTT)
+	case "read":
+		err = s.MarkRead(msg.NotificationID, msg.RecipientType, msg.RecipientID)
+	default:
+		err = fmt.Errorf("不支持的回执状态: %s", msg.Status)
+	}
+	if err != nil {
+		log.Printf("[Notification] 处理通知 %d 的回执失败(%s %d): %v", msg.NotificationID, msg.RecipientType, msg.RecipientID, err)
+	}
+}
+
+// deliver 逐条发送待投递记录，并回写发送结果
+func (s *EmergencyNotificationService) deliver(notification *models.EmergencyNotification, recipients []NotificationRecipient, channels []NotificationChannel, deliveries []models.EmergencyNotificationDelivery) {
+	recipientIndex := make(map[string]NotificationRecipient, len(recipients))
+	for _, recipient := range recipients {
+		recipientIndex[recipientKey(recipient.Type, recipient.ID)] = recipient
+	}
+	channelIndex := make(map[string]NotificationChannel, len(channels))
+	for _, channel := range channels {
+		channelIndex[channel.Name()] = channel
+	}
+
+	sent, failed := 0, 0
+	for _, delivery := range deliveries {
+		if delivery.Status != models.DeliveryStatusPending {
+			continue
+		}
+		channel, ok := channelIndex[delivery.Channel]
+		if !ok {
+			continue
+		}
+
+		updates := map[string]interface{}{}
+		if err := channel.Send(notification, recipientIndex[recipientKey(delivery.RecipientType, delivery.RecipientID)]); err != nil {
+			updates["status"] = models.DeliveryStatusFailed
+			updates["error"] = truncate(err.Error(), 255)
+			failed++
+		} else {
+			updates["status"] = models.DeliveryStatusSent
+			updates["sent_at"] = time.Now()
+			sent++
+		}
+
+		// 回执可能先于结果回写到达，只更新仍处于待投递状态的记录
+		if err := s.DB.Model(&models.EmergencyNotificationDelivery{}).
+			Where("id = ? AND status = ?", delivery.ID, models.DeliveryStatusPending).
+			Updates(updates).Error; err != nil {
+			log.Printf("[Notification] 更新投递记录 %d 失败: %v", delivery.ID, err)
+		}
+	}
+
+	log.Printf("[Notification] 紧急通知 %d 投递完成: 成功%d条，失败%d条", notification.ID, sent, failed)
+}
+
+// resolveRecipients 根据目标类型和物业解析通知受众
+func (s *EmergencyNotificationService) resolveRecipients(targetType string, propertyID *uint) ([]NotificationRecipient, error) {
+	if targetType == "" {
+		targetType = NotificationTargetAll
+	}
+	if targetType != NotificationTargetAll && targetType != NotificationTargetResidents && targetType != NotificationTargetStaff {
+		return nil, ErrInvalidTargetType
+	}
+
+	var recipients []NotificationRecipient
+
+	if targetType == NotificationTargetAll || targetType == NotificationTargetResidents {
+		var residents []models.Resident
+		db := s.DB.Model(&models.Resident{})
+		if propertyID != nil {
+			db = db.Joins("JOIN households ON households.id = residents.household_id").
+				Joins("JOIN buildings ON buildings.id = households.building_id").
+				Where("buildings.property_id = ?", *propertyID)
+		}
+		if err := db.Select("residents.*").Find(&residents).Error; err != nil {
+			return nil, fmt.Errorf("获取居民失败: %w", err)
+		}
+		for _, resident := range residents {
+			recipients = append(recipients, NotificationRecipient{
+				Type:  models.RecipientTypeResident,
+				ID:    resident.ID,
+				Name:  resident.Name,
+				Phone: resident.Phone,
+				Email: resident.Email,
+			})
+		}
+	}
+
+	if targetType == NotificationTargetAll || targetType == NotificationTargetStaff {
+		// 物业人员暂未关联物业ID，物业范围的通知同样发给所有在职人员
+		var staffs []models.PropertyStaff
+		if err := s.DB.Where("status = ?", "active").Find(&staffs).Error; err != nil {
+			return nil, fmt.Errorf("获取物业人员失败: %w", err)
+		}
+		for _, staff := range staffs {
+			recipients = append(recipients, NotificationRecipient{
+				Type:  models.RecipientTypeStaff,
+				ID:    staff.ID,
+				Name:  staff.Username,
+				Phone: staff.Phone,
+			})
+		}
+	}
+
+	return recipients, nil
+}
+
+// checkNotification 校验通知存在且属于当前物业，其他物业的通知视为不存在
+func (s *EmergencyNotificationService) checkNotification(notificationID uint) error {
+	var notification models.EmergencyNotification
+	if err := s.DB.Scopes(s.Scope.Owned).Select("id").First(&notification, notificationID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotificationNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// ensureDelivery 检查接收人是否有该通知的投递记录
+func (s *EmergencyNotificationService) ensureDelivery(notificationID uint, recipientType string, recipientID uint) error {
+	var count int64
+	if err := s.DB.Model(&models.EmergencyNotificationDelivery{}).
+		Where("notification_id = ? AND recipient_type = ? AND recipient_id = ?", notificationID, recipientType, recipientID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrDeliveryNotFound
+	}
+	return nil
+}
+
+// recipientKey 生成接收人索引键
+func recipientKey(recipientType string, recipientID uint) string {
+	return fmt.Sprintf("%s:%d", recipientType, recipientID)
+}
+
+// truncate 截断字符串到指定字节数以内，避免超出字段长度
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	cut := 0
+	for i := range s {
+		if i > max {
+			break
+		}
+		cut = i
+	}
+	return s[:cut]
+}
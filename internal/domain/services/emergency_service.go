@@ -2,19 +2,59 @@ package services
 
 import (
 	"errors"
+	"fmt"
 	"ilock-http-service/internal/infrastructure/config"
+	"ilock-http-service/internal/infrastructure/notifier"
+	"ilock-http-service/internal/domain/events"
 	"ilock-http-service/internal/domain/models"
+	"log"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // InterfaceEmergencyService defines the emergency service interface
 type InterfaceEmergencyService interface {
-	TriggerAlarm(alarm *models.EmergencyAlarm) error
+	WithPropertyScope(propertyID *uint) InterfaceEmergencyService
+	TriggerAlarm(alarm *models.EmergencyAlarm, operator AlarmOperator) error
 	GetEmergencyContacts() ([]models.EmergencyContact, error)
-	EmergencyUnlockAll(reason string) error
 	NotifyAllUsers(notificationData *models.EmergencyNotification) error
+	GetAlarms(query AlarmQuery) ([]models.EmergencyAlarm, int64, error)
+	GetAlarmByID(id uint) (*models.EmergencyAlarm, error)
+	AcknowledgeAlarm(id uint, operator AlarmOperator, remark string) (*models.EmergencyAlarm, error)
+	AssignAlarm(id, staffID uint, operator AlarmOperator, remark string) (*models.EmergencyAlarm, error)
+	ResolveAlarm(id uint, operator AlarmOperator, resolution string) (*models.EmergencyAlarm, error)
+	TriggerEmergency(emergency *models.EmergencyLog) error
+	GetEmergencyLogs(status string, page, pageSize int) ([]models.EmergencyLog, int64, error)
+	GetEmergencyLogByID(id uint) (*models.EmergencyLog, error)
+	UpdateEmergencyStatus(id uint, status models.EmergencyStatus, operatorID uint) (*models.EmergencyLog, error)
+}
+
+var (
+	// ErrAlarmNotFound 警报不存在
+	ErrAlarmNotFound = errors.New("警报不存在")
+	// ErrAlarmTransition 警报状态流转不合法
+	ErrAlarmTransition = errors.New("警报状态不允许此操作")
+	// ErrEmergencyNotFound 紧急事件不存在
+	ErrEmergencyNotFound = errors.New("紧急事件不存在")
+)
+
+// AlarmQuery 警报列表查询条件
+type AlarmQuery struct {
+	Status     string
+	Type       string
+	PropertyID *uint
+	AssignedTo *uint
+	IsDrill    *bool // 为空表示不区分演练
+	Page       int
+	PageSize   int
+}
+
+// AlarmOperator 警报操作人
+type AlarmOperator struct {
+	ID   uint
+	Role string
 }
 
 // EmergencyContact 紧急联系人
@@ -49,40 +89,66 @@ type EmergencyNotification struct {
 
 // EmergencyService 提供紧急事件相关服务
 type EmergencyService struct {
-	DB     *gorm.DB
-	Config *config.Config
+	DB                  *gorm.DB
+	Config              *config.Config
+	NotificationService InterfaceEmergencyNotificationService
+	ContactService      InterfaceEmergencyContactService
+	Notifier            InterfaceNotificationService
+	Events              *events.Bus
+	Scope               PropertyScope
 }
 
 // NewEmergencyService 创建新的紧急事件服务
-func NewEmergencyService(db *gorm.DB, cfg *config.Config) InterfaceEmergencyService {
+func NewEmergencyService(db *gorm.DB, cfg *config.Config, notificationService InterfaceEmergencyNotificationService,
+	contactService InterfaceEmergencyContactService, notifierService InterfaceNotificationService, bus *events.Bus) InterfaceEmergencyService {
 	return &EmergencyService{
-		DB:     db,
-		Config: cfg,
+		DB:                  db,
+		Config:              cfg,
+		NotificationService: notificationService,
+		ContactService:      contactService,
+		Notifier:            notifierService,
+		Events:              bus,
 	}
 }
 
-// 1 TriggerAlarm 触发紧急警报
-func (s *EmergencyService) TriggerAlarm(alarm *models.EmergencyAlarm) error {
+// WithPropertyScope 返回限定在指定物业内的紧急事件服务，nil表示不限物业
+func (s *EmergencyService) WithPropertyScope(propertyID *uint) InterfaceEmergencyService {
+	scoped := *s
+	scoped.Scope = PropertyScope{PropertyID: propertyID}
+	return &scoped
+}
+
+// 1 TriggerAlarm 触发紧急警报，operator 记录为触发日志的操作人，ID为0表示系统自动报警
+func (s *EmergencyService) TriggerAlarm(alarm *models.EmergencyAlarm, operator AlarmOperator) error {
+	propertyID, err := s.Scope.ownProperty(alarm.PropertyID)
+	if err != nil {
+		return err
+	}
+	alarm.PropertyID = propertyID
+
 	// 设置时间戳和初始状态
 	now := time.Now()
 	alarm.Timestamp = now
-	alarm.Status = "triggered"
+	alarm.Status = models.AlarmStatusTriggered
 	alarm.CreatedAt = now
 	alarm.UpdatedAt = now
 
-	// 保存警报记录
-	if err := s.DB.Create(alarm).Error; err != nil {
-		// 如果保存失败，我们创建一个虚拟的响应用于演示
-		alarm.ID = 999
-		alarm.Status = "demo_mode"
-		return nil
+	// 保存警报记录及触发审计日志
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(alarm).Error; err != nil {
+			return fmt.Errorf("保存警报失败: %w", err)
+		}
+
+		return createAlarmLog(tx, alarm.ID, "trigger", "", alarm.Status, operator, alarm.Description)
+	})
+	if err != nil {
+		return err
 	}
 
-	// TODO: 在实际应用中，这里可能需要：
-	// 1. 通知安保人员
-	// 2. 自动通知紧急联系人
-	// 3. 触发联动设备（如警报器、自动喷淋系统等）
-	// 4. 记录到日志系统
+	// 短信通知警报所在物业的紧急联系人
+	go s.notifyAlarmContacts(*alarm)
+
+	s.Events.Publish(events.AlarmTriggered{Alarm: alarm})
 
 	return nil
 }
@@ -91,55 +157,27 @@ func (s *EmergencyService) TriggerAlarm(alarm *models.EmergencyAlarm) error {
 func (s *EmergencyService) GetEmergencyContacts() ([]models.EmergencyContact, error) {
 	var contacts []models.EmergencyContact
 
-	// 查询所有紧急联系人，按优先级排序
-	if err := s.DB.Order("priority DESC").Find(&contacts).Error; err != nil {
+	// 查询所有紧急联系人，按优先级排序；限定物业时只返回本物业及全局联系人
+	db := s.DB.Model(&models.EmergencyContact{})
+	if s.Scope.Scoped() {
+		db = db.Where("property_id = ? OR property_id IS NULL", *s.Scope.PropertyID)
+	}
+	if err := db.Order("priority DESC").Find(&contacts).Error; err != nil {
 		return nil, err
 	}
 
 	return contacts, nil
 }
 
-// 3 EmergencyUnlockAll 紧急情况下解锁所有门
-func (s *EmergencyService) EmergencyUnlockAll(reason string) error {
-	if reason == "" {
-		return errors.New("必须提供紧急解锁原因")
-	}
-
-	// 获取所有设备
-	var devices []models.Device
-	if err := s.DB.Find(&devices).Error; err != nil {
+// 3 NotifyAllUsers 保存紧急通知并投递给目标类型和物业对应的受众
+func (s *EmergencyService) NotifyAllUsers(notificationData *models.EmergencyNotification) error {
+	// 物业账号只能向本物业发送通知
+	propertyID, err := s.Scope.ownProperty(notificationData.PropertyID)
+	if err != nil {
 		return err
 	}
+	notificationData.PropertyID = propertyID
 
-	// 记录解锁操作
-	unlockTime := time.Now()
-
-	// 遍历设备并解锁
-	for _, device := range devices {
-		// TODO: 在实际应用中，这里需要调用设备API或硬件接口来执行实际的解锁操作
-
-		// 记录解锁事件
-		unlockLog := models.OperationLog{
-			OperationType: "emergency_unlock",
-			DeviceID:      device.ID,
-			UserID:        0, // 系统自动操作
-			Details:       reason,
-			Timestamp:     unlockTime,
-			Success:       true,
-		}
-
-		if err := s.DB.Create(&unlockLog).Error; err != nil {
-			// 继续尝试解锁其他设备，但记录错误
-			// 在实际应用中，可能需要更复杂的错误处理和重试机制
-			continue
-		}
-	}
-
-	return nil
-}
-
-// 4 NotifyAllUsers 向所有用户发送紧急通知
-func (s *EmergencyService) NotifyAllUsers(notificationData *models.EmergencyNotification) error {
 	// 设置时间戳
 	now := time.Now()
 	notificationData.Timestamp = now
@@ -155,31 +193,370 @@ func (s *EmergencyService) NotifyAllUsers(notificationData *models.EmergencyNoti
 	if notificationData.Title == "" || notificationData.Content == "" {
 		return errors.New("通知标题和内容不能为空")
 	}
+	switch notificationData.TargetType {
+	case "", NotificationTargetAll, NotificationTargetResidents, NotificationTargetStaff:
+	default:
+		return ErrInvalidTargetType
+	}
+
+	if notificationData.TargetType == "" {
+		notificationData.TargetType = NotificationTargetAll
+	}
 
 	// 保存通知到数据库
 	if err := s.DB.Create(notificationData).Error; err != nil {
 		return err
 	}
 
-	// TODO: 在实际应用中，这里需要：
-	// 1. 通过推送服务发送给所有用户设备
-	// 2. 可能还需要通过短信、电子邮件等渠道发送
-	// 3. 根据TargetType确定通知发送的目标用户群体
+	// 按目标类型和物业扇出到接收人，外部渠道在后台投递
+	recipients, err := s.NotificationService.Dispatch(notificationData)
+	if err != nil {
+		return fmt.Errorf("投递通知失败: %w", err)
+	}
+	log.Printf("[Emergency] 紧急通知 %d 已扇出到 %d 个接收人", notificationData.ID, recipients)
+
+	return nil
+}
+
+// 4 GetAlarms 获取警报列表，支持按状态、类型、物业、负责人和是否演练筛选
+func (s *EmergencyService) GetAlarms(query AlarmQuery) ([]models.EmergencyAlarm, int64, error) {
+	var alarms []models.EmergencyAlarm
+	var total int64
+
+	db := s.DB.Model(&models.EmergencyAlarm{}).Scopes(s.Scope.Owned)
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+	if query.Type != "" {
+		db = db.Where("type = ?", query.Type)
+	}
+	if query.PropertyID != nil {
+		db = db.Where("property_id = ?", *query.PropertyID)
+	}
+	if query.AssignedTo != nil {
+		db = db.Where("assigned_to = ?", *query.AssignedTo)
+	}
+	if query.IsDrill != nil {
+		db = db.Where("is_drill = ?", *query.IsDrill)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (query.Page - 1) * query.PageSize
+	if err := db.Preload("Assignee").Order("timestamp DESC").Limit(query.PageSize).Offset(offset).Find(&alarms).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return alarms, total, nil
+}
+
+// 5 GetAlarmByID 根据ID获取警报及其处理记录
+func (s *EmergencyService) GetAlarmByID(id uint) (*models.EmergencyAlarm, error) {
+	var alarm models.EmergencyAlarm
+	if err := s.DB.Scopes(s.Scope.Owned).Preload("Assignee").
+		Preload("Logs", func(db *gorm.DB) *gorm.DB { return db.Order("timestamp ASC") }).
+		First(&alarm, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAlarmNotFound
+		}
+		return nil, err
+	}
+	return &alarm, nil
+}
+
+// 6 AcknowledgeAlarm 确认警报，警报进入处理中状态
+func (s *EmergencyService) AcknowledgeAlarm(id uint, operator AlarmOperator, remark string) (*models.EmergencyAlarm, error) {
+	alarm, err := s.transitionAlarm(id, models.AlarmStatusProcessing, "acknowledge", operator, remark, func(alarm *models.EmergencyAlarm, now time.Time) (map[string]interface{}, error) {
+		if alarm.Status != models.AlarmStatusTriggered {
+			return nil, ErrAlarmTransition
+		}
+		return map[string]interface{}{
+			"acknowledged_at": now,
+			"acknowledged_by": operator.ID,
+		}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.Events.Publish(events.AlarmAcknowledged{Alarm: alarm, OperatorID: operator.ID})
+	return alarm, nil
+}
+
+// 7 AssignAlarm 将警报指派给物业员工处理
+func (s *EmergencyService) AssignAlarm(id, staffID uint, operator AlarmOperator, remark string) (*models.EmergencyAlarm, error) {
+	var staff models.PropertyStaff
+	if err := s.DB.Scopes(s.Scope.Staff).First(&staff, staffID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("物业员工不存在")
+		}
+		return nil, err
+	}
+
+	if remark == "" {
+		remark = fmt.Sprintf("指派给物业员工 %s(ID=%d)", staff.Username, staff.ID)
+	}
+
+	alarm, err := s.transitionAlarm(id, models.AlarmStatusProcessing, "assign", operator, remark, func(alarm *models.EmergencyAlarm, now time.Time) (map[string]interface{}, error) {
+		updates := map[string]interface{}{
+			"assigned_to": staffID,
+			"assigned_at": now,
+		}
+		// 未确认的警报在指派时视为已确认
+		if alarm.AcknowledgedAt == nil {
+			updates["acknowledged_at"] = now
+			updates["acknowledged_by"] = operator.ID
+		}
+		return updates, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.Events.Publish(events.AlarmAssigned{Alarm: alarm, StaffID: staffID, OperatorID: operator.ID})
+	return alarm, nil
+}
+
+// 8 ResolveAlarm 解决警报
+func (s *EmergencyService) ResolveAlarm(id uint, operator AlarmOperator, resolution string) (*models.EmergencyAlarm, error) {
+	if resolution == "" {
+		return nil, errors.New("必须提供处理结果")
+	}
 
-	// 获取所有居民
-	var residents []models.Resident
-	if err := s.DB.Find(&residents).Error; err != nil {
+	alarm, err := s.transitionAlarm(id, models.AlarmStatusResolved, "resolve", operator, resolution, func(alarm *models.EmergencyAlarm, now time.Time) (map[string]interface{}, error) {
+		return map[string]interface{}{
+			"resolved_at": now,
+			"resolved_by": operator.ID,
+			"resolution":  resolution,
+		}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.Events.Publish(events.AlarmResolved{Alarm: alarm, OperatorID: operator.ID})
+	return alarm, nil
+}
+
+// transitionAlarm 在事务中校验并执行警报状态流转，同时写入审计日志
+func (s *EmergencyService) transitionAlarm(
+	id uint,
+	toStatus, action string,
+	operator AlarmOperator,
+	remark string,
+	buildUpdates func(alarm *models.EmergencyAlarm, now time.Time) (map[string]interface{}, error),
+) (*models.EmergencyAlarm, error) {
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var alarm models.EmergencyAlarm
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Scopes(s.Scope.Owned).First(&alarm, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrAlarmNotFound
+			}
+			return err
+		}
+
+		if !alarm.CanTransitionTo(toStatus) {
+			return fmt.Errorf("%w: %s → %s", ErrAlarmTransition, alarm.Status, toStatus)
+		}
+
+		now := time.Now()
+		updates, err := buildUpdates(&alarm, now)
+		if err != nil {
+			return err
+		}
+		updates["status"] = toStatus
+
+		if err := tx.Model(&alarm).Updates(updates).Error; err != nil {
+			return fmt.Errorf("更新警报失败: %w", err)
+		}
+
+		return createAlarmLog(tx, alarm.ID, action, alarm.Status, toStatus, operator, remark)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetAlarmByID(id)
+}
+
+// notifyAlarmContacts 按解析顺序给物业紧急联系人和全局联系人发送警报短信
+func (s *EmergencyService) notifyAlarmContacts(alarm models.EmergencyAlarm) {
+	if s.ContactService == nil || s.Notifier == nil {
+		return
+	}
+
+	contacts, err := s.ContactService.ResolveContacts(alarm.PropertyID)
+	if err != nil {
+		log.Printf("[Emergency] 获取警报 %d 的紧急联系人失败: %v", alarm.ID, err)
+		return
+	}
+
+	for _, contact := range contacts {
+		s.Notifier.SendAsync(notifier.Message{
+			Channel:  notifier.ChannelSMS,
+			To:       contact.PhoneNumber,
+			Template: "emergency_alarm",
+			Data: map[string]interface{}{
+				"type":        alarm.Type,
+				"location":    alarm.Location,
+				"description": alarm.Description,
+				"is_drill":    alarm.IsDrill,
+			},
+		})
+	}
+}
+
+// createAlarmLog 写入警报审计日志
+func createAlarmLog(tx *gorm.DB, alarmID uint, action, fromStatus, toStatus string, operator AlarmOperator, remark string) error {
+	alarmLog := models.EmergencyAlarmLog{
+		AlarmID:      alarmID,
+		Action:       action,
+		FromStatus:   fromStatus,
+		ToStatus:     toStatus,
+		OperatorID:   operator.ID,
+		OperatorRole: operator.Role,
+		Remark:       remark,
+		Timestamp:    time.Now(),
+	}
+	if err := tx.Create(&alarmLog).Error; err != nil {
+		return fmt.Errorf("保存警报审计日志失败: %w", err)
+	}
+	return nil
+}
+
+// 9 TriggerEmergency 记录居民或设备发起的紧急求助，等待响应，超时未响应将由升级服务处理
+func (s *EmergencyService) TriggerEmergency(emergency *models.EmergencyLog) error {
+	if emergency.ResidentID == 0 && emergency.DeviceID == 0 {
+		return errors.New("必须提供居民ID或设备ID")
+	}
+
+	// 限定物业时求助的设备和居民都必须属于本物业
+	if emergency.DeviceID != 0 {
+		if err := s.Scope.checkDevice(s.DB, emergency.DeviceID); err != nil {
+			return err
+		}
+	}
+	if emergency.ResidentID != 0 {
+		if err := s.Scope.checkResident(s.DB, emergency.ResidentID); err != nil {
+			return err
+		}
+	}
+
+	// 未指定物业时按设备或居民所在物业确定，用于按物业通知紧急联系人和查询
+	if emergency.PropertyID == nil {
+		emergency.PropertyID = s.resolveEmergencyPropertyID(emergency)
+	}
+	propertyID, err := s.Scope.ownProperty(emergency.PropertyID)
+	if err != nil {
 		return err
 	}
+	emergency.PropertyID = propertyID
 
-	// 获取所有物业人员
-	var staffs []models.PropertyStaff
-	if err := s.DB.Find(&staffs).Error; err != nil {
+	emergency.Status = models.EmergencyStatusPending
+	emergency.TriggeredAt = time.Now()
+	emergency.EscalationLevel = 0
+
+	if err := s.DB.Create(emergency).Error; err != nil {
 		return err
 	}
 
-	// 模拟发送通知（实际应用中需要调用推送服务）
-	// 这里只是示例代码，不会真正发送通知
+	s.Events.Publish(events.EmergencyTriggered{Emergency: emergency})
+	return nil
+}
+
+// 10 GetEmergencyLogs 获取紧急事件列表
+func (s *EmergencyService) GetEmergencyLogs(status string, page, pageSize int) ([]models.EmergencyLog, int64, error) {
+	var logs []models.EmergencyLog
+	var total int64
+
+	db := s.DB.Model(&models.EmergencyLog{}).Scopes(s.Scope.Owned)
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	if err := db.Preload("Device").Preload("Resident").
+		Order("triggered_at DESC").
+		Limit(pageSize).Offset(offset).
+		Find(&logs).Error; err != nil {
+		return nil, 0, err
+	}
 
+	return logs, total, nil
+}
+
+// 11 GetEmergencyLogByID 获取紧急事件详情及升级记录
+func (s *EmergencyService) GetEmergencyLogByID(id uint) (*models.EmergencyLog, error) {
+	var emergency models.EmergencyLog
+	if err := s.DB.Scopes(s.Scope.Owned).Preload("Device").Preload("Resident").
+		Preload("EscalationSteps", func(db *gorm.DB) *gorm.DB { return db.Order("timestamp ASC") }).
+		First(&emergency, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrEmergencyNotFound
+		}
+		return nil, err
+	}
+	return &emergency, nil
+}
+
+// 12 UpdateEmergencyStatus 响应或解决紧急事件，响应后停止升级
+func (s *EmergencyService) UpdateEmergencyStatus(id uint, status models.EmergencyStatus, operatorID uint) (*models.EmergencyLog, error) {
+	emergency, err := s.GetEmergencyLogByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if emergency.Status == models.EmergencyStatusResolved {
+		return nil, errors.New("紧急事件已解决")
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{"status": status}
+
+	switch status {
+	case models.EmergencyStatusResponded:
+		if !emergency.IsAwaitingResponse() {
+			return nil, errors.New("紧急事件已被响应")
+		}
+		updates["responded_at"] = now
+		updates["responded_by"] = operatorID
+	case models.EmergencyStatusResolved:
+		// 未经响应直接解决时同时记录响应信息，确保升级停止
+		if emergency.RespondedAt == nil {
+			updates["responded_at"] = now
+			updates["responded_by"] = operatorID
+		}
+		updates["resolved_at"] = now
+	default:
+		return nil, fmt.Errorf("不支持的紧急事件状态: %s", status)
+	}
+
+	if err := s.DB.Model(&models.EmergencyLog{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+
+	return s.GetEmergencyLogByID(id)
+}
+
+// resolveEmergencyPropertyID 按设备所在楼号或居民所在户号查找紧急事件所属物业
+func (s *EmergencyService) resolveEmergencyPropertyID(emergency *models.EmergencyLog) *uint {
+	if emergency.DeviceID != 0 {
+		var device models.Device
+		if err := s.DB.Preload("Building").First(&device, emergency.DeviceID).Error; err == nil && device.Building != nil {
+			return device.Building.PropertyID
+		}
+	}
+	if emergency.ResidentID != 0 {
+		var resident models.Resident
+		if err := s.DB.Select("id", "household_id").First(&resident, emergency.ResidentID).Error; err == nil {
+			return ResolveHouseholdPropertyID(s.DB, resident.HouseholdID)
+		}
+	}
 	return nil
 }
@@ -0,0 +1,590 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"ilock-http-service/internal/domain/models"
+	"ilock-http-service/internal/infrastructure/config"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// InterfaceEmergencyUnlockService 定义紧急解锁服务接口
+type InterfaceEmergencyUnlockService interface {
+	WithPropertyScope(propertyID *uint) InterfaceEmergencyUnlockService
+	StartUnlock(req UnlockRequest) (*models.EmergencyUnlockSession, error)
+	EndUnlock(id uint, operator AlarmOperator, reason string) (*models.EmergencyUnlockSession, error)
+	GetUnlockSession(id uint) (*models.EmergencyUnlockSession, error)
+	GetUnlockSessions(status string, page, pageSize int) ([]models.EmergencyUnlockSession, int64, error)
+	HandleMQTTAck(payload []byte)
+	Stop()
+}
+
+var (
+	// ErrUnlockSessionNotFound 紧急解锁会话不存在
+	ErrUnlockSessionNotFound = errors.New("紧急解锁会话不存在")
+	// ErrUnlockSessionEnded 紧急解锁会话已结束
+	ErrUnlockSessionEnded = errors.New("紧急解锁会话已结束")
+	// ErrNoDevicesInScope 解锁范围内没有设备
+	ErrNoDevicesInScope = errors.New("解锁范围内没有设备")
+	// ErrUnlockDeviceNotFound 指定的设备不存在
+	ErrUnlockDeviceNotFound = errors.New("部分指定的设备不存在")
+	// ErrUnlockAllOutOfScope 物业账号不能解锁所有物业的设备
+	ErrUnlockAllOutOfScope = fmt.Errorf("%w，物业账号只能解锁本物业的设备", ErrOutOfPropertyScope)
+)
+
+// drillSkipReason 演练会话中设备指令的跳过原因
+const drillSkipReason = "演练模式，未下发指令"
+
+// 设备指令
+const (
+	DeviceCommandEmergencyUnlock = "emergency_unlock" // 紧急解锁
+	DeviceCommandEmergencyRelock = "emergency_relock" // 结束紧急解锁，重新上锁
+)
+
+// UnlockRequest 紧急解锁请求，按设备、楼号、物业的顺序取第一个非空范围，都为空时解锁所有设备
+type UnlockRequest struct {
+	Reason      string
+	PropertyID  *uint
+	BuildingIDs []uint
+	DeviceIDs   []uint
+	RelockAfter int  // 多少秒后自动重新上锁，0表示需要手动结束
+	IsDrill     bool // 演练只记录会话，不向设备下发指令
+	Operator    AlarmOperator
+}
+
+// DeviceCommandMessage 通过MQTT下发给设备的指令
+type DeviceCommandMessage struct {
+	Command   string `json:"command"` // emergency_unlock, emergency_relock
+	SessionID uint   `json:"session_id"`
+	DeviceID  uint   `json:"device_id"`
+	Reason    string `json:"reason,omitempty"`
+	Duration  int    `json:"duration,omitempty"` // 计划保持解锁的秒数，设备可据此自行上锁兜底
+	Timestamp int64  `json:"timestamp"`
+}
+
+// DeviceCommandAck 设备执行指令后上报的回执
+type DeviceCommandAck struct {
+	Command   string `json:"command"`
+	SessionID uint   `json:"session_id"`
+	DeviceID  uint   `json:"device_id"`
+	Success   bool   `json:"success"`
+	Error     string `json:"error,omitempty"`
+}
+
+// EmergencyUnlockService 按范围下发紧急解锁指令，跟踪设备回执并在到期后自动重新上锁
+type EmergencyUnlockService struct {
+	DB          *gorm.DB
+	Config      *config.Config
+	MQTTService InterfaceMQTTCallService
+	Scope       PropertyScope
+	stopChan    chan struct{}
+	stopOnce    *sync.Once
+}
+
+// NewEmergencyUnlockService 创建紧急解锁服务并启动回执超时与自动上锁检查任务
+func NewEmergencyUnlockService(db *gorm.DB, cfg *config.Config, mqttService InterfaceMQTTCallService) InterfaceEmergencyUnlockService {
+	service := &EmergencyUnlockService{
+		DB:          db,
+		Config:      cfg,
+		MQTTService: mqttService,
+		stopChan:    make(chan struct{}),
+		stopOnce:    &sync.Once{},
+	}
+
+	// 启动回执超时与自动上锁检查任务
+	go service.startUnlockCheckTask()
+
+	return service
+}
+
+// WithPropertyScope 返回限定在指定物业内的紧急解锁服务，nil表示不限物业
+func (s *EmergencyUnlockService) WithPropertyScope(propertyID *uint) InterfaceEmergencyUnlockService {
+	scoped := *s
+	scoped.Scope = PropertyScope{PropertyID: propertyID}
+	return &scoped
+}
+
+// 1 StartUnlock 解析范围内的设备，创建解锁会话并下发解锁指令
+func (s *EmergencyUnlockService) StartUnlock(req UnlockRequest) (*models.EmergencyUnlockSession, error) {
+	if req.Reason == "" {
+		return nil, errors.New("必须提供紧急解锁原因")
+	}
+	if req.RelockAfter < 0 {
+		return nil, errors.New("自动上锁时间不能为负数")
+	}
+
+	scopeType, scopeIDs, devices, err := s.resolveDevices(req)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := &models.EmergencyUnlockSession{
+		Reason:       req.Reason,
+		ScopeType:    scopeType,
+		ScopeIDs:     joinIDs(scopeIDs),
+		PropertyID:   s.sessionPropertyID(devices),
+		Status:       models.UnlockSessionActive,
+		OperatorID:   req.Operator.ID,
+		OperatorRole: req.Operator.Role,
+		StartedAt:    now,
+		IsDrill:      req.IsDrill,
+	}
+	if req.RelockAfter > 0 {
+		relockAt := now.Add(time.Duration(req.RelockAfter) * time.Second)
+		session.RelockAt = &relockAt
+	}
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(session).Error; err != nil {
+			return err
+		}
+
+		rows := make([]models.EmergencyUnlockDevice, 0, len(devices))
+		for _, device := range devices {
+			row := models.EmergencyUnlockDevice{
+				SessionID:    session.ID,
+				DeviceID:     device.ID,
+				UnlockStatus: models.CommandStatusPending,
+				UnlockSentAt: &now,
+			}
+			if req.IsDrill {
+				row.UnlockStatus, row.UnlockSentAt, row.Error = models.CommandStatusSkipped, nil, drillSkipReason
+			}
+			rows = append(rows, row)
+		}
+		return tx.CreateInBatches(rows, 200).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("保存紧急解锁会话失败: %w", err)
+	}
+
+	if req.IsDrill {
+		log.Printf("[EmergencyUnlock] 演练会话 %d 覆盖 %d 台设备，未下发解锁指令，范围=%s", session.ID, len(devices), scopeType)
+		return s.GetUnlockSession(session.ID)
+	}
+
+	for _, device := range devices {
+		cmd := DeviceCommandMessage{
+			Command:   DeviceCommandEmergencyUnlock,
+			SessionID: session.ID,
+			DeviceID:  device.ID,
+			Reason:    req.Reason,
+			Duration:  req.RelockAfter,
+			Timestamp: now.UnixMilli(),
+		}
+		if err := s.MQTTService.PublishMessage(TopicDeviceCommand, cmd); err != nil {
+			s.failCommand(session.ID, device.ID, DeviceCommandEmergencyUnlock, "下发指令失败: "+err.Error(), req.Operator.ID)
+		}
+	}
+
+	log.Printf("[EmergencyUnlock] 会话 %d 已向 %d 台设备下发解锁指令，范围=%s", session.ID, len(devices), scopeType)
+
+	return s.GetUnlockSession(session.ID)
+}
+
+// 2 EndUnlock 手动结束紧急解锁并下发重新上锁指令
+func (s *EmergencyUnlockService) EndUnlock(id uint, operator AlarmOperator, reason string) (*models.EmergencyUnlockSession, error) {
+	session, err := s.GetUnlockSession(id)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status != models.UnlockSessionActive {
+		return nil, ErrUnlockSessionEnded
+	}
+	if reason == "" {
+		reason = "手动结束紧急解锁"
+	}
+
+	if err := s.endSession(session, models.UnlockSessionEnded, &operator.ID, reason); err != nil {
+		return nil, err
+	}
+
+	return s.GetUnlockSession(id)
+}
+
+// 3 GetUnlockSession 获取紧急解锁会话及每台设备的回执
+func (s *EmergencyUnlockService) GetUnlockSession(id uint) (*models.EmergencyUnlockSession, error) {
+	var session models.EmergencyUnlockSession
+	if err := s.DB.Scopes(s.Scope.Owned).Preload("Devices").Preload("Devices.Device").First(&session, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUnlockSessionNotFound
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// 4 GetUnlockSessions 分页获取紧急解锁会话
+func (s *EmergencyUnlockService) GetUnlockSessions(status string, page, pageSize int) ([]models.EmergencyUnlockSession, int64, error) {
+	var sessions []models.EmergencyUnlockSession
+	var total int64
+
+	db := s.DB.Model(&models.EmergencyUnlockSession{}).Scopes(s.Scope.Owned)
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	if err := db.Order("started_at DESC").Limit(pageSize).Offset(offset).Find(&sessions).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return sessions, total, nil
+}
+
+// 5 HandleMQTTAck 处理设备上报的指令回执，超时后到达的回执仍会更新为设备的真实状态
+func (s *EmergencyUnlockService) HandleMQTTAck(payload []byte) {
+	var ack DeviceCommandAck
+	if err := json.Unmarshal(payload, &ack); err != nil {
+		log.Printf("[EmergencyUnlock] 解析设备回执失败: %v", err)
+		return
+	}
+
+	var statusColumn, ackColumn, operationType string
+	switch ack.Command {
+	case DeviceCommandEmergencyUnlock:
+		statusColumn, ackColumn, operationType = "unlock_status", "unlock_ack_at", "emergency_unlock"
+	case DeviceCommandEmergencyRelock:
+		statusColumn, ackColumn, operationType = "relock_status", "relock_ack_at", "emergency_relock"
+	default:
+		// 其他指令的回执不由本服务处理
+		return
+	}
+
+	status := models.CommandStatusAcked
+	if !ack.Success {
+		status = models.CommandStatusFailed
+	}
+
+	result := s.DB.Model(&models.EmergencyUnlockDevice{}).
+		Where("session_id = ? AND device_id = ? AND "+statusColumn+" IN ?", ack.SessionID, ack.DeviceID,
+			[]string{models.CommandStatusPending, models.CommandStatusTimeout}).
+		Updates(map[string]interface{}{
+			statusColumn: status,
+			ackColumn:    time.Now(),
+			"error":      truncate(ack.Error, 255),
+		})
+	if result.Error != nil {
+		log.Printf("[EmergencyUnlock] 更新设备 %d 回执失败: %v", ack.DeviceID, result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		return
+	}
+
+	var session models.EmergencyUnlockSession
+	if err := s.DB.Select("id", "reason", "operator_id").First(&session, ack.SessionID).Error; err != nil {
+		return
+	}
+	details := fmt.Sprintf("紧急解锁会话#%d: %s", session.ID, session.Reason)
+	if ack.Error != "" {
+		details += "，设备返回: " + ack.Error
+	}
+	s.recordOperation(ack.DeviceID, operationType, session.OperatorID, details, ack.Success)
+}
+
+// 6 Stop 停止检查任务
+func (s *EmergencyUnlockService) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stopChan)
+	})
+}
+
+// resolveDevices 解析解锁范围内的设备，限定物业时指定的设备、楼号和物业都必须属于当前物业，且不能解锁所有设备
+func (s *EmergencyUnlockService) resolveDevices(req UnlockRequest) (string, []uint, []models.Device, error) {
+	var devices []models.Device
+	var scopeType string
+	var scopeIDs []uint
+
+	db := s.DB.Model(&models.Device{})
+	switch {
+	case len(req.DeviceIDs) > 0:
+		scopeType, scopeIDs = models.UnlockScopeDevices, req.DeviceIDs
+		if err := db.Where("id IN ?", req.DeviceIDs).Find(&devices).Error; err != nil {
+			return "", nil, nil, err
+		}
+		if len(devices) != len(uniqueIDs(req.DeviceIDs)) {
+			return "", nil, nil, ErrUnlockDeviceNotFound
+		}
+		if s.Scope.Scoped() {
+			var count int64
+			if err := s.DB.Model(&models.Device{}).Scopes(s.Scope.Devices).
+				Where("devices.id IN ?", req.DeviceIDs).Count(&count).Error; err != nil {
+				return "", nil, nil, err
+			}
+			if int(count) != len(devices) {
+				return "", nil, nil, ErrOutOfPropertyScope
+			}
+		}
+	case len(req.BuildingIDs) > 0:
+		scopeType, scopeIDs = models.UnlockScopeBuilding, req.BuildingIDs
+		for _, buildingID := range uniqueIDs(req.BuildingIDs) {
+			if err := s.Scope.checkBuilding(s.DB, buildingID); err != nil {
+				return "", nil, nil, err
+			}
+		}
+		if err := db.Where("building_id IN ?", req.BuildingIDs).Find(&devices).Error; err != nil {
+			return "", nil, nil, err
+		}
+	case req.PropertyID != nil:
+		if s.Scope.Scoped() && *req.PropertyID != *s.Scope.PropertyID {
+			return "", nil, nil, ErrOutOfPropertyScope
+		}
+		scopeType, scopeIDs = models.UnlockScopeProperty, []uint{*req.PropertyID}
+		if err := db.Joins("JOIN buildings ON buildings.id = devices.building_id").
+			Where("buildings.property_id = ?", *req.PropertyID).
+			Select("devices.*").Find(&devices).Error; err != nil {
+			return "", nil, nil, err
+		}
+	default:
+		if s.Scope.Scoped() {
+			return "", nil, nil, ErrUnlockAllOutOfScope
+		}
+		scopeType = models.UnlockScopeAll
+		if err := db.Find(&devices).Error; err != nil {
+			return "", nil, nil, err
+		}
+	}
+
+	if len(devices) == 0 {
+		return "", nil, nil, ErrNoDevicesInScope
+	}
+	return scopeType, scopeIDs, devices, nil
+}
+
+// sessionPropertyID 解锁的设备都属于同一物业时返回该物业，用于按物业查询解锁会话
+func (s *EmergencyUnlockService) sessionPropertyID(devices []models.Device) *uint {
+	if s.Scope.Scoped() {
+		id := *s.Scope.PropertyID
+		return &id
+	}
+
+	buildingIDs := make([]uint, 0, len(devices))
+	for _, device := range devices {
+		if device.BuildingID == 0 {
+			return nil
+		}
+		buildingIDs = append(buildingIDs, device.BuildingID)
+	}
+
+	var propertyIDs []*uint
+	if err := s.DB.Model(&models.Building{}).Where("id IN ?", uniqueIDs(buildingIDs)).
+		Distinct().Pluck("property_id", &propertyIDs).Error; err != nil {
+		log.Printf("[EmergencyUnlock] 查询解锁设备所属物业失败: %v", err)
+		return nil
+	}
+	if len(propertyIDs) != 1 {
+		return nil
+	}
+	return propertyIDs[0]
+}
+
+// endSession 结束会话并下发重新上锁指令，仍被其他解锁会话占用的设备保持解锁
+func (s *EmergencyUnlockService) endSession(session *models.EmergencyUnlockSession, status string, endedBy *uint, reason string) error {
+	now := time.Now()
+
+	// 条件更新，避免手动结束与自动上锁并发时重复下发
+	result := s.DB.Model(&models.EmergencyUnlockSession{}).
+		Where("id = ? AND status = ?", session.ID, models.UnlockSessionActive).
+		Updates(map[string]interface{}{
+			"status":     status,
+			"ended_at":   now,
+			"ended_by":   endedBy,
+			"end_reason": reason,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrUnlockSessionEnded
+	}
+
+	if session.IsDrill {
+		if err := s.DB.Model(&models.EmergencyUnlockDevice{}).Where("session_id = ?", session.ID).
+			Updates(map[string]interface{}{"relock_status": models.CommandStatusSkipped}).Error; err != nil {
+			return err
+		}
+		log.Printf("[EmergencyUnlock] 演练会话 %d 已结束(%s): %s", session.ID, status, reason)
+		return nil
+	}
+
+	// 查询仍处于解锁中的其他实际会话占用的设备，演练会话不会真正解锁设备
+	var busyDeviceIDs []uint
+	if err := s.DB.Model(&models.EmergencyUnlockDevice{}).
+		Joins("JOIN emergency_unlock_sessions ON emergency_unlock_sessions.id = emergency_unlock_devices.session_id").
+		Where("emergency_unlock_sessions.status = ? AND emergency_unlock_sessions.id <> ? AND emergency_unlock_sessions.is_drill = ?",
+			models.UnlockSessionActive, session.ID, false).
+		Pluck("emergency_unlock_devices.device_id", &busyDeviceIDs).Error; err != nil {
+		return err
+	}
+	busy := make(map[uint]bool, len(busyDeviceIDs))
+	for _, id := range busyDeviceIDs {
+		busy[id] = true
+	}
+
+	operatorID := session.OperatorID
+	if endedBy != nil {
+		operatorID = *endedBy
+	}
+
+	for _, device := range session.Devices {
+		if busy[device.DeviceID] {
+			s.DB.Model(&models.EmergencyUnlockDevice{}).Where("id = ?", device.ID).
+				Updates(map[string]interface{}{"relock_status": models.CommandStatusSkipped, "error": "设备仍处于其他紧急解锁会话中"})
+			continue
+		}
+
+		if err := s.DB.Model(&models.EmergencyUnlockDevice{}).Where("id = ?", device.ID).
+			Updates(map[string]interface{}{"relock_status": models.CommandStatusPending, "relock_sent_at": now}).Error; err != nil {
+			log.Printf("[EmergencyUnlock] 更新设备 %d 上锁状态失败: %v", device.DeviceID, err)
+			continue
+		}
+
+		cmd := DeviceCommandMessage{
+			Command:   DeviceCommandEmergencyRelock,
+			SessionID: session.ID,
+			DeviceID:  device.DeviceID,
+			Reason:    reason,
+			Timestamp: now.UnixMilli(),
+		}
+		if err := s.MQTTService.PublishMessage(TopicDeviceCommand, cmd); err != nil {
+			s.failCommand(session.ID, device.DeviceID, DeviceCommandEmergencyRelock, "下发指令失败: "+err.Error(), operatorID)
+		}
+	}
+
+	log.Printf("[EmergencyUnlock] 会话 %d 已结束(%s): %s", session.ID, status, reason)
+	return nil
+}
+
+// failCommand 将指令标记为失败并记录操作日志
+func (s *EmergencyUnlockService) failCommand(sessionID, deviceID uint, command, reason string, operatorID uint) {
+	statusColumn, operationType := "unlock_status", "emergency_unlock"
+	if command == DeviceCommandEmergencyRelock {
+		statusColumn, operationType = "relock_status", "emergency_relock"
+	}
+
+	if err := s.DB.Model(&models.EmergencyUnlockDevice{}).
+		Where("session_id = ? AND device_id = ?", sessionID, deviceID).
+		Updates(map[string]interface{}{statusColumn: models.CommandStatusFailed, "error": truncate(reason, 255)}).Error; err != nil {
+		log.Printf("[EmergencyUnlock] 更新设备 %d 指令状态失败: %v", deviceID, err)
+	}
+	s.recordOperation(deviceID, operationType, operatorID, fmt.Sprintf("紧急解锁会话#%d: %s", sessionID, reason), false)
+}
+
+// checkAckTimeouts 将超时未回执的指令标记为超时
+func (s *EmergencyUnlockService) checkAckTimeouts() {
+	timeout := time.Duration(s.Config.EmergencyUnlockAckTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = 15 * time.Second
+	}
+	deadline := time.Now().Add(-timeout)
+
+	phases := []struct {
+		statusColumn, sentColumn, operationType string
+	}{
+		{"unlock_status", "unlock_sent_at", "emergency_unlock"},
+		{"relock_status", "relock_sent_at", "emergency_relock"},
+	}
+
+	for _, phase := range phases {
+		var rows []models.EmergencyUnlockDevice
+		if err := s.DB.Where(phase.statusColumn+" = ? AND "+phase.sentColumn+" <= ?", models.CommandStatusPending, deadline).
+			Find(&rows).Error; err != nil {
+			log.Printf("[EmergencyUnlock] 查询超时指令失败: %v", err)
+			continue
+		}
+
+		for _, row := range rows {
+			result := s.DB.Model(&models.EmergencyUnlockDevice{}).
+				Where("id = ? AND "+phase.statusColumn+" = ?", row.ID, models.CommandStatusPending).
+				Updates(map[string]interface{}{phase.statusColumn: models.CommandStatusTimeout, "error": "设备未在规定时间内回执"})
+			if result.Error != nil || result.RowsAffected == 0 {
+				continue
+			}
+			s.recordOperation(row.DeviceID, phase.operationType, 0,
+				fmt.Sprintf("紧急解锁会话#%d: 设备未在%d秒内回执", row.SessionID, int(timeout.Seconds())), false)
+		}
+	}
+}
+
+// relockExpiredSessions 自动结束到期的解锁会话
+func (s *EmergencyUnlockService) relockExpiredSessions() {
+	var sessions []models.EmergencyUnlockSession
+	if err := s.DB.Preload("Devices").
+		Where("status = ? AND relock_at IS NOT NULL AND relock_at <= ?", models.UnlockSessionActive, time.Now()).
+		Find(&sessions).Error; err != nil {
+		log.Printf("[EmergencyUnlock] 查询到期会话失败: %v", err)
+		return
+	}
+
+	for i := range sessions {
+		if err := s.endSession(&sessions[i], models.UnlockSessionExpired, nil, "到达设定时间自动上锁"); err != nil && !errors.Is(err, ErrUnlockSessionEnded) {
+			log.Printf("[EmergencyUnlock] 自动结束会话 %d 失败: %v", sessions[i].ID, err)
+		}
+	}
+}
+
+// recordOperation 写入设备操作日志，成功与否以设备回执为准
+func (s *EmergencyUnlockService) recordOperation(deviceID uint, operationType string, userID uint, details string, success bool) {
+	operationLog := models.OperationLog{
+		OperationType: operationType,
+		DeviceID:      deviceID,
+		UserID:        userID,
+		Details:       details,
+		Timestamp:     time.Now(),
+		Success:       success,
+	}
+	if err := s.DB.Create(&operationLog).Error; err != nil {
+		log.Printf("[EmergencyUnlock] 记录设备 %d 操作日志失败: %v", deviceID, err)
+	}
+}
+
+// startUnlockCheckTask 定时检查回执超时和到期的解锁会话
+func (s *EmergencyUnlockService) startUnlockCheckTask() {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.checkAckTimeouts()
+			s.relockExpiredSessions()
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+// joinIDs 将ID列表拼接为逗号分隔的字符串
+func joinIDs(ids []uint) string {
+	parts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		parts = append(parts, strconv.FormatUint(uint64(id), 10))
+	}
+	return strings.Join(parts, ",")
+}
+
+// uniqueIDs 去除重复ID
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"ilock-http-service/internal/domain/models"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// fakeRedis 进程内的 InterfaceRedisService，值与 RedisService 一样以JSON保存，不处理过期；
+// 设置 err 后所有操作返回该错误，模拟Redis不可用
+type fakeRedis struct {
+	values map[string][]byte
+	err    error
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{values: make(map[string][]byte)}
+}
+
+func (r *fakeRedis) Set(key string, value interface{}, expiration time.Duration) error {
+	if r.err != nil {
+		return r.err
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	r.values[key] = data
+	return nil
+}
+
+func (r *fakeRedis) Get(key string, dest interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+	data, ok := r.values[key]
+	if !ok {
+		return redis.Nil
+	}
+	return json.Unmarshal(data, dest)
+}
+
+func (r *fakeRedis) Delete(key string) error {
+	if r.err != nil {
+		return r.err
+	}
+	delete(r.values, key)
+	return nil
+}
+
+func (r *fakeRedis) CacheRTCToken(userID, channelID, token string, expiration time.Duration) error {
+	return r.Set("rtc_token:"+userID+":"+channelID, token, expiration)
+}
+
+func (r *fakeRedis) GetRTCToken(userID, channelID string) (string, error) {
+	var token string
+	err := r.Get("rtc_token:"+userID+":"+channelID, &token)
+	return token, err
+}
+
+func (r *fakeRedis) GetCallRecordByID(id string) (*models.CallRecord, error) {
+	var record models.CallRecord
+	if err := r.Get("call_record:"+id, &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (r *fakeRedis) CacheCallRecord(record *models.CallRecord, expiration time.Duration) error {
+	return r.Set(fmt.Sprintf("call_record:%d", record.ID), record, expiration)
+}
+
+func (r *fakeRedis) SetNX(key string, value interface{}, expiration time.Duration) (bool, error) {
+	if r.err != nil {
+		return false, r.err
+	}
+	if _, ok := r.values[key]; ok {
+		return false, nil
+	}
+	return true, r.Set(key, value, expiration)
+}
+
+func (r *fakeRedis) Incr(key string, expiration time.Duration) (int64, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	var count int64
+	if data, ok := r.values[key]; ok {
+		var err error
+		if count, err = strconv.ParseInt(string(data), 10, 64); err != nil {
+			return 0, errors.New("value is not an integer")
+		}
+	}
+	count++
+	r.values[key] = []byte(strconv.FormatInt(count, 10))
+	return count, nil
+}
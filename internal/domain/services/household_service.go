@@ -3,6 +3,7 @@ package services
 import (
 	"errors"
 	"ilock-http-service/internal/infrastructure/config"
+	"ilock-http-service/internal/domain/events"
 	"ilock-http-service/internal/domain/models"
 
 	"gorm.io/gorm"
@@ -10,6 +11,7 @@ import (
 
 // InterfaceHouseholdService 定义户号服务接口
 type InterfaceHouseholdService interface {
+	WithPropertyScope(propertyID *uint) InterfaceHouseholdService
 	GetAllHouseholds(page, pageSize int) ([]models.Household, int64, error)
 	GetHouseholdsByBuildingID(buildingID uint, page, pageSize int) ([]models.Household, int64, error)
 	GetHouseholdByID(id uint) (*models.Household, error)
@@ -26,29 +28,39 @@ type InterfaceHouseholdService interface {
 type HouseholdService struct {
 	DB     *gorm.DB
 	Config *config.Config
+	Events *events.Bus
+	Scope  PropertyScope
 }
 
 // NewHouseholdService 创建一个新的户号服务
-func NewHouseholdService(db *gorm.DB, cfg *config.Config) InterfaceHouseholdService {
+func NewHouseholdService(db *gorm.DB, cfg *config.Config, bus *events.Bus) InterfaceHouseholdService {
 	return &HouseholdService{
 		DB:     db,
 		Config: cfg,
+		Events: bus,
 	}
 }
 
+// 0. WithPropertyScope 返回限定在指定物业内的服务
+func (s *HouseholdService) WithPropertyScope(propertyID *uint) InterfaceHouseholdService {
+	scoped := *s
+	scoped.Scope = PropertyScope{PropertyID: propertyID}
+	return &scoped
+}
+
 // 1. GetAllHouseholds 获取所有户号列表，支持分页
 func (s *HouseholdService) GetAllHouseholds(page, pageSize int) ([]models.Household, int64, error) {
 	var households []models.Household
 	var total int64
 
 	// 获取总数
-	if err := s.DB.Model(&models.Household{}).Count(&total).Error; err != nil {
+	if err := s.DB.Model(&models.Household{}).Scopes(s.Scope.Households).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
 	// 分页查询
 	offset := (page - 1) * pageSize
-	if err := s.DB.Preload("Building").Limit(pageSize).Offset(offset).Find(&households).Error; err != nil {
+	if err := s.DB.Scopes(s.Scope.Households).Preload("Building").Limit(pageSize).Offset(offset).Find(&households).Error; err != nil {
 		return nil, 0, err
 	}
 
@@ -61,13 +73,13 @@ func (s *HouseholdService) GetHouseholdsByBuildingID(buildingID uint, page, page
 	var total int64
 
 	// 获取总数
-	if err := s.DB.Model(&models.Household{}).Where("building_id = ?", buildingID).Count(&total).Error; err != nil {
+	if err := s.DB.Model(&models.Household{}).Scopes(s.Scope.Households).Where("building_id = ?", buildingID).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
 	// 分页查询
 	offset := (page - 1) * pageSize
-	if err := s.DB.Where("building_id = ?", buildingID).Preload("Building").Limit(pageSize).Offset(offset).Find(&households).Error; err != nil {
+	if err := s.DB.Scopes(s.Scope.Households).Where("building_id = ?", buildingID).Preload("Building").Limit(pageSize).Offset(offset).Find(&households).Error; err != nil {
 		return nil, 0, err
 	}
 
@@ -77,7 +89,7 @@ func (s *HouseholdService) GetHouseholdsByBuildingID(buildingID uint, page, page
 // 3. GetHouseholdByID 根据ID获取户号
 func (s *HouseholdService) GetHouseholdByID(id uint) (*models.Household, error) {
 	var household models.Household
-	if err := s.DB.Preload("Building").Preload("Devices").Preload("Residents").First(&household, id).Error; err != nil {
+	if err := s.DB.Scopes(s.Scope.Households).Preload("Building").Preload("Devices").Preload("Residents").First(&household, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("户号不存在")
 		}
@@ -88,6 +100,11 @@ func (s *HouseholdService) GetHouseholdByID(id uint) (*models.Household, error)
 
 // 4. CreateHousehold 创建新户号
 func (s *HouseholdService) CreateHousehold(household *models.Household) error {
+	// 楼号必须属于当前物业
+	if err := s.Scope.checkBuilding(s.DB, household.BuildingID); err != nil {
+		return err
+	}
+
 	// 验证户号唯一性（同一楼号下户号编号不能重复）
 	var count int64
 	if err := s.DB.Model(&models.Household{}).Where("building_id = ? AND household_number = ?", household.BuildingID, household.HouseholdNumber).Count(&count).Error; err != nil {
@@ -102,7 +119,12 @@ func (s *HouseholdService) CreateHousehold(household *models.Household) error {
 		household.Status = "active"
 	}
 
-	return s.DB.Create(household).Error
+	if err := s.DB.Create(household).Error; err != nil {
+		return err
+	}
+
+	s.Events.Publish(events.HouseholdCreated{Household: household})
+	return nil
 }
 
 // 5. UpdateHousehold 更新户号信息
@@ -116,6 +138,13 @@ func (s *HouseholdService) UpdateHousehold(id uint, updates map[string]interface
 	buildingID, hasBuildingID := updates["building_id"].(uint)
 	householdNumber, hasHouseholdNumber := updates["household_number"].(string)
 
+	// 不能把户号移到其他物业的楼号下
+	if hasBuildingID {
+		if err := s.Scope.checkBuilding(s.DB, buildingID); err != nil {
+			return nil, err
+		}
+	}
+
 	if (hasBuildingID || hasHouseholdNumber) && (hasBuildingID && buildingID != household.BuildingID || hasHouseholdNumber && householdNumber != household.HouseholdNumber) {
 		// 确定要检查的楼号ID
 		checkBuildingID := household.BuildingID
@@ -144,7 +173,13 @@ func (s *HouseholdService) UpdateHousehold(id uint, updates map[string]interface
 	}
 
 	// 重新获取更新后的户号信息
-	return s.GetHouseholdByID(id)
+	updated, err := s.GetHouseholdByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.Events.Publish(events.HouseholdUpdated{Household: updated, Changes: updates})
+	return updated, nil
 }
 
 // 6. DeleteHousehold 删除户号
@@ -172,7 +207,12 @@ func (s *HouseholdService) DeleteHousehold(id uint) error {
 		return errors.New("该户号下存在关联设备，请先解除关联")
 	}
 
-	return s.DB.Delete(household).Error
+	if err := s.DB.Delete(household).Error; err != nil {
+		return err
+	}
+
+	s.Events.Publish(events.HouseholdDeleted{HouseholdID: id})
+	return nil
 }
 
 // 7. GetHouseholdDevices 获取户号关联的设备
@@ -222,6 +262,11 @@ func (s *HouseholdService) AssociateHouseholdWithDevice(householdID, deviceID ui
 		return err
 	}
 
+	// 设备必须属于当前物业
+	if err := s.Scope.checkDevice(s.DB, deviceID); err != nil {
+		return err
+	}
+
 	// 直接更新设备的household_id字段
 	if err := s.DB.Model(&device).Update("household_id", householdID).Error; err != nil {
 		return err
@@ -0,0 +1,446 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"ilock-http-service/internal/domain/events"
+	"ilock-http-service/internal/domain/models"
+	"ilock-http-service/internal/infrastructure/config"
+	"ilock-http-service/internal/infrastructure/database"
+	"net/mail"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// InterfaceImportService 定义批量导入服务接口
+type InterfaceImportService interface {
+	WithPropertyScope(propertyID *uint) InterfaceImportService
+	Import(kind string, rows [][]string, dryRun bool) (*ImportReport, error)
+}
+
+// 导入类型
+const (
+	ImportBuildings  = "buildings"
+	ImportHouseholds = "households"
+	ImportResidents  = "residents"
+)
+
+// importMaxRows 单次导入的最大数据行数
+const importMaxRows = 5000
+
+var (
+	// ErrImportKindInvalid 导入类型无效
+	ErrImportKindInvalid = errors.New("导入类型只能是 buildings、households 或 residents")
+	// ErrImportHeaderInvalid 表头缺少必填列或列名重复
+	ErrImportHeaderInvalid = errors.New("导入文件的表头无效")
+	// ErrImportEmpty 文件中没有数据行
+	ErrImportEmpty = errors.New("导入文件中没有数据行")
+	// ErrImportTooManyRows 数据行超过单次导入上限
+	ErrImportTooManyRows = fmt.Errorf("单次最多导入 %d 行", importMaxRows)
+	// ErrImportRowsInvalid 有数据行校验失败，整个导入已回滚
+	ErrImportRowsInvalid = errors.New("导入数据校验失败，没有写入任何数据")
+
+	// errImportRollback 试运行结束后回滚事务
+	errImportRollback = errors.New("试运行，回滚导入")
+)
+
+// ImportRowError 一行数据的错误，Column 为空表示整行的错误
+type ImportRowError struct {
+	Row     int    `json:"row"` // 文件中的行号，表头为第1行
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// ImportReport 导入结果，试运行时只校验不写入
+type ImportReport struct {
+	Kind      string           `json:"type"`
+	DryRun    bool             `json:"dry_run"`
+	Total     int              `json:"total"`     // 数据行数，不含表头和空行
+	Succeeded int              `json:"succeeded"` // 校验通过的行数
+	Failed    int              `json:"failed"`    // 校验失败的行数
+	Committed bool             `json:"committed"` // 数据是否已经写入
+	Errors    []ImportRowError `json:"errors"`
+}
+
+// importColumn 导入文件中的一列
+type importColumn struct {
+	Name     string // 英文列名
+	Alias    string // 中文列名
+	Required bool
+	MaxLen   int // 最大字符数，0 表示不限制
+}
+
+// importColumns 各导入类型的列，列名不区分大小写，英文列名和中文列名都可以使用
+var importColumns = map[string][]importColumn{
+	ImportBuildings: {
+		{Name: "building_code", Alias: "楼号编码", Required: true, MaxLen: 20},
+		{Name: "building_name", Alias: "楼号名称", Required: true, MaxLen: 50},
+		{Name: "address", Alias: "地址", MaxLen: 200},
+		{Name: "status", Alias: "状态"},
+		{Name: "property_id", Alias: "物业ID"},
+	},
+	ImportHouseholds: {
+		{Name: "building_code", Alias: "楼号编码", Required: true, MaxLen: 20},
+		{Name: "household_number", Alias: "户号", Required: true, MaxLen: 50},
+		{Name: "status", Alias: "状态"},
+	},
+	ImportResidents: {
+		{Name: "building_code", Alias: "楼号编码", Required: true, MaxLen: 20},
+		{Name: "household_number", Alias: "户号", Required: true, MaxLen: 50},
+		{Name: "name", Alias: "姓名", Required: true, MaxLen: 50},
+		{Name: "phone", Alias: "手机号", Required: true, MaxLen: 20},
+		{Name: "email", Alias: "邮箱", MaxLen: 100},
+	},
+}
+
+// importStatuses 楼号和户号可以导入的状态
+var importStatuses = map[string]bool{"active": true, "inactive": true}
+
+// importRecord 一行数据，键为英文列名
+type importRecord struct {
+	Row    int
+	Values map[string]string
+}
+
+// ImportService 从CSV或XLSX文件批量导入楼号、户号和居民。
+// 每一行通过楼号、户号和居民服务创建，遵循与接口相同的校验规则；所有行在同一个事务中写入，任意一行失败时整体回滚
+type ImportService struct {
+	DB     *gorm.DB
+	Config *config.Config
+	Pool   *database.ConnectionPool
+	Events *events.Bus
+	Scope  PropertyScope
+}
+
+// NewImportService 创建一个新的批量导入服务
+func NewImportService(db *gorm.DB, cfg *config.Config, pool *database.ConnectionPool, bus *events.Bus) InterfaceImportService {
+	return &ImportService{
+		DB:     db,
+		Config: cfg,
+		Pool:   pool,
+		Events: bus,
+	}
+}
+
+// 0 WithPropertyScope 返回限定在指定物业内的服务，楼号固定导入到该物业，户号和居民只能导入到该物业的楼号下
+func (s *ImportService) WithPropertyScope(propertyID *uint) InterfaceImportService {
+	scoped := *s
+	scoped.Scope = PropertyScope{PropertyID: propertyID}
+	return &scoped
+}
+
+// 1 Import 导入文件中的数据行，第一行为表头。
+// 所有行在一个事务中逐行校验并创建，后面的行可以看到前面的行创建的数据；有任意一行失败时回滚并返回 ErrImportRowsInvalid 和逐行的错误，
+// dryRun 为真时校验完成后同样回滚
+func (s *ImportService) Import(kind string, rows [][]string, dryRun bool) (*ImportReport, error) {
+	columns, ok := importColumns[kind]
+	if !ok {
+		return nil, ErrImportKindInvalid
+	}
+	records, err := parseImportRows(rows, columns)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ImportReport{Kind: kind, DryRun: dryRun, Total: len(records), Errors: []ImportRowError{}}
+	var created []events.Event
+	err = s.Pool.WithTransaction(func(tx *gorm.DB) error {
+		importer := &rowImporter{tx: tx, service: s, buildings: make(map[string]uint)}
+		var err error
+		created, err = importRecords(report, records, columns, dryRun, func(record importRecord) (events.Event, error) {
+			return importer.importRow(kind, record)
+		})
+		return err
+	})
+	return s.finishImport(report, created, err)
+}
+
+// importRecords 逐行校验并创建数据，把每行的结果计入报告；
+// 返回的错误决定事务是否回滚：试运行返回 errImportRollback，有失败的行返回 ErrImportRowsInvalid
+func importRecords(report *ImportReport, records []importRecord, columns []importColumn, dryRun bool,
+	create func(importRecord) (events.Event, error)) ([]events.Event, error) {
+	var created []events.Event
+	for _, record := range records {
+		rowErrors := validateImportRecord(record, columns)
+		if len(rowErrors) == 0 {
+			event, err := create(record)
+			if err != nil {
+				rowErrors = append(rowErrors, ImportRowError{Row: record.Row, Message: err.Error()})
+			} else if event != nil {
+				created = append(created, event)
+			}
+		}
+
+		if len(rowErrors) > 0 {
+			report.Failed++
+			report.Errors = append(report.Errors, rowErrors...)
+		} else {
+			report.Succeeded++
+		}
+	}
+
+	if dryRun {
+		return created, errImportRollback
+	}
+	if report.Failed > 0 {
+		return created, ErrImportRowsInvalid
+	}
+	return created, nil
+}
+
+// finishImport 根据事务的结果完成报告，事务提交后才发布创建事件
+func (s *ImportService) finishImport(report *ImportReport, created []events.Event, err error) (*ImportReport, error) {
+	switch {
+	case err == nil:
+		report.Committed = true
+		// 提交后再发布创建事件，回滚的数据不会触发Webhook
+		for _, event := range created {
+			s.Events.Publish(event)
+		}
+		return report, nil
+	case errors.Is(err, errImportRollback):
+		return report, nil
+	case errors.Is(err, ErrImportRowsInvalid):
+		return report, ErrImportRowsInvalid
+	default:
+		return nil, err
+	}
+}
+
+// rowImporter 在导入事务中逐行创建数据
+type rowImporter struct {
+	tx      *gorm.DB
+	service *ImportService
+	// buildings 已查到的楼号编码对应的楼号ID
+	buildings map[string]uint
+}
+
+// importRow 在保存点内创建一行数据，失败时回滚到保存点，不影响其他行
+func (r *rowImporter) importRow(kind string, record importRecord) (events.Event, error) {
+	if err := r.tx.SavePoint("import_row").Error; err != nil {
+		return nil, err
+	}
+
+	var event events.Event
+	var err error
+	switch kind {
+	case ImportBuildings:
+		err = r.createBuilding(record.Values)
+	case ImportHouseholds:
+		event, err = r.createHousehold(record.Values)
+	case ImportResidents:
+		event, err = r.createResident(record.Values)
+	}
+	if err != nil {
+		if rollbackErr := r.tx.RollbackTo("import_row").Error; rollbackErr != nil {
+			return nil, rollbackErr
+		}
+		return nil, err
+	}
+	return event, nil
+}
+
+// createBuilding 通过楼号服务创建楼号，限定物业时忽略 property_id 列
+func (r *rowImporter) createBuilding(values map[string]string) error {
+	building := &models.Building{
+		BuildingCode: values["building_code"],
+		BuildingName: values["building_name"],
+		Address:      values["address"],
+		Status:       values["status"],
+	}
+	if value := values["property_id"]; value != "" {
+		id, _ := strconv.ParseUint(value, 10, 64)
+		propertyID := uint(id)
+		building.PropertyID = &propertyID
+	}
+
+	service := &BuildingService{DB: r.tx, Config: r.service.Config, Scope: r.service.Scope}
+	return service.CreateBuilding(building)
+}
+
+// createHousehold 通过户号服务在楼号编码对应的楼号下创建户号
+func (r *rowImporter) createHousehold(values map[string]string) (events.Event, error) {
+	buildingID, err := r.buildingID(values["building_code"])
+	if err != nil {
+		return nil, err
+	}
+	household := &models.Household{
+		HouseholdNumber: values["household_number"],
+		BuildingID:      buildingID,
+		Status:          values["status"],
+	}
+
+	// 不传事件总线，事务提交后再发布
+	service := &HouseholdService{DB: r.tx, Config: r.service.Config, Scope: r.service.Scope}
+	if err := service.CreateHousehold(household); err != nil {
+		return nil, err
+	}
+	return events.HouseholdCreated{Household: household}, nil
+}
+
+// createResident 通过居民服务在楼号编码和户号对应的户号下创建居民，居民可以使用短信验证码登录或找回密码后设置密码
+func (r *rowImporter) createResident(values map[string]string) (events.Event, error) {
+	buildingID, err := r.buildingID(values["building_code"])
+	if err != nil {
+		return nil, err
+	}
+	var household models.Household
+	if err := r.tx.Select("id").Where("building_id = ? AND household_number = ?", buildingID, values["household_number"]).
+		First(&household).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("楼号 %s 下不存在户号 %s", values["building_code"], values["household_number"])
+		}
+		return nil, err
+	}
+	resident := &models.Resident{
+		Name:        values["name"],
+		Phone:       values["phone"],
+		Email:       values["email"],
+		HouseholdID: household.ID,
+	}
+
+	service := &ResidentService{DB: r.tx, Config: r.service.Config, Scope: r.service.Scope}
+	if err := service.CreateResident(resident); err != nil {
+		return nil, err
+	}
+	return events.ResidentCreated{Resident: resident}, nil
+}
+
+// buildingID 查找当前物业内楼号编码对应的楼号，其他物业的楼号视为不存在
+func (r *rowImporter) buildingID(code string) (uint, error) {
+	if id, ok := r.buildings[code]; ok {
+		return id, nil
+	}
+	var building models.Building
+	if err := r.tx.Select("id").Scopes(r.service.Scope.Buildings).Where("building_code = ?", code).First(&building).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, fmt.Errorf("楼号 %s 不存在", code)
+		}
+		return 0, err
+	}
+	r.buildings[code] = building.ID
+	return building.ID, nil
+}
+
+// parseImportRows 按表头把数据行转换为记录，跳过空行
+func parseImportRows(rows [][]string, columns []importColumn) ([]importRecord, error) {
+	headerRow := -1
+	for i, row := range rows {
+		if !blankRow(row) {
+			headerRow = i
+			break
+		}
+	}
+	if headerRow < 0 {
+		return nil, ErrImportEmpty
+	}
+
+	positions, err := mapImportHeader(rows[headerRow], columns)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []importRecord
+	for i := headerRow + 1; i < len(rows); i++ {
+		if blankRow(rows[i]) {
+			continue
+		}
+		if len(records) == importMaxRows {
+			return nil, ErrImportTooManyRows
+		}
+		values := make(map[string]string, len(positions))
+		for name, position := range positions {
+			if position < len(rows[i]) {
+				values[name] = strings.TrimSpace(rows[i][position])
+			}
+		}
+		records = append(records, importRecord{Row: i + 1, Values: values})
+	}
+	if len(records) == 0 {
+		return nil, ErrImportEmpty
+	}
+	return records, nil
+}
+
+// mapImportHeader 找到每一列在表头中的位置，未知的列被忽略
+func mapImportHeader(header []string, columns []importColumn) (map[string]int, error) {
+	names := make(map[string]string, len(columns)*2)
+	for _, column := range columns {
+		names[column.Name] = column.Name
+		names[strings.ToLower(column.Alias)] = column.Name
+	}
+
+	positions := make(map[string]int, len(columns))
+	for i, title := range header {
+		name, ok := names[strings.ToLower(strings.TrimSpace(title))]
+		if !ok {
+			continue
+		}
+		if _, exists := positions[name]; exists {
+			return nil, fmt.Errorf("%w: 列 %s 重复", ErrImportHeaderInvalid, name)
+		}
+		positions[name] = i
+	}
+
+	var missing []string
+	for _, column := range columns {
+		if _, ok := positions[column.Name]; column.Required && !ok {
+			missing = append(missing, column.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: 缺少列 %s", ErrImportHeaderInvalid, strings.Join(missing, "、"))
+	}
+	return positions, nil
+}
+
+// validateImportRecord 校验一行的必填列、长度和格式，数据库相关的规则由各服务在创建时校验
+func validateImportRecord(record importRecord, columns []importColumn) []ImportRowError {
+	var rowErrors []ImportRowError
+	fail := func(column, message string) {
+		rowErrors = append(rowErrors, ImportRowError{Row: record.Row, Column: column, Message: message})
+	}
+
+	for _, column := range columns {
+		value := record.Values[column.Name]
+		if value == "" {
+			if column.Required {
+				fail(column.Name, "不能为空")
+			}
+			continue
+		}
+		if column.MaxLen > 0 && utf8.RuneCountInString(value) > column.MaxLen {
+			fail(column.Name, fmt.Sprintf("不能超过%d个字符", column.MaxLen))
+			continue
+		}
+
+		switch column.Name {
+		case "status":
+			if !importStatuses[value] {
+				fail(column.Name, "只能是 active 或 inactive")
+			}
+		case "property_id":
+			if id, err := strconv.ParseUint(value, 10, 64); err != nil || id == 0 {
+				fail(column.Name, "必须是正整数")
+			}
+		case "email":
+			if address, err := mail.ParseAddress(value); err != nil || address.Address != value {
+				fail(column.Name, "邮箱格式不正确")
+			}
+		}
+	}
+	return rowErrors
+}
+
+// blankRow 是否为空行
+func blankRow(row []string) bool {
+	for _, value := range row {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
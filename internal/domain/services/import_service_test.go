@@ -0,0 +1,239 @@
+package services
+
+import (
+	"errors"
+	"ilock-http-service/internal/domain/events"
+	"ilock-http-service/internal/domain/models"
+	"ilock-http-service/internal/infrastructure/spreadsheet"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// readImportFixture 读取 testdata/import 下的CSV文件
+func readImportFixture(t *testing.T, name string) [][]string {
+	t.Helper()
+	file, err := os.Open(filepath.Join("testdata", "import", name))
+	if err != nil {
+		t.Fatalf("open fixture: %v", err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		t.Fatalf("stat fixture: %v", err)
+	}
+	rows, err := spreadsheet.ReadRows(file, info.Size(), spreadsheet.FormatCSV)
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	return rows
+}
+
+// fakeHouseholdCreator 代替户号服务，同一楼号下户号重复时返回与户号服务相同的错误
+func fakeHouseholdCreator() (func(importRecord) (events.Event, error), *[]string) {
+	var created []string
+	seen := make(map[string]bool)
+	return func(record importRecord) (events.Event, error) {
+		key := record.Values["building_code"] + "/" + record.Values["household_number"]
+		if seen[key] {
+			return nil, errors.New("该楼号下已存在相同户号")
+		}
+		seen[key] = true
+		created = append(created, key)
+		return events.HouseholdCreated{Household: &models.Household{HouseholdNumber: record.Values["household_number"]}}, nil
+	}, &created
+}
+
+func TestMapImportHeader(t *testing.T) {
+	columns := importColumns[ImportResidents]
+
+	tests := []struct {
+		name    string
+		header  []string
+		want    map[string]int
+		wantErr error
+	}{
+		{
+			name:   "english names",
+			header: []string{"building_code", "household_number", "name", "phone", "email"},
+			want:   map[string]int{"building_code": 0, "household_number": 1, "name": 2, "phone": 3, "email": 4},
+		},
+		{
+			name:   "chinese aliases in any order with unknown columns",
+			header: []string{"备注", "手机号", " 姓名 ", "户号", "楼号编码"},
+			want:   map[string]int{"phone": 1, "name": 2, "household_number": 3, "building_code": 4},
+		},
+		{
+			name:   "names are case insensitive",
+			header: []string{"Building_Code", "HOUSEHOLD_NUMBER", "Name", "Phone"},
+			want:   map[string]int{"building_code": 0, "household_number": 1, "name": 2, "phone": 3},
+		},
+		{name: "missing required column", header: []string{"building_code", "household_number", "name"}, wantErr: ErrImportHeaderInvalid},
+		{name: "duplicate column via alias", header: []string{"building_code", "楼号编码", "household_number", "name", "phone"}, wantErr: ErrImportHeaderInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mapImportHeader(tt.header, columns)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("positions = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseImportRowsMissingColumn(t *testing.T) {
+	_, err := parseImportRows(readImportFixture(t, "households_missing_column.csv"), importColumns[ImportHouseholds])
+	if !errors.Is(err, ErrImportHeaderInvalid) {
+		t.Fatalf("err = %v, want ErrImportHeaderInvalid", err)
+	}
+}
+
+func TestParseImportRowsSkipsBlankRows(t *testing.T) {
+	records, err := parseImportRows(readImportFixture(t, "households_blank_rows.csv"), importColumns[ImportHouseholds])
+	if err != nil {
+		t.Fatalf("parseImportRows: %v", err)
+	}
+
+	want := []importRecord{
+		{Row: 3, Values: map[string]string{"building_code": "B1", "household_number": "101", "status": "active"}},
+		{Row: 5, Values: map[string]string{"building_code": "B1", "household_number": "102", "status": ""}},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Fatalf("records = %+v, want %+v", records, want)
+	}
+}
+
+func TestParseImportRowsEmpty(t *testing.T) {
+	columns := importColumns[ImportHouseholds]
+	if _, err := parseImportRows([][]string{{"", " "}, {}}, columns); !errors.Is(err, ErrImportEmpty) {
+		t.Fatalf("blank file: err = %v, want ErrImportEmpty", err)
+	}
+	if _, err := parseImportRows([][]string{{"building_code", "household_number"}, {"", ""}}, columns); !errors.Is(err, ErrImportEmpty) {
+		t.Fatalf("header only: err = %v, want ErrImportEmpty", err)
+	}
+}
+
+func TestValidateImportRecord(t *testing.T) {
+	rows := readImportFixture(t, "residents_chinese_header.csv")
+	columns := importColumns[ImportResidents]
+	records, err := parseImportRows(rows, columns)
+	if err != nil {
+		t.Fatalf("parseImportRows: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("records = %d, want 2", len(records))
+	}
+
+	if rowErrors := validateImportRecord(records[0], columns); len(rowErrors) != 0 {
+		t.Fatalf("valid row: errors = %+v", rowErrors)
+	}
+	want := []ImportRowError{
+		{Row: 3, Column: "name", Message: "不能为空"},
+		{Row: 3, Column: "email", Message: "邮箱格式不正确"},
+	}
+	if got := validateImportRecord(records[1], columns); !reflect.DeepEqual(got, want) {
+		t.Fatalf("errors = %+v, want %+v", got, want)
+	}
+
+	building := importRecord{Row: 2, Values: map[string]string{
+		"building_code": "B-0000000000000000001",
+		"building_name": "1号楼",
+		"status":        "closed",
+		"property_id":   "0",
+	}}
+	want = []ImportRowError{
+		{Row: 2, Column: "building_code", Message: "不能超过20个字符"},
+		{Row: 2, Column: "status", Message: "只能是 active 或 inactive"},
+		{Row: 2, Column: "property_id", Message: "必须是正整数"},
+	}
+	if got := validateImportRecord(building, importColumns[ImportBuildings]); !reflect.DeepEqual(got, want) {
+		t.Fatalf("building errors = %+v, want %+v", got, want)
+	}
+}
+
+func TestImportRecordsReportsRowErrors(t *testing.T) {
+	columns := importColumns[ImportHouseholds]
+	records, err := parseImportRows(readImportFixture(t, "households_duplicate.csv"), columns)
+	if err != nil {
+		t.Fatalf("parseImportRows: %v", err)
+	}
+	create, created := fakeHouseholdCreator()
+
+	report := &ImportReport{Total: len(records), Errors: []ImportRowError{}}
+	_, err = importRecords(report, records, columns, false, create)
+	if !errors.Is(err, ErrImportRowsInvalid) {
+		t.Fatalf("err = %v, want ErrImportRowsInvalid so the transaction rolls back", err)
+	}
+
+	if report.Succeeded != 2 || report.Failed != 2 {
+		t.Fatalf("succeeded = %d, failed = %d, want 2 and 2", report.Succeeded, report.Failed)
+	}
+	want := []ImportRowError{
+		{Row: 4, Message: "该楼号下已存在相同户号"},
+		{Row: 5, Column: "status", Message: "只能是 active 或 inactive"},
+	}
+	if !reflect.DeepEqual(report.Errors, want) {
+		t.Fatalf("errors = %+v, want %+v", report.Errors, want)
+	}
+	// 校验失败的行不会交给服务创建
+	if !reflect.DeepEqual(*created, []string{"B1/101", "B1/102"}) {
+		t.Fatalf("created = %v", *created)
+	}
+}
+
+func TestImportDryRunReportsWithoutCommitting(t *testing.T) {
+	columns := importColumns[ImportHouseholds]
+	records, err := parseImportRows(readImportFixture(t, "households_duplicate.csv"), columns)
+	if err != nil {
+		t.Fatalf("parseImportRows: %v", err)
+	}
+	create, _ := fakeHouseholdCreator()
+	bus := events.NewBus()
+	published := 0
+	bus.Subscribe(events.HouseholdCreatedEvent, func(events.Event) { published++ })
+	service := &ImportService{Events: bus}
+
+	report := &ImportReport{Kind: ImportHouseholds, DryRun: true, Total: len(records), Errors: []ImportRowError{}}
+	createdEvents, txErr := importRecords(report, records, columns, true, create)
+	if !errors.Is(txErr, errImportRollback) {
+		t.Fatalf("dry run returned %v to the transaction, want errImportRollback", txErr)
+	}
+
+	report, err = service.finishImport(report, createdEvents, txErr)
+	if err != nil {
+		t.Fatalf("finishImport: %v", err)
+	}
+	if report.Committed {
+		t.Fatal("dry run report should not be committed")
+	}
+	// 试运行有失败的行时同样只返回报告，不返回错误
+	if report.Succeeded != 2 || report.Failed != 2 || len(report.Errors) != 2 {
+		t.Fatalf("succeeded = %d, failed = %d, errors = %+v", report.Succeeded, report.Failed, report.Errors)
+	}
+	if published != 0 {
+		t.Fatalf("dry run published %d events, want 0", published)
+	}
+}
+
+func TestFinishImportPublishesAfterCommit(t *testing.T) {
+	bus := events.NewBus()
+	published := 0
+	bus.Subscribe(events.HouseholdCreatedEvent, func(events.Event) { published++ })
+	service := &ImportService{Events: bus}
+	created := []events.Event{events.HouseholdCreated{Household: &models.Household{}}}
+
+	report, err := service.finishImport(&ImportReport{Errors: []ImportRowError{}}, created, nil)
+	if err != nil || !report.Committed || published != 1 {
+		t.Fatalf("commit: err = %v, committed = %v, published = %d", err, report.Committed, published)
+	}
+
+	published = 0
+	report, err = service.finishImport(&ImportReport{Failed: 1, Errors: []ImportRowError{}}, created, ErrImportRowsInvalid)
+	if !errors.Is(err, ErrImportRowsInvalid) || report == nil || report.Committed || published != 0 {
+		t.Fatalf("rollback: err = %v, report = %+v, published = %d", err, report, published)
+	}
+}
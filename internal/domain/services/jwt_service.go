@@ -1,12 +1,18 @@
 package services
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"ilock-http-service/internal/domain/models"
 	"ilock-http-service/internal/infrastructure/config"
+	"ilock-http-service/pkg/utils"
+	"log"
+	"strconv"
 	"time"
 
+	"github.com/go-redis/redis/v8"
 	"github.com/golang-jwt/jwt/v4"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
@@ -17,24 +23,106 @@ type InterfaceJWTService interface {
 	GenerateToken(userID uint, role string, propertyID, deviceID *uint) (string, error)
 	ValidateToken(tokenString string) (*jwt.Token, error)
 	ExtractClaims(tokenString string) (*JWTClaims, error)
-	Login(username, password string) (*LoginResult, error)
+	Login(username, password string, client ClientInfo) (*LoginResult, error)
+	VerifyTwoFactor(challengeToken, code string, client ClientInfo) (*LoginResult, error)
+	IssueTokens(userID uint, role string, propertyID *uint, client ClientInfo) (*TokenPair, error)
+	RefreshTokens(refreshToken string, client ClientInfo) (*TokenPair, error)
+	Logout(claims jwt.MapClaims) error
+	RevokeAllTokens(role string, userID uint) error
+	IsTokenRevoked(claims jwt.MapClaims) (bool, error)
+	IssueDeviceToken(deviceID uint) (*DeviceToken, error)
 }
 
-// LoginResult 表示登录结果
+var (
+	// ErrRefreshTokenInvalid 刷新令牌无效或已过期
+	ErrRefreshTokenInvalid = errors.New("刷新令牌无效或已过期")
+	// ErrRefreshTokenReused 已轮换的刷新令牌被再次使用，整个登录会话被注销
+	ErrRefreshTokenReused = errors.New("刷新令牌已被使用，登录会话已失效，请重新登录")
+	// ErrInvalidCredentials 用户名或密码错误
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	// ErrAccountDisabled 账号已被停用或手动锁定
+	ErrAccountDisabled = errors.New("账号已被停用或锁定，请联系管理员")
+	// ErrTwoFactorChallengeInvalid 两步验证凭证无效、过期或输错次数过多，需要重新输入密码
+	ErrTwoFactorChallengeInvalid = errors.New("两步验证已过期，请重新登录")
+)
+
+// 令牌撤销相关的Redis键前缀
+const (
+	tokenDenylistPrefix      = "jwt:denylist:"       // 已注销的访问令牌ID
+	tokenRevokedBeforePrefix = "jwt:revoked_before:" // 账号在此时间（毫秒）之前签发的访问令牌全部失效
+)
+
+// 两步验证登录的Redis键前缀
+const (
+	twoFactorChallengePrefix   = "login:2fa:"          // 密码验证通过、等待输入验证码的登录
+	twoFactorAttemptsPrefix    = "login:2fa:attempts:" // 验证码输错次数
+	twoFactorChallengeMaxTries = 5                     // 同一次登录最多可以输错几次验证码
+)
+
+// DeviceRole 设备令牌的角色，user_id 和 device_id 都是设备ID
+const DeviceRole = "device"
+
+// ClientInfo 签发令牌时记录的客户端信息
+type ClientInfo struct {
+	UserAgent string
+	IP        string
+}
+
+// TokenPair 表示登录或刷新后返回的令牌
+type TokenPair struct {
+	AccessToken      string    `json:"token"`
+	RefreshToken     string    `json:"refresh_token"`
+	ExpiresIn        int       `json:"expires_in"` // 访问令牌有效秒数
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+	UserID           uint      `json:"user_id"`
+	Role             string    `json:"role"`
+	PropertyID       *uint     `json:"property_id"`
+	// 为真时访问令牌只能用于修改密码和注销，修改密码后重新登录
+	PasswordChangeRequired bool `json:"password_change_required,omitempty"`
+	// 为真时访问令牌只能用于绑定两步验证、修改密码和注销，绑定后重新登录
+	TwoFactorSetupRequired bool `json:"two_factor_setup_required,omitempty"`
+}
+
+// DeviceToken 表示设备认证后返回的令牌，设备令牌没有刷新令牌，过期后重新认证
+type DeviceToken struct {
+	Token     string `json:"token"`
+	ExpiresIn int    `json:"expires_in"` // 令牌有效秒数
+	DeviceID  uint   `json:"device_id"`
+}
+
+// LoginResult 表示登录结果，包含新签发的令牌；
+// 启用两步验证的管理员密码验证通过后不签发令牌，返回两步验证凭证
 type LoginResult struct {
-	Token     string      `json:"token"`
-	UserID    uint        `json:"user_id"`
-	Role      string      `json:"role"`
+	*TokenPair
 	Username  string      `json:"username"`
 	Phone     string      `json:"phone,omitempty"`
-	CreatedAt interface{} `json:"created_at"`
+	CreatedAt interface{} `json:"created_at,omitempty"`
+
+	TwoFactorRequired  bool   `json:"two_factor_required,omitempty"`
+	TwoFactorToken     string `json:"two_factor_token,omitempty"`      // 提交验证码时使用的一次性凭证
+	TwoFactorExpiresIn int    `json:"two_factor_expires_in,omitempty"` // 凭证有效秒数
+}
+
+// twoFactorChallenge 保存在Redis中的待完成两步验证的登录
+type twoFactorChallenge struct {
+	AdminID  uint   `json:"admin_id"`
+	Username string `json:"username"` // 登录时输入的用户名，用于失败计数
 }
 
 // JWTService 提供JWT相关服务
 type JWTService struct {
-	secretKey string
-	issuer    string
-	DB        *gorm.DB
+	secretKey  string
+	issuer     string
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	DB         *gorm.DB
+	Redis      InterfaceRedisService
+	Guard      InterfaceLoginGuardService
+	TwoFactor  InterfaceTwoFactorService
+	// 密码验证通过后输入两步验证码的有效期
+	twoFactorTTL time.Duration
+	// 默认管理员密码，使用该密码登录的管理员必须先修改密码
+	defaultAdminPassword string
 }
 
 // JWTClaims 定义JWT令牌的声明结构
@@ -43,37 +131,56 @@ type JWTClaims struct {
 	Role       string `json:"role"`
 	PropertyID *uint  `json:"property_id,omitempty"` // 物业ID，用于标识用户所属物业
 	DeviceID   *uint  `json:"device_id,omitempty"`
+	SessionID  string `json:"sid,omitempty"` // 登录会话ID，与刷新令牌对应
+	// 账号必须先修改密码，认证中间件只放行修改密码和注销接口
+	PasswordChangeRequired bool `json:"pwd_change,omitempty"`
+	// 账号必须先绑定两步验证，认证中间件只放行两步验证、修改密码和注销接口
+	TwoFactorSetupRequired bool `json:"mfa_setup,omitempty"`
+	// 签发时间的毫秒数，iat 只精确到秒，注销全部会话后同一秒内重新登录签发的令牌需要按毫秒区分
+	IssuedAtMilli int64 `json:"iat_ms,omitempty"`
 	jwt.RegisteredClaims
 }
 
 // NewJWTService 创建一个新的JWT服务
-func NewJWTService(cfg *config.Config, db *gorm.DB) InterfaceJWTService {
+func NewJWTService(cfg *config.Config, db *gorm.DB, redisService InterfaceRedisService, loginGuard InterfaceLoginGuardService, twoFactor InterfaceTwoFactorService) InterfaceJWTService {
 	return &JWTService{
-		secretKey: cfg.JWTSecretKey,
-		issuer:    "ilock-http-service",
-		DB:        db,
+		secretKey:  cfg.JWTSecretKey,
+		issuer:     "ilock-http-service",
+		accessTTL:  time.Duration(cfg.JWTAccessTokenTTL) * time.Minute,
+		refreshTTL: time.Duration(cfg.JWTRefreshTokenTTL) * time.Hour,
+		DB:         db,
+		Redis:      redisService,
+		Guard:      loginGuard,
+		TwoFactor:  twoFactor,
+
+		twoFactorTTL:         time.Duration(cfg.TwoFactorChallengeTTL) * time.Second,
+		defaultAdminPassword: cfg.DefaultAdminPassword,
 	}
 }
 
-// GenerateToken 生成JWT令牌
+// GenerateToken 生成访问令牌，不关联登录会话
 func (s *JWTService) GenerateToken(userID uint, role string, propertyID, deviceID *uint) (string, error) {
-	// 令牌有效期为24小时
-	expirationTime := time.Now().Add(24 * time.Hour)
-
-	claims := &JWTClaims{
-		UserID:     userID,
-		Role:       role,
-		PropertyID: propertyID,
-		DeviceID:   deviceID,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-			Issuer:    s.issuer,
-		},
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return s.signAccessToken(JWTClaims{UserID: userID, Role: role, PropertyID: propertyID, DeviceID: deviceID})
+}
+
+// signAccessToken 签发访问令牌，每个令牌带有唯一ID（jti），用于注销
+func (s *JWTService) signAccessToken(claims JWTClaims) (string, error) {
+	tokenID, err := utils.RandomHex(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims.IssuedAtMilli = now.UnixMilli()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        tokenID,
+		ExpiresAt: jwt.NewNumericDate(now.Add(s.accessTTL)),
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		Issuer:    s.issuer,
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &claims)
 	return token.SignedString([]byte(s.secretKey))
 }
 
@@ -131,108 +238,572 @@ func (s *JWTService) ExtractClaims(tokenString string) (*JWTClaims, error) {
 	return nil, errors.New("invalid token claims")
 }
 
-// Login 处理用户登录请求
-func (s *JWTService) Login(username, password string) (*LoginResult, error) {
-	// 尝试查找管理员用户
-	var admin models.Admin
-	if err := s.DB.Where("username = ?", username).First(&admin).Error; err == nil {
-		// 比较密码
-		if err := bcrypt.CompareHashAndPassword([]byte(admin.Password), []byte(password)); err == nil {
-			// 生成管理员令牌
-			token, err := s.GenerateToken(admin.ID, "admin", nil, nil)
+// Login 处理用户登录请求：依次匹配管理员用户名、物业员工用户名或手机号和居民手机号，
+// 登录前检查IP封禁和递增延迟，密码正确后检查该账号是否被锁定，失败时按用户名和IP计数，成功后开启新的登录会话；
+// 启用两步验证的管理员返回两步验证凭证，调用 VerifyTwoFactor 提交验证码后才签发令牌
+func (s *JWTService) Login(username, password string, client ClientInfo) (*LoginResult, error) {
+	if err := s.Guard.CheckIP(client.IP); err != nil {
+		return nil, err
+	}
+	if err := s.Guard.CheckUsername(username); err != nil {
+		return nil, err
+	}
+
+	candidates, err := s.findLoginCandidates(username)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, candidate := range candidates {
+		if bcrypt.CompareHashAndPassword([]byte(candidate.password), []byte(password)) != nil {
+			continue
+		}
+		// 只检查密码匹配的账号是否被锁定，同一登录名下其他人的账号被锁定不影响本人登录
+		if err := s.Guard.CheckAccount(candidate.account); err != nil {
+			return nil, err
+		}
+		// 密码正确后才提示账号被停用，避免泄露账号状态
+		if candidate.disabled {
+			return nil, ErrAccountDisabled
+		}
+
+		// 仍在使用部署配置中默认密码的管理员必须先修改密码
+		if candidate.account.SubjectType == "admin" && s.defaultAdminPassword != "" && password == s.defaultAdminPassword {
+			if err := s.DB.Model(&models.Admin{}).Where("id = ?", candidate.account.SubjectID).
+				Update("must_change_password", true).Error; err != nil {
+				return nil, err
+			}
+		}
+
+		if candidate.account.SubjectType == "admin" {
+			enabled, err := s.TwoFactor.IsEnabled(candidate.account.SubjectID)
 			if err != nil {
 				return nil, err
 			}
+			if enabled {
+				// 验证码通过后才清除失败计数
+				return s.startTwoFactorChallenge(candidate, username)
+			}
+		}
+
+		s.Guard.RecordSuccess(username)
+		return s.completeLogin(candidate, client)
+	}
 
-			return &LoginResult{
-				Token:     token,
-				UserID:    admin.ID,
-				Role:      "admin",
-				Username:  admin.Username,
-				CreatedAt: admin.CreatedAt,
-			}, nil
+	// 登录名只对应一个账号时失败计入该账号，达到阈值后锁定；对应多个账号时无法判断尝试的是哪个账号，
+	// 只按登录名和IP计数，避免猜测一个人的密码锁定共用手机号的其他人
+	var accounts []LoginAccount
+	if len(candidates) == 1 {
+		accounts = []LoginAccount{candidates[0].account}
+		// 锁定期内无论密码是否正确都返回锁定，避免通过响应差异猜测密码
+		if err := s.Guard.CheckAccount(accounts[0]); err != nil {
+			return nil, err
 		}
 	}
+	s.Guard.RecordFailure(username, client.IP, accounts)
+	return nil, ErrInvalidCredentials
+}
 
-	// 尝试查找物业人员
-	var staff models.PropertyStaff
-	if err := s.DB.Where("username = ?", username).First(&staff).Error; err == nil {
-		// 获取密码字段
-		var dbPassword string
+// VerifyTwoFactor 提交登录的两步验证码或恢复码，通过后签发令牌；
+// 输错时按用户名和IP计数，同一凭证输错次数过多后失效，需要重新输入密码
+func (s *JWTService) VerifyTwoFactor(challengeToken, code string, client ClientInfo) (*LoginResult, error) {
+	if err := s.Guard.CheckIP(client.IP); err != nil {
+		return nil, err
+	}
+
+	key := twoFactorChallengePrefix + hashToken(challengeToken)
+	var challenge twoFactorChallenge
+	if err := s.Redis.Get(key, &challenge); err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrTwoFactorChallengeInvalid
+		}
+		return nil, err
+	}
 
-		// 使用原始查询获取所需字段
-		row := s.DB.Table("property_staffs").
-			Select("password").
-			Where("id = ?", staff.ID).
-			Row()
+	account := LoginAccount{SubjectType: "admin", SubjectID: challenge.AdminID}
+	if err := s.Guard.CheckAccount(account); err != nil {
+		return nil, err
+	}
 
-		if err := row.Scan(&dbPassword); err != nil {
+	if err := s.TwoFactor.Verify(challenge.AdminID, code); err != nil {
+		if !errors.Is(err, ErrTwoFactorCodeInvalid) {
 			return nil, err
 		}
+		s.Guard.RecordFailure(challenge.Username, client.IP, []LoginAccount{account})
+		attemptsKey := twoFactorAttemptsPrefix + hashToken(challengeToken)
+		if attempts, incrErr := s.Redis.Incr(attemptsKey, s.twoFactorTTL); incrErr != nil || attempts >= twoFactorChallengeMaxTries {
+			_ = s.Redis.Delete(key)
+			_ = s.Redis.Delete(attemptsKey)
+			return nil, ErrTwoFactorChallengeInvalid
+		}
+		return nil, err
+	}
 
-		// 比较密码
-		if err := bcrypt.CompareHashAndPassword([]byte(dbPassword), []byte(password)); err == nil {
-			// 生成物业人员令牌
-			token, err := s.GenerateToken(staff.ID, "staff", nil, nil)
-			if err != nil {
-				return nil, err
-			}
+	// 凭证只能使用一次
+	if err := s.Redis.Delete(key); err != nil {
+		return nil, err
+	}
+	_ = s.Redis.Delete(twoFactorAttemptsPrefix + hashToken(challengeToken))
 
-			// 获取用户名
-			var username string
-			s.DB.Table("property_staffs").
-				Select("username").
-				Where("id = ?", staff.ID).
-				Row().
-				Scan(&username)
+	var admin models.Admin
+	if err := s.DB.First(&admin, challenge.AdminID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTwoFactorChallengeInvalid
+		}
+		return nil, err
+	}
+	if admin.Status == "locked" || admin.Status == "inactive" {
+		return nil, ErrAccountDisabled
+	}
 
-			return &LoginResult{
-				Token:     token,
-				UserID:    staff.ID,
-				Role:      "staff",
-				Username:  username,
-				CreatedAt: staff.CreatedAt,
-			}, nil
+	s.Guard.RecordSuccess(challenge.Username)
+	return s.completeLogin(loginCandidate{
+		account:    account,
+		username:   admin.Username,
+		propertyID: admin.PropertyID,
+		createdAt:  admin.CreatedAt,
+	}, client)
+}
+
+// startTwoFactorChallenge 密码验证通过后生成两步验证凭证，在有效期内提交验证码完成登录
+func (s *JWTService) startTwoFactorChallenge(candidate loginCandidate, username string) (*LoginResult, error) {
+	token, err := utils.RandomHex(32)
+	if err != nil {
+		return nil, err
+	}
+	challenge := twoFactorChallenge{AdminID: candidate.account.SubjectID, Username: username}
+	if err := s.Redis.Set(twoFactorChallengePrefix+hashToken(token), challenge, s.twoFactorTTL); err != nil {
+		return nil, err
+	}
+
+	return &LoginResult{
+		Username:           candidate.username,
+		TwoFactorRequired:  true,
+		TwoFactorToken:     token,
+		TwoFactorExpiresIn: int(s.twoFactorTTL.Seconds()),
+	}, nil
+}
+
+// completeLogin 开启新的登录会话并返回登录结果，物业员工同时记录登录时间
+func (s *JWTService) completeLogin(candidate loginCandidate, client ClientInfo) (*LoginResult, error) {
+	tokens, err := s.IssueTokens(candidate.account.SubjectID, candidate.account.SubjectType, candidate.propertyID, client)
+	if err != nil {
+		return nil, err
+	}
+	if candidate.account.SubjectType == "staff" {
+		if err := s.DB.Model(&models.PropertyStaff{}).Where("id = ?", candidate.account.SubjectID).
+			UpdateColumn("last_login_at", time.Now()).Error; err != nil {
+			log.Printf("[JWT] 记录物业员工 %d 的登录时间失败: %v", candidate.account.SubjectID, err)
 		}
 	}
+	return &LoginResult{
+		TokenPair: tokens,
+		Username:  candidate.username,
+		Phone:     candidate.phone,
+		CreatedAt: candidate.createdAt,
+	}, nil
+}
+
+// loginCandidate 用户名匹配到的账号
+type loginCandidate struct {
+	account    LoginAccount
+	password   string
+	username   string
+	phone      string
+	propertyID *uint
+	createdAt  time.Time
+	disabled   bool
+}
+
+// findLoginCandidates 查找登录名匹配的管理员、物业员工（按用户名或手机号）和居民（按手机号）
+func (s *JWTService) findLoginCandidates(username string) ([]loginCandidate, error) {
+	var candidates []loginCandidate
+
+	var admin models.Admin
+	if err := s.DB.Where("username = ?", username).First(&admin).Error; err == nil {
+		candidates = append(candidates, loginCandidate{
+			account:    LoginAccount{SubjectType: "admin", SubjectID: admin.ID},
+			password:   admin.Password,
+			username:   admin.Username,
+			propertyID: admin.PropertyID,
+			createdAt:  admin.CreatedAt,
+			disabled:   admin.Status == "locked" || admin.Status == "inactive",
+		})
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	// 物业员工可以使用用户名或手机号登录，用户名优先
+	var staff models.PropertyStaff
+	err := s.DB.Where("username = ?", username).First(&staff).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = s.DB.Where("phone = ?", username).First(&staff).Error
+	}
+	if err == nil {
+		candidates = append(candidates, loginCandidate{
+			account:    LoginAccount{SubjectType: "staff", SubjectID: staff.ID},
+			password:   staff.Password,
+			username:   staff.Username,
+			phone:      staff.Phone,
+			propertyID: staff.PropertyID,
+			createdAt:  staff.CreatedAt,
+			disabled:   staff.Status != "" && staff.Status != models.StaffStatusActive,
+		})
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
 
-	// 尝试查找普通居民
 	var resident models.Resident
 	if err := s.DB.Where("phone = ?", username).First(&resident).Error; err == nil {
-		// 获取密码字段和其他信息
-		var dbPassword string
-		var name string
-		var phone string
-
-		// 使用原始查询获取所需字段
-		row := s.DB.Table("residents").
-			Select("password, name, phone").
-			Where("id = ?", resident.ID).
-			Row()
-
-		if err := row.Scan(&dbPassword, &name, &phone); err != nil {
+		candidates = append(candidates, loginCandidate{
+			account:    LoginAccount{SubjectType: "user", SubjectID: resident.ID},
+			password:   resident.Password,
+			username:   resident.Name,
+			phone:      resident.Phone,
+			propertyID: ResolveHouseholdPropertyID(s.DB, resident.HouseholdID),
+			createdAt:  resident.CreatedAt,
+		})
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	return candidates, nil
+}
+
+// IssueTokens 登录成功后签发访问令牌和刷新令牌，开启新的登录会话
+func (s *JWTService) IssueTokens(userID uint, role string, propertyID *uint, client ClientInfo) (*TokenPair, error) {
+	sessionID, err := utils.RandomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	return s.issueTokens(s.DB, userID, role, propertyID, sessionID, client)
+}
+
+// RefreshTokens 用刷新令牌换取新的令牌，旧刷新令牌立即失效；
+// 已失效的刷新令牌被再次使用时视为泄露，注销整个登录会话
+func (s *JWTService) RefreshTokens(refreshToken string, client ClientInfo) (*TokenPair, error) {
+	var stored models.RefreshToken
+	if err := s.DB.Where("token_hash = ?", hashToken(refreshToken)).First(&stored).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRefreshTokenInvalid
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if stored.RevokedAt != nil {
+		if err := s.revokeSession(stored.SessionID); err != nil {
 			return nil, err
 		}
+		return nil, ErrRefreshTokenReused
+	}
+	if now.After(stored.ExpiresAt) {
+		return nil, ErrRefreshTokenInvalid
+	}
 
-		// 比较密码
-		if err := bcrypt.CompareHashAndPassword([]byte(dbPassword), []byte(password)); err == nil {
-			// 生成居民令牌
-			token, err := s.GenerateToken(resident.ID, "user", nil, nil)
-			if err != nil {
-				return nil, err
+	// 账号被删除或停用后不能再刷新
+	propertyID, err := s.resolveSubject(stored.SubjectType, stored.SubjectID)
+	if err != nil {
+		if errors.Is(err, ErrRefreshTokenInvalid) {
+			if revokeErr := s.RevokeAllTokens(stored.SubjectType, stored.SubjectID); revokeErr != nil {
+				return nil, revokeErr
 			}
+		}
+		return nil, err
+	}
+
+	var pair *TokenPair
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		// 条件更新保证并发刷新时只有一个请求成功
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", stored.ID).
+			Update("revoked_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRefreshTokenInvalid
+		}
+
+		var err error
+		pair, err = s.issueTokens(tx, stored.SubjectID, stored.SubjectType, propertyID, stored.SessionID, client)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// Logout 注销当前访问令牌及其登录会话的刷新令牌
+func (s *JWTService) Logout(claims jwt.MapClaims) error {
+	if sessionID, ok := claims["sid"].(string); ok && sessionID != "" {
+		if err := s.revokeSession(sessionID); err != nil {
+			return err
+		}
+	}
+
+	tokenID, _ := claims["jti"].(string)
+	if tokenID == "" {
+		return nil
+	}
+	ttl := s.accessTTL
+	if exp, ok := claims["exp"].(float64); ok {
+		ttl = time.Until(time.Unix(int64(exp), 0))
+	}
+	if ttl <= 0 {
+		return nil
+	}
+	return s.Redis.Set(tokenDenylistPrefix+tokenID, true, ttl)
+}
+
+// RevokeAllTokens 注销账号的所有登录会话：刷新令牌全部失效，此前签发的访问令牌加入拒绝名单
+func (s *JWTService) RevokeAllTokens(role string, userID uint) error {
+	if err := s.DB.Model(&models.RefreshToken{}).
+		Where("subject_type = ? AND subject_id = ? AND revoked_at IS NULL", role, userID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return err
+	}
+	// 访问令牌最长有效期内记录撤销时间即可
+	return s.Redis.Set(revokedBeforeKey(role, userID), time.Now().UnixMilli(), s.accessTTL)
+}
+
+// IsTokenRevoked 检查访问令牌是否已注销，或签发于账号注销全部会话之前；
+// Redis不可用时改为按数据库中的登录会话和账号状态判断
+func (s *JWTService) IsTokenRevoked(claims jwt.MapClaims) (bool, error) {
+	revoked, err := s.isTokenRevokedInRedis(claims)
+	if err == nil {
+		return revoked, nil
+	}
+	log.Printf("[JWT] 令牌拒绝名单不可用，改为查询数据库: %v", err)
+	return s.isTokenRevokedInDB(claims)
+}
+
+// isTokenRevokedInRedis 按Redis中的拒绝名单和账号撤销时间判断令牌是否已注销
+func (s *JWTService) isTokenRevokedInRedis(claims jwt.MapClaims) (bool, error) {
+	if tokenID, ok := claims["jti"].(string); ok && tokenID != "" {
+		var revoked bool
+		err := s.Redis.Get(tokenDenylistPrefix+tokenID, &revoked)
+		if err == nil && revoked {
+			return true, nil
+		}
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return false, err
+		}
+	}
+
+	role, _ := claims["role"].(string)
+	userID, _ := claims["user_id"].(float64)
+	var revokedBefore int64
+	err := s.Redis.Get(revokedBeforeKey(role, uint(userID)), &revokedBefore)
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return false, nil
+		}
+		return false, err
+	}
+	// 升级前按秒记录的撤销时间
+	if revokedBefore < 1e12 {
+		revokedBefore *= 1000
+	}
+	return issuedAtMilli(claims) <= revokedBefore, nil
+}
+
+// isTokenRevokedInDB 按数据库判断令牌是否已注销：设备被删除或密钥轮换前签发的设备令牌、
+// 账号被删除或停用、登录会话的刷新令牌已全部注销（注销、注销全部会话、修改密码）都视为已注销
+func (s *JWTService) isTokenRevokedInDB(claims jwt.MapClaims) (bool, error) {
+	role, _ := claims["role"].(string)
+	userID, _ := claims["user_id"].(float64)
+
+	if role == DeviceRole {
+		var device models.Device
+		if err := s.DB.Select("id, secret_issued_at").First(&device, uint(userID)).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return true, nil
+			}
+			return false, err
+		}
+		return device.SecretIssuedAt != nil && issuedAtMilli(claims) < device.SecretIssuedAt.Truncate(time.Second).UnixMilli(), nil
+	}
+
+	if _, err := s.resolveSubject(role, uint(userID)); err != nil {
+		if errors.Is(err, ErrRefreshTokenInvalid) {
+			return true, nil
+		}
+		return false, err
+	}
+
+	sessionID, _ := claims["sid"].(string)
+	if sessionID == "" {
+		return false, nil
+	}
+	var active int64
+	if err := s.DB.Model(&models.RefreshToken{}).
+		Where("session_id = ? AND revoked_at IS NULL", sessionID).
+		Count(&active).Error; err != nil {
+		return false, err
+	}
+	return active == 0, nil
+}
+
+// issuedAtMilli 取得访问令牌签发时间的毫秒数，没有 iat_ms 的旧令牌按 iat 所在秒的第一毫秒计算，
+// 与注销发生在同一秒时视为注销前签发
+func issuedAtMilli(claims jwt.MapClaims) int64 {
+	if milli, ok := claims["iat_ms"].(float64); ok && milli > 0 {
+		return int64(milli)
+	}
+	issuedAt, _ := claims["iat"].(float64)
+	return int64(issuedAt) * 1000
+}
 
-			return &LoginResult{
-				Token:     token,
-				UserID:    resident.ID,
-				Role:      "user",
-				Username:  name,
-				Phone:     phone,
-				CreatedAt: resident.CreatedAt,
-			}, nil
+// IssueDeviceToken 为已通过密钥认证的设备签发设备令牌
+func (s *JWTService) IssueDeviceToken(deviceID uint) (*DeviceToken, error) {
+	token, err := s.signAccessToken(JWTClaims{UserID: deviceID, Role: DeviceRole, DeviceID: &deviceID})
+	if err != nil {
+		return nil, err
+	}
+	return &DeviceToken{
+		Token:     token,
+		ExpiresIn: int(s.accessTTL.Seconds()),
+		DeviceID:  deviceID,
+	}, nil
+}
+
+// issueTokens 在指定会话中签发访问令牌并保存新的刷新令牌
+func (s *JWTService) issueTokens(db *gorm.DB, userID uint, role string, propertyID *uint, sessionID string, client ClientInfo) (*TokenPair, error) {
+	mustChange, err := s.passwordChangeRequired(db, role, userID)
+	if err != nil {
+		return nil, err
+	}
+	setupTwoFactor, err := s.twoFactorSetupRequired(role, userID)
+	if err != nil {
+		return nil, err
+	}
+	accessToken, err := s.signAccessToken(JWTClaims{
+		UserID:                 userID,
+		Role:                   role,
+		PropertyID:             propertyID,
+		SessionID:              sessionID,
+		PasswordChangeRequired: mustChange,
+		TwoFactorSetupRequired: setupTwoFactor,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := utils.RandomHex(32)
+	if err != nil {
+		return nil, err
+	}
+	stored := &models.RefreshToken{
+		SubjectType: role,
+		SubjectID:   userID,
+		SessionID:   sessionID,
+		TokenHash:   hashToken(refreshToken),
+		ExpiresAt:   time.Now().Add(s.refreshTTL),
+		UserAgent:   truncate(client.UserAgent, 255),
+		IP:          truncate(client.IP, 64),
+	}
+	if err := db.Create(stored).Error; err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		ExpiresIn:        int(s.accessTTL.Seconds()),
+		RefreshExpiresAt: stored.ExpiresAt,
+		UserID:           userID,
+		Role:             role,
+		PropertyID:       propertyID,
+
+		PasswordChangeRequired: mustChange,
+		TwoFactorSetupRequired: setupTwoFactor,
+	}, nil
+}
+
+// passwordChangeRequired 检查账号是否必须先修改密码，管理员和物业员工会被要求修改密码
+func (s *JWTService) passwordChangeRequired(db *gorm.DB, role string, userID uint) (bool, error) {
+	switch role {
+	case "admin":
+		var admin models.Admin
+		if err := db.Select("id, must_change_password").First(&admin, userID).Error; err != nil {
+			return false, err
+		}
+		return admin.MustChangePassword, nil
+	case "staff":
+		var staff models.PropertyStaff
+		if err := db.Select("id, must_change_password").First(&staff, userID).Error; err != nil {
+			return false, err
+		}
+		return staff.MustChangePassword, nil
+	default:
+		return false, nil
+	}
+}
+
+// twoFactorSetupRequired 检查管理员是否拥有要求两步验证的权限但尚未绑定
+func (s *JWTService) twoFactorSetupRequired(role string, userID uint) (bool, error) {
+	if role != "admin" {
+		return false, nil
+	}
+	return s.TwoFactor.SetupRequired(userID)
+}
+
+// revokeSession 注销登录会话的所有刷新令牌
+func (s *JWTService) revokeSession(sessionID string) error {
+	return s.DB.Model(&models.RefreshToken{}).
+		Where("session_id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// resolveSubject 确认账号仍然有效并返回其当前所属物业，刷新后的令牌使用最新的物业
+func (s *JWTService) resolveSubject(role string, userID uint) (*uint, error) {
+	switch role {
+	case "admin":
+		var admin models.Admin
+		if err := s.DB.Select("id, property_id, status").First(&admin, userID).Error; err != nil {
+			return nil, subjectError(err)
+		}
+		if admin.Status == "locked" || admin.Status == "inactive" {
+			return nil, ErrRefreshTokenInvalid
+		}
+		return admin.PropertyID, nil
+	case "staff":
+		var staff models.PropertyStaff
+		if err := s.DB.Select("id, property_id, status").First(&staff, userID).Error; err != nil {
+			return nil, subjectError(err)
+		}
+		if staff.Status != "" && staff.Status != models.StaffStatusActive {
+			return nil, ErrRefreshTokenInvalid
+		}
+		return staff.PropertyID, nil
+	case "user":
+		var resident models.Resident
+		if err := s.DB.Select("id, household_id").First(&resident, userID).Error; err != nil {
+			return nil, subjectError(err)
 		}
+		return ResolveHouseholdPropertyID(s.DB, resident.HouseholdID), nil
+	default:
+		return nil, ErrRefreshTokenInvalid
 	}
+}
+
+// subjectError 账号不存在时视为刷新令牌无效
+func subjectError(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrRefreshTokenInvalid
+	}
+	return err
+}
+
+// hashToken 计算刷新令牌的SHA-256哈希，数据库中不保存明文
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
 
-	// 用户名或密码无效
-	return nil, errors.New("invalid username or password")
+// revokedBeforeKey 账号注销全部会话时间的Redis键
+func revokedBeforeKey(role string, userID uint) string {
+	return tokenRevokedBeforePrefix + role + ":" + strconv.FormatUint(uint64(userID), 10)
 }
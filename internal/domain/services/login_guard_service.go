@@ -0,0 +1,457 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"ilock-http-service/internal/domain/models"
+	"ilock-http-service/internal/infrastructure/config"
+	"log"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+// InterfaceLoginGuardService 定义登录防暴力破解服务接口
+type InterfaceLoginGuardService interface {
+	CheckIP(ip string) error
+	CheckUsername(username string) error
+	CheckAccount(account LoginAccount) error
+	RecordFailure(username, ip string, accounts []LoginAccount)
+	RecordSuccess(username string)
+	Unlock(account LoginAccount, operatorID uint, ip string) error
+	GetLockouts(activeOnly bool, page, pageSize int) ([]models.AccountLockout, int64, error)
+}
+
+var (
+	// ErrAccountLocked 账号因连续登录失败被锁定
+	ErrAccountLocked = errors.New("登录失败次数过多，账号已被锁定")
+	// ErrLoginThrottled 连续登录失败后需要等待一段时间才能重试
+	ErrLoginThrottled = errors.New("登录失败次数过多，请稍后再试")
+	// ErrLoginIPBlocked 同一IP登录失败次数过多被暂时封禁
+	ErrLoginIPBlocked = errors.New("该IP登录失败次数过多，已被暂时封禁")
+)
+
+// LoginBlockedError 表示登录被拒绝及可以重试的时间
+type LoginBlockedError struct {
+	Reason     error
+	RetryAfter time.Duration
+}
+
+func (e *LoginBlockedError) Error() string { return e.Reason.Error() }
+
+func (e *LoginBlockedError) Unwrap() error { return e.Reason }
+
+// LoginAccount 表示参与登录的账号
+type LoginAccount struct {
+	SubjectType string // 账号类型：admin, staff, user
+	SubjectID   uint
+}
+
+// 登录失败计数的Redis键前缀
+const (
+	loginFailUserPrefix = "login:fail:user:" // 用户名在统计窗口内的失败次数
+	loginDelayPrefix    = "login:delay:"     // 用户名下次允许尝试的时间
+	loginFailIPPrefix   = "login:fail:ip:"   // IP在统计窗口内的失败次数
+	loginBlockIPPrefix  = "login:block:ip:"  // IP封禁到期时间
+)
+
+// 递增延迟：第3次失败起，每次失败后需要等待 1, 2, 4 ... 秒，最多30秒
+const (
+	loginDelayFreeAttempts = 2
+	loginMaxDelay          = 30 * time.Second
+)
+
+// 审计日志中的登录安全事件
+const (
+	AuditLoginAccountLocked   = "login_account_locked"
+	AuditLoginUsernameLocked  = "login_username_locked" // 登录名不对应唯一账号时锁定登录名
+	AuditLoginIPBlocked       = "login_ip_blocked"
+	AuditLoginAccountUnlocked = "login_account_unlocked"
+	AuditLoginGuardDegraded   = "login_guard_degraded"  // Redis不可用，改用进程内计数
+	AuditLoginGuardRecovered  = "login_guard_recovered" // Redis恢复，重新使用Redis计数
+)
+
+// loginFallbackMaxEntries 进程内计数最多保存的键数，超过时不再记录新的键
+const loginFallbackMaxEntries = 100000
+
+// LoginGuardService 按账号和IP统计登录失败，提供递增延迟、自动锁定和管理员解锁。
+// 失败计数、延迟和IP封禁保存在Redis中，Redis不可用时改用进程内计数，并在系统日志中记录降级和恢复
+type LoginGuardService struct {
+	DB     *gorm.DB
+	Config *config.Config
+	Redis  InterfaceRedisService
+
+	fallback *loginFallbackStore
+	mu       sync.Mutex
+	degraded bool
+}
+
+// NewLoginGuardService 创建一个新的登录防暴力破解服务
+func NewLoginGuardService(db *gorm.DB, cfg *config.Config, redisService InterfaceRedisService) InterfaceLoginGuardService {
+	return &LoginGuardService{
+		DB:       db,
+		Config:   cfg,
+		Redis:    redisService,
+		fallback: newLoginFallbackStore(),
+	}
+}
+
+// 1 CheckIP 检查IP是否因失败次数过多被封禁
+func (s *LoginGuardService) CheckIP(ip string) error {
+	return s.checkUntil(loginBlockIPPrefix+ip, ErrLoginIPBlocked)
+}
+
+// 2 CheckUsername 检查用户名是否处于递增延迟中，不存在的用户名同样计数，避免通过响应差异枚举账号
+func (s *LoginGuardService) CheckUsername(username string) error {
+	return s.checkUntil(loginDelayPrefix+normalizeLoginName(username), ErrLoginThrottled)
+}
+
+// 3 CheckAccount 检查账号是否处于锁定期
+func (s *LoginGuardService) CheckAccount(account LoginAccount) error {
+	var lockout models.AccountLockout
+	err := s.DB.Where("subject_type = ? AND subject_id = ? AND unlocked_at IS NULL AND locked_until > ?", account.SubjectType, account.SubjectID, time.Now()).
+		Order("locked_until DESC").
+		First(&lockout).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	return &LoginBlockedError{Reason: ErrAccountLocked, RetryAfter: time.Until(lockout.LockedUntil)}
+}
+
+// 4 RecordFailure 记录一次失败登录：用户名失败次数达到阈值时锁定 accounts 中的账号，accounts 为空时锁定登录名本身；
+// IP失败次数达到阈值时封禁IP，都写入审计日志
+func (s *LoginGuardService) RecordFailure(username, ip string, accounts []LoginAccount) {
+	name := normalizeLoginName(username)
+	window := time.Duration(s.Config.LoginFailureWindow) * time.Minute
+	lockout := time.Duration(s.Config.LoginLockoutMinutes) * time.Minute
+
+	failures := s.incr(loginFailUserPrefix+name, window)
+	switch {
+	case failures >= int64(s.Config.LoginMaxFailures) && len(accounts) > 0:
+		s.lockAccounts(name, ip, int(failures), accounts, lockout)
+	case failures >= int64(s.Config.LoginMaxFailures):
+		s.lockUsername(name, ip, lockout)
+	default:
+		if delay := loginDelay(failures); delay > 0 {
+			s.set(loginDelayPrefix+name, time.Now().Add(delay).Unix(), delay)
+		}
+	}
+
+	if ip == "" {
+		return
+	}
+	if s.incr(loginFailIPPrefix+ip, window) < int64(s.Config.LoginIPMaxFailures) {
+		return
+	}
+	// 只在首次封禁时写审计日志，封禁期内的后续失败不重复记录
+	if s.setNX(loginBlockIPPrefix+ip, time.Now().Add(lockout).Unix(), lockout) {
+		s.delete(loginFailIPPrefix + ip)
+		s.audit(0, AuditLoginIPBlocked, "ip:"+ip, ip)
+	}
+}
+
+// 5 RecordSuccess 登录成功后清除用户名的失败计数和延迟，IP计数保留到窗口结束
+func (s *LoginGuardService) RecordSuccess(username string) {
+	name := normalizeLoginName(username)
+	for _, key := range []string{loginFailUserPrefix + name, loginDelayPrefix + name} {
+		s.delete(key)
+	}
+}
+
+// 6 Unlock 管理员解锁账号：结束所有锁定记录，把被手动锁定的管理员或物业员工恢复为正常状态，并清除失败计数
+func (s *LoginGuardService) Unlock(account LoginAccount, operatorID uint, ip string) error {
+	var usernames []string
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.AccountLockout{}).
+			Where("subject_type = ? AND subject_id = ? AND unlocked_at IS NULL AND locked_until > ?", account.SubjectType, account.SubjectID, time.Now()).
+			Pluck("username", &usernames).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.AccountLockout{}).
+			Where("subject_type = ? AND subject_id = ? AND unlocked_at IS NULL", account.SubjectType, account.SubjectID).
+			Updates(map[string]interface{}{"unlocked_at": time.Now(), "unlocked_by": operatorID}).Error; err != nil {
+			return err
+		}
+
+		switch account.SubjectType {
+		case "admin":
+			return tx.Model(&models.Admin{}).Where("id = ? AND status = ?", account.SubjectID, "locked").Update("status", "active").Error
+		case "staff":
+			return tx.Model(&models.PropertyStaff{}).Where("id = ? AND status = ?", account.SubjectID, "locked").Update("status", "active").Error
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, name := range usernames {
+		s.RecordSuccess(name)
+	}
+	s.audit(operatorID, AuditLoginAccountUnlocked, fmt.Sprintf("%s:%d", account.SubjectType, account.SubjectID), ip)
+	return nil
+}
+
+// 7 GetLockouts 分页获取账号锁定记录，activeOnly 为 true 时只返回仍在锁定期的记录
+func (s *LoginGuardService) GetLockouts(activeOnly bool, page, pageSize int) ([]models.AccountLockout, int64, error) {
+	var lockouts []models.AccountLockout
+	var total int64
+
+	db := s.DB.Model(&models.AccountLockout{})
+	if activeOnly {
+		db = db.Where("unlocked_at IS NULL AND locked_until > ?", time.Now())
+	}
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	if err := db.Order("id DESC").Limit(pageSize).Offset(offset).Find(&lockouts).Error; err != nil {
+		return nil, 0, err
+	}
+	return lockouts, total, nil
+}
+
+// lockAccounts 锁定用户名匹配的账号并写入审计日志，之后重新开始计数
+func (s *LoginGuardService) lockAccounts(name, ip string, failures int, accounts []LoginAccount, lockout time.Duration) {
+	until := time.Now().Add(lockout)
+	for _, account := range accounts {
+		record := &models.AccountLockout{
+			SubjectType:    account.SubjectType,
+			SubjectID:      account.SubjectID,
+			Username:       truncate(name, 100),
+			IP:             truncate(ip, 64),
+			FailedAttempts: failures,
+			LockedUntil:    until,
+		}
+		if err := s.DB.Create(record).Error; err != nil {
+			log.Printf("[LoginGuard] 锁定账号 %s:%d 失败: %v", account.SubjectType, account.SubjectID, err)
+			continue
+		}
+		s.audit(0, AuditLoginAccountLocked, fmt.Sprintf("%s:%d", account.SubjectType, account.SubjectID), ip)
+	}
+	s.RecordSuccess(name)
+}
+
+// lockUsername 登录名不对应唯一账号时，在锁定期内拒绝该登录名的所有登录，不影响账号使用其他登录名登录；之后重新开始计数
+func (s *LoginGuardService) lockUsername(name, ip string, lockout time.Duration) {
+	s.delete(loginFailUserPrefix + name)
+	s.set(loginDelayPrefix+name, time.Now().Add(lockout).Unix(), lockout)
+	s.audit(0, AuditLoginUsernameLocked, "username:"+truncate(name, 100), ip)
+}
+
+// checkUntil 键中保存的到期时间未到时返回 LoginBlockedError，Redis和进程内计数中取较晚的到期时间
+func (s *LoginGuardService) checkUntil(key string, reason error) error {
+	var until int64
+	if err := s.Redis.Get(key, &until); err != nil {
+		if !errors.Is(err, redis.Nil) {
+			s.redisFailed("读取 "+key, err)
+		}
+	} else {
+		s.redisOK()
+	}
+	if local, ok := s.fallback.get(key); ok && local > until {
+		until = local
+	}
+	if until == 0 {
+		return nil
+	}
+
+	retryAfter := time.Until(time.Unix(until, 0))
+	if retryAfter <= 0 {
+		return nil
+	}
+	return &LoginBlockedError{Reason: reason, RetryAfter: retryAfter}
+}
+
+// incr 增加计数并返回增加后的值，Redis不可用时使用进程内计数
+func (s *LoginGuardService) incr(key string, ttl time.Duration) int64 {
+	count, err := s.Redis.Incr(key, ttl)
+	if err != nil {
+		s.redisFailed("增加 "+key, err)
+		return s.fallback.incr(key, ttl)
+	}
+	s.redisOK()
+	return count
+}
+
+// set 保存到期时间，Redis不可用时保存在进程内
+func (s *LoginGuardService) set(key string, value int64, ttl time.Duration) {
+	if err := s.Redis.Set(key, value, ttl); err != nil {
+		s.redisFailed("设置 "+key, err)
+		s.fallback.set(key, value, ttl)
+		return
+	}
+	s.redisOK()
+}
+
+// setNX 键不存在时保存并返回 true，Redis不可用时保存在进程内
+func (s *LoginGuardService) setNX(key string, value int64, ttl time.Duration) bool {
+	fresh, err := s.Redis.SetNX(key, value, ttl)
+	if err != nil {
+		s.redisFailed("设置 "+key, err)
+		return s.fallback.setNX(key, value, ttl)
+	}
+	s.redisOK()
+	return fresh
+}
+
+// delete 同时清除Redis和进程内的键
+func (s *LoginGuardService) delete(key string) {
+	s.fallback.delete(key)
+	if err := s.Redis.Delete(key); err != nil {
+		s.redisFailed("清除 "+key, err)
+		return
+	}
+	s.redisOK()
+}
+
+// redisFailed 记录Redis操作失败，首次失败时在系统日志中记录降级为进程内计数
+func (s *LoginGuardService) redisFailed(op string, err error) {
+	log.Printf("[LoginGuard] %s 失败，使用进程内计数: %v", op, err)
+
+	s.mu.Lock()
+	first := !s.degraded
+	s.degraded = true
+	s.mu.Unlock()
+	if first {
+		s.audit(0, AuditLoginGuardDegraded, "redis", "")
+	}
+}
+
+// redisOK Redis操作成功，之前处于降级状态时在系统日志中记录恢复
+func (s *LoginGuardService) redisOK() {
+	s.mu.Lock()
+	recovered := s.degraded
+	s.degraded = false
+	s.mu.Unlock()
+	if recovered {
+		log.Printf("[LoginGuard] Redis已恢复，重新使用Redis计数")
+		s.audit(0, AuditLoginGuardRecovered, "redis", "")
+	}
+}
+
+// audit 把登录安全事件写入审计日志，adminID 为0表示系统自动操作
+func (s *LoginGuardService) audit(adminID uint, action, target, ip string) {
+	entry := &models.SystemLog{
+		Action:    action,
+		Target:    truncate(target, 100),
+		IPAddress: truncate(ip, 45),
+		Timestamp: time.Now(),
+	}
+	if adminID > 0 {
+		entry.AdminID = &adminID
+		entry.ActorType = "admin"
+		entry.ActorID = adminID
+	}
+	if err := s.DB.Create(entry).Error; err != nil {
+		log.Printf("[LoginGuard] 写入审计日志 %s %s 失败: %v", action, target, err)
+	}
+}
+
+// loginDelay 计算第 failures 次失败后需要等待的时间
+func loginDelay(failures int64) time.Duration {
+	if failures <= loginDelayFreeAttempts {
+		return 0
+	}
+	delay := time.Duration(math.Pow(2, float64(failures-loginDelayFreeAttempts-1))) * time.Second
+	if delay > loginMaxDelay || delay <= 0 {
+		return loginMaxDelay
+	}
+	return delay
+}
+
+// normalizeLoginName 统一用户名大小写和空白，避免换个写法绕过计数
+func normalizeLoginName(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// loginFallbackStore Redis不可用时在进程内保存失败计数、延迟和IP封禁，多实例部署时各实例分别计数
+type loginFallbackStore struct {
+	mu      sync.Mutex
+	entries map[string]loginFallbackEntry
+}
+
+// loginFallbackEntry 进程内保存的值和过期时间
+type loginFallbackEntry struct {
+	value   int64
+	expires time.Time
+}
+
+func newLoginFallbackStore() *loginFallbackStore {
+	return &loginFallbackStore{entries: make(map[string]loginFallbackEntry)}
+}
+
+// get 返回未过期的值
+func (f *loginFallbackStore) get(key string) (int64, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	entry, ok := f.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		return 0, false
+	}
+	return entry.value, true
+}
+
+// incr 增加计数，键不存在或已过期时从1开始并设置过期时间
+func (f *loginFallbackStore) incr(key string, ttl time.Duration) int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := time.Now()
+	entry, ok := f.entries[key]
+	if !ok || now.After(entry.expires) {
+		entry = loginFallbackEntry{expires: now.Add(ttl)}
+	}
+	entry.value++
+	f.store(key, entry)
+	return entry.value
+}
+
+// set 保存值并设置过期时间
+func (f *loginFallbackStore) set(key string, value int64, ttl time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.store(key, loginFallbackEntry{value: value, expires: time.Now().Add(ttl)})
+}
+
+// setNX 键不存在或已过期时保存并返回 true
+func (f *loginFallbackStore) setNX(key string, value int64, ttl time.Duration) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := time.Now()
+	if entry, ok := f.entries[key]; ok && !now.After(entry.expires) {
+		return false
+	}
+	f.store(key, loginFallbackEntry{value: value, expires: now.Add(ttl)})
+	return true
+}
+
+// delete 删除键
+func (f *loginFallbackStore) delete(key string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.entries, key)
+}
+
+// store 保存键，达到上限时先清理过期的键，仍然已满时不记录新的键
+func (f *loginFallbackStore) store(key string, entry loginFallbackEntry) {
+	if _, exists := f.entries[key]; !exists && len(f.entries) >= loginFallbackMaxEntries {
+		now := time.Now()
+		for k, e := range f.entries {
+			if now.After(e.expires) {
+				delete(f.entries, k)
+			}
+		}
+		if len(f.entries) >= loginFallbackMaxEntries {
+			return
+		}
+	}
+	f.entries[key] = entry
+}
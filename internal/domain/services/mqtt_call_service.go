@@ -5,8 +5,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"ilock-http-service/internal/infrastructure/config"
+	"ilock-http-service/internal/domain/events"
 	"ilock-http-service/internal/domain/models"
+	"ilock-http-service/internal/infrastructure/notifier"
 	"log"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -32,6 +35,8 @@ type InterfaceMQTTCallService interface {
 	SubscribeToTopics() error
 	PublishDeviceStatus(deviceID string, status map[string]interface{}) error
 	PublishSystemMessage(messageType string, message map[string]interface{}) error
+	RegisterTopicHandler(topic string, handler func(payload []byte)) error
+	PublishMessage(topic string, payload interface{}) error
 }
 
 // MQTTCallService 整合MQTT和通话服务的实现
@@ -39,11 +44,14 @@ type MQTTCallService struct {
 	DB              *gorm.DB
 	Config          *config.Config
 	RTCService      InterfaceTencentRTCService
+	Notifier        InterfaceNotificationService
+	Events          *events.Bus
 	Client          mqtt.Client
 	IsConnected     bool
 	connectedMutex  sync.RWMutex // 保护IsConnected字段的读写
 	CallManager     *models.CallManager
 	TopicHandlers   map[string]mqtt.MessageHandler
+	handlersMutex   sync.RWMutex // 保护TopicHandlers的读写
 	CallRecordMutex sync.Mutex   // 用于保护通话记录创建
 	ProcessedMsgs   *sync.Map    // 用于记录已处理的消息，防止重复处理
 	PublishMutex    sync.Mutex   // 用于保护MQTT消息发布
@@ -64,6 +72,21 @@ const (
 
 	// 系统消息主题
 	TopicSystemMessage = "mqtt_call/system"
+
+	// 设备事件上报主题（门磁、防拆等）
+	TopicDeviceEvent = "mqtt_call/device/event"
+
+	// 紧急通知主题前缀，接收人主题为 mqtt_call/notification/{recipient_type}/{recipient_id}
+	TopicNotificationPrefix = "mqtt_call/notification"
+
+	// 紧急通知回执主题（送达、已读）
+	TopicNotificationReceipt = "mqtt_call/notification/receipt"
+
+	// 设备指令下发主题（紧急解锁、重新上锁）
+	TopicDeviceCommand = "mqtt_call/device/command"
+
+	// 设备指令回执主题
+	TopicDeviceCommandAck = "mqtt_call/device/ack"
 )
 
 // 消息结构体定义
@@ -151,11 +174,13 @@ type CallControlMessage struct {
 }
 
 // NewMQTTCallService 创建一个新的MQTT通话服务实现
-func NewMQTTCallService(db *gorm.DB, cfg *config.Config, rtcService InterfaceTencentRTCService) InterfaceMQTTCallService {
+func NewMQTTCallService(db *gorm.DB, cfg *config.Config, rtcService InterfaceTencentRTCService, notifierService InterfaceNotificationService, bus *events.Bus) InterfaceMQTTCallService {
 	service := &MQTTCallService{
 		DB:            db,
 		Config:        cfg,
 		RTCService:    rtcService,
+		Notifier:      notifierService,
+		Events:        bus,
 		CallManager:   models.NewCallManager(),
 		TopicHandlers: make(map[string]mqtt.MessageHandler),
 		IsConnected:   false,
@@ -318,6 +343,9 @@ func (s *MQTTCallService) SubscribeToTopics() error {
 	// 使用QoS 1确保消息至少被传递一次
 	qos := byte(1)
 
+	s.handlersMutex.RLock()
+	defer s.handlersMutex.RUnlock()
+
 	for topic, handler := range s.TopicHandlers {
 		if token := s.Client.Subscribe(topic, qos, handler); token.Wait() && token.Error() != nil {
 			return fmt.Errorf("订阅主题失败 [%s]: %v", topic, token.Error())
@@ -327,6 +355,38 @@ func (s *MQTTCallService) SubscribeToTopics() error {
 	return nil
 }
 
+// RegisterTopicHandler 为其他服务注册主题处理程序，已连接时立即订阅
+func (s *MQTTCallService) RegisterTopicHandler(topic string, handler func(payload []byte)) error {
+	messageHandler := func(_ mqtt.Client, msg mqtt.Message) {
+		// 使用defer和recover防止处理程序panic导致整个服务崩溃
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("[MQTT] 处理主题 %s 的消息发生panic: %v", msg.Topic(), r)
+			}
+		}()
+		handler(msg.Payload())
+	}
+
+	s.handlersMutex.Lock()
+	s.TopicHandlers[topic] = messageHandler
+	s.handlersMutex.Unlock()
+
+	s.connectedMutex.RLock()
+	isConnected := s.IsConnected && s.Client.IsConnected()
+	s.connectedMutex.RUnlock()
+
+	// 未连接时由连接回调统一订阅
+	if !isConnected {
+		return nil
+	}
+
+	if token := s.Client.Subscribe(topic, byte(1), messageHandler); token.Wait() && token.Error() != nil {
+		return fmt.Errorf("订阅主题失败 [%s]: %v", topic, token.Error())
+	}
+	log.Printf("[MQTT] 已订阅主题: %s", topic)
+	return nil
+}
+
 // InitiateCall 发起通话
 func (s *MQTTCallService) InitiateCall(deviceID, residentID string) (string, error) {
 	// 使用互斥锁保护整个通话创建过程
@@ -534,6 +594,7 @@ func (s *MQTTCallService) handleCallSession(callID, deviceID, residentID string,
 			if status == "ringing" {
 				log.Printf("[MQTT] 通话振铃超时: callID=%s", callID)
 				s.EndCallSession(callID, "ring_timeout")
+				s.notifyMissedCall(deviceID, residentID)
 				return
 			}
 
@@ -640,7 +701,7 @@ func (s *MQTTCallService) HandleCallerAction(callID, action, reason string) erro
 // HandleCalleeAction 处理被呼叫方动作
 func (s *MQTTCallService) HandleCalleeAction(callID, action, reason string) error {
 	// 获取会话
-	_, exists := s.CallManager.GetSession(callID)
+	session, exists := s.CallManager.GetSession(callID)
 	if !exists {
 		return fmt.Errorf("会话不存在: %s", callID)
 	}
@@ -731,6 +792,19 @@ func (s *MQTTCallService) HandleCalleeAction(callID, action, reason string) erro
 
 		// 更新通话记录
 		s.updateCallRecord(callID, "callee_"+action, reason)
+
+		// 住户端超时未接听，补发未接来电通知
+		if action == "timeout" {
+			s.notifyMissedCall(session.DeviceID, session.ResidentID)
+		}
+	}
+
+	if action == "answered" {
+		s.Events.Publish(events.CallAnswered{
+			CallID:     callID,
+			DeviceID:   session.DeviceID,
+			ResidentID: session.ResidentID,
+		})
 	}
 
 	return nil
@@ -1419,6 +1493,13 @@ func (s *MQTTCallService) createCallRecord(callID, deviceID, residentID, status
 	// 这里只是示例，实际使用时需要替换成真实的数据库操作
 	log.Printf("[MQTT] 创建通话记录: ID=%s, 设备=%s, 住户=%s, 状态=%s",
 		callID, deviceID, residentID, status)
+
+	s.Events.Publish(events.CallStarted{
+		CallID:     callID,
+		DeviceID:   deviceID,
+		ResidentID: residentID,
+		Status:     status,
+	})
 }
 
 // updateCallRecord 更新通话记录
@@ -1429,6 +1510,12 @@ func (s *MQTTCallService) updateCallRecord(callID, status, reason string) {
 	// 在实际实现中，这里应该更新数据库中的通话记录
 	// 这里只是示例，实际使用时需要替换成真实的数据库操作
 	log.Printf("[MQTT] 更新通话记录: ID=%s, 状态=%s, 原因=%s", callID, status, reason)
+
+	s.Events.Publish(events.CallEnded{
+		CallID: callID,
+		Status: status,
+		Reason: reason,
+	})
 }
 
 // PublishDeviceStatus 发布设备状态
@@ -1436,6 +1523,36 @@ func (s *MQTTCallService) PublishDeviceStatus(deviceID string, status map[string
 	return s.publishMessage(TopicDeviceController, status)
 }
 
+// notifyMissedCall 通过App推送告知住户有未接来电
+func (s *MQTTCallService) notifyMissedCall(deviceID, residentID string) {
+	if s.Notifier == nil {
+		return
+	}
+
+	deviceName := deviceID
+	if id, err := strconv.ParseUint(deviceID, 10, 64); err == nil {
+		var device models.Device
+		if err := s.DB.Select("name").First(&device, id).Error; err == nil && device.Name != "" {
+			deviceName = device.Name
+		}
+	}
+
+	s.Notifier.SendAsync(notifier.Message{
+		Channel:  notifier.ChannelPush,
+		To:       fmt.Sprintf("%s:%s", models.RecipientTypeResident, residentID),
+		Template: "missed_call",
+		Data: map[string]interface{}{
+			"device_name": deviceName,
+			"time":        time.Now().Format("2006-01-02 15:04:05"),
+		},
+	})
+}
+
+// PublishMessage 发布消息到指定主题，供其他服务复用MQTT连接
+func (s *MQTTCallService) PublishMessage(topic string, payload interface{}) error {
+	return s.publishMessage(topic, payload)
+}
+
 // PublishSystemMessage 发布系统消息
 func (s *MQTTCallService) PublishSystemMessage(messageType string, message map[string]interface{}) error {
 	// 创建标准格式的系统消息
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"ilock-http-service/internal/infrastructure/config"
+	"ilock-http-service/internal/infrastructure/notifier"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// InterfaceNotificationService 定义统一通知发送服务接口
+type InterfaceNotificationService interface {
+	Send(msg notifier.Message) error
+	SendAsync(msg notifier.Message)
+	HasChannel(channel notifier.Channel) bool
+	RegisterTemplate(name string, channel notifier.Channel, subject, body string) error
+}
+
+// notificationSendTimeout 单条消息（含重试和限流等待）的最长发送时间
+const notificationSendTimeout = 2 * time.Minute
+
+// NotificationService 通过短信、邮件、App推送和Webhook发送通知的统一入口
+type NotificationService struct {
+	DB         *gorm.DB
+	Config     *config.Config
+	Dispatcher *notifier.Dispatcher
+}
+
+// NewNotificationService 创建通知服务，按配置选择各渠道的发送方
+func NewNotificationService(db *gorm.DB, cfg *config.Config) InterfaceNotificationService {
+	return &NotificationService{
+		DB:         db,
+		Config:     cfg,
+		Dispatcher: notifier.NewDispatcherFromConfig(cfg),
+	}
+}
+
+// 1 Send 同步发送消息，失败时按配置重试
+func (s *NotificationService) Send(msg notifier.Message) error {
+	ctx, cancel := context.WithTimeout(context.Background(), notificationSendTimeout)
+	defer cancel()
+
+	return s.Dispatcher.Send(ctx, msg)
+}
+
+// 2 SendAsync 在后台发送消息，失败只记录日志
+func (s *NotificationService) SendAsync(msg notifier.Message) {
+	go func() {
+		if err := s.Send(msg); err != nil {
+			log.Printf("[Notification] 发送%s消息给 %s 失败: %v", msg.Channel, msg.To, err)
+		}
+	}()
+}
+
+// 3 HasChannel 判断渠道是否可用
+func (s *NotificationService) HasChannel(channel notifier.Channel) bool {
+	return s.Dispatcher.HasChannel(channel)
+}
+
+// 4 RegisterTemplate 注册或覆盖消息模板
+func (s *NotificationService) RegisterTemplate(name string, channel notifier.Channel, subject, body string) error {
+	return s.Dispatcher.RegisterTemplate(name, channel, subject, body)
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"ilock-http-service/internal/domain/models"
+	"ilock-http-service/internal/infrastructure/config"
+	"ilock-http-service/pkg/utils"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrPasswordTooWeak 密码不符合密码策略
+	ErrPasswordTooWeak = errors.New("密码不符合密码策略")
+	// ErrPasswordReused 新密码与最近使用过的密码相同
+	ErrPasswordReused = errors.New("新密码不能与最近使用过的密码相同")
+)
+
+// PasswordPolicy 表示当前生效的密码策略
+type PasswordPolicy struct {
+	MinLength     int  `json:"min_length"`
+	RequireUpper  bool `json:"require_upper"`
+	RequireLower  bool `json:"require_lower"`
+	RequireDigit  bool `json:"require_digit"`
+	RequireSymbol bool `json:"require_symbol"`
+	HistorySize   int  `json:"history_size"` // 新密码不能与最近多少个密码相同
+}
+
+// NewPasswordPolicy 从配置读取密码策略
+func NewPasswordPolicy(cfg *config.Config) PasswordPolicy {
+	return PasswordPolicy{
+		MinLength:     cfg.PasswordMinLength,
+		RequireUpper:  cfg.PasswordRequireUpper,
+		RequireLower:  cfg.PasswordRequireLower,
+		RequireDigit:  cfg.PasswordRequireDigit,
+		RequireSymbol: cfg.PasswordRequireSymbol,
+		HistorySize:   cfg.PasswordHistorySize,
+	}
+}
+
+// Validate 校验密码强度，不符合时返回包含所有未满足要求的 ErrPasswordTooWeak
+func (p PasswordPolicy) Validate(password string) error {
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			symbol = true
+		}
+	}
+
+	var missing []string
+	if utf8.RuneCountInString(password) < p.MinLength {
+		missing = append(missing, fmt.Sprintf("长度至少%d位", p.MinLength))
+	}
+	if p.RequireUpper && !upper {
+		missing = append(missing, "包含大写字母")
+	}
+	if p.RequireLower && !lower {
+		missing = append(missing, "包含小写字母")
+	}
+	if p.RequireDigit && !digit {
+		missing = append(missing, "包含数字")
+	}
+	if p.RequireSymbol && !symbol {
+		missing = append(missing, "包含特殊字符")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: 需要%s", ErrPasswordTooWeak, strings.Join(missing, "、"))
+	}
+	return nil
+}
+
+// hashNewPassword 校验密码策略和最近使用过的密码，返回新密码的哈希；
+// currentHash 为账号当前的密码哈希，新建账号时为空
+func hashNewPassword(db *gorm.DB, cfg *config.Config, account LoginAccount, currentHash, password string) (string, error) {
+	policy := NewPasswordPolicy(cfg)
+	if err := policy.Validate(password); err != nil {
+		return "", err
+	}
+
+	if policy.HistorySize > 0 {
+		var hashes []string
+		if currentHash != "" {
+			hashes = append(hashes, currentHash)
+		}
+		if account.SubjectID > 0 {
+			var history []string
+			if err := db.Model(&models.PasswordHistory{}).
+				Where("subject_type = ? AND subject_id = ?", account.SubjectType, account.SubjectID).
+				Order("id DESC").
+				Limit(policy.HistorySize).
+				Pluck("password_hash", &history).Error; err != nil {
+				return "", err
+			}
+			hashes = append(hashes, history...)
+		}
+		if passwordInHistory(password, hashes) {
+			return "", ErrPasswordReused
+		}
+	}
+
+	hashed, err := utils.HashPassword(password)
+	if err != nil {
+		return "", fmt.Errorf("密码加密失败: %v", err)
+	}
+	return hashed, nil
+}
+
+// passwordInHistory 判断密码是否与任一历史密码哈希相同
+func passwordInHistory(password string, hashes []string) bool {
+	for _, hash := range hashes {
+		if utils.CheckPasswordHash(password, hash) {
+			return true
+		}
+	}
+	return false
+}
+
+// recordPasswordHistory 记录账号的新密码，只保留策略要求的条数
+func recordPasswordHistory(db *gorm.DB, cfg *config.Config, account LoginAccount, hash string) error {
+	if cfg.PasswordHistorySize <= 0 {
+		return nil
+	}
+	if err := db.Create(&models.PasswordHistory{
+		SubjectType:  account.SubjectType,
+		SubjectID:    account.SubjectID,
+		PasswordHash: hash,
+	}).Error; err != nil {
+		return err
+	}
+
+	var keep []uint
+	if err := db.Model(&models.PasswordHistory{}).
+		Where("subject_type = ? AND subject_id = ?", account.SubjectType, account.SubjectID).
+		Order("id DESC").
+		Limit(cfg.PasswordHistorySize).
+		Pluck("id", &keep).Error; err != nil {
+		return err
+	}
+	return db.Where("subject_type = ? AND subject_id = ? AND id NOT IN ?", account.SubjectType, account.SubjectID, keep).
+		Delete(&models.PasswordHistory{}).Error
+}
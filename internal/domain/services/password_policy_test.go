@@ -0,0 +1,100 @@
+package services
+
+import (
+	"errors"
+	"ilock-http-service/internal/infrastructure/config"
+	"ilock-http-service/pkg/utils"
+	"strings"
+	"testing"
+)
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	strict := PasswordPolicy{MinLength: 8, RequireUpper: true, RequireLower: true, RequireDigit: true, RequireSymbol: true}
+
+	tests := []struct {
+		name        string
+		policy      PasswordPolicy
+		password    string
+		wantMissing []string
+	}{
+		{name: "meets every rule", policy: strict, password: "Passw0rd!"},
+		{name: "too short", policy: strict, password: "Pa0!", wantMissing: []string{"长度至少8位"}},
+		{name: "length counts characters not bytes", policy: PasswordPolicy{MinLength: 4}, password: "密码密码"},
+		{name: "no upper", policy: strict, password: "passw0rd!", wantMissing: []string{"包含大写字母"}},
+		{name: "no lower", policy: strict, password: "PASSW0RD!", wantMissing: []string{"包含小写字母"}},
+		{name: "no digit", policy: strict, password: "Password!", wantMissing: []string{"包含数字"}},
+		{name: "no symbol", policy: strict, password: "Passw0rdX", wantMissing: []string{"包含特殊字符"}},
+		{name: "symbol rule off", policy: PasswordPolicy{MinLength: 8, RequireUpper: true, RequireLower: true, RequireDigit: true}, password: "Passw0rdX"},
+		{name: "reports every missing rule", policy: strict, password: "abc", wantMissing: []string{"长度至少8位", "包含大写字母", "包含数字", "包含特殊字符"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate(tt.password)
+			if len(tt.wantMissing) == 0 {
+				if err != nil {
+					t.Fatalf("Validate(%q) = %v, want nil", tt.password, err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrPasswordTooWeak) {
+				t.Fatalf("Validate(%q) = %v, want ErrPasswordTooWeak", tt.password, err)
+			}
+			for _, rule := range tt.wantMissing {
+				if !strings.Contains(err.Error(), rule) {
+					t.Errorf("error %q does not mention %q", err, rule)
+				}
+			}
+		})
+	}
+}
+
+func TestPasswordInHistory(t *testing.T) {
+	var hashes []string
+	for _, password := range []string{"OldPassw0rd!", "OlderPassw0rd!"} {
+		hash, err := utils.HashPassword(password)
+		if err != nil {
+			t.Fatalf("HashPassword: %v", err)
+		}
+		hashes = append(hashes, hash)
+	}
+
+	if !passwordInHistory("OlderPassw0rd!", hashes) {
+		t.Error("password from history should be rejected")
+	}
+	if passwordInHistory("NewPassw0rd!", hashes) {
+		t.Error("new password should be accepted")
+	}
+	if passwordInHistory("OldPassw0rd!", nil) {
+		t.Error("empty history should accept any password")
+	}
+}
+
+func TestHashNewPasswordRejectsCurrentPassword(t *testing.T) {
+	cfg := &config.Config{PasswordMinLength: 8, PasswordRequireDigit: true, PasswordHistorySize: 5}
+	currentHash, err := utils.HashPassword("Current1pass")
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+
+	// SubjectID 为0时不查询历史密码表，只比较当前密码
+	account := LoginAccount{SubjectType: "admin"}
+	if _, err := hashNewPassword(nil, cfg, account, currentHash, "Current1pass"); !errors.Is(err, ErrPasswordReused) {
+		t.Fatalf("reusing current password: err = %v, want ErrPasswordReused", err)
+	}
+	if _, err := hashNewPassword(nil, cfg, account, currentHash, "short1"); !errors.Is(err, ErrPasswordTooWeak) {
+		t.Fatalf("weak password: err = %v, want ErrPasswordTooWeak", err)
+	}
+
+	hashed, err := hashNewPassword(nil, cfg, account, currentHash, "Another1pass")
+	if err != nil {
+		t.Fatalf("new password: %v", err)
+	}
+	if !utils.CheckPasswordHash("Another1pass", hashed) {
+		t.Fatal("returned hash does not match the new password")
+	}
+
+	cfg.PasswordHistorySize = 0
+	if _, err := hashNewPassword(nil, cfg, account, currentHash, "Current1pass"); err != nil {
+		t.Fatalf("history disabled: err = %v, want nil", err)
+	}
+}
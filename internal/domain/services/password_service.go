@@ -0,0 +1,361 @@
+package services
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"ilock-http-service/internal/domain/models"
+	"ilock-http-service/internal/infrastructure/config"
+	"ilock-http-service/internal/infrastructure/notifier"
+	"ilock-http-service/pkg/utils"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+// InterfacePasswordService 定义修改密码和找回密码服务接口
+type InterfacePasswordService interface {
+	GetPolicy() PasswordPolicy
+	ChangePassword(account LoginAccount, oldPassword, newPassword string) error
+	RequestReset(identifier, channel string) error
+	ConfirmReset(identifier, code, newPassword string) (*LoginAccount, error)
+	RegisterNotifier(n PasswordResetNotifier)
+}
+
+var (
+	// ErrResetCodeInvalid 找回密码验证码错误、过期或输错次数过多
+	ErrResetCodeInvalid = errors.New("验证码无效或已过期")
+	// ErrResetTooFrequent 同一账号发送验证码过于频繁
+	ErrResetTooFrequent = errors.New("验证码发送过于频繁，请稍后再试")
+)
+
+// 找回密码的Redis键前缀
+const (
+	passwordResetPrefix         = "password:reset:"          // 待验证的验证码
+	passwordResetAttemptsPrefix = "password:reset:attempts:" // 验证码输错次数
+	passwordResetCooldownPrefix = "password:reset:cooldown:" // 发送验证码的冷却时间
+)
+
+// passwordResetCodeLength 找回密码验证码位数
+const passwordResetCodeLength = 6
+
+// PasswordResetRecipient 找回密码验证码的接收人
+type PasswordResetRecipient struct {
+	Name  string
+	Phone string
+	Email string
+}
+
+// PasswordResetNotifier 发送找回密码验证码的渠道，短信、邮件或其他实现都可以注册
+type PasswordResetNotifier interface {
+	Name() string
+	Accepts(recipient PasswordResetRecipient) bool
+	SendResetCode(recipient PasswordResetRecipient, code string, ttl time.Duration) error
+}
+
+// DispatchPasswordResetNotifier 通过统一通知服务发送验证码短信或邮件
+type DispatchPasswordResetNotifier struct {
+	Channel  notifier.Channel
+	Notifier InterfaceNotificationService
+}
+
+// Name 返回渠道名称
+func (n *DispatchPasswordResetNotifier) Name() string {
+	return string(n.Channel)
+}
+
+// Accepts 短信需要手机号，邮件需要邮箱
+func (n *DispatchPasswordResetNotifier) Accepts(recipient PasswordResetRecipient) bool {
+	return n.address(recipient) != ""
+}
+
+// SendResetCode 使用找回密码模板发送验证码
+func (n *DispatchPasswordResetNotifier) SendResetCode(recipient PasswordResetRecipient, code string, ttl time.Duration) error {
+	return n.Notifier.Send(notifier.Message{
+		Channel:  n.Channel,
+		To:       n.address(recipient),
+		Template: "password_reset",
+		Data: map[string]interface{}{
+			"name": recipient.Name,
+			"code": code,
+			"ttl":  int(ttl.Minutes()),
+		},
+	})
+}
+
+// address 返回接收人在该渠道的地址
+func (n *DispatchPasswordResetNotifier) address(recipient PasswordResetRecipient) string {
+	switch n.Channel {
+	case notifier.ChannelSMS:
+		return recipient.Phone
+	case notifier.ChannelEmail:
+		return recipient.Email
+	default:
+		return ""
+	}
+}
+
+// passwordResetState 保存在Redis中的待验证验证码
+type passwordResetState struct {
+	SubjectType string `json:"subject_type"`
+	SubjectID   uint   `json:"subject_id"`
+	CodeHash    string `json:"code_hash"`
+}
+
+// PasswordService 处理修改密码和通过验证码找回密码，新密码都要符合密码策略且不能重复使用最近的密码
+type PasswordService struct {
+	DB        *gorm.DB
+	Config    *config.Config
+	Redis     InterfaceRedisService
+	notifiers []PasswordResetNotifier
+	mu        sync.RWMutex
+}
+
+// NewPasswordService 创建一个新的密码服务
+func NewPasswordService(db *gorm.DB, cfg *config.Config, redisService InterfaceRedisService) InterfacePasswordService {
+	return &PasswordService{
+		DB:     db,
+		Config: cfg,
+		Redis:  redisService,
+	}
+}
+
+// 1 GetPolicy 获取当前生效的密码策略
+func (s *PasswordService) GetPolicy() PasswordPolicy {
+	return NewPasswordPolicy(s.Config)
+}
+
+// 2 ChangePassword 校验原密码后修改本人密码，居民尚未设置密码时不校验原密码
+func (s *PasswordService) ChangePassword(account LoginAccount, oldPassword, newPassword string) error {
+	currentHash, err := s.currentPasswordHash(account)
+	if err != nil {
+		return err
+	}
+	if (currentHash != "" || account.SubjectType != "user") && !utils.CheckPasswordHash(oldPassword, currentHash) {
+		return ErrOldPasswordIncorrect
+	}
+	return s.setPassword(account, currentHash, newPassword)
+}
+
+// 3 RequestReset 按管理员或物业员工用户名、居民手机号查找账号，通过已注册的渠道发送验证码；
+// channel 为空时使用第一个可以送达的渠道。账号不存在或无法送达时同样返回成功，避免通过响应枚举账号
+func (s *PasswordService) RequestReset(identifier, channel string) error {
+	identifier = strings.TrimSpace(identifier)
+	key := normalizeLoginName(identifier)
+	if key == "" {
+		return ErrResetCodeInvalid
+	}
+
+	cooldown := time.Duration(s.Config.PasswordResetCooldown) * time.Second
+	if cooldown > 0 {
+		fresh, err := s.Redis.SetNX(passwordResetCooldownPrefix+key, time.Now().Unix(), cooldown)
+		if err != nil {
+			return fmt.Errorf("记录验证码发送时间失败: %w", err)
+		}
+		if !fresh {
+			return ErrResetTooFrequent
+		}
+	}
+
+	account, recipient, err := s.findAccount(identifier)
+	if err != nil {
+		return err
+	}
+	if account == nil {
+		return nil
+	}
+
+	n := s.notifierFor(recipient, channel)
+	if n == nil {
+		log.Printf("[Password] 账号 %s:%d 没有可用的找回密码渠道", account.SubjectType, account.SubjectID)
+		return nil
+	}
+
+	code, err := utils.RandomDigits(passwordResetCodeLength)
+	if err != nil {
+		return err
+	}
+	ttl := time.Duration(s.Config.PasswordResetCodeTTL) * time.Minute
+	state := passwordResetState{SubjectType: account.SubjectType, SubjectID: account.SubjectID, CodeHash: hashToken(code)}
+	if err := s.Redis.Set(passwordResetPrefix+key, state, ttl); err != nil {
+		return fmt.Errorf("保存验证码失败: %w", err)
+	}
+	// 新验证码重新计算输错次数
+	if err := s.Redis.Delete(passwordResetAttemptsPrefix + key); err != nil {
+		log.Printf("[Password] 清除 %s 的验证码输错次数失败: %v", key, err)
+	}
+
+	// 后台发送，响应时间不因账号是否存在而不同
+	go func() {
+		if err := n.SendResetCode(recipient, code, ttl); err != nil {
+			log.Printf("[Password] 通过%s发送找回密码验证码给账号 %s:%d 失败: %v", n.Name(), account.SubjectType, account.SubjectID, err)
+		}
+	}()
+	return nil
+}
+
+// 4 ConfirmReset 校验验证码并设置新密码，返回被重置的账号；验证码只能使用一次，输错次数过多后作废
+func (s *PasswordService) ConfirmReset(identifier, code, newPassword string) (*LoginAccount, error) {
+	key := normalizeLoginName(identifier)
+
+	var state passwordResetState
+	if err := s.Redis.Get(passwordResetPrefix+key, &state); err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrResetCodeInvalid
+		}
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashToken(strings.TrimSpace(code))), []byte(state.CodeHash)) != 1 {
+		ttl := time.Duration(s.Config.PasswordResetCodeTTL) * time.Minute
+		attempts, err := s.Redis.Incr(passwordResetAttemptsPrefix+key, ttl)
+		if err != nil {
+			return nil, err
+		}
+		if attempts >= int64(s.Config.PasswordResetMaxAttempts) {
+			s.clearReset(key)
+		}
+		return nil, ErrResetCodeInvalid
+	}
+
+	account := LoginAccount{SubjectType: state.SubjectType, SubjectID: state.SubjectID}
+	currentHash, err := s.currentPasswordHash(account)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			s.clearReset(key)
+			return nil, ErrResetCodeInvalid
+		}
+		return nil, err
+	}
+	// 新密码不符合策略时保留验证码，可以换一个密码重试
+	if err := s.setPassword(account, currentHash, newPassword); err != nil {
+		return nil, err
+	}
+	s.clearReset(key)
+	return &account, nil
+}
+
+// 5 RegisterNotifier 注册找回密码验证码的发送渠道
+func (s *PasswordService) RegisterNotifier(n PasswordResetNotifier) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notifiers = append(s.notifiers, n)
+}
+
+// setPassword 校验密码策略和历史密码后保存新密码，管理员和物业员工修改密码后不再被要求修改密码
+func (s *PasswordService) setPassword(account LoginAccount, currentHash, newPassword string) error {
+	hashed, err := hashNewPassword(s.DB, s.Config, account, currentHash, newPassword)
+	if err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{"password": hashed}
+	if account.SubjectType == "admin" || account.SubjectType == "staff" {
+		updates["must_change_password"] = false
+	}
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		model, err := passwordModel(account.SubjectType)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(model).Where("id = ?", account.SubjectID).Updates(updates).Error; err != nil {
+			return err
+		}
+		return recordPasswordHistory(tx, s.Config, account, hashed)
+	})
+}
+
+// currentPasswordHash 获取账号当前的密码哈希
+func (s *PasswordService) currentPasswordHash(account LoginAccount) (string, error) {
+	model, err := passwordModel(account.SubjectType)
+	if err != nil {
+		return "", err
+	}
+	var hashes []string
+	if err := s.DB.Model(model).Where("id = ?", account.SubjectID).Limit(1).Pluck("password", &hashes).Error; err != nil {
+		return "", err
+	}
+	if len(hashes) == 0 {
+		return "", gorm.ErrRecordNotFound
+	}
+	return hashes[0], nil
+}
+
+// findAccount 依次匹配管理员用户名、物业员工用户名、居民手机号和物业员工手机号，未找到时返回nil
+func (s *PasswordService) findAccount(identifier string) (*LoginAccount, PasswordResetRecipient, error) {
+	var admin models.Admin
+	if err := s.DB.Select("id, username, email, phone").Where("username = ?", identifier).First(&admin).Error; err == nil {
+		return &LoginAccount{SubjectType: "admin", SubjectID: admin.ID},
+			PasswordResetRecipient{Name: admin.Username, Phone: admin.Phone, Email: admin.Email}, nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, PasswordResetRecipient{}, err
+	}
+
+	var staff models.PropertyStaff
+	if err := s.DB.Select("id, username, phone").Where("username = ?", identifier).First(&staff).Error; err == nil {
+		return &LoginAccount{SubjectType: "staff", SubjectID: staff.ID},
+			PasswordResetRecipient{Name: staff.Username, Phone: staff.Phone}, nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, PasswordResetRecipient{}, err
+	}
+
+	var resident models.Resident
+	if err := s.DB.Select("id, name, phone, email").Where("phone = ?", identifier).First(&resident).Error; err == nil {
+		return &LoginAccount{SubjectType: "user", SubjectID: resident.ID},
+			PasswordResetRecipient{Name: resident.Name, Phone: resident.Phone, Email: resident.Email}, nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, PasswordResetRecipient{}, err
+	}
+
+	// 手机号同时属于居民和物业员工时优先找回居民的密码，物业员工可以改用用户名
+	if err := s.DB.Select("id, username, phone").Where("phone = ?", identifier).First(&staff).Error; err == nil {
+		return &LoginAccount{SubjectType: "staff", SubjectID: staff.ID},
+			PasswordResetRecipient{Name: staff.Username, Phone: staff.Phone}, nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, PasswordResetRecipient{}, err
+	}
+
+	return nil, PasswordResetRecipient{}, nil
+}
+
+// notifierFor 选择可以送达接收人的渠道，指定渠道时只使用该渠道
+func (s *PasswordService) notifierFor(recipient PasswordResetRecipient, channel string) PasswordResetNotifier {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, n := range s.notifiers {
+		if channel != "" && n.Name() != channel {
+			continue
+		}
+		if n.Accepts(recipient) {
+			return n
+		}
+	}
+	return nil
+}
+
+// clearReset 作废验证码
+func (s *PasswordService) clearReset(key string) {
+	for _, k := range []string{passwordResetPrefix + key, passwordResetAttemptsPrefix + key} {
+		if err := s.Redis.Delete(k); err != nil {
+			log.Printf("[Password] 清除验证码 %s 失败: %v", k, err)
+		}
+	}
+}
+
+// passwordModel 返回账号类型对应的模型
+func passwordModel(subjectType string) (interface{}, error) {
+	switch subjectType {
+	case "admin":
+		return &models.Admin{}, nil
+	case "staff":
+		return &models.PropertyStaff{}, nil
+	case "user":
+		return &models.Resident{}, nil
+	default:
+		return nil, fmt.Errorf("未知的账号类型: %s", subjectType)
+	}
+}
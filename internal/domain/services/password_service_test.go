@@ -0,0 +1,79 @@
+package services
+
+import (
+	"errors"
+	"ilock-http-service/internal/infrastructure/config"
+	"testing"
+)
+
+// newTestPasswordService 创建只使用Redis的密码服务，并为 identifier 保存验证码 code
+func newTestPasswordService(t *testing.T, maxAttempts int, identifier, code string) (*PasswordService, *fakeRedis) {
+	t.Helper()
+	redisService := newFakeRedis()
+	service := &PasswordService{
+		Config: &config.Config{PasswordResetCodeTTL: 10, PasswordResetMaxAttempts: maxAttempts},
+		Redis:  redisService,
+	}
+	state := passwordResetState{SubjectType: "admin", SubjectID: 1, CodeHash: hashToken(code)}
+	if err := redisService.Set(passwordResetPrefix+normalizeLoginName(identifier), state, 0); err != nil {
+		t.Fatalf("seed reset code: %v", err)
+	}
+	return service, redisService
+}
+
+func TestConfirmResetLocksAfterMaxAttempts(t *testing.T) {
+	service, redisService := newTestPasswordService(t, 3, "Admin", "123456")
+
+	for i := 1; i <= 3; i++ {
+		if _, err := service.ConfirmReset("admin", "000000", "NewPassw0rd!"); !errors.Is(err, ErrResetCodeInvalid) {
+			t.Fatalf("attempt %d: err = %v, want ErrResetCodeInvalid", i, err)
+		}
+		if i < 3 {
+			var attempts int64
+			if err := redisService.Get(passwordResetAttemptsPrefix+"admin", &attempts); err != nil || attempts != int64(i) {
+				t.Fatalf("after attempt %d: attempts = %d, %v", i, attempts, err)
+			}
+		}
+	}
+
+	// 输错次数达到上限后验证码作废，正确的验证码也不能再使用
+	if _, ok := redisService.values[passwordResetPrefix+"admin"]; ok {
+		t.Fatal("reset code should be cleared after the last allowed attempt")
+	}
+	if _, ok := redisService.values[passwordResetAttemptsPrefix+"admin"]; ok {
+		t.Fatal("attempt counter should be cleared together with the code")
+	}
+	if _, err := service.ConfirmReset("admin", "123456", "NewPassw0rd!"); !errors.Is(err, ErrResetCodeInvalid) {
+		t.Fatalf("correct code after lockout: err = %v, want ErrResetCodeInvalid", err)
+	}
+}
+
+func TestConfirmResetKeepsCodeBelowMaxAttempts(t *testing.T) {
+	service, redisService := newTestPasswordService(t, 5, "13800138000", "654321")
+
+	for i := 0; i < 4; i++ {
+		if _, err := service.ConfirmReset(" 13800138000 ", "111111", "NewPassw0rd!"); !errors.Is(err, ErrResetCodeInvalid) {
+			t.Fatalf("attempt %d: err = %v, want ErrResetCodeInvalid", i+1, err)
+		}
+	}
+	if _, ok := redisService.values[passwordResetPrefix+"13800138000"]; !ok {
+		t.Fatal("reset code should survive attempts below the limit")
+	}
+}
+
+func TestConfirmResetWithoutCode(t *testing.T) {
+	service := &PasswordService{Config: &config.Config{PasswordResetMaxAttempts: 5}, Redis: newFakeRedis()}
+	if _, err := service.ConfirmReset("nobody", "123456", "NewPassw0rd!"); !errors.Is(err, ErrResetCodeInvalid) {
+		t.Fatalf("err = %v, want ErrResetCodeInvalid", err)
+	}
+}
+
+func TestConfirmResetRedisUnavailable(t *testing.T) {
+	redisService := newFakeRedis()
+	redisService.err = errors.New("connection refused")
+	service := &PasswordService{Config: &config.Config{PasswordResetMaxAttempts: 5}, Redis: redisService}
+	_, err := service.ConfirmReset("admin", "123456", "NewPassw0rd!")
+	if err == nil || errors.Is(err, ErrResetCodeInvalid) {
+		t.Fatalf("err = %v, want the Redis error", err)
+	}
+}
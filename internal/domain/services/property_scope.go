@@ -0,0 +1,234 @@
+package services
+
+import (
+	"errors"
+	"ilock-http-service/internal/domain/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrOutOfPropertyScope 操作的资源不属于当前用户所在物业
+var ErrOutOfPropertyScope = errors.New("资源不属于当前物业")
+
+// PropertyScope 物业数据范围，PropertyID为空表示不限物业（平台管理员或系统内部调用）
+// 楼号和物业员工直接归属物业；户号、设备通过楼号归属物业；居民通过户号归属物业；通话记录通过设备归属物业
+// 警报、紧急事件、紧急联系人、紧急通知和紧急解锁会话直接记录property_id
+type PropertyScope struct {
+	PropertyID *uint
+}
+
+// Scoped 是否限定了物业
+func (p PropertyScope) Scoped() bool {
+	return p.PropertyID != nil
+}
+
+// Buildings 限定楼号查询范围
+func (p PropertyScope) Buildings(db *gorm.DB) *gorm.DB {
+	if !p.Scoped() {
+		return db
+	}
+	return db.Where("buildings.property_id = ?", *p.PropertyID)
+}
+
+// Households 限定户号查询范围
+func (p PropertyScope) Households(db *gorm.DB) *gorm.DB {
+	if !p.Scoped() {
+		return db
+	}
+	return db.Where("households.building_id IN (?)", p.buildingIDs(db))
+}
+
+// Devices 限定设备查询范围
+func (p PropertyScope) Devices(db *gorm.DB) *gorm.DB {
+	if !p.Scoped() {
+		return db
+	}
+	return db.Where("devices.building_id IN (?)", p.buildingIDs(db))
+}
+
+// Residents 限定居民查询范围
+func (p PropertyScope) Residents(db *gorm.DB) *gorm.DB {
+	if !p.Scoped() {
+		return db
+	}
+	householdIDs := db.Session(&gorm.Session{NewDB: true}).
+		Model(&models.Household{}).
+		Select("households.id").
+		Where("households.building_id IN (?)", p.buildingIDs(db))
+	return db.Where("residents.household_id IN (?)", householdIDs)
+}
+
+// Staff 限定物业员工查询范围
+func (p PropertyScope) Staff(db *gorm.DB) *gorm.DB {
+	if !p.Scoped() {
+		return db
+	}
+	return db.Where("property_staffs.property_id = ?", *p.PropertyID)
+}
+
+// Owned 限定直接记录了property_id的数据的查询范围，如警报、紧急事件、紧急通知和紧急解锁会话
+func (p PropertyScope) Owned(db *gorm.DB) *gorm.DB {
+	if !p.Scoped() {
+		return db
+	}
+	return db.Where("property_id = ?", *p.PropertyID)
+}
+
+// CallRecords 限定通话记录查询范围，通话记录通过设备归属物业
+func (p PropertyScope) CallRecords(db *gorm.DB) *gorm.DB {
+	if !p.Scoped() {
+		return db
+	}
+	deviceIDs := db.Session(&gorm.Session{NewDB: true}).
+		Model(&models.Device{}).
+		Select("devices.id").
+		Where("devices.building_id IN (?)", p.buildingIDs(db))
+	return db.Where("call_records.device_id IN (?)", deviceIDs)
+}
+
+// checkOwned 校验property_id为当前物业，未关联物业的数据只有不限物业时可以操作
+func (p PropertyScope) checkOwned(propertyID *uint) error {
+	if !p.Scoped() {
+		return nil
+	}
+	if propertyID == nil || *propertyID != *p.PropertyID {
+		return ErrOutOfPropertyScope
+	}
+	return nil
+}
+
+// ownProperty 新建直接记录property_id的数据时确定其物业：限定物业时未指定则归属当前物业，指定其他物业返回错误
+func (p PropertyScope) ownProperty(propertyID *uint) (*uint, error) {
+	if !p.Scoped() {
+		return propertyID, nil
+	}
+	if propertyID == nil {
+		id := *p.PropertyID
+		return &id, nil
+	}
+	if *propertyID != *p.PropertyID {
+		return nil, ErrOutOfPropertyScope
+	}
+	return propertyID, nil
+}
+
+// checkBuilding 校验楼号属于当前物业
+func (p PropertyScope) checkBuilding(db *gorm.DB, buildingID uint) error {
+	if !p.Scoped() {
+		return nil
+	}
+	var count int64
+	if err := db.Model(&models.Building{}).Scopes(p.Buildings).Where("buildings.id = ?", buildingID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrOutOfPropertyScope
+	}
+	return nil
+}
+
+// checkHousehold 校验户号属于当前物业
+func (p PropertyScope) checkHousehold(db *gorm.DB, householdID uint) error {
+	if !p.Scoped() {
+		return nil
+	}
+	var count int64
+	if err := db.Model(&models.Household{}).Scopes(p.Households).Where("households.id = ?", householdID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrOutOfPropertyScope
+	}
+	return nil
+}
+
+// checkDevice 校验设备属于当前物业
+func (p PropertyScope) checkDevice(db *gorm.DB, deviceID uint) error {
+	if !p.Scoped() {
+		return nil
+	}
+	var count int64
+	if err := db.Model(&models.Device{}).Scopes(p.Devices).Where("devices.id = ?", deviceID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrOutOfPropertyScope
+	}
+	return nil
+}
+
+// checkResident 校验居民属于当前物业
+func (p PropertyScope) checkResident(db *gorm.DB, residentID uint) error {
+	if !p.Scoped() {
+		return nil
+	}
+	var count int64
+	if err := db.Model(&models.Resident{}).Scopes(p.Residents).Where("residents.id = ?", residentID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrOutOfPropertyScope
+	}
+	return nil
+}
+
+// checkPropertyChange 校验对property_id的修改：限定物业时不允许把数据移出当前物业，未限定时目标物业必须存在
+func (p PropertyScope) checkPropertyChange(db *gorm.DB, updates map[string]interface{}) error {
+	value, ok := updates["property_id"]
+	if !ok {
+		return nil
+	}
+
+	var target *uint
+	switch v := value.(type) {
+	case uint:
+		target = &v
+	case *uint:
+		target = v
+	}
+
+	if p.Scoped() {
+		if target == nil || *target != *p.PropertyID {
+			return ErrOutOfPropertyScope
+		}
+		return nil
+	}
+	if target != nil {
+		return checkPropertyExists(db, *target)
+	}
+	return nil
+}
+
+// buildingIDs 当前物业下所有楼号ID的子查询
+func (p PropertyScope) buildingIDs(db *gorm.DB) *gorm.DB {
+	return db.Session(&gorm.Session{NewDB: true}).
+		Model(&models.Building{}).
+		Select("buildings.id").
+		Where("buildings.property_id = ?", *p.PropertyID)
+}
+
+// checkPropertyExists 校验物业存在
+func checkPropertyExists(db *gorm.DB, propertyID uint) error {
+	var count int64
+	if err := db.Model(&models.Property{}).Where("id = ?", propertyID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrPropertyNotFound
+	}
+	return nil
+}
+
+// ResolveHouseholdPropertyID 通过户号所在楼号查找所属物业，未关联物业时返回nil
+func ResolveHouseholdPropertyID(db *gorm.DB, householdID uint) *uint {
+	var building models.Building
+	err := db.Model(&models.Building{}).
+		Joins("JOIN households ON households.building_id = buildings.id").
+		Where("households.id = ?", householdID).
+		Select("buildings.property_id").
+		First(&building).Error
+	if err != nil {
+		return nil
+	}
+	return building.PropertyID
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"errors"
+	"ilock-http-service/internal/domain/models"
+	"ilock-http-service/internal/infrastructure/config"
+
+	"gorm.io/gorm"
+)
+
+// InterfacePropertyService 定义物业服务接口
+type InterfacePropertyService interface {
+	WithPropertyScope(propertyID *uint) InterfacePropertyService
+	GetAllProperties(page, pageSize int) ([]models.Property, int64, error)
+	GetPropertyByID(id uint) (*models.Property, error)
+	CreateProperty(property *models.Property) error
+	UpdateProperty(id uint, updates map[string]interface{}) (*models.Property, error)
+	DeleteProperty(id uint) error
+}
+
+var (
+	// ErrPropertyNotFound 物业不存在
+	ErrPropertyNotFound = errors.New("物业不存在")
+	// ErrPropertyCodeExists 物业编码已存在
+	ErrPropertyCodeExists = errors.New("物业编码已存在")
+	// ErrPropertyInUse 物业下仍有楼号或员工
+	ErrPropertyInUse = errors.New("该物业下存在楼号或物业员工，无法删除")
+)
+
+// PropertyService 提供物业相关的服务
+type PropertyService struct {
+	DB     *gorm.DB
+	Config *config.Config
+	Scope  PropertyScope
+}
+
+// NewPropertyService 创建一个新的物业服务
+func NewPropertyService(db *gorm.DB, cfg *config.Config) InterfacePropertyService {
+	return &PropertyService{
+		DB:     db,
+		Config: cfg,
+	}
+}
+
+// 0. WithPropertyScope 返回限定在指定物业内的服务，物业管理员只能查看和修改自己的物业
+func (s *PropertyService) WithPropertyScope(propertyID *uint) InterfacePropertyService {
+	scoped := *s
+	scoped.Scope = PropertyScope{PropertyID: propertyID}
+	return &scoped
+}
+
+// 1. GetAllProperties 获取物业列表，支持分页
+func (s *PropertyService) GetAllProperties(page, pageSize int) ([]models.Property, int64, error) {
+	var properties []models.Property
+	var total int64
+
+	query := s.DB.Model(&models.Property{})
+	if s.Scope.Scoped() {
+		query = query.Where("id = ?", *s.Scope.PropertyID)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.Order("id ASC").Limit(pageSize).Offset(offset).Find(&properties).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return properties, total, nil
+}
+
+// 2. GetPropertyByID 根据ID获取物业及其楼号
+func (s *PropertyService) GetPropertyByID(id uint) (*models.Property, error) {
+	if s.Scope.Scoped() && id != *s.Scope.PropertyID {
+		return nil, ErrPropertyNotFound
+	}
+
+	var property models.Property
+	if err := s.DB.Preload("Buildings").First(&property, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPropertyNotFound
+		}
+		return nil, err
+	}
+	return &property, nil
+}
+
+// 3. CreateProperty 创建物业，只有平台管理员可以创建
+func (s *PropertyService) CreateProperty(property *models.Property) error {
+	if s.Scope.Scoped() {
+		return ErrOutOfPropertyScope
+	}
+
+	var count int64
+	if err := s.DB.Model(&models.Property{}).Where("code = ?", property.Code).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrPropertyCodeExists
+	}
+
+	if property.Status == "" {
+		property.Status = "active"
+	}
+
+	return s.DB.Create(property).Error
+}
+
+// 4. UpdateProperty 更新物业信息
+func (s *PropertyService) UpdateProperty(id uint, updates map[string]interface{}) (*models.Property, error) {
+	property, err := s.GetPropertyByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if code, ok := updates["code"].(string); ok && code != property.Code {
+		var count int64
+		if err := s.DB.Model(&models.Property{}).Where("code = ? AND id != ?", code, id).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, ErrPropertyCodeExists
+		}
+	}
+
+	if err := s.DB.Model(property).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+
+	// 同步物业员工上冗余的物业名称
+	if name, ok := updates["name"].(string); ok && name != property.Name {
+		if err := s.DB.Model(&models.PropertyStaff{}).Where("property_id = ?", id).Update("property_name", name).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return s.GetPropertyByID(id)
+}
+
+// 5. DeleteProperty 删除物业，物业下仍有楼号或员工时拒绝删除，只有平台管理员可以删除
+func (s *PropertyService) DeleteProperty(id uint) error {
+	if s.Scope.Scoped() {
+		return ErrOutOfPropertyScope
+	}
+
+	property, err := s.GetPropertyByID(id)
+	if err != nil {
+		return err
+	}
+
+	var buildingCount, staffCount int64
+	if err := s.DB.Model(&models.Building{}).Where("property_id = ?", id).Count(&buildingCount).Error; err != nil {
+		return err
+	}
+	if err := s.DB.Model(&models.PropertyStaff{}).Where("property_id = ?", id).Count(&staffCount).Error; err != nil {
+		return err
+	}
+	if buildingCount > 0 || staffCount > 0 {
+		return ErrPropertyInUse
+	}
+
+	return s.DB.Delete(property).Error
+}
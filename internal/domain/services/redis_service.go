@@ -20,6 +20,8 @@ type InterfaceRedisService interface {
 	GetRTCToken(userID, channelID string) (string, error)
 	GetCallRecordByID(id string) (*models.CallRecord, error)
 	CacheCallRecord(record *models.CallRecord, expiration time.Duration) error
+	SetNX(key string, value interface{}, expiration time.Duration) (bool, error)
+	Incr(key string, expiration time.Duration) (int64, error)
 }
 
 // RedisService handles Redis operations
@@ -97,3 +99,27 @@ func (s *RedisService) CacheCallRecord(record *models.CallRecord, expiration tim
 	key := fmt.Sprintf("call_record:%d", record.ID)
 	return s.Set(key, record, expiration)
 }
+
+// 8 SetNX sets a key only if it does not exist, returns false when the key already exists
+func (s *RedisService) SetNX(key string, value interface{}, expiration time.Duration) (bool, error) {
+	jsonValue, err := json.Marshal(value)
+	if err != nil {
+		return false, err
+	}
+
+	return s.Client.SetNX(s.Ctx, key, jsonValue, expiration).Result()
+}
+
+// 9 Incr increments a counter, the expiration is set when the counter is created
+func (s *RedisService) Incr(key string, expiration time.Duration) (int64, error) {
+	count, err := s.Client.Incr(s.Ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if count == 1 {
+		if err := s.Client.Expire(s.Ctx, key, expiration).Err(); err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
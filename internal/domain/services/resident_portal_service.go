@@ -0,0 +1,317 @@
+package services
+
+import (
+	"errors"
+	"ilock-http-service/internal/domain/events"
+	"ilock-http-service/internal/domain/models"
+	"ilock-http-service/internal/infrastructure/config"
+	"ilock-http-service/pkg/utils"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// InterfaceResidentPortalService 定义居民自助服务接口，所有方法都限定在调用者本人及其所在户号内
+type InterfaceResidentPortalService interface {
+	GetProfile(residentID uint) (*models.Resident, error)
+	UpdateProfile(residentID uint, updates map[string]interface{}) (*models.Resident, error)
+	GetHousehold(residentID uint) (*models.Household, error)
+	GetCallRecords(residentID uint, page, pageSize int) ([]models.CallRecord, int64, error)
+	GetDevices(residentID uint) ([]models.Device, error)
+	GetActivePasscodes(residentID uint) ([]models.Passcode, error)
+	CreatePasscode(residentID uint, passcode *models.Passcode) error
+	RevokePasscode(residentID, passcodeID uint) error
+	GetInbox(residentID uint, query InboxQuery) ([]models.EmergencyNotificationDelivery, int64, int64, error)
+	MarkInboxRead(residentID, notificationID uint) error
+}
+
+var (
+	// ErrResidentNotFound 居民不存在
+	ErrResidentNotFound = errors.New("居民不存在")
+	// ErrResidentNoHousehold 居民未关联户号
+	ErrResidentNoHousehold = errors.New("居民未关联户号")
+	// ErrOldPasswordIncorrect 原密码错误
+	ErrOldPasswordIncorrect = errors.New("原密码错误")
+	// ErrPasscodeNotFound 通行码不存在
+	ErrPasscodeNotFound = errors.New("通行码不存在")
+	// ErrInvalidPasscode 通行码有效期或次数不合法
+	ErrInvalidPasscode = errors.New("通行码有效期或使用次数不合法")
+)
+
+// 通行码参数
+const (
+	passcodeLength      = 6                   // 通行码位数
+	passcodeMaxValidity = 30 * 24 * time.Hour // 最长有效期
+	passcodeDefaultTTL  = 24 * time.Hour      // 未指定失效时间时的有效期
+)
+
+// InboxQuery 收件箱查询条件
+type InboxQuery struct {
+	UnreadOnly bool
+	Page       int
+	PageSize   int
+}
+
+// ResidentPortalService 提供居民自助相关的服务
+type ResidentPortalService struct {
+	DB            *gorm.DB
+	Config        *config.Config
+	Events        *events.Bus
+	Notifications InterfaceEmergencyNotificationService
+}
+
+// NewResidentPortalService 创建一个新的居民自助服务
+func NewResidentPortalService(db *gorm.DB, cfg *config.Config, notifications InterfaceEmergencyNotificationService, bus *events.Bus) InterfaceResidentPortalService {
+	return &ResidentPortalService{
+		DB:            db,
+		Config:        cfg,
+		Events:        bus,
+		Notifications: notifications,
+	}
+}
+
+// 1 GetProfile 获取本人资料，包括所在户号和楼号
+func (s *ResidentPortalService) GetProfile(residentID uint) (*models.Resident, error) {
+	var resident models.Resident
+	if err := s.DB.Preload("Household.Building").First(&resident, residentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrResidentNotFound
+		}
+		return nil, err
+	}
+	return &resident, nil
+}
+
+// 2 UpdateProfile 更新本人资料，只允许修改姓名、邮箱和手机号
+func (s *ResidentPortalService) UpdateProfile(residentID uint, updates map[string]interface{}) (*models.Resident, error) {
+	resident, err := s.GetProfile(residentID)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := make(map[string]interface{})
+	for _, field := range []string{"name", "email", "phone"} {
+		if value, ok := updates[field]; ok {
+			allowed[field] = value
+		}
+	}
+	if len(allowed) == 0 {
+		return resident, nil
+	}
+
+	// 手机号即登录账号，需要检查唯一性
+	if phone, ok := allowed["phone"].(string); ok && phone != resident.Phone {
+		var count int64
+		if err := s.DB.Model(&models.Resident{}).Where("phone = ? AND id != ?", phone, residentID).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, errors.New("手机号已被其他居民使用")
+		}
+	}
+
+	if err := s.DB.Model(&models.Resident{BaseModel: models.BaseModel{ID: residentID}}).Updates(allowed).Error; err != nil {
+		return nil, err
+	}
+
+	updated, err := s.GetProfile(residentID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.Events.Publish(events.ResidentUpdated{Resident: updated, Changes: allowed})
+	return updated, nil
+}
+
+// 3 GetHousehold 获取本人所在户号、楼号和同户成员
+func (s *ResidentPortalService) GetHousehold(residentID uint) (*models.Household, error) {
+	householdID, err := s.householdID(residentID)
+	if err != nil {
+		return nil, err
+	}
+
+	var household models.Household
+	if err := s.DB.Preload("Building").Preload("Residents").First(&household, householdID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrResidentNoHousehold
+		}
+		return nil, err
+	}
+	return &household, nil
+}
+
+// 4 GetCallRecords 分页获取本人的通话记录
+func (s *ResidentPortalService) GetCallRecords(residentID uint, page, pageSize int) ([]models.CallRecord, int64, error) {
+	var records []models.CallRecord
+	var total int64
+
+	query := s.DB.Model(&models.CallRecord{}).Where("resident_id = ?", residentID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.Preload("Device").Order("timestamp DESC").Limit(pageSize).Offset(offset).Find(&records).Error; err != nil {
+		return nil, 0, err
+	}
+	return records, total, nil
+}
+
+// 5 GetDevices 获取本户可用的设备：关联到本户的设备和所在楼号的公共设备（未关联户号）
+func (s *ResidentPortalService) GetDevices(residentID uint) ([]models.Device, error) {
+	household, err := s.GetHousehold(residentID)
+	if err != nil {
+		return nil, err
+	}
+
+	var devices []models.Device
+	query := s.DB.Where("household_id = ?", household.ID)
+	if household.BuildingID > 0 {
+		query = query.Or("building_id = ? AND (household_id = 0 OR household_id IS NULL)", household.BuildingID)
+	}
+	if err := query.Order("id ASC").Find(&devices).Error; err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+// 6 GetActivePasscodes 获取本户当前有效的通行码
+func (s *ResidentPortalService) GetActivePasscodes(residentID uint) ([]models.Passcode, error) {
+	householdID, err := s.householdID(residentID)
+	if err != nil {
+		return nil, err
+	}
+
+	var passcodes []models.Passcode
+	if err := s.DB.
+		Where("household_id = ? AND status = ? AND valid_until > ?", householdID, models.PasscodeStatusActive, time.Now()).
+		Where("max_uses = 0 OR used_count < max_uses").
+		Order("valid_until ASC").
+		Find(&passcodes).Error; err != nil {
+		return nil, err
+	}
+	return passcodes, nil
+}
+
+// 7 CreatePasscode 为本户生成通行码，未指定时间时立即生效、24小时后失效，最长30天
+func (s *ResidentPortalService) CreatePasscode(residentID uint, passcode *models.Passcode) error {
+	householdID, err := s.householdID(residentID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if passcode.ValidFrom.IsZero() {
+		passcode.ValidFrom = now
+	}
+	if passcode.ValidUntil.IsZero() {
+		passcode.ValidUntil = passcode.ValidFrom.Add(passcodeDefaultTTL)
+	}
+	if !passcode.ValidUntil.After(passcode.ValidFrom) || !passcode.ValidUntil.After(now) ||
+		passcode.ValidUntil.Sub(now) > passcodeMaxValidity || passcode.MaxUses < 0 {
+		return ErrInvalidPasscode
+	}
+
+	// 同一户号下有效的通行码不重复
+	for attempt := 0; ; attempt++ {
+		code, err := utils.RandomDigits(passcodeLength)
+		if err != nil {
+			return err
+		}
+		var count int64
+		if err := s.DB.Model(&models.Passcode{}).
+			Where("household_id = ? AND code = ? AND status = ? AND valid_until > ?", householdID, code, models.PasscodeStatusActive, now).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			passcode.Code = code
+			break
+		}
+		if attempt >= 4 {
+			return errors.New("生成通行码失败，请重试")
+		}
+	}
+
+	passcode.HouseholdID = householdID
+	passcode.ResidentID = residentID
+	passcode.Label = strings.TrimSpace(passcode.Label)
+	passcode.UsedCount = 0
+	passcode.Status = models.PasscodeStatusActive
+	return s.DB.Create(passcode).Error
+}
+
+// 8 RevokePasscode 作废本户的通行码
+func (s *ResidentPortalService) RevokePasscode(residentID, passcodeID uint) error {
+	householdID, err := s.householdID(residentID)
+	if err != nil {
+		return err
+	}
+
+	result := s.DB.Model(&models.Passcode{}).
+		Where("id = ? AND household_id = ?", passcodeID, householdID).
+		Update("status", models.PasscodeStatusRevoked)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		var count int64
+		if err := s.DB.Model(&models.Passcode{}).Where("id = ? AND household_id = ?", passcodeID, householdID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrPasscodeNotFound
+		}
+	}
+	return nil
+}
+
+// 9 GetInbox 分页获取本人的站内信，同时返回未读数
+func (s *ResidentPortalService) GetInbox(residentID uint, query InboxQuery) ([]models.EmergencyNotificationDelivery, int64, int64, error) {
+	var deliveries []models.EmergencyNotificationDelivery
+	var total, unread int64
+
+	base := func() *gorm.DB {
+		return s.DB.Model(&models.EmergencyNotificationDelivery{}).
+			Where("recipient_type = ? AND recipient_id = ? AND channel = ?", models.RecipientTypeResident, residentID, NotificationChannelInbox)
+	}
+
+	if err := base().Where("read_at IS NULL").Count(&unread).Error; err != nil {
+		return nil, 0, 0, err
+	}
+
+	db := base()
+	if query.UnreadOnly {
+		db = db.Where("read_at IS NULL")
+	}
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, 0, err
+	}
+
+	offset := (query.Page - 1) * query.PageSize
+	if err := db.Preload("Notification").Order("id DESC").Limit(query.PageSize).Offset(offset).Find(&deliveries).Error; err != nil {
+		return nil, 0, 0, err
+	}
+	return deliveries, total, unread, nil
+}
+
+// 10 MarkInboxRead 将本人收到的通知标记为已读
+func (s *ResidentPortalService) MarkInboxRead(residentID, notificationID uint) error {
+	return s.Notifications.MarkRead(notificationID, models.RecipientTypeResident, residentID)
+}
+
+// householdID 获取居民所在户号ID
+func (s *ResidentPortalService) householdID(residentID uint) (uint, error) {
+	var resident models.Resident
+	if err := s.DB.Select("id, household_id").First(&resident, residentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, ErrResidentNotFound
+		}
+		return 0, err
+	}
+	if resident.HouseholdID == 0 {
+		return 0, ErrResidentNoHousehold
+	}
+	return resident.HouseholdID, nil
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"errors"
 	"ilock-http-service/internal/infrastructure/config"
+	"ilock-http-service/internal/domain/events"
 	"ilock-http-service/internal/domain/models"
 
 	"gorm.io/gorm"
@@ -10,6 +11,7 @@ import (
 
 // InterfaceResidentService defines the resident service interface
 type InterfaceResidentService interface {
+	WithPropertyScope(propertyID *uint) InterfaceResidentService
 	GetAllResidents(page int, pageSize int) ([]models.Resident, int64, error)
 	GetResidentByID(id uint) (*models.Resident, error)
 	CreateResident(resident *models.Resident) error
@@ -21,24 +23,34 @@ type InterfaceResidentService interface {
 type ResidentService struct {
 	DB     *gorm.DB
 	Config *config.Config
+	Events *events.Bus
+	Scope  PropertyScope
 }
 
 // NewResidentService 创建一个新的居民服务
-func NewResidentService(db *gorm.DB, cfg *config.Config) InterfaceResidentService {
+func NewResidentService(db *gorm.DB, cfg *config.Config, bus *events.Bus) InterfaceResidentService {
 	return &ResidentService{
 		DB:     db,
 		Config: cfg,
+		Events: bus,
 	}
 }
 
+// 0 WithPropertyScope 返回限定在指定物业内的服务
+func (s *ResidentService) WithPropertyScope(propertyID *uint) InterfaceResidentService {
+	scoped := *s
+	scoped.Scope = PropertyScope{PropertyID: propertyID}
+	return &scoped
+}
+
 // 1 GetAllResidents 获取所有居民
 func (s *ResidentService) GetAllResidents(page int, pageSize int) ([]models.Resident, int64, error) {
 	var residents []models.Resident
 	var total int64
-	if err := s.DB.Model(&models.Resident{}).Count(&total).Error; err != nil {
+	if err := s.DB.Model(&models.Resident{}).Scopes(s.Scope.Residents).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
-	if err := s.DB.Offset((page - 1) * pageSize).Limit(pageSize).Find(&residents).Error; err != nil {
+	if err := s.DB.Scopes(s.Scope.Residents).Offset((page - 1) * pageSize).Limit(pageSize).Find(&residents).Error; err != nil {
 		return nil, 0, err
 	}
 	return residents, total, nil
@@ -47,7 +59,7 @@ func (s *ResidentService) GetAllResidents(page int, pageSize int) ([]models.Resi
 // 2 GetResidentByID 根据ID获取居民
 func (s *ResidentService) GetResidentByID(id uint) (*models.Resident, error) {
 	var resident models.Resident
-	if err := s.DB.First(&resident, id).Error; err != nil {
+	if err := s.DB.Scopes(s.Scope.Residents).First(&resident, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("居民不存在")
 		}
@@ -80,7 +92,17 @@ func (s *ResidentService) CreateResident(resident *models.Resident) error {
 		return errors.New("必须提供有效的户号ID")
 	}
 
-	return s.DB.Create(resident).Error
+	// 户号必须属于当前物业
+	if err := s.Scope.checkHousehold(s.DB, resident.HouseholdID); err != nil {
+		return err
+	}
+
+	if err := s.DB.Create(resident).Error; err != nil {
+		return err
+	}
+
+	s.Events.Publish(events.ResidentCreated{Resident: resident})
+	return nil
 }
 
 // 4 UpdateResident 更新居民信息
@@ -110,6 +132,9 @@ func (s *ResidentService) UpdateResident(id uint, updates map[string]interface{}
 			}
 			return nil, err
 		}
+		if err := s.Scope.checkHousehold(s.DB, householdID); err != nil {
+			return nil, err
+		}
 	}
 
 	if err := s.DB.Model(resident).Updates(updates).Error; err != nil {
@@ -117,7 +142,13 @@ func (s *ResidentService) UpdateResident(id uint, updates map[string]interface{}
 	}
 
 	// 重新获取更新后的居民信息
-	return s.GetResidentByID(id)
+	updated, err := s.GetResidentByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.Events.Publish(events.ResidentUpdated{Resident: updated, Changes: updates})
+	return updated, nil
 }
 
 // 5 DeleteResident 删除居民
@@ -126,5 +157,10 @@ func (s *ResidentService) DeleteResident(id uint) error {
 	if err != nil {
 		return err
 	}
-	return s.DB.Delete(resident).Error
+	if err := s.DB.Delete(resident).Error; err != nil {
+		return err
+	}
+
+	s.Events.Publish(events.ResidentDeleted{ResidentID: id})
+	return nil
 }
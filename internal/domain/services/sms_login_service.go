@@ -0,0 +1,251 @@
+package services
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"ilock-http-service/internal/domain/models"
+	"ilock-http-service/internal/infrastructure/config"
+	"ilock-http-service/internal/infrastructure/notifier"
+	"ilock-http-service/pkg/utils"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+// InterfaceSMSLoginService 定义居民短信验证码登录服务接口
+type InterfaceSMSLoginService interface {
+	RequestCode(phone string) error
+	VerifyCode(phone, code string, client ClientInfo) (*LoginResult, error)
+	RegisterSender(sender LoginCodeSender)
+}
+
+var (
+	// ErrLoginCodeInvalid 登录验证码错误、过期或输错次数过多
+	ErrLoginCodeInvalid = errors.New("验证码无效或已过期")
+	// ErrLoginCodeTooFrequent 同一手机号发送验证码过于频繁或超过每日上限
+	ErrLoginCodeTooFrequent = errors.New("验证码发送过于频繁，请稍后再试")
+	// ErrSMSLoginUnavailable 没有配置短信发送方
+	ErrSMSLoginUnavailable = errors.New("短信验证码登录未开启")
+)
+
+// 短信验证码登录的Redis键前缀
+const (
+	smsLoginCodePrefix     = "login:sms:"          // 待验证的验证码
+	smsLoginAttemptsPrefix = "login:sms:attempts:" // 验证码输错次数
+	smsLoginCooldownPrefix = "login:sms:cooldown:" // 发送验证码的冷却时间
+	smsLoginDailyPrefix    = "login:sms:daily:"    // 当天发送次数
+)
+
+// smsLoginCodeLength 登录验证码位数
+const smsLoginCodeLength = 6
+
+// LoginCodeSender 发送登录验证码的短信发送方，可以替换为其他实现
+type LoginCodeSender interface {
+	Name() string
+	SendLoginCode(phone, code string, ttl time.Duration) error
+}
+
+// DispatchLoginCodeSender 通过统一通知服务的短信渠道发送登录验证码，
+// 短信发送方由 NOTIFY_SMS_PROVIDER 配置，默认的 log 只把短信写入日志，便于本地调试
+type DispatchLoginCodeSender struct {
+	Notifier InterfaceNotificationService
+}
+
+// Name 返回发送方名称
+func (s *DispatchLoginCodeSender) Name() string {
+	return string(notifier.ChannelSMS)
+}
+
+// SendLoginCode 使用登录验证码模板发送短信
+func (s *DispatchLoginCodeSender) SendLoginCode(phone, code string, ttl time.Duration) error {
+	return s.Notifier.Send(notifier.Message{
+		Channel:  notifier.ChannelSMS,
+		To:       phone,
+		Template: "login_code",
+		Data: map[string]interface{}{
+			"code": code,
+			"ttl":  int(ttl.Minutes()),
+		},
+	})
+}
+
+// smsLoginState 保存在Redis中的待验证登录验证码
+type smsLoginState struct {
+	ResidentID uint   `json:"resident_id"`
+	CodeHash   string `json:"code_hash"`
+}
+
+// SMSLoginService 居民使用手机号和短信验证码登录，登录成功后签发与密码登录相同的令牌
+type SMSLoginService struct {
+	DB     *gorm.DB
+	Config *config.Config
+	Redis  InterfaceRedisService
+	Guard  InterfaceLoginGuardService
+	JWT    InterfaceJWTService
+
+	sender LoginCodeSender
+	mu     sync.RWMutex
+}
+
+// NewSMSLoginService 创建一个新的短信验证码登录服务
+func NewSMSLoginService(db *gorm.DB, cfg *config.Config, redisService InterfaceRedisService, loginGuard InterfaceLoginGuardService, jwtService InterfaceJWTService) InterfaceSMSLoginService {
+	return &SMSLoginService{
+		DB:     db,
+		Config: cfg,
+		Redis:  redisService,
+		Guard:  loginGuard,
+		JWT:    jwtService,
+	}
+}
+
+// 1 RequestCode 向居民手机号发送登录验证码；同一手机号受发送间隔和每日次数限制。
+// 手机号不属于任何居民时同样返回成功，避免通过响应枚举账号
+func (s *SMSLoginService) RequestCode(phone string) error {
+	phone = strings.TrimSpace(phone)
+	if phone == "" {
+		return ErrLoginCodeInvalid
+	}
+
+	s.mu.RLock()
+	sender := s.sender
+	s.mu.RUnlock()
+	if sender == nil {
+		return ErrSMSLoginUnavailable
+	}
+
+	cooldown := time.Duration(s.Config.SMSLoginCooldown) * time.Second
+	if cooldown > 0 {
+		fresh, err := s.Redis.SetNX(smsLoginCooldownPrefix+phone, time.Now().Unix(), cooldown)
+		if err != nil {
+			return fmt.Errorf("记录验证码发送时间失败: %w", err)
+		}
+		if !fresh {
+			return ErrLoginCodeTooFrequent
+		}
+	}
+	if s.Config.SMSLoginDailyLimit > 0 {
+		key := smsLoginDailyPrefix + time.Now().Format("20060102") + ":" + phone
+		count, err := s.Redis.Incr(key, 24*time.Hour)
+		if err != nil {
+			return fmt.Errorf("记录验证码发送次数失败: %w", err)
+		}
+		if count > int64(s.Config.SMSLoginDailyLimit) {
+			return ErrLoginCodeTooFrequent
+		}
+	}
+
+	var resident models.Resident
+	if err := s.DB.Select("id, phone").Where("phone = ?", phone).First(&resident).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	code, err := utils.RandomDigits(smsLoginCodeLength)
+	if err != nil {
+		return err
+	}
+	ttl := time.Duration(s.Config.SMSLoginCodeTTL) * time.Minute
+	state := smsLoginState{ResidentID: resident.ID, CodeHash: hashToken(code)}
+	if err := s.Redis.Set(smsLoginCodePrefix+phone, state, ttl); err != nil {
+		return fmt.Errorf("保存验证码失败: %w", err)
+	}
+	// 新验证码重新计算输错次数
+	if err := s.Redis.Delete(smsLoginAttemptsPrefix + phone); err != nil {
+		log.Printf("[SMSLogin] 清除 %s 的验证码输错次数失败: %v", phone, err)
+	}
+
+	// 后台发送，响应时间不因手机号是否存在而不同
+	go func() {
+		if err := sender.SendLoginCode(phone, code, ttl); err != nil {
+			log.Printf("[SMSLogin] 通过%s发送登录验证码给居民 %d 失败: %v", sender.Name(), resident.ID, err)
+		}
+	}()
+	return nil
+}
+
+// 2 VerifyCode 校验登录验证码，通过后签发令牌；验证码只能使用一次，输错次数过多后作废。
+// 与密码登录共用IP封禁、递增延迟和账号锁定，输错时按手机号和IP计数
+func (s *SMSLoginService) VerifyCode(phone, code string, client ClientInfo) (*LoginResult, error) {
+	phone = strings.TrimSpace(phone)
+	if err := s.Guard.CheckIP(client.IP); err != nil {
+		return nil, err
+	}
+	if err := s.Guard.CheckUsername(phone); err != nil {
+		return nil, err
+	}
+
+	var state smsLoginState
+	if err := s.Redis.Get(smsLoginCodePrefix+phone, &state); err != nil {
+		if errors.Is(err, redis.Nil) {
+			s.Guard.RecordFailure(phone, client.IP, nil)
+			return nil, ErrLoginCodeInvalid
+		}
+		return nil, err
+	}
+
+	account := LoginAccount{SubjectType: "user", SubjectID: state.ResidentID}
+	if err := s.Guard.CheckAccount(account); err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashToken(strings.TrimSpace(code))), []byte(state.CodeHash)) != 1 {
+		s.Guard.RecordFailure(phone, client.IP, []LoginAccount{account})
+		ttl := time.Duration(s.Config.SMSLoginCodeTTL) * time.Minute
+		attempts, err := s.Redis.Incr(smsLoginAttemptsPrefix+phone, ttl)
+		if err != nil {
+			return nil, err
+		}
+		if attempts >= int64(s.Config.SMSLoginMaxAttempts) {
+			s.clearCode(phone)
+		}
+		return nil, ErrLoginCodeInvalid
+	}
+	s.clearCode(phone)
+
+	var resident models.Resident
+	if err := s.DB.First(&resident, state.ResidentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLoginCodeInvalid
+		}
+		return nil, err
+	}
+	// 发送验证码后手机号被修改时，验证码不再有效
+	if resident.Phone != phone {
+		return nil, ErrLoginCodeInvalid
+	}
+
+	s.Guard.RecordSuccess(phone)
+	tokens, err := s.JWT.IssueTokens(resident.ID, "user", ResolveHouseholdPropertyID(s.DB, resident.HouseholdID), client)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginResult{
+		TokenPair: tokens,
+		Username:  resident.Name,
+		Phone:     resident.Phone,
+		CreatedAt: resident.CreatedAt,
+	}, nil
+}
+
+// 3 RegisterSender 注册登录验证码的短信发送方，后注册的替换先注册的
+func (s *SMSLoginService) RegisterSender(sender LoginCodeSender) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sender = sender
+}
+
+// clearCode 作废验证码
+func (s *SMSLoginService) clearCode(phone string) {
+	for _, k := range []string{smsLoginCodePrefix + phone, smsLoginAttemptsPrefix + phone} {
+		if err := s.Redis.Delete(k); err != nil {
+			log.Printf("[SMSLogin] 清除验证码 %s 失败: %v", k, err)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"errors"
+	"ilock-http-service/internal/domain/models"
+	"ilock-http-service/internal/infrastructure/config"
+
+	"gorm.io/gorm"
+)
+
+// InterfaceStaffPortalService 定义物业员工工作台服务接口，数据限定在员工负责的设备范围内
+type InterfaceStaffPortalService interface {
+	GetDevices(staffID uint) ([]models.StaffDeviceRelation, error)
+	GetCallRecords(staffID uint, query AssignedQuery) ([]models.CallRecord, int64, error)
+	GetAlarms(staffID uint, query AssignedQuery) ([]models.EmergencyAlarm, int64, error)
+	GetAccessLogs(staffID uint, query AssignedQuery) ([]models.AccessLog, int64, error)
+}
+
+// AssignedQuery 员工工作台列表查询条件
+type AssignedQuery struct {
+	DeviceID uint   // 只看某台负责的设备，0表示全部
+	Status   string // 警报状态，只对警报列表生效
+	Page     int
+	PageSize int
+}
+
+// StaffPortalService 提供物业员工查看本人负责范围的服务
+type StaffPortalService struct {
+	DB     *gorm.DB
+	Config *config.Config
+}
+
+// NewStaffPortalService 创建一个新的物业员工工作台服务
+func NewStaffPortalService(db *gorm.DB, cfg *config.Config) InterfaceStaffPortalService {
+	return &StaffPortalService{
+		DB:     db,
+		Config: cfg,
+	}
+}
+
+// 1 GetDevices 获取本人负责的设备及职责
+func (s *StaffPortalService) GetDevices(staffID uint) ([]models.StaffDeviceRelation, error) {
+	if err := s.checkStaff(staffID); err != nil {
+		return nil, err
+	}
+
+	var relations []models.StaffDeviceRelation
+	if err := s.DB.Preload("Device.Building").Where("staff_id = ?", staffID).Order("device_id ASC").Find(&relations).Error; err != nil {
+		return nil, err
+	}
+	return relations, nil
+}
+
+// 2 GetCallRecords 分页获取负责设备的通话记录
+func (s *StaffPortalService) GetCallRecords(staffID uint, query AssignedQuery) ([]models.CallRecord, int64, error) {
+	if err := s.checkStaff(staffID); err != nil {
+		return nil, 0, err
+	}
+
+	var records []models.CallRecord
+	var total int64
+
+	db := s.DB.Model(&models.CallRecord{}).Where("device_id IN (?)", s.deviceIDs(staffID, query.DeviceID))
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (query.Page - 1) * query.PageSize
+	if err := db.Preload("Device").Preload("Resident").Order("timestamp DESC").Limit(query.PageSize).Offset(offset).Find(&records).Error; err != nil {
+		return nil, 0, err
+	}
+	return records, total, nil
+}
+
+// 3 GetAlarms 分页获取与本人相关的警报：指派给本人的警报，以及负责设备上报事件触发的警报
+func (s *StaffPortalService) GetAlarms(staffID uint, query AssignedQuery) ([]models.EmergencyAlarm, int64, error) {
+	if err := s.checkStaff(staffID); err != nil {
+		return nil, 0, err
+	}
+
+	var alarms []models.EmergencyAlarm
+	var total int64
+
+	deviceAlarms := s.DB.Model(&models.DeviceEvent{}).
+		Select("alarm_id").
+		Where("alarm_id IS NOT NULL AND device_id IN (?)", s.deviceIDs(staffID, query.DeviceID))
+
+	db := s.DB.Model(&models.EmergencyAlarm{})
+	if query.DeviceID > 0 {
+		db = db.Where("id IN (?)", deviceAlarms)
+	} else {
+		db = db.Where("assigned_to = ? OR id IN (?)", staffID, deviceAlarms)
+	}
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (query.Page - 1) * query.PageSize
+	if err := db.Preload("Assignee").Order("timestamp DESC").Limit(query.PageSize).Offset(offset).Find(&alarms).Error; err != nil {
+		return nil, 0, err
+	}
+	return alarms, total, nil
+}
+
+// 4 GetAccessLogs 分页获取负责设备的门禁记录
+func (s *StaffPortalService) GetAccessLogs(staffID uint, query AssignedQuery) ([]models.AccessLog, int64, error) {
+	if err := s.checkStaff(staffID); err != nil {
+		return nil, 0, err
+	}
+
+	var logs []models.AccessLog
+	var total int64
+
+	db := s.DB.Model(&models.AccessLog{}).Where("device_id IN (?)", s.deviceIDs(staffID, query.DeviceID))
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (query.Page - 1) * query.PageSize
+	if err := db.Preload("Device").Preload("Resident").Order("timestamp DESC").Limit(query.PageSize).Offset(offset).Find(&logs).Error; err != nil {
+		return nil, 0, err
+	}
+	return logs, total, nil
+}
+
+// deviceIDs 返回员工负责的设备ID子查询，deviceID 不为0时只保留该设备
+func (s *StaffPortalService) deviceIDs(staffID, deviceID uint) *gorm.DB {
+	query := s.DB.Model(&models.StaffDeviceRelation{}).Select("device_id").Where("staff_id = ?", staffID)
+	if deviceID > 0 {
+		query = query.Where("device_id = ?", deviceID)
+	}
+	return query
+}
+
+// checkStaff 校验物业员工存在
+func (s *StaffPortalService) checkStaff(staffID uint) error {
+	var staff models.PropertyStaff
+	if err := s.DB.Select("id").First(&staff, staffID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrStaffNotFound
+		}
+		return err
+	}
+	return nil
+}
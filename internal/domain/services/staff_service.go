@@ -11,6 +11,7 @@ import (
 
 // InterfaceStaffService defines the staff service interface
 type InterfaceStaffService interface {
+	WithPropertyScope(propertyID *uint) InterfaceStaffService
 	GetAllStaff(page, pageSize int, search string) ([]models.PropertyStaff, int64, error)
 	GetStaffByID(id uint) (*models.PropertyStaff, error)
 	CreateStaff(staff *models.PropertyStaff) error
@@ -18,12 +19,43 @@ type InterfaceStaffService interface {
 	DeleteStaff(id uint) error
 	GetStaffDevices(staffID uint) ([]models.Device, error)
 	GetStaffByIDWithDevices(id uint) (*models.PropertyStaff, error)
+	GetStaffDeviceRelations(staffID uint) ([]models.StaffDeviceRelation, error)
+	AssignDevice(staffID, deviceID uint, role string) (*models.StaffDeviceRelation, error)
+	UnassignDevice(staffID, deviceID uint) error
+	SetStaffDevices(staffID uint, deviceIDs []uint) error
+	SetCredentials(id uint, username, password string, requireChange bool) (*models.PropertyStaff, error)
+	ResetPassword(id uint) (*models.PropertyStaff, string, error)
+	SetStatus(id uint, status string) (*models.PropertyStaff, error)
 }
 
+var (
+	// ErrStaffNotFound 物业员工不存在
+	ErrStaffNotFound = errors.New("物业员工不存在")
+	// ErrStaffDeviceNotFound 设备不存在
+	ErrStaffDeviceNotFound = errors.New("设备不存在")
+	// ErrStaffDeviceNotAssigned 设备未分配给该物业员工
+	ErrStaffDeviceNotAssigned = errors.New("设备未分配给该物业员工")
+	// ErrStaffUsernameExists 用户名已被其他物业员工使用
+	ErrStaffUsernameExists = errors.New("用户名已被其他物业员工使用")
+	// ErrStaffStatusInvalid 物业员工状态无效
+	ErrStaffStatusInvalid = errors.New("物业员工状态只能是 active、inactive 或 suspended")
+)
+
+// staffStatuses 可以由管理员设置的物业员工状态，locked 由登录防暴力破解设置、解锁后恢复
+var staffStatuses = map[string]bool{
+	models.StaffStatusActive:    true,
+	models.StaffStatusInactive:  true,
+	models.StaffStatusSuspended: true,
+}
+
+// staffTemporaryPasswordLength 重置密码时生成的临时密码的最小长度
+const staffTemporaryPasswordLength = 12
+
 // StaffService 提供物业人员相关的服务
 type StaffService struct {
 	DB     *gorm.DB
 	Config *config.Config
+	Scope  PropertyScope
 }
 
 // NewStaffService 创建一个新的物业人员服务
@@ -34,16 +66,23 @@ func NewStaffService(db *gorm.DB, cfg *config.Config) InterfaceStaffService {
 	}
 }
 
+// 0 WithPropertyScope 返回限定在指定物业内的服务
+func (s *StaffService) WithPropertyScope(propertyID *uint) InterfaceStaffService {
+	scoped := *s
+	scoped.Scope = PropertyScope{PropertyID: propertyID}
+	return &scoped
+}
+
 // 1 GetAllStaff 获取所有物业人员，支持分页和搜索
 func (s *StaffService) GetAllStaff(page, pageSize int, search string) ([]models.PropertyStaff, int64, error) {
 	var staff []models.PropertyStaff
 	var total int64
 
-	query := s.DB.Model(&models.PropertyStaff{})
+	query := s.DB.Model(&models.PropertyStaff{}).Scopes(s.Scope.Staff)
 
 	// 如果有搜索关键词，添加搜索条件
 	if search != "" {
-		query = query.Where("name LIKE ? OR phone LIKE ? OR property_name LIKE ?",
+		query = query.Where("username LIKE ? OR phone LIKE ? OR property_name LIKE ?",
 			"%"+search+"%", "%"+search+"%", "%"+search+"%")
 	}
 
@@ -64,9 +103,9 @@ func (s *StaffService) GetAllStaff(page, pageSize int, search string) ([]models.
 // 2 GetStaffByID 根据ID获取物业人员
 func (s *StaffService) GetStaffByID(id uint) (*models.PropertyStaff, error) {
 	var staff models.PropertyStaff
-	if err := s.DB.First(&staff, id).Error; err != nil {
+	if err := s.DB.Scopes(s.Scope.Staff).First(&staff, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("物业员工不存在")
+			return nil, ErrStaffNotFound
 		}
 		return nil, err
 	}
@@ -75,31 +114,55 @@ func (s *StaffService) GetStaffByID(id uint) (*models.PropertyStaff, error) {
 
 // 3 CreateStaff 创建新物业人员
 func (s *StaffService) CreateStaff(staff *models.PropertyStaff) error {
-	// 验证手机号唯一性
-	var count int64
-	if err := s.DB.Model(&models.PropertyStaff{}).Where("phone = ?", staff.Phone).Count(&count).Error; err != nil {
+	// 验证手机号唯一性，也不能与其他员工的用户名相同
+	taken, err := s.loginNameTaken(0, staff.Phone)
+	if err != nil {
 		return err
 	}
-	if count > 0 {
+	if taken {
 		return errors.New("手机号已被使用")
 	}
 
-	// 验证用户名唯一性
-	if err := s.DB.Model(&models.PropertyStaff{}).Where("username = ?", staff.Username).Count(&count).Error; err != nil {
+	if staff.Status != "" && !staffStatuses[staff.Status] {
+		return ErrStaffStatusInvalid
+	}
+
+	// 验证用户名唯一性，也不能与其他员工的手机号相同
+	if taken, err = s.loginNameTaken(0, staff.Username); err != nil {
 		return err
 	}
-	if count > 0 {
+	if taken {
 		return errors.New("用户名已存在")
 	}
 
-	// 处理密码（在实际应用中应该进行哈希处理）
-	hashedPassword, err := utils.HashPassword(staff.Password)
+	// 限定物业时员工归属当前物业；物业名称跟随所属物业
+	if s.Scope.Scoped() {
+		staff.PropertyID = s.Scope.PropertyID
+	}
+	if staff.PropertyID != nil {
+		var property models.Property
+		if err := s.DB.First(&property, *staff.PropertyID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrPropertyNotFound
+			}
+			return err
+		}
+		staff.PropertyName = property.Name
+	}
+
+	// 校验密码策略并设置密码哈希
+	hashedPassword, err := hashNewPassword(s.DB, s.Config, LoginAccount{SubjectType: "staff"}, "", staff.Password)
 	if err != nil {
-		return errors.New("密码加密失败")
+		return err
 	}
 	staff.Password = hashedPassword
 
-	return s.DB.Create(staff).Error
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(staff).Error; err != nil {
+			return err
+		}
+		return recordPasswordHistory(tx, s.Config, LoginAccount{SubjectType: "staff", SubjectID: staff.ID}, hashedPassword)
+	})
 }
 
 // 4 UpdateStaff 更新物业人员信息
@@ -109,38 +172,60 @@ func (s *StaffService) UpdateStaff(id uint, updates map[string]interface{}) (*mo
 		return nil, err
 	}
 
-	// 如果更新手机号，需要检查唯一性
+	// 如果更新手机号，需要检查唯一性，也不能与其他员工的用户名相同
 	if phone, ok := updates["phone"].(string); ok && phone != staff.Phone {
-		var count int64
-		if err := s.DB.Model(&models.PropertyStaff{}).Where("phone = ? AND id != ?", phone, id).Count(&count).Error; err != nil {
+		taken, err := s.loginNameTaken(id, phone)
+		if err != nil {
 			return nil, err
 		}
-		if count > 0 {
+		if taken {
 			return nil, errors.New("手机号已被其他物业员工使用")
 		}
 	}
 
 	// 如果更新用户名，需要检查唯一性
 	if username, ok := updates["username"].(string); ok && username != staff.Username {
-		var count int64
-		if err := s.DB.Model(&models.PropertyStaff{}).Where("username = ? AND id != ?", username, id).Count(&count).Error; err != nil {
+		if err := s.checkUsername(id, username); err != nil {
 			return nil, err
 		}
-		if count > 0 {
-			return nil, errors.New("用户名已被其他物业员工使用")
+	}
+
+	if status, ok := updates["status"].(string); ok && status != staff.Status && !staffStatuses[status] {
+		return nil, ErrStaffStatusInvalid
+	}
+
+	if err := s.Scope.checkPropertyChange(s.DB, updates); err != nil {
+		return nil, err
+	}
+	if propertyID, ok := updates["property_id"].(uint); ok {
+		var property models.Property
+		if err := s.DB.First(&property, propertyID).Error; err != nil {
+			return nil, err
 		}
+		updates["property_name"] = property.Name
 	}
 
-	// 如果更新密码，需要进行哈希处理
+	// 如果更新密码，需要校验密码策略和历史密码并进行哈希处理
+	account := LoginAccount{SubjectType: "staff", SubjectID: staff.ID}
+	hashedPassword := ""
 	if password, ok := updates["password"].(string); ok {
-		hashedPassword, err := utils.HashPassword(password)
+		hashedPassword, err = hashNewPassword(s.DB, s.Config, account, staff.Password, password)
 		if err != nil {
-			return nil, errors.New("密码加密失败")
+			return nil, err
 		}
 		updates["password"] = hashedPassword
 	}
 
-	if err := s.DB.Model(staff).Updates(updates).Error; err != nil {
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(staff).Updates(updates).Error; err != nil {
+			return err
+		}
+		if hashedPassword == "" {
+			return nil
+		}
+		return recordPasswordHistory(tx, s.Config, account, hashedPassword)
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -154,10 +239,15 @@ func (s *StaffService) DeleteStaff(id uint) error {
 	if err != nil {
 		return err
 	}
-	return s.DB.Delete(staff).Error
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("staff_id = ?", staff.ID).Delete(&models.StaffDeviceRelation{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(staff).Error
+	})
 }
 
-// 6 GetStaffDevices 获取物业人员管理的设备列表
+// 6 GetStaffDevices 获取物业人员负责的设备列表（通过员工设备关系表）
 func (s *StaffService) GetStaffDevices(staffID uint) ([]models.Device, error) {
 	// 检查物业人员是否存在
 	staff, err := s.GetStaffByID(staffID)
@@ -165,28 +255,284 @@ func (s *StaffService) GetStaffDevices(staffID uint) ([]models.Device, error) {
 		return nil, err
 	}
 
-	// 查询所有关联该物业人员的设备
 	var devices []models.Device
-	if err := s.DB.Where("property_id = ?", staff.ID).Find(&devices).Error; err != nil {
+	if err := s.DB.
+		Joins("JOIN staff_device_relations ON staff_device_relations.device_id = devices.id").
+		Where("staff_device_relations.staff_id = ?", staff.ID).
+		Order("devices.id ASC").
+		Find(&devices).Error; err != nil {
 		return nil, err
 	}
 
 	return devices, nil
 }
 
-// 7 GetStaffByIDWithDevices 获取物业人员信息及其管理的设备
+// 7 GetStaffByIDWithDevices 获取物业人员信息及其负责的设备
 func (s *StaffService) GetStaffByIDWithDevices(id uint) (*models.PropertyStaff, error) {
 	staff, err := s.GetStaffByID(id)
 	if err != nil {
 		return nil, err
 	}
 
-	// 手动加载设备信息
-	var devices []models.Device
-	if err := s.DB.Where("property_id = ?", staff.ID).Find(&devices).Error; err != nil {
+	devices, err := s.GetStaffDevices(staff.ID)
+	if err != nil {
 		return nil, err
 	}
 	staff.Devices = devices
 
 	return staff, nil
 }
+
+// 8 GetStaffDeviceRelations 获取物业人员的设备分配记录，包含设备信息和职责
+func (s *StaffService) GetStaffDeviceRelations(staffID uint) ([]models.StaffDeviceRelation, error) {
+	if _, err := s.GetStaffByID(staffID); err != nil {
+		return nil, err
+	}
+
+	var relations []models.StaffDeviceRelation
+	if err := s.DB.Preload("Device").Where("staff_id = ?", staffID).Order("device_id ASC").Find(&relations).Error; err != nil {
+		return nil, err
+	}
+	return relations, nil
+}
+
+// 9 AssignDevice 将设备分配给物业人员，已分配时更新职责；设备必须属于员工所在物业
+func (s *StaffService) AssignDevice(staffID, deviceID uint, role string) (*models.StaffDeviceRelation, error) {
+	staff, err := s.GetStaffByID(staffID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkAssignableDevice(staff, deviceID); err != nil {
+		return nil, err
+	}
+	if role == "" {
+		role = models.StaffDeviceRoleMaintainer
+	}
+
+	var relation models.StaffDeviceRelation
+	err = s.DB.Where("staff_id = ? AND device_id = ?", staffID, deviceID).First(&relation).Error
+	switch {
+	case err == nil:
+		if relation.Role != role {
+			if err := s.DB.Model(&relation).Update("role", role).Error; err != nil {
+				return nil, err
+			}
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		relation = models.StaffDeviceRelation{StaffID: staffID, DeviceID: deviceID, Role: role}
+		if err := s.DB.Create(&relation).Error; err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	if err := s.DB.Preload("Device").First(&relation, relation.ID).Error; err != nil {
+		return nil, err
+	}
+	return &relation, nil
+}
+
+// 10 UnassignDevice 取消物业人员与设备的分配关系
+func (s *StaffService) UnassignDevice(staffID, deviceID uint) error {
+	if _, err := s.GetStaffByID(staffID); err != nil {
+		return err
+	}
+
+	result := s.DB.Where("staff_id = ? AND device_id = ?", staffID, deviceID).Delete(&models.StaffDeviceRelation{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrStaffDeviceNotAssigned
+	}
+	return nil
+}
+
+// 11 SetStaffDevices 将物业人员负责的设备替换为指定列表，保留仍在列表中的设备的职责
+func (s *StaffService) SetStaffDevices(staffID uint, deviceIDs []uint) error {
+	staff, err := s.GetStaffByID(staffID)
+	if err != nil {
+		return err
+	}
+	for _, deviceID := range deviceIDs {
+		if err := s.checkAssignableDevice(staff, deviceID); err != nil {
+			return err
+		}
+	}
+
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		remove := tx.Where("staff_id = ?", staffID)
+		if len(deviceIDs) > 0 {
+			remove = remove.Where("device_id NOT IN ?", deviceIDs)
+		}
+		if err := remove.Delete(&models.StaffDeviceRelation{}).Error; err != nil {
+			return err
+		}
+
+		var existing []uint
+		if err := tx.Model(&models.StaffDeviceRelation{}).Where("staff_id = ?", staffID).Pluck("device_id", &existing).Error; err != nil {
+			return err
+		}
+		assigned := make(map[uint]bool, len(existing))
+		for _, id := range existing {
+			assigned[id] = true
+		}
+
+		for _, deviceID := range deviceIDs {
+			if assigned[deviceID] {
+				continue
+			}
+			assigned[deviceID] = true
+			relation := models.StaffDeviceRelation{StaffID: staffID, DeviceID: deviceID, Role: models.StaffDeviceRoleMaintainer}
+			if err := tx.Create(&relation).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// 12 SetCredentials 设置物业员工的登录用户名和密码，为空的字段不修改；
+// requireChange 为真时员工下次登录后必须先修改密码
+func (s *StaffService) SetCredentials(id uint, username, password string, requireChange bool) (*models.PropertyStaff, error) {
+	staff, err := s.GetStaffByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make(map[string]interface{})
+	if username != "" && username != staff.Username {
+		if err := s.checkUsername(id, username); err != nil {
+			return nil, err
+		}
+		updates["username"] = username
+	}
+
+	account := LoginAccount{SubjectType: "staff", SubjectID: staff.ID}
+	hashedPassword := ""
+	if password != "" {
+		hashedPassword, err = hashNewPassword(s.DB, s.Config, account, staff.Password, password)
+		if err != nil {
+			return nil, err
+		}
+		updates["password"] = hashedPassword
+		updates["must_change_password"] = requireChange
+	}
+	if len(updates) == 0 {
+		return staff, nil
+	}
+
+	if err := s.saveCredentials(staff, updates, hashedPassword); err != nil {
+		return nil, err
+	}
+	return s.GetStaffByID(id)
+}
+
+// 13 ResetPassword 为物业员工生成符合密码策略的临时密码，员工登录后必须先修改密码；
+// 临时密码只在返回值中出现一次
+func (s *StaffService) ResetPassword(id uint) (*models.PropertyStaff, string, error) {
+	staff, err := s.GetStaffByID(id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	length := staffTemporaryPasswordLength
+	if minLength := NewPasswordPolicy(s.Config).MinLength; minLength > length {
+		length = minLength
+	}
+	password, err := utils.RandomPassword(length)
+	if err != nil {
+		return nil, "", err
+	}
+
+	account := LoginAccount{SubjectType: "staff", SubjectID: staff.ID}
+	hashedPassword, err := hashNewPassword(s.DB, s.Config, account, staff.Password, password)
+	if err != nil {
+		return nil, "", err
+	}
+	updates := map[string]interface{}{
+		"password":             hashedPassword,
+		"must_change_password": true,
+	}
+	if err := s.saveCredentials(staff, updates, hashedPassword); err != nil {
+		return nil, "", err
+	}
+
+	staff, err = s.GetStaffByID(id)
+	if err != nil {
+		return nil, "", err
+	}
+	return staff, password, nil
+}
+
+// 14 SetStatus 启用或停用物业员工账号，只有 active 状态的员工可以登录
+func (s *StaffService) SetStatus(id uint, status string) (*models.PropertyStaff, error) {
+	if !staffStatuses[status] {
+		return nil, ErrStaffStatusInvalid
+	}
+	staff, err := s.GetStaffByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if staff.Status == status {
+		return staff, nil
+	}
+
+	if err := s.DB.Model(staff).Update("status", status).Error; err != nil {
+		return nil, err
+	}
+	return s.GetStaffByID(id)
+}
+
+// checkUsername 校验用户名没有被其他物业员工用作用户名或手机号
+func (s *StaffService) checkUsername(id uint, username string) error {
+	taken, err := s.loginNameTaken(id, username)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrStaffUsernameExists
+	}
+	return nil
+}
+
+// loginNameTaken 判断登录名是否已被其他物业员工用作用户名或手机号。
+// 登录时先按用户名再按手机号查找员工，一个员工的用户名与另一个员工的手机号相同时，后者将无法用手机号登录
+func (s *StaffService) loginNameTaken(id uint, name string) (bool, error) {
+	var count int64
+	if err := s.DB.Model(&models.PropertyStaff{}).
+		Where("(username = ? OR phone = ?) AND id != ?", name, name, id).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// saveCredentials 保存登录凭证，修改了密码时同时记录历史密码
+func (s *StaffService) saveCredentials(staff *models.PropertyStaff, updates map[string]interface{}, hashedPassword string) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(staff).Updates(updates).Error; err != nil {
+			return err
+		}
+		if hashedPassword == "" {
+			return nil
+		}
+		return recordPasswordHistory(tx, s.Config, LoginAccount{SubjectType: "staff", SubjectID: staff.ID}, hashedPassword)
+	})
+}
+
+// checkAssignableDevice 校验设备存在且属于当前物业和员工所在物业
+func (s *StaffService) checkAssignableDevice(staff *models.PropertyStaff, deviceID uint) error {
+	var count int64
+	if err := s.DB.Model(&models.Device{}).Where("id = ?", deviceID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrStaffDeviceNotFound
+	}
+	if err := s.Scope.checkDevice(s.DB, deviceID); err != nil {
+		return err
+	}
+	return PropertyScope{PropertyID: staff.PropertyID}.checkDevice(s.DB, deviceID)
+}
@@ -0,0 +1,453 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"ilock-http-service/internal/domain/models"
+	"ilock-http-service/internal/infrastructure/config"
+	"ilock-http-service/pkg/utils"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// InterfaceTwoFactorService 定义管理员两步验证服务接口
+type InterfaceTwoFactorService interface {
+	GetStatus(adminID uint) (*TwoFactorStatus, error)
+	Setup(adminID uint) (*TwoFactorSetup, error)
+	Enable(adminID uint, code string) ([]string, error)
+	Disable(adminID uint, password, code string) error
+	RegenerateRecoveryCodes(adminID uint, code string) ([]string, error)
+	Reset(adminID, operatorID uint, ip string) error
+	IsEnabled(adminID uint) (bool, error)
+	Verify(adminID uint, code string) error
+	SetupRequired(adminID uint) (bool, error)
+}
+
+var (
+	// ErrTwoFactorCodeInvalid 验证码或恢复码错误
+	ErrTwoFactorCodeInvalid = errors.New("两步验证码无效")
+	// ErrTwoFactorAlreadyEnabled 已经启用两步验证
+	ErrTwoFactorAlreadyEnabled = errors.New("已启用两步验证")
+	// ErrTwoFactorNotEnabled 尚未启用两步验证
+	ErrTwoFactorNotEnabled = errors.New("尚未启用两步验证")
+	// ErrTwoFactorNotSetup 启用前需要先生成密钥
+	ErrTwoFactorNotSetup = errors.New("请先生成两步验证密钥")
+	// ErrTwoFactorRequired 账号的权限要求启用两步验证，不能关闭
+	ErrTwoFactorRequired = errors.New("当前账号的权限要求启用两步验证，不能关闭")
+)
+
+// 审计日志中的两步验证事件
+const (
+	AuditTwoFactorEnabled       = "two_factor_enabled"
+	AuditTwoFactorDisabled      = "two_factor_disabled"
+	AuditTwoFactorReset         = "two_factor_reset"
+	AuditTwoFactorRecoveryCodes = "two_factor_recovery_codes"
+	AuditTwoFactorRecoveryUsed  = "two_factor_recovery_used"
+)
+
+// 恢复码数量和格式：10个 xxxx-xxxx 形式的十六进制字符串
+const (
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 8
+)
+
+// totpAllowedSkew 验证码允许前后偏差的时间步数
+const totpAllowedSkew = 1
+
+// TwoFactorStatus 表示管理员的两步验证状态
+type TwoFactorStatus struct {
+	Enabled                bool       `json:"enabled"`
+	EnabledAt              *time.Time `json:"enabled_at,omitempty"`
+	Required               bool       `json:"required"`                 // 账号权限是否要求启用两步验证
+	RecoveryCodesRemaining int        `json:"recovery_codes_remaining"` // 剩余未使用的恢复码数量
+}
+
+// TwoFactorSetup 表示绑定身份验证器所需的信息
+type TwoFactorSetup struct {
+	Secret          string `json:"secret"`           // Base32密钥，无法扫码时手动输入
+	ProvisioningURI string `json:"provisioning_uri"` // otpauth:// 地址，生成二维码供身份验证器App扫描
+}
+
+// TwoFactorService 提供管理员TOTP两步验证：绑定、校验、恢复码和强制策略
+type TwoFactorService struct {
+	DB     *gorm.DB
+	Config *config.Config
+	RBAC   InterfaceRBACService
+}
+
+// NewTwoFactorService 创建一个新的两步验证服务
+func NewTwoFactorService(db *gorm.DB, cfg *config.Config, rbacService InterfaceRBACService) InterfaceTwoFactorService {
+	return &TwoFactorService{
+		DB:     db,
+		Config: cfg,
+		RBAC:   rbacService,
+	}
+}
+
+// 1 GetStatus 获取管理员的两步验证状态
+func (s *TwoFactorService) GetStatus(adminID uint) (*TwoFactorStatus, error) {
+	status := &TwoFactorStatus{}
+
+	record, err := s.find(adminID)
+	if err != nil {
+		return nil, err
+	}
+	if record != nil && record.EnabledAt != nil {
+		status.Enabled = true
+		status.EnabledAt = record.EnabledAt
+
+		var remaining int64
+		if err := s.DB.Model(&models.AdminRecoveryCode{}).
+			Where("admin_id = ? AND used_at IS NULL", adminID).
+			Count(&remaining).Error; err != nil {
+			return nil, err
+		}
+		status.RecoveryCodesRemaining = int(remaining)
+	}
+
+	if status.Required, err = s.policyApplies(adminID); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// 2 Setup 生成新的TOTP密钥，用身份验证器扫码后调用 Enable 完成绑定；已启用时需要先关闭
+func (s *TwoFactorService) Setup(adminID uint) (*TwoFactorSetup, error) {
+	var admin models.Admin
+	if err := s.DB.Select("id, username").First(&admin, adminID).Error; err != nil {
+		return nil, err
+	}
+
+	record, err := s.find(adminID)
+	if err != nil {
+		return nil, err
+	}
+	if record != nil && record.EnabledAt != nil {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := utils.EncryptString(s.encryptionKey(), secret)
+	if err != nil {
+		return nil, fmt.Errorf("加密两步验证密钥失败: %v", err)
+	}
+
+	// 重新生成时覆盖尚未启用的密钥
+	if record == nil {
+		record = &models.AdminTwoFactor{AdminID: adminID}
+	}
+	record.Secret = encrypted
+	record.LastUsedStep = 0
+	if err := s.DB.Save(record).Error; err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorSetup{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(s.Config.TwoFactorIssuer, admin.Username, secret),
+	}, nil
+}
+
+// 3 Enable 用身份验证器生成的验证码确认绑定，返回一次性展示的恢复码
+func (s *TwoFactorService) Enable(adminID uint, code string) ([]string, error) {
+	record, err := s.find(adminID)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, ErrTwoFactorNotSetup
+	}
+	if record.EnabledAt != nil {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if err := s.verifyTOTP(record, code); err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(record).Update("enabled_at", time.Now()).Error; err != nil {
+			return err
+		}
+		var err error
+		codes, err = replaceRecoveryCodes(tx, adminID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit(adminID, AuditTwoFactorEnabled, adminID)
+	return codes, nil
+}
+
+// 4 Disable 关闭两步验证，需要当前密码和验证码（或恢复码）；权限要求启用两步验证的账号不能关闭
+func (s *TwoFactorService) Disable(adminID uint, password, code string) error {
+	var admin models.Admin
+	if err := s.DB.Select("id, password").First(&admin, adminID).Error; err != nil {
+		return err
+	}
+	if !utils.CheckPasswordHash(password, admin.Password) {
+		return ErrOldPasswordIncorrect
+	}
+
+	required, err := s.policyApplies(adminID)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrTwoFactorRequired
+	}
+
+	if err := s.Verify(adminID, code); err != nil {
+		return err
+	}
+	if err := s.remove(adminID); err != nil {
+		return err
+	}
+
+	s.audit(adminID, AuditTwoFactorDisabled, adminID)
+	return nil
+}
+
+// 5 RegenerateRecoveryCodes 使用验证码重新生成恢复码，原有恢复码全部失效
+func (s *TwoFactorService) RegenerateRecoveryCodes(adminID uint, code string) ([]string, error) {
+	record, err := s.find(adminID)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil || record.EnabledAt == nil {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	// 只接受身份验证器的验证码，丢失手机的账号应由其他管理员重置
+	if err := s.verifyTOTP(record, code); err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = replaceRecoveryCodes(tx, adminID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit(adminID, AuditTwoFactorRecoveryCodes, adminID)
+	return codes, nil
+}
+
+// 6 Reset 由其他管理员重置丢失身份验证器的管理员的两步验证，该管理员下次登录后重新绑定
+func (s *TwoFactorService) Reset(adminID, operatorID uint, ip string) error {
+	record, err := s.find(adminID)
+	if err != nil {
+		return err
+	}
+	if record == nil {
+		return ErrTwoFactorNotEnabled
+	}
+	if err := s.remove(adminID); err != nil {
+		return err
+	}
+
+	s.auditWithIP(operatorID, AuditTwoFactorReset, adminID, ip)
+	return nil
+}
+
+// 7 IsEnabled 判断管理员是否已启用两步验证
+func (s *TwoFactorService) IsEnabled(adminID uint) (bool, error) {
+	record, err := s.find(adminID)
+	if err != nil {
+		return false, err
+	}
+	return record != nil && record.EnabledAt != nil, nil
+}
+
+// 8 Verify 校验登录时输入的验证码或恢复码：同一验证码只能使用一次，恢复码使用后失效
+func (s *TwoFactorService) Verify(adminID uint, code string) error {
+	record, err := s.find(adminID)
+	if err != nil {
+		return err
+	}
+	if record == nil || record.EnabledAt == nil {
+		return ErrTwoFactorNotEnabled
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) == utils.TOTPDigits {
+		return s.verifyTOTP(record, code)
+	}
+	return s.useRecoveryCode(adminID, code)
+}
+
+// 9 SetupRequired 判断管理员是否拥有要求两步验证的权限但尚未启用
+func (s *TwoFactorService) SetupRequired(adminID uint) (bool, error) {
+	required, err := s.policyApplies(adminID)
+	if err != nil || !required {
+		return false, err
+	}
+	enabled, err := s.IsEnabled(adminID)
+	if err != nil {
+		return false, err
+	}
+	return !enabled, nil
+}
+
+// find 获取管理员的两步验证记录，不存在时返回nil
+func (s *TwoFactorService) find(adminID uint) (*models.AdminTwoFactor, error) {
+	var record models.AdminTwoFactor
+	if err := s.DB.Where("admin_id = ?", adminID).First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &record, nil
+}
+
+// verifyTOTP 校验TOTP验证码，通过后记录时间步，防止同一验证码在有效期内被重放
+func (s *TwoFactorService) verifyTOTP(record *models.AdminTwoFactor, code string) error {
+	secret, err := utils.DecryptString(s.encryptionKey(), record.Secret)
+	if err != nil {
+		return fmt.Errorf("解密两步验证密钥失败: %v", err)
+	}
+
+	step, err := matchTOTP(secret, code, record.LastUsedStep, time.Now())
+	if err != nil {
+		return err
+	}
+
+	// 条件更新保证并发请求中同一时间步只能通过一次
+	result := s.DB.Model(&models.AdminTwoFactor{}).
+		Where("id = ? AND last_used_step < ?", record.ID, step).
+		Update("last_used_step", step)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTwoFactorCodeInvalid
+	}
+	return nil
+}
+
+// matchTOTP 校验验证码并返回匹配的时间步，不晚于上次通过的时间步视为重放
+func matchTOTP(secret, code string, lastUsedStep int64, now time.Time) (int64, error) {
+	step, ok := utils.ValidateTOTP(secret, code, now, totpAllowedSkew)
+	if !ok || step <= lastUsedStep {
+		return 0, ErrTwoFactorCodeInvalid
+	}
+	return step, nil
+}
+
+// useRecoveryCode 使用一个未使用的恢复码
+func (s *TwoFactorService) useRecoveryCode(adminID uint, code string) error {
+	normalized := normalizeRecoveryCode(code)
+	if len(normalized) != recoveryCodeLength {
+		return ErrTwoFactorCodeInvalid
+	}
+
+	result := s.DB.Model(&models.AdminRecoveryCode{}).
+		Where("admin_id = ? AND code_hash = ? AND used_at IS NULL", adminID, hashToken(normalized)).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTwoFactorCodeInvalid
+	}
+
+	s.audit(adminID, AuditTwoFactorRecoveryUsed, adminID)
+	return nil
+}
+
+// remove 删除管理员的两步验证密钥和恢复码
+func (s *TwoFactorService) remove(adminID uint) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("admin_id = ?", adminID).Delete(&models.AdminRecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("admin_id = ?", adminID).Delete(&models.AdminTwoFactor{}).Error
+	})
+}
+
+// policyApplies 判断管理员是否拥有配置中要求两步验证的任一权限
+func (s *TwoFactorService) policyApplies(adminID uint) (bool, error) {
+	for _, permission := range strings.Split(s.Config.TwoFactorRequiredPermissions, ",") {
+		permission = strings.TrimSpace(permission)
+		if permission == "" {
+			continue
+		}
+		ok, err := s.RBAC.HasPermission("admin", adminID, permission)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// encryptionKey 加密TOTP密钥使用的密钥，未单独配置时使用JWT密钥
+func (s *TwoFactorService) encryptionKey() string {
+	if s.Config.TwoFactorEncryptionKey != "" {
+		return s.Config.TwoFactorEncryptionKey
+	}
+	return s.Config.JWTSecretKey
+}
+
+// audit 把两步验证事件写入审计日志
+func (s *TwoFactorService) audit(adminID uint, action string, target uint) {
+	s.auditWithIP(adminID, action, target, "")
+}
+
+// auditWithIP 把两步验证事件写入审计日志，target 为两步验证所属的管理员
+func (s *TwoFactorService) auditWithIP(adminID uint, action string, target uint, ip string) {
+	entry := &models.SystemLog{
+		AdminID:   &adminID,
+		ActorType: "admin",
+		ActorID:   adminID,
+		Action:    action,
+		Target:    fmt.Sprintf("admin:%d", target),
+		IPAddress: truncate(ip, 45),
+		Timestamp: time.Now(),
+	}
+	if err := s.DB.Create(entry).Error; err != nil {
+		log.Printf("[TwoFactor] 写入审计日志 %s admin:%d 失败: %v", action, target, err)
+	}
+}
+
+// replaceRecoveryCodes 删除原有恢复码并生成新的一组，数据库只保存哈希
+func replaceRecoveryCodes(tx *gorm.DB, adminID uint) ([]string, error) {
+	if err := tx.Unscoped().Where("admin_id = ?", adminID).Delete(&models.AdminRecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]models.AdminRecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw, err := utils.RandomHex(recoveryCodeLength / 2)
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, raw[:recoveryCodeLength/2]+"-"+raw[recoveryCodeLength/2:])
+		records = append(records, models.AdminRecoveryCode{AdminID: adminID, CodeHash: hashToken(raw)})
+	}
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// normalizeRecoveryCode 去掉恢复码中的分隔符和空白并统一为小写
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
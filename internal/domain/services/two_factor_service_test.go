@@ -0,0 +1,69 @@
+package services
+
+import (
+	"errors"
+	"ilock-http-service/pkg/utils"
+	"testing"
+	"time"
+)
+
+const testTOTPSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestMatchTOTPReplayGuard(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	current := utils.TOTPStep(now)
+	codeAt := func(step int64) string {
+		code, err := utils.TOTPCode(testTOTPSecret, step)
+		if err != nil {
+			t.Fatalf("TOTPCode: %v", err)
+		}
+		return code
+	}
+
+	tests := []struct {
+		name         string
+		code         string
+		lastUsedStep int64
+		wantStep     int64
+		wantErr      error
+	}{
+		{name: "first use", code: codeAt(current), wantStep: current},
+		{name: "newer than last use", code: codeAt(current), lastUsedStep: current - 1, wantStep: current},
+		{name: "same code reused", code: codeAt(current), lastUsedStep: current, wantErr: ErrTwoFactorCodeInvalid},
+		{name: "older code after newer one", code: codeAt(current - 1), lastUsedStep: current, wantErr: ErrTwoFactorCodeInvalid},
+		{name: "previous step within skew", code: codeAt(current - 1), lastUsedStep: current - 2, wantStep: current - 1},
+		{name: "outside skew", code: codeAt(current - 2), wantErr: ErrTwoFactorCodeInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, err := matchTOTP(testTOTPSecret, tt.code, tt.lastUsedStep, now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if step != tt.wantStep {
+				t.Fatalf("step = %d, want %d", step, tt.wantStep)
+			}
+		})
+	}
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "a1b2-c3d4", want: "a1b2c3d4"},
+		{in: "A1B2-C3D4", want: "a1b2c3d4"},
+		{in: "  a1b2 c3d4\n", want: "a1b2c3d4"},
+		{in: "a1b2c3d4", want: "a1b2c3d4"},
+		{in: "a1-b2-c3-d4", want: "a1b2c3d4"},
+	}
+	for _, tt := range tests {
+		if got := normalizeRecoveryCode(tt.in); got != tt.want {
+			t.Errorf("normalizeRecoveryCode(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+	if got := normalizeRecoveryCode("a1b2-c3d"); len(got) == recoveryCodeLength {
+		t.Errorf("short code %q should not have the recovery code length", got)
+	}
+}
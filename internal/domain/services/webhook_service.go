@@ -0,0 +1,594 @@
+package services
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"ilock-http-service/internal/domain/events"
+	"ilock-http-service/internal/domain/models"
+	"ilock-http-service/internal/infrastructure/config"
+	"ilock-http-service/pkg/utils"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// InterfaceWebhookService 定义Webhook订阅与投递服务接口
+type InterfaceWebhookService interface {
+	CreateSubscription(subscription *models.WebhookSubscription) error
+	UpdateSubscription(id uint, updates map[string]interface{}) (*models.WebhookSubscription, error)
+	DeleteSubscription(id uint) error
+	GetSubscriptions() ([]models.WebhookSubscription, error)
+	GetSubscriptionByID(id uint) (*models.WebhookSubscription, error)
+	RotateSecret(id uint) (string, error)
+	Emit(eventType string, data interface{})
+	SubscribeEvents(bus *events.Bus)
+	GetDeliveries(query WebhookDeliveryQuery) ([]models.WebhookDelivery, int64, error)
+	ReplayDelivery(id uint) (*models.WebhookDelivery, error)
+	Stop()
+}
+
+var (
+	// ErrWebhookNotFound Webhook订阅不存在
+	ErrWebhookNotFound = errors.New("Webhook订阅不存在")
+	// ErrWebhookDeliveryNotFound Webhook投递记录不存在
+	ErrWebhookDeliveryNotFound = errors.New("Webhook投递记录不存在")
+	// ErrWebhookNotReplayable 只有失败的投递可以重放
+	ErrWebhookNotReplayable = errors.New("只有失败的投递可以重放")
+	// ErrInvalidWebhook Webhook地址或事件类型不合法
+	ErrInvalidWebhook = errors.New("Webhook地址或事件类型不合法")
+)
+
+// Webhook事件类型
+const (
+	WebhookEventCallStarted    = "call.started"    // 发起呼叫
+	WebhookEventCallAnswered   = "call.answered"   // 住户接听
+	WebhookEventCallEnded      = "call.ended"      // 通话结束，包括拒接、超时和挂断
+	WebhookEventDeviceOnline   = "device.online"   // 设备上线
+	WebhookEventDeviceOffline  = "device.offline"  // 设备离线
+	WebhookEventAlarmTriggered = "alarm.triggered" // 紧急警报触发
+	WebhookEventAlarmResolved  = "alarm.resolved"  // 紧急警报解决
+	WebhookEventAccessGranted  = "access.granted"  // 开门成功
+	WebhookEventAccessDenied   = "access.denied"   // 开门被拒绝
+)
+
+// WebhookEventTypes 所有可订阅的事件类型
+var WebhookEventTypes = []string{
+	WebhookEventCallStarted, WebhookEventCallAnswered, WebhookEventCallEnded,
+	WebhookEventDeviceOnline, WebhookEventDeviceOffline,
+	WebhookEventAlarmTriggered, WebhookEventAlarmResolved,
+	WebhookEventAccessGranted, WebhookEventAccessDenied,
+}
+
+// Webhook请求头
+const (
+	WebhookHeaderEvent     = "X-Webhook-Event"
+	WebhookHeaderID        = "X-Webhook-Id"
+	WebhookHeaderTimestamp = "X-Webhook-Timestamp"
+	WebhookHeaderSignature = "X-Webhook-Signature"
+)
+
+// WebhookEvent Webhook请求体
+type WebhookEvent struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// WebhookDeliveryQuery 投递记录查询条件
+type WebhookDeliveryQuery struct {
+	SubscriptionID uint
+	EventType      string
+	Status         string
+	Page           int
+	PageSize       int
+}
+
+// WebhookService 将领域事件推送给订阅的外部系统，失败按指数退避重试
+type WebhookService struct {
+	DB       *gorm.DB
+	Config   *config.Config
+	Client   *http.Client
+	stopChan chan struct{}
+	stopOnce sync.Once
+}
+
+// NewWebhookService 创建Webhook服务并启动重试任务
+func NewWebhookService(db *gorm.DB, cfg *config.Config) InterfaceWebhookService {
+	timeout := time.Duration(cfg.WebhookTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	service := &WebhookService{
+		DB:       db,
+		Config:   cfg,
+		Client:   &http.Client{Timeout: timeout},
+		stopChan: make(chan struct{}),
+	}
+
+	// 启动失败投递的重试任务
+	go service.startRetryTask()
+
+	return service
+}
+
+// 1 CreateSubscription 创建Webhook订阅，未提供密钥时自动生成
+func (s *WebhookService) CreateSubscription(subscription *models.WebhookSubscription) error {
+	events, err := normalizeWebhookEvents(subscription.Events)
+	if err != nil {
+		return err
+	}
+	if err := validateWebhookURL(subscription.URL); err != nil {
+		return err
+	}
+	subscription.Events = events
+
+	if subscription.Secret == "" {
+		secret, err := utils.RandomHex(32)
+		if err != nil {
+			return fmt.Errorf("生成签名密钥失败: %w", err)
+		}
+		subscription.Secret = secret
+	}
+	subscription.Active = true
+
+	return s.DB.Create(subscription).Error
+}
+
+// 2 UpdateSubscription 更新Webhook订阅
+func (s *WebhookService) UpdateSubscription(id uint, updates map[string]interface{}) (*models.WebhookSubscription, error) {
+	subscription, err := s.GetSubscriptionByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if events, ok := updates["events"].(string); ok {
+		normalized, err := normalizeWebhookEvents(events)
+		if err != nil {
+			return nil, err
+		}
+		updates["events"] = normalized
+	}
+	if rawURL, ok := updates["url"].(string); ok {
+		if err := validateWebhookURL(rawURL); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.DB.Model(subscription).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+
+	return s.GetSubscriptionByID(id)
+}
+
+// 3 DeleteSubscription 删除Webhook订阅，尚未完成的投递会在下次尝试时标记为失败
+func (s *WebhookService) DeleteSubscription(id uint) error {
+	subscription, err := s.GetSubscriptionByID(id)
+	if err != nil {
+		return err
+	}
+	return s.DB.Delete(subscription).Error
+}
+
+// 4 GetSubscriptions 获取所有Webhook订阅
+func (s *WebhookService) GetSubscriptions() ([]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+	if err := s.DB.Order("id ASC").Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+// 5 GetSubscriptionByID 根据ID获取Webhook订阅
+func (s *WebhookService) GetSubscriptionByID(id uint) (*models.WebhookSubscription, error) {
+	var subscription models.WebhookSubscription
+	if err := s.DB.First(&subscription, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// 6 RotateSecret 重新生成签名密钥并返回新密钥
+func (s *WebhookService) RotateSecret(id uint) (string, error) {
+	subscription, err := s.GetSubscriptionByID(id)
+	if err != nil {
+		return "", err
+	}
+
+	secret, err := utils.RandomHex(32)
+	if err != nil {
+		return "", fmt.Errorf("生成签名密钥失败: %w", err)
+	}
+	if err := s.DB.Model(subscription).Update("secret", secret).Error; err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// 7 Emit 为订阅了该事件的Webhook生成投递记录并在后台发送，不阻塞调用方
+func (s *WebhookService) Emit(eventType string, data interface{}) {
+	event := WebhookEvent{
+		ID:        uuid.New().String(),
+		Type:      eventType,
+		CreatedAt: time.Now(),
+		Data:      data,
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("[Webhook] 序列化 %s 事件失败: %v", eventType, err)
+		return
+	}
+
+	go func() {
+		var subscriptions []models.WebhookSubscription
+		if err := s.DB.Where("active = ?", true).Find(&subscriptions).Error; err != nil {
+			log.Printf("[Webhook] 查询 %s 事件的订阅失败: %v", eventType, err)
+			return
+		}
+
+		now := time.Now()
+		for _, subscription := range subscriptions {
+			if !subscription.Subscribes(eventType) {
+				continue
+			}
+
+			delivery := models.WebhookDelivery{
+				SubscriptionID: subscription.ID,
+				EventID:        event.ID,
+				EventType:      eventType,
+				Payload:        string(payload),
+				Status:         models.WebhookDeliveryPending,
+				NextAttemptAt:  &now,
+			}
+			if err := s.DB.Create(&delivery).Error; err != nil {
+				log.Printf("[Webhook] 保存订阅 %d 的投递记录失败: %v", subscription.ID, err)
+				continue
+			}
+			s.attempt(delivery.ID)
+		}
+	}()
+}
+
+// 7.1 SubscribeEvents 订阅事件总线，把可推送的领域事件转换为Webhook投递
+func (s *WebhookService) SubscribeEvents(bus *events.Bus) {
+	bus.SubscribeAsync(events.All, func(event events.Event) {
+		if eventType, data, ok := webhookPayload(event); ok {
+			s.Emit(eventType, data)
+		}
+	})
+}
+
+// 8 GetDeliveries 分页查询投递记录
+func (s *WebhookService) GetDeliveries(query WebhookDeliveryQuery) ([]models.WebhookDelivery, int64, error) {
+	var deliveries []models.WebhookDelivery
+	var total int64
+
+	db := s.DB.Model(&models.WebhookDelivery{})
+	if query.SubscriptionID > 0 {
+		db = db.Where("subscription_id = ?", query.SubscriptionID)
+	}
+	if query.EventType != "" {
+		db = db.Where("event_type = ?", query.EventType)
+	}
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (query.Page - 1) * query.PageSize
+	if err := db.Order("id DESC").Limit(query.PageSize).Offset(offset).Find(&deliveries).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return deliveries, total, nil
+}
+
+// 9 ReplayDelivery 重放失败的投递，重置重试次数并立即发送一次
+func (s *WebhookService) ReplayDelivery(id uint) (*models.WebhookDelivery, error) {
+	now := time.Now()
+	result := s.DB.Model(&models.WebhookDelivery{}).
+		Where("id = ? AND status = ?", id, models.WebhookDeliveryFailed).
+		Updates(map[string]interface{}{
+			"status":          models.WebhookDeliveryPending,
+			"attempts":        0,
+			"last_error":      "",
+			"next_attempt_at": now,
+			"replays":         gorm.Expr("replays + 1"),
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		if _, err := s.getDelivery(id); err != nil {
+			return nil, err
+		}
+		return nil, ErrWebhookNotReplayable
+	}
+
+	s.attempt(id)
+
+	return s.getDelivery(id)
+}
+
+// 10 Stop 停止重试任务
+func (s *WebhookService) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stopChan)
+	})
+}
+
+// attempt 认领并发送一条待投递记录，失败时安排下一次重试
+func (s *WebhookService) attempt(id uint) {
+	now := time.Now()
+
+	// 认领：把下次尝试时间推后，避免重试任务和即时发送重复投递
+	lease := now.Add(2 * s.Client.Timeout)
+	result := s.DB.Model(&models.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND next_attempt_at <= ?", id, models.WebhookDeliveryPending, now).
+		Update("next_attempt_at", lease)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return
+	}
+
+	delivery, err := s.getDelivery(id)
+	if err != nil {
+		return
+	}
+
+	var subscription models.WebhookSubscription
+	if err := s.DB.First(&subscription, delivery.SubscriptionID).Error; err != nil || !subscription.Active {
+		s.DB.Model(delivery).Updates(map[string]interface{}{
+			"status":          models.WebhookDeliveryFailed,
+			"last_error":      "订阅已删除或停用",
+			"next_attempt_at": nil,
+		})
+		return
+	}
+
+	statusCode, sendErr := s.send(&subscription, delivery)
+	attempts := delivery.Attempts + 1
+	updates := map[string]interface{}{
+		"attempts":      attempts,
+		"response_code": statusCode,
+	}
+
+	switch {
+	case sendErr == nil:
+		updates["status"] = models.WebhookDeliverySuccess
+		updates["delivered_at"] = time.Now()
+		updates["last_error"] = ""
+		updates["next_attempt_at"] = nil
+	case attempts >= s.maxAttempts():
+		updates["status"] = models.WebhookDeliveryFailed
+		updates["last_error"] = truncate(sendErr.Error(), 500)
+		updates["next_attempt_at"] = nil
+		log.Printf("[Webhook] 投递 %d(%s -> %s) 已失败%d次，停止重试: %v", delivery.ID, delivery.EventType, subscription.URL, attempts, sendErr)
+	default:
+		backoff := time.Duration(s.Config.WebhookRetryBackoff) * time.Second
+		if backoff <= 0 {
+			backoff = 30 * time.Second
+		}
+		updates["last_error"] = truncate(sendErr.Error(), 500)
+		updates["next_attempt_at"] = time.Now().Add(backoff * time.Duration(1<<(attempts-1)))
+	}
+
+	if err := s.DB.Model(&models.WebhookDelivery{}).Where("id = ?", delivery.ID).Updates(updates).Error; err != nil {
+		log.Printf("[Webhook] 更新投递 %d 状态失败: %v", delivery.ID, err)
+	}
+}
+
+// send 签名并发送请求，非2xx响应视为失败
+func (s *WebhookService) send(subscription *models.WebhookSubscription, delivery *models.WebhookDelivery) (int, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	signature, err := SignWebhookPayload(subscription.Secret, timestamp, []byte(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, subscription.URL, bytes.NewReader([]byte(delivery.Payload)))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookHeaderEvent, delivery.EventType)
+	req.Header.Set(WebhookHeaderID, delivery.EventID)
+	req.Header.Set(WebhookHeaderTimestamp, timestamp)
+	req.Header.Set(WebhookHeaderSignature, "sha256="+signature)
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		return resp.StatusCode, fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return resp.StatusCode, nil
+}
+
+// getDelivery 根据ID获取投递记录
+func (s *WebhookService) getDelivery(id uint) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	if err := s.DB.First(&delivery, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookDeliveryNotFound
+		}
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// maxAttempts 返回最大投递次数
+func (s *WebhookService) maxAttempts() int {
+	if s.Config.WebhookMaxAttempts < 1 {
+		return 1
+	}
+	return s.Config.WebhookMaxAttempts
+}
+
+// retryDue 发送所有到期的待投递记录，最多同时发送10条
+func (s *WebhookService) retryDue() {
+	var ids []uint
+	if err := s.DB.Model(&models.WebhookDelivery{}).
+		Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryPending, time.Now()).
+		Order("next_attempt_at ASC").Limit(100).
+		Pluck("id", &ids).Error; err != nil {
+		log.Printf("[Webhook] 查询待重试投递失败: %v", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 10)
+	for _, id := range ids {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(id uint) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			s.attempt(id)
+		}(id)
+	}
+	wg.Wait()
+}
+
+// startRetryTask 定时重试到期的投递
+func (s *WebhookService) startRetryTask() {
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.retryDue()
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+// SignWebhookPayload 计算Webhook签名：HMAC-SHA256(secret, timestamp + "." + body)，十六进制编码
+func SignWebhookPayload(secret, timestamp string, body []byte) (string, error) {
+	message := make([]byte, 0, len(timestamp)+1+len(body))
+	message = append(message, timestamp...)
+	message = append(message, '.')
+	message = append(message, body...)
+
+	signature, err := utils.Sign([]byte(secret), message)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(signature), nil
+}
+
+// normalizeWebhookEvents 校验并规范化订阅的事件列表
+func normalizeWebhookEvents(events string) (string, error) {
+	var normalized []string
+	seen := make(map[string]bool)
+	for _, event := range strings.Split(events, ",") {
+		event = strings.TrimSpace(event)
+		if event == "" || seen[event] {
+			continue
+		}
+		if event != "*" && !isWebhookEventType(event) {
+			return "", fmt.Errorf("%w: 不支持的事件类型 %s", ErrInvalidWebhook, event)
+		}
+		seen[event] = true
+		normalized = append(normalized, event)
+	}
+	if len(normalized) == 0 {
+		return "", fmt.Errorf("%w: 至少订阅一个事件", ErrInvalidWebhook)
+	}
+	return strings.Join(normalized, ","), nil
+}
+
+// isWebhookEventType 判断是否为可订阅的事件类型
+func isWebhookEventType(eventType string) bool {
+	for _, t := range WebhookEventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// validateWebhookURL 校验Webhook地址
+func validateWebhookURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: 地址必须是http或https URL", ErrInvalidWebhook)
+	}
+	return nil
+}
+
+// webhookPayload 将领域事件转换为Webhook事件类型和请求体中的data
+func webhookPayload(event events.Event) (string, interface{}, bool) {
+	switch e := event.(type) {
+	case events.CallStarted:
+		return WebhookEventCallStarted, map[string]interface{}{
+			"call_id":     e.CallID,
+			"device_id":   e.DeviceID,
+			"resident_id": e.ResidentID,
+			"status":      e.Status,
+		}, true
+	case events.CallAnswered:
+		return WebhookEventCallAnswered, map[string]interface{}{
+			"call_id":     e.CallID,
+			"device_id":   e.DeviceID,
+			"resident_id": e.ResidentID,
+		}, true
+	case events.CallEnded:
+		return WebhookEventCallEnded, map[string]interface{}{
+			"call_id": e.CallID,
+			"status":  e.Status,
+			"reason":  e.Reason,
+		}, true
+	case events.DeviceStatusChanged:
+		switch e.Current {
+		case models.DeviceStatusOnline:
+			return WebhookEventDeviceOnline, e.Device, true
+		case models.DeviceStatusOffline:
+			return WebhookEventDeviceOffline, e.Device, true
+		}
+	case events.AlarmTriggered:
+		return WebhookEventAlarmTriggered, e.Alarm, true
+	case events.AlarmResolved:
+		return WebhookEventAlarmResolved, e.Alarm, true
+	case events.AccessAttempted:
+		eventType := WebhookEventAccessDenied
+		if e.Granted {
+			eventType = WebhookEventAccessGranted
+		}
+		return eventType, map[string]interface{}{
+			"access_log_id": e.AccessLog.ID,
+			"device_id":     e.AccessLog.DeviceID,
+			"device_name":   e.Device.Name,
+			"resident_id":   e.AccessLog.ResidentID,
+			"method":        e.AccessLog.Method,
+			"result":        e.AccessLog.Result,
+			"timestamp":     e.AccessLog.Timestamp,
+			"details":       e.Event.Details,
+		}, true
+	}
+	return "", nil, false
+}
@@ -42,6 +42,36 @@ const (
 	ErrUserAlreadyExist
 	// ErrUserPasswordIncorrect - 401: 用户密码错误.
 	ErrUserPasswordIncorrect
+	// ErrAccountLocked - 403: 登录失败次数过多，账号已被锁定.
+	ErrAccountLocked
+	// ErrAccountDisabled - 403: 账号已被停用或锁定.
+	ErrAccountDisabled
+	// ErrLoginThrottled - 429: 登录失败次数过多，需稍后再试.
+	ErrLoginThrottled
+	// ErrPasswordPolicy - 400: 密码不符合密码策略.
+	ErrPasswordPolicy
+	// ErrPasswordReused - 400: 新密码与最近使用过的密码相同.
+	ErrPasswordReused
+	// ErrPasswordChangeRequired - 403: 必须先修改密码.
+	ErrPasswordChangeRequired
+	// ErrResetCodeInvalid - 400: 找回密码验证码无效或已过期.
+	ErrResetCodeInvalid
+	// ErrTwoFactorCodeInvalid - 401: 两步验证码无效.
+	ErrTwoFactorCodeInvalid
+	// ErrTwoFactorExpired - 401: 两步验证凭证无效或已过期，需要重新登录.
+	ErrTwoFactorExpired
+	// ErrTwoFactorSetupRequired - 403: 账号的权限要求先绑定两步验证.
+	ErrTwoFactorSetupRequired
+	// ErrTwoFactorAlreadyEnabled - 400: 已启用两步验证.
+	ErrTwoFactorAlreadyEnabled
+	// ErrTwoFactorNotEnabled - 400: 尚未启用两步验证.
+	ErrTwoFactorNotEnabled
+	// ErrTwoFactorRequired - 403: 账号的权限要求启用两步验证，不能关闭.
+	ErrTwoFactorRequired
+	// ErrLoginCodeInvalid - 401: 登录验证码无效或已过期.
+	ErrLoginCodeInvalid
+	// ErrSMSLoginUnavailable - 400: 短信验证码登录未开启.
+	ErrSMSLoginUnavailable
 )
 
 // 设备相关错误码 (102xxx).
@@ -54,6 +84,16 @@ const (
 	ErrDeviceOffline
 	// ErrDeviceBusy - 400: 设备忙.
 	ErrDeviceBusy
+	// ErrDeviceCredentialsInvalid - 401: 设备序列号或密钥错误.
+	ErrDeviceCredentialsInvalid
+	// ErrDeviceMismatch - 403: 请求中的设备与设备令牌不一致.
+	ErrDeviceMismatch
+	// ErrDeviceSignatureInvalid - 401: 设备请求签名无效.
+	ErrDeviceSignatureInvalid
+	// ErrDeviceSignatureExpired - 401: 设备请求签名时间戳超出允许偏差.
+	ErrDeviceSignatureExpired
+	// ErrDeviceNonceReused - 401: 设备请求随机数已被使用.
+	ErrDeviceNonceReused
 )
 
 // 住户相关错误码 (103xxx).
@@ -70,6 +110,8 @@ const (
 	ErrCallNotFound int = iota + 104000
 	// ErrCallTimeout - 400: 呼叫超时.
 	ErrCallTimeout
+	// ErrCallNotParticipant - 403: 当前账号不是通话的参与方.
+	ErrCallNotParticipant
 )
 
 // 数据库相关错误码 (105xxx).
@@ -80,6 +122,94 @@ const (
 	ErrRecordNotFound
 )
 
+// 紧急事件相关错误码 (106xxx).
+const (
+	// ErrAlarmNotFound - 404: 警报不存在.
+	ErrAlarmNotFound int = iota + 106000
+	// ErrAlarmStatusInvalid - 400: 警报状态不允许此操作.
+	ErrAlarmStatusInvalid
+	// ErrContactNotFound - 404: 紧急联系人不存在.
+	ErrContactNotFound
+	// ErrNotificationNotFound - 404: 紧急通知不存在.
+	ErrNotificationNotFound
+	// ErrUnlockSessionNotFound - 404: 紧急解锁会话不存在.
+	ErrUnlockSessionNotFound
+	// ErrUnlockSessionEnded - 400: 紧急解锁会话已结束.
+	ErrUnlockSessionEnded
+)
+
+// Webhook相关错误码 (107xxx).
+const (
+	// ErrWebhookNotFound - 404: Webhook订阅不存在.
+	ErrWebhookNotFound int = iota + 107000
+	// ErrWebhookDeliveryNotFound - 404: Webhook投递记录不存在.
+	ErrWebhookDeliveryNotFound
+	// ErrWebhookNotReplayable - 400: 只有失败的投递可以重放.
+	ErrWebhookNotReplayable
+)
+
+// 物业相关错误码 (108xxx).
+const (
+	// ErrPropertyNotFound - 404: 物业不存在.
+	ErrPropertyNotFound int = iota + 108000
+	// ErrPropertyScope - 403: 资源不属于当前物业.
+	ErrPropertyScope
+	// ErrPropertyInUse - 400: 物业下存在楼号或物业员工.
+	ErrPropertyInUse
+)
+
+// 角色权限相关错误码 (110xxx).
+const (
+	// ErrRoleNotFound - 404: 角色不存在.
+	ErrRoleNotFound int = iota + 110000
+	// ErrRoleBindingNotFound - 404: 角色绑定不存在.
+	ErrRoleBindingNotFound
+	// ErrRoleProtected - 400: 内置角色或管理员默认绑定不能删除或修改.
+	ErrRoleProtected
+)
+
+// 居民自助相关错误码 (111xxx).
+const (
+	// ErrPasscodeNotFound - 404: 通行码不存在.
+	ErrPasscodeNotFound int = iota + 111000
+	// ErrResidentNoHousehold - 400: 居民未关联户号.
+	ErrResidentNoHousehold
+	// ErrInvalidPasscode - 400: 通行码有效期或使用次数不合法.
+	ErrInvalidPasscode
+)
+
+// 物业员工相关错误码 (112xxx).
+const (
+	// ErrStaffNotFound - 404: 物业员工不存在.
+	ErrStaffNotFound int = iota + 112000
+	// ErrStaffDeviceNotAssigned - 404: 设备未分配给该物业员工.
+	ErrStaffDeviceNotAssigned
+	// ErrStaffUsernameExists - 400: 用户名已被其他物业员工使用.
+	ErrStaffUsernameExists
+	// ErrStaffStatusInvalid - 400: 物业员工状态无效.
+	ErrStaffStatusInvalid
+)
+
+// API密钥相关错误码 (113xxx).
+const (
+	// ErrAPIKeyNotFound - 404: API密钥不存在.
+	ErrAPIKeyNotFound int = iota + 113000
+	// ErrAPIKeyRevoked - 400: API密钥已被吊销.
+	ErrAPIKeyRevoked
+	// ErrAPIKeyInvalid - 401: API密钥无效或已过期.
+	ErrAPIKeyInvalid
+	// ErrAPIKeyIPDenied - 403: 请求IP不在API密钥的白名单中.
+	ErrAPIKeyIPDenied
+)
+
+// 批量导入相关错误码 (114xxx).
+const (
+	// ErrImportFileInvalid - 400: 导入文件无效.
+	ErrImportFileInvalid int = iota + 114000
+	// ErrImportRowsInvalid - 400: 导入数据校验失败.
+	ErrImportRowsInvalid
+)
+
 // 迁移相关错误码 (109xxx).
 const (
 	// ErrMigrationFailed - 500: 迁移失败.
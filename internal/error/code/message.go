@@ -3,35 +3,101 @@ package code
 // 错误码消息映射.
 var codeMessageMap = map[int]string{
 	// 通用错误码
-	ErrSuccess:      "成功",
-	ErrUnknown:      "未知错误",
-	ErrBind:         "请求参数绑定错误",
-	ErrValidation:   "请求参数验证错误",
-	ErrTokenInvalid: "无效的认证令牌",
+	ErrSuccess:         "成功",
+	ErrUnknown:         "未知错误",
+	ErrBind:            "请求参数绑定错误",
+	ErrValidation:      "请求参数验证错误",
+	ErrTokenInvalid:    "无效的认证令牌",
+	ErrTooManyRequests: "请求频率过高",
 
 	// 用户相关错误码
-	ErrUserNotFound:          "用户不存在",
-	ErrUserAlreadyExist:      "用户已存在",
-	ErrUserPasswordIncorrect: "用户密码错误",
+	ErrUserNotFound:            "用户不存在",
+	ErrUserAlreadyExist:        "用户已存在",
+	ErrUserPasswordIncorrect:   "用户密码错误",
+	ErrAccountLocked:           "登录失败次数过多，账号已被锁定",
+	ErrAccountDisabled:         "账号已被停用或锁定",
+	ErrLoginThrottled:          "登录失败次数过多，请稍后再试",
+	ErrPasswordPolicy:          "密码不符合密码策略",
+	ErrPasswordReused:          "新密码不能与最近使用过的密码相同",
+	ErrPasswordChangeRequired:  "请先修改密码",
+	ErrResetCodeInvalid:        "验证码无效或已过期",
+	ErrTwoFactorCodeInvalid:    "两步验证码无效",
+	ErrTwoFactorExpired:        "两步验证已过期，请重新登录",
+	ErrTwoFactorSetupRequired:  "请先绑定两步验证",
+	ErrTwoFactorAlreadyEnabled: "已启用两步验证",
+	ErrTwoFactorNotEnabled:     "尚未启用两步验证",
+	ErrTwoFactorRequired:       "当前账号的权限要求启用两步验证",
+	ErrLoginCodeInvalid:        "登录验证码无效或已过期",
+	ErrSMSLoginUnavailable:     "短信验证码登录未开启",
 
 	// 设备相关错误码
-	ErrDeviceNotFound:     "设备不存在",
-	ErrDeviceAlreadyExist: "设备已存在",
-	ErrDeviceOffline:      "设备当前离线",
-	ErrDeviceBusy:         "设备忙，请稍后再试",
+	ErrDeviceNotFound:           "设备不存在",
+	ErrDeviceAlreadyExist:       "设备已存在",
+	ErrDeviceOffline:            "设备当前离线",
+	ErrDeviceBusy:               "设备忙，请稍后再试",
+	ErrDeviceCredentialsInvalid: "设备序列号或密钥错误",
+	ErrDeviceMismatch:           "请求中的设备与设备令牌不一致",
+	ErrDeviceSignatureInvalid:   "设备请求签名无效",
+	ErrDeviceSignatureExpired:   "设备请求签名已过期，请校准设备时间",
+	ErrDeviceNonceReused:        "设备请求随机数已被使用",
 
 	// 住户相关错误码
 	ErrResidentNotFound:     "住户不存在",
 	ErrResidentAlreadyExist: "住户已存在",
 
 	// 呼叫相关错误码
-	ErrCallNotFound: "呼叫记录不存在",
-	ErrCallTimeout:  "呼叫超时",
+	ErrCallNotFound:       "呼叫记录不存在",
+	ErrCallTimeout:        "呼叫超时",
+	ErrCallNotParticipant: "不是通话的参与方",
 
 	// 数据库相关错误码
 	ErrDatabase:       "数据库错误",
 	ErrRecordNotFound: "记录不存在",
 
+	// 紧急事件相关错误码
+	ErrAlarmNotFound:         "警报不存在",
+	ErrAlarmStatusInvalid:    "警报状态不允许此操作",
+	ErrContactNotFound:       "紧急联系人不存在",
+	ErrNotificationNotFound:  "紧急通知不存在",
+	ErrUnlockSessionNotFound: "紧急解锁会话不存在",
+	ErrUnlockSessionEnded:    "紧急解锁会话已结束",
+
+	// Webhook相关错误码
+	ErrWebhookNotFound:         "Webhook订阅不存在",
+	ErrWebhookDeliveryNotFound: "Webhook投递记录不存在",
+	ErrWebhookNotReplayable:    "只有失败的投递可以重放",
+
+	// 物业相关错误码
+	ErrPropertyNotFound: "物业不存在",
+	ErrPropertyScope:    "资源不属于当前物业",
+	ErrPropertyInUse:    "物业下存在楼号或物业员工",
+
+	// 角色权限相关错误码
+	ErrRoleNotFound:        "角色不存在",
+	ErrRoleBindingNotFound: "角色绑定不存在",
+	ErrRoleProtected:       "内置角色或管理员默认绑定不能删除或修改",
+
+	// 居民自助相关错误码
+	ErrPasscodeNotFound:    "通行码不存在",
+	ErrResidentNoHousehold: "居民未关联户号",
+	ErrInvalidPasscode:     "通行码有效期或使用次数不合法",
+
+	// 物业员工相关错误码
+	ErrStaffNotFound:          "物业员工不存在",
+	ErrStaffDeviceNotAssigned: "设备未分配给该物业员工",
+	ErrStaffUsernameExists:    "用户名已被其他物业员工使用",
+	ErrStaffStatusInvalid:     "物业员工状态无效",
+
+	// API密钥相关错误码
+	ErrAPIKeyNotFound: "API密钥不存在",
+	ErrAPIKeyRevoked:  "API密钥已被吊销",
+	ErrAPIKeyInvalid:  "API密钥无效或已过期",
+	ErrAPIKeyIPDenied: "请求IP不在API密钥的白名单中",
+
+	// 批量导入相关错误码
+	ErrImportFileInvalid: "导入文件无效",
+	ErrImportRowsInvalid: "导入数据校验失败",
+
 	// 迁移相关错误码
 	ErrMigrationFailed:  "迁移失败",
 	ErrBackupFailed:     "备份失败",
@@ -42,35 +108,101 @@ var codeMessageMap = map[int]string{
 // 错误码HTTP状态码映射.
 var codeStatusMap = map[int]int{
 	// 通用错误码
-	ErrSuccess:      StatusOK,
-	ErrUnknown:      StatusInternalServerError,
-	ErrBind:         StatusBadRequest,
-	ErrValidation:   StatusBadRequest,
-	ErrTokenInvalid: StatusUnauthorized,
+	ErrSuccess:         StatusOK,
+	ErrUnknown:         StatusInternalServerError,
+	ErrBind:            StatusBadRequest,
+	ErrValidation:      StatusBadRequest,
+	ErrTokenInvalid:    StatusUnauthorized,
+	ErrTooManyRequests: StatusTooManyRequests,
 
 	// 用户相关错误码
-	ErrUserNotFound:          StatusNotFound,
-	ErrUserAlreadyExist:      StatusBadRequest,
-	ErrUserPasswordIncorrect: StatusUnauthorized,
+	ErrUserNotFound:            StatusNotFound,
+	ErrUserAlreadyExist:        StatusBadRequest,
+	ErrUserPasswordIncorrect:   StatusUnauthorized,
+	ErrAccountLocked:           StatusForbidden,
+	ErrAccountDisabled:         StatusForbidden,
+	ErrLoginThrottled:          StatusTooManyRequests,
+	ErrPasswordPolicy:          StatusBadRequest,
+	ErrPasswordReused:          StatusBadRequest,
+	ErrPasswordChangeRequired:  StatusForbidden,
+	ErrResetCodeInvalid:        StatusBadRequest,
+	ErrTwoFactorCodeInvalid:    StatusUnauthorized,
+	ErrTwoFactorExpired:        StatusUnauthorized,
+	ErrTwoFactorSetupRequired:  StatusForbidden,
+	ErrTwoFactorAlreadyEnabled: StatusBadRequest,
+	ErrTwoFactorNotEnabled:     StatusBadRequest,
+	ErrTwoFactorRequired:       StatusForbidden,
+	ErrLoginCodeInvalid:        StatusUnauthorized,
+	ErrSMSLoginUnavailable:     StatusBadRequest,
 
 	// 设备相关错误码
-	ErrDeviceNotFound:     StatusNotFound,
-	ErrDeviceAlreadyExist: StatusBadRequest,
-	ErrDeviceOffline:      StatusBadRequest,
-	ErrDeviceBusy:         StatusBadRequest,
+	ErrDeviceNotFound:           StatusNotFound,
+	ErrDeviceAlreadyExist:       StatusBadRequest,
+	ErrDeviceOffline:            StatusBadRequest,
+	ErrDeviceBusy:               StatusBadRequest,
+	ErrDeviceCredentialsInvalid: StatusUnauthorized,
+	ErrDeviceMismatch:           StatusForbidden,
+	ErrDeviceSignatureInvalid:   StatusUnauthorized,
+	ErrDeviceSignatureExpired:   StatusUnauthorized,
+	ErrDeviceNonceReused:        StatusUnauthorized,
 
 	// 住户相关错误码
 	ErrResidentNotFound:     StatusNotFound,
 	ErrResidentAlreadyExist: StatusBadRequest,
 
 	// 呼叫相关错误码
-	ErrCallNotFound: StatusNotFound,
-	ErrCallTimeout:  StatusBadRequest,
+	ErrCallNotFound:       StatusNotFound,
+	ErrCallTimeout:        StatusBadRequest,
+	ErrCallNotParticipant: StatusForbidden,
 
 	// 数据库相关错误码
 	ErrDatabase:       StatusInternalServerError,
 	ErrRecordNotFound: StatusNotFound,
 
+	// 紧急事件相关错误码
+	ErrAlarmNotFound:         StatusNotFound,
+	ErrAlarmStatusInvalid:    StatusBadRequest,
+	ErrContactNotFound:       StatusNotFound,
+	ErrNotificationNotFound:  StatusNotFound,
+	ErrUnlockSessionNotFound: StatusNotFound,
+	ErrUnlockSessionEnded:    StatusBadRequest,
+
+	// Webhook相关错误码
+	ErrWebhookNotFound:         StatusNotFound,
+	ErrWebhookDeliveryNotFound: StatusNotFound,
+	ErrWebhookNotReplayable:    StatusBadRequest,
+
+	// 物业相关错误码
+	ErrPropertyNotFound: StatusNotFound,
+	ErrPropertyScope:    StatusForbidden,
+	ErrPropertyInUse:    StatusBadRequest,
+
+	// 角色权限相关错误码
+	ErrRoleNotFound:        StatusNotFound,
+	ErrRoleBindingNotFound: StatusNotFound,
+	ErrRoleProtected:       StatusBadRequest,
+
+	// 居民自助相关错误码
+	ErrPasscodeNotFound:    StatusNotFound,
+	ErrResidentNoHousehold: StatusBadRequest,
+	ErrInvalidPasscode:     StatusBadRequest,
+
+	// 物业员工相关错误码
+	ErrStaffNotFound:          StatusNotFound,
+	ErrStaffDeviceNotAssigned: StatusNotFound,
+	ErrStaffUsernameExists:    StatusBadRequest,
+	ErrStaffStatusInvalid:     StatusBadRequest,
+
+	// API密钥相关错误码
+	ErrAPIKeyNotFound: StatusNotFound,
+	ErrAPIKeyRevoked:  StatusBadRequest,
+	ErrAPIKeyInvalid:  StatusUnauthorized,
+	ErrAPIKeyIPDenied: StatusForbidden,
+
+	// 批量导入相关错误码
+	ErrImportFileInvalid: StatusBadRequest,
+	ErrImportRowsInvalid: StatusBadRequest,
+
 	// 迁移相关错误码
 	ErrMigrationFailed:  StatusInternalServerError,
 	ErrBackupFailed:     StatusInternalServerError,
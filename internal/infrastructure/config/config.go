@@ -27,7 +27,8 @@ type Config struct {
 	DBMigrationMode string // 数据库迁移模式: "auto"(默认), "alter"(修改), "drop"(删除重建)
 
 	// Server
-	ServerPort string
+	ServerPort     string
+	TrustedProxies string // 可信反向代理的IP或CIDR，逗号分隔；为空时不信任X-Forwarded-For，客户端IP取连接来源地址
 
 	// Redis
 	RedisHost string
@@ -54,8 +55,78 @@ type Config struct {
 	MQTTSSLEnabled bool   // 是否启用SSL/TLS
 	MQTTCACertPath string // CA证书路径，用于SSL/TLS验证
 
+	// 紧急事件升级配置
+	EmergencyEscalationTimeout       int // 紧急事件未响应多少秒后升级
+	EmergencyEscalationCheckInterval int // 升级检查间隔（秒）
+	EmergencyEscalationBatchSize     int // 每轮升级通知的联系人数量
+	EmergencyUnlockAckTimeout        int // 紧急解锁指令等待设备回执的秒数
+
+	// 通知渠道配置
+	NotifySMSProvider          string // 短信发送方: log, file, http, none
+	NotifyEmailProvider        string // 邮件发送方: log, file, smtp, none
+	NotifyPushProvider         string // App推送发送方: log, file, http, none
+	NotifyWebhookProvider      string // Webhook发送方: log, file, http, none
+	NotifyFileDir              string // file发送方的输出目录
+	NotifySMSGatewayURL        string // 短信网关地址
+	NotifySMSGatewayToken      string // 短信网关令牌
+	NotifyPushGatewayURL       string // 推送网关地址
+	NotifyPushGatewayToken     string // 推送网关令牌
+	NotifyWebhookURL           string // 默认Webhook地址
+	NotifySMTPHost             string // SMTP服务器
+	NotifySMTPPort             int    // SMTP端口
+	NotifySMTPUsername         string // SMTP用户名
+	NotifySMTPPassword         string // SMTP密码
+	NotifySMTPFrom             string // 发件人地址
+	NotifySMSRatePerMinute     int    // 短信每分钟发送上限
+	NotifyEmailRatePerMinute   int    // 邮件每分钟发送上限
+	NotifyPushRatePerMinute    int    // 推送每分钟发送上限
+	NotifyWebhookRatePerMinute int    // Webhook每分钟发送上限
+	NotifyMaxRetries           int    // 发送失败的最大重试次数
+	NotifyRetryBackoffMs       int    // 首次重试等待毫秒数，之后按指数增长
+	EmergencyNotifyChannels    string // 紧急通知除站内信和MQTT外使用的渠道，逗号分隔
+
+	// Webhook配置
+	WebhookTimeout      int // 单次投递的超时秒数
+	WebhookMaxAttempts  int // 最大投递次数（含首次），用尽后标记为失败
+	WebhookRetryBackoff int // 首次重试等待秒数，之后按指数增长
+
 	// JWT Authentication
-	JWTSecretKey string
+	JWTSecretKey       string
+	JWTAccessTokenTTL  int // 访问令牌有效分钟数
+	JWTRefreshTokenTTL int // 刷新令牌有效小时数
+
+	// 设备签名请求
+	DeviceSignatureMaxSkew int    // 签名时间戳与服务器时间允许的最大偏差秒数
+	DeviceSigningKeySecret string // 加密保存设备签名密钥的密钥，为空时使用JWT密钥
+
+	// 登录防暴力破解
+	LoginFailureWindow  int // 统计登录失败次数的窗口分钟数
+	LoginMaxFailures    int // 同一账号在窗口内失败多少次后锁定
+	LoginLockoutMinutes int // 账号锁定和IP封禁的分钟数
+	LoginIPMaxFailures  int // 同一IP在窗口内失败多少次后封禁
+
+	// 密码策略与找回密码
+	PasswordMinLength        int  // 密码最小长度
+	PasswordRequireUpper     bool // 是否必须包含大写字母
+	PasswordRequireLower     bool // 是否必须包含小写字母
+	PasswordRequireDigit     bool // 是否必须包含数字
+	PasswordRequireSymbol    bool // 是否必须包含特殊字符
+	PasswordHistorySize      int  // 新密码不能与最近多少个密码相同，0表示不限制
+	PasswordResetCodeTTL     int  // 找回密码验证码有效分钟数
+	PasswordResetMaxAttempts int  // 验证码最多可以输错多少次
+	PasswordResetCooldown    int  // 同一账号两次发送验证码的最短间隔秒数
+
+	// 管理员两步验证
+	TwoFactorIssuer              string // 身份验证器App中显示的服务名称
+	TwoFactorEncryptionKey       string // 加密保存TOTP密钥的密钥，为空时使用JWT密钥
+	TwoFactorRequiredPermissions string // 拥有其中任一权限的管理员必须启用两步验证，逗号分隔，为空表示不强制
+	TwoFactorChallengeTTL        int    // 密码验证通过后输入验证码的有效秒数
+
+	// 居民短信验证码登录
+	SMSLoginCodeTTL     int // 登录验证码有效分钟数
+	SMSLoginMaxAttempts int // 验证码最多可以输错多少次
+	SMSLoginCooldown    int // 同一手机号两次发送验证码的最短间隔秒数
+	SMSLoginDailyLimit  int // 同一手机号每天最多发送多少次验证码，0表示不限制
 
 	// Admin
 	DefaultAdminPassword string
@@ -96,7 +167,8 @@ func LoadConfig() *Config {
 		DBMigrationMode: getEnv(prefix+"DB_MIGRATION_MODE", "auto"),
 
 		// Server config
-		ServerPort: getEnv(prefix+"SERVER_PORT", getEnv("SERVER_PORT", "8080")),
+		ServerPort:     getEnv(prefix+"SERVER_PORT", getEnv("SERVER_PORT", "8080")),
+		TrustedProxies: getEnv("TRUSTED_PROXIES", ""),
 
 		// Redis config
 		RedisHost: getEnv(prefix+"REDIS_HOST", getEnv("	REDIS_HOST", "localhost")),
@@ -123,8 +195,78 @@ func LoadConfig() *Config {
 		MQTTSSLEnabled: getEnvAsBool("MQTT_SSL_ENABLED", false),
 		MQTTCACertPath: getEnv("MQTT_CA_CERT_PATH", ""),
 
+		// 紧急事件升级配置
+		EmergencyEscalationTimeout:       getEnvAsInt("EMERGENCY_ESCALATION_TIMEOUT", 120),
+		EmergencyEscalationCheckInterval: getEnvAsInt("EMERGENCY_ESCALATION_CHECK_INTERVAL", 15),
+		EmergencyEscalationBatchSize:     getEnvAsInt("EMERGENCY_ESCALATION_BATCH_SIZE", 1),
+		EmergencyUnlockAckTimeout:        getEnvAsInt("EMERGENCY_UNLOCK_ACK_TIMEOUT", 15),
+
+		// 通知渠道配置
+		NotifySMSProvider:          getEnv("NOTIFY_SMS_PROVIDER", "log"),
+		NotifyEmailProvider:        getEnv("NOTIFY_EMAIL_PROVIDER", "log"),
+		NotifyPushProvider:         getEnv("NOTIFY_PUSH_PROVIDER", "log"),
+		NotifyWebhookProvider:      getEnv("NOTIFY_WEBHOOK_PROVIDER", "log"),
+		NotifyFileDir:              getEnv("NOTIFY_FILE_DIR", "logs/notifications"),
+		NotifySMSGatewayURL:        getEnv("NOTIFY_SMS_GATEWAY_URL", ""),
+		NotifySMSGatewayToken:      getEnv("NOTIFY_SMS_GATEWAY_TOKEN", ""),
+		NotifyPushGatewayURL:       getEnv("NOTIFY_PUSH_GATEWAY_URL", ""),
+		NotifyPushGatewayToken:     getEnv("NOTIFY_PUSH_GATEWAY_TOKEN", ""),
+		NotifyWebhookURL:           getEnv("NOTIFY_WEBHOOK_URL", ""),
+		NotifySMTPHost:             getEnv("NOTIFY_SMTP_HOST", "localhost"),
+		NotifySMTPPort:             getEnvAsInt("NOTIFY_SMTP_PORT", 587),
+		NotifySMTPUsername:         getEnv("NOTIFY_SMTP_USERNAME", ""),
+		NotifySMTPPassword:         getEnv("NOTIFY_SMTP_PASSWORD", ""),
+		NotifySMTPFrom:             getEnv("NOTIFY_SMTP_FROM", "noreply@ilock.local"),
+		NotifySMSRatePerMinute:     getEnvAsInt("NOTIFY_SMS_RATE_PER_MINUTE", 120),
+		NotifyEmailRatePerMinute:   getEnvAsInt("NOTIFY_EMAIL_RATE_PER_MINUTE", 120),
+		NotifyPushRatePerMinute:    getEnvAsInt("NOTIFY_PUSH_RATE_PER_MINUTE", 600),
+		NotifyWebhookRatePerMinute: getEnvAsInt("NOTIFY_WEBHOOK_RATE_PER_MINUTE", 300),
+		NotifyMaxRetries:           getEnvAsInt("NOTIFY_MAX_RETRIES", 3),
+		NotifyRetryBackoffMs:       getEnvAsInt("NOTIFY_RETRY_BACKOFF_MS", 500),
+		EmergencyNotifyChannels:    getEnv("EMERGENCY_NOTIFY_CHANNELS", "sms,push"),
+
+		// Webhook配置
+		WebhookTimeout:      getEnvAsInt("WEBHOOK_TIMEOUT", 10),
+		WebhookMaxAttempts:  getEnvAsInt("WEBHOOK_MAX_ATTEMPTS", 6),
+		WebhookRetryBackoff: getEnvAsInt("WEBHOOK_RETRY_BACKOFF", 30),
+
 		// JWT Config
-		JWTSecretKey: getEnv("JWT_SECRET_KEY", "ilock-secret-key-change-in-production"),
+		JWTSecretKey:       getEnv("JWT_SECRET_KEY", "ilock-secret-key-change-in-production"),
+		JWTAccessTokenTTL:  getEnvAsInt("JWT_ACCESS_TOKEN_TTL", 15),
+		JWTRefreshTokenTTL: getEnvAsInt("JWT_REFRESH_TOKEN_TTL", 720),
+
+		// 设备签名请求配置
+		DeviceSignatureMaxSkew: getEnvAsInt("DEVICE_SIGNATURE_MAX_SKEW", 300),
+		DeviceSigningKeySecret: getEnv("DEVICE_SIGNING_KEY_SECRET", ""),
+
+		// 登录防暴力破解配置
+		LoginFailureWindow:  getEnvAsInt("LOGIN_FAILURE_WINDOW", 15),
+		LoginMaxFailures:    getEnvAsInt("LOGIN_MAX_FAILURES", 5),
+		LoginLockoutMinutes: getEnvAsInt("LOGIN_LOCKOUT_MINUTES", 30),
+		LoginIPMaxFailures:  getEnvAsInt("LOGIN_IP_MAX_FAILURES", 20),
+
+		// 密码策略与找回密码配置
+		PasswordMinLength:        getEnvAsInt("PASSWORD_MIN_LENGTH", 8),
+		PasswordRequireUpper:     getEnvAsBool("PASSWORD_REQUIRE_UPPER", true),
+		PasswordRequireLower:     getEnvAsBool("PASSWORD_REQUIRE_LOWER", true),
+		PasswordRequireDigit:     getEnvAsBool("PASSWORD_REQUIRE_DIGIT", true),
+		PasswordRequireSymbol:    getEnvAsBool("PASSWORD_REQUIRE_SYMBOL", false),
+		PasswordHistorySize:      getEnvAsInt("PASSWORD_HISTORY_SIZE", 5),
+		PasswordResetCodeTTL:     getEnvAsInt("PASSWORD_RESET_CODE_TTL", 10),
+		PasswordResetMaxAttempts: getEnvAsInt("PASSWORD_RESET_MAX_ATTEMPTS", 5),
+		PasswordResetCooldown:    getEnvAsInt("PASSWORD_RESET_COOLDOWN", 60),
+
+		// 管理员两步验证配置
+		TwoFactorIssuer:              getEnv("TWO_FACTOR_ISSUER", "iLock"),
+		TwoFactorEncryptionKey:       getEnv("TWO_FACTOR_ENCRYPTION_KEY", ""),
+		TwoFactorRequiredPermissions: getEnv("TWO_FACTOR_REQUIRED_PERMISSIONS", ""),
+		TwoFactorChallengeTTL:        getEnvAsInt("TWO_FACTOR_CHALLENGE_TTL", 300),
+
+		// 居民短信验证码登录配置
+		SMSLoginCodeTTL:     getEnvAsInt("SMS_LOGIN_CODE_TTL", 5),
+		SMSLoginMaxAttempts: getEnvAsInt("SMS_LOGIN_MAX_ATTEMPTS", 5),
+		SMSLoginCooldown:    getEnvAsInt("SMS_LOGIN_COOLDOWN", 60),
+		SMSLoginDailyLimit:  getEnvAsInt("SMS_LOGIN_DAILY_LIMIT", 10),
 
 		// Admin Config
 		DefaultAdminPassword: getEnvRequired("DEFAULT_ADMIN_PASSWORD"),
@@ -45,6 +45,11 @@ func NewConnectionPool(cfg *config.Config) (*ConnectionPool, error) {
 	return pool, nil
 }
 
+// NewConnectionPoolWithDB 使用已经建立的数据库连接创建连接池管理对象，不修改连接的连接池参数
+func NewConnectionPoolWithDB(db *gorm.DB) *ConnectionPool {
+	return &ConnectionPool{DB: db}
+}
+
 // ConfigurePool 配置连接池参数
 func (p *ConnectionPool) ConfigurePool() error {
 	// 获取底层SQL连接
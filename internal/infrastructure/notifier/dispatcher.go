@@ -0,0 +1,161 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"ilock-http-service/internal/infrastructure/config"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Dispatcher 统一的通知发送入口，负责模板渲染、按渠道限流和失败重试
+type Dispatcher struct {
+	mu         sync.RWMutex
+	notifiers  map[Channel]Notifier
+	limiters   map[Channel]*rateLimiter
+	templates  *templateRegistry
+	maxRetries int
+	backoff    time.Duration
+}
+
+// NewDispatcher 创建通知分发器
+func NewDispatcher(maxRetries int, backoff time.Duration) *Dispatcher {
+	if maxRetries < 0 {
+		maxRetries = 0
+	}
+	if backoff <= 0 {
+		backoff = 500 * time.Millisecond
+	}
+	return &Dispatcher{
+		notifiers:  make(map[Channel]Notifier),
+		limiters:   make(map[Channel]*rateLimiter),
+		templates:  newTemplateRegistry(),
+		maxRetries: maxRetries,
+		backoff:    backoff,
+	}
+}
+
+// NewDispatcherFromConfig 根据配置为每个渠道选择发送方
+// 发送方可选: log(默认)、file、http(短信/推送/Webhook)、smtp(邮件)、none(禁用)
+func NewDispatcherFromConfig(cfg *config.Config) *Dispatcher {
+	d := NewDispatcher(cfg.NotifyMaxRetries, time.Duration(cfg.NotifyRetryBackoffMs)*time.Millisecond)
+
+	channels := []struct {
+		channel   Channel
+		provider  string
+		perMinute int
+		url       string
+		token     string
+	}{
+		{ChannelSMS, cfg.NotifySMSProvider, cfg.NotifySMSRatePerMinute, cfg.NotifySMSGatewayURL, cfg.NotifySMSGatewayToken},
+		{ChannelEmail, cfg.NotifyEmailProvider, cfg.NotifyEmailRatePerMinute, "", ""},
+		{ChannelPush, cfg.NotifyPushProvider, cfg.NotifyPushRatePerMinute, cfg.NotifyPushGatewayURL, cfg.NotifyPushGatewayToken},
+		{ChannelWebhook, cfg.NotifyWebhookProvider, cfg.NotifyWebhookRatePerMinute, cfg.NotifyWebhookURL, ""},
+	}
+
+	for _, c := range channels {
+		var n Notifier
+		switch strings.ToLower(c.provider) {
+		case "", "log":
+			n = &LogNotifier{Channel: c.channel}
+		case "file":
+			n = NewFileNotifier(c.channel, cfg.NotifyFileDir)
+		case "http":
+			n = NewHTTPNotifier(c.channel, c.url, c.token)
+		case "smtp":
+			if c.channel != ChannelEmail {
+				log.Printf("[Notifier] smtp只能用于邮件渠道，%s渠道改用log", c.channel)
+				n = &LogNotifier{Channel: c.channel}
+				break
+			}
+			n = &SMTPNotifier{
+				Host:     cfg.NotifySMTPHost,
+				Port:     cfg.NotifySMTPPort,
+				Username: cfg.NotifySMTPUsername,
+				Password: cfg.NotifySMTPPassword,
+				From:     cfg.NotifySMTPFrom,
+			}
+		case "none":
+			continue
+		default:
+			log.Printf("[Notifier] 未知的%s发送方 %s，改用log", c.channel, c.provider)
+			n = &LogNotifier{Channel: c.channel}
+		}
+		d.Register(c.channel, n, c.perMinute)
+	}
+
+	return d
+}
+
+// Register 注册渠道发送方，perMinute为每分钟发送上限，<=0表示不限流
+func (d *Dispatcher) Register(channel Channel, n Notifier, perMinute int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.notifiers[channel] = n
+	d.limiters[channel] = newRateLimiter(perMinute)
+}
+
+// HasChannel 判断渠道是否已配置发送方
+func (d *Dispatcher) HasChannel(channel Channel) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	_, ok := d.notifiers[channel]
+	return ok
+}
+
+// RegisterTemplate 注册或覆盖消息模板，channel为空表示所有渠道通用
+func (d *Dispatcher) RegisterTemplate(name string, channel Channel, subject, body string) error {
+	return d.templates.Register(name, channel, subject, body)
+}
+
+// Send 渲染并发送消息，可重试错误按指数退避重试
+func (d *Dispatcher) Send(ctx context.Context, msg Message) error {
+	if msg.To == "" && msg.Channel != ChannelWebhook {
+		return ErrEmptyRecipient
+	}
+
+	d.mu.RLock()
+	n, ok := d.notifiers[msg.Channel]
+	limiter := d.limiters[msg.Channel]
+	d.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrChannelNotConfigured, msg.Channel)
+	}
+
+	if msg.Template != "" {
+		subject, body, err := d.templates.Render(msg.Template, msg.Channel, msg.Data)
+		if err != nil {
+			return err
+		}
+		msg.Subject, msg.Body = subject, body
+	}
+
+	var err error
+	for attempt := 0; attempt <= d.maxRetries; attempt++ {
+		if attempt > 0 {
+			delay := d.backoff * time.Duration(1<<(attempt-1))
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		if err = limiter.Wait(ctx); err != nil {
+			return err
+		}
+
+		if err = n.Send(ctx, msg); err == nil {
+			return nil
+		}
+		if errors.Is(err, ErrPermanent) {
+			break
+		}
+		log.Printf("[Notifier] %s渠道第%d次发送给 %s 失败: %v", msg.Channel, attempt+1, msg.To, err)
+	}
+
+	return fmt.Errorf("%s渠道发送失败: %w", msg.Channel, err)
+}
@@ -0,0 +1,42 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+)
+
+// Channel 通知渠道
+type Channel string
+
+const (
+	ChannelSMS     Channel = "sms"     // 短信
+	ChannelEmail   Channel = "email"   // 电子邮件
+	ChannelPush    Channel = "push"    // App推送
+	ChannelWebhook Channel = "webhook" // Webhook回调
+)
+
+var (
+	// ErrChannelNotConfigured 渠道未配置发送方
+	ErrChannelNotConfigured = errors.New("通知渠道未配置")
+	// ErrEmptyRecipient 接收方为空
+	ErrEmptyRecipient = errors.New("通知接收方不能为空")
+	// ErrPermanent 不可重试的发送错误，如参数错误、接收方无效
+	ErrPermanent = errors.New("不可重试的发送错误")
+)
+
+// Message 待发送的通知消息
+// 指定Template时，Subject和Body由模板和Data渲染生成
+type Message struct {
+	Channel  Channel                `json:"channel"`
+	To       string                 `json:"to"` // 手机号、邮箱、推送目标或Webhook地址
+	Subject  string                 `json:"subject,omitempty"`
+	Body     string                 `json:"body"`
+	Template string                 `json:"template,omitempty"`
+	Data     map[string]interface{} `json:"data,omitempty"`
+}
+
+// Notifier 通知发送方，每个渠道可以接入真实服务商或本地替身
+type Notifier interface {
+	Name() string
+	Send(ctx context.Context, msg Message) error
+}
@@ -0,0 +1,188 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogNotifier 只把消息写入日志的本地替身，用于开发环境
+type LogNotifier struct {
+	Channel Channel
+}
+
+// Name 返回发送方名称
+func (n *LogNotifier) Name() string {
+	return "log"
+}
+
+// Send 将消息写入日志
+func (n *LogNotifier) Send(ctx context.Context, msg Message) error {
+	log.Printf("[Notifier] %s -> %s: %s %s", n.Channel, msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// FileNotifier 将消息按JSON行追加到文件的本地替身，便于开发时查看发出的内容
+type FileNotifier struct {
+	Channel Channel
+	Path    string
+	mu      sync.Mutex
+}
+
+// NewFileNotifier 创建写入 dir/{channel}.log 的文件替身
+func NewFileNotifier(channel Channel, dir string) *FileNotifier {
+	return &FileNotifier{
+		Channel: channel,
+		Path:    filepath.Join(dir, string(channel)+".log"),
+	}
+}
+
+// Name 返回发送方名称
+func (n *FileNotifier) Name() string {
+	return "file"
+}
+
+// Send 追加一行JSON记录到文件
+func (n *FileNotifier) Send(ctx context.Context, msg Message) error {
+	record, err := json.Marshal(struct {
+		Message
+		SentAt time.Time `json:"sent_at"`
+	}{msg, time.Now()})
+	if err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(n.Path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(n.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(record, '\n'))
+	return err
+}
+
+// HTTPNotifier 通过HTTP网关发送消息，适用于短信网关、推送网关和Webhook
+// 请求体为JSON: {"channel","to","subject","body"}
+type HTTPNotifier struct {
+	Channel Channel
+	URL     string // 网关地址，Webhook渠道的消息可通过To指定回调地址
+	Token   string // 可选，以Bearer方式放入Authorization头
+	Client  *http.Client
+}
+
+// NewHTTPNotifier 创建HTTP网关发送方
+func NewHTTPNotifier(channel Channel, url, token string) *HTTPNotifier {
+	return &HTTPNotifier{
+		Channel: channel,
+		URL:     url,
+		Token:   token,
+		Client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Name 返回发送方名称
+func (n *HTTPNotifier) Name() string {
+	return "http"
+}
+
+// Send 发送HTTP请求，4xx（429除外）视为不可重试错误
+func (n *HTTPNotifier) Send(ctx context.Context, msg Message) error {
+	url := n.URL
+	if n.Channel == ChannelWebhook && strings.HasPrefix(msg.To, "http") {
+		url = msg.To
+	}
+	if url == "" {
+		return fmt.Errorf("%w: 未配置%s网关地址", ErrPermanent, n.Channel)
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"channel": n.Channel,
+		"to":      msg.To,
+		"subject": msg.Subject,
+		"body":    msg.Body,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.Token)
+	}
+
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("网关返回状态码 %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+		return fmt.Errorf("%w: %v", ErrPermanent, err)
+	}
+	return err
+}
+
+// SMTPNotifier 通过SMTP服务器发送纯文本邮件
+type SMTPNotifier struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// Name 返回发送方名称
+func (n *SMTPNotifier) Name() string {
+	return "smtp"
+}
+
+// Send 发送邮件
+func (n *SMTPNotifier) Send(ctx context.Context, msg Message) error {
+	if !strings.Contains(msg.To, "@") {
+		return fmt.Errorf("%w: 无效的邮箱地址 %s", ErrPermanent, msg.To)
+	}
+
+	var auth smtp.Auth
+	if n.Username != "" {
+		auth = smtp.PlainAuth("", n.Username, n.Password, n.Host)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", n.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	buf.WriteString(msg.Body)
+
+	addr := fmt.Sprintf("%s:%d", n.Host, n.Port)
+	return smtp.SendMail(addr, auth, n.From, []string{msg.To}, buf.Bytes())
+}
@@ -0,0 +1,71 @@
+package notifier
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// rateLimiter 令牌桶限流器，桶容量等于每分钟配额
+type rateLimiter struct {
+	mu       sync.Mutex
+	capacity float64
+	tokens   float64
+	rate     float64 // 每秒补充的令牌数
+	last     time.Time
+}
+
+// newRateLimiter 创建每分钟最多perMinute次的限流器，perMinute<=0表示不限流
+func newRateLimiter(perMinute int) *rateLimiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &rateLimiter{
+		capacity: float64(perMinute),
+		tokens:   float64(perMinute),
+		rate:     float64(perMinute) / 60,
+		last:     time.Now(),
+	}
+}
+
+// Wait 阻塞直到获得一个令牌或ctx结束
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	for {
+		delay := l.reserve()
+		if delay == 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// reserve 尝试取走一个令牌，失败时返回需要等待的时间
+func (l *rateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.capacity {
+		l.tokens = l.capacity
+	}
+	l.last = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+
+	return time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+}
@@ -0,0 +1,92 @@
+package notifier
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"text/template"
+)
+
+// messageTemplate 已解析的消息模板
+type messageTemplate struct {
+	subject *template.Template
+	body    *template.Template
+}
+
+// templateRegistry 按名称和渠道管理消息模板，渠道为空的模板作为该名称的默认模板
+type templateRegistry struct {
+	mu        sync.RWMutex
+	templates map[string]map[Channel]*messageTemplate
+}
+
+// drillPrefix 演练消息的标题前缀，数据中is_drill为true时输出
+const drillPrefix = "{{if .is_drill}}【演练】{{end}}"
+
+// 内置模板
+var defaultTemplates = []struct {
+	Name    string
+	Channel Channel
+	Subject string
+	Body    string
+}{
+	{"emergency_notification", "", drillPrefix + "【紧急通知】{{.title}}", drillPrefix + "{{.content}}"},
+	{"emergency_notification", ChannelSMS, "", drillPrefix + "【紧急通知】{{.title}}：{{.content}}"},
+	{"emergency_alarm", "", drillPrefix + "【紧急警报】{{.type}}", drillPrefix + "{{.location}}触发{{.type}}警报：{{.description}}，请尽快处理。"},
+	{"emergency_escalation", "", drillPrefix + "【紧急求助升级】第{{.level}}轮", drillPrefix + "{{.name}}您好，紧急事件#{{.emergency_id}}已{{.timeout}}秒无人响应：{{.description}}，请立即处理。"},
+	{"missed_call", "", "未接来电", "您有一个来自{{.device_name}}的未接来电，时间：{{.time}}。"},
+	{"password_reset", "", "找回密码验证码", "{{.name}}您好，您的找回密码验证码为{{.code}}，{{.ttl}}分钟内有效。如非本人操作，请忽略。"},
+	{"login_code", "", "登录验证码", "您的登录验证码为{{.code}}，{{.ttl}}分钟内有效。请勿告知他人，如非本人操作，请忽略。"},
+}
+
+// newTemplateRegistry 创建包含内置模板的模板注册表
+func newTemplateRegistry() *templateRegistry {
+	registry := &templateRegistry{templates: make(map[string]map[Channel]*messageTemplate)}
+	for _, t := range defaultTemplates {
+		if err := registry.Register(t.Name, t.Channel, t.Subject, t.Body); err != nil {
+			panic(fmt.Sprintf("内置模板 %s 解析失败: %v", t.Name, err))
+		}
+	}
+	return registry
+}
+
+// Register 注册或覆盖模板，channel为空表示所有渠道通用
+func (r *templateRegistry) Register(name string, channel Channel, subject, body string) error {
+	subjectTmpl, err := template.New(name + ".subject").Option("missingkey=zero").Parse(subject)
+	if err != nil {
+		return fmt.Errorf("解析模板标题失败: %w", err)
+	}
+	bodyTmpl, err := template.New(name + ".body").Option("missingkey=zero").Parse(body)
+	if err != nil {
+		return fmt.Errorf("解析模板内容失败: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.templates[name] == nil {
+		r.templates[name] = make(map[Channel]*messageTemplate)
+	}
+	r.templates[name][channel] = &messageTemplate{subject: subjectTmpl, body: bodyTmpl}
+	return nil
+}
+
+// Render 渲染模板，优先使用渠道专属模板
+func (r *templateRegistry) Render(name string, channel Channel, data map[string]interface{}) (string, string, error) {
+	r.mu.RLock()
+	tmpl, ok := r.templates[name][channel]
+	if !ok {
+		tmpl, ok = r.templates[name][""]
+	}
+	r.mu.RUnlock()
+	if !ok {
+		return "", "", fmt.Errorf("%w: 模板 %s 不存在", ErrPermanent, name)
+	}
+
+	var subject, body bytes.Buffer
+	if err := tmpl.subject.Execute(&subject, data); err != nil {
+		return "", "", fmt.Errorf("%w: 渲染模板 %s 失败: %v", ErrPermanent, name, err)
+	}
+	if err := tmpl.body.Execute(&body, data); err != nil {
+		return "", "", fmt.Errorf("%w: 渲染模板 %s 失败: %v", ErrPermanent, name, err)
+	}
+	return subject.String(), body.String(), nil
+}
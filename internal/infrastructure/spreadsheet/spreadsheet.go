@@ -0,0 +1,293 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// 支持的文件格式
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// maxXMLSize XLSX 中单个XML文件解压后的最大字节数，防止压缩炸弹
+const maxXMLSize = 64 << 20
+
+var (
+	// ErrUnsupportedFormat 文件格式不支持
+	ErrUnsupportedFormat = errors.New("只支持 CSV 和 XLSX 文件")
+	// ErrInvalidFile 文件内容无法解析
+	ErrInvalidFile = errors.New("文件内容无法解析")
+)
+
+// FormatFromFilename 根据文件扩展名判断文件格式
+func FormatFromFilename(name string) (string, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return FormatCSV, nil
+	case ".xlsx":
+		return FormatXLSX, nil
+	default:
+		return "", ErrUnsupportedFormat
+	}
+}
+
+// ReadRows 读取文件中的所有行，XLSX 只读取第一个工作表。
+// 返回值的下标加1即为文件中的行号，XLSX 中跳过的空行以空切片占位
+func ReadRows(r io.ReaderAt, size int64, format string) ([][]string, error) {
+	switch format {
+	case FormatCSV:
+		return readCSV(io.NewSectionReader(r, 0, size))
+	case FormatXLSX:
+		return readXLSX(r, size)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+// readCSV 读取CSV文件，去掉Excel导出时带的UTF-8 BOM
+func readCSV(r io.Reader) ([][]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF"))
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	return rows, nil
+}
+
+// xlsxWorkbook xl/workbook.xml 中的工作表列表
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+// xlsxRelationships xl/_rels/workbook.xml.rels 中工作表ID与文件的对应关系
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// xlsxText 富文本或普通文本，富文本由多个 r 拼接
+type xlsxText struct {
+	T string `xml:"t"`
+	R []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.R) == 0 {
+		return t.T
+	}
+	var b strings.Builder
+	b.WriteString(t.T)
+	for _, r := range t.R {
+		b.WriteString(r.T)
+	}
+	return b.String()
+}
+
+// xlsxSharedStrings xl/sharedStrings.xml 共享字符串表
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+// xlsxWorksheet 工作表中的行和单元格
+type xlsxWorksheet struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			R  string    `xml:"r,attr"`
+			T  string    `xml:"t,attr"`
+			V  string    `xml:"v"`
+			IS *xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// readXLSX 读取XLSX文件第一个工作表的单元格文本
+func readXLSX(r io.ReaderAt, size int64) ([][]string, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, f := range archive.File {
+		files[strings.TrimPrefix(f.Name, "/")] = f
+	}
+
+	sheetPath, err := firstSheetPath(files)
+	if err != nil {
+		return nil, err
+	}
+
+	var shared xlsxSharedStrings
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := decodeXML(f, &shared); err != nil {
+			return nil, err
+		}
+	}
+
+	f, ok := files[sheetPath]
+	if !ok {
+		return nil, fmt.Errorf("%w: 缺少工作表 %s", ErrInvalidFile, sheetPath)
+	}
+	var sheet xlsxWorksheet
+	if err := decodeXML(f, &sheet); err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	for _, row := range sheet.Rows {
+		rowNumber := row.R
+		if rowNumber <= 0 {
+			rowNumber = len(rows) + 1
+		}
+		if rowNumber < len(rows)+1 {
+			return nil, fmt.Errorf("%w: 行号 %d 顺序错误", ErrInvalidFile, rowNumber)
+		}
+		for len(rows) < rowNumber {
+			rows = append(rows, nil)
+		}
+
+		var values []string
+		for _, cell := range row.Cells {
+			column := len(values)
+			if cell.R != "" {
+				if column, err = columnIndex(cell.R); err != nil {
+					return nil, err
+				}
+			}
+			for len(values) <= column {
+				values = append(values, "")
+			}
+			values[column] = cellValue(cell.T, cell.V, cell.IS, shared.Items)
+		}
+		rows[rowNumber-1] = values
+	}
+	return rows, nil
+}
+
+// firstSheetPath 找到工作簿中第一个工作表的文件路径
+func firstSheetPath(files map[string]*zip.File) (string, error) {
+	var workbook xlsxWorkbook
+	f, ok := files["xl/workbook.xml"]
+	if !ok {
+		return "", fmt.Errorf("%w: 缺少 xl/workbook.xml", ErrInvalidFile)
+	}
+	if err := decodeXML(f, &workbook); err != nil {
+		return "", err
+	}
+	if len(workbook.Sheets) == 0 {
+		return "", fmt.Errorf("%w: 工作簿中没有工作表", ErrInvalidFile)
+	}
+
+	var rels xlsxRelationships
+	if f, ok := files["xl/_rels/workbook.xml.rels"]; ok {
+		if err := decodeXML(f, &rels); err != nil {
+			return "", err
+		}
+	}
+	for _, rel := range rels.Relationships {
+		if rel.ID != workbook.Sheets[0].RID {
+			continue
+		}
+		// 相对路径相对于 xl 目录，以 / 开头的为包内绝对路径
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Clean(path.Join("xl", rel.Target)), nil
+	}
+	return "xl/worksheets/sheet1.xml", nil
+}
+
+// decodeXML 解压并解析XML文件
+func decodeXML(f *zip.File, v interface{}) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, maxXMLSize+1))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	if len(data) > maxXMLSize {
+		return fmt.Errorf("%w: %s 过大", ErrInvalidFile, f.Name)
+	}
+	if err := xml.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidFile, f.Name, err)
+	}
+	return nil
+}
+
+// columnIndex 把单元格引用（如 "AB12"）的列转换为从0开始的下标
+func columnIndex(ref string) (int, error) {
+	index := 0
+	letters := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		index = index*26 + int(r-'A'+1)
+		letters++
+	}
+	if letters == 0 || letters > 3 {
+		return 0, fmt.Errorf("%w: 无效的单元格引用 %s", ErrInvalidFile, ref)
+	}
+	return index - 1, nil
+}
+
+// cellValue 按单元格类型取得文本，整数形式的数字（如手机号）不使用科学计数法
+func cellValue(cellType, value string, inline *xlsxText, shared []xlsxText) string {
+	switch cellType {
+	case "s":
+		i, err := strconv.Atoi(value)
+		if err != nil || i < 0 || i >= len(shared) {
+			return ""
+		}
+		return shared[i].String()
+	case "inlineStr":
+		if inline == nil {
+			return ""
+		}
+		return inline.String()
+	case "b":
+		if value == "1" {
+			return "TRUE"
+		}
+		return "FALSE"
+	case "str", "e":
+		return value
+	default:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil || f != math.Trunc(f) || math.Abs(f) >= 1e15 {
+			return value
+		}
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+}
@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// 手机号：1开头的11位号码
+	mobilePattern = regexp.MustCompile(`^1[3-9]\d{9}$`)
+	// 固定电话：区号(3-4位，0开头)加7-8位号码，可带分机号
+	landlinePattern = regexp.MustCompile(`^0\d{2,3}\d{7,8}(\d{1,6})?$`)
+	// 特服号码：如110、119、120，以及95/96开头的客服号码
+	servicePattern = regexp.MustCompile(`^(1\d{2}|9[56]\d{3,6})$`)
+	// 国际号码：+国家码加号码，共8-15位数字
+	internationalPattern = regexp.MustCompile(`^\+[1-9]\d{7,14}$`)
+)
+
+// NormalizePhoneNumber 去除电话号码中的空格、短横线和括号
+func NormalizePhoneNumber(phone string) string {
+	return strings.NewReplacer(" ", "", "-", "", "(", "", ")", "").Replace(strings.TrimSpace(phone))
+}
+
+// IsValidPhoneNumber 检查电话号码是否为有效的手机、固话、特服或国际号码
+func IsValidPhoneNumber(phone string) bool {
+	phone = NormalizePhoneNumber(phone)
+
+	return mobilePattern.MatchString(phone) ||
+		landlinePattern.MatchString(phone) ||
+		servicePattern.MatchString(phone) ||
+		internationalPattern.MatchString(phone)
+}
@@ -3,6 +3,9 @@ package utils
 import (
 	"crypto/rand"
 	"encoding/binary"
+	"encoding/hex"
+	"math/big"
+	"strings"
 )
 
 // RandomInt32 生成一个安全的随机32位整数
@@ -15,3 +18,71 @@ func RandomInt32() int32 {
 
 	return num
 }
+
+// RandomHex 生成指定字节数的安全随机数，并以十六进制字符串返回
+func RandomHex(n int) (string, error) {
+	bytes := make([]byte, n)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+// RandomDigits 生成指定位数的安全随机数字串，可用于验证码、通行码
+func RandomDigits(n int) (string, error) {
+	digits := make([]byte, 0, n)
+	buf := make([]byte, n)
+	for len(digits) < n {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			// 丢弃250及以上的值，避免取模偏差
+			if b >= 250 || len(digits) == n {
+				continue
+			}
+			digits = append(digits, '0'+b%10)
+		}
+	}
+	return string(digits), nil
+}
+
+// passwordCharsets 随机密码使用的字符集，每类至少包含一个字符
+var passwordCharsets = []string{
+	"ABCDEFGHJKLMNPQRSTUVWXYZ",
+	"abcdefghijkmnpqrstuvwxyz",
+	"23456789",
+	"!@#$%^&*-_=+",
+}
+
+// RandomPassword 生成指定长度的安全随机密码，包含大小写字母、数字和特殊字符，
+// 不使用容易混淆的 0、O、1、l、I，可用于临时密码
+func RandomPassword(n int) (string, error) {
+	if n < len(passwordCharsets) {
+		n = len(passwordCharsets)
+	}
+	all := strings.Join(passwordCharsets, "")
+
+	password := make([]byte, n)
+	for i := range password {
+		charset := all
+		if i < len(passwordCharsets) {
+			charset = passwordCharsets[i]
+		}
+		index, err := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
+		if err != nil {
+			return "", err
+		}
+		password[i] = charset[index.Int64()]
+	}
+
+	// 打乱顺序，避免前几位的字符类型固定
+	for i := len(password) - 1; i > 0; i-- {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return "", err
+		}
+		password[i], password[j.Int64()] = password[j.Int64()], password[i]
+	}
+	return string(password), nil
+}
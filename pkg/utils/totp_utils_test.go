@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret RFC 6238 附录B SHA1测试向量的密钥 "12345678901234567890" 的Base32编码
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// RFC 6238 附录B的SHA1测试向量，取8位验证码的后6位
+var rfc6238Vectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestTOTPCodeRFC6238Vectors(t *testing.T) {
+	for _, v := range rfc6238Vectors {
+		got, err := TOTPCode(rfc6238Secret, TOTPStep(time.Unix(v.unix, 0)))
+		if err != nil {
+			t.Fatalf("TOTPCode(%d): %v", v.unix, err)
+		}
+		if got != v.code {
+			t.Errorf("TOTPCode(%d) = %s, want %s", v.unix, got, v.code)
+		}
+	}
+}
+
+func TestValidateTOTPRFC6238Vectors(t *testing.T) {
+	for _, v := range rfc6238Vectors {
+		now := time.Unix(v.unix, 0)
+		step, ok := ValidateTOTP(rfc6238Secret, v.code, now, 0)
+		if !ok || step != TOTPStep(now) {
+			t.Errorf("ValidateTOTP(%d) = %d, %v, want %d, true", v.unix, step, ok, TOTPStep(now))
+		}
+	}
+}
+
+func TestValidateTOTPSkewWindow(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := TOTPStep(now)
+	codeAt := func(offset int64) string {
+		code, err := TOTPCode(rfc6238Secret, current+offset)
+		if err != nil {
+			t.Fatalf("TOTPCode: %v", err)
+		}
+		return code
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		skew     int
+		wantStep int64
+		wantOK   bool
+	}{
+		{name: "current step", code: codeAt(0), skew: 1, wantStep: current, wantOK: true},
+		{name: "previous step within skew", code: codeAt(-1), skew: 1, wantStep: current - 1, wantOK: true},
+		{name: "next step within skew", code: codeAt(1), skew: 1, wantStep: current + 1, wantOK: true},
+		{name: "two steps behind", code: codeAt(-2), skew: 1},
+		{name: "two steps ahead", code: codeAt(2), skew: 1},
+		{name: "previous step without skew", code: codeAt(-1), skew: 0},
+		{name: "surrounding whitespace", code: " " + codeAt(0) + "\n", skew: 1, wantStep: current, wantOK: true},
+		{name: "too short", code: codeAt(0)[:5], skew: 1},
+		{name: "too long", code: codeAt(0) + "0", skew: 1},
+		{name: "wrong code", code: "000000", skew: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := ValidateTOTP(rfc6238Secret, tt.code, now, tt.skew)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Fatalf("ValidateTOTP = %d, %v, want %d, %v", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPSecretFormat(t *testing.T) {
+	now := time.Unix(59, 0)
+	if _, ok := ValidateTOTP("gezdgnbvgy3tqojqgezdgnbvgy3tqojq", "287082", now, 0); !ok {
+		t.Fatal("lowercase secret should be accepted")
+	}
+	if _, ok := ValidateTOTP("not base32!", "287082", now, 0); ok {
+		t.Fatal("invalid secret should be rejected")
+	}
+}